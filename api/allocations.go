@@ -147,6 +147,30 @@ func (a *Allocations) Signal(alloc *Allocation, q *QueryOptions, task, signal st
 	return err
 }
 
+// Pause freezes the processes of the allocation's task, or of all of its
+// running tasks if task is empty, until Resume is called.
+func (a *Allocations) Pause(alloc *Allocation, task string, q *QueryOptions) error {
+	req := AllocPauseRequest{
+		Task: task,
+	}
+
+	var resp GenericResponse
+	_, err := a.client.putQuery("/v1/client/allocation/"+alloc.ID+"/pause", &req, &resp, q)
+	return err
+}
+
+// Resume thaws the processes of the allocation's paused task, or of all of
+// its paused tasks if task is empty.
+func (a *Allocations) Resume(alloc *Allocation, task string, q *QueryOptions) error {
+	req := AllocPauseRequest{
+		Task: task,
+	}
+
+	var resp GenericResponse
+	_, err := a.client.putQuery("/v1/client/allocation/"+alloc.ID+"/resume", &req, &resp, q)
+	return err
+}
+
 // Allocation is used for serialization of allocations.
 type Allocation struct {
 	ID                    string
@@ -402,6 +426,10 @@ type AllocSignalRequest struct {
 	Signal string
 }
 
+type AllocPauseRequest struct {
+	Task string
+}
+
 // GenericResponse is used to respond to a request where no
 // specific response information is needed.
 type GenericResponse struct {
@@ -507,6 +507,7 @@ type DriverInfo struct {
 	Healthy           bool
 	HealthDescription string
 	UpdateTime        time.Time
+	Degraded          bool
 }
 
 // HostVolumeInfo is used to return metadata about a given HostVolume.
@@ -41,10 +41,21 @@ type CpuStats struct {
 	Measured         []string
 }
 
+// DiskIOStats holds block device I/O stats
+type DiskIOStats struct {
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadOps    uint64
+	WriteOps   uint64
+	Devices    map[string]*DiskIOStats
+	Measured   []string
+}
+
 // ResourceUsage holds information related to cpu and memory stats
 type ResourceUsage struct {
 	MemoryStats *MemoryStats
 	CpuStats    *CpuStats
+	DiskIOStats *DiskIOStats
 	DeviceStats []*DeviceGroupStats
 }
 
@@ -431,6 +442,7 @@ type TaskGroup struct {
 	Services                  []*Service                `hcl:"service,block"`
 	ShutdownDelay             *time.Duration            `mapstructure:"shutdown_delay" hcl:"shutdown_delay,optional"`
 	StopAfterClientDisconnect *time.Duration            `mapstructure:"stop_after_client_disconnect" hcl:"stop_after_client_disconnect,optional"`
+	CSIClaimTimeout           *time.Duration            `mapstructure:"csi_claim_timeout" hcl:"csi_claim_timeout,optional"`
 	Scaling                   *ScalingPolicy            `hcl:"scaling,block"`
 	Consul                    *Consul                   `hcl:"consul,block"`
 }
@@ -657,6 +669,26 @@ func (l *TaskLifecycle) Empty() bool {
 	return l == nil || (l.Hook == "")
 }
 
+// TaskTmpfs configures a scratch directory backed by RAM that is mounted into
+// the task.
+type TaskTmpfs struct {
+	SizeMB      *int    `mapstructure:"size" hcl:"size,optional"`
+	Mode        *string `mapstructure:"mode" hcl:"mode,optional"`
+	CountMemory *bool   `mapstructure:"count_memory" hcl:"count_memory,optional"`
+}
+
+func (t *TaskTmpfs) Canonicalize() {
+	if t.SizeMB == nil {
+		t.SizeMB = intToPtr(0)
+	}
+	if t.Mode == nil {
+		t.Mode = stringToPtr("0700")
+	}
+	if t.CountMemory == nil {
+		t.CountMemory = boolToPtr(true)
+	}
+}
+
 // Task is a single process in a task group.
 type Task struct {
 	Name            string                 `hcl:"name,label"`
@@ -684,6 +716,8 @@ type Task struct {
 	KillSignal      string                 `mapstructure:"kill_signal" hcl:"kill_signal,optional"`
 	Kind            string                 `hcl:"kind,optional"`
 	ScalingPolicies []*ScalingPolicy       `hcl:"scaling,block"`
+	ChrootExtras    []string               `mapstructure:"chroot_extras" hcl:"chroot_extras,optional"`
+	Tmpfs           *TaskTmpfs             `hcl:"tmpfs,block"`
 }
 
 func (t *Task) Canonicalize(tg *TaskGroup, job *Job) {
@@ -724,6 +758,9 @@ func (t *Task) Canonicalize(tg *TaskGroup, job *Job) {
 	if t.CSIPluginConfig != nil {
 		t.CSIPluginConfig.Canonicalize()
 	}
+	if t.Tmpfs != nil {
+		t.Tmpfs.Canonicalize()
+	}
 	if t.RestartPolicy == nil {
 		t.RestartPolicy = tg.RestartPolicy
 	} else {
@@ -790,19 +827,41 @@ func (wc *WaitConfig) Copy() *WaitConfig {
 	return nwc
 }
 
+// TemplateRetryConfig overrides the client's retry configs for the requests
+// made to render a task's templates.
+type TemplateRetryConfig struct {
+	Attempts   *int           `mapstructure:"attempts" hcl:"attempts,optional"`
+	Backoff    *time.Duration `mapstructure:"backoff" hcl:"backoff,optional"`
+	MaxBackoff *time.Duration `mapstructure:"max_backoff" hcl:"max_backoff,optional"`
+}
+
+func (rc *TemplateRetryConfig) Copy() *TemplateRetryConfig {
+	if rc == nil {
+		return nil
+	}
+
+	nrc := new(TemplateRetryConfig)
+	*nrc = *rc
+
+	return nrc
+}
+
 type Template struct {
-	SourcePath   *string        `mapstructure:"source" hcl:"source,optional"`
-	DestPath     *string        `mapstructure:"destination" hcl:"destination,optional"`
-	EmbeddedTmpl *string        `mapstructure:"data" hcl:"data,optional"`
-	ChangeMode   *string        `mapstructure:"change_mode" hcl:"change_mode,optional"`
-	ChangeSignal *string        `mapstructure:"change_signal" hcl:"change_signal,optional"`
-	Splay        *time.Duration `mapstructure:"splay" hcl:"splay,optional"`
-	Perms        *string        `mapstructure:"perms" hcl:"perms,optional"`
-	LeftDelim    *string        `mapstructure:"left_delimiter" hcl:"left_delimiter,optional"`
-	RightDelim   *string        `mapstructure:"right_delimiter" hcl:"right_delimiter,optional"`
-	Envvars      *bool          `mapstructure:"env" hcl:"env,optional"`
-	VaultGrace   *time.Duration `mapstructure:"vault_grace" hcl:"vault_grace,optional"`
-	Wait         *WaitConfig    `mapstructure:"wait" hcl:"wait,block"`
+	SourcePath   *string              `mapstructure:"source" hcl:"source,optional"`
+	DestPath     *string              `mapstructure:"destination" hcl:"destination,optional"`
+	EmbeddedTmpl *string              `mapstructure:"data" hcl:"data,optional"`
+	ChangeMode   *string              `mapstructure:"change_mode" hcl:"change_mode,optional"`
+	ChangeSignal *string              `mapstructure:"change_signal" hcl:"change_signal,optional"`
+	Splay        *time.Duration       `mapstructure:"splay" hcl:"splay,optional"`
+	Perms        *string              `mapstructure:"perms" hcl:"perms,optional"`
+	LeftDelim    *string              `mapstructure:"left_delimiter" hcl:"left_delimiter,optional"`
+	RightDelim   *string              `mapstructure:"right_delimiter" hcl:"right_delimiter,optional"`
+	Envvars      *bool                `mapstructure:"env" hcl:"env,optional"`
+	VaultGrace   *time.Duration       `mapstructure:"vault_grace" hcl:"vault_grace,optional"`
+	Wait         *WaitConfig          `mapstructure:"wait" hcl:"wait,block"`
+	Retry        *TemplateRetryConfig `mapstructure:"retry" hcl:"retry,block"`
+	RestartOrder *string              `mapstructure:"restart_order" hcl:"restart_order,optional"`
+	DependsOn    []string             `mapstructure:"depends_on" hcl:"depends_on,optional"`
 }
 
 func (tmpl *Template) Canonicalize() {
@@ -938,6 +997,29 @@ type TaskState struct {
 	// Experimental -  TaskHandle is based on drivers.TaskHandle and used
 	// by remote task drivers to migrate task handles between allocations.
 	TaskHandle *TaskHandle
+
+	// TerminationReason classifies the task's most recent termination or
+	// failure to start. It's nil until the task terminates, and for clients
+	// that don't report it.
+	TerminationReason *TaskTerminationReason
+}
+
+const (
+	TaskTerminationExited        = "exited"
+	TaskTerminationSignaled      = "signaled"
+	TaskTerminationOOMKilled     = "oom_killed"
+	TaskTerminationDriverFailure = "driver_failure"
+	TaskTerminationSetupFailure  = "setup_failure"
+)
+
+// TaskTerminationReason is the structured classification of why a task
+// terminated or failed to start.
+type TaskTerminationReason struct {
+	// Reason is one of the TaskTermination constants
+	Reason    string
+	ExitCode  int
+	Signal    int
+	Subsystem string
 }
 
 // Experimental - TaskHandle is based on drivers.TaskHandle and used by remote
@@ -406,6 +406,32 @@ func TestTask_Canonicalize_TaskLifecycle(t *testing.T) {
 	}
 }
 
+func TestTask_Canonicalize_Tmpfs(t *testing.T) {
+	tg := &TaskGroup{Name: stringToPtr("foo")}
+	j := &Job{ID: stringToPtr("test")}
+
+	task := &Task{Tmpfs: &TaskTmpfs{SizeMB: intToPtr(64)}}
+	task.Canonicalize(tg, j)
+	require.Equal(t, &TaskTmpfs{
+		SizeMB:      intToPtr(64),
+		Mode:        stringToPtr("0700"),
+		CountMemory: boolToPtr(true),
+	}, task.Tmpfs)
+
+	task = &Task{Tmpfs: &TaskTmpfs{
+		SizeMB:      intToPtr(64),
+		Mode:        stringToPtr("0750"),
+		CountMemory: boolToPtr(false),
+	}}
+	task.Canonicalize(tg, j)
+	require.Equal(t, "0750", *task.Tmpfs.Mode)
+	require.False(t, *task.Tmpfs.CountMemory)
+
+	task = &Task{}
+	task.Canonicalize(tg, j)
+	require.Nil(t, task.Tmpfs)
+}
+
 func TestTask_Template_WaitConfig_Canonicalize_and_Copy(t *testing.T) {
 	taskWithWait := func(wc *WaitConfig) *Task {
 		return &Task{
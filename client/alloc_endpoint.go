@@ -31,8 +31,9 @@ func NewAllocationsEndpoint(c *Client) *Allocations {
 }
 
 // GarbageCollectAll is used to garbage collect all allocations on a client.
-func (a *Allocations) GarbageCollectAll(args *nstructs.NodeSpecificRequest, reply *nstructs.GenericResponse) error {
+func (a *Allocations) GarbageCollectAll(args *nstructs.NodeSpecificRequest, reply *nstructs.GenericResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "allocations", "garbage_collect_all"}, time.Now())
+	defer a.c.auditRPC("Allocations.GarbageCollectAll", args.AuthToken, args, &err)
 
 	// Check node write permissions
 	if aclObj, err := a.c.ResolveToken(args.AuthToken); err != nil {
@@ -46,8 +47,9 @@ func (a *Allocations) GarbageCollectAll(args *nstructs.NodeSpecificRequest, repl
 }
 
 // GarbageCollect is used to garbage collect an allocation on a client.
-func (a *Allocations) GarbageCollect(args *nstructs.AllocSpecificRequest, reply *nstructs.GenericResponse) error {
+func (a *Allocations) GarbageCollect(args *nstructs.AllocSpecificRequest, reply *nstructs.GenericResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "allocations", "garbage_collect"}, time.Now())
+	defer a.c.auditRPC("Allocations.GarbageCollect", args.AuthToken, args, &err)
 
 	alloc, err := a.c.GetAlloc(args.AllocID)
 	if err != nil {
@@ -69,8 +71,9 @@ func (a *Allocations) GarbageCollect(args *nstructs.AllocSpecificRequest, reply
 }
 
 // Signal is used to send a signal to an allocation's tasks on a client.
-func (a *Allocations) Signal(args *nstructs.AllocSignalRequest, reply *nstructs.GenericResponse) error {
+func (a *Allocations) Signal(args *nstructs.AllocSignalRequest, reply *nstructs.GenericResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "allocations", "signal"}, time.Now())
+	defer a.c.auditRPC("Allocations.Signal", args.AuthToken, args, &err)
 
 	alloc, err := a.c.GetAlloc(args.AllocID)
 	if err != nil {
@@ -87,9 +90,65 @@ func (a *Allocations) Signal(args *nstructs.AllocSignalRequest, reply *nstructs.
 	return a.c.SignalAllocation(args.AllocID, args.Task, args.Signal)
 }
 
+// Pause is used to freeze the processes of an allocation's tasks on a client.
+func (a *Allocations) Pause(args *nstructs.AllocPauseRequest, reply *nstructs.GenericResponse) (err error) {
+	defer metrics.MeasureSince([]string{"client", "allocations", "pause"}, time.Now())
+	defer a.c.auditRPC("Allocations.Pause", args.AuthToken, args, &err)
+
+	alloc, err := a.c.GetAlloc(args.AllocID)
+	if err != nil {
+		return err
+	}
+
+	// Check namespace alloc-lifecycle permission.
+	aclObj, token, err := a.c.resolveTokenAndACL(args.AuthToken)
+	if err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNsOp(alloc.Namespace, acl.NamespaceCapabilityAllocLifecycle) {
+		return nstructs.ErrPermissionDenied
+	}
+
+	return a.c.PauseAllocation(args.AllocID, args.Task, requester(token))
+}
+
+// Resume is used to thaw the processes of an allocation's paused tasks on a
+// client.
+func (a *Allocations) Resume(args *nstructs.AllocPauseRequest, reply *nstructs.GenericResponse) (err error) {
+	defer metrics.MeasureSince([]string{"client", "allocations", "resume"}, time.Now())
+	defer a.c.auditRPC("Allocations.Resume", args.AuthToken, args, &err)
+
+	alloc, err := a.c.GetAlloc(args.AllocID)
+	if err != nil {
+		return err
+	}
+
+	// Check namespace alloc-lifecycle permission.
+	aclObj, token, err := a.c.resolveTokenAndACL(args.AuthToken)
+	if err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNsOp(alloc.Namespace, acl.NamespaceCapabilityAllocLifecycle) {
+		return nstructs.ErrPermissionDenied
+	}
+
+	return a.c.ResumeAllocation(args.AllocID, args.Task, requester(token))
+}
+
+// requester identifies who made a request by their ACL token, for task
+// events. The token is nil if ACLs are disabled.
+func requester(token *nstructs.ACLToken) string {
+	if token == nil {
+		return "operator"
+	}
+	if token.Name == "" {
+		return token.AccessorID
+	}
+	return fmt.Sprintf("%s (%s)", token.Name, token.AccessorID)
+}
+
 // Restart is used to trigger a restart of an allocation or a subtask on a client.
-func (a *Allocations) Restart(args *nstructs.AllocRestartRequest, reply *nstructs.GenericResponse) error {
+func (a *Allocations) Restart(args *nstructs.AllocRestartRequest, reply *nstructs.GenericResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "allocations", "restart"}, time.Now())
+	defer a.c.auditRPC("Allocations.Restart", args.AuthToken, args, &err)
 
 	alloc, err := a.c.GetAlloc(args.AllocID)
 	if err != nil {
@@ -137,6 +196,32 @@ func (a *Allocations) Stats(args *cstructs.AllocStatsRequest, reply *cstructs.Al
 	return nil
 }
 
+// CSIMounts is used to find where the CSI volumes of an allocation are
+// mounted on the host
+func (a *Allocations) CSIMounts(args *cstructs.AllocCSIMountsRequest, reply *cstructs.AllocCSIMountsResponse) error {
+	defer metrics.MeasureSince([]string{"client", "allocations", "csi_mounts"}, time.Now())
+
+	alloc, err := a.c.GetAlloc(args.AllocID)
+	if err != nil {
+		return err
+	}
+
+	// Check read-job permission.
+	if aclObj, err := a.c.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if aclObj != nil && !aclObj.AllowNsOp(alloc.Namespace, acl.NamespaceCapabilityReadJob) {
+		return nstructs.ErrPermissionDenied
+	}
+
+	mounts, err := a.c.GetAllocCSIMounts(args.AllocID)
+	if err != nil {
+		return err
+	}
+
+	reply.Mounts = mounts
+	return nil
+}
+
 // exec is used to execute command in a running task
 func (a *Allocations) exec(conn io.ReadWriteCloser) {
 	defer metrics.MeasureSince([]string{"client", "allocations", "exec"}, time.Now())
@@ -163,6 +248,7 @@ func (a *Allocations) execImpl(encoder *codec.Encoder, decoder *codec.Decoder, e
 	if err := decoder.Decode(&req); err != nil {
 		return helper.Int64ToPtr(500), err
 	}
+	defer a.c.auditRPC("Allocations.Exec", req.QueryOptions.AuthToken, &req, &err)
 
 	if a.c.GetConfig().DisableRemoteExec {
 		return nil, nstructs.ErrPermissionDenied
@@ -347,6 +347,41 @@ func TestAllocations_Signal(t *testing.T) {
 	require.Contains(t, err.Error(), "Failed to signal task: web, err: Task not running")
 }
 
+func TestAllocations_Pause(t *testing.T) {
+	t.Parallel()
+
+	client, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	a := mock.SystemAlloc()
+	require.Nil(t, client.addAlloc(a, ""))
+
+	// Try with bad alloc
+	req := &nstructs.AllocPauseRequest{}
+	var resp nstructs.GenericResponse
+	err := client.ClientRPC("Allocations.Pause", &req, &resp)
+	require.NotNil(t, err)
+	require.True(t, nstructs.IsErrUnknownAllocation(err))
+
+	// Tasks of system jobs can't be paused
+	req.AllocID = a.ID
+	err = client.ClientRPC("Allocations.Pause", &req, &resp)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Failed to pause task: web, err: tasks of system jobs can't be paused")
+
+	req.Task = "web"
+	err = client.ClientRPC("Allocations.Pause", &req, &resp)
+	require.EqualError(t, err, "tasks of system jobs can't be paused")
+
+	// Resuming an allocation without paused tasks does nothing
+	req.Task = ""
+	require.NoError(t, client.ClientRPC("Allocations.Resume", &req, &resp))
+
+	req.Task = "web"
+	err = client.ClientRPC("Allocations.Resume", &req, &resp)
+	require.EqualError(t, err, "task is not paused")
+}
+
 func TestAllocations_Signal_ACL(t *testing.T) {
 	t.Parallel()
 	require := require.New(t)
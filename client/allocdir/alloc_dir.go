@@ -58,6 +58,10 @@ var (
 	// directory
 	TaskSecrets = "secrets"
 
+	// TaskTmpfs is the name of the memory backed scratch directory inside
+	// each task directory that requests one
+	TaskTmpfs = "tmpfs"
+
 	// TaskDirs is the set of directories created in each tasks directory.
 	TaskDirs = map[string]os.FileMode{TmpDirName: os.ModeSticky | 0777}
 
@@ -304,6 +308,11 @@ func (d *AllocDir) UnmountAll() error {
 			}
 		}
 
+		if err := dir.RemoveTmpfs(); err != nil {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("failed to remove the tmpfs dir %q: %v", dir.TmpfsDir, err))
+		}
+
 		// Unmount dev/ and proc/ have been mounted.
 		if err := dir.unmountSpecialDirs(); err != nil {
 			mErr.Errors = append(mErr.Errors, err)
@@ -20,6 +20,12 @@ func createSecretDir(dir string) error {
 	return os.MkdirAll(dir, 0777)
 }
 
+// createTmpfsDir creates the tmpfs dir folder at the given path. The size
+// is only enforced on Linux.
+func createTmpfsDir(dir string, sizeMB int) error {
+	return os.MkdirAll(dir, 0777)
+}
+
 // removeSecretDir removes the secrets dir folder
 func removeSecretDir(dir string) error {
 	return os.RemoveAll(dir)
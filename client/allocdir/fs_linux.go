@@ -74,7 +74,50 @@ func createSecretDir(dir string) error {
 	return os.MkdirAll(dir, 0777)
 }
 
-// createSecretDir removes the secrets dir folder
+// createTmpfsDir creates the tmpfs dir folder at the given path using a tmpfs
+// of the given size in MBs
+func createTmpfsDir(dir string, sizeMB int) error {
+	// Only mount the tmpfs if we are root
+	if unix.Geteuid() == 0 {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+
+		// Skip mounting if the tmpfs is already mounted. Unlike the secrets
+		// dir no marker file is used, as the task could remove it.
+		mounted, err := isMountPoint(dir)
+		if err != nil {
+			return err
+		}
+		if mounted {
+			return nil
+		}
+
+		flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV)
+		options := fmt.Sprintf("size=%dm", sizeMB)
+		if err := syscall.Mount("tmpfs", dir, "tmpfs", flags, options); err != nil {
+			return os.NewSyscallError("mount", err)
+		}
+		return nil
+	}
+
+	return os.MkdirAll(dir, 0777)
+}
+
+// isMountPoint returns whether a filesystem is mounted at dir, which is the
+// case when it's on a different device than its parent.
+func isMountPoint(dir string) (bool, error) {
+	var st, parent unix.Stat_t
+	if err := unix.Lstat(dir, &st); err != nil {
+		return false, os.NewSyscallError("lstat", err)
+	}
+	if err := unix.Lstat(filepath.Dir(dir), &parent); err != nil {
+		return false, os.NewSyscallError("lstat", err)
+	}
+	return st.Dev != parent.Dev, nil
+}
+
+// removeSecretDir removes the secrets dir folder
 func removeSecretDir(dir string) error {
 	if unix.Geteuid() == 0 {
 		if err := unlinkDir(dir); err != nil {
@@ -165,3 +165,63 @@ func TestLinuxUnprivilegedSecretDir(t *testing.T) {
 		t.Fatalf("error removing nonexistent secrets dir %q: %v", secretsDir, err)
 	}
 }
+
+// TestLinuxRootTaskTmpfs asserts the task tmpfs dir is a tmpfs mount with the
+// requested size and permissions, and that building and removing it are
+// idempotent.
+func TestLinuxRootTaskTmpfs(t *testing.T) {
+	if unix.Geteuid() != 0 {
+		t.Skip("Must be run as root")
+	}
+	tmpdir, err := ioutil.TempDir("", "nomadtest-roottmpfsdir")
+	if err != nil {
+		t.Fatalf("unable to create tempdir for test: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	td := &TaskDir{TmpfsDir: filepath.Join(tmpdir, TaskTmpfs)}
+
+	// removing a nonexistent tmpfs dir should NOT error
+	if err := td.RemoveTmpfs(); err != nil {
+		t.Fatalf("error removing nonexistent tmpfs dir: %v", err)
+	}
+
+	// building it twice should work
+	for i := 0; i < 2; i++ {
+		if err := td.BuildTmpfs(4, 0750, ""); err != nil {
+			t.Fatalf("error building tmpfs dir %q: %v", td.TmpfsDir, err)
+		}
+	}
+
+	if err := isMount(td.TmpfsDir); err != nil {
+		t.Fatalf("tmpfs dir %q is not a mount: %v", td.TmpfsDir, err)
+	}
+	if entries, err := ioutil.ReadDir(td.TmpfsDir); err != nil || len(entries) != 0 {
+		t.Fatalf("expected tmpfs dir %q to be empty but found %v: %v", td.TmpfsDir, entries, err)
+	}
+	fi, err := os.Stat(td.TmpfsDir)
+	if err != nil {
+		t.Fatalf("error stat'ing tmpfs dir %q: %v", td.TmpfsDir, err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0750 {
+		t.Fatalf("expected tmpfs dir permissions 0750 but found %o", perm)
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(td.TmpfsDir, &st); err != nil {
+		t.Fatalf("error stat'ing tmpfs fs %q: %v", td.TmpfsDir, err)
+	}
+	if size := uint64(st.Blocks) * uint64(st.Bsize); size != 4*1024*1024 {
+		t.Fatalf("expected tmpfs size of 4MB but found %d bytes", size)
+	}
+
+	// now remove it
+	if err := td.RemoveTmpfs(); err != nil {
+		t.Fatalf("error removing tmpfs dir %q: %v", td.TmpfsDir, err)
+	}
+	if err := isMount(td.TmpfsDir); err != notFoundErr {
+		t.Fatalf("error ensuring tmpfs dir %q isn't mounted: %v", td.TmpfsDir, err)
+	}
+	if _, err := os.Stat(td.TmpfsDir); !os.IsNotExist(err) {
+		t.Fatalf("expected tmpfs dir %q to be removed: %v", td.TmpfsDir, err)
+	}
+}
@@ -26,6 +26,10 @@ var (
 	// TaskSecretsContainerPath is the path inside a container for mounted
 	// secrets directory
 	TaskSecretsContainerPath = filepath.Join("/", TaskSecrets)
+
+	// TaskTmpfsContainerPath is the path inside a container for the mounted
+	// tmpfs scratch directory
+	TaskTmpfsContainerPath = filepath.Join("/", TaskTmpfs)
 )
 
 // dropDirPermissions gives full access to a directory to all users and sets
@@ -62,6 +66,39 @@ func dropDirPermissions(path string, desired os.FileMode) error {
 	return nil
 }
 
+// setDirOwner sets the owner of a directory to the given user, or to nobody
+// if no user is given. It is a noop if not root.
+func setDirOwner(path, username string) error {
+	if unix.Geteuid() != 0 {
+		return nil
+	}
+
+	if username == "" {
+		username = "nobody"
+	}
+
+	u, err := user.Lookup(username)
+	if err != nil {
+		return err
+	}
+
+	uid, err := getUid(u)
+	if err != nil {
+		return err
+	}
+
+	gid, err := getGid(u)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chown(path, uid, gid); err != nil {
+		return fmt.Errorf("Couldn't change owner/group of %v to (uid: %v, gid: %v): %v", path, uid, gid, err)
+	}
+
+	return nil
+}
+
 // getUid for a user
 func getUid(u *user.User) (int, error) {
 	uid, err := strconv.Atoi(u.Uid)
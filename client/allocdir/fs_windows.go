@@ -17,6 +17,10 @@ var (
 	// TaskSecretsContainerPath is the path inside a container for mounted
 	// secrets directory
 	TaskSecretsContainerPath = filepath.Join("c:\\", TaskSecrets)
+
+	// TaskTmpfsContainerPath is the path inside a container for the mounted
+	// tmpfs scratch directory
+	TaskTmpfsContainerPath = filepath.Join("c:\\", TaskTmpfs)
 )
 
 // linkOrCopy is always copies dst to src on Windows.
@@ -44,6 +48,17 @@ func removeSecretDir(dir string) error {
 	return os.RemoveAll(dir)
 }
 
+// createTmpfsDir creates the tmpfs dir folder at the given path. Windows
+// has no tmpfs so it is a plain directory.
+func createTmpfsDir(dir string, sizeMB int) error {
+	return os.MkdirAll(dir, 0777)
+}
+
+// The windows version does nothing currently.
+func setDirOwner(path, username string) error {
+	return nil
+}
+
 // The windows version does nothing currently.
 func dropDirPermissions(path string, desired os.FileMode) error {
 	return nil
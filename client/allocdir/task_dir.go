@@ -39,6 +39,11 @@ type TaskDir struct {
 	// <task_dir>/secrets/
 	SecretsDir string
 
+	// TmpfsDir is the path to the tmpfs/ scratch directory on the host,
+	// only created for tasks requesting one
+	// <task_dir>/tmpfs/
+	TmpfsDir string
+
 	// skip embedding these paths in chroots. Used for avoiding embedding
 	// client.alloc_dir recursively.
 	skip map[string]struct{}
@@ -66,6 +71,7 @@ func newTaskDir(logger hclog.Logger, clientAllocDir, allocDir, taskName string)
 		SharedTaskDir:  filepath.Join(taskDir, SharedAllocName),
 		LocalDir:       filepath.Join(taskDir, TaskLocal),
 		SecretsDir:     filepath.Join(taskDir, TaskSecrets),
+		TmpfsDir:       filepath.Join(taskDir, TaskTmpfs),
 		skip:           skip,
 		logger:         logger,
 	}
@@ -138,6 +144,32 @@ func (t *TaskDir) Build(createChroot bool, chroot map[string]string) error {
 	return nil
 }
 
+// BuildTmpfs creates the task's tmpfs scratch directory of the given size in
+// MBs, owned by the given user and with the given permissions. On Linux the
+// directory is a tmpfs mount when running as root, elsewhere it is a plain
+// directory and its size isn't enforced. Building an existing tmpfs
+// directory only resets its owner and permissions.
+func (t *TaskDir) BuildTmpfs(sizeMB int, mode os.FileMode, username string) error {
+	if err := createTmpfsDir(t.TmpfsDir, sizeMB); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(t.TmpfsDir, mode); err != nil {
+		return fmt.Errorf("Chmod(%v) failed: %v", t.TmpfsDir, err)
+	}
+
+	return setDirOwner(t.TmpfsDir, username)
+}
+
+// RemoveTmpfs unmounts and removes the task's tmpfs scratch directory along
+// with its content. It is a noop if the directory doesn't exist.
+func (t *TaskDir) RemoveTmpfs() error {
+	if !pathExists(t.TmpfsDir) {
+		return nil
+	}
+	return removeSecretDir(t.TmpfsDir)
+}
+
 // buildChroot takes a mapping of absolute directory or file paths on the host
 // to their intended, relative location within the task directory. This
 // attempts hardlink and then defaults to copying. If the path exists on the
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	"github.com/hashicorp/nomad/client/lib/cgutil"
 
 	log "github.com/hashicorp/go-hclog"
@@ -14,6 +15,7 @@ import (
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
 	"github.com/hashicorp/nomad/client/allocrunner/state"
 	"github.com/hashicorp/nomad/client/allocrunner/taskrunner"
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/getter"
 	"github.com/hashicorp/nomad/client/allocwatcher"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/consul"
@@ -42,6 +44,10 @@ type allocRunner struct {
 
 	clientConfig *config.Config
 
+	// getClientConfig returns the client's current configuration. It may be
+	// nil.
+	getClientConfig func() *config.Config
+
 	// stateUpdater is used to emit updated alloc state
 	stateUpdater cinterfaces.AllocStateHandler
 
@@ -125,6 +131,14 @@ type allocRunner struct {
 	// transistions.
 	runnerHooks []interfaces.RunnerHook
 
+	// hookShutdownCtx is passed to postrun hooks, and is cancelled when the
+	// client shuts down. hookPrerunCtx is passed to prerun hooks, and is
+	// also cancelled when the alloc is destroyed.
+	hookShutdownCtx    context.Context
+	hookShutdownCancel context.CancelFunc
+	hookPrerunCtx      context.Context
+	hookPrerunCancel   context.CancelFunc
+
 	// hookState is the output of allocrunner hooks
 	hookState   *cstructs.AllocHookResources
 	hookStateMu sync.RWMutex
@@ -153,9 +167,36 @@ type allocRunner struct {
 	// runner to manage their mounting
 	csiManager csimanager.Manager
 
+	// csiOpScheduler bounds the CSI volume claims and mounts running
+	// concurrently on the node
+	csiOpScheduler *csimanager.OpScheduler
+
+	// csiCapacityBudget bounds the total capacity of the CSI volumes mounted
+	// on the node
+	csiCapacityBudget *csimanager.CapacityBudget
+
+	// csiWriteClaims tracks the write claims on CSI volumes held by the
+	// allocations on the node
+	csiWriteClaims *csimanager.WriteClaimTracker
+
+	// csiClaimBatch records whether the servers support batched CSI volume
+	// claims, and is shared with the other allocations on the node
+	csiClaimBatch *csimanager.ClaimBatchSupport
+
 	// cpusetManager is responsible for configuring task cgroups if supported by the platform
 	cpusetManager cgutil.CpusetManager
 
+	// diskIOCollector reads the block device I/O stats of tasks
+	diskIOCollector *cgutil.DiskIOCollector
+
+	// artifactChecksumPolicy decides whether task artifacts must specify a
+	// checksum
+	artifactChecksumPolicy *getter.ChecksumPolicy
+
+	// bandwidth throttles artifact downloads to their share of the node's
+	// download bandwidth
+	bandwidth *bandwidth.Manager
+
 	// devicemanager is used to mount devices as well as lookup device
 	// statistics
 	devicemanager devicemanager.Manager
@@ -171,6 +212,11 @@ type allocRunner struct {
 
 	taskHookCoordinator *taskHookCoordinator
 
+	// restartSequencer orders template driven restarts by task lifecycle.
+	// It is nil unless a template in the group has restart_order
+	// "lifecycle".
+	restartSequencer *templateRestartSequencer
+
 	shutdownDelayCtx      context.Context
 	shutdownDelayCancelFn context.CancelFunc
 
@@ -196,6 +242,7 @@ func NewAllocRunner(config *Config) (*allocRunner, error) {
 		id:                       alloc.ID,
 		alloc:                    alloc,
 		clientConfig:             config.ClientConfig,
+		getClientConfig:          config.GetClientConfig,
 		consulClient:             config.Consul,
 		consulProxiesClient:      config.ConsulProxies,
 		sidsClient:               config.ConsulSI,
@@ -215,13 +262,23 @@ func NewAllocRunner(config *Config) (*allocRunner, error) {
 		prevAllocMigrator:        config.PrevAllocMigrator,
 		dynamicRegistry:          config.DynamicRegistry,
 		csiManager:               config.CSIManager,
+		csiOpScheduler:           config.CSIOpScheduler,
+		csiCapacityBudget:        config.CSICapacityBudget,
+		csiWriteClaims:           config.CSIWriteClaims,
+		csiClaimBatch:            config.CSIClaimBatch,
 		cpusetManager:            config.CpusetManager,
+		diskIOCollector:          config.DiskIOCollector,
+		artifactChecksumPolicy:   config.ArtifactChecksumPolicy,
+		bandwidth:                config.Bandwidth,
 		devicemanager:            config.DeviceManager,
 		driverManager:            config.DriverManager,
 		serversContactedCh:       config.ServersContactedCh,
 		rpcClient:                config.RPCClient,
 	}
 
+	ar.hookShutdownCtx, ar.hookShutdownCancel = interfaces.NewShutdownContext()
+	ar.hookPrerunCtx, ar.hookPrerunCancel = context.WithCancel(ar.hookShutdownCtx)
+
 	// Create the logger based on the allocation ID
 	ar.logger = config.Logger.Named("alloc_runner").With("alloc_id", alloc.ID)
 
@@ -233,6 +290,15 @@ func NewAllocRunner(config *Config) (*allocRunner, error) {
 
 	ar.taskHookCoordinator = newTaskHookCoordinator(ar.logger, tg.Tasks)
 
+	if hasLifecycleTemplates(tg.Tasks) {
+		var stageTimeout time.Duration
+		if tc := config.ClientConfig.TemplateConfig; tc != nil && tc.RestartStageTimeout != nil {
+			stageTimeout = *tc.RestartStageTimeout
+		}
+		ar.restartSequencer = newTemplateRestartSequencer(ar.logger, tg.Tasks,
+			ar.lookupSequencedTask, stageTimeout, ar.waitCh)
+	}
+
 	shutdownDelayCtx, shutdownDelayCancel := context.WithCancel(context.Background())
 	ar.shutdownDelayCtx = shutdownDelayCtx
 	ar.shutdownDelayCancelFn = shutdownDelayCancel
@@ -254,25 +320,33 @@ func NewAllocRunner(config *Config) (*allocRunner, error) {
 func (ar *allocRunner) initTaskRunners(tasks []*structs.Task) error {
 	for _, task := range tasks {
 		trConfig := &taskrunner.Config{
-			Alloc:                ar.alloc,
-			ClientConfig:         ar.clientConfig,
-			Task:                 task,
-			TaskDir:              ar.allocDir.NewTaskDir(task.Name),
-			Logger:               ar.logger,
-			StateDB:              ar.stateDB,
-			StateUpdater:         ar,
-			DynamicRegistry:      ar.dynamicRegistry,
-			Consul:               ar.consulClient,
-			ConsulProxies:        ar.consulProxiesClient,
-			ConsulSI:             ar.sidsClient,
-			Vault:                ar.vaultClient,
-			DeviceStatsReporter:  ar.deviceStatsReporter,
-			CSIManager:           ar.csiManager,
-			DeviceManager:        ar.devicemanager,
-			DriverManager:        ar.driverManager,
-			ServersContactedCh:   ar.serversContactedCh,
-			StartConditionMetCtx: ar.taskHookCoordinator.startConditionForTask(task),
-			ShutdownDelayCtx:     ar.shutdownDelayCtx,
+			Alloc:                  ar.alloc,
+			ClientConfig:           ar.clientConfig,
+			GetClientConfig:        ar.getClientConfig,
+			Task:                   task,
+			TaskDir:                ar.allocDir.NewTaskDir(task.Name),
+			Logger:                 ar.logger,
+			StateDB:                ar.stateDB,
+			StateUpdater:           ar,
+			DynamicRegistry:        ar.dynamicRegistry,
+			Consul:                 ar.consulClient,
+			ConsulProxies:          ar.consulProxiesClient,
+			ConsulSI:               ar.sidsClient,
+			Vault:                  ar.vaultClient,
+			DeviceStatsReporter:    ar.deviceStatsReporter,
+			CSIManager:             ar.csiManager,
+			DeviceManager:          ar.devicemanager,
+			DriverManager:          ar.driverManager,
+			ServersContactedCh:     ar.serversContactedCh,
+			StartConditionMetCtx:   ar.taskHookCoordinator.startConditionForTask(task),
+			ShutdownDelayCtx:       ar.shutdownDelayCtx,
+			DiskIOCollector:        ar.diskIOCollector,
+			ArtifactChecksumPolicy: ar.artifactChecksumPolicy,
+			Bandwidth:              ar.bandwidth,
+		}
+
+		if ar.restartSequencer != nil {
+			trConfig.RestartSequencer = ar.restartSequencer.forTask(task.Name)
 		}
 
 		if ar.cpusetManager != nil {
@@ -282,7 +356,7 @@ func (ar *allocRunner) initTaskRunners(tasks []*structs.Task) error {
 		// Create, but do not Run, the task runner
 		tr, err := taskrunner.NewTaskRunner(trConfig)
 		if err != nil {
-			return fmt.Errorf("failed creating runner for task %q: %v", task.Name, err)
+			return fmt.Errorf("failed creating runner for task %q: %w", task.Name, err)
 		}
 
 		ar.tasks[task.Name] = tr
@@ -290,6 +364,16 @@ func (ar *allocRunner) initTaskRunners(tasks []*structs.Task) error {
 	return nil
 }
 
+// lookupSequencedTask returns the task runner for the named task, or nil if
+// the alloc has no such task.
+func (ar *allocRunner) lookupSequencedTask(name string) sequencedTask {
+	tr, ok := ar.tasks[name]
+	if !ok {
+		return nil
+	}
+	return tr
+}
+
 func (ar *allocRunner) WaitCh() <-chan struct{} {
 	return ar.waitCh
 }
@@ -306,6 +390,11 @@ func (ar *allocRunner) Run() {
 	// Start the alloc update handler
 	go ar.handleAllocUpdates()
 
+	// Start ordering template driven restarts
+	if ar.restartSequencer != nil {
+		go ar.restartSequencer.run()
+	}
+
 	// If task update chan has been closed, that means we've been shutdown.
 	select {
 	case <-ar.taskStateUpdateHandlerCh:
@@ -319,6 +408,16 @@ func (ar *allocRunner) Run() {
 	// Run the prestart hooks if non-terminal
 	if ar.shouldRun() {
 		if err := ar.prerun(); err != nil {
+			if ar.isShuttingDown() {
+				// The alloc is restored and its prerun hooks run again
+				// when the client restarts, so leave its tasks as they are
+				ar.logger.Debug("prerun interrupted by shutdown", "error", err)
+				for _, tr := range ar.tasks {
+					tr.ShutdownBeforeRun()
+				}
+				return
+			}
+
 			ar.logger.Error("prerun failed", "error", err)
 
 			for _, tr := range ar.tasks {
@@ -337,6 +436,14 @@ POST:
 		return
 	}
 
+	// A drained allocation releases what it holds in order before the
+	// postrun hooks run
+	if ar.Alloc().DesiredTransition.ShouldMigrate() {
+		if err := ar.drainShutdownHooks(); err != nil {
+			ar.logger.Error("drain shutdown hooks failed", "error", err)
+		}
+	}
+
 	// Run the postrun hooks
 	if err := ar.postrun(); err != nil {
 		ar.logger.Error("postrun failed", "error", err)
@@ -980,6 +1087,9 @@ func (ar *allocRunner) Destroy() {
 
 	ar.destroyLaunched = true
 
+	// Interrupt any prerun hooks, as the alloc won't run
+	ar.hookPrerunCancel()
+
 	// Synchronize calls to shutdown/destroy
 	if ar.shutdownLaunched {
 		go func() {
@@ -1041,6 +1151,10 @@ func (ar *allocRunner) Shutdown() {
 	ar.destroyedLock.Lock()
 	defer ar.destroyedLock.Unlock()
 
+	// Interrupt any running hooks so they don't delay the client's shutdown,
+	// even if the alloc is being destroyed
+	ar.hookShutdownCancel()
+
 	// Destroy is a superset of Shutdown so there's nothing to do if this
 	// has already been destroyed.
 	if ar.destroyed {
@@ -1094,6 +1208,37 @@ func (ar *allocRunner) IsMigrating() bool {
 	return ar.prevAllocMigrator.IsMigrating()
 }
 
+// CSIMounts returns where each of the allocation's CSI volumes is mounted on
+// the host, by the alias of its volume request.
+func (ar *allocRunner) CSIMounts() map[string]*cstructs.AllocCSIMount {
+	ar.hookStateMu.RLock()
+	res := ar.hookState
+	ar.hookStateMu.RUnlock()
+	if res == nil {
+		return nil
+	}
+
+	mounts := res.GetCSIMounts()
+	sources := res.GetCSIMountSources()
+	out := make(map[string]*cstructs.AllocCSIMount, len(mounts))
+	for alias, mountInfo := range mounts {
+		if mountInfo == nil {
+			continue
+		}
+		mount := &cstructs.AllocCSIMount{
+			HostPath: mountInfo.Source,
+			IsDevice: mountInfo.IsDevice,
+		}
+		if source := sources[alias]; source != nil {
+			mount.VolumeID = source.VolumeID
+			mount.PluginID = source.PluginID
+			mount.AccessMode = source.AccessMode
+		}
+		out[alias] = mount
+	}
+	return out
+}
+
 func (ar *allocRunner) StatsReporter() interfaces.AllocStatsReporter {
 	return ar
 }
@@ -1233,6 +1378,65 @@ func (ar *allocRunner) Signal(taskName, signal string) error {
 	return err.ErrorOrNil()
 }
 
+// Pause freezes the processes of a task, or of every running task of the
+// allocation if taskName is empty. If any task fails to pause, the tasks that
+// were paused are resumed. pausedBy identifies who paused the tasks.
+func (ar *allocRunner) Pause(taskName, pausedBy string) error {
+	if taskName != "" {
+		tr, ok := ar.tasks[taskName]
+		if !ok {
+			return fmt.Errorf("Task not found")
+		}
+
+		return tr.Pause(pausedBy)
+	}
+
+	var paused []*taskrunner.TaskRunner
+	for tn, tr := range ar.tasks {
+		err := tr.Pause(pausedBy)
+		if err == taskrunner.ErrTaskNotRunning {
+			continue
+		}
+		if err != nil {
+			for _, p := range paused {
+				if rerr := p.Resume(pausedBy); rerr != nil {
+					ar.logger.Error("failed to resume task", "task", p.Task().Name, "error", rerr)
+				}
+			}
+			return fmt.Errorf("Failed to pause task: %s, err: %v", tn, err)
+		}
+		paused = append(paused, tr)
+	}
+
+	return nil
+}
+
+// Resume thaws the processes of a paused task, or of every paused task of the
+// allocation if taskName is empty. resumedBy identifies who resumed the tasks.
+func (ar *allocRunner) Resume(taskName, resumedBy string) error {
+	if taskName != "" {
+		tr, ok := ar.tasks[taskName]
+		if !ok {
+			return fmt.Errorf("Task not found")
+		}
+
+		return tr.Resume(resumedBy)
+	}
+
+	var err *multierror.Error
+
+	for tn, tr := range ar.tasks {
+		if !tr.IsPaused() {
+			continue
+		}
+		if rerr := tr.Resume(resumedBy); rerr != nil {
+			err = multierror.Append(err, fmt.Errorf("Failed to resume task: %s, err: %v", tn, rerr))
+		}
+	}
+
+	return err.ErrorOrNil()
+}
+
 func (ar *allocRunner) GetTaskExecHandler(taskName string) drivermanager.TaskExecHandler {
 	tr, ok := ar.tasks[taskName]
 	if !ok {
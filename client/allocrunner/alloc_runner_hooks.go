@@ -41,6 +41,19 @@ func (a *allocHookResourceSetter) SetAllocHookResources(res *cstructs.AllocHookR
 	}
 }
 
+// allocTaskEventEmitter is a shim to allow alloc runner hooks to emit an
+// event on every task in the allocation
+type allocTaskEventEmitter struct {
+	ar *allocRunner
+}
+
+// EmitEvent emits a copy of the event on each of the alloc's tasks.
+func (a *allocTaskEventEmitter) EmitEvent(event *structs.TaskEvent) {
+	for _, tr := range a.ar.tasks {
+		tr.EmitEvent(event.Copy())
+	}
+}
+
 // allocHealthSetter is a shim to allow the alloc health watcher hook to set
 // and clear the alloc health without full access to the alloc runner state
 type allocHealthSetter struct {
@@ -121,6 +134,9 @@ func (ar *allocRunner) initRunnerHooks(config *clientconfig.Config) error {
 	hrs := &allocHookResourceSetter{ar: ar}
 	hrs.SetAllocHookResources(&cstructs.AllocHookResources{})
 
+	// create task event emitting shim
+	tes := &allocTaskEventEmitter{ar: ar}
+
 	// build the network manager
 	nm, err := newNetworkManager(ar.Alloc(), ar.driverManager)
 	if err != nil {
@@ -163,7 +179,23 @@ func (ar *allocRunner) initRunnerHooks(config *clientconfig.Config) error {
 		}),
 		newConsulGRPCSocketHook(hookLogger, alloc, ar.allocDir, config.ConsulConfig),
 		newConsulHTTPSocketHook(hookLogger, alloc, ar.allocDir, config.ConsulConfig),
-		newCSIHook(alloc, hookLogger, ar.csiManager, ar.rpcClient, ar, hrs, ar.clientConfig.Node.SecretID),
+		newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               hookLogger,
+			csiManager:           ar.csiManager,
+			rpcClient:            ar.rpcClient,
+			taskCapabilityGetter: ar,
+			hookResources:        hrs,
+			eventer:              tes,
+			opScheduler:          ar.csiOpScheduler,
+			capacityBudget:       ar.csiCapacityBudget,
+			writeClaims:          ar.csiWriteClaims,
+			claimBatch:           ar.csiClaimBatch,
+			nodeSecret:           ar.clientConfig.Node.SecretID,
+			vaultClient:          ar.vaultClient,
+			stateDB:              ar.stateDB,
+			clientConfig:         config,
+		}),
 	}
 
 	return nil
@@ -181,19 +213,24 @@ func (ar *allocRunner) prerun() error {
 	}
 
 	for _, hook := range ar.runnerHooks {
-		pre, ok := hook.(interfaces.RunnerPrerunHook)
-		if !ok {
+		var prerun func() error
+		switch pre := hook.(type) {
+		case interfaces.RunnerPrerunHook:
+			prerun = func() error { return pre.Prerun(ar.hookPrerunCtx) }
+		case interfaces.LegacyRunnerPrerunHook:
+			prerun = pre.Prerun
+		default:
 			continue
 		}
 
-		name := pre.Name()
+		name := hook.Name()
 		var start time.Time
 		if ar.logger.IsTrace() {
 			start = time.Now()
 			ar.logger.Trace("running pre-run hook", "name", name, "start", start)
 		}
 
-		if err := pre.Prerun(); err != nil {
+		if err := prerun(); err != nil {
 			return fmt.Errorf("pre-run hook %q failed: %v", name, err)
 		}
 
@@ -220,6 +257,7 @@ func (ar *allocRunner) update(update *structs.Allocation) error {
 
 	req := &interfaces.RunnerUpdateRequest{
 		Alloc: update,
+		Ctx:   ar.hookPrerunCtx,
 	}
 
 	var merr multierror.Error
@@ -261,19 +299,24 @@ func (ar *allocRunner) postrun() error {
 	}
 
 	for _, hook := range ar.runnerHooks {
-		post, ok := hook.(interfaces.RunnerPostrunHook)
-		if !ok {
+		var postrun func() error
+		switch post := hook.(type) {
+		case interfaces.RunnerPostrunHook:
+			postrun = func() error { return post.Postrun(ar.hookShutdownCtx) }
+		case interfaces.LegacyRunnerPostrunHook:
+			postrun = post.Postrun
+		default:
 			continue
 		}
 
-		name := post.Name()
+		name := hook.Name()
 		var start time.Time
 		if ar.logger.IsTrace() {
 			start = time.Now()
 			ar.logger.Trace("running post-run hook", "name", name, "start", start)
 		}
 
-		if err := post.Postrun(); err != nil {
+		if err := postrun(); err != nil {
 			return fmt.Errorf("hook %q failed: %v", name, err)
 		}
 
@@ -325,6 +368,37 @@ func (ar *allocRunner) destroy() error {
 	return merr.ErrorOrNil()
 }
 
+// drainShutdownHooks runs the runner's drain shutdown hooks, once the tasks
+// of an allocation stopped by a node drain have stopped. All hooks are run
+// and errors are returned as a multierror.
+func (ar *allocRunner) drainShutdownHooks() error {
+	var merr multierror.Error
+	for _, hook := range ar.runnerHooks {
+		h, ok := hook.(interfaces.RunnerDrainShutdownHook)
+		if !ok {
+			continue
+		}
+
+		name := h.Name()
+		var start time.Time
+		if ar.logger.IsTrace() {
+			start = time.Now()
+			ar.logger.Trace("running drain shutdown hook", "name", name, "start", start)
+		}
+
+		if err := h.Shutdown(ar.hookShutdownCtx); err != nil {
+			merr.Errors = append(merr.Errors, fmt.Errorf("drain shutdown hook %q failed: %v", name, err))
+		}
+
+		if ar.logger.IsTrace() {
+			end := time.Now()
+			ar.logger.Trace("finished drain shutdown hook", "name", name, "end", end, "duration", end.Sub(start))
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
+
 func (ar *allocRunner) preKillHooks() {
 	for _, hook := range ar.runnerHooks {
 		pre, ok := hook.(interfaces.RunnerPreKillHook)
@@ -1,19 +1,23 @@
 package allocrunner
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/nomad/client/allochealth"
+	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
 	"github.com/hashicorp/nomad/client/allocwatcher"
 	cconsul "github.com/hashicorp/nomad/client/consul"
 	"github.com/hashicorp/nomad/client/state"
 	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/helper/uuid"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -1568,3 +1572,224 @@ func TestAllocRunner_PersistState_Destroyed(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, ts)
 }
+
+// blockingPrerunHook blocks in Prerun until its context is cancelled, and
+// records whether the client was shutting down.
+type blockingPrerunHook struct {
+	started      chan struct{}
+	shuttingDown chan bool
+}
+
+func newBlockingPrerunHook() *blockingPrerunHook {
+	return &blockingPrerunHook{
+		started:      make(chan struct{}),
+		shuttingDown: make(chan bool, 1),
+	}
+}
+
+func (*blockingPrerunHook) Name() string { return "blocking_prerun" }
+
+func (h *blockingPrerunHook) Prerun(ctx context.Context) error {
+	close(h.started)
+	<-ctx.Done()
+	h.shuttingDown <- interfaces.ShuttingDown(ctx)
+	return ctx.Err()
+}
+
+// TestAllocRunner_Shutdown_InterruptsPrerun asserts that shutting down the
+// alloc runner interrupts a blocked prerun hook, without failing the alloc's
+// tasks so that they're restored.
+func TestAllocRunner_Shutdown_InterruptsPrerun(t *testing.T) {
+	t.Parallel()
+
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Driver = "mock_driver"
+
+	conf, cleanup := testAllocRunnerConfig(t, alloc)
+	defer cleanup()
+
+	ar, err := NewAllocRunner(conf)
+	require.NoError(t, err)
+
+	hook := newBlockingPrerunHook()
+	ar.runnerHooks = append(ar.runnerHooks, hook)
+
+	go ar.Run()
+	<-hook.started
+
+	ar.Shutdown()
+	select {
+	case <-ar.ShutdownCh():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected shutdown to interrupt the prerun hook")
+	}
+	require.True(t, <-hook.shuttingDown)
+
+	state := ar.AllocState().TaskStates[task.Name]
+	require.NotEqual(t, structs.TaskStateDead, state.State)
+	require.False(t, state.Failed)
+}
+
+// TestAllocRunner_Destroy_InterruptsPrerun asserts that destroying the alloc
+// runner interrupts a blocked prerun hook.
+func TestAllocRunner_Destroy_InterruptsPrerun(t *testing.T) {
+	t.Parallel()
+
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Tasks[0].Driver = "mock_driver"
+
+	conf, cleanup := testAllocRunnerConfig(t, alloc)
+	defer cleanup()
+
+	ar, err := NewAllocRunner(conf)
+	require.NoError(t, err)
+
+	hook := newBlockingPrerunHook()
+	ar.runnerHooks = append(ar.runnerHooks, hook)
+
+	go ar.Run()
+	<-hook.started
+
+	ar.Destroy()
+	select {
+	case <-ar.DestroyCh():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected destroy to interrupt the prerun hook")
+	}
+	require.False(t, <-hook.shuttingDown)
+}
+
+// legacyHook implements the prerun and postrun hooks that aren't passed a
+// context.
+type legacyHook struct {
+	prerun  bool
+	postrun bool
+}
+
+func (*legacyHook) Name() string { return "legacy" }
+
+func (h *legacyHook) Prerun() error {
+	h.prerun = true
+	return nil
+}
+
+func (h *legacyHook) Postrun() error {
+	h.postrun = true
+	return nil
+}
+
+// TestAllocRunner_LegacyHooks asserts that hooks that aren't passed a context
+// are still run.
+func TestAllocRunner_LegacyHooks(t *testing.T) {
+	t.Parallel()
+
+	alloc := mock.BatchAlloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Driver = "mock_driver"
+	task.Config = map[string]interface{}{
+		"run_for": "10ms",
+	}
+
+	conf, cleanup := testAllocRunnerConfig(t, alloc)
+	defer cleanup()
+
+	ar, err := NewAllocRunner(conf)
+	require.NoError(t, err)
+
+	hook := &legacyHook{}
+	ar.runnerHooks = append(ar.runnerHooks, hook)
+
+	go ar.Run()
+	defer destroy(ar)
+
+	select {
+	case <-ar.WaitCh():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for alloc to complete")
+	}
+	require.True(t, hook.prerun)
+	require.True(t, hook.postrun)
+}
+
+// drainHook records the drain shutdown and postrun hooks run, in order.
+type drainHook struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (*drainHook) Name() string { return "drain" }
+
+func (h *drainHook) Shutdown(context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, "shutdown")
+	return nil
+}
+
+func (h *drainHook) Postrun(context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, "postrun")
+	return nil
+}
+
+// TestAllocRunner_DrainShutdownHooks asserts that the drain shutdown hooks are
+// run before the postrun hooks only when the allocation is migrated off its
+// node.
+func TestAllocRunner_DrainShutdownHooks(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		migrate  bool
+		expected []string
+	}{
+		{
+			name:     "migrated",
+			migrate:  true,
+			expected: []string{"shutdown", "postrun"},
+		},
+		{
+			name:     "not migrated",
+			expected: []string{"postrun"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			alloc := mock.BatchAlloc()
+			alloc.DesiredTransition.Migrate = helper.BoolToPtr(tc.migrate)
+			task := alloc.Job.TaskGroups[0].Tasks[0]
+			task.Driver = "mock_driver"
+			task.Config = map[string]interface{}{
+				"run_for": "10ms",
+			}
+
+			conf, cleanup := testAllocRunnerConfig(t, alloc)
+			defer cleanup()
+
+			ar, err := NewAllocRunner(conf)
+			require.NoError(t, err)
+
+			hook := &drainHook{}
+			ar.runnerHooks = append(ar.runnerHooks, hook)
+
+			go ar.Run()
+			defer destroy(ar)
+
+			select {
+			case <-ar.WaitCh():
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for alloc to complete")
+			}
+
+			hook.mu.Lock()
+			defer hook.mu.Unlock()
+			require.Equal(t, tc.expected, hook.calls)
+		})
+	}
+}
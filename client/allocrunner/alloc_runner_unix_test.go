@@ -4,6 +4,7 @@
 package allocrunner
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -296,6 +297,6 @@ type allocFailingPrestartHook struct{}
 
 func (*allocFailingPrestartHook) Name() string { return "failing_prestart" }
 
-func (*allocFailingPrestartHook) Prerun() error {
+func (*allocFailingPrestartHook) Prerun(context.Context) error {
 	return fmt.Errorf("failing prestart hooks")
 }
@@ -1,6 +1,8 @@
 package allocrunner
 
 import (
+	"context"
+
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad/client/allocdir"
 )
@@ -24,7 +26,7 @@ func (h *allocDirHook) Name() string {
 	return "alloc_dir"
 }
 
-func (h *allocDirHook) Prerun() error {
+func (h *allocDirHook) Prerun(context.Context) error {
 	return h.allocDir.Build()
 }
 
@@ -1,6 +1,8 @@
 package allocrunner
 
 import (
+	"context"
+
 	"github.com/hashicorp/nomad/client/lib/cgutil"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -21,12 +23,12 @@ func (c *cgroupHook) Name() string {
 	return "cgroup"
 }
 
-func (c *cgroupHook) Prerun() error {
+func (c *cgroupHook) Prerun(context.Context) error {
 	c.cpusetManager.AddAlloc(c.alloc)
 	return nil
 }
 
-func (c *cgroupHook) Postrun() error {
+func (c *cgroupHook) Postrun(context.Context) error {
 	c.cpusetManager.RemoveAlloc(c.alloc.ID)
 	return nil
 }
@@ -2,12 +2,14 @@ package allocrunner
 
 import (
 	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/getter"
 	"github.com/hashicorp/nomad/client/allocwatcher"
 	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/consul"
 	"github.com/hashicorp/nomad/client/devicemanager"
 	"github.com/hashicorp/nomad/client/dynamicplugins"
 	"github.com/hashicorp/nomad/client/interfaces"
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	"github.com/hashicorp/nomad/client/lib/cgutil"
 	"github.com/hashicorp/nomad/client/pluginmanager/csimanager"
 	"github.com/hashicorp/nomad/client/pluginmanager/drivermanager"
@@ -24,6 +26,11 @@ type Config struct {
 	// ClientConfig is the clients configuration.
 	ClientConfig *clientconfig.Config
 
+	// GetClientConfig returns the client's current configuration, which is
+	// replaced when the client reloads its config. It may be nil, in which
+	// case ClientConfig is used.
+	GetClientConfig func() *clientconfig.Config
+
 	// Alloc captures the allocation that should be run.
 	Alloc *structs.Allocation
 
@@ -63,6 +70,22 @@ type Config struct {
 	// runner to manage their mounting
 	CSIManager csimanager.Manager
 
+	// CSIOpScheduler bounds the CSI volume claims and mounts running
+	// concurrently on the node
+	CSIOpScheduler *csimanager.OpScheduler
+
+	// CSICapacityBudget bounds the total capacity of the CSI volumes mounted
+	// on the node
+	CSICapacityBudget *csimanager.CapacityBudget
+
+	// CSIWriteClaims tracks the write claims on CSI volumes held by the
+	// allocations on the node
+	CSIWriteClaims *csimanager.WriteClaimTracker
+
+	// CSIClaimBatch records whether the servers support batched CSI volume
+	// claims, for all the allocations on the node
+	CSIClaimBatch *csimanager.ClaimBatchSupport
+
 	// DeviceManager is used to mount devices as well as lookup device
 	// statistics
 	DeviceManager devicemanager.Manager
@@ -73,6 +96,18 @@ type Config struct {
 	// CpusetManager configures the cpuset cgroup if supported by the platform
 	CpusetManager cgutil.CpusetManager
 
+	// DiskIOCollector reads the block device I/O stats of tasks if
+	// supported by the platform
+	DiskIOCollector *cgutil.DiskIOCollector
+
+	// ArtifactChecksumPolicy decides whether task artifacts must specify a
+	// checksum
+	ArtifactChecksumPolicy *getter.ChecksumPolicy
+
+	// Bandwidth throttles artifact downloads to their share of the node's
+	// download bandwidth. It may be nil.
+	Bandwidth *bandwidth.Manager
+
 	// ServersContactedCh is closed when the first GetClientAllocs call to
 	// servers succeeds and allocs are synced.
 	ServersContactedCh chan struct{}
@@ -76,7 +76,7 @@ func (h *consulGRPCSocketHook) shouldRun() bool {
 	return false
 }
 
-func (h *consulGRPCSocketHook) Prerun() error {
+func (h *consulGRPCSocketHook) Prerun(context.Context) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -102,7 +102,7 @@ func (h *consulGRPCSocketHook) Update(req *interfaces.RunnerUpdateRequest) error
 	return h.proxy.run(h.alloc)
 }
 
-func (h *consulGRPCSocketHook) Postrun() error {
+func (h *consulGRPCSocketHook) Postrun(context.Context) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -44,7 +44,7 @@ func TestConsulGRPCSocketHook_PrerunPostrun_Ok(t *testing.T) {
 
 	// Start the unix socket proxy
 	h := newConsulGRPCSocketHook(logger, alloc, allocDir, consulConfig)
-	require.NoError(t, h.Prerun())
+	require.NoError(t, h.Prerun(context.Background()))
 
 	gRPCSock := filepath.Join(allocDir.AllocDir, allocdir.AllocGRPCSocket)
 	envoyConn, err := net.Dial("unix", gRPCSock)
@@ -82,7 +82,7 @@ func TestConsulGRPCSocketHook_PrerunPostrun_Ok(t *testing.T) {
 	require.Equal(t, input, output)
 
 	// Stop the unix socket proxy
-	require.NoError(t, h.Postrun())
+	require.NoError(t, h.Postrun(context.Background()))
 
 	// Consul reads should error
 	n, err := consulConn.Read(output)
@@ -118,27 +118,27 @@ func TestConsulGRPCSocketHook_Prerun_Error(t *testing.T) {
 		// An alloc without a Connect proxy sidecar should not return
 		// an error.
 		h := newConsulGRPCSocketHook(logger, alloc, allocDir, consulConfig)
-		require.NoError(t, h.Prerun())
+		require.NoError(t, h.Prerun(context.Background()))
 
 		// Postrun should be a noop
-		require.NoError(t, h.Postrun())
+		require.NoError(t, h.Postrun(context.Background()))
 	}
 
 	{
 		// An alloc *with* a Connect proxy sidecar *should* return an error
 		// when Consul is not configured.
 		h := newConsulGRPCSocketHook(logger, connectAlloc, allocDir, consulConfig)
-		require.EqualError(t, h.Prerun(), "consul address must be set on nomad client")
+		require.EqualError(t, h.Prerun(context.Background()), "consul address must be set on nomad client")
 
 		// Postrun should be a noop
-		require.NoError(t, h.Postrun())
+		require.NoError(t, h.Postrun(context.Background()))
 	}
 
 	{
 		// Updating an alloc without a sidecar to have a sidecar should
 		// error when the sidecar is added.
 		h := newConsulGRPCSocketHook(logger, alloc, allocDir, consulConfig)
-		require.NoError(t, h.Prerun())
+		require.NoError(t, h.Prerun(context.Background()))
 
 		req := &interfaces.RunnerUpdateRequest{
 			Alloc: connectAlloc,
@@ -146,7 +146,7 @@ func TestConsulGRPCSocketHook_Prerun_Error(t *testing.T) {
 		require.EqualError(t, h.Update(req), "consul address must be set on nomad client")
 
 		// Postrun should be a noop
-		require.NoError(t, h.Postrun())
+		require.NoError(t, h.Postrun(context.Background()))
 	}
 }
 
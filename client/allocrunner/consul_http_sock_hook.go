@@ -69,7 +69,7 @@ func (h *consulHTTPSockHook) shouldRun() bool {
 	return false
 }
 
-func (h *consulHTTPSockHook) Prerun() error {
+func (h *consulHTTPSockHook) Prerun(context.Context) error {
 	h.lock.Lock()
 	defer h.lock.Unlock()
 
@@ -93,7 +93,7 @@ func (h *consulHTTPSockHook) Update(req *interfaces.RunnerUpdateRequest) error {
 	return h.proxy.run(h.alloc)
 }
 
-func (h *consulHTTPSockHook) Postrun() error {
+func (h *consulHTTPSockHook) Postrun(context.Context) error {
 	h.lock.Lock()
 	defer h.lock.Unlock()
 
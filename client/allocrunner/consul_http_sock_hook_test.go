@@ -2,6 +2,7 @@ package allocrunner
 
 import (
 	"bytes"
+	"context"
 	"net"
 	"path/filepath"
 	"testing"
@@ -33,7 +34,7 @@ func TestConsulSocketHook_PrerunPostrun_Ok(t *testing.T) {
 
 	// start unix socket proxy
 	h := newConsulHTTPSocketHook(logger, alloc, allocDir, consulConfig)
-	require.NoError(t, h.Prerun())
+	require.NoError(t, h.Prerun(context.Background()))
 
 	httpSocket := filepath.Join(allocDir.AllocDir, allocdir.AllocHTTPSocket)
 	taskCon, err := net.Dial("unix", httpSocket)
@@ -72,7 +73,7 @@ func TestConsulSocketHook_PrerunPostrun_Ok(t *testing.T) {
 	require.Equal(t, input, output)
 
 	// stop the unix socket proxy
-	require.NoError(t, h.Postrun())
+	require.NoError(t, h.Postrun(context.Background()))
 
 	// consul reads should now error
 	n, err := consulConn.Read(output)
@@ -104,19 +105,19 @@ func TestConsulHTTPSocketHook_Prerun_Error(t *testing.T) {
 	{
 		// an alloc without a connect native task should not return an error
 		h := newConsulHTTPSocketHook(logger, alloc, allocDir, consulConfig)
-		require.NoError(t, h.Prerun())
+		require.NoError(t, h.Prerun(context.Background()))
 
 		// postrun should be a noop
-		require.NoError(t, h.Postrun())
+		require.NoError(t, h.Postrun(context.Background()))
 	}
 
 	{
 		// an alloc with a native task should return an error when consul is not
 		// configured
 		h := newConsulHTTPSocketHook(logger, connectNativeAlloc, allocDir, consulConfig)
-		require.EqualError(t, h.Prerun(), "consul address must be set on nomad client")
+		require.EqualError(t, h.Prerun(context.Background()), "consul address must be set on nomad client")
 
 		// Postrun should be a noop
-		require.NoError(t, h.Postrun())
+		require.NoError(t, h.Postrun(context.Background()))
 	}
 }
@@ -2,13 +2,32 @@ package allocrunner
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/lib"
 	hclog "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	ti "github.com/hashicorp/nomad/client/allocrunner/taskrunner/interfaces"
+	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/pluginmanager/csimanager"
+	cstate "github.com/hashicorp/nomad/client/state"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/client/vaultclient"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/plugins/drivers"
+	"golang.org/x/sync/errgroup"
 )
 
 // csiHook will wait for remote csi volumes to be attached to the host before
@@ -22,9 +41,353 @@ type csiHook struct {
 	rpcClient            RPCer
 	taskCapabilityGetter taskCapabilityGetter
 	updater              hookResourceSetter
+	eventer              ti.EventEmitter
 	nodeSecret           string
 
+	// vaultClient reads the volume secrets referencing Vault, with a token
+	// derived for a task of the allocation
+	vaultClient vaultclient.VaultClient
+
+	// perAllocCanaries allows canary allocations to claim per_alloc
+	// volumes. Canaries share the name index of the allocation they will
+	// replace, so they claim that allocation's volume.
+	perAllocCanaries bool
+
+	// mountTimeout bounds each call to the node plugin to mount a volume
+	mountTimeout time.Duration
+
+	// pluginRegistrationWait is how long to wait for a node plugin that
+	// hasn't registered on this node yet, such as right after the client
+	// starts, when claiming and mounting its volumes. Zero fails the claim or
+	// mount immediately.
+	pluginRegistrationWait time.Duration
+
+	// claimTimeout bounds each volume claim, including its retries. The task
+	// group's csi_claim_timeout takes precedence over the mount timeout.
+	claimTimeout time.Duration
+
+	// claimRetry configures the retries of claims failing with transient
+	// errors. Its fields are all set.
+	claimRetry *clientconfig.RetryConfig
+
+	// unpublishRetry configures the retries of the unpublish requests
+	// releasing claims when the allocation stops. Its fields are all set.
+	unpublishRetry *clientconfig.RetryConfig
+
+	// maxVolumes is the maximum number of CSI volumes the allocation may
+	// request
+	maxVolumes int
+
+	// stateDB persists the volumes claimed and mounted by Prerun, so that a
+	// restored allocation doesn't claim and mount them again
+	stateDB cstate.StateDB
+
+	// opScheduler bounds the volume claims and mounts running concurrently
+	// on the node, ordering waiting operations by job priority
+	opScheduler *csimanager.OpScheduler
+
+	// capacityBudget bounds the total capacity of the volumes mounted on
+	// the node, and is shared with the hooks of other allocations
+	capacityBudget *csimanager.CapacityBudget
+
+	// writeClaims rejects write claims on single-writer volumes already
+	// claimed for writing by other allocations on the node, and is shared
+	// with their hooks
+	writeClaims *csimanager.WriteClaimTracker
+
+	// claimLabelEnv maps the names of labels attached to volume claims to
+	// the environment variables their values are read from
+	claimLabelEnv map[string]string
+
+	// idempotencyKeys records each volume's claim before it's sent and
+	// whether it was released, so that a restarted client neither releases a
+	// claim twice nor leaves an interrupted one behind. Servers don't
+	// deduplicate the requests, so only the client's state makes them
+	// idempotent.
+	idempotencyKeys bool
+
+	// claimBatch records whether the servers support batched volume claims,
+	// and is shared with the hooks of other allocations
+	claimBatch *csimanager.ClaimBatchSupport
+
+	// volumeHealthCheck fails the mount of a volume whose claim reported it
+	// as unhealthy, without calling the node plugin
+	volumeHealthCheck bool
+
+	// mountMetadata writes a file describing the allocation next to each of
+	// its volume mounts, for external tools
+	mountMetadata bool
+
+	// claimTopology fails the mount of a claimed volume that isn't
+	// accessible from the topology its node plugin fingerprinted
+	claimTopology bool
+
+	// volumeRequests are the claimed and mounted volumes by alias
 	volumeRequests map[string]*volumeAndRequest
+
+	// mounted is set once Prerun succeeds, so that Update only claims and
+	// mounts the volumes added to an allocation whose volumes are mounted
+	mounted bool
+
+	// claims counts the volumes claimed by the hook, to number their claims
+	claims int
+
+	// stopped is set once Shutdown or Postrun starts releasing the volumes,
+	// after which updates no longer claim volumes
+	stopped bool
+
+	// lock is held by Prerun, Postrun, Shutdown and while applying an
+	// update, as updates are applied concurrently with the others
+	lock sync.Mutex
+
+	// pendingUpdate is the latest update not applied yet, and updating is
+	// set while a goroutine applies the updates. Updates are applied apart
+	// from the alloc runner's update goroutine, so that later updates, such
+	// as the one stopping the allocation, don't wait for the claims and
+	// mounts of Prerun or of an earlier update. cancelUpdate cancels the
+	// claims and mounts of the update being applied.
+	pendingUpdate  *interfaces.RunnerUpdateRequest
+	updating       bool
+	updatesStopped bool
+	cancelUpdate   context.CancelFunc
+	updateLock     sync.Mutex
+}
+
+const (
+	// csiPluginWaitBackoffBaseline is the baseline time for exponential
+	// backoff while waiting for an unavailable node plugin.
+	csiPluginWaitBackoffBaseline = 100 * time.Millisecond
+
+	// csiPluginWaitBackoffLimit is the limit of the exponential backoff
+	// while waiting for an unavailable node plugin.
+	csiPluginWaitBackoffLimit = 5 * time.Second
+
+	// csiMaxParallelMounts is the maximum number of an allocation's volumes
+	// mounted concurrently.
+	csiMaxParallelMounts = 4
+
+	// csiVaultSecretPrefix prefixes the volume secrets whose value is a
+	// reference to a Vault secret, in the form "vault:<path>#<key>".
+	csiVaultSecretPrefix = "vault:"
+)
+
+func init() {
+	clientconfig.RegisterOptionKeys("csi.per_alloc_canaries")
+}
+
+// csiPerAllocCanaryError is returned when a canary allocation requests a
+// per_alloc volume and the client has not been configured to map canaries
+// onto the volume of the allocation they will replace.
+type csiPerAllocCanaryError struct {
+	alias  string
+	source string
+}
+
+func (e *csiPerAllocCanaryError) Error() string {
+	return fmt.Sprintf("canary allocation cannot claim per_alloc volume %q (source %q)",
+		e.alias, e.source)
+}
+
+// csiVolumeUnhealthyError is returned when the health check of a claimed
+// volume fails before it's mounted.
+type csiVolumeUnhealthyError struct {
+	alias  string
+	source string
+	reason string
+}
+
+func (e *csiVolumeUnhealthyError) Error() string {
+	return fmt.Sprintf("volume %q (source %q) is unhealthy: %s",
+		e.alias, e.source, e.reason)
+}
+
+// checkVolumeHealth returns a csiVolumeUnhealthyError if the volume returned
+// by its claim is reported as unhealthy. The health is that of the volume's
+// plugins, which the server denormalizes onto the volume, so the check
+// doesn't need a request of its own.
+func checkVolumeHealth(alias string, vol *structs.CSIVolume) error {
+	if vol.Schedulable {
+		return nil
+	}
+
+	var reason string
+	switch {
+	case vol.NodesHealthy == 0:
+		reason = fmt.Sprintf("plugin %q has no healthy nodes", vol.PluginID)
+	case vol.ControllerRequired && vol.ControllersHealthy == 0:
+		reason = fmt.Sprintf("plugin %q has no healthy controllers", vol.PluginID)
+	default:
+		reason = "volume is not schedulable"
+	}
+	return &csiVolumeUnhealthyError{alias: alias, source: vol.ID, reason: reason}
+}
+
+// csiTopologyError is returned when a claimed volume isn't accessible from
+// the topology the node's plugin fingerprinted.
+type csiTopologyError struct {
+	alias    string
+	source   string
+	pluginID string
+	topology *structs.CSITopology
+}
+
+func (e *csiTopologyError) Error() string {
+	return fmt.Sprintf("volume %q (source %q) is not accessible from topology %v of plugin %q on this node",
+		e.alias, e.source, e.topology.Segments, e.pluginID)
+}
+
+// checkVolumeTopology returns a csiTopologyError if the volume returned by its
+// claim isn't accessible from the node's topology: every segment of one of
+// the volume's topologies must match the node's segment of the same name.
+// Volumes without topologies, and plugins that didn't fingerprint one for the
+// node, aren't checked.
+func checkVolumeTopology(alias string, vol *structs.CSIVolume, node *structs.CSITopology) error {
+	if vol.IsAccessibleFrom(node) {
+		return nil
+	}
+	return &csiTopologyError{alias: alias, source: vol.ID, pluginID: vol.PluginID, topology: node}
+}
+
+// csiModeError is returned when a volume request's access mode, attachment
+// mode and options can't be satisfied together.
+type csiModeError struct {
+	alias  string
+	source string
+	reason string
+}
+
+func (e *csiModeError) Error() string {
+	return fmt.Sprintf("volume %q (source %q) has an invalid mode: %s",
+		e.alias, e.source, e.reason)
+}
+
+// csiModeChecks enumerates the invalid combinations of a volume request's
+// access mode, attachment mode and options, which are checked before claiming
+// the volume. Each check returns why the request is invalid, or an empty
+// string. Conflicts between the write claims of
+// several allocations depend on where they are placed, so they are rejected
+// when claiming rather than here.
+var csiModeChecks = []func(req *structs.VolumeRequest) string{
+	// The access mode must be known to the plugin
+	func(req *structs.VolumeRequest) string {
+		if _, ok := csiAccessModeScopes[req.AccessMode]; !ok {
+			return fmt.Sprintf("unknown access mode %q", req.AccessMode)
+		}
+		return ""
+	},
+
+	// The attachment mode must be known to the plugin
+	func(req *structs.VolumeRequest) string {
+		switch req.AttachmentMode {
+		case structs.CSIVolumeAttachmentModeBlockDevice, structs.CSIVolumeAttachmentModeFilesystem:
+			return ""
+		}
+		return fmt.Sprintf("unknown attachment mode %q", req.AttachmentMode)
+	},
+
+	// Reader-only access modes can't be mounted for writing
+	func(req *structs.VolumeRequest) string {
+		switch req.AccessMode {
+		case structs.CSIVolumeAccessModeSingleNodeReader, structs.CSIVolumeAccessModeMultiNodeReader:
+			if !req.ReadOnly {
+				return fmt.Sprintf("access mode %q requires the volume to be read_only", req.AccessMode)
+			}
+		}
+		return ""
+	},
+
+	// Block devices aren't mounted as a filesystem, so they can't have a
+	// filesystem type or mount flags
+	func(req *structs.VolumeRequest) string {
+		if req.AttachmentMode != structs.CSIVolumeAttachmentModeBlockDevice || req.MountOptions == nil {
+			return ""
+		}
+		if req.MountOptions.FSType != "" || len(req.MountOptions.MountFlags) > 0 {
+			return fmt.Sprintf("attachment mode %q cannot have mount options", req.AttachmentMode)
+		}
+		return ""
+	},
+}
+
+// validateCSIModes returns a csiModeError for the first invalid combination
+// of modes and options the volume request has.
+func validateCSIModes(alias string, req *structs.VolumeRequest) error {
+	for _, check := range csiModeChecks {
+		if reason := check(req); reason != "" {
+			return &csiModeError{alias: alias, source: req.Source, reason: reason}
+		}
+	}
+	return nil
+}
+
+// conflictingMountFlags pairs each mount flag with the flag that undoes it.
+var conflictingMountFlags = map[string]string{
+	"ro":         "rw",
+	"rw":         "ro",
+	"sync":       "async",
+	"async":      "sync",
+	"exec":       "noexec",
+	"noexec":     "exec",
+	"suid":       "nosuid",
+	"nosuid":     "suid",
+	"dev":        "nodev",
+	"nodev":      "dev",
+	"atime":      "noatime",
+	"noatime":    "atime",
+	"diratime":   "nodiratime",
+	"nodiratime": "diratime",
+	"relatime":   "norelatime",
+	"norelatime": "relatime",
+	"auto":       "noauto",
+	"noauto":     "auto",
+}
+
+// normalizeMountOptions returns a copy of the mount options with the mount
+// flags trimmed, deduplicated and sorted, so that the mounter is passed the
+// same flags however the job spells them. Flags that undo each other, or
+// options given different values, are rejected. The values of options aren't
+// part of the error, as mount flags may be sensitive.
+func normalizeMountOptions(opts *structs.CSIMountOptions) (*structs.CSIMountOptions, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]struct{}, len(opts.MountFlags))
+	values := make(map[string]string)
+	flags := make([]string, 0, len(opts.MountFlags))
+	for _, flag := range opts.MountFlags {
+		flag = strings.TrimSpace(flag)
+		if flag == "" {
+			continue
+		}
+		if _, ok := seen[flag]; ok {
+			continue
+		}
+
+		if other, ok := conflictingMountFlags[flag]; ok {
+			if _, ok := seen[other]; ok {
+				return nil, fmt.Errorf("mount flags %q and %q conflict", flag, other)
+			}
+		}
+		if parts := strings.SplitN(flag, "=", 2); len(parts) == 2 {
+			key, value := parts[0], parts[1]
+			if prev, ok := values[key]; ok && prev != value {
+				return nil, fmt.Errorf("mount option %q is set more than once with different values", key)
+			}
+			values[key] = value
+		}
+
+		seen[flag] = struct{}{}
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+
+	normalized := opts.Copy()
+	normalized.MountFlags = flags
+	if len(flags) == 0 {
+		normalized.MountFlags = nil
+	}
+	return normalized, nil
 }
 
 // implemented by allocrunner
@@ -32,16 +395,69 @@ type taskCapabilityGetter interface {
 	GetTaskDriverCapabilities(string) (*drivers.Capabilities, error)
 }
 
-func newCSIHook(alloc *structs.Allocation, logger hclog.Logger, csi csimanager.Manager, rpcClient RPCer, taskCapabilityGetter taskCapabilityGetter, updater hookResourceSetter, nodeSecret string) *csiHook {
+// csiHookConfig is the configuration of a csiHook. The op scheduler, capacity
+// budget, write claims and claim batch support are shared by the hooks of all
+// of the client's allocations.
+type csiHookConfig struct {
+	alloc                *structs.Allocation
+	logger               hclog.Logger
+	csiManager           csimanager.Manager
+	rpcClient            RPCer
+	taskCapabilityGetter taskCapabilityGetter
+	hookResources        hookResourceSetter
+	eventer              ti.EventEmitter
+	opScheduler          *csimanager.OpScheduler
+	capacityBudget       *csimanager.CapacityBudget
+	writeClaims          *csimanager.WriteClaimTracker
+	claimBatch           *csimanager.ClaimBatchSupport
+	nodeSecret           string
+	vaultClient          vaultclient.VaultClient
+	stateDB              cstate.StateDB
+	clientConfig         *clientconfig.Config
+}
+
+func newCSIHook(cfg csiHookConfig) *csiHook {
+	alloc, clientConfig := cfg.alloc, cfg.clientConfig
+	mountTimeout := clientConfig.CSIVolumeMountTimeout
+	if mountTimeout <= 0 {
+		mountTimeout = clientconfig.DefaultCSIVolumeMountTimeout
+	}
+	maxVolumes, _ := clientConfig.CSIVolumeLimits()
+
+	claimTimeout := mountTimeout
+	if tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup); tg != nil &&
+		tg.CSIClaimTimeout != nil && *tg.CSIClaimTimeout > 0 {
+		claimTimeout = *tg.CSIClaimTimeout
+	}
+
 	return &csiHook{
-		alloc:                alloc,
-		logger:               logger.Named("csi_hook"),
-		csimanager:           csi,
-		rpcClient:            rpcClient,
-		taskCapabilityGetter: taskCapabilityGetter,
-		updater:              updater,
-		nodeSecret:           nodeSecret,
-		volumeRequests:       map[string]*volumeAndRequest{},
+		alloc:                  alloc,
+		logger:                 cfg.logger.Named("csi_hook"),
+		csimanager:             cfg.csiManager,
+		rpcClient:              cfg.rpcClient,
+		taskCapabilityGetter:   cfg.taskCapabilityGetter,
+		updater:                cfg.hookResources,
+		eventer:                cfg.eventer,
+		nodeSecret:             cfg.nodeSecret,
+		vaultClient:            cfg.vaultClient,
+		perAllocCanaries:       clientConfig.ReadBoolDefault("csi.per_alloc_canaries", false),
+		mountTimeout:           mountTimeout,
+		pluginRegistrationWait: clientConfig.CSIPluginRegistrationWait,
+		claimTimeout:           claimTimeout,
+		claimRetry:             clientconfig.DefaultCSIClaimRetry().Merge(clientConfig.CSIClaimRetry),
+		unpublishRetry:         clientconfig.DefaultCSIUnpublishRetry().Merge(clientConfig.CSIUnpublishRetry),
+		maxVolumes:             maxVolumes,
+		stateDB:                cfg.stateDB,
+		opScheduler:            cfg.opScheduler,
+		capacityBudget:         cfg.capacityBudget,
+		writeClaims:            cfg.writeClaims,
+		claimBatch:             cfg.claimBatch,
+		claimLabelEnv:          clientConfig.CSIClaimLabelEnv,
+		idempotencyKeys:        clientConfig.CSIIdempotencyKeys,
+		volumeHealthCheck:      clientConfig.CSIVolumeHealthCheck,
+		mountMetadata:          clientConfig.CSIMountMetadata,
+		claimTopology:          clientConfig.CSIClaimTopology,
+		volumeRequests:         map[string]*volumeAndRequest{},
 	}
 }
 
@@ -49,96 +465,1032 @@ func (c *csiHook) Name() string {
 	return "csi_hook"
 }
 
-func (c *csiHook) Prerun() error {
-	if !c.shouldRun() {
+// Prerun claims and mounts the allocation's volumes. Cancelling ctx
+// interrupts waiting claims and in-flight mounts.
+//
+// The claimed and mounted volumes are persisted, so that when the client
+// restarts the restored allocation reuses them instead of claiming and
+// mounting them again. Only the volumes whose mount is gone are claimed and
+// mounted again.
+func (c *csiHook) Prerun(ctx context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	run, err := c.shouldRun()
+	if err != nil {
+		return err
+	}
+	if !run {
+		c.mounted = true
+		return nil
+	}
+
+	// If any volume fails to be claimed or mounted, the volumes already
+	// mounted are unmounted and every claim made is released, so that a
+	// failed Prerun leaves nothing behind. If the client is shutting down
+	// they're left in place instead, as the restored alloc claims and mounts
+	// its volumes again.
+	restored := c.restoredVolumes()
+	volumes, err := c.claimVolumesFromAlloc(ctx, restored)
+	if err != nil {
+		if !interfaces.ShuttingDown(ctx) {
+			c.unmountVolumes(restoredPairs(volumes))
+			c.releaseClaims(volumes)
+			c.forgetReleasedVolumes()
+		}
+		c.capacityBudget.Release(c.alloc.ID)
+		c.writeClaims.Release(c.alloc.ID)
+		return fmt.Errorf("claim volumes: %w", err)
+	}
+
+	if err := c.resolveVolumeSecrets(volumes); err != nil {
+		if !interfaces.ShuttingDown(ctx) {
+			c.unmountVolumes(restoredPairs(volumes))
+			c.releaseClaims(volumes)
+			c.forgetReleasedVolumes()
+		}
+		c.capacityBudget.Release(c.alloc.ID)
+		c.writeClaims.Release(c.alloc.ID)
+		return fmt.Errorf("resolve volume secrets: %w", err)
+	}
+
+	mounts, err := c.mountVolumes(ctx, volumes)
+	if err != nil {
+		if !interfaces.ShuttingDown(ctx) {
+			c.releaseClaims(volumes)
+			c.forgetReleasedVolumes()
+		}
+		c.capacityBudget.Release(c.alloc.ID)
+		c.writeClaims.Release(c.alloc.ID)
+		return err
+	}
+	c.restoreRemovedVolumes(volumes, mounts, restored)
+	c.volumeRequests = volumes
+	c.mounted = true
+	c.persistVolumes(volumes, mounts)
+
+	res := c.updater.GetAllocHookResources()
+	res.CSIMounts = mounts
+	res.SetCSIMountSources(mountSources(volumes))
+	c.updater.SetAllocHookResources(res)
+
+	return nil
+}
+
+// Update queues an in-place update of the allocation to be applied by
+// applyUpdate and returns without waiting for it. Updates are applied one at
+// a time in order, and an update queued while another is applied replaces
+// any update still waiting, as each carries the whole allocation. Errors
+// applying an update are logged, as the alloc runner does with the errors
+// of update hooks.
+func (c *csiHook) Update(req *interfaces.RunnerUpdateRequest) error {
+	c.updateLock.Lock()
+	defer c.updateLock.Unlock()
+
+	if c.updatesStopped {
+		return nil
+	}
+	c.pendingUpdate = req
+	if !c.updating {
+		c.updating = true
+		go c.applyUpdates()
+	}
+	return nil
+}
+
+// applyUpdates applies the queued updates until none is left.
+func (c *csiHook) applyUpdates() {
+	for {
+		c.updateLock.Lock()
+		req := c.pendingUpdate
+		c.pendingUpdate = nil
+		if req == nil || c.updatesStopped {
+			c.updating = false
+			c.updateLock.Unlock()
+			return
+		}
+		ctx := req.Ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		c.cancelUpdate = cancel
+		c.updateLock.Unlock()
+
+		if err := c.applyUpdate(ctx, req.Alloc); err != nil {
+			c.logger.Error("failed to update volumes", "error", err)
+		}
+		cancel()
+	}
+}
+
+// stopUpdates drops the updates not applied yet and cancels the claims and
+// mounts of the update being applied, once the allocation stops.
+func (c *csiHook) stopUpdates() {
+	c.updateLock.Lock()
+	defer c.updateLock.Unlock()
+
+	c.updatesStopped = true
+	c.pendingUpdate = nil
+	if c.cancelUpdate != nil {
+		c.cancelUpdate()
+	}
+}
+
+// applyUpdate claims and mounts the volumes added to the task group by an
+// in-place update of the allocation. Volumes whose request is unchanged keep
+// their claims and mounts. Running tasks only see the new mounts once they
+// restart, and may bind mount the removed volumes until then, so the removed
+// volumes stay claimed and mounted until Postrun releases them. An update
+// changing the request of a mounted volume is rejected for the same reason,
+// and the volume keeps its claim.
+//
+// The claims and mounts are bounded by the claim and mount timeouts, and are
+// cancelled if the allocation is destroyed or stops, so that they don't hold
+// up stopping the allocation. If an added volume fails to be claimed or
+// mounted, the added volumes are released as Prerun does.
+func (c *csiHook) applyUpdate(ctx context.Context, alloc *structs.Allocation) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// The volumes of a stopped allocation are released, so none are added
+	if c.stopped {
+		return nil
+	}
+	c.alloc = alloc
+
+	// Volumes are only claimed for an allocation once Prerun mounted its
+	// volumes, which it does from the updated allocation otherwise
+	if !c.mounted {
+		return nil
+	}
+
+	tg, err := c.taskGroup()
+	if err != nil {
+		return fmt.Errorf("update volumes: %w", err)
+	}
+	requests, err := c.volumeRequestsFromGroup(tg)
+	if err != nil {
+		return fmt.Errorf("update volumes: %w", err)
+	}
+
+	// The mounts of the kept volumes are reused under their new aliases
+	res := c.updater.GetAllocHookResources()
+	pairMounts := make(map[*volumeAndRequest]*csimanager.MountInfo, len(c.volumeRequests))
+	for alias, pair := range c.volumeRequests {
+		pairMounts[pair] = res.CSIMounts[alias]
+	}
+
+	volumes := make(map[string]*volumeAndRequest, len(requests))
+	mounts := make(map[string]*csimanager.MountInfo, len(requests))
+	added := make(map[string]*volumeAndRequest)
+	kept := make(map[*volumeAndRequest]struct{}, len(c.volumeRequests))
+	for alias, pair := range requests {
+		if old := c.matchVolume(pair.request); old != nil {
+			volumes[alias] = old
+			mounts[alias] = pairMounts[old]
+			kept[old] = struct{}{}
+			continue
+		}
+		added[alias] = pair
+	}
+
+	// The removed volumes are kept under their aliases until the allocation
+	// stops
+	removedSources := make(map[string]struct{})
+	for _, alias := range sortedAliases(c.volumeRequests) {
+		pair := c.volumeRequests[alias]
+		if _, ok := kept[pair]; ok {
+			continue
+		}
+		volumes[alias] = pair
+		mounts[alias] = pairMounts[pair]
+		if !pair.removed {
+			pair.removed = true
+			c.emitEvent(alias, pair.volume.PluginID,
+				fmt.Sprintf("Volume %q removed by update is released when the allocation stops", alias))
+		}
+		removedSources[c.volumeSource(pair.request)] = struct{}{}
+	}
+
+	// A changed volume can't be claimed again before the removed one is
+	// released, nor mounted under the alias the removed one still uses
+	var mErr *multierror.Error
+	for _, alias := range sortedAliases(added) {
+		source := c.volumeSource(added[alias].request)
+		_, aliasUsed := volumes[alias]
+		_, sourceUsed := removedSources[source]
+		if aliasUsed || sourceUsed {
+			mErr = multierror.Append(mErr, fmt.Errorf(
+				"volume %q (source %q) can't change while the allocation's tasks may use it", alias, source))
+			delete(added, alias)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.claimTimeout+c.mountTimeout)
+	defer cancel()
+
+	addedMounts, err := c.addVolumes(ctx, added, kept)
+	if err != nil {
+		mErr = multierror.Append(mErr, err)
+	}
+	for alias, mountInfo := range addedMounts {
+		volumes[alias] = added[alias]
+		mounts[alias] = mountInfo
+	}
+
+	c.volumeRequests = volumes
+	c.persistVolumes(volumes, mounts)
+
+	res.CSIMounts = mounts
+	res.SetCSIMountSources(mountSources(volumes))
+	c.updater.SetAllocHookResources(res)
+
+	return mErr.ErrorOrNil()
+}
+
+// matchVolume returns the claimed and mounted volume satisfying the request,
+// or nil if the request needs a volume to be claimed and mounted.
+func (c *csiHook) matchVolume(req *structs.VolumeRequest) *volumeAndRequest {
+	for _, pair := range c.volumeRequests {
+		old := pair.request
+		if c.volumeSource(old) == c.volumeSource(req) &&
+			old.ReadOnly == req.ReadOnly &&
+			old.AccessMode == req.AccessMode &&
+			old.AttachmentMode == req.AttachmentMode &&
+			reflect.DeepEqual(old.MountOptions, req.MountOptions) {
+			return pair
+		}
+	}
+	return nil
+}
+
+// addVolumes claims and mounts the volumes added by an update of the
+// allocation, returning their mounts by alias. If any fails, the added
+// volumes are unmounted and released.
+func (c *csiHook) addVolumes(ctx context.Context, added map[string]*volumeAndRequest, kept map[*volumeAndRequest]struct{}) (map[string]*csimanager.MountInfo, error) {
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	volumes, err := c.claimVolumes(ctx, added, nil)
+	if err == nil {
+		err = c.resolveVolumeSecrets(volumes)
+	}
+	var mounts map[string]*csimanager.MountInfo
+	if err == nil {
+		mounts, err = c.mountVolumes(ctx, volumes)
+	}
+	if err != nil {
+		c.releaseClaims(added)
+		for _, pair := range added {
+			if c.sharesSource(pair, kept) {
+				continue
+			}
+			if pair.volume != nil {
+				c.capacityBudget.ReleaseVolume(c.alloc.ID, pair.volume)
+			}
+			c.writeClaims.ReleaseVolume(c.alloc.ID, c.alloc.Job.Namespace, c.volumeSource(pair.request))
+		}
+		return nil, fmt.Errorf("add volumes: %w", err)
+	}
+	return mounts, nil
+}
+
+// sharesSource returns whether one of the kept volumes has the same source as
+// the volume.
+func (c *csiHook) sharesSource(pair *volumeAndRequest, kept map[*volumeAndRequest]struct{}) bool {
+	source := c.volumeSource(pair.request)
+	for other := range kept {
+		if c.volumeSource(other.request) == source {
+			return true
+		}
+	}
+	return false
+}
+
+// restoredVolumes returns the volumes persisted by the Prerun of the
+// allocation before the client restarted, by alias.
+func (c *csiHook) restoredVolumes() map[string]*cstructs.CSIVolumeState {
+	vols, err := c.stateDB.GetCSIVolumes(c.alloc.ID)
+	if err != nil {
+		c.logger.Warn("failed to load volume state, claiming and mounting volumes again", "error", err)
+		return nil
+	}
+	return vols
+}
+
+// restoreVolume reuses the claim and mount of a volume from before the
+// client restarted, returning whether the volume is still mounted.
+func (c *csiHook) restoreVolume(ctx context.Context, alias string, pair *volumeAndRequest, state *cstructs.CSIVolumeState) bool {
+	if state.Volume == nil || state.Volume.ID != c.volumeSource(pair.request) ||
+		state.MountInfo == nil || state.Unpublished {
+		return false
+	}
+
+	mounter, err := c.csimanager.MounterForPlugin(ctx, state.Volume.PluginID)
+	if err == nil {
+		err = mounter.RestoreVolume(state.Volume, c.alloc, usageOptsFor(pair.request), state.MountInfo)
+	}
+	if err != nil {
+		c.logger.Warn("failed to restore volume mount, claiming and mounting it again",
+			"volume", alias, "error", err)
+		return false
+	}
+
+	c.logger.Debug("restored volume mount", "volume", alias, "volume_id", state.Volume.ID)
+	pair.volume = state.Volume
+	pair.mountInfo = state.MountInfo
+	pair.metadataPath = c.mountMetadataPath(state.MountInfo)
+	return true
+}
+
+// restoreRemovedVolumes adds the volumes an update removed from the task group
+// before the client restarted to the volumes claimed by Prerun, so that they
+// stay claimed and mounted until the allocation stops.
+func (c *csiHook) restoreRemovedVolumes(volumes map[string]*volumeAndRequest, mounts map[string]*csimanager.MountInfo, restored map[string]*cstructs.CSIVolumeState) {
+	bySource := make(map[string]*volumeAndRequest)
+	for alias, state := range restored {
+		if state.RemovedRequest == nil || state.Volume == nil || state.Unpublished {
+			continue
+		}
+		if _, ok := volumes[alias]; ok {
+			continue
+		}
+		pair, ok := bySource[state.Volume.ID]
+		if !ok {
+			pair = &volumeAndRequest{
+				volume:       state.Volume,
+				request:      state.RemovedRequest,
+				mountInfo:    state.MountInfo,
+				metadataPath: c.mountMetadataPath(state.MountInfo),
+				removed:      true,
+				claimIndex:   state.ClaimIndex,
+			}
+			bySource[state.Volume.ID] = pair
+		}
+		volumes[alias] = pair
+		mounts[alias] = state.MountInfo
+	}
+}
+
+// restoredPairs returns the volumes whose mounts were restored.
+func restoredPairs(volumes map[string]*volumeAndRequest) []*volumeAndRequest {
+	var restored []*volumeAndRequest
+	seen := make(map[*volumeAndRequest]struct{}, len(volumes))
+	for _, pair := range volumes {
+		if _, ok := seen[pair]; ok || pair.mountInfo == nil {
+			continue
+		}
+		seen[pair] = struct{}{}
+		restored = append(restored, pair)
+	}
+	return restored
+}
+
+// persistVolumes stores the claimed and mounted volumes so that a restored
+// allocation can reuse them. Errors are only logged, as the restored
+// allocation then claims and mounts its volumes again.
+func (c *csiHook) persistVolumes(volumes map[string]*volumeAndRequest, mounts map[string]*csimanager.MountInfo) {
+	var states map[string]*cstructs.CSIVolumeState
+	if len(volumes) > 0 {
+		states = make(map[string]*cstructs.CSIVolumeState, len(volumes))
+		for alias, pair := range volumes {
+			states[alias] = c.volumeState(pair, mounts[alias])
+		}
+	}
+
+	if err := c.stateDB.PutCSIVolumes(c.alloc.ID, states); err != nil {
+		c.logger.Warn("failed to persist volume state", "error", err)
+	}
+}
+
+// recordVolume persists the state of a single volume as its requests are
+// made, keeping the state of the allocation's other volumes, so that a
+// restarted client knows the requests made for it.
+func (c *csiHook) recordVolume(alias string, pair *volumeAndRequest) {
+	states := make(map[string]*cstructs.CSIVolumeState)
+	if vols, err := c.stateDB.GetCSIVolumes(c.alloc.ID); err == nil {
+		for a, state := range vols {
+			states[a] = state
+		}
+	}
+	states[alias] = c.volumeState(pair, pair.mountInfo)
+
+	if err := c.stateDB.PutCSIVolumes(c.alloc.ID, states); err != nil {
+		c.logger.Warn("failed to persist volume state", "volume", alias, "error", err)
+	}
+}
+
+// forgetReleasedVolumes drops the persisted state of the volumes whose claims
+// were released by a failed Prerun, when their requests are recorded as they
+// are made.
+func (c *csiHook) forgetReleasedVolumes() {
+	if c.idempotencyKeys {
+		c.persistVolumes(nil, nil)
+	}
+}
+
+// mountSources returns the volume mounted under each alias, for introspection
+// of the allocation's mounts.
+func mountSources(volumes map[string]*volumeAndRequest) map[string]*cstructs.CSIMountSource {
+	sources := make(map[string]*cstructs.CSIMountSource, len(volumes))
+	for alias, pair := range volumes {
+		if pair.volume == nil {
+			continue
+		}
+		sources[alias] = &cstructs.CSIMountSource{
+			VolumeID:   pair.volume.ID,
+			PluginID:   pair.volume.PluginID,
+			AccessMode: pair.request.AccessMode,
+		}
+	}
+	return sources
+}
+
+// volumeState returns the state of the volume to persist. A volume that
+// isn't claimed yet is recorded by its ID.
+func (c *csiHook) volumeState(pair *volumeAndRequest, mountInfo *csimanager.MountInfo) *cstructs.CSIVolumeState {
+	vol := pair.volume
+	if vol == nil {
+		vol = &structs.CSIVolume{ID: c.volumeSource(pair.request), Namespace: c.alloc.Job.Namespace}
+	}
+	state := cstructs.NewCSIVolumeState(vol, mountInfo)
+	state.Unpublished = pair.unpublished
+	state.ClaimIndex = pair.claimIndex
+	if pair.removed {
+		state.RemovedRequest = pair.request
+	}
+	return state
+}
+
+// mountVolumes mounts the claimed volumes concurrently, bounded by
+// csiMaxParallelMounts, and returns their mounts by alias. Aliases that
+// resolve to the same volume share a request, so each volume is mounted once
+// and its mount is exposed under every alias. If any mount fails the rest are
+// cancelled, the volumes already mounted are unmounted unless the client is
+// shutting down, and the first error is returned. The same happens if ctx is
+// cancelled before every volume is mounted.
+func (c *csiHook) mountVolumes(ctx context.Context, volumes map[string]*volumeAndRequest) (map[string]*csimanager.MountInfo, error) {
+	mounts := make(map[string]*csimanager.MountInfo, len(volumes))
+	mounted := make([]*volumeAndRequest, 0, len(volumes))
+
+	// Volumes whose mounts were restored aren't mounted again
+	aliases := make(map[*volumeAndRequest][]string, len(volumes))
+	pairs := make([]*volumeAndRequest, 0, len(volumes))
+	for _, alias := range sortedAliases(volumes) {
+		pair := volumes[alias]
+		if pair.mountInfo != nil {
+			mounts[alias] = pair.mountInfo
+			continue
+		}
+		if _, ok := aliases[pair]; !ok {
+			pairs = append(pairs, pair)
+		}
+		aliases[pair] = append(aliases[pair], alias)
+	}
+	mounted = append(mounted, restoredPairs(volumes)...)
+	var lock sync.Mutex
+
+	g, gCtx := errgroup.WithContext(ctx)
+
+	// Cap the workers
+	workers := len(pairs)
+	if workers > csiMaxParallelMounts {
+		workers = csiMaxParallelMounts
+	}
+
+	input := make(chan *volumeAndRequest, workers)
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			for {
+				select {
+				case pair, ok := <-input:
+					if !ok {
+						return nil
+					}
+
+					// Events and errors refer to the volume by its first alias
+					mountInfo, err := c.mountVolume(gCtx, aliases[pair][0], pair)
+					if err != nil {
+						return err
+					}
+
+					lock.Lock()
+					for _, alias := range aliases[pair] {
+						mounts[alias] = mountInfo
+					}
+					mounted = append(mounted, pair)
+					lock.Unlock()
+				case <-gCtx.Done():
+					return gCtx.Err()
+				}
+			}
+		})
+	}
+
+	// Send the input
+	go func() {
+		defer close(input)
+		for _, pair := range pairs {
+			select {
+			case <-gCtx.Done():
+				return
+			case input <- pair:
+			}
+		}
+	}()
+
+	err := g.Wait()
+	if err == nil {
+		// The workers stop taking volumes once ctx is cancelled, so check it
+		// in case they stopped before mounting all of them
+		err = ctx.Err()
+	}
+	if err != nil {
+		if !interfaces.ShuttingDown(ctx) {
+			c.unmountVolumes(mounted)
+		}
+		return nil, err
+	}
+	return mounts, nil
+}
+
+// mountVolume mounts a single claimed volume, bounding the call to the node
+// plugin by the configured mount timeout.
+func (c *csiHook) mountVolume(ctx context.Context, alias string, pair *volumeAndRequest) (*csimanager.MountInfo, error) {
+	pluginID := pair.volume.PluginID
+
+	if c.volumeHealthCheck {
+		if err := checkVolumeHealth(alias, pair.volume); err != nil {
+			c.emitFailure(alias, pluginID, fmt.Sprintf("Volume %q is unhealthy", alias), err)
+			return nil, err
+		}
+	}
+
+	release, err := c.opScheduler.Acquire(ctx, c.alloc.Job.Priority)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	mounter, err := c.mounterForPlugin(ctx, alias, pluginID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The topology is the plugin's own, so that it's known even if the
+	// plugin registered after the allocation was restored
+	if c.claimTopology {
+		if err := checkVolumeTopology(alias, pair.volume, mounter.NodeTopology()); err != nil {
+			c.emitFailure(alias, pluginID, fmt.Sprintf("Volume %q is not accessible from this node", alias), err)
+			return nil, err
+		}
+	}
+
+	c.emitEvent(alias, pluginID, fmt.Sprintf("Mounting volume %q via plugin %q", alias, pluginID))
+
+	ctx, cancel := context.WithTimeout(ctx, c.mountTimeout)
+	defer cancel()
+
+	// The mount duration is sampled whether or not the mount succeeds, so
+	// that slow failures show up as well
+	labels := []metrics.Label{{Name: "plugin_id", Value: pluginID}}
+	start := time.Now()
+	mountInfo, err := mounter.MountVolume(ctx, pair.volumeWithSecrets(), c.alloc, usageOptsFor(pair.request), pair.publishContext)
+	metrics.MeasureSinceWithLabels([]string{"client", "csi", "mount_duration"}, start, labels)
+	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"client", "csi", "mount_failures"}, 1, labels)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("volume %q mount via plugin %q timed out after %v: %w",
+				alias, pluginID, c.mountTimeout, err)
+		}
+		c.emitFailure(alias, pluginID,
+			fmt.Sprintf("Failed to mount volume %q via plugin %q", alias, pluginID), err)
+		return nil, err
+	}
+
+	c.emitEvent(alias, pluginID, fmt.Sprintf("Volume %q mounted", alias))
+	c.writeMountMetadata(pair, mountInfo)
+	return mountInfo, nil
+}
+
+// csiMountMetadata describes the allocation using a volume mount, in the file
+// written next to the mount for external tools.
+type csiMountMetadata struct {
+	AllocID   string `json:"alloc_id"`
+	Namespace string `json:"namespace"`
+	JobID     string `json:"job_id"`
+	TaskGroup string `json:"task_group"`
+	VolumeID  string `json:"volume_id"`
+	PluginID  string `json:"plugin_id"`
+}
+
+// mountMetadataPath returns the path of the metadata file of a volume mount,
+// or an empty string if the client doesn't write mount metadata.
+func (c *csiHook) mountMetadataPath(mountInfo *csimanager.MountInfo) string {
+	if !c.mountMetadata || mountInfo == nil || mountInfo.Source == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(mountInfo.Source), csimanager.MountMetadataFileName)
+}
+
+// writeMountMetadata writes the metadata file of the volume's mount. The
+// metadata is only for external tools, so failing to write it doesn't fail
+// the mount.
+func (c *csiHook) writeMountMetadata(pair *volumeAndRequest, mountInfo *csimanager.MountInfo) {
+	path := c.mountMetadataPath(mountInfo)
+	if path == "" {
+		return
+	}
+
+	buf, err := json.MarshalIndent(&csiMountMetadata{
+		AllocID:   c.alloc.ID,
+		Namespace: c.alloc.Namespace,
+		JobID:     c.alloc.JobID,
+		TaskGroup: c.alloc.TaskGroup,
+		VolumeID:  pair.volume.ID,
+		PluginID:  pair.volume.PluginID,
+	}, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(path, buf, 0644)
+	}
+	if err != nil {
+		c.logger.Warn("failed to write volume mount metadata",
+			"volume_id", pair.volume.ID, "path", path, "error", err)
+		return
+	}
+	pair.metadataPath = path
+}
+
+// removeMountMetadata removes the metadata file of the volume's mount, once
+// the volume is unmounted or released.
+func (c *csiHook) removeMountMetadata(pair *volumeAndRequest) {
+	if pair.metadataPath == "" {
+		return
+	}
+	if err := os.Remove(pair.metadataPath); err != nil && !os.IsNotExist(err) {
+		c.logger.Warn("failed to remove volume mount metadata",
+			"volume_id", pair.volume.ID, "path", pair.metadataPath, "error", err)
+		return
+	}
+	pair.metadataPath = ""
+}
+
+// mounterForPlugin returns the VolumeMounter for a node plugin. If the plugin
+// has registered on this node but is temporarily unavailable, it waits for
+// the plugin with backoff, bounded by the mount timeout. A plugin that has
+// never registered on this node is waited for up to the plugin registration
+// wait, which also tolerates plugins starting slightly after the allocation
+// on a freshly started client. Once it elapses the mount fails, so that the
+// allocation can be rescheduled onto a node where the plugin runs.
+func (c *csiHook) mounterForPlugin(ctx context.Context, alias, pluginID string) (csimanager.VolumeMounter, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.mountTimeout)
+	defer cancel()
+
+	start := time.Now()
+	backoff := csiPluginWaitBackoffBaseline
+	for waiting := false; ; waiting = true {
+		mounter, err := c.csimanager.MounterForPlugin(ctx, pluginID)
+		switch {
+		case err == nil:
+			return mounter, nil
+		case errors.Is(err, csimanager.ErrPluginNotRegistered):
+			if time.Since(start) < c.pluginRegistrationWait {
+				break
+			}
+			if waiting {
+				err = fmt.Errorf("plugin %q not registered after waiting %v: %w",
+					pluginID, c.pluginRegistrationWait, err)
+			}
+			c.emitFailure(alias, pluginID,
+				fmt.Sprintf("Plugin %q for volume %q is not registered on this node", pluginID, alias), err)
+			return nil, err
+		case !errors.Is(err, csimanager.ErrPluginUnavailable):
+			c.emitFailure(alias, pluginID,
+				fmt.Sprintf("Failed to mount volume %q via plugin %q", alias, pluginID), err)
+			return nil, err
+		}
+
+		if !waiting {
+			c.emitEvent(alias, pluginID,
+				fmt.Sprintf("Waiting for plugin %q to become available", pluginID))
+		}
+		c.logger.Debug("waiting for CSI plugin", "plugin_id", pluginID, "backoff", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			err = fmt.Errorf("plugin %q unavailable after waiting %v: %w", pluginID, c.mountTimeout, err)
+			c.emitFailure(alias, pluginID,
+				fmt.Sprintf("Failed to mount volume %q via plugin %q", alias, pluginID), err)
+			return nil, err
+		}
+
+		backoff *= 2
+		if backoff > csiPluginWaitBackoffLimit {
+			backoff = csiPluginWaitBackoffLimit
+		}
+	}
+}
+
+// emitEvent emits a task event describing the progress of claiming and
+// mounting a volume.
+func (c *csiHook) emitEvent(alias, pluginID, msg string) {
+	event := structs.NewTaskEvent(structs.TaskSetup).
+		SetMessage(msg).
+		SetDisplayMessage(msg)
+	c.eventer.EmitEvent(withVolumeDetails(event, alias, pluginID))
+}
+
+// emitFailure emits a task event for a failure to claim or mount a volume.
+func (c *csiHook) emitFailure(alias, pluginID, msg string, err error) {
+	event := structs.NewTaskEvent(structs.TaskSetupFailure).
+		SetSetupError(err).
+		SetDisplayMessage(fmt.Sprintf("%s: %v", msg, err))
+	c.eventer.EmitEvent(withVolumeDetails(event, alias, pluginID))
+}
+
+func withVolumeDetails(event *structs.TaskEvent, alias, pluginID string) *structs.TaskEvent {
+	event.Details = map[string]string{"volume": alias}
+	if pluginID != "" {
+		event.Details["plugin_id"] = pluginID
+	}
+	return event
+}
+
+// unmountVolumes makes a best-effort attempt to unmount volumes that were
+// mounted before a later mount in the same Prerun failed.
+func (c *csiHook) unmountVolumes(pairs []*volumeAndRequest) {
+	for _, pair := range pairs {
+		if err := c.unmountVolume(context.Background(), pair); err != nil {
+			c.logger.Warn("failed to unmount volume after failed prerun",
+				"volume", pair.volume.ID, "plugin", pair.volume.PluginID, "error", err)
+		}
+	}
+}
+
+// unmountVolume unmounts the volume with its node plugin, waiting for the
+// plugin to confirm for up to the mount timeout.
+func (c *csiHook) unmountVolume(ctx context.Context, pair *volumeAndRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, c.mountTimeout)
+	defer cancel()
+
+	mounter, err := c.csimanager.MounterForPlugin(ctx, pair.volume.PluginID)
+	if err == nil {
+		err = mounter.UnmountVolume(ctx, pair.volume.ID, pair.volume.RemoteID(),
+			c.alloc.ID, usageOptsFor(pair.request))
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v waiting for plugin %q: %w", c.mountTimeout, pair.volume.PluginID, err)
+	}
+	if err == nil {
+		c.removeMountMetadata(pair)
+	}
+	return err
+}
+
+// Shutdown releases the volumes of an allocation stopped by a node drain, one
+// at a time in the reverse order of their claims, so that the volumes claimed
+// last are released first. Each volume is unmounted by its node plugin, bounded
+// by the mount timeout, before its claim is unpublished. The volumes released
+// are skipped by Postrun, which releases any left.
+func (c *csiHook) Shutdown(ctx context.Context) error {
+	c.stopUpdates()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stopped = true
+
+	run, err := c.shouldRun()
+	if err != nil {
+		return err
+	}
+	if !run {
+		return nil
+	}
+
+	var mErr *multierror.Error
+	mounts := c.updater.GetAllocHookResources().GetCSIMounts()
+	for _, alias := range c.drainOrder() {
+		pair := c.volumeRequests[alias]
+		if err := ctx.Err(); err != nil {
+			mErr = multierror.Append(mErr, err)
+			break
+		}
+
+		// A volume the plugin didn't confirm is unmounted is left to Postrun
+		if err := c.unmountVolume(ctx, pair); err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("could not unmount volume %q: %w", alias, err))
+			continue
+		}
+
+		err := c.retryVolumeRPC(ctx, c.unpublishRetry, "unpublish", c.volumeSource(pair.request), func() error {
+			return c.unpublishVolume(pair)
+		})
+		if err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("could not unpublish volume %q: %w", alias, err))
+			continue
+		}
+
+		pair.unpublished = true
+		if c.idempotencyKeys {
+			c.persistVolumes(c.volumeRequests, mounts)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// drainOrder returns an alias of each claimed volume that isn't released, in
+// the reverse order of their claims.
+func (c *csiHook) drainOrder() []string {
+	var aliases []string
+	seen := make(map[*volumeAndRequest]struct{}, len(c.volumeRequests))
+	for _, alias := range sortedAliases(c.volumeRequests) {
+		pair := c.volumeRequests[alias]
+		if _, ok := seen[pair]; ok || pair.volume == nil || pair.unpublished {
+			continue
+		}
+		seen[pair] = struct{}{}
+		aliases = append(aliases, alias)
+	}
+
+	sort.SliceStable(aliases, func(i, j int) bool {
+		return c.volumeRequests[aliases[i]].claimIndex > c.volumeRequests[aliases[j]].claimIndex
+	})
+	return aliases
+}
+
+// usageOptsFor returns the UsageOptions the volume request is mounted with.
+func usageOptsFor(req *structs.VolumeRequest) *csimanager.UsageOptions {
+	return &csimanager.UsageOptions{
+		ReadOnly:       req.ReadOnly,
+		AttachmentMode: req.AttachmentMode,
+		AccessMode:     req.AccessMode,
+		MountOptions:   req.MountOptions,
+	}
+}
+
+// Postrun sends an RPC to the server to unpublish the volume. This may
+// forward client RPCs to the node plugins or to the controller plugins,
+// depending on whether other allocations on this node have claims on this
+// volume. Unpublish requests failing with transient errors are retried with
+// backoff, and a volume failing to unpublish doesn't stop the others from
+// being unpublished.
+//
+// If ctx is cancelled by the client shutting down, the remaining volumes are
+// left to be unpublished when the restored allocation's Postrun runs. The
+// volumes that failed to unpublish stay recorded for the same reason.
+func (c *csiHook) Postrun(ctx context.Context) error {
+	c.stopUpdates()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stopped = true
+
+	run, err := c.shouldRun()
+	if err != nil {
+		return err
+	}
+	if !run {
 		return nil
 	}
 
-	// We use this context only to attach hclog to the gRPC context. The
-	// lifetime is the lifetime of the gRPC stream, not specific RPC timeouts,
-	// but we manage the stream lifetime via Close in the pluginmanager.
-	ctx := context.Background()
+	// The volumes are unmounted by the time the allocation stops
+	c.capacityBudget.Release(c.alloc.ID)
+	c.writeClaims.Release(c.alloc.ID)
+
+	var mErr *multierror.Error
 
-	volumes, err := c.claimVolumesFromAlloc()
-	if err != nil {
-		return fmt.Errorf("claim volumes: %v", err)
+	// A restored allocation that stopped before the client restarted
+	// doesn't run Prerun, so its volumes are read from the recorded state
+	volumes := c.volumeRequests
+	if len(volumes) == 0 && c.idempotencyKeys {
+		volumes = c.recordedVolumes()
 	}
-	c.volumeRequests = volumes
+	mounts := c.updater.GetAllocHookResources().GetCSIMounts()
 
-	mounts := make(map[string]*csimanager.MountInfo, len(volumes))
-	for alias, pair := range volumes {
-		mounter, err := c.csimanager.MounterForPlugin(ctx, pair.volume.PluginID)
-		if err != nil {
-			return err
+	attempted := make(map[*volumeAndRequest]struct{}, len(volumes))
+	released := make(map[*volumeAndRequest]struct{}, len(volumes))
+	for _, pair := range volumes {
+		if _, ok := attempted[pair]; ok || pair.unpublished {
+			continue
 		}
+		attempted[pair] = struct{}{}
 
-		usageOpts := &csimanager.UsageOptions{
-			ReadOnly:       pair.request.ReadOnly,
-			AttachmentMode: pair.request.AttachmentMode,
-			AccessMode:     pair.request.AccessMode,
-			MountOptions:   pair.request.MountOptions,
+		if err := ctx.Err(); err != nil {
+			mErr = multierror.Append(mErr, err)
+			break
 		}
 
-		mountInfo, err := mounter.MountVolume(ctx, pair.volume, c.alloc, usageOpts, pair.publishContext)
+		err := c.retryVolumeRPC(ctx, c.unpublishRetry, "unpublish", c.volumeSource(pair.request), func() error {
+			return c.unpublishVolume(pair)
+		})
 		if err != nil {
-			return err
+			mErr = multierror.Append(mErr, err)
+			continue
 		}
+		c.removeMountMetadata(pair)
+		released[pair] = struct{}{}
 
-		mounts[alias] = mountInfo
+		// Released claims are recorded, so that they aren't released again
+		// if the client restarts before every volume is
+		if c.idempotencyKeys {
+			pair.unpublished = true
+			c.persistVolumes(volumes, mounts)
+		}
 	}
 
-	res := c.updater.GetAllocHookResources()
-	res.CSIMounts = mounts
-	c.updater.SetAllocHookResources(res)
-
-	return nil
+	// The stopped allocation's volumes are never restored, so only the
+	// volumes that failed to unpublish are kept
+	if ctx.Err() == nil {
+		remaining := make(map[string]*volumeAndRequest)
+		for alias, pair := range volumes {
+			if _, ok := released[pair]; !ok && !pair.unpublished {
+				remaining[alias] = pair
+			}
+		}
+		c.persistVolumes(remaining, mounts)
+	}
+	return mErr.ErrorOrNil()
 }
 
-// Postrun sends an RPC to the server to unpublish the volume. This may
-// forward client RPCs to the node plugins or to the controller plugins,
-// depending on whether other allocations on this node have claims on this
-// volume.
-func (c *csiHook) Postrun() error {
-	if !c.shouldRun() {
+// recordedVolumes returns the volumes recorded for the allocation, by alias,
+// with the requests of the task group. Aliases recorded for the same volume
+// share a request, so that the volume is released once.
+func (c *csiHook) recordedVolumes() map[string]*volumeAndRequest {
+	tg, err := c.taskGroup()
+	if err != nil {
 		return nil
 	}
+	states := c.restoredVolumes()
 
-	var mErr *multierror.Error
-
-	for _, pair := range c.volumeRequests {
-
-		mode := structs.CSIVolumeClaimRead
-		if !pair.request.ReadOnly {
-			mode = structs.CSIVolumeClaimWrite
+	volumes := make(map[string]*volumeAndRequest, len(states))
+	bySource := make(map[string]*volumeAndRequest, len(states))
+	for alias, state := range states {
+		req := tg.Volumes[alias]
+		if state.RemovedRequest != nil {
+			req = state.RemovedRequest
 		}
-
-		source := pair.request.Source
-		if pair.request.PerAlloc {
-			// NOTE: PerAlloc can't be set if we have canaries
-			source = source + structs.AllocSuffix(c.alloc.Name)
+		if state.Volume == nil || req == nil || req.Type != structs.VolumeTypeCSI {
+			continue
+		}
+		pair, ok := bySource[state.Volume.ID]
+		if !ok {
+			pair = &volumeAndRequest{
+				volume:       state.Volume,
+				request:      req,
+				mountInfo:    state.MountInfo,
+				metadataPath: c.mountMetadataPath(state.MountInfo),
+				unpublished:  state.Unpublished,
+			}
+			bySource[state.Volume.ID] = pair
 		}
+		volumes[alias] = pair
+	}
+	return volumes
+}
 
-		req := &structs.CSIVolumeUnpublishRequest{
-			VolumeID: source,
-			Claim: &structs.CSIVolumeClaim{
-				AllocationID: c.alloc.ID,
-				NodeID:       c.alloc.NodeID,
-				Mode:         mode,
-				State:        structs.CSIVolumeClaimStateUnpublishing,
-			},
-			WriteRequest: structs.WriteRequest{
-				Region:    c.alloc.Job.Region,
-				Namespace: c.alloc.Job.Namespace,
-				AuthToken: c.nodeSecret,
-			},
+// releaseClaims makes a best-effort attempt to release the claims on the
+// volumes that were claimed before a later claim or mount in the same Prerun
+// failed.
+func (c *csiHook) releaseClaims(volumes map[string]*volumeAndRequest) {
+	released := make(map[*volumeAndRequest]struct{}, len(volumes))
+	for _, pair := range volumes {
+		if _, ok := released[pair]; ok || pair.volume == nil {
+			continue
 		}
-		err := c.rpcClient.RPC("CSIVolume.Unpublish",
-			req, &structs.CSIVolumeUnpublishResponse{})
-		if err != nil {
-			mErr = multierror.Append(mErr, err)
+		released[pair] = struct{}{}
+
+		if err := c.unpublishVolume(pair); err != nil {
+			c.logger.Warn("failed to release volume claim after failed prerun",
+				"volume", pair.volume.ID, "error", err)
 		}
 	}
-	return mErr.ErrorOrNil()
+}
+
+// unpublishVolume sends the RPC to the server to release the allocation's
+// claim on the volume.
+func (c *csiHook) unpublishVolume(pair *volumeAndRequest) error {
+	mode := structs.CSIVolumeClaimRead
+	if !pair.request.ReadOnly {
+		mode = structs.CSIVolumeClaimWrite
+	}
+
+	req := &structs.CSIVolumeUnpublishRequest{
+		VolumeID: c.volumeSource(pair.request),
+		Claim: &structs.CSIVolumeClaim{
+			AllocationID: c.alloc.ID,
+			NodeID:       c.alloc.NodeID,
+			Mode:         mode,
+			State:        structs.CSIVolumeClaimStateUnpublishing,
+		},
+		WriteRequest: structs.WriteRequest{
+			Region:    c.alloc.Job.Region,
+			Namespace: c.alloc.Job.Namespace,
+			AuthToken: c.nodeSecret,
+		},
+	}
+	return c.rpcClient.RPC("CSIVolume.Unpublish",
+		req, &structs.CSIVolumeUnpublishResponse{})
 }
 
 type volumeAndRequest struct {
@@ -148,19 +1500,91 @@ type volumeAndRequest struct {
 	// When volumeAndRequest was returned from a volume claim, this field will be
 	// populated for plugins that require it.
 	publishContext map[string]string
+
+	// mountInfo is set when the volume's mount from before the client
+	// restarted was restored, so that it isn't claimed or mounted again
+	mountInfo *csimanager.MountInfo
+
+	// metadataPath is the metadata file written next to the volume's mount,
+	// if any, which is removed once the volume is released
+	metadataPath string
+
+	// unpublished is set once the volume's claim is released
+	unpublished bool
+
+	// claimIndex is the order the hook claimed the volume in, starting at
+	// 1, so that a drained allocation releases its volumes in reverse. It's
+	// persisted with the volume, so restored volumes keep their order.
+	claimIndex int
+
+	// removed is set once an update removes the volume from the task group.
+	// It's released when the allocation stops rather than when it's removed,
+	// as the tasks may still bind mount it until they restart.
+	removed bool
+
+	// secrets are the volume's secrets with their Vault references resolved.
+	// They are only passed to the node plugin, and never persisted with the
+	// volume. It is nil if the volume has no references.
+	secrets structs.CSISecrets
+}
+
+// volumeWithSecrets returns the volume to mount, with its resolved secrets.
+func (p *volumeAndRequest) volumeWithSecrets() *structs.CSIVolume {
+	if p.secrets == nil {
+		return p.volume
+	}
+	vol := p.volume.Copy()
+	vol.Secrets = p.secrets
+	return vol
 }
 
 // claimVolumesFromAlloc is used by the pre-run hook to fetch all of the volume
-// metadata and claim it for use by this alloc/node at the same time.
-func (c *csiHook) claimVolumesFromAlloc() (map[string]*volumeAndRequest, error) {
+// metadata and claim it for use by this alloc/node at the same time. If a
+// claim fails, the volumes claimed so far are returned along with the error
+// so that their claims can be released. Volumes whose mounts are restored
+// from before the client restarted keep their claims.
+func (c *csiHook) claimVolumesFromAlloc(ctx context.Context, restored map[string]*cstructs.CSIVolumeState) (map[string]*volumeAndRequest, error) {
+	tg, err := c.taskGroup()
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.volumeRequestsFromGroup(tg)
+	if err != nil {
+		return nil, err
+	}
+	return c.claimVolumes(ctx, result, restored)
+}
+
+// volumeRequestsFromGroup validates the task group's CSI volume requests and
+// returns them by alias, with the aliases resolving to the same volume
+// sharing a request.
+func (c *csiHook) volumeRequestsFromGroup(tg *structs.TaskGroup) (map[string]*volumeAndRequest, error) {
 	result := make(map[string]*volumeAndRequest)
-	tg := c.alloc.Job.LookupTaskGroup(c.alloc.TaskGroup)
+
+	if err := c.checkVolumeLimit(tg); err != nil {
+		return nil, err
+	}
 
 	// Initially, populate the result map with all of the requests
 	for alias, volumeRequest := range tg.Volumes {
 
 		if volumeRequest.Type == structs.VolumeTypeCSI {
 
+			if err := validateCSIModes(alias, volumeRequest); err != nil {
+				return nil, err
+			}
+
+			if volumeRequest.MountOptions != nil {
+				opts, err := normalizeMountOptions(volumeRequest.MountOptions)
+				if err != nil {
+					return nil, &csiModeError{alias: alias, source: volumeRequest.Source, reason: err.Error()}
+				}
+
+				// Don't modify the job's request, which may be shared
+				volumeRequest = volumeRequest.Copy()
+				volumeRequest.MountOptions = opts
+			}
+
 			for _, task := range tg.Tasks {
 				caps, err := c.taskCapabilityGetter.GetTaskDriverCapabilities(task.Name)
 				if err != nil {
@@ -173,61 +1597,625 @@ func (c *csiHook) claimVolumesFromAlloc() (map[string]*volumeAndRequest, error)
 				}
 			}
 
+			if volumeRequest.PerAlloc && c.alloc.DeploymentStatus.IsCanary() && !c.perAllocCanaries {
+				return nil, &csiPerAllocCanaryError{alias: alias, source: volumeRequest.Source}
+			}
+
 			result[alias] = &volumeAndRequest{request: volumeRequest}
 		}
 	}
 
-	// Iterate over the result map and upsert the volume field as each volume gets
-	// claimed by the server.
-	for alias, pair := range result {
+	c.dedupeVolumeRequests(result)
+	return result, nil
+}
+
+// claimVolumes claims the requested volumes, setting each request's volume
+// and publish context. If a claim fails, the volumes are returned along with
+// the error so that the claims made can be released. Volumes whose mounts are
+// restored from before the client restarted keep their claims.
+func (c *csiHook) claimVolumes(ctx context.Context, result map[string]*volumeAndRequest, restored map[string]*cstructs.CSIVolumeState) (map[string]*volumeAndRequest, error) {
+	labels := c.claimLabels()
+
+	// The claims carry the topologies the node's plugins fingerprinted, so
+	// that the server rejects volumes that aren't accessible from them
+	var topologies map[string]*structs.CSITopology
+	if c.claimTopology {
+		topologies = c.csimanager.NodeTopologies()
+	}
+
+	// Volumes claimed now are ordered after the restored ones
+	for _, state := range restored {
+		if state.ClaimIndex > c.claims {
+			c.claims = state.ClaimIndex
+		}
+	}
+
+	// The claims of the volumes that aren't restored are prepared first, so
+	// that they can be sent to the server together
+	var pending []*pendingClaim
+	claimed := make(map[*volumeAndRequest]struct{}, len(result))
+	for _, alias := range sortedAliases(result) {
+		pair := result[alias]
+		if _, ok := claimed[pair]; ok {
+			continue
+		}
+		claimed[pair] = struct{}{}
+
+		if err := c.claimWrite(alias, pair.request); err != nil {
+			c.emitFailure(alias, "", fmt.Sprintf("Failed to claim volume %q", alias), err)
+			return result, err
+		}
+
+		if state := restored[alias]; state != nil && c.restoreVolume(ctx, alias, pair, state) {
+			pair.claimIndex = state.ClaimIndex
+			if pair.claimIndex == 0 {
+				c.claims++
+				pair.claimIndex = c.claims
+			}
+			if err := c.capacityBudget.Reserve(c.alloc.ID, pair.volume); err != nil {
+				c.emitFailure(alias, "", fmt.Sprintf("Failed to claim volume %q", alias), err)
+				return result, err
+			}
+			continue
+		}
+		c.claims++
+		pair.claimIndex = c.claims
+
 		claimType := structs.CSIVolumeClaimWrite
 		if pair.request.ReadOnly {
 			claimType = structs.CSIVolumeClaimRead
 		}
 
-		source := pair.request.Source
-		if pair.request.PerAlloc {
-			source = source + structs.AllocSuffix(c.alloc.Name)
+		c.emitEvent(alias, "", fmt.Sprintf("Claiming volume %q", alias))
+
+		// The claim is recorded before it's sent, so that a claim
+		// interrupted by a restart is released if the allocation stops
+		if c.idempotencyKeys {
+			c.recordVolume(alias, pair)
 		}
 
-		req := &structs.CSIVolumeClaimRequest{
-			VolumeID:       source,
-			AllocationID:   c.alloc.ID,
-			NodeID:         c.alloc.NodeID,
-			Claim:          claimType,
-			AccessMode:     pair.request.AccessMode,
-			AttachmentMode: pair.request.AttachmentMode,
-			WriteRequest: structs.WriteRequest{
-				Region:    c.alloc.Job.Region,
-				Namespace: c.alloc.Job.Namespace,
-				AuthToken: c.nodeSecret,
+		pending = append(pending, &pendingClaim{
+			alias: alias,
+			pair:  pair,
+			req: &structs.CSIVolumeClaimRequest{
+				VolumeID:       c.volumeSource(pair.request),
+				AllocationID:   c.alloc.ID,
+				NodeID:         c.alloc.NodeID,
+				Claim:          claimType,
+				AccessMode:     pair.request.AccessMode,
+				AttachmentMode: pair.request.AttachmentMode,
+				Labels:         labels,
+				NodeTopologies: topologies,
+				WriteRequest: structs.WriteRequest{
+					Region:    c.alloc.Job.Region,
+					Namespace: c.alloc.Job.Namespace,
+					AuthToken: c.nodeSecret,
+				},
 			},
-		}
+		})
+	}
 
-		var resp structs.CSIVolumeClaimResponse
-		if err := c.rpcClient.RPC("CSIVolume.Claim", req, &resp); err != nil {
-			return nil, fmt.Errorf("could not claim volume %s: %w", req.VolumeID, err)
+	// The volumes claimed before a claim failed are kept in the result, so
+	// that their claims are released
+	resps, claimErr := c.sendClaims(ctx, pending)
+	for i, resp := range resps {
+		claim := pending[i]
+		if resp.Volume == nil {
+			err := fmt.Errorf("Unexpected nil volume returned for ID: %v", claim.pair.request.Source)
+			c.emitFailure(claim.alias, "", fmt.Sprintf("Failed to claim volume %q", claim.alias), err)
+			if claimErr == nil {
+				claimErr = err
+			}
+			continue
 		}
 
-		if resp.Volume == nil {
-			return nil, fmt.Errorf("Unexpected nil volume returned for ID: %v", pair.request.Source)
+		claim.pair.volume = resp.Volume
+		claim.pair.publishContext = resp.PublishContext
+		if c.idempotencyKeys {
+			c.recordVolume(claim.alias, claim.pair)
 		}
+	}
+	if claimErr != nil {
+		return result, claimErr
+	}
 
-		result[alias].request = pair.request
-		result[alias].volume = resp.Volume
-		result[alias].publishContext = resp.PublishContext
+	for _, claim := range pending {
+		if err := c.capacityBudget.Reserve(c.alloc.ID, claim.pair.volume); err != nil {
+			c.emitFailure(claim.alias, "", fmt.Sprintf("Failed to claim volume %q", claim.alias), err)
+			return result, err
+		}
 	}
 
 	return result, nil
 }
 
-func (c *csiHook) shouldRun() bool {
-	tg := c.alloc.Job.LookupTaskGroup(c.alloc.TaskGroup)
+// pendingClaim is a volume claim to send to the server
+type pendingClaim struct {
+	alias string
+	pair  *volumeAndRequest
+	req   *structs.CSIVolumeClaimRequest
+}
+
+// sendClaims sends the claims to the server, returning the responses of the
+// claims that succeeded, in order. Several claims are sent in a single batch
+// request, unless the server doesn't support it, in which case each claim is
+// sent on its own and the claims made before one fails are returned with its
+// error.
+func (c *csiHook) sendClaims(ctx context.Context, claims []*pendingClaim) ([]*structs.CSIVolumeClaimResponse, error) {
+	if len(claims) > 1 && c.claimBatch.Supported() {
+		resps, err := c.sendClaimBatch(ctx, claims)
+		if !structs.IsErrUnknownMethod(err) {
+			return resps, err
+		}
+		c.logger.Debug("servers don't support batched volume claims, claiming volumes one at a time")
+		c.claimBatch.SetUnsupported()
+	}
+
+	resps := make([]*structs.CSIVolumeClaimResponse, 0, len(claims))
+	for _, claim := range claims {
+		resp, err := c.sendClaim(ctx, claim)
+		if err != nil {
+			return resps, err
+		}
+		resps = append(resps, resp)
+	}
+	return resps, nil
+}
+
+// sendClaim sends a single volume claim to the server.
+func (c *csiHook) sendClaim(ctx context.Context, claim *pendingClaim) (*structs.CSIVolumeClaimResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var resp structs.CSIVolumeClaimResponse
+	claimCtx, cancel := context.WithTimeout(ctx, c.claimTimeout)
+	err := c.claimVolume(claimCtx, claim.req, &resp)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v", c.claimTimeout)
+	}
+	if err != nil {
+		err = fmt.Errorf("could not claim volume %s: %w", claim.req.VolumeID, err)
+		c.emitFailure(claim.alias, "", fmt.Sprintf("Failed to claim volume %q", claim.alias), err)
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// sendClaimBatch sends the volume claims to the server in a single request.
+// The server makes every claim or none, so either every response or an
+// error is returned. Errors due to the server not supporting batched claims
+// are returned as is, for the caller to fall back to single claims.
+func (c *csiHook) sendClaimBatch(ctx context.Context, claims []*pendingClaim) ([]*structs.CSIVolumeClaimResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	aliases := make([]string, 0, len(claims))
+	volumeIDs := make([]string, 0, len(claims))
+	req := &structs.CSIVolumeBatchClaimRequest{
+		Claims: make([]*structs.CSIVolumeClaimRequest, 0, len(claims)),
+		WriteRequest: structs.WriteRequest{
+			Region:    c.alloc.Job.Region,
+			Namespace: c.alloc.Job.Namespace,
+			AuthToken: c.nodeSecret,
+		},
+	}
+	for _, claim := range claims {
+		aliases = append(aliases, claim.alias)
+		volumeIDs = append(volumeIDs, claim.req.VolumeID)
+		req.Claims = append(req.Claims, claim.req)
+	}
+
+	var resp structs.CSIVolumeBatchClaimResponse
+	claimCtx, cancel := context.WithTimeout(ctx, c.claimTimeout)
+	err := c.claimVolumeBatch(claimCtx, req, &resp)
+	cancel()
+	if err != nil && (ctx.Err() != nil || structs.IsErrUnknownMethod(err)) {
+		return nil, err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v", c.claimTimeout)
+	}
+	if err == nil && len(resp.Claims) != len(claims) {
+		err = fmt.Errorf("expected %d claim responses, got %d", len(claims), len(resp.Claims))
+	}
+	if err != nil {
+		err = fmt.Errorf("could not claim volumes %s: %w", strings.Join(volumeIDs, ", "), err)
+		c.emitFailure(strings.Join(aliases, ","), "",
+			fmt.Sprintf("Failed to claim volumes %q", strings.Join(aliases, ", ")), err)
+		return nil, err
+	}
+	return resp.Claims, nil
+}
+
+// claimWrite records the allocation's write claim on the requested volume
+// with the other allocations on the node, returning an error if another
+// allocation already holds a write claim on the single-writer volume. Read
+// claims are never recorded.
+func (c *csiHook) claimWrite(alias string, req *structs.VolumeRequest) error {
+	if req.ReadOnly {
+		return nil
+	}
+
+	err := c.writeClaims.Claim(c.alloc.ID, c.alloc.Job.Namespace, c.volumeSource(req), req.AccessMode)
+	if err != nil {
+		return fmt.Errorf("could not claim volume %q for writing: %w", alias, err)
+	}
+	return nil
+}
+
+// resolveVolumeSecrets resolves the Vault references among the secrets of
+// the claimed volumes that must be mounted. References are read with a Vault
+// token derived for the first task of the group with a vault block, so with
+// that task's policies. The token is only derived if a volume has a reference.
+func (c *csiHook) resolveVolumeSecrets(volumes map[string]*volumeAndRequest) error {
+	var token string
+	resolved := make(map[*volumeAndRequest]struct{}, len(volumes))
+	for _, alias := range sortedAliases(volumes) {
+		pair := volumes[alias]
+		if _, ok := resolved[pair]; ok || pair.mountInfo != nil || pair.volume == nil {
+			continue
+		}
+		resolved[pair] = struct{}{}
+
+		if !hasVaultSecrets(pair.volume.Secrets) {
+			continue
+		}
+
+		if token == "" {
+			var err error
+			if token, err = c.deriveVaultToken(); err != nil {
+				err = fmt.Errorf("could not derive Vault token for secrets of volume %q: %w", alias, err)
+				c.emitFailure(alias, pair.volume.PluginID, fmt.Sprintf("Failed to resolve secrets of volume %q", alias), err)
+				return err
+			}
+		}
+
+		secrets := make(structs.CSISecrets, len(pair.volume.Secrets))
+		for name, value := range pair.volume.Secrets {
+			if !strings.HasPrefix(value, csiVaultSecretPrefix) {
+				secrets[name] = value
+				continue
+			}
+			value, err := c.readVaultSecret(token, strings.TrimPrefix(value, csiVaultSecretPrefix))
+			if err != nil {
+				err = fmt.Errorf("could not resolve secret %q of volume %q: %w", name, alias, err)
+				c.emitFailure(alias, pair.volume.PluginID, fmt.Sprintf("Failed to resolve secrets of volume %q", alias), err)
+				return err
+			}
+			secrets[name] = value
+		}
+		pair.secrets = secrets
+	}
+	return nil
+}
+
+// hasVaultSecrets returns whether any of the secrets references Vault.
+func hasVaultSecrets(secrets structs.CSISecrets) bool {
+	for _, value := range secrets {
+		if strings.HasPrefix(value, csiVaultSecretPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// deriveVaultToken derives a Vault token for the first task of the group with
+// a vault block.
+func (c *csiHook) deriveVaultToken() (string, error) {
+	if c.vaultClient == nil {
+		return "", errors.New("Vault is not configured on the client")
+	}
+
+	tg, err := c.taskGroup()
+	if err != nil {
+		return "", err
+	}
+	for _, task := range tg.Tasks {
+		if task.Vault == nil {
+			continue
+		}
+		tokens, err := c.vaultClient.DeriveToken(c.alloc, []string{task.Name})
+		if err != nil {
+			return "", err
+		}
+		if tokens[task.Name] == "" {
+			return "", fmt.Errorf("no token derived for task %q", task.Name)
+		}
+		return tokens[task.Name], nil
+	}
+	return "", fmt.Errorf("no task of group %q has a vault block", tg.Name)
+}
+
+// readVaultSecret reads the key of the Vault secret referenced as
+// "<path>#<key>". The keys of KV version 2 secrets are read from their data.
+func (c *csiHook) readVaultSecret(token, ref string) (string, error) {
+	idx := strings.LastIndex(ref, "#")
+	if idx <= 0 || idx == len(ref)-1 {
+		return "", fmt.Errorf("invalid Vault reference %q, expected %s<path>#<key>", csiVaultSecretPrefix+ref, csiVaultSecretPrefix)
+	}
+	path, key := ref[:idx], ref[idx+1:]
+
+	secret, err := c.vaultClient.ReadSecret(token, path)
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("no secret at Vault path %q", path)
+	}
+
+	data := secret.Data
+	if _, ok := data[key]; !ok {
+		if nested, ok := data["data"].(map[string]interface{}); ok {
+			data = nested
+		}
+	}
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("no key %q in Vault secret %q", key, path)
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("key %q of Vault secret %q is not a string", key, path)
+	}
+	return value, nil
+}
+
+// claimVolume makes the CSIVolume.Claim RPC, retrying with exponential
+// backoff while it fails with transient errors. Other errors, such as for an
+// unknown volume, are returned without retrying.
+func (c *csiHook) claimVolume(ctx context.Context, req *structs.CSIVolumeClaimRequest, resp *structs.CSIVolumeClaimResponse) error {
+	return c.retryVolumeRPC(ctx, c.claimRetry, "claim", req.VolumeID, func() error {
+		release, err := c.opScheduler.Acquire(ctx, c.alloc.Job.Priority)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return c.rpcClient.RPC("CSIVolume.Claim", req, resp)
+	})
+}
+
+// claimVolumeBatch sends a batch of volume claims to the server, taking a
+// single slot of the operation scheduler for the whole batch.
+func (c *csiHook) claimVolumeBatch(ctx context.Context, req *structs.CSIVolumeBatchClaimRequest, resp *structs.CSIVolumeBatchClaimResponse) error {
+	volumeIDs := make([]string, 0, len(req.Claims))
+	for _, claim := range req.Claims {
+		volumeIDs = append(volumeIDs, claim.VolumeID)
+	}
+
+	return c.retryVolumeRPC(ctx, c.claimRetry, "claim_batch", strings.Join(volumeIDs, ","), func() error {
+		release, err := c.opScheduler.Acquire(ctx, c.alloc.Job.Priority)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return c.rpcClient.RPC("CSIVolume.ClaimBatch", req, resp)
+	})
+}
+
+// retryVolumeRPC calls rpc until it succeeds, fails with an error that isn't
+// transient, or the retry config's attempts run out, backing off
+// exponentially between attempts. A request that fails because the servers
+// don't know of the volume's node plugin on this client yet is also retried
+// for up to the plugin registration wait, whatever the attempts, as the
+// plugin may still be registering after the client started. The error of the
+// last attempt is returned, or ctx's error if it's done while backing off.
+func (c *csiHook) retryVolumeRPC(ctx context.Context, retry *clientconfig.RetryConfig, op, volumeID string, rpc func() error) error {
+	start := time.Now()
+	backoff := *retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err := rpc()
+		if err == nil {
+			return nil
+		}
+
+		// Attempts of 0 retries until the hook is cancelled. A node plugin
+		// that hasn't registered since the client started is waited for
+		attempts := *retry.Attempts
+		waitPlugin := structs.IsErrCSIPluginNotFingerprinted(err) && time.Since(start) < c.pluginRegistrationWait
+		if !waitPlugin && (!isRetryableVolumeError(err) || (attempts > 0 && attempt > attempts)) {
+			return err
+		}
+
+		c.logger.Warn("volume request failed, retrying", "operation", op, "volume_id", volumeID,
+			"attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if max := *retry.MaxBackoff; max > 0 && backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// isRetryableVolumeError returns whether a volume claim or unpublish failed
+// with an error that a later attempt may not hit: the servers had no leader
+// or couldn't be reached, or the controller plugin reported a transient
+// failure.
+func isRetryableVolumeError(err error) bool {
+	return structs.IsErrNoLeader(err) || lib.IsErrEOF(err) ||
+		structs.IsErrNotReadyForConsistentReads(err) ||
+		structs.IsErrCSIClientRPCRetryable(err) ||
+		structs.IsErrNoServers(err)
+}
+
+// checkVolumeLimit returns an error if the task group requests more CSI
+// volumes than an allocation may, so that the allocation fails before any
+// volume is claimed.
+func (c *csiHook) checkVolumeLimit(tg *structs.TaskGroup) error {
+	requested := 0
+	for _, req := range tg.Volumes {
+		if req.Type == structs.VolumeTypeCSI {
+			requested++
+		}
+	}
+	if requested <= c.maxVolumes {
+		return nil
+	}
+
+	metrics.IncrCounterWithLabels([]string{"client", "csi", "limit_rejections"}, 1,
+		[]metrics.Label{{Name: "limit", Value: "volumes_per_alloc"}})
+	return fmt.Errorf("allocation requests %d CSI volumes, over the limit of %d per allocation",
+		requested, c.maxVolumes)
+}
+
+// claimLabels returns the labels to attach to volume claims, read from the
+// configured environment variables of the client. Labels whose environment
+// variable is unset are omitted.
+func (c *csiHook) claimLabels() map[string]string {
+	if len(c.claimLabelEnv) == 0 {
+		return nil
+	}
+
+	labels := make(map[string]string, len(c.claimLabelEnv))
+	for label, env := range c.claimLabelEnv {
+		if val, ok := os.LookupEnv(env); ok {
+			labels[label] = val
+		}
+	}
+	return labels
+}
+
+// dedupeVolumeRequests merges requests under different aliases that resolve
+// to the same volume, so that the volume is claimed once with the access all
+// of the aliases need. Merged aliases share a single
+// volumeAndRequest. per_alloc requests are never merged, nor are requests
+// with different attachment modes, as they can't share a mount.
+func (c *csiHook) dedupeVolumeRequests(result map[string]*volumeAndRequest) {
+	type volumeKey struct {
+		source         string
+		attachmentMode structs.CSIVolumeAttachmentMode
+	}
+
+	merged := make(map[volumeKey]*volumeAndRequest)
+	for _, alias := range sortedAliases(result) {
+		req := result[alias].request
+		if req.PerAlloc {
+			continue
+		}
+
+		key := volumeKey{source: c.volumeSource(req), attachmentMode: req.AttachmentMode}
+		pair, ok := merged[key]
+		if !ok {
+			merged[key] = result[alias]
+			continue
+		}
+
+		// Don't modify the job's request, which may be shared
+		mergedReq := pair.request.Copy()
+		mergedReq.ReadOnly = mergedReq.ReadOnly && req.ReadOnly
+		mergedReq.AccessMode = mergeAccessModes(mergedReq.AccessMode, req.AccessMode)
+		if mergedReq.MountOptions == nil {
+			mergedReq.MountOptions = req.MountOptions.Copy()
+		}
+		pair.request = mergedReq
+		result[alias] = pair
+	}
+}
+
+// csiAccessScope is the access an access mode grants: whether the volume can
+// be attached to several nodes, and how many writers it allows.
+type csiAccessScope struct {
+	multiNode bool
+	writers   int
+}
+
+// csiAccessModeScopes are the scopes of the known access modes. A single
+// node's writers aren't counted, so single-node-writer allows one.
+var csiAccessModeScopes = map[structs.CSIVolumeAccessMode]csiAccessScope{
+	structs.CSIVolumeAccessModeSingleNodeReader:      {multiNode: false, writers: 0},
+	structs.CSIVolumeAccessModeSingleNodeWriter:      {multiNode: false, writers: 1},
+	structs.CSIVolumeAccessModeMultiNodeReader:       {multiNode: true, writers: 0},
+	structs.CSIVolumeAccessModeMultiNodeSingleWriter: {multiNode: true, writers: 1},
+	structs.CSIVolumeAccessModeMultiNodeMultiWriter:  {multiNode: true, writers: 2},
+}
+
+// mergeAccessModes returns the narrowest access mode granting the access of
+// both modes. Attaching to several nodes and writing are merged separately,
+// so that single-node-writer and multi-node-reader-only merge into
+// multi-node-single-writer rather than either losing the other's access.
+func mergeAccessModes(a, b structs.CSIVolumeAccessMode) structs.CSIVolumeAccessMode {
+	scopeA, okA := csiAccessModeScopes[a]
+	scopeB, okB := csiAccessModeScopes[b]
+	switch {
+	case !okA:
+		return b
+	case !okB:
+		return a
+	}
+
+	merged := csiAccessScope{
+		multiNode: scopeA.multiNode || scopeB.multiNode,
+		writers:   scopeA.writers,
+	}
+	if scopeB.writers > merged.writers {
+		merged.writers = scopeB.writers
+	}
+	if !merged.multiNode && merged.writers > 1 {
+		merged.writers = 1
+	}
+	for mode, scope := range csiAccessModeScopes {
+		if scope == merged {
+			return mode
+		}
+	}
+	return a
+}
+
+// sortedAliases returns the aliases of the volume requests in a stable order.
+func sortedAliases(volumes map[string]*volumeAndRequest) []string {
+	aliases := make([]string, 0, len(volumes))
+	for alias := range volumes {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// volumeSource returns the ID of the volume that satisfies the request. For
+// per_alloc volumes this is the source suffixed with the allocation's name
+// index. Canaries are placed at the name index of the allocation they will
+// replace, so a canary resolves to that allocation's volume.
+func (c *csiHook) volumeSource(req *structs.VolumeRequest) string {
+	source := req.Source
+	if req.PerAlloc {
+		source = source + structs.AllocSuffix(c.alloc.Name)
+	}
+	return source
+}
+
+// shouldRun returns whether the allocation's task group requests CSI
+// volumes, or an error if the job doesn't have the task group.
+func (c *csiHook) shouldRun() (bool, error) {
+	tg, err := c.taskGroup()
+	if err != nil {
+		return false, err
+	}
 	for _, vol := range tg.Volumes {
 		if vol.Type == structs.VolumeTypeCSI {
-			return true
+			return true, nil
 		}
 	}
 
-	return false
+	return false, nil
+}
+
+// taskGroup returns the allocation's task group, or an error if the job
+// doesn't have it, as when the allocation and its job don't match after an
+// update.
+func (c *csiHook) taskGroup() (*structs.TaskGroup, error) {
+	tg := c.alloc.Job.LookupTaskGroup(c.alloc.TaskGroup)
+	if tg == nil {
+		return nil, fmt.Errorf("task group %q not found in job", c.alloc.TaskGroup)
+	}
+	return tg, nil
 }
@@ -2,28 +2,39 @@ package allocrunner
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
+	metrics "github.com/armon/go-metrics"
 	"github.com/stretchr/testify/require"
 
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/pluginmanager"
 	"github.com/hashicorp/nomad/client/pluginmanager/csimanager"
+	cstate "github.com/hashicorp/nomad/client/state"
 	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/client/vaultclient"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/helper/testlog"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/plugins/drivers"
+	"github.com/hashicorp/nomad/testutil"
+	vaultapi "github.com/hashicorp/vault/api"
 )
 
 var _ interfaces.RunnerPrerunHook = (*csiHook)(nil)
 var _ interfaces.RunnerPostrunHook = (*csiHook)(nil)
-
-// TODO https://github.com/hashicorp/nomad/issues/11786
-// we should implement Update as well
-// var _ interfaces.RunnerUpdateHook = (*csiHook)(nil)
+var _ interfaces.RunnerUpdateHook = (*csiHook)(nil)
+var _ interfaces.RunnerDrainShutdownHook = (*csiHook)(nil)
 
 func TestCSIHook(t *testing.T) {
 
@@ -88,44 +99,113 @@ func TestCSIHook(t *testing.T) {
 			expectedUnpublishCalls: 1,
 		},
 
-		// TODO: this won't actually work on the client.
-		// https://github.com/hashicorp/nomad/issues/11798
-		//
-		// {
-		// 	name: "one source volume mounted read-only twice",
-		// 	volumeRequests: map[string]*structs.VolumeRequest{
-		// 		"vol0": {
-		// 			Name:           "vol0",
-		// 			Type:           structs.VolumeTypeCSI,
-		// 			Source:         "testvolume0",
-		// 			ReadOnly:       true,
-		// 			AccessMode:     structs.CSIVolumeAccessModeMultiNodeReader,
-		// 			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
-		// 			MountOptions:   &structs.CSIMountOptions{},
-		// 			PerAlloc:       false,
-		// 		},
-		// 		"vol1": {
-		// 			Name:           "vol1",
-		// 			Type:           structs.VolumeTypeCSI,
-		// 			Source:         "testvolume0",
-		// 			ReadOnly:       false,
-		// 			AccessMode:     structs.CSIVolumeAccessModeMultiNodeReader,
-		// 			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
-		// 			MountOptions:   &structs.CSIMountOptions{},
-		// 			PerAlloc:       false,
-		// 		},
-		// 	},
-		// 	expectedMounts: map[string]*csimanager.MountInfo{
-		// 		"vol0": &csimanager.MountInfo{Source: fmt.Sprintf(
-		// 			"test-alloc-dir/%s/testvolume0/ro-file-system-multi-node-reader-only", alloc.ID)},
-		// 		"vol1": &csimanager.MountInfo{Source: fmt.Sprintf(
-		// 			"test-alloc-dir/%s/testvolume0/ro-file-system-multi-node-reader-only", alloc.ID)},
-		// 	},
-		// 	expectedMountCalls:     1,
-		// 	expectedUnmountCalls:   0, // not until this is done client-side
-		// 	expectedClaimCalls:     1,
-		// 	expectedUnpublishCalls: 1,
-		// },
+		{
+			name: "one source volume mounted read-only and read-write",
+			volumeRequests: map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+					PerAlloc:       false,
+				},
+				"vol1": {
+					Name:           "vol1",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       false,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+					PerAlloc:       false,
+				},
+			},
+			expectedMounts: map[string]*csimanager.MountInfo{
+				"vol0": &csimanager.MountInfo{Source: fmt.Sprintf(
+					"test-alloc-dir/%s/testvolume0/rw-file-system-single-node-writer", alloc.ID)},
+				"vol1": &csimanager.MountInfo{Source: fmt.Sprintf(
+					"test-alloc-dir/%s/testvolume0/rw-file-system-single-node-writer", alloc.ID)},
+			},
+			expectedMountCalls:     1,
+			expectedUnmountCalls:   0, // not until this is done client-side
+			expectedClaimCalls:     1,
+			expectedUnpublishCalls: 1,
+		},
+
+		{
+			name: "one source volume mounted multi-node read-only and single-node read-write",
+			volumeRequests: map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeMultiNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+					PerAlloc:       false,
+				},
+				"vol1": {
+					Name:           "vol1",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       false,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+					PerAlloc:       false,
+				},
+			},
+			expectedMounts: map[string]*csimanager.MountInfo{
+				"vol0": &csimanager.MountInfo{Source: fmt.Sprintf(
+					"test-alloc-dir/%s/testvolume0/rw-file-system-multi-node-single-writer", alloc.ID)},
+				"vol1": &csimanager.MountInfo{Source: fmt.Sprintf(
+					"test-alloc-dir/%s/testvolume0/rw-file-system-multi-node-single-writer", alloc.ID)},
+			},
+			expectedMountCalls:     1,
+			expectedUnmountCalls:   0, // not until this is done client-side
+			expectedClaimCalls:     1,
+			expectedUnpublishCalls: 1,
+		},
+
+		{
+			name: "one per-alloc source volume requested twice",
+			volumeRequests: map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+					PerAlloc:       true,
+				},
+				"vol1": {
+					Name:           "vol1",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       false,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+					PerAlloc:       true,
+				},
+			},
+			expectedMounts: map[string]*csimanager.MountInfo{
+				"vol0": &csimanager.MountInfo{Source: fmt.Sprintf(
+					"test-alloc-dir/%s/testvolume0/ro-file-system-single-node-reader-only", alloc.ID)},
+				"vol1": &csimanager.MountInfo{Source: fmt.Sprintf(
+					"test-alloc-dir/%s/testvolume0/rw-file-system-single-node-writer", alloc.ID)},
+			},
+			expectedMountCalls:     2,
+			expectedUnmountCalls:   0, // not until this is done client-side
+			expectedClaimCalls:     2,
+			expectedUnpublishCalls: 2,
+		},
 	}
 
 	for i := range testcases {
@@ -133,7 +213,7 @@ func TestCSIHook(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			alloc.Job.TaskGroups[0].Volumes = tc.volumeRequests
 
-			callCounts := map[string]int{}
+			callCounts := newCallCounter()
 			mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
 			rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
 			ar := mockAllocRunner{
@@ -143,114 +223,3522 @@ func TestCSIHook(t *testing.T) {
 					MountConfigs: drivers.MountConfigSupportAll,
 				},
 			}
-			hook := newCSIHook(alloc, logger, mgr, rpcer, ar, ar, "secret")
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               logger,
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              &mockEventEmitter{},
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         clientconfig.DefaultConfig(),
+			})
 			require.NotNil(t, hook)
 
-			require.NoError(t, hook.Prerun())
+			require.NoError(t, hook.Prerun(context.Background()))
 			mounts := ar.GetAllocHookResources().GetCSIMounts()
 			require.NotNil(t, mounts)
 			require.Equal(t, tc.expectedMounts, mounts)
 
-			require.NoError(t, hook.Postrun())
-			require.Equal(t, tc.expectedMountCalls, callCounts["mount"])
-			require.Equal(t, tc.expectedUnmountCalls, callCounts["unmount"])
-			require.Equal(t, tc.expectedClaimCalls, callCounts["claim"])
-			require.Equal(t, tc.expectedUnpublishCalls, callCounts["unpublish"])
+			require.NoError(t, hook.Postrun(context.Background()))
+			require.Equal(t, tc.expectedMountCalls, callCounts.get("mount"))
+			require.Equal(t, tc.expectedUnmountCalls, callCounts.get("unmount"))
+			require.Equal(t, tc.expectedClaimCalls, callCounts.get("claim"))
+			require.Equal(t, tc.expectedUnpublishCalls, callCounts.get("unpublish"))
 
 		})
 	}
 
 }
 
-// HELPERS AND MOCKS
+func TestCSIHook_PerAllocCanary(t *testing.T) {
 
-func testVolume(id string) *structs.CSIVolume {
-	vol := structs.NewCSIVolume(id, 0)
-	vol.Schedulable = true
-	vol.RequestedCapabilities = []*structs.CSIVolumeCapability{
+	logger := testlog.HCLogger(t)
+
+	testcases := []struct {
+		name          string
+		options       map[string]string
+		expectErr     bool
+		expectedClaim string
+	}{
 		{
-			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
-			AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+			name:      "canary rejected by default",
+			expectErr: true,
 		},
 		{
-			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
-			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			name:          "canary mapped onto replaced alloc volume",
+			options:       map[string]string{"csi.per_alloc_canaries": "true"},
+			expectedClaim: "testvolume0[2]",
 		},
 	}
-	return vol
-}
 
-type mockRPCer struct {
-	alloc      *structs.Allocation
-	callCounts map[string]int
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Name = "my-job.web[2]"
+			alloc.DeploymentStatus = &structs.AllocDeploymentStatus{Canary: true}
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+					PerAlloc:       true,
+				},
+			}
+
+			conf := clientconfig.DefaultConfig()
+			conf.Options = tc.options
+
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+			rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               logger,
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              &mockEventEmitter{},
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         conf,
+			})
+
+			err := hook.Prerun(context.Background())
+			if tc.expectErr {
+				var canaryErr *csiPerAllocCanaryError
+				require.ErrorAs(t, err, &canaryErr)
+				require.Equal(t, "vol0", canaryErr.alias)
+				require.Equal(t, 0, callCounts.get("claim"))
+				require.Equal(t, 0, callCounts.get("mount"))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, 1, callCounts.get("claim"))
+			require.Equal(t, tc.expectedClaim, hook.volumeRequests["vol0"].volume.ID)
+		})
+	}
 }
 
-// RPC mocks the server RPCs, acting as though any request succeeds
-func (r mockRPCer) RPC(method string, args interface{}, reply interface{}) error {
-	switch method {
-	case "CSIVolume.Claim":
-		r.callCounts["claim"]++
-		req := args.(*structs.CSIVolumeClaimRequest)
-		vol := testVolume(req.VolumeID)
-		err := vol.Claim(req.ToClaim(), r.alloc)
-		if err != nil {
-			return err
+func TestCSIHook_VaultSecrets(t *testing.T) {
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Tasks[0].Vault = &structs.Vault{Policies: []string{"csi"}}
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+		"vol0": {
+			Name:           "vol0",
+			Type:           structs.VolumeTypeCSI,
+			Source:         "testvolume0",
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		},
+	}
+	taskName := alloc.Job.TaskGroups[0].Tasks[0].Name
+
+	// fakeVault serves a KV version 1 and a KV version 2 secret to the token
+	// derived for the task
+	fakeVault := func() *vaultclient.MockVaultClient {
+		vc := vaultclient.NewMockVaultClient()
+		vc.DeriveTokenFn = func(a *structs.Allocation, tasks []string) (map[string]string, error) {
+			return map[string]string{tasks[0]: "task-token-" + tasks[0]}, nil
+		}
+		vc.ReadSecretFn = func(token, path string) (*vaultapi.Secret, error) {
+			if token != "task-token-"+taskName {
+				return nil, fmt.Errorf("permission denied")
+			}
+			switch path {
+			case "kv/ceph":
+				return &vaultapi.Secret{Data: map[string]interface{}{"userKey": "hunter2"}}, nil
+			case "secret/data/ceph":
+				return &vaultapi.Secret{Data: map[string]interface{}{
+					"data":     map[string]interface{}{"adminKey": "correct-horse"},
+					"metadata": map[string]interface{}{"version": 1},
+				}}, nil
+			}
+			return nil, nil
 		}
+		return vc
+	}
 
-		resp := reply.(*structs.CSIVolumeClaimResponse)
-		resp.PublishContext = map[string]string{}
-		resp.Volume = vol
-		resp.QueryMeta = structs.QueryMeta{}
-	case "CSIVolume.Unpublish":
-		r.callCounts["unpublish"]++
-		resp := reply.(*structs.CSIVolumeUnpublishResponse)
-		resp.QueryMeta = structs.QueryMeta{}
-	default:
-		return fmt.Errorf("unexpected method")
+	newHook := func(secrets structs.CSISecrets, vc vaultclient.VaultClient, db cstate.StateDB) (*csiHook, *mockSecretsVolumeMounter, *callCounter) {
+		callCounts := newCallCounter()
+		mounter := &mockSecretsVolumeMounter{
+			mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+			secrets:           map[string]structs.CSISecrets{},
+		}
+		rpcer := mockRPCer{alloc: alloc, callCounts: callCounts, secrets: secrets}
+		ar := mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mockPluginManager{mounter: mounter},
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			vaultClient:          vc,
+			stateDB:              db,
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		return hook, mounter, callCounts
 	}
-	return nil
-}
 
-type mockVolumeMounter struct {
-	callCounts map[string]int
-}
+	t.Run("references are resolved", func(t *testing.T) {
+		db := cstate.NewMemDB(testlog.HCLogger(t))
+		hook, mounter, _ := newHook(structs.CSISecrets{
+			"userKey":  "vault:kv/ceph#userKey",
+			"adminKey": "vault:secret/data/ceph#adminKey",
+			"pool":     "rbd",
+		}, fakeVault(), db)
+		require.NoError(t, hook.Prerun(context.Background()))
 
-func (vm mockVolumeMounter) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions, publishContext map[string]string) (*csimanager.MountInfo, error) {
-	vm.callCounts["mount"]++
-	return &csimanager.MountInfo{
-		Source: filepath.Join("test-alloc-dir", alloc.ID, vol.ID, usageOpts.ToFS()),
-	}, nil
-}
-func (vm mockVolumeMounter) UnmountVolume(ctx context.Context, volID, remoteID, allocID string, usageOpts *csimanager.UsageOptions) error {
-	vm.callCounts["unmount"]++
-	return nil
-}
+		require.Equal(t, structs.CSISecrets{
+			"userKey":  "hunter2",
+			"adminKey": "correct-horse",
+			"pool":     "rbd",
+		}, mounter.secrets["testvolume0"])
 
-type mockPluginManager struct {
-	mounter mockVolumeMounter
-}
+		// The resolved secrets are neither persisted nor kept with the volume
+		vols, err := db.GetCSIVolumes(alloc.ID)
+		require.NoError(t, err)
+		require.Empty(t, vols["vol0"].Volume.Secrets)
+		require.Equal(t, "vault:kv/ceph#userKey", hook.volumeRequests["vol0"].volume.Secrets["userKey"])
+	})
 
-func (mgr mockPluginManager) MounterForPlugin(ctx context.Context, pluginID string) (csimanager.VolumeMounter, error) {
-	return mgr.mounter, nil
-}
+	t.Run("volumes without references don't derive a token", func(t *testing.T) {
+		vc := fakeVault()
+		vc.DeriveTokenFn = func(*structs.Allocation, []string) (map[string]string, error) {
+			return nil, fmt.Errorf("unexpected token derivation")
+		}
+		hook, mounter, _ := newHook(structs.CSISecrets{"pool": "rbd"}, vc, cstate.NoopDB{})
+		require.NoError(t, hook.Prerun(context.Background()))
+		require.Equal(t, structs.CSISecrets{"pool": "rbd"}, mounter.secrets["testvolume0"])
+	})
 
-// no-op methods to fulfill the interface
-func (mgr mockPluginManager) PluginManager() pluginmanager.PluginManager { return nil }
-func (mgr mockPluginManager) Shutdown()                                  {}
+	cases := []struct {
+		name    string
+		secret  string
+		vault   func() vaultclient.VaultClient
+		noVault bool
+		err     string
+	}{
+		{
+			name:   "missing key",
+			secret: "vault:kv/ceph#adminKey",
+			err:    `no key "adminKey" in Vault secret "kv/ceph"`,
+		},
+		{
+			name:   "missing secret",
+			secret: "vault:kv/missing#userKey",
+			err:    `no secret at Vault path "kv/missing"`,
+		},
+		{
+			name:   "invalid reference",
+			secret: "vault:kv/ceph",
+			err:    `invalid Vault reference "vault:kv/ceph", expected vault:<path>#<key>`,
+		},
+		{
+			name:    "no task with a vault block",
+			secret:  "vault:kv/ceph#userKey",
+			noVault: true,
+			err:     `no task of group "web" has a vault block`,
+		},
+		{
+			name:   "vault not configured",
+			secret: "vault:kv/ceph#userKey",
+			vault:  func() vaultclient.VaultClient { return nil },
+			err:    "Vault is not configured on the client",
+		},
+	}
 
-type mockAllocRunner struct {
-	res  *cstructs.AllocHookResources
-	caps *drivers.Capabilities
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var vc vaultclient.VaultClient = fakeVault()
+			if tc.vault != nil {
+				vc = tc.vault()
+			}
+			if tc.noVault {
+				alloc.Job.TaskGroups[0].Tasks[0].Vault = nil
+				defer func() { alloc.Job.TaskGroups[0].Tasks[0].Vault = &structs.Vault{Policies: []string{"csi"}} }()
+			}
+
+			hook, mounter, callCounts := newHook(structs.CSISecrets{"userKey": tc.secret}, vc, cstate.NoopDB{})
+			err := hook.Prerun(context.Background())
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+			require.NotContains(t, err.Error(), "hunter2")
+
+			// The volume isn't mounted and its claim is released
+			require.Empty(t, mounter.secrets)
+			require.Equal(t, 1, callCounts.get("unpublish"))
+		})
+	}
 }
 
-func (ar mockAllocRunner) GetAllocHookResources() *cstructs.AllocHookResources {
-	return ar.res
+func TestCSIHook_MountTimeout(t *testing.T) {
+
+	alloc := mock.Alloc()
+	logger := testlog.HCLogger(t)
+
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+	for _, name := range []string{"vol0", "vol1"} {
+		alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         "test" + name,
+			ReadOnly:       true,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			MountOptions:   &structs.CSIMountOptions{},
+		}
+	}
+
+	conf := clientconfig.DefaultConfig()
+	conf.CSIVolumeMountTimeout = 100 * time.Millisecond
+
+	callCounts := newCallCounter()
+	mgr := mockPluginManager{mounter: mockBlockingVolumeMounter{
+		mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+		succeed:           1,
+	}}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               logger,
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         conf,
+	})
+
+	start := time.Now()
+	err := hook.Prerun(context.Background())
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), "timed out")
+	require.Contains(t, err.Error(), `plugin "minnie"`)
+
+	require.Equal(t, 2, callCounts.get("claim"))
+	require.Equal(t, 1, callCounts.get("mount"))
+	require.Equal(t, 1, callCounts.get("blocked"))
+	require.Equal(t, 1, callCounts.get("unmount"), "expected earlier mount to be cleaned up")
+	require.Equal(t, 2, callCounts.get("unpublish"), "expected claims to be released")
+	require.Nil(t, ar.GetAllocHookResources().GetCSIMounts())
 }
 
-func (ar mockAllocRunner) SetAllocHookResources(res *cstructs.AllocHookResources) {
-	ar.res = res
+func TestCSIHook_ClaimBatch(t *testing.T) {
+
+	alloc := mock.Alloc()
+	logger := testlog.HCLogger(t)
+
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+	for _, name := range []string{"vol0", "vol1", "vol2"} {
+		alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         "test" + name,
+			ReadOnly:       true,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			MountOptions:   &structs.CSIMountOptions{},
+		}
+	}
+
+	// newHook returns a hook sharing claimBatch, as the hooks of the
+	// allocations on a client do
+	newHook := func(claimBatch *csimanager.ClaimBatchSupport, newRPCer func(mockRPCer) RPCer) (*csiHook, *callCounter) {
+		callCounts := newCallCounter()
+		mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+		rpcer := newRPCer(mockRPCer{alloc: alloc, callCounts: callCounts})
+		ar := mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               logger,
+			csiManager:           mgr,
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			claimBatch:           claimBatch,
+			nodeSecret:           "secret",
+			stateDB:              cstate.NoopDB{},
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		return hook, callCounts
+	}
+
+	t.Run("batched", func(t *testing.T) {
+		hook, callCounts := newHook(csimanager.NewClaimBatchSupport(), func(r mockRPCer) RPCer { return batchRPCer{mockRPCer: r} })
+		require.NoError(t, hook.Prerun(context.Background()))
+
+		// The volumes are claimed in a single request
+		require.Equal(t, 1, callCounts.get("claim_batch"))
+		require.Equal(t, 3, callCounts.get("claim"))
+		require.Equal(t, 3, callCounts.get("mount"))
+		require.True(t, hook.claimBatch.Supported())
+		for alias, pair := range hook.volumeRequests {
+			require.NotNil(t, pair.volume, "volume %q", alias)
+			require.NotNil(t, pair.publishContext, "volume %q", alias)
+		}
+
+		require.NoError(t, hook.Postrun(context.Background()))
+		require.Equal(t, 3, callCounts.get("unpublish"))
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		claimBatch := csimanager.NewClaimBatchSupport()
+		hook, callCounts := newHook(claimBatch, func(r mockRPCer) RPCer { return noBatchRPCer{mockRPCer: r} })
+		require.NoError(t, hook.Prerun(context.Background()))
+
+		// The batch is rejected and each volume is claimed on its own
+		require.Equal(t, 1, callCounts.get("claim_batch"))
+		require.Equal(t, 3, callCounts.get("claim"))
+		require.Equal(t, 3, callCounts.get("mount"))
+		require.False(t, hook.claimBatch.Supported())
+
+		// Later claims don't try a batch again
+		_, err := hook.claimVolumesFromAlloc(context.Background(), nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, callCounts.get("claim_batch"))
+		require.Equal(t, 6, callCounts.get("claim"))
+
+		// Nor do the other allocations on the client
+		hook, callCounts = newHook(claimBatch, func(r mockRPCer) RPCer { return noBatchRPCer{mockRPCer: r} })
+		require.NoError(t, hook.Prerun(context.Background()))
+		require.Zero(t, callCounts.get("claim_batch"))
+		require.Equal(t, 3, callCounts.get("claim"))
+	})
+
+	t.Run("batch failure", func(t *testing.T) {
+		hook, callCounts := newHook(csimanager.NewClaimBatchSupport(), func(r mockRPCer) RPCer {
+			return batchRPCer{mockRPCer: r, err: fmt.Errorf("volume max claims reached")}
+		})
+		err := hook.Prerun(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "could not claim volumes testvol0, testvol1, testvol2")
+		require.Contains(t, err.Error(), "volume max claims reached")
+
+		// A failed batch isn't sent again as single claims, and claims
+		// nothing to release
+		require.Equal(t, 1, callCounts.get("claim_batch"))
+		require.Zero(t, callCounts.get("claim"))
+		require.Zero(t, callCounts.get("mount"))
+		require.Zero(t, callCounts.get("unpublish"))
+		require.True(t, hook.claimBatch.Supported())
+	})
 }
 
-func (ar mockAllocRunner) GetTaskDriverCapabilities(taskName string) (*drivers.Capabilities, error) {
-	return ar.caps, nil
+func TestCSIHook_DrainShutdown(t *testing.T) {
+
+	volumeRequest := func(name string) *structs.VolumeRequest {
+		return &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         "test" + name,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			MountOptions:   &structs.CSIMountOptions{},
+		}
+	}
+
+	newAllocRunner := func() mockAllocRunner {
+		return mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+	}
+
+	newHook := func(t *testing.T, block string, db cstate.StateDB) (*csiHook, *mockDrainVolumeMounter, *unpublishOrderRPCer, *callCounter) {
+		alloc := mock.Alloc()
+		alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+		for _, name := range []string{"vol0", "vol1", "vol2"} {
+			alloc.Job.TaskGroups[0].Volumes[name] = volumeRequest(name)
+		}
+
+		conf := clientconfig.DefaultConfig()
+		conf.CSIVolumeMountTimeout = 100 * time.Millisecond
+
+		callCounts := newCallCounter()
+		mounter := &mockDrainVolumeMounter{
+			mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+			block:             block,
+		}
+		rpcer := &unpublishOrderRPCer{mockRPCer: mockRPCer{alloc: alloc, callCounts: callCounts}}
+		ar := newAllocRunner()
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mockPluginManager{mounter: mounter},
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			stateDB:              db,
+			clientConfig:         conf,
+		})
+		require.NoError(t, hook.Prerun(context.Background()))
+
+		// A volume added by an update is claimed last, although its alias
+		// sorts first
+		updated := alloc.Copy()
+		updated.Job.TaskGroups[0].Volumes["avol"] = volumeRequest("avol")
+		require.NoError(t, hook.applyUpdate(context.Background(), updated))
+		require.Equal(t, 4, callCounts.get("claim"))
+		return hook, mounter, rpcer, callCounts
+	}
+
+	t.Run("reverse claim order", func(t *testing.T) {
+		hook, mounter, rpcer, callCounts := newHook(t, "", cstate.NoopDB{})
+		require.NoError(t, hook.Shutdown(context.Background()))
+
+		expected := []string{"testavol", "testvol2", "testvol1", "testvol0"}
+		require.Equal(t, expected, mounter.unmounted)
+		require.Equal(t, expected, rpcer.unpublished)
+
+		// Postrun leaves the released volumes alone
+		require.NoError(t, hook.Postrun(context.Background()))
+		require.Equal(t, 4, callCounts.get("unpublish"))
+	})
+
+	t.Run("restored", func(t *testing.T) {
+		db := cstate.NewMemDB(testlog.HCLogger(t))
+		hook, mounter, _, _ := newHook(t, "", db)
+
+		// The allocation restored after the client restarts keeps the
+		// order of the claims, rather than the order its volumes are
+		// restored in
+		callCounts := newCallCounter()
+		rpcer := &unpublishOrderRPCer{mockRPCer: mockRPCer{alloc: hook.alloc, callCounts: callCounts}}
+		ar := newAllocRunner()
+		restored := newCSIHook(csiHookConfig{
+			alloc:                hook.alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mockPluginManager{mounter: mounter},
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			stateDB:              db,
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		require.NoError(t, restored.Prerun(context.Background()))
+		require.Equal(t, 0, callCounts.get("claim"))
+
+		// A volume added after the restore is claimed last
+		updated := hook.alloc.Copy()
+		updated.Job.TaskGroups[0].Volumes["bvol"] = volumeRequest("bvol")
+		require.NoError(t, restored.applyUpdate(context.Background(), updated))
+		require.Equal(t, 5, restored.volumeRequests["bvol"].claimIndex)
+
+		require.NoError(t, restored.Shutdown(context.Background()))
+		expected := []string{"testbvol", "testavol", "testvol2", "testvol1", "testvol0"}
+		require.Equal(t, expected, rpcer.unpublished)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		hook, mounter, rpcer, callCounts := newHook(t, "testvol1", cstate.NoopDB{})
+
+		start := time.Now()
+		err := hook.Shutdown(context.Background())
+		require.Less(t, time.Since(start), 5*time.Second)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `could not unmount volume "vol1"`)
+		require.Contains(t, err.Error(), "timed out")
+
+		// The volume the plugin didn't confirm is skipped, and the others
+		// are still released in order
+		require.Equal(t, []string{"testavol", "testvol2", "testvol0"}, mounter.unmounted)
+		require.Equal(t, []string{"testavol", "testvol2", "testvol0"}, rpcer.unpublished)
+
+		// Postrun releases the volume left
+		require.NoError(t, hook.Postrun(context.Background()))
+		require.Equal(t, 4, callCounts.get("unpublish"))
+		require.Equal(t, "testvol1", rpcer.unpublished[3])
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		hook, mounter, rpcer, _ := newHook(t, "", cstate.NoopDB{})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.ErrorIs(t, hook.Shutdown(ctx), context.Canceled)
+		require.Empty(t, mounter.unmounted)
+		require.Empty(t, rpcer.unpublished)
+	})
+}
+
+func TestCSIHook_ClaimRetry(t *testing.T) {
+
+	notFingerprinted := fmt.Errorf(`controller publish: failed to find storage provider info for client "node1", node plugin "minnie": %w`,
+		structs.ErrCSIPluginNotFingerprinted)
+
+	testcases := []struct {
+		name             string
+		err              error
+		failures         int
+		registrationWait time.Duration
+		expectErr        string
+		expectAttempts   int
+	}{
+		{
+			name:           "transient",
+			err:            fmt.Errorf("rpc error: %w", structs.ErrNoLeader),
+			failures:       2,
+			expectAttempts: 3,
+		},
+		{
+			name:           "transient exhausted",
+			err:            fmt.Errorf("rpc error: %w", structs.ErrNoLeader),
+			failures:       10,
+			expectErr:      "No cluster leader",
+			expectAttempts: 4,
+		},
+		{
+			name:           "controller transient",
+			err:            fmt.Errorf("controller publish: %v: plugin is busy", structs.ErrCSIClientRPCRetryable),
+			failures:       1,
+			expectAttempts: 2,
+		},
+		{
+			name:           "permanent",
+			err:            fmt.Errorf("rpc error: volume not found: testvol0"),
+			failures:       10,
+			expectErr:      "volume not found",
+			expectAttempts: 1,
+		},
+		{
+			// The attempts don't bound the wait for the plugin
+			name:             "plugin registering",
+			err:              notFingerprinted,
+			failures:         5,
+			registrationWait: 5 * time.Second,
+			expectAttempts:   6,
+		},
+		{
+			name:             "plugin never registers",
+			err:              notFingerprinted,
+			failures:         100,
+			registrationWait: 100 * time.Millisecond,
+			expectErr:        "has not fingerprinted",
+		},
+		{
+			name:           "plugin not registered without wait",
+			err:            notFingerprinted,
+			failures:       10,
+			expectErr:      "has not fingerprinted",
+			expectAttempts: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvol0",
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+				},
+			}
+
+			conf := clientconfig.DefaultConfig()
+			conf.CSIClaimRetry = &clientconfig.RetryConfig{
+				Attempts:   helper.IntToPtr(3),
+				Backoff:    helper.TimeToPtr(10 * time.Millisecond),
+				MaxBackoff: helper.TimeToPtr(20 * time.Millisecond),
+			}
+			conf.CSIPluginRegistrationWait = tc.registrationWait
+
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+			rpcer := flakyClaimRPCer{
+				mockRPCer: mockRPCer{alloc: alloc, callCounts: callCounts},
+				err:       tc.err,
+				failures:  tc.failures,
+			}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               testlog.HCLogger(t),
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              &mockEventEmitter{},
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         conf,
+			})
+
+			err := hook.Prerun(context.Background())
+			if tc.expectAttempts != 0 {
+				require.Equal(t, tc.expectAttempts, callCounts.get("claim_attempt"))
+			} else {
+				require.Greater(t, callCounts.get("claim_attempt"), 4)
+			}
+			if tc.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectErr)
+				require.Equal(t, 0, callCounts.get("mount"))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 1, callCounts.get("claim"))
+			require.Equal(t, 1, callCounts.get("mount"))
+		})
+	}
+}
+
+func TestCSIHook_ClaimTimeout(t *testing.T) {
+
+	testcases := []struct {
+		name          string
+		groupTimeout  *time.Duration
+		expectTimeout time.Duration
+	}{
+		{
+			name:          "node default",
+			expectTimeout: 80 * time.Millisecond,
+		},
+		{
+			name:          "group override",
+			groupTimeout:  helper.TimeToPtr(40 * time.Millisecond),
+			expectTimeout: 40 * time.Millisecond,
+		},
+		{
+			name:          "zero group timeout",
+			groupTimeout:  helper.TimeToPtr(0),
+			expectTimeout: 80 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].CSIClaimTimeout = tc.groupTimeout
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvol0",
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+				},
+			}
+
+			// Retry the claim until it times out
+			conf := clientconfig.DefaultConfig()
+			conf.CSIVolumeMountTimeout = 80 * time.Millisecond
+			conf.CSIClaimRetry = &clientconfig.RetryConfig{
+				Attempts:   helper.IntToPtr(0),
+				Backoff:    helper.TimeToPtr(5 * time.Millisecond),
+				MaxBackoff: helper.TimeToPtr(5 * time.Millisecond),
+			}
+
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+			rpcer := flakyClaimRPCer{
+				mockRPCer: mockRPCer{alloc: alloc, callCounts: callCounts},
+				err:       fmt.Errorf("rpc error: %w", structs.ErrNoLeader),
+				failures:  math.MaxInt32,
+			}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               testlog.HCLogger(t),
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              &mockEventEmitter{},
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         conf,
+			})
+			require.Equal(t, tc.expectTimeout, hook.claimTimeout)
+
+			err := hook.Prerun(context.Background())
+			require.EqualError(t, err, fmt.Sprintf(
+				"claim volumes: could not claim volume testvol0: timed out after %v", tc.expectTimeout))
+			require.Equal(t, 0, callCounts.get("mount"))
+		})
+	}
+}
+
+func TestCSIHook_UnpublishRetry(t *testing.T) {
+
+	testcases := []struct {
+		name           string
+		err            error
+		failures       int
+		expectErr      string
+		expectAttempts int
+	}{
+		{
+			name:           "transient",
+			err:            fmt.Errorf("rpc error: %w", structs.ErrNoLeader),
+			failures:       2,
+			expectAttempts: 3,
+		},
+		{
+			name:           "transient exhausted",
+			err:            fmt.Errorf("rpc error: %w", structs.ErrNoLeader),
+			failures:       10,
+			expectErr:      "No cluster leader",
+			expectAttempts: 4,
+		},
+		{
+			name:           "permanent",
+			err:            fmt.Errorf("rpc error: volume not found: testvol0"),
+			failures:       10,
+			expectErr:      "volume not found",
+			expectAttempts: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+			for _, name := range []string{"vol0", "vol1"} {
+				alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+					Name:           name,
+					Type:           structs.VolumeTypeCSI,
+					Source:         "test" + name,
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+				}
+			}
+
+			conf := clientconfig.DefaultConfig()
+			conf.CSIUnpublishRetry = &clientconfig.RetryConfig{
+				Attempts:   helper.IntToPtr(3),
+				Backoff:    helper.TimeToPtr(10 * time.Millisecond),
+				MaxBackoff: helper.TimeToPtr(20 * time.Millisecond),
+			}
+
+			db := cstate.NewMemDB(testlog.HCLogger(t))
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+			rpcer := flakyUnpublishRPCer{
+				mockRPCer: mockRPCer{alloc: alloc, callCounts: callCounts},
+				volumeID:  "testvol0",
+				err:       tc.err,
+				failures:  tc.failures,
+			}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               testlog.HCLogger(t),
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              &mockEventEmitter{},
+				nodeSecret:           "secret",
+				stateDB:              db,
+				clientConfig:         conf,
+			})
+			require.NoError(t, hook.Prerun(context.Background()))
+
+			err := hook.Postrun(context.Background())
+			require.Equal(t, tc.expectAttempts, callCounts.get("unpublish_attempt"))
+
+			vols, dbErr := db.GetCSIVolumes(alloc.ID)
+			require.NoError(t, dbErr)
+			if tc.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectErr)
+				require.Equal(t, 1, callCounts.get("unpublish"), "expected other volume to be unpublished")
+
+				// The volume that failed to unpublish stays recorded
+				require.Len(t, vols, 1)
+				require.Contains(t, vols, "vol0")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, 2, callCounts.get("unpublish"))
+			require.Empty(t, vols)
+		})
+	}
+}
+
+func TestCSIHook_Cancel(t *testing.T) {
+
+	testcases := []struct {
+		name            string
+		shutdown        bool
+		expectUnmount   int
+		expectUnpublish int
+	}{
+		{
+			// The alloc won't run, so its volumes are unmounted and
+			// unpublished
+			name:            "destroy",
+			expectUnmount:   1,
+			expectUnpublish: 2,
+		},
+		{
+			// The restored alloc mounts its volumes again, so they're left
+			// for it
+			name:     "shutdown",
+			shutdown: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+			for _, name := range []string{"vol0", "vol1"} {
+				alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+					Name:           name,
+					Type:           structs.VolumeTypeCSI,
+					Source:         "test" + name,
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+				}
+			}
+
+			// The hung mount would only time out long after the test
+			conf := clientconfig.DefaultConfig()
+			conf.CSIVolumeMountTimeout = time.Hour
+
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: mockBlockingVolumeMounter{
+				mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+				succeed:           1,
+			}}
+			rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               testlog.HCLogger(t),
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              &mockEventEmitter{},
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         conf,
+			})
+
+			shutdownCtx, shutdown := interfaces.NewShutdownContext()
+			defer shutdown()
+			ctx, destroy := context.WithCancel(shutdownCtx)
+			defer destroy()
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- hook.Prerun(ctx)
+			}()
+
+			testutil.WaitForResult(func() (bool, error) {
+				return callCounts.get("blocked") == 1, fmt.Errorf("mount not blocked")
+			}, func(err error) {
+				require.NoError(t, err)
+			})
+
+			if tc.shutdown {
+				shutdown()
+			} else {
+				destroy()
+			}
+
+			select {
+			case err := <-errCh:
+				require.ErrorIs(t, err, context.Canceled)
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected cancelling the context to interrupt the mount")
+			}
+			require.Equal(t, tc.expectUnmount, callCounts.get("unmount"))
+			require.Equal(t, tc.expectUnpublish, callCounts.get("unpublish"))
+		})
+	}
+}
+
+func TestCSIHook_PartialFailureRollback(t *testing.T) {
+
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("vol%d", i)
+		alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         "testvolume" + name,
+			ReadOnly:       true,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			MountOptions:   &structs.CSIMountOptions{},
+		}
+	}
+
+	callCounts := newCallCounter()
+	mounter := &mockFailingVolumeMounter{
+		mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+		fail:              "testvolumevol3",
+		unmounted:         map[string]bool{},
+	}
+	mgr := mockPluginManager{mounter: mounter}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               testlog.HCLogger(t),
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         clientconfig.DefaultConfig(),
+	})
+
+	err := hook.Prerun(context.Background())
+	require.EqualError(t, err, "mount of testvolumevol3 failed")
+
+	// Every volume that was mounted is unmounted, and every claim released
+	mounted := mounter.mountedVolumes()
+	require.NotEmpty(t, mounted)
+	require.NotContains(t, mounted, "testvolumevol3")
+	for _, vol := range mounted {
+		require.True(t, mounter.isUnmounted(vol), "expected %s to be unmounted", vol)
+	}
+	require.Equal(t, len(mounted), callCounts.get("unmount"))
+	require.Equal(t, 5, callCounts.get("claim"))
+	require.Equal(t, 5, callCounts.get("unpublish"))
+	require.Nil(t, ar.GetAllocHookResources().GetCSIMounts())
+
+	// Nothing is left for Postrun to release
+	require.NoError(t, hook.Postrun(context.Background()))
+	require.Equal(t, 5, callCounts.get("unpublish"))
+}
+
+func TestCSIHook_MountMetricsAndEvents(t *testing.T) {
+	// Not parallel as the test replaces the global metrics sink
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	metricsConf := metrics.DefaultConfig("nomad")
+	metricsConf.EnableHostname = false
+	metricsConf.EnableRuntimeMetrics = false
+	_, err := metrics.NewGlobal(metricsConf, sink)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		// Put back the blackhole sink the global metrics start with
+		metrics.NewGlobal(metricsConf, &metrics.BlackholeSink{})
+	})
+
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+		"vol0": {
+			Name:           "vol0",
+			Type:           structs.VolumeTypeCSI,
+			Source:         "testvolume0",
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		},
+	}
+
+	callCounts := newCallCounter()
+	mounter := &mockFailingVolumeMounter{
+		mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+		fail:              "testvolume0",
+		unmounted:         map[string]bool{},
+	}
+	mgr := mockPluginManager{mounter: mounter}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	eventer := &mockEventEmitter{}
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               testlog.HCLogger(t),
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              eventer,
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         clientconfig.DefaultConfig(),
+	})
+	require.EqualError(t, hook.Prerun(context.Background()), "mount of testvolume0 failed")
+
+	// The failed mount is timed and counted by plugin
+	var samples, failures int
+	for _, interval := range sink.Data() {
+		interval.RLock()
+		if sample, ok := interval.Samples["nomad.client.csi.mount_duration;plugin_id=minnie"]; ok {
+			samples += sample.Count
+		}
+		if counter, ok := interval.Counters["nomad.client.csi.mount_failures;plugin_id=minnie"]; ok {
+			failures += counter.Count
+		}
+		interval.RUnlock()
+	}
+	require.Equal(t, 1, samples)
+	require.Equal(t, 1, failures)
+
+	// The failure is surfaced as a task event naming the volume and plugin
+	var failure *structs.TaskEvent
+	for _, event := range eventer.events {
+		if event.Type == structs.TaskSetupFailure {
+			failure = event
+		}
+	}
+	require.NotNil(t, failure)
+	require.Equal(t, map[string]string{"volume": "vol0", "plugin_id": "minnie"}, failure.Details)
+	require.Equal(t, `Failed to mount volume "vol0" via plugin "minnie": mount of testvolume0 failed`,
+		failure.DisplayMessage)
+}
+
+func TestCSIHook_OpScheduler(t *testing.T) {
+
+	alloc := mock.Alloc()
+	logger := testlog.HCLogger(t)
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+		"vol0": {
+			Name:           "vol0",
+			Type:           structs.VolumeTypeCSI,
+			Source:         "testvolume0",
+			ReadOnly:       true,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			MountOptions:   &structs.CSIMountOptions{},
+		},
+	}
+
+	callCounts := newCallCounter()
+	mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+
+	// Hold the only slot so the hook cannot claim its volume
+	scheduler := csimanager.NewOpScheduler(1, true)
+	release, err := scheduler.Acquire(context.Background(), 0)
+	require.NoError(t, err)
+
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               logger,
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		opScheduler:          scheduler,
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         clientconfig.DefaultConfig(),
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- hook.Prerun(context.Background())
+	}()
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("expected prerun to wait for the scheduler, got: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for prerun")
+	}
+	require.Equal(t, 1, callCounts.get("claim"))
+	require.Equal(t, 1, callCounts.get("mount"))
+}
+
+func TestCSIHook_Events(t *testing.T) {
+
+	logger := testlog.HCLogger(t)
+
+	testcases := []struct {
+		name           string
+		mounter        csimanager.VolumeMounter
+		expectErr      bool
+		expectedEvents []string
+	}{
+		{
+			name: "success",
+			expectedEvents: []string{
+				`Task Setup: Claiming volume "vol0"`,
+				`Task Setup: Mounting volume "vol0" via plugin "minnie"`,
+				`Task Setup: Volume "vol0" mounted`,
+			},
+		},
+		{
+			name:      "mount failure",
+			expectErr: true,
+			expectedEvents: []string{
+				`Task Setup: Claiming volume "vol0"`,
+				`Task Setup: Mounting volume "vol0" via plugin "minnie"`,
+				`Setup Failure: Failed to mount volume "vol0" via plugin "minnie": ` +
+					`volume "vol0" mount via plugin "minnie" timed out after 50ms: context deadline exceeded`,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+				},
+			}
+
+			conf := clientconfig.DefaultConfig()
+			conf.CSIVolumeMountTimeout = 50 * time.Millisecond
+
+			callCounts := newCallCounter()
+			var mounter csimanager.VolumeMounter = mockVolumeMounter{callCounts: callCounts}
+			if tc.expectErr {
+				mounter = mockBlockingVolumeMounter{
+					mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+				}
+			}
+			mgr := mockPluginManager{mounter: mounter}
+			rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			eventer := &mockEventEmitter{}
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               logger,
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              eventer,
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         conf,
+			})
+
+			err := hook.Prerun(context.Background())
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			events := make([]string, 0, len(eventer.events))
+			for _, e := range eventer.events {
+				events = append(events, e.Type+": "+e.DisplayMessage)
+				require.Equal(t, "vol0", e.Details["volume"])
+			}
+			require.Equal(t, tc.expectedEvents, events)
+
+			// Every event after the claim knows which plugin is in use
+			for _, e := range eventer.events[1:] {
+				require.Equal(t, "minnie", e.Details["plugin_id"])
+			}
+		})
+	}
+}
+
+func TestCSIHook_PluginUnavailable(t *testing.T) {
+
+	logger := testlog.HCLogger(t)
+
+	testcases := []struct {
+		name             string
+		pluginErr        error
+		availableAfter   int
+		registrationWait time.Duration
+		mountTimeout     time.Duration
+		expectErr        error
+		expectedEvents   []string
+	}{
+		{
+			name:           "plugin registers while waiting",
+			pluginErr:      csimanager.ErrPluginUnavailable,
+			availableAfter: 1,
+			expectedEvents: []string{
+				`Task Setup: Claiming volume "vol0"`,
+				`Task Setup: Waiting for plugin "minnie" to become available`,
+				`Task Setup: Mounting volume "vol0" via plugin "minnie"`,
+				`Task Setup: Volume "vol0" mounted`,
+			},
+		},
+		{
+			name:           "plugin never becomes available",
+			pluginErr:      csimanager.ErrPluginUnavailable,
+			availableAfter: -1,
+			expectErr:      csimanager.ErrPluginUnavailable,
+			expectedEvents: []string{
+				`Task Setup: Claiming volume "vol0"`,
+				`Task Setup: Waiting for plugin "minnie" to become available`,
+				`Setup Failure: Failed to mount volume "vol0" via plugin "minnie": ` +
+					`plugin "minnie" unavailable after waiting 300ms: ` +
+					`plugin minnie for type csi-node not found: plugin is temporarily unavailable on this node`,
+			},
+		},
+		{
+			name:           "plugin never registered",
+			pluginErr:      csimanager.ErrPluginNotRegistered,
+			availableAfter: -1,
+			expectErr:      csimanager.ErrPluginNotRegistered,
+			expectedEvents: []string{
+				`Task Setup: Claiming volume "vol0"`,
+				`Setup Failure: Plugin "minnie" for volume "vol0" is not registered on this node: ` +
+					`plugin minnie for type csi-node not found: plugin has not registered on this node`,
+			},
+		},
+		{
+			name:             "plugin registers late",
+			pluginErr:        csimanager.ErrPluginNotRegistered,
+			availableAfter:   3,
+			registrationWait: time.Minute,
+			mountTimeout:     time.Minute,
+			expectedEvents: []string{
+				`Task Setup: Claiming volume "vol0"`,
+				`Task Setup: Waiting for plugin "minnie" to become available`,
+				`Task Setup: Mounting volume "vol0" via plugin "minnie"`,
+				`Task Setup: Volume "vol0" mounted`,
+			},
+		},
+		{
+			name:             "plugin never registers while waiting",
+			pluginErr:        csimanager.ErrPluginNotRegistered,
+			availableAfter:   -1,
+			registrationWait: 150 * time.Millisecond,
+			mountTimeout:     time.Minute,
+			expectErr:        csimanager.ErrPluginNotRegistered,
+			expectedEvents: []string{
+				`Task Setup: Claiming volume "vol0"`,
+				`Task Setup: Waiting for plugin "minnie" to become available`,
+				`Setup Failure: Plugin "minnie" for volume "vol0" is not registered on this node: ` +
+					`plugin "minnie" not registered after waiting 150ms: ` +
+					`plugin minnie for type csi-node not found: plugin has not registered on this node`,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       true,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+					MountOptions:   &structs.CSIMountOptions{},
+				},
+			}
+
+			conf := clientconfig.DefaultConfig()
+			conf.CSIVolumeMountTimeout = 300 * time.Millisecond
+			if tc.mountTimeout != 0 {
+				conf.CSIVolumeMountTimeout = tc.mountTimeout
+			}
+			conf.CSIPluginRegistrationWait = tc.registrationWait
+
+			callCounts := newCallCounter()
+			mgr := &mockUnavailablePluginManager{
+				mockPluginManager: mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}},
+				err:               tc.pluginErr,
+				availableAfter:    tc.availableAfter,
+			}
+			rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			eventer := &mockEventEmitter{}
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               logger,
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              eventer,
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         conf,
+			})
+
+			err := hook.Prerun(context.Background())
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+				require.Equal(t, 0, callCounts.get("mount"))
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, 1, callCounts.get("mount"))
+				require.Equal(t, tc.availableAfter+1, mgr.calls)
+			}
+
+			events := make([]string, 0, len(eventer.events))
+			for _, e := range eventer.events {
+				events = append(events, e.Type+": "+e.DisplayMessage)
+			}
+			require.Equal(t, tc.expectedEvents, events)
+		})
+	}
+}
+
+func TestCSIHook_ClaimLabels(t *testing.T) {
+	t.Setenv("NOMAD_TEST_CSI_DEPLOYMENT", "deploy-1234")
+	t.Setenv("NOMAD_TEST_CSI_EMPTY", "")
+
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+		"vol0": {
+			Name:           "vol0",
+			Type:           structs.VolumeTypeCSI,
+			Source:         "testvolume0",
+			ReadOnly:       true,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			MountOptions:   &structs.CSIMountOptions{},
+		},
+	}
+
+	conf := clientconfig.DefaultConfig()
+	conf.CSIClaimLabelEnv = map[string]string{
+		"deployment_id": "NOMAD_TEST_CSI_DEPLOYMENT",
+		"empty":         "NOMAD_TEST_CSI_EMPTY",
+		"unset":         "NOMAD_TEST_CSI_UNSET",
+	}
+
+	callCounts := newCallCounter()
+	mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+	rpcer := &recordingRPCer{mockRPCer: mockRPCer{alloc: alloc, callCounts: callCounts}}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               testlog.HCLogger(t),
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         conf,
+	})
+
+	require.NoError(t, hook.Prerun(context.Background()))
+	require.Len(t, rpcer.claims, 1)
+	require.Equal(t, map[string]string{
+		"deployment_id": "deploy-1234",
+		"empty":         "",
+	}, rpcer.claims[0].Labels)
+	require.Equal(t, rpcer.claims[0].Labels, rpcer.claims[0].ToClaim().Labels)
+}
+
+func TestCSIHook_MaxVolumesPerAlloc(t *testing.T) {
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+		"host": {
+			Name:   "host",
+			Type:   structs.VolumeTypeHost,
+			Source: "shared",
+		},
+	}
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("vol%d", i)
+		alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         fmt.Sprintf("testvolume%d", i),
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		}
+	}
+
+	newHook := func(max int) (*csiHook, *callCounter) {
+		conf := clientconfig.DefaultConfig()
+		conf.CSIMaxVolumesPerAlloc = max
+
+		callCounts := newCallCounter()
+		mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+		rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+		ar := mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+		return newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mgr,
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			stateDB:              cstate.NoopDB{},
+			clientConfig:         conf,
+		}), callCounts
+	}
+
+	// Requests over the limit fail before any volume is claimed. Host
+	// volumes don't count towards it.
+	hook, callCounts := newHook(2)
+	err := hook.Prerun(context.Background())
+	require.EqualError(t, err,
+		"claim volumes: allocation requests 3 CSI volumes, over the limit of 2 per allocation")
+	require.Zero(t, callCounts.get("claim"))
+
+	hook, callCounts = newHook(3)
+	require.NoError(t, hook.Prerun(context.Background()))
+	require.Equal(t, 3, callCounts.get("claim"))
+	require.Equal(t, 3, callCounts.get("mount"))
+}
+
+func TestCSIHook_Restore(t *testing.T) {
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+	for i := 0; i < 2; i++ {
+		name := fmt.Sprintf("vol%d", i)
+		alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         fmt.Sprintf("testvolume%d", i),
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		}
+	}
+
+	// newHook returns a hook for the alloc sharing db, as the restored
+	// alloc's hook does after the client restarts
+	newHook := func(db cstate.StateDB, restoreErr error) (*csiHook, *callCounter, mockAllocRunner) {
+		callCounts := newCallCounter()
+		mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts, restoreErr: restoreErr}}
+		rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+		ar := mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mgr,
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			stateDB:              db,
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		return hook, callCounts, ar
+	}
+
+	t.Run("restored mounts are reused", func(t *testing.T) {
+		db := cstate.NewMemDB(testlog.HCLogger(t))
+
+		hook, callCounts, ar := newHook(db, nil)
+		require.NoError(t, hook.Prerun(context.Background()))
+		require.Equal(t, 2, callCounts.get("claim"))
+		require.Equal(t, 2, callCounts.get("mount"))
+		mounts := ar.GetAllocHookResources().CSIMounts
+
+		vols, err := db.GetCSIVolumes(alloc.ID)
+		require.NoError(t, err)
+		require.Len(t, vols, 2)
+		require.Equal(t, "testvolume0", vols["vol0"].Volume.ID)
+		require.Equal(t, mounts["vol0"], vols["vol0"].MountInfo)
+
+		// The restored alloc neither claims nor mounts its volumes again
+		hook, callCounts, ar = newHook(db, nil)
+		require.NoError(t, hook.Prerun(context.Background()))
+		require.Equal(t, 2, callCounts.get("restore"))
+		require.Zero(t, callCounts.get("claim"))
+		require.Zero(t, callCounts.get("mount"))
+		require.Equal(t, mounts, ar.GetAllocHookResources().CSIMounts)
+
+		// Stopping the alloc unpublishes the restored volumes and forgets
+		// them
+		require.NoError(t, hook.Postrun(context.Background()))
+		require.Equal(t, 2, callCounts.get("unpublish"))
+		vols, err = db.GetCSIVolumes(alloc.ID)
+		require.NoError(t, err)
+		require.Empty(t, vols)
+	})
+
+	t.Run("volumes that fail to restore are claimed again", func(t *testing.T) {
+		db := cstate.NewMemDB(testlog.HCLogger(t))
+
+		hook, _, ar := newHook(db, nil)
+		require.NoError(t, hook.Prerun(context.Background()))
+		mounts := ar.GetAllocHookResources().CSIMounts
+
+		hook, callCounts, ar := newHook(db, fmt.Errorf("not a mount point"))
+		require.NoError(t, hook.Prerun(context.Background()))
+		require.Equal(t, 2, callCounts.get("restore"))
+		require.Equal(t, 2, callCounts.get("claim"))
+		require.Equal(t, 2, callCounts.get("mount"))
+		require.Equal(t, mounts, ar.GetAllocHookResources().CSIMounts)
+	})
+}
+
+func TestCSIHook_ParallelMounts(t *testing.T) {
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+	for i := 0; i < csiMaxParallelMounts+2; i++ {
+		name := fmt.Sprintf("vol%d", i)
+		alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         fmt.Sprintf("testvolume%d", i),
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		}
+	}
+
+	callCounts := newCallCounter()
+	mounter := &mockConcurrentVolumeMounter{
+		mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+		started:           make(chan string, len(alloc.Job.TaskGroups[0].Volumes)),
+		release:           make(chan struct{}),
+	}
+	mgr := mockPluginManager{mounter: mounter}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               testlog.HCLogger(t),
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         clientconfig.DefaultConfig(),
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- hook.Prerun(context.Background())
+	}()
+
+	// Mounts block until released, so they must be running concurrently to
+	// all start, up to the limit
+	for i := 0; i < csiMaxParallelMounts; i++ {
+		select {
+		case <-mounter.started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %d concurrent mounts, got %d", csiMaxParallelMounts, i)
+		}
+	}
+	select {
+	case vol := <-mounter.started:
+		t.Fatalf("expected mounts to be limited to %d, but %s started", csiMaxParallelMounts, vol)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(mounter.release)
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for prerun")
+	}
+	require.Equal(t, csiMaxParallelMounts+2, callCounts.get("mount"))
+	require.Len(t, ar.GetAllocHookResources().GetCSIMounts(), csiMaxParallelMounts+2)
+}
+
+// TestCSIHook_ParallelMounts_Cancelled asserts that mounting returns an error
+// and releases the volumes it mounted when its context is cancelled before
+// every volume is mounted.
+func TestCSIHook_ParallelMounts_Cancelled(t *testing.T) {
+	alloc := mock.Alloc()
+	callCounts := newCallCounter()
+	mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               testlog.HCLogger(t),
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         clientconfig.DefaultConfig(),
+	})
+
+	volumes := map[string]*volumeAndRequest{}
+	for i := 0; i < csiMaxParallelMounts+2; i++ {
+		name := fmt.Sprintf("vol%d", i)
+		volumes[name] = &volumeAndRequest{
+			volume: &structs.CSIVolume{ID: fmt.Sprintf("testvolume%d", i), PluginID: "testplugin"},
+			request: &structs.VolumeRequest{
+				Name:           name,
+				Type:           structs.VolumeTypeCSI,
+				Source:         fmt.Sprintf("testvolume%d", i),
+				AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+				AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			},
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mounts, err := hook.mountVolumes(ctx, volumes)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, mounts)
+	require.Equal(t, callCounts.get("mount"), callCounts.get("unmount"))
+}
+
+func TestCSIHook_CapacityBudget(t *testing.T) {
+
+	const gb = 1024 * 1024 * 1024
+	capacities := map[string]int64{
+		"testvolume0": 4 * gb,
+		"testvolume1": 4 * gb,
+		"testvolume2": 4 * gb,
+	}
+	budget := csimanager.NewCapacityBudget(10)
+
+	newHook := func(sources ...string) (*csiHook, *callCounter) {
+		alloc := mock.Alloc()
+		alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+		for i, source := range sources {
+			name := fmt.Sprintf("vol%d", i)
+			alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+				Name:           name,
+				Type:           structs.VolumeTypeCSI,
+				Source:         source,
+				ReadOnly:       true,
+				AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+				AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+				MountOptions:   &structs.CSIMountOptions{},
+			}
+		}
+
+		callCounts := newCallCounter()
+		mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+		rpcer := mockRPCer{alloc: alloc, callCounts: callCounts, capacities: capacities}
+		ar := mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mgr,
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			capacityBudget:       budget,
+			nodeSecret:           "secret",
+			stateDB:              cstate.NoopDB{},
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		return hook, callCounts
+	}
+
+	// The first allocation's volumes fit in the budget
+	hook1, _ := newHook("testvolume0", "testvolume1")
+	require.NoError(t, hook1.Prerun(context.Background()))
+	require.Equal(t, int64(8*gb), budget.Used())
+
+	// The second allocation shares a volume, which counts once, but its
+	// other volume crosses the budget
+	hook2, callCounts := newHook("testvolume1", "testvolume2")
+	err := hook2.Prerun(context.Background())
+	require.ErrorIs(t, err, csimanager.ErrCapacityBudgetExceeded)
+	require.Equal(t, 0, callCounts.get("mount"))
+	require.Equal(t, 2, callCounts.get("unpublish"))
+	require.Equal(t, int64(8*gb), budget.Used())
+
+	// Once the first allocation stops, the second fits
+	require.NoError(t, hook1.Postrun(context.Background()))
+	require.Equal(t, int64(0), budget.Used())
+
+	hook2, callCounts = newHook("testvolume1", "testvolume2")
+	require.NoError(t, hook2.Prerun(context.Background()))
+	require.Equal(t, 2, callCounts.get("mount"))
+	require.Equal(t, int64(8*gb), budget.Used())
+}
+
+func TestCSIHook_WriteClaims(t *testing.T) {
+
+	writeClaims := csimanager.NewWriteClaimTracker()
+
+	newHook := func(readOnly bool, mode structs.CSIVolumeAccessMode) (*csiHook, *callCounter, *mockEventEmitter) {
+		alloc := mock.Alloc()
+		alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+			"vol0": {
+				Name:           "vol0",
+				Type:           structs.VolumeTypeCSI,
+				Source:         "testvolume0",
+				ReadOnly:       readOnly,
+				AccessMode:     mode,
+				AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+				MountOptions:   &structs.CSIMountOptions{},
+			},
+		}
+
+		callCounts := newCallCounter()
+		mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+		rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+		ar := mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+		eventer := &mockEventEmitter{}
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mgr,
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              eventer,
+			writeClaims:          writeClaims,
+			nodeSecret:           "secret",
+			stateDB:              cstate.NoopDB{},
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		return hook, callCounts, eventer
+	}
+
+	// The first allocation claims the single-writer volume for writing
+	hook1, _, _ := newHook(false, structs.CSIVolumeAccessModeSingleNodeWriter)
+	require.NoError(t, hook1.Prerun(context.Background()))
+
+	// A second write claim on the node is rejected before it reaches the
+	// server
+	hook2, callCounts, eventer := newHook(false, structs.CSIVolumeAccessModeSingleNodeWriter)
+	err := hook2.Prerun(context.Background())
+	require.ErrorIs(t, err, csimanager.ErrVolumeWriteClaimed)
+	require.Contains(t, err.Error(), `could not claim volume "vol0" for writing`)
+	require.Contains(t, err.Error(), hook1.alloc.ID)
+	require.Equal(t, 0, callCounts.get("claim"))
+	require.Equal(t, 0, callCounts.get("mount"))
+	require.Len(t, eventer.events, 1)
+	require.Equal(t, structs.TaskSetupFailure, eventer.events[0].Type)
+	require.Equal(t, map[string]string{"volume": "vol0"}, eventer.events[0].Details)
+
+	// Read claims don't conflict with the write claim
+	hook3, callCounts, _ := newHook(true, structs.CSIVolumeAccessModeSingleNodeReader)
+	require.NoError(t, hook3.Prerun(context.Background()))
+	require.Equal(t, 1, callCounts.get("claim"))
+
+	// Nor do write claims on multi-writer volumes
+	hook4, _, _ := newHook(false, structs.CSIVolumeAccessModeMultiNodeMultiWriter)
+	require.NoError(t, hook4.Prerun(context.Background()))
+	hook5, _, _ := newHook(false, structs.CSIVolumeAccessModeMultiNodeMultiWriter)
+	require.NoError(t, hook5.Prerun(context.Background()))
+	require.NoError(t, hook4.Postrun(context.Background()))
+	require.NoError(t, hook5.Postrun(context.Background()))
+
+	// Once the first allocation stops, another can claim the volume for
+	// writing
+	require.NoError(t, hook1.Postrun(context.Background()))
+	hook2, callCounts, _ = newHook(false, structs.CSIVolumeAccessModeSingleNodeWriter)
+	require.NoError(t, hook2.Prerun(context.Background()))
+	require.Equal(t, 1, callCounts.get("claim"))
+	require.Equal(t, 1, callCounts.get("mount"))
+}
+
+func TestCSIHook_Update(t *testing.T) {
+
+	writeClaims := csimanager.NewWriteClaimTracker()
+
+	volumeRequest := func(name, source string) *structs.VolumeRequest {
+		return &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         source,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			MountOptions:   &structs.CSIMountOptions{},
+		}
+	}
+
+	// update returns a copy of the alloc requesting the volumes
+	update := func(alloc *structs.Allocation, volumes ...*structs.VolumeRequest) *structs.Allocation {
+		alloc = alloc.Copy()
+		alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+		for _, vol := range volumes {
+			alloc.Job.TaskGroups[0].Volumes[vol.Name] = vol
+		}
+		return alloc
+	}
+
+	alloc := update(mock.Alloc(), volumeRequest("vol0", "testvolume0"))
+	callCounts := newCallCounter()
+	mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	db := cstate.NewMemDB(testlog.HCLogger(t))
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               testlog.HCLogger(t),
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		writeClaims:          writeClaims,
+		nodeSecret:           "secret",
+		stateDB:              db,
+		clientConfig:         clientconfig.DefaultConfig(),
+	})
+
+	// Updates before Prerun leave the volumes to Prerun
+	alloc = update(alloc, volumeRequest("vol0", "testvolume0"), volumeRequest("vol1", "testvolume1"))
+	require.NoError(t, hook.applyUpdate(context.Background(), alloc))
+	require.Zero(t, callCounts.get("claim"))
+
+	require.NoError(t, hook.Prerun(context.Background()))
+	require.Equal(t, 2, callCounts.get("claim"))
+	require.Equal(t, 2, callCounts.get("mount"))
+	mounts := ar.GetAllocHookResources().GetCSIMounts()
+	require.Len(t, mounts, 2)
+
+	t.Run("added volumes are claimed and mounted", func(t *testing.T) {
+		alloc = update(alloc, volumeRequest("vol0", "testvolume0"), volumeRequest("vol1", "testvolume1"),
+			volumeRequest("vol2", "testvolume2"))
+		require.NoError(t, hook.applyUpdate(context.Background(), alloc))
+		require.Equal(t, 3, callCounts.get("claim"))
+		require.Equal(t, 3, callCounts.get("mount"))
+		require.Zero(t, callCounts.get("unpublish"))
+
+		// The mounts of the kept volumes are unchanged
+		updated := ar.GetAllocHookResources().GetCSIMounts()
+		require.Len(t, updated, 3)
+		require.Equal(t, mounts["vol0"], updated["vol0"])
+		require.Equal(t, mounts["vol1"], updated["vol1"])
+		require.NotNil(t, updated["vol2"])
+
+		vols, err := db.GetCSIVolumes(alloc.ID)
+		require.NoError(t, err)
+		require.Len(t, vols, 3)
+		require.Equal(t, "testvolume2", vols["vol2"].Volume.ID)
+	})
+
+	t.Run("removed volumes are kept until the allocation stops", func(t *testing.T) {
+		alloc = update(alloc, volumeRequest("vol1", "testvolume1"), volumeRequest("vol2", "testvolume2"))
+		require.NoError(t, hook.applyUpdate(context.Background(), alloc))
+		require.Equal(t, 3, callCounts.get("claim"))
+		require.Zero(t, callCounts.get("unpublish"))
+
+		// The running tasks may still use the removed volume
+		updated := ar.GetAllocHookResources().GetCSIMounts()
+		require.Len(t, updated, 3)
+		require.Equal(t, mounts["vol0"], updated["vol0"])
+		require.ErrorIs(t, writeClaims.Claim("other", structs.DefaultNamespace, "testvolume0",
+			structs.CSIVolumeAccessModeSingleNodeWriter), csimanager.ErrVolumeWriteClaimed)
+
+		vols, err := db.GetCSIVolumes(alloc.ID)
+		require.NoError(t, err)
+		require.Len(t, vols, 3)
+		require.Equal(t, "testvolume0", vols["vol0"].RemovedRequest.Source)
+		require.Nil(t, vols["vol1"].RemovedRequest)
+
+		// A restored allocation keeps the removed volume
+		hook2 := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mgr,
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			stateDB:              db,
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		require.NoError(t, hook2.Prerun(context.Background()))
+		require.Equal(t, 3, callCounts.get("claim"))
+		require.Contains(t, hook2.volumeRequests, "vol0")
+		require.True(t, hook2.volumeRequests["vol0"].removed)
+	})
+
+	t.Run("changed volumes are rejected", func(t *testing.T) {
+		mounts := ar.GetAllocHookResources().GetCSIMounts()
+
+		readOnly := volumeRequest("vol1", "testvolume1")
+		readOnly.ReadOnly = true
+		readOnly.AccessMode = structs.CSIVolumeAccessModeSingleNodeReader
+		changed := update(alloc, readOnly, volumeRequest("vol2", "testvolume2"))
+		err := hook.applyUpdate(context.Background(), changed)
+		require.EqualError(t, err, `1 error occurred:
+	* volume "vol1" (source "testvolume1") can't change while the allocation's tasks may use it
+
+`)
+		require.Equal(t, 3, callCounts.get("claim"))
+		require.Zero(t, callCounts.get("unpublish"))
+		require.Equal(t, mounts, ar.GetAllocHookResources().GetCSIMounts())
+
+		// Nor can a removed volume be added back with another request
+		readOnly.Name = "vol0"
+		readOnly.Source = "testvolume0"
+		changed = update(alloc, readOnly, alloc.Job.TaskGroups[0].Volumes["vol1"], volumeRequest("vol2", "testvolume2"))
+		err = hook.applyUpdate(context.Background(), changed)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `volume "vol0" (source "testvolume0") can't change`)
+		require.Equal(t, 3, callCounts.get("claim"))
+		require.Equal(t, mounts, ar.GetAllocHookResources().GetCSIMounts())
+	})
+
+	t.Run("failed volumes are released", func(t *testing.T) {
+		// vol3 conflicts with another allocation's write claim, so it's
+		// released along with vol4 and the allocation keeps its volumes
+		require.NoError(t, writeClaims.Claim("other", structs.DefaultNamespace, "testvolume3",
+			structs.CSIVolumeAccessModeSingleNodeWriter))
+		defer writeClaims.Release("other")
+
+		mounts := ar.GetAllocHookResources().GetCSIMounts()
+		failing := update(alloc, alloc.Job.TaskGroups[0].Volumes["vol1"], volumeRequest("vol2", "testvolume2"),
+			volumeRequest("vol3", "testvolume3"), volumeRequest("vol4", "testvolume4"))
+		err := hook.applyUpdate(context.Background(), failing)
+		require.ErrorIs(t, err, csimanager.ErrVolumeWriteClaimed)
+		require.Equal(t, mounts, ar.GetAllocHookResources().GetCSIMounts())
+		require.Equal(t, 3, callCounts.get("mount"))
+
+		// Stopping the allocation unpublishes the volumes it kept, including
+		// the removed one
+		require.NoError(t, hook.Postrun(context.Background()))
+		require.Equal(t, 3, callCounts.get("unpublish"))
+	})
+
+	t.Run("destroyed allocations cancel the claims", func(t *testing.T) {
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mgr,
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			stateDB:              cstate.NoopDB{},
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		require.NoError(t, hook.Prerun(context.Background()))
+		claims := callCounts.get("claim")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		added := update(alloc, alloc.Job.TaskGroups[0].Volumes["vol1"], volumeRequest("vol2", "testvolume2"),
+			volumeRequest("vol5", "testvolume5"))
+		err := hook.applyUpdate(ctx, added)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, claims, callCounts.get("claim"))
+		require.NotContains(t, ar.GetAllocHookResources().GetCSIMounts(), "vol5")
+	})
+}
+
+// TestCSIHook_Update_Async asserts that updates don't wait for the claims and
+// mounts of Prerun, and that stopping the allocation cancels the claims and
+// mounts of an update.
+func TestCSIHook_Update_Async(t *testing.T) {
+
+	volumes := func(alloc *structs.Allocation, sources ...string) *structs.Allocation {
+		alloc = alloc.Copy()
+		alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+		for i, source := range sources {
+			name := fmt.Sprintf("vol%d", i)
+			alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+				Name:           name,
+				Type:           structs.VolumeTypeCSI,
+				Source:         source,
+				AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+				AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+				MountOptions:   &structs.CSIMountOptions{},
+			}
+		}
+		return alloc
+	}
+
+	newHook := func(alloc *structs.Allocation, mounter csimanager.VolumeMounter, callCounts *callCounter) (*csiHook, mockAllocRunner) {
+		ar := mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mockPluginManager{mounter: mounter},
+			rpcClient:            mockRPCer{alloc: alloc, callCounts: callCounts},
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			stateDB:              cstate.NoopDB{},
+			clientConfig:         clientconfig.DefaultConfig(),
+		})
+		return hook, ar
+	}
+
+	t.Run("updates don't wait for prerun", func(t *testing.T) {
+		alloc := volumes(mock.Alloc(), "testvolume0")
+		callCounts := newCallCounter()
+		mounter := &mockConcurrentVolumeMounter{
+			mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+			started:           make(chan string, 2),
+			release:           make(chan struct{}),
+		}
+		hook, ar := newHook(alloc, mounter, callCounts)
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- hook.Prerun(context.Background())
+		}()
+		select {
+		case <-mounter.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for prerun to mount")
+		}
+
+		// The update returns while Prerun is mounting
+		updated := make(chan error, 1)
+		go func() {
+			updated <- hook.Update(&interfaces.RunnerUpdateRequest{
+				Alloc: volumes(alloc, "testvolume0", "testvolume1"),
+				Ctx:   context.Background(),
+			})
+		}()
+		select {
+		case err := <-updated:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("update waited for prerun")
+		}
+
+		// The added volume is claimed and mounted once Prerun is done
+		close(mounter.release)
+		require.NoError(t, <-errCh)
+		testutil.WaitForResult(func() (bool, error) {
+			mounts := ar.GetAllocHookResources().GetCSIMounts()
+			return len(mounts) == 2, fmt.Errorf("expected 2 mounts, got %d", len(mounts))
+		}, func(err error) {
+			t.Fatal(err)
+		})
+		require.Equal(t, 2, callCounts.get("claim"))
+	})
+
+	t.Run("stopping cancels updates", func(t *testing.T) {
+		alloc := volumes(mock.Alloc(), "testvolume0")
+		callCounts := newCallCounter()
+		mounter := mockBlockingVolumeMounter{
+			mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+			succeed:           1,
+		}
+		hook, ar := newHook(alloc, mounter, callCounts)
+		require.NoError(t, hook.Prerun(context.Background()))
+
+		// The added volume's mount blocks until it's cancelled
+		require.NoError(t, hook.Update(&interfaces.RunnerUpdateRequest{
+			Alloc: volumes(alloc, "testvolume0", "testvolume1"),
+			Ctx:   context.Background(),
+		}))
+		testutil.WaitForResult(func() (bool, error) {
+			return callCounts.get("blocked") == 1, fmt.Errorf("mount not blocked")
+		}, func(err error) {
+			t.Fatal(err)
+		})
+
+		start := time.Now()
+		require.NoError(t, hook.Postrun(context.Background()))
+		require.Less(t, time.Since(start), 5*time.Second)
+
+		// Both the kept volume and the cancelled one are released, and
+		// later updates are dropped
+		require.Equal(t, 2, callCounts.get("unpublish"))
+		require.Len(t, ar.GetAllocHookResources().GetCSIMounts(), 1)
+		require.NoError(t, hook.Update(&interfaces.RunnerUpdateRequest{
+			Alloc: volumes(alloc, "testvolume0", "testvolume2"),
+			Ctx:   context.Background(),
+		}))
+		require.Equal(t, 2, callCounts.get("claim"))
+	})
+}
+
+func TestCSIHook_MissingTaskGroup(t *testing.T) {
+
+	alloc := mock.Alloc()
+	alloc.TaskGroup = "missing"
+
+	callCounts := newCallCounter()
+	mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               testlog.HCLogger(t),
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         clientconfig.DefaultConfig(),
+	})
+
+	require.EqualError(t, hook.Prerun(context.Background()), `task group "missing" not found in job`)
+	_, err := hook.claimVolumesFromAlloc(context.Background(), nil)
+	require.EqualError(t, err, `task group "missing" not found in job`)
+	require.EqualError(t, hook.Postrun(context.Background()), `task group "missing" not found in job`)
+	require.Zero(t, callCounts.get("claim"))
+	require.Zero(t, callCounts.get("unpublish"))
+}
+
+func TestCSIHook_IdempotencyKeys(t *testing.T) {
+
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{}
+	for i := 0; i < 2; i++ {
+		name := fmt.Sprintf("vol%d", i)
+		alloc.Job.TaskGroups[0].Volumes[name] = &structs.VolumeRequest{
+			Name:           name,
+			Type:           structs.VolumeTypeCSI,
+			Source:         fmt.Sprintf("testvolume%d", i),
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			MountOptions:   &structs.CSIMountOptions{},
+		}
+	}
+
+	db := cstate.NewMemDB(testlog.HCLogger(t))
+	rpcer := &operationRPCer{
+		mockRPCer:   mockRPCer{alloc: alloc, callCounts: newCallCounter()},
+		claims:      map[string]int{},
+		unpublishes: map[string]int{},
+	}
+
+	// newHook returns a hook for the alloc sharing db and rpcer, as the
+	// restored alloc's hook does after the client restarts
+	newHook := func(enabled bool) (*csiHook, *callCounter) {
+		callCounts := newCallCounter()
+		mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+		ar := mockAllocRunner{
+			res: &cstructs.AllocHookResources{},
+			caps: &drivers.Capabilities{
+				FSIsolation:  drivers.FSIsolationChroot,
+				MountConfigs: drivers.MountConfigSupportAll,
+			},
+		}
+		conf := clientconfig.DefaultConfig()
+		conf.CSIIdempotencyKeys = enabled
+		hook := newCSIHook(csiHookConfig{
+			alloc:                alloc,
+			logger:               testlog.HCLogger(t),
+			csiManager:           mgr,
+			rpcClient:            rpcer,
+			taskCapabilityGetter: ar,
+			hookResources:        ar,
+			eventer:              &mockEventEmitter{},
+			nodeSecret:           "secret",
+			stateDB:              db,
+			clientConfig:         conf,
+		})
+		return hook, callCounts
+	}
+
+	// The client restarts after claiming the volumes, before mounting them
+	hook, _ := newHook(true)
+	_, err := hook.claimVolumesFromAlloc(context.Background(), nil)
+	require.NoError(t, err)
+	vols, err := db.GetCSIVolumes(alloc.ID)
+	require.NoError(t, err)
+	require.Len(t, vols, 2)
+
+	// The restored alloc claims the volumes it didn't mount again
+	hook, callCounts := newHook(true)
+	require.NoError(t, hook.Prerun(context.Background()))
+	require.Equal(t, 2, callCounts.get("mount"))
+	require.Equal(t, map[string]int{"testvolume0": 2, "testvolume1": 2}, rpcer.claims)
+
+	// Servers don't deduplicate requests, so none carries a token
+	require.False(t, rpcer.tokens)
+
+	// The client restarts after releasing the first volume's claim
+	ctx, cancel := context.WithCancel(context.Background())
+	rpcer.cancel = cancel
+	require.Error(t, hook.Postrun(ctx))
+	rpcer.cancel = nil
+	require.Equal(t, 1, rpcer.callCounts.get("unpublish"))
+
+	// The restored alloc has stopped, so it doesn't run Prerun, and its
+	// Postrun only releases the claim left to release
+	hook, _ = newHook(true)
+	require.NoError(t, hook.Postrun(context.Background()))
+	require.Equal(t, 2, rpcer.callCounts.get("unpublish"))
+	require.Equal(t, map[string]int{"testvolume0": 1, "testvolume1": 1}, rpcer.unpublishes)
+
+	vols, err = db.GetCSIVolumes(alloc.ID)
+	require.NoError(t, err)
+	require.Empty(t, vols)
+
+	// Otherwise nothing is recorded before the volumes are mounted
+	hook, _ = newHook(false)
+	_, err = hook.claimVolumesFromAlloc(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, rpcer.claims["testvolume0"])
+	vols, err = db.GetCSIVolumes(alloc.ID)
+	require.NoError(t, err)
+	require.Empty(t, vols)
+}
+
+// HELPERS AND MOCKS
+
+type mockEventEmitter struct {
+	events []*structs.TaskEvent
+	lock   sync.Mutex
+}
+
+func (m *mockEventEmitter) EmitEvent(event *structs.TaskEvent) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.events = append(m.events, event)
+}
+
+func testVolume(id string) *structs.CSIVolume {
+	vol := structs.NewCSIVolume(id, 0)
+	vol.PluginID = "minnie"
+	vol.Schedulable = true
+	vol.RequestedCapabilities = []*structs.CSIVolumeCapability{
+		{
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeReader,
+		},
+		{
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+		},
+	}
+	return vol
+}
+
+type mockRPCer struct {
+	alloc      *structs.Allocation
+	callCounts *callCounter
+
+	// capacities are the capacities of claimed volumes by ID
+	capacities map[string]int64
+
+	// secrets are the secrets of claimed volumes
+	secrets structs.CSISecrets
+}
+
+// RPC mocks the server RPCs, acting as though any request succeeds
+func (r mockRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	switch method {
+	case "CSIVolume.Claim":
+		r.callCounts.inc("claim")
+		req := args.(*structs.CSIVolumeClaimRequest)
+		vol := testVolume(req.VolumeID)
+		vol.Capacity = r.capacities[req.VolumeID]
+		for name, value := range r.secrets {
+			vol.Secrets[name] = value
+		}
+		err := vol.Claim(req.ToClaim(), r.alloc)
+		if err != nil {
+			return err
+		}
+
+		resp := reply.(*structs.CSIVolumeClaimResponse)
+		resp.PublishContext = map[string]string{}
+		resp.Volume = vol
+		resp.QueryMeta = structs.QueryMeta{}
+	case "CSIVolume.Unpublish":
+		r.callCounts.inc("unpublish")
+		resp := reply.(*structs.CSIVolumeUnpublishResponse)
+		resp.QueryMeta = structs.QueryMeta{}
+	default:
+		// Servers fail the methods they don't have as net/rpc does
+		return fmt.Errorf("rpc: can't find method %s", method)
+	}
+	return nil
+}
+
+// batchRPCer supports batched volume claims, making each claim of a batch
+// with the mockRPCer, or failing every batch with err if it's set
+type batchRPCer struct {
+	mockRPCer
+	err error
+}
+
+func (r batchRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	if method != "CSIVolume.ClaimBatch" {
+		return r.mockRPCer.RPC(method, args, reply)
+	}
+
+	r.callCounts.inc("claim_batch")
+	if r.err != nil {
+		return r.err
+	}
+	req := args.(*structs.CSIVolumeBatchClaimRequest)
+	resp := reply.(*structs.CSIVolumeBatchClaimResponse)
+	for _, claim := range req.Claims {
+		var claimResp structs.CSIVolumeClaimResponse
+		if err := r.mockRPCer.RPC("CSIVolume.Claim", claim, &claimResp); err != nil {
+			return err
+		}
+		resp.Claims = append(resp.Claims, &claimResp)
+	}
+	return nil
+}
+
+// noBatchRPCer counts the batched volume claims it receives, failing them as
+// servers without the endpoint do
+type noBatchRPCer struct {
+	mockRPCer
+}
+
+func (r noBatchRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	if method == "CSIVolume.ClaimBatch" {
+		r.callCounts.inc("claim_batch")
+	}
+	return r.mockRPCer.RPC(method, args, reply)
+}
+
+// healthRPCer sets the health of the volumes claimed with the mockRPCer
+type healthRPCer struct {
+	mockRPCer
+	setHealth func(vol *structs.CSIVolume)
+}
+
+func (r healthRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	if err := r.mockRPCer.RPC(method, args, reply); err != nil {
+		return err
+	}
+	if resp, ok := reply.(*structs.CSIVolumeClaimResponse); ok {
+		r.setHealth(resp.Volume)
+	}
+	return nil
+}
+
+// topologyRPCer records the claim requests it receives, and returns claimed
+// volumes with the given topologies
+type topologyRPCer struct {
+	recordingRPCer
+	topologies []*structs.CSITopology
+}
+
+func (r *topologyRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	if err := r.recordingRPCer.RPC(method, args, reply); err != nil {
+		return err
+	}
+	if resp, ok := reply.(*structs.CSIVolumeClaimResponse); ok {
+		resp.Volume.Topologies = r.topologies
+	}
+	return nil
+}
+
+// recordingRPCer records the claim requests it receives
+type recordingRPCer struct {
+	mockRPCer
+	claims []*structs.CSIVolumeClaimRequest
+}
+
+func (r *recordingRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	if req, ok := args.(*structs.CSIVolumeClaimRequest); ok {
+		r.claims = append(r.claims, req)
+	}
+	return r.mockRPCer.RPC(method, args, reply)
+}
+
+// operationRPCer counts the claim and unpublish requests it receives by
+// volume ID, records whether any carried an idempotency token, and calls
+// cancel, if set, after each unpublish request
+type operationRPCer struct {
+	mockRPCer
+	claims      map[string]int
+	unpublishes map[string]int
+	tokens      bool
+	cancel      func()
+}
+
+func (r *operationRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	switch req := args.(type) {
+	case *structs.CSIVolumeClaimRequest:
+		r.claims[req.VolumeID]++
+		r.tokens = r.tokens || req.IdempotencyToken != ""
+	case *structs.CSIVolumeUnpublishRequest:
+		r.unpublishes[req.VolumeID]++
+		r.tokens = r.tokens || req.IdempotencyToken != ""
+		if r.cancel != nil {
+			defer r.cancel()
+		}
+	}
+	return r.mockRPCer.RPC(method, args, reply)
+}
+
+// unpublishOrderRPCer records the volumes of the unpublish requests it
+// receives in order
+type unpublishOrderRPCer struct {
+	mockRPCer
+	unpublished []string
+}
+
+func (r *unpublishOrderRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	if req, ok := args.(*structs.CSIVolumeUnpublishRequest); ok {
+		r.unpublished = append(r.unpublished, req.VolumeID)
+	}
+	return r.mockRPCer.RPC(method, args, reply)
+}
+
+// flakyClaimRPCer fails the first volume claims with err, before passing
+// the rest to the mockRPCer
+type flakyClaimRPCer struct {
+	mockRPCer
+	err      error
+	failures int
+}
+
+func (r flakyClaimRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	if method == "CSIVolume.Claim" {
+		r.callCounts.inc("claim_attempt")
+		if r.callCounts.incBelow("claim_failure", r.failures) {
+			return r.err
+		}
+	}
+	return r.mockRPCer.RPC(method, args, reply)
+}
+
+// flakyUnpublishRPCer fails the first unpublish requests for volumeID with
+// err, before passing the rest to the mockRPCer
+type flakyUnpublishRPCer struct {
+	mockRPCer
+	volumeID string
+	err      error
+	failures int
+}
+
+func (r flakyUnpublishRPCer) RPC(method string, args interface{}, reply interface{}) error {
+	if method == "CSIVolume.Unpublish" && args.(*structs.CSIVolumeUnpublishRequest).VolumeID == r.volumeID {
+		r.callCounts.inc("unpublish_attempt")
+		if r.callCounts.incBelow("unpublish_failure", r.failures) {
+			return r.err
+		}
+	}
+	return r.mockRPCer.RPC(method, args, reply)
+}
+
+// callCounter counts the calls made to the mocks. Volumes are mounted
+// concurrently, so it's safe for concurrent use.
+type callCounter struct {
+	counts map[string]int
+	lock   sync.Mutex
+}
+
+func newCallCounter() *callCounter {
+	return &callCounter{counts: map[string]int{}}
+}
+
+func (c *callCounter) inc(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counts[name]++
+}
+
+// incBelow increments the count if it's below max, returning whether it did.
+func (c *callCounter) incBelow(name string, max int) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.counts[name] >= max {
+		return false
+	}
+	c.counts[name]++
+	return true
+}
+
+func (c *callCounter) get(name string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.counts[name]
+}
+
+func mockMountInfo(vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions) *csimanager.MountInfo {
+	return &csimanager.MountInfo{
+		Source: filepath.Join("test-alloc-dir", alloc.ID, vol.ID, usageOpts.ToFS()),
+	}
+}
+
+type mockVolumeMounter struct {
+	callCounts *callCounter
+	restoreErr error
+	topology   *structs.CSITopology
+}
+
+func (vm mockVolumeMounter) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions, publishContext map[string]string) (*csimanager.MountInfo, error) {
+	vm.callCounts.inc("mount")
+	return mockMountInfo(vol, alloc, usageOpts), nil
+}
+func (vm mockVolumeMounter) UnmountVolume(ctx context.Context, volID, remoteID, allocID string, usageOpts *csimanager.UsageOptions) error {
+	vm.callCounts.inc("unmount")
+	return nil
+}
+func (vm mockVolumeMounter) RestoreVolume(vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions, mountInfo *csimanager.MountInfo) error {
+	vm.callCounts.inc("restore")
+	return vm.restoreErr
+}
+func (vm mockVolumeMounter) NodeTopology() *structs.CSITopology {
+	return vm.topology
+}
+
+// dirVolumeMounter creates the target directory of the volumes it mounts
+// under root, as the csimanager does
+type dirVolumeMounter struct {
+	mockVolumeMounter
+	root string
+}
+
+func (vm dirVolumeMounter) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions, publishContext map[string]string) (*csimanager.MountInfo, error) {
+	vm.callCounts.inc("mount")
+	target := filepath.Join(vm.root, csimanager.AllocSpecificDirName, alloc.ID, vol.ID, usageOpts.ToFS())
+	if err := os.MkdirAll(target, 0700); err != nil {
+		return nil, err
+	}
+	return &csimanager.MountInfo{Source: target}, nil
+}
+
+// mockSecretsVolumeMounter records the secrets of the volumes it mounts
+type mockSecretsVolumeMounter struct {
+	mockVolumeMounter
+	secrets map[string]structs.CSISecrets
+	lock    sync.Mutex
+}
+
+func (vm *mockSecretsVolumeMounter) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions, publishContext map[string]string) (*csimanager.MountInfo, error) {
+	vm.lock.Lock()
+	vm.secrets[vol.ID] = vol.Secrets
+	vm.lock.Unlock()
+	return vm.mockVolumeMounter.MountVolume(ctx, vol, alloc, usageOpts, publishContext)
+}
+
+// mockDrainVolumeMounter records the volumes it unmounts in order, and blocks
+// the unmount of the block volume until its context is cancelled
+type mockDrainVolumeMounter struct {
+	mockVolumeMounter
+	block     string
+	unmounted []string
+	lock      sync.Mutex
+}
+
+func (vm *mockDrainVolumeMounter) UnmountVolume(ctx context.Context, volID, remoteID, allocID string, usageOpts *csimanager.UsageOptions) error {
+	if volID == vm.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	vm.lock.Lock()
+	vm.unmounted = append(vm.unmounted, volID)
+	vm.lock.Unlock()
+	return vm.mockVolumeMounter.UnmountVolume(ctx, volID, remoteID, allocID, usageOpts)
+}
+
+// mockBlockingVolumeMounter mounts the first succeed volumes and then blocks
+// every further mount until its context is cancelled.
+type mockBlockingVolumeMounter struct {
+	mockVolumeMounter
+	succeed int
+}
+
+func (vm mockBlockingVolumeMounter) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions, publishContext map[string]string) (*csimanager.MountInfo, error) {
+	if vm.callCounts.incBelow("mount", vm.succeed) {
+		return mockMountInfo(vol, alloc, usageOpts), nil
+	}
+	vm.callCounts.inc("blocked")
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// mockFailingVolumeMounter fails to mount the fail volume, and records the
+// volumes it mounts and unmounts.
+type mockFailingVolumeMounter struct {
+	mockVolumeMounter
+	fail      string
+	mounted   []string
+	unmounted map[string]bool
+	lock      sync.Mutex
+}
+
+func (vm *mockFailingVolumeMounter) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions, publishContext map[string]string) (*csimanager.MountInfo, error) {
+	if vol.ID == vm.fail {
+		return nil, fmt.Errorf("mount of %s failed", vol.ID)
+	}
+
+	vm.lock.Lock()
+	vm.mounted = append(vm.mounted, vol.ID)
+	vm.lock.Unlock()
+	return vm.mockVolumeMounter.MountVolume(ctx, vol, alloc, usageOpts, publishContext)
+}
+
+func (vm *mockFailingVolumeMounter) UnmountVolume(ctx context.Context, volID, remoteID, allocID string, usageOpts *csimanager.UsageOptions) error {
+	vm.lock.Lock()
+	vm.unmounted[volID] = true
+	vm.lock.Unlock()
+	return vm.mockVolumeMounter.UnmountVolume(ctx, volID, remoteID, allocID, usageOpts)
+}
+
+func (vm *mockFailingVolumeMounter) mountedVolumes() []string {
+	vm.lock.Lock()
+	defer vm.lock.Unlock()
+	return append([]string{}, vm.mounted...)
+}
+
+func (vm *mockFailingVolumeMounter) isUnmounted(volID string) bool {
+	vm.lock.Lock()
+	defer vm.lock.Unlock()
+	return vm.unmounted[volID]
+}
+
+// mockConcurrentVolumeMounter signals started as each mount starts, and
+// blocks the mount until release is closed or its context is cancelled.
+type mockConcurrentVolumeMounter struct {
+	mockVolumeMounter
+	started chan string
+	release chan struct{}
+}
+
+func (vm *mockConcurrentVolumeMounter) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *csimanager.UsageOptions, publishContext map[string]string) (*csimanager.MountInfo, error) {
+	vm.started <- vol.ID
+	select {
+	case <-vm.release:
+		return vm.mockVolumeMounter.MountVolume(ctx, vol, alloc, usageOpts, publishContext)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type mockPluginManager struct {
+	mounter    csimanager.VolumeMounter
+	topologies map[string]*structs.CSITopology
+}
+
+func (mgr mockPluginManager) MounterForPlugin(ctx context.Context, pluginID string) (csimanager.VolumeMounter, error) {
+	return mgr.mounter, nil
+}
+
+// mockUnavailablePluginManager returns err for the first availableAfter
+// lookups of a plugin, or for every lookup if availableAfter is negative.
+type mockUnavailablePluginManager struct {
+	mockPluginManager
+	err            error
+	availableAfter int
+	calls          int
+}
+
+func (mgr *mockUnavailablePluginManager) MounterForPlugin(ctx context.Context, pluginID string) (csimanager.VolumeMounter, error) {
+	mgr.calls++
+	if mgr.availableAfter < 0 || mgr.calls <= mgr.availableAfter {
+		return nil, fmt.Errorf("plugin %s for type csi-node not found: %w", pluginID, mgr.err)
+	}
+	return mgr.mockPluginManager.MounterForPlugin(ctx, pluginID)
+}
+
+// no-op methods to fulfill the interface
+func (mgr mockPluginManager) PluginManager() pluginmanager.PluginManager { return nil }
+func (mgr mockPluginManager) Shutdown()                                  {}
+func (mgr mockPluginManager) DegradedPlugins() []string                  { return nil }
+func (mgr mockPluginManager) NodeTopologies() map[string]*structs.CSITopology {
+	return mgr.topologies
+}
+func (mgr mockPluginManager) ValidateVolumes(*structs.Allocation, map[string]*structs.CSIVolume) map[string]error {
+	return nil
+}
+
+type mockAllocRunner struct {
+	res  *cstructs.AllocHookResources
+	caps *drivers.Capabilities
+}
+
+func (ar mockAllocRunner) GetAllocHookResources() *cstructs.AllocHookResources {
+	return ar.res
+}
+
+func (ar mockAllocRunner) SetAllocHookResources(res *cstructs.AllocHookResources) {
+	ar.res = res
+}
+
+func (ar mockAllocRunner) GetTaskDriverCapabilities(taskName string) (*drivers.Capabilities, error) {
+	return ar.caps, nil
+}
+
+func TestCSIHook_ValidateModes(t *testing.T) {
+	testcases := []struct {
+		name      string
+		readOnly  bool
+		access    structs.CSIVolumeAccessMode
+		attach    structs.CSIVolumeAttachmentMode
+		options   *structs.CSIMountOptions
+		expectErr string
+	}{
+		{
+			name:   "filesystem writer with mount options",
+			access: structs.CSIVolumeAccessModeSingleNodeWriter,
+			attach: structs.CSIVolumeAttachmentModeFilesystem,
+			options: &structs.CSIMountOptions{
+				FSType:     "ext4",
+				MountFlags: []string{"noatime"},
+			},
+		},
+		{
+			name:     "block device reader",
+			readOnly: true,
+			access:   structs.CSIVolumeAccessModeMultiNodeReader,
+			attach:   structs.CSIVolumeAttachmentModeBlockDevice,
+		},
+		{
+			name:    "block device with empty mount options",
+			access:  structs.CSIVolumeAccessModeMultiNodeMultiWriter,
+			attach:  structs.CSIVolumeAttachmentModeBlockDevice,
+			options: &structs.CSIMountOptions{},
+		},
+		{
+			name:      "missing access mode",
+			attach:    structs.CSIVolumeAttachmentModeFilesystem,
+			expectErr: `unknown access mode ""`,
+		},
+		{
+			name:      "unknown access mode",
+			access:    "single-node-multi-writer",
+			attach:    structs.CSIVolumeAttachmentModeFilesystem,
+			expectErr: `unknown access mode "single-node-multi-writer"`,
+		},
+		{
+			name:      "missing attachment mode",
+			access:    structs.CSIVolumeAccessModeSingleNodeWriter,
+			expectErr: `unknown attachment mode ""`,
+		},
+		{
+			name:      "unknown attachment mode",
+			access:    structs.CSIVolumeAccessModeSingleNodeWriter,
+			attach:    "object",
+			expectErr: `unknown attachment mode "object"`,
+		},
+		{
+			name:      "single node reader not read only",
+			access:    structs.CSIVolumeAccessModeSingleNodeReader,
+			attach:    structs.CSIVolumeAttachmentModeFilesystem,
+			expectErr: `access mode "single-node-reader-only" requires the volume to be read_only`,
+		},
+		{
+			name:      "multi node reader not read only",
+			access:    structs.CSIVolumeAccessModeMultiNodeReader,
+			attach:    structs.CSIVolumeAttachmentModeBlockDevice,
+			expectErr: `access mode "multi-node-reader-only" requires the volume to be read_only`,
+		},
+		{
+			name:      "block device with filesystem type",
+			access:    structs.CSIVolumeAccessModeSingleNodeWriter,
+			attach:    structs.CSIVolumeAttachmentModeBlockDevice,
+			options:   &structs.CSIMountOptions{FSType: "xfs"},
+			expectErr: `attachment mode "block-device" cannot have mount options`,
+		},
+		{
+			name:      "block device with mount flags",
+			readOnly:  true,
+			access:    structs.CSIVolumeAccessModeSingleNodeReader,
+			attach:    structs.CSIVolumeAttachmentModeBlockDevice,
+			options:   &structs.CSIMountOptions{MountFlags: []string{"ro"}},
+			expectErr: `attachment mode "block-device" cannot have mount options`,
+		},
+		{
+			name:      "conflicting mount flags",
+			access:    structs.CSIVolumeAccessModeSingleNodeWriter,
+			attach:    structs.CSIVolumeAttachmentModeFilesystem,
+			options:   &structs.CSIMountOptions{MountFlags: []string{"noexec", "exec"}},
+			expectErr: `mount flags "exec" and "noexec" conflict`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					ReadOnly:       tc.readOnly,
+					AccessMode:     tc.access,
+					AttachmentMode: tc.attach,
+					MountOptions:   tc.options,
+				},
+			}
+
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+			rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               testlog.HCLogger(t),
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              &mockEventEmitter{},
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         clientconfig.DefaultConfig(),
+			})
+
+			err := hook.Prerun(context.Background())
+			if tc.expectErr == "" {
+				require.NoError(t, err)
+				require.Equal(t, 1, callCounts.get("claim"))
+				return
+			}
+
+			// Invalid requests are rejected before any RPC is sent
+			var modeErr *csiModeError
+			require.ErrorAs(t, err, &modeErr)
+			require.Equal(t, "vol0", modeErr.alias)
+			require.Equal(t, tc.expectErr, modeErr.reason)
+			require.EqualError(t, err, `claim volumes: volume "vol0" (source "testvolume0") has an invalid mode: `+tc.expectErr)
+			require.Zero(t, callCounts.get("claim"))
+			require.Zero(t, callCounts.get("mount"))
+		})
+	}
+}
+
+func TestNormalizeMountOptions(t *testing.T) {
+	testcases := []struct {
+		name      string
+		options   *structs.CSIMountOptions
+		expected  *structs.CSIMountOptions
+		expectErr string
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name:     "no flags",
+			options:  &structs.CSIMountOptions{FSType: "ext4"},
+			expected: &structs.CSIMountOptions{FSType: "ext4"},
+		},
+		{
+			name: "sorted",
+			options: &structs.CSIMountOptions{
+				FSType:     "xfs",
+				MountFlags: []string{"noatime", "nodev", "discard"},
+			},
+			expected: &structs.CSIMountOptions{
+				FSType:     "xfs",
+				MountFlags: []string{"discard", "noatime", "nodev"},
+			},
+		},
+		{
+			name: "duplicates dropped",
+			options: &structs.CSIMountOptions{
+				MountFlags: []string{"ro", " noatime", "ro", "noatime ", "uid=1000", "uid=1000"},
+			},
+			expected: &structs.CSIMountOptions{
+				MountFlags: []string{"noatime", "ro", "uid=1000"},
+			},
+		},
+		{
+			name:     "empty flags dropped",
+			options:  &structs.CSIMountOptions{MountFlags: []string{"", " "}},
+			expected: &structs.CSIMountOptions{},
+		},
+		{
+			name:      "ro and rw",
+			options:   &structs.CSIMountOptions{MountFlags: []string{"rw", "noatime", "ro"}},
+			expectErr: `mount flags "ro" and "rw" conflict`,
+		},
+		{
+			name:      "sync and async",
+			options:   &structs.CSIMountOptions{MountFlags: []string{"async", "sync"}},
+			expectErr: `mount flags "sync" and "async" conflict`,
+		},
+		{
+			name:      "option with different values",
+			options:   &structs.CSIMountOptions{MountFlags: []string{"password=foo", "password=bar"}},
+			expectErr: `mount option "password" is set more than once with different values`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var original *structs.CSIMountOptions
+			if tc.options != nil {
+				original = tc.options.Copy()
+			}
+
+			normalized, err := normalizeMountOptions(tc.options)
+			if tc.expectErr != "" {
+				require.EqualError(t, err, tc.expectErr)
+				require.Nil(t, normalized)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, normalized)
+
+			// The options passed in are left as they were
+			require.Equal(t, original, tc.options)
+		})
+	}
+}
+
+func TestCSIHook_VolumeHealthCheck(t *testing.T) {
+	healthy := func(vol *structs.CSIVolume) {
+		vol.NodesHealthy = 1
+	}
+	noNodes := func(vol *structs.CSIVolume) {
+		vol.Schedulable = false
+	}
+	noControllers := func(vol *structs.CSIVolume) {
+		vol.Schedulable = false
+		vol.NodesHealthy = 1
+		vol.ControllerRequired = true
+	}
+
+	testcases := []struct {
+		name        string
+		healthCheck bool
+		setHealth   func(vol *structs.CSIVolume)
+		expectErr   string
+	}{
+		{
+			name:        "healthy",
+			healthCheck: true,
+			setHealth:   healthy,
+		},
+		{
+			name:        "no healthy nodes",
+			healthCheck: true,
+			setHealth:   noNodes,
+			expectErr:   `volume "vol0" (source "testvolume0") is unhealthy: plugin "minnie" has no healthy nodes`,
+		},
+		{
+			name:        "no healthy controllers",
+			healthCheck: true,
+			setHealth:   noControllers,
+			expectErr:   `volume "vol0" (source "testvolume0") is unhealthy: plugin "minnie" has no healthy controllers`,
+		},
+		{
+			name:      "unhealthy without the check",
+			setHealth: noNodes,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+				},
+			}
+
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+			rpcer := healthRPCer{
+				mockRPCer: mockRPCer{alloc: alloc, callCounts: callCounts},
+				setHealth: tc.setHealth,
+			}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			eventer := &mockEventEmitter{}
+			config := clientconfig.DefaultConfig()
+			config.CSIVolumeHealthCheck = tc.healthCheck
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               testlog.HCLogger(t),
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              eventer,
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         config,
+			})
+
+			err := hook.Prerun(context.Background())
+			require.Equal(t, 1, callCounts.get("claim"))
+			if tc.expectErr == "" {
+				require.NoError(t, err)
+				require.Equal(t, 1, callCounts.get("mount"))
+				return
+			}
+
+			// The unhealthy volume is never passed to the node plugin
+			var healthErr *csiVolumeUnhealthyError
+			require.ErrorAs(t, err, &healthErr)
+			require.Contains(t, err.Error(), tc.expectErr)
+			require.Zero(t, callCounts.get("mount"))
+
+			var failed *structs.TaskEvent
+			for _, event := range eventer.events {
+				if event.Type == structs.TaskSetupFailure {
+					failed = event
+				}
+			}
+			require.NotNil(t, failed)
+			require.Equal(t, "vol0", failed.Details["volume"])
+			require.Contains(t, failed.DisplayMessage, `Volume "vol0" is unhealthy`)
+		})
+	}
+}
+
+func TestCSIHook_MountMetadata(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			root := t.TempDir()
+
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+				},
+			}
+
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: dirVolumeMounter{
+				mockVolumeMounter: mockVolumeMounter{callCounts: callCounts},
+				root:              root,
+			}}
+			rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			config := clientconfig.DefaultConfig()
+			config.CSIMountMetadata = enabled
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               testlog.HCLogger(t),
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              &mockEventEmitter{},
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         config,
+			})
+
+			require.NoError(t, hook.Prerun(context.Background()))
+			mounts := ar.GetAllocHookResources().GetCSIMounts()
+			require.Contains(t, mounts, "vol0")
+			path := filepath.Join(filepath.Dir(mounts["vol0"].Source), csimanager.MountMetadataFileName)
+
+			if !enabled {
+				require.NoFileExists(t, path)
+				require.NoError(t, hook.Postrun(context.Background()))
+				return
+			}
+
+			// The metadata describes the allocation using the mount
+			buf, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			var metadata csiMountMetadata
+			require.NoError(t, json.Unmarshal(buf, &metadata))
+			require.Equal(t, csiMountMetadata{
+				AllocID:   alloc.ID,
+				Namespace: alloc.Namespace,
+				JobID:     alloc.JobID,
+				TaskGroup: alloc.TaskGroup,
+				VolumeID:  "testvolume0",
+				PluginID:  "minnie",
+			}, metadata)
+
+			// The metadata is removed once the volume is released
+			require.NoError(t, hook.Postrun(context.Background()))
+			require.Equal(t, 1, callCounts.get("unpublish"))
+			require.NoFileExists(t, path)
+		})
+	}
+}
+
+// TestCSIHook_AllocCSIMounts asserts the alloc runner reports the mounts
+// Prerun stored, with the volume mounted under each alias.
+func TestCSIHook_AllocCSIMounts(t *testing.T) {
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+		"vol0": {
+			Name:           "vol0",
+			Type:           structs.VolumeTypeCSI,
+			Source:         "testvolume0",
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		},
+		"vol1": {
+			Name:           "vol1",
+			Type:           structs.VolumeTypeCSI,
+			Source:         "testvolume1",
+			ReadOnly:       true,
+			AccessMode:     structs.CSIVolumeAccessModeMultiNodeReader,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		},
+	}
+
+	callCounts := newCallCounter()
+	mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts}}
+	rpcer := mockRPCer{alloc: alloc, callCounts: callCounts}
+	ar := mockAllocRunner{
+		res: &cstructs.AllocHookResources{},
+		caps: &drivers.Capabilities{
+			FSIsolation:  drivers.FSIsolationChroot,
+			MountConfigs: drivers.MountConfigSupportAll,
+		},
+	}
+	hook := newCSIHook(csiHookConfig{
+		alloc:                alloc,
+		logger:               testlog.HCLogger(t),
+		csiManager:           mgr,
+		rpcClient:            rpcer,
+		taskCapabilityGetter: ar,
+		hookResources:        ar,
+		eventer:              &mockEventEmitter{},
+		nodeSecret:           "secret",
+		stateDB:              cstate.NoopDB{},
+		clientConfig:         clientconfig.DefaultConfig(),
+	})
+	require.NoError(t, hook.Prerun(context.Background()))
+
+	mounts := ar.GetAllocHookResources().GetCSIMounts()
+	require.Len(t, mounts, 2)
+
+	runner := &allocRunner{hookState: ar.GetAllocHookResources()}
+	require.Equal(t, map[string]*cstructs.AllocCSIMount{
+		"vol0": {
+			VolumeID:   "testvolume0",
+			PluginID:   "minnie",
+			AccessMode: structs.CSIVolumeAccessModeSingleNodeWriter,
+			HostPath:   mounts["vol0"].Source,
+		},
+		"vol1": {
+			VolumeID:   "testvolume1",
+			PluginID:   "minnie",
+			AccessMode: structs.CSIVolumeAccessModeMultiNodeReader,
+			HostPath:   mounts["vol1"].Source,
+		},
+	}, runner.CSIMounts())
+
+	// No mounts are reported before the hooks run
+	require.Empty(t, (&allocRunner{}).CSIMounts())
+}
+
+func TestCSIHook_ClaimTopology(t *testing.T) {
+	nodeTopology := &structs.CSITopology{Segments: map[string]string{
+		"region": "us-east-1",
+		"zone":   "us-east-1a",
+	}}
+
+	testcases := []struct {
+		name          string
+		claimTopology bool
+		node          *structs.CSITopology
+		volume        []*structs.CSITopology
+		expectErr     string
+	}{
+		{
+			name:          "matching topology",
+			claimTopology: true,
+			node:          nodeTopology,
+			volume: []*structs.CSITopology{
+				{Segments: map[string]string{"zone": "us-east-1b"}},
+				{Segments: map[string]string{"zone": "us-east-1a"}},
+			},
+		},
+		{
+			name:          "mismatching topology",
+			claimTopology: true,
+			node:          nodeTopology,
+			volume: []*structs.CSITopology{
+				{Segments: map[string]string{"region": "us-east-1", "zone": "us-east-1b"}},
+			},
+			expectErr: `volume "vol0" (source "testvolume0") is not accessible from topology map[region:us-east-1 zone:us-east-1a] of plugin "minnie" on this node`,
+		},
+		{
+			name:          "volume without topology",
+			claimTopology: true,
+			node:          nodeTopology,
+		},
+		{
+			name:          "node without topology",
+			claimTopology: true,
+			volume: []*structs.CSITopology{
+				{Segments: map[string]string{"zone": "us-east-1b"}},
+			},
+		},
+		{
+			name: "mismatching topology without the check",
+			node: nodeTopology,
+			volume: []*structs.CSITopology{
+				{Segments: map[string]string{"zone": "us-east-1b"}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"vol0": {
+					Name:           "vol0",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "testvolume0",
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+				},
+			}
+
+			callCounts := newCallCounter()
+			mgr := mockPluginManager{mounter: mockVolumeMounter{callCounts: callCounts, topology: tc.node}}
+			if tc.node != nil {
+				mgr.topologies = map[string]*structs.CSITopology{"minnie": tc.node}
+			}
+			rpcer := &topologyRPCer{
+				recordingRPCer: recordingRPCer{mockRPCer: mockRPCer{alloc: alloc, callCounts: callCounts}},
+				topologies:     tc.volume,
+			}
+			ar := mockAllocRunner{
+				res: &cstructs.AllocHookResources{},
+				caps: &drivers.Capabilities{
+					FSIsolation:  drivers.FSIsolationChroot,
+					MountConfigs: drivers.MountConfigSupportAll,
+				},
+			}
+			eventer := &mockEventEmitter{}
+			// The node's topology comes from the plugin, even though the
+			// client's node hasn't fingerprinted the plugin yet
+			config := clientconfig.DefaultConfig()
+			config.CSIClaimTopology = tc.claimTopology
+			config.Node = mock.Node()
+			hook := newCSIHook(csiHookConfig{
+				alloc:                alloc,
+				logger:               testlog.HCLogger(t),
+				csiManager:           mgr,
+				rpcClient:            rpcer,
+				taskCapabilityGetter: ar,
+				hookResources:        ar,
+				eventer:              eventer,
+				nodeSecret:           "secret",
+				stateDB:              cstate.NoopDB{},
+				clientConfig:         config,
+			})
+
+			err := hook.Prerun(context.Background())
+
+			// The claim carries the node's topology when the check is enabled
+			require.Len(t, rpcer.claims, 1)
+			if tc.claimTopology && tc.node != nil {
+				require.Equal(t, map[string]*structs.CSITopology{"minnie": tc.node}, rpcer.claims[0].NodeTopologies)
+			} else {
+				require.Empty(t, rpcer.claims[0].NodeTopologies)
+			}
+
+			if tc.expectErr == "" {
+				require.NoError(t, err)
+				require.Equal(t, 1, callCounts.get("mount"))
+				return
+			}
+
+			// The inaccessible volume is never passed to the node plugin, and
+			// its claim is released
+			var topologyErr *csiTopologyError
+			require.ErrorAs(t, err, &topologyErr)
+			require.EqualError(t, err, tc.expectErr)
+			require.Zero(t, callCounts.get("mount"))
+			require.Equal(t, 1, callCounts.get("unpublish"))
+
+			var failed *structs.TaskEvent
+			for _, event := range eventer.events {
+				if event.Type == structs.TaskSetupFailure {
+					failed = event
+				}
+			}
+			require.NotNil(t, failed)
+			require.Contains(t, failed.DisplayMessage, `Volume "vol0" is not accessible from this node`)
+		})
+	}
 }
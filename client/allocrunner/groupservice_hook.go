@@ -98,7 +98,7 @@ func (*groupServiceHook) Name() string {
 	return groupServiceHookName
 }
 
-func (h *groupServiceHook) Prerun() error {
+func (h *groupServiceHook) Prerun(context.Context) error {
 	h.mu.Lock()
 	defer func() {
 		// Mark prerun as true to unblock Updates
@@ -199,7 +199,7 @@ func (h *groupServiceHook) preKillLocked() {
 	}
 }
 
-func (h *groupServiceHook) Postrun() error {
+func (h *groupServiceHook) Postrun(context.Context) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -1,6 +1,7 @@
 package allocrunner
 
 import (
+	"context"
 	"io/ioutil"
 	"testing"
 	"time"
@@ -44,12 +45,12 @@ func TestGroupServiceHook_NoGroupServices(t *testing.T) {
 		taskEnvBuilder: taskenv.NewBuilder(mock.Node(), alloc, nil, alloc.Job.Region),
 		logger:         logger,
 	})
-	require.NoError(t, h.Prerun())
+	require.NoError(t, h.Prerun(context.Background()))
 
 	req := &interfaces.RunnerUpdateRequest{Alloc: alloc}
 	require.NoError(t, h.Update(req))
 
-	require.NoError(t, h.Postrun())
+	require.NoError(t, h.Postrun(context.Background()))
 
 	require.NoError(t, h.PreTaskRestart())
 
@@ -80,7 +81,7 @@ func TestGroupServiceHook_ShutdownDelayUpdate(t *testing.T) {
 		taskEnvBuilder: taskenv.NewBuilder(mock.Node(), alloc, nil, alloc.Job.Region),
 		logger:         logger,
 	})
-	require.NoError(t, h.Prerun())
+	require.NoError(t, h.Prerun(context.Background()))
 
 	// Incease shutdown Delay
 	alloc.Job.TaskGroups[0].ShutdownDelay = helper.TimeToPtr(15 * time.Second)
@@ -115,12 +116,12 @@ func TestGroupServiceHook_GroupServices(t *testing.T) {
 		taskEnvBuilder: taskenv.NewBuilder(mock.Node(), alloc, nil, alloc.Job.Region),
 		logger:         logger,
 	})
-	require.NoError(t, h.Prerun())
+	require.NoError(t, h.Prerun(context.Background()))
 
 	req := &interfaces.RunnerUpdateRequest{Alloc: alloc}
 	require.NoError(t, h.Update(req))
 
-	require.NoError(t, h.Postrun())
+	require.NoError(t, h.Postrun(context.Background()))
 
 	require.NoError(t, h.PreTaskRestart())
 
@@ -161,12 +162,12 @@ func TestGroupServiceHook_NoNetwork(t *testing.T) {
 		taskEnvBuilder: taskenv.NewBuilder(mock.Node(), alloc, nil, alloc.Job.Region),
 		logger:         logger,
 	})
-	require.NoError(t, h.Prerun())
+	require.NoError(t, h.Prerun(context.Background()))
 
 	req := &interfaces.RunnerUpdateRequest{Alloc: alloc}
 	require.NoError(t, h.Update(req))
 
-	require.NoError(t, h.Postrun())
+	require.NoError(t, h.Postrun(context.Background()))
 
 	require.NoError(t, h.PreTaskRestart())
 
@@ -291,7 +292,7 @@ func TestGroupServiceHook_Update08Alloc(t *testing.T) {
 		logger:         testlog.HCLogger(t),
 	})
 
-	require.NoError(t, h.Prerun())
+	require.NoError(t, h.Prerun(context.Background()))
 	require.NoError(t, h.Update(&interfaces.RunnerUpdateRequest{Alloc: alloc}))
 
 	// Assert the group and sidecar services are registered
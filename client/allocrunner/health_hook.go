@@ -142,7 +142,7 @@ func (h *allocHealthWatcherHook) init() error {
 	return nil
 }
 
-func (h *allocHealthWatcherHook) Prerun() error {
+func (h *allocHealthWatcherHook) Prerun(context.Context) error {
 	h.hookLock.Lock()
 	defer h.hookLock.Unlock()
 
@@ -179,7 +179,7 @@ func (h *allocHealthWatcherHook) Update(req *interfaces.RunnerUpdateRequest) err
 	return h.init()
 }
 
-func (h *allocHealthWatcherHook) Postrun() error {
+func (h *allocHealthWatcherHook) Postrun(context.Context) error {
 	h.hookLock.Lock()
 	defer h.hookLock.Unlock()
 
@@ -194,7 +194,7 @@ func (h *allocHealthWatcherHook) Postrun() error {
 
 func (h *allocHealthWatcherHook) Shutdown() {
 	// Same as Postrun
-	h.Postrun()
+	h.Postrun(context.Background())
 }
 
 // watchHealth watches alloc health until it is set, the alloc is stopped, the
@@ -1,6 +1,7 @@
 package allocrunner
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -106,7 +107,7 @@ func TestHealthHook_PrerunPostrun(t *testing.T) {
 	require.True(ok)
 
 	// Prerun
-	require.NoError(prerunh.Prerun())
+	require.NoError(prerunh.Prerun(context.Background()))
 
 	// Assert isDeploy is false (other tests peek at isDeploy to determine
 	// if an Update applied)
@@ -116,7 +117,7 @@ func TestHealthHook_PrerunPostrun(t *testing.T) {
 	ahw.hookLock.Unlock()
 
 	// Postrun
-	require.NoError(postrunh.Postrun())
+	require.NoError(postrunh.Postrun(context.Background()))
 }
 
 // TestHealthHook_PrerunUpdatePostrun asserts Updates may be applied concurrently.
@@ -136,7 +137,7 @@ func TestHealthHook_PrerunUpdatePostrun(t *testing.T) {
 	h := newAllocHealthWatcherHook(logger, alloc.Copy(), hs, b.Listen(), consul).(*allocHealthWatcherHook)
 
 	// Prerun
-	require.NoError(h.Prerun())
+	require.NoError(h.Prerun(context.Background()))
 
 	// Update multiple times in a goroutine to mimic Client behavior
 	// (Updates are concurrent with alloc runner but are applied serially).
@@ -154,7 +155,7 @@ func TestHealthHook_PrerunUpdatePostrun(t *testing.T) {
 	}
 
 	// Postrun
-	require.NoError(h.Postrun())
+	require.NoError(h.Postrun(context.Background()))
 }
 
 // TestHealthHook_UpdatePrerunPostrun asserts that a hook may have Update
@@ -190,7 +191,7 @@ func TestHealthHook_UpdatePrerunPostrun(t *testing.T) {
 	}
 
 	// Prerun should be a noop
-	require.NoError(h.Prerun())
+	require.NoError(h.Prerun(context.Background()))
 
 	// Assert that the Update took affect by isDeploy being true
 	h.hookLock.Lock()
@@ -198,7 +199,7 @@ func TestHealthHook_UpdatePrerunPostrun(t *testing.T) {
 	h.hookLock.Unlock()
 
 	// Postrun
-	require.NoError(h.Postrun())
+	require.NoError(h.Postrun(context.Background()))
 }
 
 // TestHealthHook_Postrun asserts that a hook may have only Postrun called.
@@ -216,7 +217,7 @@ func TestHealthHook_Postrun(t *testing.T) {
 	h := newAllocHealthWatcherHook(logger, mock.Alloc(), hs, b.Listen(), consul).(*allocHealthWatcherHook)
 
 	// Postrun
-	require.NoError(h.Postrun())
+	require.NoError(h.Postrun(context.Background()))
 }
 
 // TestHealthHook_SetHealth_healthy asserts SetHealth is called when health status is
@@ -282,7 +283,7 @@ func TestHealthHook_SetHealth_healthy(t *testing.T) {
 	h := newAllocHealthWatcherHook(logger, alloc.Copy(), hs, b.Listen(), consul).(*allocHealthWatcherHook)
 
 	// Prerun
-	require.NoError(h.Prerun())
+	require.NoError(h.Prerun(context.Background()))
 
 	// Wait for health to be set (healthy)
 	select {
@@ -297,7 +298,7 @@ func TestHealthHook_SetHealth_healthy(t *testing.T) {
 	}
 
 	// Postrun
-	require.NoError(h.Postrun())
+	require.NoError(h.Postrun(context.Background()))
 }
 
 // TestHealthHook_SetHealth_unhealthy asserts SetHealth notices unhealthy allocs
@@ -370,7 +371,7 @@ func TestHealthHook_SetHealth_unhealthy(t *testing.T) {
 	h := newAllocHealthWatcherHook(logger, alloc.Copy(), hs, b.Listen(), consul).(*allocHealthWatcherHook)
 
 	// Prerun
-	require.NoError(h.Prerun())
+	require.NoError(h.Prerun(context.Background()))
 
 	// Wait to ensure we don't get a healthy status
 	select {
@@ -381,7 +382,7 @@ func TestHealthHook_SetHealth_unhealthy(t *testing.T) {
 	}
 
 	// Postrun
-	require.NoError(h.Postrun())
+	require.NoError(h.Postrun(context.Background()))
 }
 
 // TestHealthHook_SystemNoop asserts that system jobs return the noop tracker.
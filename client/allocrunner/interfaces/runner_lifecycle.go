@@ -1,6 +1,8 @@
 package interfaces
 
 import (
+	"context"
+
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -11,7 +13,23 @@ type RunnerHook interface {
 
 // RunnerPrerunHooks are executed before calling TaskRunner.Run for
 // non-terminal allocations. Terminal allocations do *not* call prerun.
+//
+// The context is cancelled when the allocation is destroyed or the client
+// shuts down, and hooks must return promptly once it is. When the allocation
+// is destroyed the hook should unwind any partial work, as the allocation
+// will not run. When the client shuts down, which ShuttingDown reports, the
+// allocation is restored and its prerun hooks run again when the client
+// restarts, so the hook may leave partial work in place to be retried.
 type RunnerPrerunHook interface {
+	RunnerHook
+	Prerun(context.Context) error
+}
+
+// LegacyRunnerPrerunHook is a RunnerPrerunHook that isn't passed a context,
+// and so can't be cancelled. It's still run for compatibility.
+//
+// Deprecated: implement RunnerPrerunHook instead.
+type LegacyRunnerPrerunHook interface {
 	RunnerHook
 	Prerun() error
 }
@@ -28,7 +46,21 @@ type RunnerPreKillHook interface {
 // RunnerPostrunHooks are executed after calling TaskRunner.Run, even for
 // terminal allocations. Therefore Postrun hooks must be safe to call without
 // first calling Prerun hooks.
+//
+// The context is cancelled only when the client shuts down, as postrun hooks
+// clean up after allocations that are being destroyed. Postrun hooks are run
+// again for the restored allocation when the client restarts, so a hook
+// interrupted by shutdown may leave its remaining cleanup to be retried.
 type RunnerPostrunHook interface {
+	RunnerHook
+	Postrun(context.Context) error
+}
+
+// LegacyRunnerPostrunHook is a RunnerPostrunHook that isn't passed a context,
+// and so can't be cancelled. It's still run for compatibility.
+//
+// Deprecated: implement RunnerPostrunHook instead.
+type LegacyRunnerPostrunHook interface {
 	RunnerHook
 	Postrun() error
 }
@@ -53,6 +85,10 @@ type RunnerUpdateHook interface {
 
 type RunnerUpdateRequest struct {
 	Alloc *structs.Allocation
+
+	// Ctx is cancelled when the client shuts down or the allocation is
+	// destroyed, so that hooks making requests don't hold up stopping it
+	Ctx context.Context
 }
 
 // RunnerTaskRestartHooks are executed just before the allocation runner is
@@ -63,6 +99,17 @@ type RunnerTaskRestartHook interface {
 	PreTaskRestart() error
 }
 
+// RunnerDrainShutdownHook is run when an allocation stops because its node is
+// drained, once all of its tasks have stopped and before its postrun hooks.
+// Unlike ShutdownHook, it isn't run when the agent shuts down.
+type RunnerDrainShutdownHook interface {
+	RunnerHook
+
+	// Shutdown releases what the allocation holds before it's migrated off
+	// the node. ctx is cancelled if the client shuts down.
+	Shutdown(ctx context.Context) error
+}
+
 // ShutdownHook may be implemented by AllocRunner or TaskRunner hooks and will
 // be called when the agent process is being shutdown gracefully.
 type ShutdownHook interface {
@@ -70,3 +117,23 @@ type ShutdownHook interface {
 
 	Shutdown()
 }
+
+// shutdownCtxKey is the key of the context a hook context is cancelled by when
+// the client shuts down.
+type shutdownCtxKey struct{}
+
+// NewShutdownContext returns the context passed to runner hooks, which is
+// cancelled by calling cancel when the client shuts down. Contexts derived
+// from it report whether it was cancelled with ShuttingDown.
+func NewShutdownContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	return context.WithValue(ctx, shutdownCtxKey{}, ctx), cancel
+}
+
+// ShuttingDown returns whether the hook context was cancelled because the
+// client is shutting down, rather than because the allocation is being
+// destroyed.
+func ShuttingDown(ctx context.Context) bool {
+	shutdownCtx, ok := ctx.Value(shutdownCtxKey{}).(context.Context)
+	return ok && shutdownCtx.Err() != nil
+}
@@ -30,9 +30,7 @@ func (h *diskMigrationHook) Name() string {
 	return "migrate_disk"
 }
 
-func (h *diskMigrationHook) Prerun() error {
-	ctx := context.TODO()
-
+func (h *diskMigrationHook) Prerun(ctx context.Context) error {
 	// Wait for a previous alloc - if any - to terminate
 	if err := h.allocWatcher.Wait(ctx); err != nil {
 		return err
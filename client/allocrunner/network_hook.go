@@ -101,7 +101,7 @@ func (h *networkHook) Name() string {
 	return "network"
 }
 
-func (h *networkHook) Prerun() error {
+func (h *networkHook) Prerun(context.Context) error {
 	tg := h.alloc.Job.LookupTaskGroup(h.alloc.TaskGroup)
 	if len(tg.Networks) == 0 || tg.Networks[0].Mode == "host" || tg.Networks[0].Mode == "" {
 		return nil
@@ -174,7 +174,7 @@ func (h *networkHook) Prerun() error {
 	return nil
 }
 
-func (h *networkHook) Postrun() error {
+func (h *networkHook) Postrun(context.Context) error {
 	if h.spec == nil {
 		return nil
 	}
@@ -1,6 +1,7 @@
 package allocrunner
 
 import (
+	"context"
 	"testing"
 
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
@@ -84,10 +85,10 @@ func TestNetworkHook_Prerun_Postrun(t *testing.T) {
 
 	logger := testlog.HCLogger(t)
 	hook := newNetworkHook(logger, setter, alloc, nm, &hostNetworkConfigurator{}, statusSetter, envBuilder.Build())
-	require.NoError(hook.Prerun())
+	require.NoError(hook.Prerun(context.Background()))
 	require.True(setter.called)
 	require.False(destroyCalled)
-	require.NoError(hook.Postrun())
+	require.NoError(hook.Postrun(context.Background()))
 	require.True(destroyCalled)
 
 	// reset and use host network mode
@@ -95,9 +96,9 @@ func TestNetworkHook_Prerun_Postrun(t *testing.T) {
 	destroyCalled = false
 	alloc.Job.TaskGroups[0].Networks[0].Mode = "host"
 	hook = newNetworkHook(logger, setter, alloc, nm, &hostNetworkConfigurator{}, statusSetter, envBuilder.Build())
-	require.NoError(hook.Prerun())
+	require.NoError(hook.Prerun(context.Background()))
 	require.False(setter.called)
 	require.False(destroyCalled)
-	require.NoError(hook.Postrun())
+	require.NoError(hook.Postrun(context.Background()))
 	require.False(destroyCalled)
 }
@@ -166,20 +166,17 @@ func newNetworkConfigurator(log hclog.Logger, alloc *structs.Allocation, config
 	}
 
 	netMode := strings.ToLower(tg.Networks[0].Mode)
-	ignorePortMappingHostIP := config.BindWildcardDefaultHostNetwork
-	if len(config.HostNetworks) > 0 {
-		ignorePortMappingHostIP = false
-	}
+	wildcardHostNetworks := wildcardHostNetworks(config)
 
 	switch {
 	case netMode == "bridge":
-		c, err := newBridgeNetworkConfigurator(log, config.BridgeNetworkName, config.BridgeNetworkAllocSubnet, config.CNIPath, ignorePortMappingHostIP)
+		c, err := newBridgeNetworkConfigurator(log, config.BridgeNetworkName, config.BridgeNetworkAllocSubnet, config.CNIPath, config.BridgeNetworkCNIConfig, wildcardHostNetworks)
 		if err != nil {
 			return nil, err
 		}
 		return &synchronizedNetworkConfigurator{c}, nil
 	case strings.HasPrefix(netMode, "cni/"):
-		c, err := newCNINetworkConfigurator(log, config.CNIPath, config.CNIInterfacePrefix, config.CNIConfigDir, netMode[4:], ignorePortMappingHostIP)
+		c, err := newCNINetworkConfigurator(log, config.CNIPath, config.CNIInterfacePrefix, config.CNIConfigDir, netMode[4:], wildcardHostNetworks)
 		if err != nil {
 			return nil, err
 		}
@@ -188,3 +185,20 @@ func newNetworkConfigurator(log hclog.Logger, alloc *structs.Allocation, config
 		return &hostNetworkConfigurator{}, nil
 	}
 }
+
+// wildcardHostNetworks returns the host networks whose port mappings match any
+// destination address: the default host network if
+// bind_wildcard_default_host_network is set, whether or not other host
+// networks are defined, and the host networks that set bind_wildcard.
+func wildcardHostNetworks(config *clientconfig.Config) map[string]struct{} {
+	networks := make(map[string]struct{})
+	if config.BindWildcardDefaultHostNetwork {
+		networks["default"] = struct{}{}
+	}
+	for name, network := range config.HostNetworks {
+		if network.BindWildcard {
+			networks[name] = struct{}{}
+		}
+	}
+	return networks
+}
@@ -2,6 +2,7 @@ package allocrunner
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hashicorp/nomad/client/pluginmanager"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -62,6 +63,8 @@ func (m *mockDriverManager) Dispense(driver string) (drivers.DriverPlugin, error
 	return mockDrivers[driver], nil
 }
 
+func (m *mockDriverManager) RecordTaskStart(driver string, latency time.Duration, err error) {}
+
 func TestNewNetworkManager(t *testing.T) {
 	for _, tc := range []struct {
 		name        string
@@ -16,6 +16,12 @@ func newNetworkManager(alloc *structs.Allocation, driverManager drivermanager.Ma
 	return nil, nil
 }
 
+// LoadBridgeNetworkCNIConfig returns nil, as the bridge network is only
+// supported on Linux.
+func LoadBridgeNetworkCNIConfig(config *clientconfig.Config) ([]byte, error) {
+	return nil, nil
+}
+
 func newNetworkConfigurator(log hclog.Logger, alloc *structs.Allocation, config *clientconfig.Config) (NetworkConfigurator, error) {
 	return &hostNetworkConfigurator{}, nil
 }
@@ -1,11 +1,17 @@
 package allocrunner
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"text/template"
 
+	cnilibrary "github.com/containernetworking/cni/libcni"
 	"github.com/coreos/go-iptables/iptables"
 	hclog "github.com/hashicorp/go-hclog"
+	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/plugins/drivers"
 )
@@ -13,7 +19,7 @@ import (
 const (
 	// defaultNomadBridgeName is the name of the bridge to use when not set by
 	// the client
-	defaultNomadBridgeName = "nomad"
+	defaultNomadBridgeName = clientconfig.DefaultBridgeNetworkName
 
 	// bridgeNetworkAllocIfPrefix is the prefix that is used for the interface
 	// name created inside of the alloc network which is connected to the bridge
@@ -21,13 +27,21 @@ const (
 
 	// defaultNomadAllocSubnet is the subnet to use for host local ip address
 	// allocation when not specified by the client
-	defaultNomadAllocSubnet = "172.26.64.0/20" // end 172.26.79.255
+	defaultNomadAllocSubnet = clientconfig.DefaultBridgeNetworkAllocSubnet
 
 	// cniAdminChainName is the name of the admin iptables chain used to allow
 	// forwarding traffic to allocations
 	cniAdminChainName = "NOMAD-ADMIN"
+
+	// bridgeNetworkCNIName is the name of the bridge network's CNI config,
+	// whose IPAM leases the client garbage collects
+	bridgeNetworkCNIName = "nomad"
 )
 
+// bridgeNetworkCNIPlugins are the plugins a custom CNI config of the bridge
+// network must chain, as bridge networking relies on them
+var bridgeNetworkCNIPlugins = []string{"firewall", "portmap"}
+
 // bridgeNetworkConfigurator is a NetworkConfigurator which adds the alloc to a
 // shared bridge, configures masquerading for egress traffic and port mapping
 // for ingress
@@ -39,22 +53,21 @@ type bridgeNetworkConfigurator struct {
 	logger hclog.Logger
 }
 
-func newBridgeNetworkConfigurator(log hclog.Logger, bridgeName, ipRange, cniPath string, ignorePortMappingHostIP bool) (*bridgeNetworkConfigurator, error) {
+// newBridgeNetworkConfigurator returns a configurator of the bridge network
+// using the rendered custom CNI config cniConfig, or the built-in config if
+// it's nil.
+func newBridgeNetworkConfigurator(log hclog.Logger, bridgeName, ipRange, cniPath string, cniConfig []byte, wildcardHostNetworks map[string]struct{}) (*bridgeNetworkConfigurator, error) {
 	b := &bridgeNetworkConfigurator{
-		bridgeName:  bridgeName,
-		allocSubnet: ipRange,
-		logger:      log,
+		logger: log,
 	}
+	b.bridgeName, b.allocSubnet = bridgeNetworkDefaults(bridgeName, ipRange)
 
-	if b.bridgeName == "" {
-		b.bridgeName = defaultNomadBridgeName
+	netConfig := cniConfig
+	if netConfig == nil {
+		netConfig = buildNomadBridgeNetConfig(b.bridgeName, b.allocSubnet)
 	}
 
-	if b.allocSubnet == "" {
-		b.allocSubnet = defaultNomadAllocSubnet
-	}
-
-	c, err := newCNINetworkConfiguratorWithConf(log, cniPath, bridgeNetworkAllocIfPrefix, ignorePortMappingHostIP, buildNomadBridgeNetConfig(b.bridgeName, b.allocSubnet))
+	c, err := newCNINetworkConfiguratorWithConf(log, cniPath, bridgeNetworkAllocIfPrefix, wildcardHostNetworks, netConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -138,6 +151,94 @@ func buildNomadBridgeNetConfig(bridgeName, subnet string) []byte {
 	return []byte(fmt.Sprintf(nomadCNIConfigTemplate, bridgeName, subnet, cniAdminChainName))
 }
 
+// nomadBridgeNetConfigData is the data substituted into a custom CNI config
+// template of the bridge network
+type nomadBridgeNetConfigData struct {
+	BridgeName     string
+	AllocSubnet    string
+	AdminChainName string
+}
+
+// bridgeNetworkDefaults returns the bridge name and alloc subnet of the
+// bridge network, defaulting those the client doesn't set.
+func bridgeNetworkDefaults(bridgeName, subnet string) (string, string) {
+	if bridgeName == "" {
+		bridgeName = defaultNomadBridgeName
+	}
+	if subnet == "" {
+		subnet = defaultNomadAllocSubnet
+	}
+	return bridgeName, subnet
+}
+
+// LoadBridgeNetworkCNIConfig renders and validates the custom CNI config of
+// the bridge network the client is configured with, or returns nil if it
+// isn't set. The client loads it once when it starts, so that a broken config
+// fails the client rather than each allocation using the bridge network.
+func LoadBridgeNetworkCNIConfig(config *clientconfig.Config) ([]byte, error) {
+	if config.BridgeNetworkCNIConfigPath == "" {
+		return nil, nil
+	}
+	bridgeName, subnet := bridgeNetworkDefaults(config.BridgeNetworkName, config.BridgeNetworkAllocSubnet)
+	return loadNomadBridgeNetConfig(config.BridgeNetworkCNIConfigPath, bridgeName, subnet)
+}
+
+// loadNomadBridgeNetConfig renders the custom CNI config list template of the
+// bridge network at path, and validates the rendered config.
+func loadNomadBridgeNetConfig(path, bridgeName, subnet string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bridge network CNI config: %v", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Parse(string(contents))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bridge network CNI config %s: %v", path, err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, &nomadBridgeNetConfigData{
+		BridgeName:     bridgeName,
+		AllocSubnet:    subnet,
+		AdminChainName: cniAdminChainName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render bridge network CNI config %s: %v", path, err)
+	}
+
+	if err := validateNomadBridgeNetConfig(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("invalid bridge network CNI config %s: %v", path, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// validateNomadBridgeNetConfig returns an error if the CNI config isn't a
+// config list of the bridge network chaining the plugins bridge networking
+// relies on, with the portmap plugin receiving the port mappings.
+func validateNomadBridgeNetConfig(config []byte) error {
+	confList, err := cnilibrary.ConfListFromBytes(config)
+	if err != nil {
+		return err
+	}
+	if confList.Name != bridgeNetworkCNIName {
+		return fmt.Errorf("network name must be %q, not %q", bridgeNetworkCNIName, confList.Name)
+	}
+
+	plugins := make(map[string]*cnilibrary.NetworkConfig, len(confList.Plugins))
+	for _, plugin := range confList.Plugins {
+		plugins[plugin.Network.Type] = plugin
+	}
+	for _, name := range bridgeNetworkCNIPlugins {
+		if _, ok := plugins[name]; !ok {
+			return fmt.Errorf("missing the %q plugin", name)
+		}
+	}
+	if !plugins["portmap"].Network.Capabilities["portMappings"] {
+		return fmt.Errorf("the %q plugin must have the portMappings capability", "portmap")
+	}
+	return nil
+}
+
 const nomadCNIConfigTemplate = `{
 	"cniVersion": "0.4.0",
 	"name": "nomad",
@@ -0,0 +1,161 @@
+//go:build linux
+// +build linux
+
+package allocrunner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cnilibrary "github.com/containernetworking/cni/libcni"
+	clientconfig "github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/stretchr/testify/require"
+)
+
+const testCustomBridgeCNIConfig = `{
+	"cniVersion": "0.4.0",
+	"name": "nomad",
+	"plugins": [
+		{
+			"type": "bridge",
+			"bridge": "{{ .BridgeName }}",
+			"ipMasq": true,
+			"isGateway": true,
+			"ipam": {
+				"type": "host-local",
+				"ranges": [[{"subnet": "{{ .AllocSubnet }}"}]],
+				"routes": [{"dst": "0.0.0.0/0"}]
+			}
+		},
+		{
+			"type": "firewall",
+			"backend": "iptables",
+			"iptablesAdminChainName": "{{ .AdminChainName }}"
+		},
+		{
+			"type": "bandwidth",
+			"capabilities": {"bandwidth": true}
+		},
+		{
+			"type": "portmap",
+			"capabilities": {"portMappings": true},
+			"snat": true
+		}
+	]
+}`
+
+func writeBridgeCNIConfig(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "nomad.conflist")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+// TestBridgeNetworkConfigurator_CustomCNIConfig asserts a custom CNI config
+// template is rendered and passed to libcni in place of the built-in one.
+func TestBridgeNetworkConfigurator_CustomCNIConfig(t *testing.T) {
+	path := writeBridgeCNIConfig(t, testCustomBridgeCNIConfig)
+
+	cniConfig, err := LoadBridgeNetworkCNIConfig(&clientconfig.Config{
+		BridgeNetworkName:          "custom0",
+		BridgeNetworkAllocSubnet:   "10.1.0.0/16",
+		BridgeNetworkCNIConfigPath: path,
+	})
+	require.NoError(t, err)
+
+	// the rendered config is reused even once the template is gone
+	require.NoError(t, os.Remove(path))
+
+	b, err := newBridgeNetworkConfigurator(testlog.HCLogger(t), "custom0", "10.1.0.0/16", "/opt/cni/bin", cniConfig, nil)
+	require.NoError(t, err)
+
+	confList, err := cnilibrary.ConfListFromBytes(b.cni.cniConf)
+	require.NoError(t, err)
+	require.Equal(t, "nomad", confList.Name)
+
+	var types []string
+	for _, plugin := range confList.Plugins {
+		types = append(types, plugin.Network.Type)
+	}
+	require.Equal(t, []string{"bridge", "firewall", "bandwidth", "portmap"}, types)
+	require.Contains(t, string(confList.Plugins[0].Bytes), `"bridge":"custom0"`)
+	require.Contains(t, string(confList.Plugins[0].Bytes), `"subnet":"10.1.0.0/16"`)
+	require.Contains(t, string(confList.Plugins[1].Bytes), `"iptablesAdminChainName":"NOMAD-ADMIN"`)
+
+	// the built-in config is used without a custom one
+	cniConfig, err = LoadBridgeNetworkCNIConfig(&clientconfig.Config{})
+	require.NoError(t, err)
+	require.Nil(t, cniConfig)
+
+	b, err = newBridgeNetworkConfigurator(testlog.HCLogger(t), "", "", "/opt/cni/bin", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, buildNomadBridgeNetConfig(defaultNomadBridgeName, defaultNomadAllocSubnet), b.cni.cniConf)
+	require.NoError(t, validateNomadBridgeNetConfig(b.cni.cniConf))
+}
+
+func TestLoadNomadBridgeNetConfig_Invalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "missing portmap",
+			config: `{"cniVersion": "0.4.0", "name": "nomad", "plugins": [
+				{"type": "bridge", "bridge": "{{ .BridgeName }}"},
+				{"type": "firewall"}]}`,
+			err: `missing the "portmap" plugin`,
+		},
+		{
+			name: "missing firewall",
+			config: `{"cniVersion": "0.4.0", "name": "nomad", "plugins": [
+				{"type": "bridge", "bridge": "{{ .BridgeName }}"},
+				{"type": "portmap", "capabilities": {"portMappings": true}}]}`,
+			err: `missing the "firewall" plugin`,
+		},
+		{
+			name: "portmap without capability",
+			config: `{"cniVersion": "0.4.0", "name": "nomad", "plugins": [
+				{"type": "firewall"},
+				{"type": "portmap"}]}`,
+			err: "portMappings capability",
+		},
+		{
+			name: "wrong name",
+			config: `{"cniVersion": "0.4.0", "name": "custom", "plugins": [
+				{"type": "firewall"},
+				{"type": "portmap", "capabilities": {"portMappings": true}}]}`,
+			err: `network name must be "nomad"`,
+		},
+		{
+			name:   "bad template",
+			config: `{"name": "{{ .BridgeName }"}`,
+			err:    "failed to parse",
+		},
+		{
+			name:   "unknown template field",
+			config: `{"name": "{{ .Unknown }}"}`,
+			err:    "failed to render",
+		},
+		{
+			name:   "bad json",
+			config: `{"name": "nomad",`,
+			err:    "invalid bridge network CNI config",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeBridgeCNIConfig(t, tc.config)
+			_, err := LoadBridgeNetworkCNIConfig(&clientconfig.Config{BridgeNetworkCNIConfigPath: path})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+
+	_, err := loadNomadBridgeNetConfig(filepath.Join(t.TempDir(), "missing.conflist"), defaultNomadBridgeName, defaultNomadAllocSubnet)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read")
+}
@@ -19,6 +19,7 @@ import (
 	cni "github.com/containerd/go-cni"
 	cnilibrary "github.com/containernetworking/cni/libcni"
 	log "github.com/hashicorp/go-hclog"
+	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/plugins/drivers"
 )
@@ -31,37 +32,40 @@ const (
 
 	// defaultCNIPath is the CNI path to use when it is not set by the client
 	// and is not set by environment variable
-	defaultCNIPath = "/opt/cni/bin"
+	defaultCNIPath = clientconfig.DefaultCNIPath
 
 	// defaultCNIInterfacePrefix is the network interface to use if not set in
 	// client config
-	defaultCNIInterfacePrefix = "eth"
+	defaultCNIInterfacePrefix = clientconfig.DefaultCNIInterfacePrefix
 )
 
 type cniNetworkConfigurator struct {
-	cni                     cni.CNI
-	cniConf                 []byte
-	ignorePortMappingHostIP bool
+	cni     cni.CNI
+	cniConf []byte
+
+	// wildcardHostNetworks are the host networks whose port mappings match
+	// any destination address rather than only the port's host IP
+	wildcardHostNetworks map[string]struct{}
 
 	rand   *rand.Rand
 	logger log.Logger
 }
 
-func newCNINetworkConfigurator(logger log.Logger, cniPath, cniInterfacePrefix, cniConfDir, networkName string, ignorePortMappingHostIP bool) (*cniNetworkConfigurator, error) {
+func newCNINetworkConfigurator(logger log.Logger, cniPath, cniInterfacePrefix, cniConfDir, networkName string, wildcardHostNetworks map[string]struct{}) (*cniNetworkConfigurator, error) {
 	cniConf, err := loadCNIConf(cniConfDir, networkName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load CNI config: %v", err)
 	}
 
-	return newCNINetworkConfiguratorWithConf(logger, cniPath, cniInterfacePrefix, ignorePortMappingHostIP, cniConf)
+	return newCNINetworkConfiguratorWithConf(logger, cniPath, cniInterfacePrefix, wildcardHostNetworks, cniConf)
 }
 
-func newCNINetworkConfiguratorWithConf(logger log.Logger, cniPath, cniInterfacePrefix string, ignorePortMappingHostIP bool, cniConf []byte) (*cniNetworkConfigurator, error) {
+func newCNINetworkConfiguratorWithConf(logger log.Logger, cniPath, cniInterfacePrefix string, wildcardHostNetworks map[string]struct{}, cniConf []byte) (*cniNetworkConfigurator, error) {
 	conf := &cniNetworkConfigurator{
-		cniConf:                 cniConf,
-		rand:                    rand.New(rand.NewSource(time.Now().Unix())),
-		logger:                  logger,
-		ignorePortMappingHostIP: ignorePortMappingHostIP,
+		cniConf:              cniConf,
+		rand:                 rand.New(rand.NewSource(time.Now().Unix())),
+		logger:               logger,
+		wildcardHostNetworks: wildcardHostNetworks,
 	}
 	if cniPath == "" {
 		if cniPath = os.Getenv(envCNIPath); cniPath == "" {
@@ -97,7 +101,7 @@ func (c *cniNetworkConfigurator) Setup(ctx context.Context, alloc *structs.Alloc
 	var res *cni.CNIResult
 	for attempt := 1; ; attempt++ {
 		var err error
-		if res, err = c.cni.Setup(ctx, alloc.ID, spec.Path, cni.WithCapabilityPortMap(getPortMapping(alloc, c.ignorePortMappingHostIP))); err != nil {
+		if res, err = c.cni.Setup(ctx, alloc.ID, spec.Path, cni.WithCapabilityPortMap(getPortMapping(alloc, c.wildcardHostNetworks))); err != nil {
 			c.logger.Warn("failed to configure network", "err", err, "attempt", attempt)
 			switch attempt {
 			case 1:
@@ -226,7 +230,7 @@ func (c *cniNetworkConfigurator) Teardown(ctx context.Context, alloc *structs.Al
 		return err
 	}
 
-	return c.cni.Remove(ctx, alloc.ID, spec.Path, cni.WithCapabilityPortMap(getPortMapping(alloc, c.ignorePortMappingHostIP)))
+	return c.cni.Remove(ctx, alloc.ID, spec.Path, cni.WithCapabilityPortMap(getPortMapping(alloc, c.wildcardHostNetworks)))
 }
 
 func (c *cniNetworkConfigurator) ensureCNIInitialized() error {
@@ -239,7 +243,10 @@ func (c *cniNetworkConfigurator) ensureCNIInitialized() error {
 
 // getPortMapping builds a list of portMapping structs that are used as the
 // portmapping capability arguments for the portmap CNI plugin
-func getPortMapping(alloc *structs.Allocation, ignoreHostIP bool) []cni.PortMapping {
+// getPortMapping returns the port mappings of the allocation. The mappings of
+// ports on wildcardHostNetworks match any destination address, and those of
+// other ports only match the port's host IP.
+func getPortMapping(alloc *structs.Allocation, wildcardHostNetworks map[string]struct{}) []cni.PortMapping {
 	ports := []cni.PortMapping{}
 
 	if len(alloc.AllocatedResources.Shared.Ports) == 0 && len(alloc.AllocatedResources.Shared.Networks) > 0 {
@@ -258,7 +265,9 @@ func getPortMapping(alloc *structs.Allocation, ignoreHostIP bool) []cni.PortMapp
 			}
 		}
 	} else {
+		hostNetworks := portHostNetworks(alloc)
 		for _, port := range alloc.AllocatedResources.Shared.Ports {
+			_, wildcard := wildcardHostNetworks[hostNetworks[port.Label]]
 			if port.To < 1 {
 				port.To = port.Value
 			}
@@ -268,7 +277,7 @@ func getPortMapping(alloc *structs.Allocation, ignoreHostIP bool) []cni.PortMapp
 					ContainerPort: int32(port.To),
 					Protocol:      proto,
 				}
-				if !ignoreHostIP {
+				if !wildcard {
 					portMapping.HostIP = port.HostIP
 				}
 				ports = append(ports, portMapping)
@@ -277,3 +286,29 @@ func getPortMapping(alloc *structs.Allocation, ignoreHostIP bool) []cni.PortMapp
 	}
 	return ports
 }
+
+// portHostNetworks returns the host network of each port of the allocation's
+// task group network by label. Ports without a host network, or that aren't
+// found, are on the default host network.
+func portHostNetworks(alloc *structs.Allocation) map[string]string {
+	hostNetworks := make(map[string]string)
+	for _, port := range alloc.AllocatedResources.Shared.Ports {
+		hostNetworks[port.Label] = "default"
+	}
+
+	if alloc.Job == nil {
+		return hostNetworks
+	}
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		return hostNetworks
+	}
+	for _, network := range tg.Networks {
+		for _, port := range append(network.ReservedPorts, network.DynamicPorts...) {
+			if port.HostNetwork != "" {
+				hostNetworks[port.Label] = port.HostNetwork
+			}
+		}
+	}
+	return hostNetworks
+}
@@ -8,7 +8,10 @@ import (
 	"testing"
 
 	cni "github.com/containerd/go-cni"
+	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -64,3 +67,90 @@ func TestCNI_cniToAllocNet_Invalid(t *testing.T) {
 	require.Error(t, err)
 	require.Nil(t, allocNet)
 }
+
+func TestCNI_getPortMapping_BindWildcard(t *testing.T) {
+	alloc := mock.Alloc()
+	alloc.Job.TaskGroups[0].Networks = []*structs.NetworkResource{{
+		Mode: "bridge",
+		DynamicPorts: []structs.Port{
+			{Label: "http", To: 8080},
+			{Label: "admin", HostNetwork: "private"},
+		},
+		ReservedPorts: []structs.Port{
+			{Label: "dns", Value: 53, HostNetwork: "public"},
+		},
+	}}
+	alloc.AllocatedResources.Shared.Ports = structs.AllocatedPorts{
+		{Label: "http", Value: 20000, To: 8080, HostIP: "10.0.0.5"},
+		{Label: "admin", Value: 20001, HostIP: "192.168.0.5"},
+		{Label: "dns", Value: 53, To: 53, HostIP: "203.0.113.5"},
+	}
+
+	hostNetworks := func(wildcard ...string) map[string]*structs.ClientHostNetworkConfig {
+		networks := map[string]*structs.ClientHostNetworkConfig{
+			"private": {Name: "private", CIDR: "192.168.0.0/16"},
+			"public":  {Name: "public", CIDR: "203.0.113.0/24"},
+		}
+		for _, name := range wildcard {
+			networks[name].BindWildcard = true
+		}
+		return networks
+	}
+
+	testCases := []struct {
+		name     string
+		config   *clientconfig.Config
+		expected map[int32]string
+	}{
+		{
+			name:     "no host networks",
+			config:   &clientconfig.Config{},
+			expected: map[int32]string{20000: "10.0.0.5", 20001: "192.168.0.5", 53: "203.0.113.5"},
+		},
+		{
+			name:     "wildcard default without host networks",
+			config:   &clientconfig.Config{BindWildcardDefaultHostNetwork: true},
+			expected: map[int32]string{20000: "", 20001: "192.168.0.5", 53: "203.0.113.5"},
+		},
+		{
+			name:     "host networks",
+			config:   &clientconfig.Config{HostNetworks: hostNetworks()},
+			expected: map[int32]string{20000: "10.0.0.5", 20001: "192.168.0.5", 53: "203.0.113.5"},
+		},
+		{
+			name: "wildcard default with host networks",
+			config: &clientconfig.Config{
+				BindWildcardDefaultHostNetwork: true,
+				HostNetworks:                   hostNetworks(),
+			},
+			expected: map[int32]string{20000: "", 20001: "192.168.0.5", 53: "203.0.113.5"},
+		},
+		{
+			name:     "wildcard host network",
+			config:   &clientconfig.Config{HostNetworks: hostNetworks("public")},
+			expected: map[int32]string{20000: "10.0.0.5", 20001: "192.168.0.5", 53: ""},
+		},
+		{
+			name: "wildcard default and host network",
+			config: &clientconfig.Config{
+				BindWildcardDefaultHostNetwork: true,
+				HostNetworks:                   hostNetworks("private"),
+			},
+			expected: map[int32]string{20000: "", 20001: "", 53: "203.0.113.5"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mappings := getPortMapping(alloc, wildcardHostNetworks(tc.config))
+			require.Len(t, mappings, 6)
+
+			hostIPs := make(map[int32]string)
+			for _, m := range mappings {
+				require.Contains(t, []string{"tcp", "udp"}, m.Protocol)
+				hostIPs[m.HostPort] = m.HostIP
+			}
+			require.Equal(t, tc.expected, hostIPs)
+		})
+	}
+}
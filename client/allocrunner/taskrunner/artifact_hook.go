@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
 	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/getter"
 	ti "github.com/hashicorp/nomad/client/allocrunner/taskrunner/interfaces"
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -15,11 +16,21 @@ import (
 type artifactHook struct {
 	eventEmitter ti.EventEmitter
 	logger       log.Logger
+
+	// checksumPolicy rejects artifacts without a checksum if the client
+	// requires them. It may be nil.
+	checksumPolicy *getter.ChecksumPolicy
+
+	// bandwidth throttles the downloads to their share of the node's
+	// download bandwidth. It may be nil.
+	bandwidth *bandwidth.Manager
 }
 
-func newArtifactHook(e ti.EventEmitter, logger log.Logger) *artifactHook {
+func newArtifactHook(e ti.EventEmitter, checksumPolicy *getter.ChecksumPolicy, bw *bandwidth.Manager, logger log.Logger) *artifactHook {
 	h := &artifactHook{
-		eventEmitter: e,
+		eventEmitter:   e,
+		checksumPolicy: checksumPolicy,
+		bandwidth:      bw,
 	}
 	h.logger = logger.Named(h.Name())
 	return h
@@ -37,6 +48,18 @@ func (h *artifactHook) Prestart(ctx context.Context, req *interfaces.TaskPrestar
 		return nil
 	}
 
+	// Reject artifacts without checksums before downloading any of them
+	for _, artifact := range req.Task.Artifacts {
+		if err := h.checksumPolicy.Check(req.TaskEnv, artifact); err != nil {
+			event := structs.NewTaskEvent(structs.TaskSetupFailure).
+				SetSetupError(err).
+				SetDisplayMessage(fmt.Sprintf("Artifact %q rejected: checksum required by client",
+					req.TaskEnv.ReplaceEnv(artifact.GetterSource))).
+				SetFailsTask()
+			return NewHookError(err, event)
+		}
+	}
+
 	// Initialize hook state to store download progress
 	resp.State = make(map[string]string, len(req.Task.Artifacts))
 
@@ -52,7 +75,7 @@ func (h *artifactHook) Prestart(ctx context.Context, req *interfaces.TaskPrestar
 
 		h.logger.Debug("downloading artifact", "artifact", artifact.GetterSource)
 		//XXX add ctx to GetArtifact to allow cancelling long downloads
-		if err := getter.GetArtifact(req.TaskEnv, artifact); err != nil {
+		if err := getter.GetArtifact(ctx, req.TaskEnv, artifact, h.bandwidth); err != nil {
 
 			wrapped := structs.NewRecoverableError(
 				fmt.Errorf("failed to download artifact %q: %v", artifact.GetterSource, err),
@@ -12,6 +12,7 @@ import (
 
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/getter"
 	"github.com/hashicorp/nomad/client/taskenv"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/helper/testlog"
@@ -36,7 +37,7 @@ func TestTaskRunner_ArtifactHook_Recoverable(t *testing.T) {
 	t.Parallel()
 
 	me := &mockEmitter{}
-	artifactHook := newArtifactHook(me, testlog.HCLogger(t))
+	artifactHook := newArtifactHook(me, nil, nil, testlog.HCLogger(t))
 
 	req := &interfaces.TaskPrestartRequest{
 		TaskEnv: taskenv.NewEmptyTaskEnv(),
@@ -69,7 +70,7 @@ func TestTaskRunner_ArtifactHook_PartialDone(t *testing.T) {
 	t.Parallel()
 
 	me := &mockEmitter{}
-	artifactHook := newArtifactHook(me, testlog.HCLogger(t))
+	artifactHook := newArtifactHook(me, nil, nil, testlog.HCLogger(t))
 
 	// Create a source directory with 1 of the 2 artifacts
 	srcdir, err := ioutil.TempDir("", "nomadtest-src")
@@ -158,3 +159,63 @@ func TestTaskRunner_ArtifactHook_PartialDone(t *testing.T) {
 	require.True(t, resp.Done)
 	require.Len(t, resp.State, 2)
 }
+
+// TestTaskRunner_ArtifactHook_ChecksumRequired asserts that artifacts without
+// a checksum fail the task when the client requires checksums, unless their
+// URL is exempt.
+func TestTaskRunner_ArtifactHook_ChecksumRequired(t *testing.T) {
+	t.Parallel()
+
+	srcdir, err := ioutil.TempDir("", "nomadtest-src")
+	require.NoError(t, err)
+	defer os.RemoveAll(srcdir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(srcdir, "foo.txt"), []byte{'1'}, 0644))
+
+	ts := httptest.NewServer(http.FileServer(http.Dir(srcdir)))
+	defer ts.Close()
+
+	destdir, err := ioutil.TempDir("", "nomadtest-dest")
+	require.NoError(t, err)
+	defer os.RemoveAll(destdir)
+
+	newRequest := func() *interfaces.TaskPrestartRequest {
+		return &interfaces.TaskPrestartRequest{
+			TaskEnv: taskenv.NewTaskEnv(nil, nil, nil, nil, destdir, ""),
+			TaskDir: &allocdir.TaskDir{Dir: destdir},
+			Task: &structs.Task{
+				Artifacts: []*structs.TaskArtifact{
+					{
+						GetterSource: ts.URL + "/foo.txt",
+						GetterMode:   structs.GetterModeAny,
+					},
+				},
+			},
+		}
+	}
+
+	// Without an exemption the artifact is rejected before downloading
+	me := &mockEmitter{}
+	policy := getter.NewChecksumPolicy(true, nil)
+	artifactHook := newArtifactHook(me, policy, nil, testlog.HCLogger(t))
+
+	resp := interfaces.TaskPrestartResponse{}
+	err = artifactHook.Prestart(context.Background(), newRequest(), &resp)
+	require.Error(t, err)
+	require.False(t, structs.IsRecoverable(err))
+	require.False(t, resp.Done)
+	require.Empty(t, me.events)
+
+	herr, ok := err.(*hookError)
+	require.True(t, ok)
+	require.Equal(t, structs.TaskSetupFailure, herr.taskEvent.Type)
+	require.True(t, herr.taskEvent.FailsTask)
+	require.Contains(t, herr.taskEvent.DisplayMessage, ts.URL+"/foo.txt")
+
+	// Exempting the URL prefix allows the download
+	policy.SetExemptPrefixes([]string{ts.URL + "/"})
+	resp = interfaces.TaskPrestartResponse{}
+	err = artifactHook.Prestart(context.Background(), newRequest(), &resp)
+	require.NoError(t, err)
+	require.True(t, resp.Done)
+	require.FileExists(t, filepath.Join(destdir, "foo.txt"))
+}
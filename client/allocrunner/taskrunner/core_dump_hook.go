@@ -0,0 +1,206 @@
+package taskrunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	ti "github.com/hashicorp/nomad/client/allocrunner/taskrunner/interfaces"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// coreDumpDirName is the directory in the task's local directory that core
+// files are collected into when no directory is configured.
+const coreDumpDirName = "cores"
+
+// coreFileRe matches the names of core files written with the kernel's
+// default core_pattern, optionally suffixed with the pid.
+var coreFileRe = regexp.MustCompile(`^core(\.[0-9]+)?$`)
+
+// coreDumpDrivers are the drivers whose executors apply the task's core file
+// size limit and whose tasks dump core files into their task directory.
+var coreDumpDrivers = map[string]struct{}{
+	"exec":     {},
+	"java":     {},
+	"raw_exec": {},
+}
+
+// coreLimit returns the core file size limit of the processes of a task run by
+// driver, or zero if core files dumped by the task aren't collected.
+func coreLimit(cores *config.CoreDumpConfig, driver string) int64 {
+	if cores == nil || !cores.Enabled {
+		return 0
+	}
+	if _, ok := coreDumpDrivers[driver]; !ok {
+		return 0
+	}
+	return cores.MaxSizeBytes()
+}
+
+// coreDumpHook collects the core files dumped by a task after it exits. Core
+// files are truncated at the configured size and only the most recent are
+// kept.
+type coreDumpHook struct {
+	config       *config.CoreDumpConfig
+	taskDir      *allocdir.TaskDir
+	eventEmitter ti.EventEmitter
+
+	// coreDir is the directory core files are collected into
+	coreDir string
+
+	logger hclog.Logger
+}
+
+func newCoreDumpHook(cfg *config.CoreDumpConfig, taskName string, taskDir *allocdir.TaskDir, eventEmitter ti.EventEmitter, logger hclog.Logger) *coreDumpHook {
+	// Core files are kept in the alloc dir so they're garbage collected
+	// with the allocation
+	coreDir := filepath.Join(taskDir.LocalDir, coreDumpDirName)
+	if cfg.Dir != "" {
+		coreDir = filepath.Join(taskDir.AllocDir, cfg.Dir, taskName)
+	}
+
+	h := &coreDumpHook{
+		config:       cfg,
+		taskDir:      taskDir,
+		eventEmitter: eventEmitter,
+		coreDir:      coreDir,
+	}
+	h.logger = logger.Named(h.Name())
+	return h
+}
+
+func (*coreDumpHook) Name() string {
+	return "core_dump"
+}
+
+func (h *coreDumpHook) Exited(context.Context, *interfaces.TaskExitedRequest, *interfaces.TaskExitedResponse) error {
+	entries, err := ioutil.ReadDir(h.taskDir.Dir)
+	if err != nil {
+		h.logger.Error("failed to search task directory for core files", "error", err)
+		return nil
+	}
+
+	collected := false
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || !coreFileRe.MatchString(entry.Name()) {
+			continue
+		}
+
+		path, truncated, err := h.collect(entry)
+		if err != nil {
+			h.logger.Error("failed to collect core file", "file", entry.Name(), "error", err)
+			continue
+		}
+		collected = true
+
+		msg := fmt.Sprintf("Core file saved to %s", path)
+		if truncated {
+			msg = fmt.Sprintf("%s, truncated at %d MB", msg, h.config.MaxSizeBytes()/1024/1024)
+		}
+		event := structs.NewTaskEvent(structs.TaskCoreDumped).SetMessage(msg)
+		event.Details["core_path"] = path
+		event.Details["truncated"] = strconv.FormatBool(truncated)
+		h.eventEmitter.EmitEvent(event)
+	}
+
+	if collected {
+		if err := h.evict(); err != nil {
+			h.logger.Error("failed to remove old core files", "error", err)
+		}
+	}
+	return nil
+}
+
+// collect moves a core file from the task directory into the core directory,
+// truncating it at the configured size. It returns the new path of the core
+// file and whether it was truncated.
+func (h *coreDumpHook) collect(entry os.FileInfo) (string, bool, error) {
+	src := filepath.Join(h.taskDir.Dir, entry.Name())
+
+	// The kernel stops writing a core file at the core file size limit, so
+	// a core file at the limit has most likely been truncated
+	max := h.config.MaxSizeBytes()
+	truncated := entry.Size() >= max
+	if entry.Size() > max {
+		if err := os.Truncate(src, max); err != nil {
+			return "", false, err
+		}
+	}
+
+	if err := os.MkdirAll(h.coreDir, 0700); err != nil {
+		return "", false, err
+	}
+
+	// Prefix the name with the time the core was dumped so that the
+	// oldest core files sort first
+	dest := filepath.Join(h.coreDir,
+		fmt.Sprintf("%d.%s", entry.ModTime().UnixNano(), entry.Name()))
+	if err := moveFile(src, dest); err != nil {
+		return "", false, err
+	}
+	return dest, truncated, nil
+}
+
+// evict removes the oldest core files beyond the number to retain.
+func (h *coreDumpHook) evict() error {
+	entries, err := ioutil.ReadDir(h.coreDir)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	retain := h.config.RetainCount()
+	for len(names) > retain {
+		if err := os.Remove(filepath.Join(h.coreDir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// moveFile renames src to dest, falling back to copying the file when they
+// are on different filesystems.
+func moveFile(src, dest string) error {
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dest)
+		return err
+	}
+	return os.Remove(src)
+}
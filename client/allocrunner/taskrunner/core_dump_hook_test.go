@@ -0,0 +1,137 @@
+package taskrunner
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+// Statically assert the core dump hook implements the expected interface
+var _ interfaces.TaskExitedHook = (*coreDumpHook)(nil)
+
+func testCoreDumpTaskDir(t *testing.T) *allocdir.TaskDir {
+	allocDir := t.TempDir()
+	dir := filepath.Join(allocDir, "web")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	return &allocdir.TaskDir{
+		AllocDir: allocDir,
+		Dir:      dir,
+		LocalDir: filepath.Join(dir, allocdir.TaskLocal),
+	}
+}
+
+// writeCoreFile writes a fake core file of size bytes into dir, dumped at the
+// given time.
+func writeCoreFile(t *testing.T, dir, name string, size int, dumped time.Time) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, make([]byte, size), 0600))
+	require.NoError(t, os.Chtimes(path, dumped, dumped))
+}
+
+func TestTaskRunner_CoreDumpHook_Collect(t *testing.T) {
+	t.Parallel()
+
+	taskDir := testCoreDumpTaskDir(t)
+	cfg := &config.CoreDumpConfig{Enabled: true, MaxSizeMB: 1}
+	me := &mockEmitter{}
+	h := newCoreDumpHook(cfg, "web", taskDir, me, testlog.HCLogger(t))
+
+	now := time.Now()
+	writeCoreFile(t, taskDir.Dir, "core", 1024, now)
+	writeCoreFile(t, taskDir.Dir, "core.1234", 2*1024*1024, now.Add(time.Second))
+	writeCoreFile(t, taskDir.Dir, "core.txt", 10, now)
+
+	require.NoError(t, h.Exited(context.Background(), nil, nil))
+
+	// Only core files were moved out of the task dir
+	_, err := os.Stat(filepath.Join(taskDir.Dir, "core"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(taskDir.Dir, "core.1234"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(taskDir.Dir, "core.txt"))
+	require.NoError(t, err)
+
+	require.Len(t, me.events, 2)
+	for _, event := range me.events {
+		require.Equal(t, structs.TaskCoreDumped, event.Type)
+		path := event.Details["core_path"]
+		require.Equal(t, filepath.Join(taskDir.LocalDir, coreDumpDirName), filepath.Dir(path))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		switch filepath.Ext(path) {
+		case ".1234":
+			require.Equal(t, "true", event.Details["truncated"])
+			require.Equal(t, int64(1024*1024), info.Size())
+			require.Contains(t, event.Message, "truncated at 1 MB")
+		default:
+			require.Equal(t, "false", event.Details["truncated"])
+			require.Equal(t, int64(1024), info.Size())
+		}
+	}
+}
+
+func TestTaskRunner_CoreDumpHook_Retain(t *testing.T) {
+	t.Parallel()
+
+	taskDir := testCoreDumpTaskDir(t)
+	cfg := &config.CoreDumpConfig{Enabled: true, Dir: "cores", Retain: 2}
+	me := &mockEmitter{}
+	h := newCoreDumpHook(cfg, "web", taskDir, me, testlog.HCLogger(t))
+
+	// Collect one core file each time the task exits
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		writeCoreFile(t, taskDir.Dir, "core", 16, start.Add(time.Duration(i)*time.Second))
+		require.NoError(t, h.Exited(context.Background(), nil, nil))
+	}
+	require.Len(t, me.events, 4)
+
+	// Only the newest cores were kept, in a directory for the task under
+	// the alloc dir
+	entries, err := ioutil.ReadDir(filepath.Join(taskDir.AllocDir, "cores", "web"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, filepath.Base(me.events[2].Details["core_path"]), entries[0].Name())
+	require.Equal(t, filepath.Base(me.events[3].Details["core_path"]), entries[1].Name())
+}
+
+func TestTaskRunner_CoreDumpHook_NoCores(t *testing.T) {
+	t.Parallel()
+
+	taskDir := testCoreDumpTaskDir(t)
+	cfg := &config.CoreDumpConfig{Enabled: true}
+	me := &mockEmitter{}
+	h := newCoreDumpHook(cfg, "web", taskDir, me, testlog.HCLogger(t))
+
+	require.NoError(t, h.Exited(context.Background(), nil, nil))
+	require.Empty(t, me.events)
+
+	// The core directory is only created once there are cores to collect
+	_, err := os.Stat(filepath.Join(taskDir.LocalDir, coreDumpDirName))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestTaskRunner_CoreDumpHook_CoreLimit(t *testing.T) {
+	t.Parallel()
+
+	cores := &config.CoreDumpConfig{Enabled: true, MaxSizeMB: 16}
+	require.Equal(t, int64(16*1024*1024), coreLimit(cores, "raw_exec"))
+	require.Equal(t, int64(16*1024*1024), coreLimit(cores, "exec"))
+
+	// Drivers whose executors don't apply the limit, or with collection
+	// disabled, leave the task's limit unchanged
+	require.Zero(t, coreLimit(cores, "docker"))
+	require.Zero(t, coreLimit(&config.CoreDumpConfig{MaxSizeMB: 16}, "exec"))
+	require.Zero(t, coreLimit(nil, "exec"))
+}
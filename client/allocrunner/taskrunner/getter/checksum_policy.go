@@ -0,0 +1,133 @@
+package getter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ChecksumPolicy decides whether artifacts must be verified by a checksum
+// before they are downloaded. A single policy is shared by every task on the
+// client so that its exemptions can be updated when the client's
+// configuration is reloaded.
+type ChecksumPolicy struct {
+	// required rejects artifacts without a checksum
+	required bool
+
+	// exemptPrefixes are artifact URL prefixes that may be downloaded
+	// without a checksum even when checksums are required
+	exemptPrefixes []string
+	lock           sync.RWMutex
+}
+
+// NewChecksumPolicy returns a ChecksumPolicy. Exemptions take precedence over
+// the requirement.
+func NewChecksumPolicy(required bool, exemptPrefixes []string) *ChecksumPolicy {
+	return &ChecksumPolicy{
+		required:       required,
+		exemptPrefixes: helper.CopySliceString(exemptPrefixes),
+	}
+}
+
+// Required returns true if artifacts must specify a checksum.
+func (p *ChecksumPolicy) Required() bool {
+	return p != nil && p.required
+}
+
+// SetExemptPrefixes replaces the URL prefixes that are exempt from the
+// checksum requirement. It returns whether the prefixes changed.
+func (p *ChecksumPolicy) SetExemptPrefixes(prefixes []string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	changed := !helper.CompareSliceSetString(p.exemptPrefixes, prefixes)
+	p.exemptPrefixes = helper.CopySliceString(prefixes)
+	return changed
+}
+
+// Check returns an error if the artifact does not specify a checksum and
+// neither the policy nor an exemption allows it. A nil policy allows every
+// artifact.
+func (p *ChecksumPolicy) Check(taskEnv EnvReplacer, artifact *structs.TaskArtifact) error {
+	if !p.Required() {
+		return nil
+	}
+
+	source := taskEnv.ReplaceEnv(artifact.GetterSource)
+	if p.exempt(source) {
+		return nil
+	}
+
+	ggURL, err := getGetterUrl(taskEnv, artifact)
+	if err != nil {
+		return err
+	}
+	if hasChecksum(ggURL) {
+		return nil
+	}
+
+	return fmt.Errorf("artifact %q does not specify a checksum, which is required by the client", source)
+}
+
+// exempt returns true if the source matches an exempt URL prefix.
+func (p *ChecksumPolicy) exempt(source string) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	for _, prefix := range p.exemptPrefixes {
+		if matchesExemptPrefix(prefix, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesExemptPrefix returns true if the source URL is under the exempt URL
+// prefix. Both URLs are parsed rather than compared as strings, so that a
+// lookalike host or userinfo can't pass for the exempt host: the forced
+// getter, scheme and host must be the same, ignoring the source's userinfo,
+// and the source's path must be the prefix's path or below it.
+func matchesExemptPrefix(prefix, source string) bool {
+	prefixGetter, prefix := splitForcedGetter(prefix)
+	sourceGetter, source := splitForcedGetter(source)
+	if prefixGetter != sourceGetter {
+		return false
+	}
+
+	p, err := url.Parse(prefix)
+	if err != nil || p.Host == "" {
+		return false
+	}
+	s, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(p.Scheme, s.Scheme) || !strings.EqualFold(p.Host, s.Host) {
+		return false
+	}
+
+	dir := strings.TrimSuffix(p.Path, "/")
+	return dir == "" || s.Path == dir || strings.HasPrefix(s.Path, dir+"/")
+}
+
+// splitForcedGetter splits a go-getter source into the getter it forces, as
+// in "git::https://...", and the rest of the source.
+func splitForcedGetter(source string) (string, string) {
+	if i := strings.Index(source, "::"); i > 0 && !strings.Contains(source[:i], "/") {
+		return source[:i], source[i+2:]
+	}
+	return "", source
+}
+
+// hasChecksum returns true if the go-getter URL has a checksum parameter,
+// which may come from the artifact's options or its source URL.
+func hasChecksum(ggURL string) bool {
+	ggURL = strings.TrimPrefix(ggURL, gitSSHPrefix)
+	u, err := url.Parse(ggURL)
+	if err != nil {
+		return false
+	}
+	return u.Query().Get("checksum") != ""
+}
@@ -1,8 +1,10 @@
 package getter
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -10,6 +12,7 @@ import (
 
 	gg "github.com/hashicorp/go-getter"
 
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -129,8 +132,10 @@ func getHeaders(env EnvReplacer, m map[string]string) http.Header {
 	return headers
 }
 
-// GetArtifact downloads an artifact into the specified task directory.
-func GetArtifact(taskEnv EnvReplacer, artifact *structs.TaskArtifact) error {
+// GetArtifact downloads an artifact into the specified task directory. The
+// downloads of the getters that stream their body, such as http and s3, are
+// throttled to the artifact class's share of bw, until ctx is cancelled.
+func GetArtifact(ctx context.Context, taskEnv EnvReplacer, artifact *structs.TaskArtifact, bw *bandwidth.Manager) error {
 	ggURL, err := getGetterUrl(taskEnv, artifact)
 	if err != nil {
 		return newGetError(artifact.GetterSource, err, false)
@@ -154,13 +159,29 @@ func GetArtifact(taskEnv EnvReplacer, artifact *structs.TaskArtifact) error {
 	}
 
 	headers := getHeaders(taskEnv, artifact.GetterHeaders)
-	if err := getClient(ggURL, headers, mode, dest).Get(); err != nil {
+	client := getClient(ggURL, headers, mode, dest)
+	if bw != nil {
+		client.ProgressListener = bandwidthTracker{ctx: ctx, bw: bw}
+	}
+	if err := client.Get(); err != nil {
 		return newGetError(ggURL, err, true)
 	}
 
 	return nil
 }
 
+// bandwidthTracker throttles the streams of artifact downloads to the
+// artifact class's share of the bandwidth. Reads waiting for bandwidth are
+// interrupted once ctx is cancelled.
+type bandwidthTracker struct {
+	ctx context.Context
+	bw  *bandwidth.Manager
+}
+
+func (t bandwidthTracker) TrackProgress(_ string, _, _ int64, stream io.ReadCloser) io.ReadCloser {
+	return t.bw.Reader(t.ctx, bandwidth.ClassArtifact, stream)
+}
+
 // GetError wraps the underlying artifact fetching error with the URL. It
 // implements the RecoverableError interface.
 type GetError struct {
@@ -1,6 +1,8 @@
 package getter
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,9 +15,12 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	"github.com/hashicorp/nomad/client/taskenv"
 	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/helper/testlog"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/stretchr/testify/require"
@@ -127,7 +132,7 @@ func TestGetArtifact_Headers(t *testing.T) {
 	taskEnv := upperReplacer{
 		taskDir: taskDir,
 	}
-	err = GetArtifact(taskEnv, artifact)
+	err = GetArtifact(context.Background(), taskEnv, artifact, nil)
 	require.NoError(t, err)
 
 	// Verify artifact exists.
@@ -161,7 +166,7 @@ func TestGetArtifact_FileAndChecksum(t *testing.T) {
 	}
 
 	// Download the artifact
-	if err := GetArtifact(noopTaskEnv(taskDir), artifact); err != nil {
+	if err := GetArtifact(context.Background(), noopTaskEnv(taskDir), artifact, nil); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -171,6 +176,33 @@ func TestGetArtifact_FileAndChecksum(t *testing.T) {
 	}
 }
 
+// TestGetArtifact_BandwidthCancelled asserts a download throttled by the
+// bandwidth manager stops once its context is cancelled.
+func TestGetArtifact_BandwidthCancelled(t *testing.T) {
+	// At 1 Mbps the file takes about 8 seconds to download
+	body := bytes.Repeat([]byte("a"), 1000*1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	bw := bandwidth.NewManager(testlog.HCLogger(t), 1, nil)
+	artifact := &structs.TaskArtifact{
+		GetterSource: ts.URL + "/file",
+		GetterMode:   structs.GetterModeFile,
+		RelativeDest: "local/file",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := GetArtifact(ctx, noopTaskEnv(t.TempDir()), artifact, bw)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
 func TestGetArtifact_File_RelativeDest(t *testing.T) {
 	// Create the test server hosting the file to download
 	ts := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir("./test-fixtures/"))))
@@ -195,7 +227,7 @@ func TestGetArtifact_File_RelativeDest(t *testing.T) {
 	}
 
 	// Download the artifact
-	if err := GetArtifact(noopTaskEnv(taskDir), artifact); err != nil {
+	if err := GetArtifact(context.Background(), noopTaskEnv(taskDir), artifact, nil); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -229,7 +261,7 @@ func TestGetArtifact_File_EscapeDest(t *testing.T) {
 	}
 
 	// attempt to download the artifact
-	err = GetArtifact(noopTaskEnv(taskDir), artifact)
+	err = GetArtifact(context.Background(), noopTaskEnv(taskDir), artifact, nil)
 	if err == nil || !strings.Contains(err.Error(), "escapes") {
 		t.Fatalf("expected GetArtifact to disallow sandbox escape: %v", err)
 	}
@@ -279,7 +311,7 @@ func TestGetArtifact_InvalidChecksum(t *testing.T) {
 	}
 
 	// Download the artifact and expect an error
-	if err := GetArtifact(noopTaskEnv(taskDir), artifact); err == nil {
+	if err := GetArtifact(context.Background(), noopTaskEnv(taskDir), artifact, nil); err == nil {
 		t.Fatalf("GetArtifact should have failed")
 	}
 }
@@ -344,7 +376,7 @@ func TestGetArtifact_Archive(t *testing.T) {
 		},
 	}
 
-	if err := GetArtifact(noopTaskEnv(taskDir), artifact); err != nil {
+	if err := GetArtifact(context.Background(), noopTaskEnv(taskDir), artifact, nil); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -377,7 +409,7 @@ func TestGetArtifact_Setuid(t *testing.T) {
 		},
 	}
 
-	require.NoError(t, GetArtifact(noopTaskEnv(taskDir), artifact))
+	require.NoError(t, GetArtifact(context.Background(), noopTaskEnv(taskDir), artifact, nil))
 
 	var expected map[string]int
 
@@ -25,3 +25,11 @@ type TaskLifecycle interface {
 	// to handle restored tasks; use this as an escape hatch.
 	IsRunning() bool
 }
+
+// TaskRestartSequencer is implemented by the alloc runner to order restarts
+// of the tasks in a group by their lifecycle.
+type TaskRestartSequencer interface {
+	// RequestRestart asks for the task to be restarted as part of a lifecycle
+	// ordered restart of the group. It does not block.
+	RequestRestart(event *structs.TaskEvent)
+}
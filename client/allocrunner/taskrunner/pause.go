@@ -0,0 +1,253 @@
+package taskrunner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/state"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// errTaskPaused is returned instead of executing the script checks of paused
+// tasks, as the scripts would be frozen along with the task's processes.
+var errTaskPaused = errors.New("task is paused")
+
+// cgroupFreezer freezes and thaws the cgroups of paused tasks. It is
+// implemented by cgutil.Freezer.
+type cgroupFreezer interface {
+	CgroupForPid(pid string) (string, error)
+	Freeze(cgroup string) error
+	Thaw(cgroup string) error
+}
+
+// Pause freezes the processes of a running task until Resume is called or the
+// client's MaxFreezeDuration has passed. pausedBy identifies who paused the
+// task for its task event.
+func (tr *TaskRunner) Pause(pausedBy string) error {
+	tr.pauseLock.Lock()
+	defer tr.pauseLock.Unlock()
+
+	if jobType := tr.Alloc().Job.Type; jobType == structs.JobTypeSystem || jobType == structs.JobTypeSysBatch {
+		return fmt.Errorf("tasks of %s jobs can't be paused", jobType)
+	}
+	if caps := tr.driverCapabilities; caps == nil || !caps.Freezable() {
+		return fmt.Errorf("driver %q doesn't support pausing tasks", tr.Task().Driver)
+	}
+	if tr.getDriverHandle() == nil {
+		return ErrTaskNotRunning
+	}
+	if tr.IsPaused() {
+		return fmt.Errorf("task is already paused")
+	}
+
+	cgroups, err := tr.taskCgroups()
+	if err != nil {
+		return err
+	}
+	for i, cgroup := range cgroups {
+		if err := tr.freezer.Freeze(cgroup); err != nil {
+			tr.thawCgroups(cgroups[:i])
+			return fmt.Errorf("failed to freeze cgroup %s: %v", cgroup, err)
+		}
+	}
+
+	maxFreeze := tr.maxFreezeDuration()
+	now := time.Now()
+	pause := &state.PauseState{
+		Cgroups:  cgroups,
+		PausedBy: pausedBy,
+		PausedAt: now,
+		ResumeAt: now.Add(maxFreeze),
+	}
+	tr.setPauseState(pause)
+	tr.startPauseTimer(pause)
+
+	event := structs.NewTaskEvent(structs.TaskPaused).
+		SetMessage(fmt.Sprintf("Task paused by %s, resuming automatically after %v", pausedBy, maxFreeze))
+	event.Details["paused_by"] = pausedBy
+	tr.EmitEvent(event)
+	return nil
+}
+
+// Resume thaws the processes of a paused task. resumedBy identifies who
+// resumed the task for its task event.
+func (tr *TaskRunner) Resume(resumedBy string) error {
+	tr.pauseLock.Lock()
+	defer tr.pauseLock.Unlock()
+
+	if !tr.IsPaused() {
+		return fmt.Errorf("task is not paused")
+	}
+	return tr.resume(resumedBy, fmt.Sprintf("Task resumed by %s", resumedBy))
+}
+
+// IsPaused returns whether the task is paused.
+func (tr *TaskRunner) IsPaused() bool {
+	tr.stateLock.RLock()
+	defer tr.stateLock.RUnlock()
+	return tr.localState.Pause != nil
+}
+
+// resume thaws the task's frozen cgroups and forgets that it was paused. The
+// pauseLock must be held.
+func (tr *TaskRunner) resume(resumedBy, msg string) error {
+	tr.stopPauseTimer()
+
+	tr.stateLock.RLock()
+	pause := tr.localState.Pause.Copy()
+	tr.stateLock.RUnlock()
+	if pause == nil {
+		return nil
+	}
+
+	if err := tr.thawCgroups(pause.Cgroups); err != nil {
+		return err
+	}
+	tr.setPauseState(nil)
+
+	event := structs.NewTaskEvent(structs.TaskResumed).SetMessage(msg)
+	event.Details["resumed_by"] = resumedBy
+	tr.EmitEvent(event)
+	return nil
+}
+
+// resumeForKill resumes the task if it's paused, since its frozen processes
+// can't exit until they're thawed.
+func (tr *TaskRunner) resumeForKill() {
+	tr.pauseLock.Lock()
+	defer tr.pauseLock.Unlock()
+
+	if !tr.IsPaused() {
+		return
+	}
+	if err := tr.resume("client", "Task resumed to be killed"); err != nil {
+		tr.logger.Error("failed to resume paused task before killing it", "error", err)
+	}
+}
+
+// restorePause applies the pause recorded in the task's local state after
+// restoring it. The task stays paused until its original deadline, or is
+// resumed if the deadline has passed or the task is no longer running.
+func (tr *TaskRunner) restorePause() {
+	tr.pauseLock.Lock()
+	defer tr.pauseLock.Unlock()
+
+	tr.stateLock.RLock()
+	pause := tr.localState.Pause.Copy()
+	tr.stateLock.RUnlock()
+	if pause == nil {
+		return
+	}
+
+	if tr.getDriverHandle() == nil {
+		// The task's processes and cgroups are gone along with it
+		tr.thawCgroups(pause.Cgroups)
+		tr.setPauseState(nil)
+		return
+	}
+
+	if !time.Now().Before(pause.ResumeAt) {
+		if err := tr.resume("client", "Task resumed after its maximum freeze duration passed"); err != nil {
+			tr.logger.Error("failed to resume paused task", "error", err)
+		}
+		return
+	}
+
+	for _, cgroup := range pause.Cgroups {
+		if err := tr.freezer.Freeze(cgroup); err != nil {
+			tr.logger.Error("failed to freeze cgroup of paused task", "cgroup", cgroup, "error", err)
+		}
+	}
+	tr.startPauseTimer(pause)
+}
+
+// taskCgroups returns the sorted cgroups of the task's processes.
+func (tr *TaskRunner) taskCgroups() ([]string, error) {
+	ru := tr.LatestResourceUsage()
+	if ru == nil || len(ru.Pids) == 0 {
+		return nil, fmt.Errorf("no processes found for task")
+	}
+
+	seen := make(map[string]struct{})
+	cgroups := []string{}
+	for pid := range ru.Pids {
+		cgroup, err := tr.freezer.CgroupForPid(pid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find cgroup of pid %s: %v", pid, err)
+		}
+		if _, ok := seen[cgroup]; ok {
+			continue
+		}
+		seen[cgroup] = struct{}{}
+		cgroups = append(cgroups, cgroup)
+	}
+	sort.Strings(cgroups)
+	return cgroups, nil
+}
+
+// thawCgroups thaws every cgroup, returning the errors of any that failed.
+func (tr *TaskRunner) thawCgroups(cgroups []string) error {
+	var mErr *multierror.Error
+	for _, cgroup := range cgroups {
+		if err := tr.freezer.Thaw(cgroup); err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to thaw cgroup %s: %v", cgroup, err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// setPauseState records whether the task is paused and persists it.
+func (tr *TaskRunner) setPauseState(pause *state.PauseState) {
+	tr.stateLock.Lock()
+	tr.localState.Pause = pause
+	tr.stateLock.Unlock()
+
+	if err := tr.persistLocalState(); err != nil {
+		tr.logger.Error("error persisting paused task state", "error", err)
+	}
+}
+
+// startPauseTimer resumes the task at its pause deadline. The pauseLock must
+// be held.
+func (tr *TaskRunner) startPauseTimer(pause *state.PauseState) {
+	tr.stopPauseTimer()
+	tr.pauseTimer = time.AfterFunc(time.Until(pause.ResumeAt), func() {
+		tr.pauseLock.Lock()
+		defer tr.pauseLock.Unlock()
+
+		// Ignore a timer that fired after the task was resumed or paused
+		// again
+		tr.stateLock.RLock()
+		current := tr.localState.Pause
+		tr.stateLock.RUnlock()
+		if current == nil || !current.PausedAt.Equal(pause.PausedAt) {
+			return
+		}
+
+		msg := fmt.Sprintf("Task resumed after being paused for the maximum of %v",
+			pause.ResumeAt.Sub(pause.PausedAt))
+		if err := tr.resume("client", msg); err != nil {
+			tr.logger.Error("failed to resume paused task", "error", err)
+		}
+	})
+}
+
+// stopPauseTimer stops the timer resuming a paused task, if any.
+func (tr *TaskRunner) stopPauseTimer() {
+	if tr.pauseTimer != nil {
+		tr.pauseTimer.Stop()
+		tr.pauseTimer = nil
+	}
+}
+
+// maxFreezeDuration returns the longest the task may stay paused.
+func (tr *TaskRunner) maxFreezeDuration() time.Duration {
+	if tr.clientConfig.MaxFreezeDuration > 0 {
+		return tr.clientConfig.MaxFreezeDuration
+	}
+	return config.DefaultMaxFreezeDuration
+}
@@ -0,0 +1,199 @@
+package taskrunner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cstate "github.com/hashicorp/nomad/client/state"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// mockFreezer is a cgroupFreezer that records which cgroups are frozen. The
+// cgroup of a pid is /nomad/<pid>.
+type mockFreezer struct {
+	frozen map[string]bool
+	lock   sync.Mutex
+}
+
+func newMockFreezer() *mockFreezer {
+	return &mockFreezer{frozen: make(map[string]bool)}
+}
+
+func (f *mockFreezer) CgroupForPid(pid string) (string, error) {
+	return "/nomad/" + pid, nil
+}
+
+func (f *mockFreezer) Freeze(cgroup string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.frozen[cgroup] = true
+	return nil
+}
+
+func (f *mockFreezer) Thaw(cgroup string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.frozen[cgroup] = false
+	return nil
+}
+
+func (f *mockFreezer) isFrozen(cgroup string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.frozen[cgroup]
+}
+
+// testPausableTaskRunner runs a mock driver task with pids 1 and 2, whose
+// cgroups are frozen by the returned mockFreezer.
+func testPausableTaskRunner(t *testing.T, conf *Config) (*TaskRunner, *mockFreezer) {
+	tr, err := NewTaskRunner(conf)
+	require.NoError(t, err)
+	freezer := newMockFreezer()
+	tr.freezer = freezer
+	go tr.Run()
+	t.Cleanup(func() { tr.Kill(context.Background(), structs.NewTaskEvent("cleanup")) })
+
+	testWaitForTaskToStart(t, tr)
+
+	// Wait for the mock driver's stats so they don't replace the pids
+	testutil.WaitForResult(func() (bool, error) {
+		return tr.LatestResourceUsage() != nil, fmt.Errorf("no resource usage")
+	}, func(err error) {
+		require.NoError(t, err)
+	})
+	tr.resourceUsageLock.Lock()
+	tr.resourceUsage = &cstructs.TaskResourceUsage{
+		Pids: map[string]*cstructs.ResourceUsage{"1": {}, "2": {}},
+	}
+	tr.resourceUsageLock.Unlock()
+
+	return tr, freezer
+}
+
+func testPausableAlloc() *structs.Allocation {
+	alloc := mock.BatchAlloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Driver = "mock_driver"
+	task.Config = map[string]interface{}{
+		"run_for": "10s",
+	}
+	return alloc
+}
+
+func findTaskEvent(tr *TaskRunner, eventType string) *structs.TaskEvent {
+	for _, ev := range tr.TaskState().Events {
+		if ev.Type == eventType {
+			return ev
+		}
+	}
+	return nil
+}
+
+func TestTaskRunner_Pause(t *testing.T) {
+	t.Parallel()
+
+	alloc := testPausableAlloc()
+	conf, cleanup := testTaskRunnerConfig(t, alloc, alloc.Job.TaskGroups[0].Tasks[0].Name)
+	defer cleanup()
+	tr, freezer := testPausableTaskRunner(t, conf)
+
+	require.NoError(t, tr.Pause("alice"))
+	require.True(t, tr.IsPaused())
+	require.True(t, freezer.isFrozen("/nomad/1"))
+	require.True(t, freezer.isFrozen("/nomad/2"))
+	require.EqualError(t, tr.Pause("alice"), "task is already paused")
+
+	event := findTaskEvent(tr, structs.TaskPaused)
+	require.NotNil(t, event)
+	require.Equal(t, "alice", event.Details["paused_by"])
+
+	require.NoError(t, tr.Resume("bob"))
+	require.False(t, tr.IsPaused())
+	require.False(t, freezer.isFrozen("/nomad/1"))
+	require.False(t, freezer.isFrozen("/nomad/2"))
+	require.EqualError(t, tr.Resume("bob"), "task is not paused")
+
+	event = findTaskEvent(tr, structs.TaskResumed)
+	require.NotNil(t, event)
+	require.Equal(t, "bob", event.Details["resumed_by"])
+	require.Equal(t, "Task resumed by bob", event.Message)
+}
+
+func TestTaskRunner_Pause_MaxFreezeDuration(t *testing.T) {
+	t.Parallel()
+
+	alloc := testPausableAlloc()
+	conf, cleanup := testTaskRunnerConfig(t, alloc, alloc.Job.TaskGroups[0].Tasks[0].Name)
+	defer cleanup()
+	conf.ClientConfig.MaxFreezeDuration = 100 * time.Millisecond
+	tr, freezer := testPausableTaskRunner(t, conf)
+
+	require.NoError(t, tr.Pause("alice"))
+	require.True(t, freezer.isFrozen("/nomad/1"))
+
+	// The task is resumed automatically
+	testutil.WaitForResult(func() (bool, error) {
+		return !tr.IsPaused(), fmt.Errorf("task still paused")
+	}, func(err error) {
+		require.NoError(t, err)
+	})
+	require.False(t, freezer.isFrozen("/nomad/1"))
+
+	event := findTaskEvent(tr, structs.TaskResumed)
+	require.NotNil(t, event)
+	require.Equal(t, "client", event.Details["resumed_by"])
+}
+
+func TestTaskRunner_Pause_SystemJob(t *testing.T) {
+	t.Parallel()
+
+	alloc := testPausableAlloc()
+	alloc.Job.Type = structs.JobTypeSystem
+	conf, cleanup := testTaskRunnerConfig(t, alloc, alloc.Job.TaskGroups[0].Tasks[0].Name)
+	defer cleanup()
+	tr, freezer := testPausableTaskRunner(t, conf)
+
+	require.EqualError(t, tr.Pause("alice"), "tasks of system jobs can't be paused")
+	require.False(t, tr.IsPaused())
+	require.False(t, freezer.isFrozen("/nomad/1"))
+}
+
+func TestTaskRunner_Pause_Restore(t *testing.T) {
+	t.Parallel()
+
+	alloc := testPausableAlloc()
+	conf, cleanup := testTaskRunnerConfig(t, alloc, alloc.Job.TaskGroups[0].Tasks[0].Name)
+	conf.StateDB = cstate.NewMemDB(conf.Logger) // "persist" state between task runners
+	defer cleanup()
+	origTR, _ := testPausableTaskRunner(t, conf)
+
+	require.NoError(t, origTR.Pause("alice"))
+
+	// Cause TR to exit without shutting down task
+	origTR.Shutdown()
+
+	// The new TaskRunner freezes the task again
+	newTR, err := NewTaskRunner(conf)
+	require.NoError(t, err)
+	freezer := newMockFreezer()
+	newTR.freezer = freezer
+	require.NoError(t, newTR.Restore())
+	go newTR.Run()
+	defer newTR.Kill(context.Background(), structs.NewTaskEvent("cleanup"))
+
+	require.True(t, newTR.IsPaused())
+	require.True(t, freezer.isFrozen("/nomad/1"))
+	require.True(t, freezer.isFrozen("/nomad/2"))
+
+	// Killing the task resumes it first
+	require.NoError(t, newTR.Kill(context.Background(), structs.NewTaskEvent("kill")))
+	require.False(t, newTR.IsPaused())
+	require.False(t, freezer.isFrozen("/nomad/1"))
+}
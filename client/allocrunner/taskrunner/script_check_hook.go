@@ -29,6 +29,10 @@ type scriptCheckHookConfig struct {
 	consul       consul.ConsulServiceAPI
 	logger       log.Logger
 	shutdownWait time.Duration
+
+	// paused returns whether the task is paused, suspending its script
+	// checks. It may be nil.
+	paused func() bool
 }
 
 // scriptCheckHook implements a task runner hook for running script
@@ -41,6 +45,7 @@ type scriptCheckHook struct {
 	logger          log.Logger
 	shutdownWait    time.Duration // max time to wait for scripts to shutdown
 	shutdownCh      chan struct{} // closed when all scripts should shutdown
+	paused          func() bool   // true while the task is paused; may be nil
 
 	// The following fields can be changed by Update()
 	driverExec tinterfaces.ScriptExecutor
@@ -68,6 +73,7 @@ func newScriptCheckHook(c scriptCheckHookConfig) *scriptCheckHook {
 		runningScripts:  make(map[string]*taskletHandle),
 		shutdownWait:    defaultShutdownWait,
 		shutdownCh:      make(chan struct{}),
+		paused:          c.paused,
 	}
 
 	if c.shutdownWait != 0 {
@@ -195,6 +201,7 @@ func (h *scriptCheckHook) newScriptChecks() map[string]*scriptCheck {
 				taskEnv:         h.taskEnv,
 				logger:          h.logger,
 				shutdownCh:      h.shutdownCh,
+				paused:          h.paused,
 			})
 			if sc != nil {
 				scriptChecks[sc.id] = sc
@@ -235,6 +242,7 @@ func (h *scriptCheckHook) newScriptChecks() map[string]*scriptCheck {
 				taskEnv:         h.taskEnv,
 				logger:          h.logger,
 				shutdownCh:      h.shutdownCh,
+				paused:          h.paused,
 				isGroup:         true,
 			})
 			if sc != nil {
@@ -272,7 +280,8 @@ type scriptCheck struct {
 	consulNamespace string
 	ttlUpdater      TTLUpdater
 	check           *structs.ServiceCheck
-	lastCheckOk     bool // true if the last check was ok; otherwise false
+	lastCheckOk     bool   // true if the last check was ok; otherwise false
+	lastState       string // the last status reported before the task was paused
 	tasklet
 }
 
@@ -288,6 +297,7 @@ type scriptCheckConfig struct {
 	taskEnv         *taskenv.TaskEnv
 	logger          log.Logger
 	shutdownCh      chan struct{}
+	paused          func() bool
 	isGroup         bool
 }
 
@@ -316,6 +326,9 @@ func newScriptCheck(config *scriptCheckConfig) *scriptCheck {
 	sc.Interval = config.check.Interval
 	sc.Timeout = config.check.Timeout
 	sc.exec = config.driverExec
+	if config.paused != nil {
+		sc.exec = &pausableExec{ScriptExecutor: config.driverExec, paused: config.paused}
+	}
 	sc.callback = newScriptCheckCallback(sc)
 	sc.logger = config.logger
 	sc.shutdownCh = config.shutdownCh
@@ -341,6 +354,20 @@ func (sc *scriptCheck) Copy() *scriptCheck {
 	return newSc
 }
 
+// pausableExec returns errTaskPaused instead of executing scripts while the
+// task is paused, since they would be frozen along with its processes.
+type pausableExec struct {
+	tinterfaces.ScriptExecutor
+	paused func() bool
+}
+
+func (e *pausableExec) Exec(timeout time.Duration, cmd string, args []string) ([]byte, int, error) {
+	if e.paused() {
+		return nil, 0, errTaskPaused
+	}
+	return e.ScriptExecutor.Exec(timeout, cmd, args)
+}
+
 // closes over the script check and returns the taskletCallback for
 // when the script check executes.
 func newScriptCheckCallback(s *scriptCheck) taskletCallback {
@@ -359,12 +386,21 @@ func newScriptCheckCallback(s *scriptCheck) taskletCallback {
 		}
 
 		var outputMsg string
-		if err != nil {
+		if err == errTaskPaused {
+			// Checks of paused tasks are suspended rather than failed, so
+			// keep reporting the last status until the task is resumed
+			if s.lastState == "" {
+				return
+			}
+			state = s.lastState
+			outputMsg = "check suspended while the task is paused"
+		} else if err != nil {
 			state = api.HealthCritical
 			outputMsg = err.Error()
 		} else {
 			outputMsg = string(output)
 		}
+		s.lastState = state
 
 		// heartbeat the check to Consul
 		err = s.updateTTL(ctx, outputMsg, state)
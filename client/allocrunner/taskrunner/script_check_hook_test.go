@@ -221,6 +221,52 @@ func TestScript_Exec_Codes(t *testing.T) {
 	}
 }
 
+// TestScript_Exec_Paused asserts script checks of paused tasks aren't executed
+// and keep reporting their last status.
+func TestScript_Exec_Paused(t *testing.T) {
+
+	exec := newScriptedExec([]execResult{
+		{[]byte("output"), 0, nil},
+	})
+	logger := testlog.HCLogger(t)
+	hb := newFakeHeartbeater()
+	script := newScriptMock(
+		hb, exec, logger, time.Nanosecond, 3*time.Second)
+
+	// The task is paused after the check last reported a warning
+	paused := int32(1)
+	script.exec = &pausableExec{
+		ScriptExecutor: exec,
+		paused:         func() bool { return atomic.LoadInt32(&paused) == 1 },
+	}
+	script.lastState = api.HealthWarning
+
+	handle := script.run()
+	defer handle.cancel() // cleanup
+	deadline := time.After(3 * time.Second)
+
+	next := func() heartbeat {
+		select {
+		case update := <-hb.heartbeats:
+			return update
+		case <-deadline:
+			t.Fatalf("timed out waiting for script checks")
+		}
+		return heartbeat{}
+	}
+
+	suspended := heartbeat{script.id, "check suspended while the task is paused", api.HealthWarning}
+	require.Equal(t, suspended, next())
+	atomic.StoreInt32(&paused, 0)
+
+	// Once resumed the script runs again
+	update := next()
+	for update == suspended {
+		update = next()
+	}
+	require.Equal(t, heartbeat{script.id, "output", api.HealthPassing}, update)
+}
+
 // TestScript_TaskEnvInterpolation asserts that script check hooks are
 // interpolated in the same way that services are
 func TestScript_TaskEnvInterpolation(t *testing.T) {
@@ -1,6 +1,8 @@
 package state
 
 import (
+	"time"
+
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/plugins/drivers"
 )
@@ -16,6 +18,10 @@ type LocalState struct {
 
 	// TaskHandle is the handle used to reattach to the task during recovery
 	TaskHandle *drivers.TaskHandle
+
+	// Pause records that an operator paused the task, so it stays paused
+	// when restarting Nomad agents
+	Pause *PauseState
 }
 
 func NewLocalState() *LocalState {
@@ -52,6 +58,7 @@ func (s *LocalState) Copy() *LocalState {
 		Hooks:         make(map[string]*HookState, len(s.Hooks)),
 		DriverNetwork: s.DriverNetwork.Copy(),
 		TaskHandle:    s.TaskHandle.Copy(),
+		Pause:         s.Pause.Copy(),
 	}
 
 	// Copy the hook state
@@ -103,3 +110,30 @@ func (h *HookState) Equal(o *HookState) bool {
 
 	return helper.CompareMapStringString(h.Env, o.Env)
 }
+
+// PauseState is the intent to keep a task's processes frozen.
+type PauseState struct {
+	// Cgroups are the frozen cgroups of the task's processes
+	Cgroups []string
+
+	// PausedBy identifies who paused the task
+	PausedBy string
+
+	// PausedAt is when the task was paused
+	PausedAt time.Time
+
+	// ResumeAt is when the task is resumed automatically
+	ResumeAt time.Time
+}
+
+// Copy PauseState. Returns nil if its nil.
+func (p *PauseState) Copy() *PauseState {
+	if p == nil {
+		return nil
+	}
+
+	c := new(PauseState)
+	*c = *p
+	c.Cgroups = helper.CopySliceString(p.Cgroups)
+	return c
+}
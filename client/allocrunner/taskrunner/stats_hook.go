@@ -7,6 +7,7 @@ import (
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	"github.com/hashicorp/nomad/client/lib/cgutil"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
 	bstructs "github.com/hashicorp/nomad/plugins/base/structs"
@@ -23,6 +24,13 @@ type statsHook struct {
 	updater  StatsUpdater
 	interval time.Duration
 
+	// diskIO reads the task's block device I/O stats from its cgroup. It
+	// may be nil.
+	diskIO *cgutil.DiskIOCollector
+
+	// diskIOUnresolvable logs once that the task's cgroup can't be found
+	diskIOUnresolvable sync.Once
+
 	// cancel is called by Exited
 	cancel context.CancelFunc
 
@@ -31,10 +39,11 @@ type statsHook struct {
 	logger hclog.Logger
 }
 
-func newStatsHook(su StatsUpdater, interval time.Duration, logger hclog.Logger) *statsHook {
+func newStatsHook(su StatsUpdater, interval time.Duration, diskIO *cgutil.DiskIOCollector, logger hclog.Logger) *statsHook {
 	h := &statsHook{
 		updater:  su,
 		interval: interval,
+		diskIO:   diskIO,
 	}
 	h.logger = logger.Named(h.Name())
 	return h
@@ -111,6 +120,7 @@ MAIN:
 			}
 
 			// Update stats on TaskRunner and emit them
+			h.addDiskIOStats(ru)
 			h.updater.UpdateStats(ru)
 
 		case <-ctx.Done():
@@ -119,6 +129,51 @@ MAIN:
 	}
 }
 
+// addDiskIOStats adds the block device I/O stats of the task's cgroup to the
+// resource usage reported by the driver, unless the driver reported them
+// itself. The cgroup is found from the task's processes, so it is only
+// resolvable for drivers that report their pids.
+//
+// Drivers with their own isolation, such as docker, don't report pids and
+// place tasks in cgroups of their own, which the only cgroup path passed to
+// drivers (the cpuset cgroup) doesn't identify. Drivers without isolation,
+// such as raw_exec, leave tasks in the agent's block I/O cgroup. Their tasks'
+// disk I/O stats are skipped rather than read from a cgroup that isn't the
+// task's.
+func (h *statsHook) addDiskIOStats(ru *cstructs.TaskResourceUsage) {
+	if h.diskIO == nil || ru == nil || ru.ResourceUsage == nil || ru.ResourceUsage.DiskIOStats != nil {
+		return
+	}
+
+	if len(ru.Pids) == 0 {
+		h.diskIOUnresolvable.Do(func() {
+			h.logger.Debug("skipping disk I/O stats", "reason", "driver doesn't report the task's pids")
+		})
+		return
+	}
+
+	for pid := range ru.Pids {
+		cgroup, err := h.diskIO.CgroupForPid(pid)
+		if err != nil {
+			continue
+		}
+		if !h.diskIO.TaskCgroup(cgroup) {
+			h.diskIOUnresolvable.Do(func() {
+				h.logger.Debug("skipping disk I/O stats", "reason", "task has no block I/O cgroup of its own", "cgroup", cgroup)
+			})
+			return
+		}
+
+		stats, err := h.diskIO.Collect(cgroup)
+		if err != nil {
+			h.logger.Trace("failed to collect disk I/O stats", "cgroup", cgroup, "error", err)
+			return
+		}
+		ru.ResourceUsage.DiskIOStats = stats
+		return
+	}
+}
+
 // callStatsWithRetry invokes handle driver Stats() functions and retries until channel is established
 // successfully.  Returns an error if it encounters a permanent error.
 //
@@ -2,11 +2,14 @@ package taskrunner
 
 import (
 	"context"
+	"os"
+	"strconv"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	"github.com/hashicorp/nomad/client/lib/cgutil"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/helper/testlog"
 	"github.com/stretchr/testify/require"
@@ -92,7 +95,7 @@ func TestTaskRunner_StatsHook_PoststartExited(t *testing.T) {
 	poststartReq := &interfaces.TaskPoststartRequest{DriverStats: ds}
 
 	// Create hook
-	h := newStatsHook(su, time.Minute, logger)
+	h := newStatsHook(su, time.Minute, nil, logger)
 
 	// Always call Exited to cleanup goroutines
 	defer h.Exited(context.Background(), nil, nil)
@@ -127,7 +130,7 @@ func TestTaskRunner_StatsHook_Periodic(t *testing.T) {
 	// Exited() can complete within the interval.
 	const interval = 500 * time.Millisecond
 
-	h := newStatsHook(su, interval, logger)
+	h := newStatsHook(su, interval, nil, logger)
 	defer h.Exited(context.Background(), nil, nil)
 
 	// Run prestart
@@ -190,7 +193,7 @@ func TestTaskRunner_StatsHook_NotImplemented(t *testing.T) {
 
 	poststartReq := &interfaces.TaskPoststartRequest{DriverStats: ds}
 
-	h := newStatsHook(su, 1, logger)
+	h := newStatsHook(su, 1, nil, logger)
 	defer h.Exited(context.Background(), nil, nil)
 
 	// Run prestart
@@ -216,7 +219,7 @@ func TestTaskRunner_StatsHook_Backoff(t *testing.T) {
 
 	poststartReq := &interfaces.TaskPoststartRequest{DriverStats: ds}
 
-	h := newStatsHook(su, time.Minute, logger)
+	h := newStatsHook(su, time.Minute, nil, logger)
 	defer h.Exited(context.Background(), nil, nil)
 
 	// Run prestart
@@ -235,3 +238,35 @@ DRAIN:
 
 	require.Equal(t, ds.Called(), 1)
 }
+
+// TestTaskRunner_StatsHook_DiskIOWithoutPids asserts disk I/O stats are
+// skipped for drivers that don't report the task's pids, as its cgroup can't
+// be resolved.
+func TestTaskRunner_StatsHook_DiskIOWithoutPids(t *testing.T) {
+	t.Parallel()
+
+	h := newStatsHook(newMockStatsUpdater(), time.Minute, cgutil.NewDiskIOCollector(), testlog.HCLogger(t))
+
+	ru := &cstructs.TaskResourceUsage{ResourceUsage: &cstructs.ResourceUsage{}}
+	h.addDiskIOStats(ru)
+	require.Nil(t, ru.ResourceUsage.DiskIOStats)
+}
+
+// TestTaskRunner_StatsHook_DiskIOAgentCgroup asserts disk I/O stats are
+// skipped for tasks that share the agent's block I/O cgroup, and that the
+// stats reported by the driver are kept.
+func TestTaskRunner_StatsHook_DiskIOAgentCgroup(t *testing.T) {
+	t.Parallel()
+
+	h := newStatsHook(newMockStatsUpdater(), time.Minute, cgutil.NewDiskIOCollector(), testlog.HCLogger(t))
+
+	pids := map[string]*cstructs.ResourceUsage{strconv.Itoa(os.Getpid()): {}}
+	ru := &cstructs.TaskResourceUsage{ResourceUsage: &cstructs.ResourceUsage{}, Pids: pids}
+	h.addDiskIOStats(ru)
+	require.Nil(t, ru.ResourceUsage.DiskIOStats)
+
+	reported := &cstructs.DiskIOStats{ReadBytes: 1}
+	ru.ResourceUsage.DiskIOStats = reported
+	h.addDiskIOStats(ru)
+	require.Same(t, reported, ru.ResourceUsage.DiskIOStats)
+}
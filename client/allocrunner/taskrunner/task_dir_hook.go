@@ -2,7 +2,6 @@ package taskrunner
 
 import (
 	"context"
-	"strings"
 
 	log "github.com/hashicorp/go-hclog"
 
@@ -23,6 +22,11 @@ const (
 	TaskDirHookIsDoneDataKey = "is_done"
 )
 
+func init() {
+	// COMPAT(1.0) using inclusive language, blacklist is kept for backward compatibility.
+	cconfig.RegisterOptionKeys("env.denylist", "env.blacklist")
+}
+
 type taskDirHook struct {
 	runner *TaskRunner
 	logger log.Logger
@@ -45,30 +49,29 @@ func (h *taskDirHook) Name() string {
 func (h *taskDirHook) Prestart(ctx context.Context, req *interfaces.TaskPrestartRequest, resp *interfaces.TaskPrestartResponse) error {
 	fsi := h.runner.driverCapabilities.FSIsolation
 	if v, ok := req.PreviousState[TaskDirHookIsDoneDataKey]; ok && v == "true" {
-		setEnvvars(h.runner.envBuilder, fsi, h.runner.taskDir, h.runner.clientConfig)
+		setEnvvars(h.runner.envBuilder, fsi, h.runner.taskDir, h.runner.clientConfig, h.runner.Alloc().Namespace)
 		resp.State = map[string]string{
 			TaskDirHookIsDoneDataKey: "true",
 		}
 		return nil
 	}
 
-	cc := h.runner.clientConfig
-	chroot := cconfig.DefaultChrootEnv
-	if len(cc.ChrootEnv) > 0 {
-		chroot = cc.ChrootEnv
+	chroot, err := h.runner.clientConfig.TaskChrootEnv(req.Task.ChrootExtras)
+	if err != nil {
+		return err
 	}
 
 	// Emit the event that we are going to be building the task directory
 	h.runner.EmitEvent(structs.NewTaskEvent(structs.TaskSetup).SetMessage(structs.TaskBuildingTaskDir))
 
 	// Build the task directory structure
-	err := h.runner.taskDir.Build(fsi == drivers.FSIsolationChroot, chroot)
+	err = h.runner.taskDir.Build(fsi == drivers.FSIsolationChroot, chroot)
 	if err != nil {
 		return err
 	}
 
 	// Update the environment variables based on the built task directory
-	setEnvvars(h.runner.envBuilder, fsi, h.runner.taskDir, h.runner.clientConfig)
+	setEnvvars(h.runner.envBuilder, fsi, h.runner.taskDir, h.runner.clientConfig, h.runner.Alloc().Namespace)
 	resp.State = map[string]string{
 		TaskDirHookIsDoneDataKey: "true",
 	}
@@ -76,7 +79,7 @@ func (h *taskDirHook) Prestart(ctx context.Context, req *interfaces.TaskPrestart
 }
 
 // setEnvvars sets path and host env vars depending on the FS isolation used.
-func setEnvvars(envBuilder *taskenv.Builder, fsi drivers.FSIsolation, taskDir *allocdir.TaskDir, conf *cconfig.Config) {
+func setEnvvars(envBuilder *taskenv.Builder, fsi drivers.FSIsolation, taskDir *allocdir.TaskDir, conf *cconfig.Config, namespace string) {
 
 	envBuilder.SetClientTaskRoot(taskDir.Dir)
 	envBuilder.SetClientSharedAllocDir(taskDir.SharedAllocDir)
@@ -99,11 +102,6 @@ func setEnvvars(envBuilder *taskenv.Builder, fsi drivers.FSIsolation, taskDir *a
 
 	// Set the host environment variables for non-image based drivers
 	if fsi != drivers.FSIsolationImage {
-		// COMPAT(1.0) using inclusive language, blacklist is kept for backward compatibility.
-		filter := strings.Split(conf.ReadAlternativeDefault(
-			[]string{"env.denylist", "env.blacklist"},
-			cconfig.DefaultEnvDenylist,
-		), ",")
-		envBuilder.SetHostEnvvars(filter)
+		envBuilder.SetHostEnvvars(conf.EffectiveEnvDenylist(namespace))
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	"github.com/hashicorp/nomad/client/lib/cgutil"
 
 	metrics "github.com/armon/go-metrics"
@@ -16,6 +17,8 @@ import (
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/getter"
+	ti "github.com/hashicorp/nomad/client/allocrunner/taskrunner/interfaces"
 	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/restarts"
 	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/state"
 	"github.com/hashicorp/nomad/client/config"
@@ -75,6 +78,10 @@ type TaskRunner struct {
 
 	clientConfig *config.Config
 
+	// getClientConfig returns the client's current configuration. It may be
+	// nil.
+	getClientConfig func() *config.Config
+
 	// stateUpdater is used to emit updated task state
 	stateUpdater interfaces.TaskStateHandler
 
@@ -117,6 +124,10 @@ type TaskRunner struct {
 	shutdownDelayCtx      context.Context
 	shutdownDelayCancelFn context.CancelFunc
 
+	// restartSequencer orders template driven restarts of this task with
+	// the other tasks in the group. It may be nil.
+	restartSequencer ti.TaskRestartSequencer
+
 	// Logger is the logger for the task runner.
 	logger log.Logger
 
@@ -211,6 +222,27 @@ type TaskRunner struct {
 	// cpusetCgroupPathGetter is used to lookup the cgroup path if supported by the platform
 	cpusetCgroupPathGetter cgutil.CgroupPathGetter
 
+	// diskIOCollector reads the block device I/O stats of the task. It may
+	// be nil.
+	diskIOCollector *cgutil.DiskIOCollector
+
+	// freezer freezes the cgroups of the task's processes while it's paused
+	freezer cgroupFreezer
+
+	// pauseTimer resumes a paused task once the client's
+	// MaxFreezeDuration has passed. pauseLock serializes pausing and
+	// resuming the task and guards pauseTimer.
+	pauseTimer *time.Timer
+	pauseLock  sync.Mutex
+
+	// artifactChecksumPolicy decides whether the task's artifacts must
+	// specify a checksum. It may be nil.
+	artifactChecksumPolicy *getter.ChecksumPolicy
+
+	// bandwidth throttles the task's artifact downloads to their share of
+	// the node's download bandwidth. It may be nil.
+	bandwidth *bandwidth.Manager
+
 	// driverManager is used to dispense driver plugins and register event
 	// handlers
 	driverManager drivermanager.Manager
@@ -247,6 +279,11 @@ type Config struct {
 	TaskDir      *allocdir.TaskDir
 	Logger       log.Logger
 
+	// GetClientConfig returns the client's current configuration, which is
+	// replaced when the client reloads its config. It may be nil, in which
+	// case ClientConfig is used.
+	GetClientConfig func() *config.Config
+
 	// Consul is the client to use for managing Consul service registrations
 	Consul consul.ConsulServiceAPI
 
@@ -278,6 +315,18 @@ type Config struct {
 	// CpusetCgroupPathGetter is used to lookup the cgroup path if supported by the platform
 	CpusetCgroupPathGetter cgutil.CgroupPathGetter
 
+	// DiskIOCollector reads the block device I/O stats of the task. It may
+	// be nil.
+	DiskIOCollector *cgutil.DiskIOCollector
+
+	// ArtifactChecksumPolicy decides whether the task's artifacts must
+	// specify a checksum. It may be nil.
+	ArtifactChecksumPolicy *getter.ChecksumPolicy
+
+	// Bandwidth throttles the task's artifact downloads to their share of
+	// the node's download bandwidth. It may be nil.
+	Bandwidth *bandwidth.Manager
+
 	// DeviceManager is used to mount devices as well as lookup device
 	// statistics
 	DeviceManager devicemanager.Manager
@@ -299,6 +348,10 @@ type Config struct {
 
 	// ShutdownDelayCancelFn should only be used in testing.
 	ShutdownDelayCancelFn context.CancelFunc
+
+	// RestartSequencer orders template driven restarts of this task with
+	// the other tasks in the group. It may be nil.
+	RestartSequencer ti.TaskRestartSequencer
 }
 
 func NewTaskRunner(config *Config) (*TaskRunner, error) {
@@ -326,6 +379,7 @@ func NewTaskRunner(config *Config) (*TaskRunner, error) {
 		alloc:                  config.Alloc,
 		allocID:                config.Alloc.ID,
 		clientConfig:           config.ClientConfig,
+		getClientConfig:        config.GetClientConfig,
 		task:                   config.Task,
 		taskDir:                config.TaskDir,
 		taskName:               config.Task.Name,
@@ -349,6 +403,10 @@ func NewTaskRunner(config *Config) (*TaskRunner, error) {
 		waitCh:                 make(chan struct{}),
 		csiManager:             config.CSIManager,
 		cpusetCgroupPathGetter: config.CpusetCgroupPathGetter,
+		diskIOCollector:        config.DiskIOCollector,
+		freezer:                cgutil.NewFreezer(),
+		artifactChecksumPolicy: config.ArtifactChecksumPolicy,
+		bandwidth:              config.Bandwidth,
 		devicemanager:          config.DeviceManager,
 		driverManager:          config.DriverManager,
 		maxEvents:              defaultMaxEvents,
@@ -356,6 +414,7 @@ func NewTaskRunner(config *Config) (*TaskRunner, error) {
 		startConditionMetCtx:   config.StartConditionMetCtx,
 		shutdownDelayCtx:       config.ShutdownDelayCtx,
 		shutdownDelayCancelFn:  config.ShutdownDelayCancelFn,
+		restartSequencer:       config.RestartSequencer,
 	}
 
 	// Create the logger based on the allocation ID
@@ -475,6 +534,13 @@ func (tr *TaskRunner) MarkFailedDead(reason string) {
 	}
 }
 
+// ShutdownBeforeRun closes WaitCh without running the task, for when the
+// client shuts down before the alloc's prerun hooks finish. The task's state
+// is left as is, so that it's restored when the client restarts.
+func (tr *TaskRunner) ShutdownBeforeRun() {
+	close(tr.waitCh)
+}
+
 // Run the TaskRunner. Starts the user's task or reattaches to a restored task.
 // Run closes WaitCh when it exits. Should be started in a goroutine.
 func (tr *TaskRunner) Run() {
@@ -822,7 +888,7 @@ func (tr *TaskRunner) runDriver() error {
 	}
 
 	// Start the job if there's no existing handle (or if RecoverTask failed)
-	handle, net, err := tr.driver.StartTask(taskConfig)
+	handle, net, err := tr.startTask(taskConfig)
 	if err != nil {
 		// The plugin has died, try relaunching it
 		if err == bstructs.ErrPluginShutdown {
@@ -833,7 +899,7 @@ func (tr *TaskRunner) runDriver() error {
 				return taskErr
 			}
 
-			handle, net, err = tr.driver.StartTask(taskConfig)
+			handle, net, err = tr.startTask(taskConfig)
 			if err != nil {
 				taskErr := fmt.Errorf("failed to start task after driver exited unexpectedly: %v", err)
 				tr.EmitEvent(structs.NewTaskEvent(structs.TaskDriverFailure).SetDriverError(taskErr))
@@ -866,11 +932,23 @@ func (tr *TaskRunner) runDriver() error {
 	return nil
 }
 
+// startTask starts the task with the driver, recording how long the driver
+// took and whether it failed. Failures caused by the task's own config aren't
+// recorded, so that they don't degrade the driver.
+func (tr *TaskRunner) startTask(taskConfig *drivers.TaskConfig) (*drivers.TaskHandle, *drivers.DriverNetwork, error) {
+	start := time.Now()
+	handle, net, err := tr.driver.StartTask(taskConfig)
+	if !drivers.IsTaskConfigError(err) {
+		tr.driverManager.RecordTaskStart(tr.Task().Driver, time.Since(start), err)
+	}
+	return handle, net, err
+}
+
 // initDriver retrives the DriverPlugin from the plugin loader for this task
 func (tr *TaskRunner) initDriver() error {
 	driver, err := tr.driverManager.Dispense(tr.Task().Driver)
 	if err != nil {
-		return err
+		return structs.NewNodePlacementError(structs.PlacementFailureDriverUnavailable, err)
 	}
 	tr.driver = driver
 
@@ -972,6 +1050,9 @@ func (tr *TaskRunner) handleKill(resultCh <-chan *drivers.ExitResult) *drivers.E
 // killTask will retry with an exponential backoff and will give up at a
 // given limit. Returns an error if the task could not be killed.
 func (tr *TaskRunner) killTask(handle *DriverHandle, resultCh <-chan *drivers.ExitResult) (*drivers.ExitResult, error) {
+	// Frozen processes can't exit, so resume a paused task first
+	tr.resumeForKill()
+
 	// Cap the number of times we attempt to kill the task.
 	var err error
 	for i := 0; i < killFailureLimit; i++ {
@@ -1059,6 +1140,7 @@ func (tr *TaskRunner) buildTaskConfig() *drivers.TaskConfig {
 				CPUShares:        taskResources.Cpu.CpuShares,
 				CpusetCpus:       strings.Join(cpusetCpus, ","),
 				PercentTicks:     float64(taskResources.Cpu.CpuShares) / float64(tr.clientConfig.Node.NodeResources.Cpu.CpuShares),
+				CoreLimitBytes:   coreLimit(tr.clientConfig.CoreDumps, task.Driver),
 			},
 			Ports: &ports,
 		},
@@ -1100,6 +1182,9 @@ func (tr *TaskRunner) Restore() error {
 		//     have to persist it at all!
 		restored := tr.restoreHandle(taskHandle, tr.localState.DriverNetwork)
 
+		// Keep the task paused if it was, now that its handle is restored
+		tr.restorePause()
+
 		// If the handle could not be restored, the alloc is
 		// non-terminal, and the task isn't a system job: wait until
 		// servers have been contacted before running. #1795
@@ -1282,6 +1367,16 @@ func (tr *TaskRunner) appendEvent(event *structs.TaskEvent) error {
 		tr.state.LastRestart = time.Unix(0, event.Time)
 	}
 
+	// Record why the task terminated or failed to start
+	if reason := event.TerminationReason(); reason != nil {
+		tr.state.TerminationReason = reason
+		labels := append([]metrics.Label{
+			{Name: "reason", Value: reason.Reason},
+			{Name: "subsystem", Value: reason.Subsystem},
+		}, tr.baseLabels...)
+		metrics.IncrCounterWithLabels([]string{"client", "allocs", "terminations"}, 1, labels)
+	}
+
 	// Append event to slice
 	appendTaskEvent(tr.state, event, tr.maxEvents)
 
@@ -1293,6 +1388,16 @@ func (tr *TaskRunner) WaitCh() <-chan struct{} {
 	return tr.waitCh
 }
 
+// currentClientConfig returns the client's current configuration, which
+// reflects reloads of the client's config when the runner was given a way to
+// read it. The returned config must not be modified.
+func (tr *TaskRunner) currentClientConfig() *config.Config {
+	if tr.getClientConfig != nil {
+		return tr.getClientConfig()
+	}
+	return tr.clientConfig
+}
+
 // Update the running allocation with a new version received from the server.
 // Calls Update hooks asynchronously with Run.
 //
@@ -1348,6 +1453,11 @@ func (tr *TaskRunner) Shutdown() {
 	tr.logger.Trace("shutting down")
 	tr.shutdownCtxCancel()
 
+	// A paused task stays paused until the client restores it
+	tr.pauseLock.Lock()
+	tr.stopPauseTimer()
+	tr.pauseLock.Unlock()
+
 	<-tr.WaitCh()
 
 	// Run shutdown hooks to cleanup
@@ -1459,6 +1569,24 @@ func (tr *TaskRunner) emitStats(ru *cstructs.TaskResourceUsage) {
 	} else {
 		tr.logger.Debug("Skipping cpu stats for allocation", "reason", "CpuStats is nil")
 	}
+
+	if ru.ResourceUsage.DiskIOStats != nil {
+		tr.setGaugeForDiskIO(ru)
+	}
+}
+
+// setGaugeForDiskIO publishes the task's block device I/O stats.
+func (tr *TaskRunner) setGaugeForDiskIO(ru *cstructs.TaskResourceUsage) {
+	ds := ru.ResourceUsage.DiskIOStats
+
+	metrics.SetGaugeWithLabels([]string{"client", "allocs", "disk_io", "read_bytes"},
+		float32(ds.ReadBytes), tr.baseLabels)
+	metrics.SetGaugeWithLabels([]string{"client", "allocs", "disk_io", "write_bytes"},
+		float32(ds.WriteBytes), tr.baseLabels)
+	metrics.SetGaugeWithLabels([]string{"client", "allocs", "disk_io", "read_ops"},
+		float32(ds.ReadOps), tr.baseLabels)
+	metrics.SetGaugeWithLabels([]string{"client", "allocs", "disk_io", "write_ops"},
+		float32(ds.WriteOps), tr.baseLabels)
 }
 
 // appendTaskEvent updates the task status by appending the new event.
@@ -65,11 +65,21 @@ func (tr *TaskRunner) initHooks() {
 		newLogMonHook(tr, hookLogger),
 		newDispatchHook(alloc, hookLogger),
 		newVolumeHook(tr, hookLogger),
-		newArtifactHook(tr, hookLogger),
-		newStatsHook(tr, tr.clientConfig.StatsCollectionInterval, hookLogger),
+		newArtifactHook(tr, tr.artifactChecksumPolicy, tr.bandwidth, hookLogger),
+		newStatsHook(tr, tr.clientConfig.StatsCollectionInterval, tr.diskIOCollector, hookLogger),
 		newDeviceHook(tr.devicemanager, hookLogger),
 	}
 
+	// If core files dumped by the task are collected, add the hook
+	if cores := tr.clientConfig.CoreDumps; coreLimit(cores, task.Driver) > 0 {
+		tr.runnerHooks = append(tr.runnerHooks, newCoreDumpHook(cores, task.Name, tr.taskDir, tr, hookLogger))
+	}
+
+	// If the task has a tmpfs stanza, add the hook.
+	if task.Tmpfs != nil {
+		tr.runnerHooks = append(tr.runnerHooks, newTmpfsHook(tr.clientConfig.MaxTaskTmpfsMB, tr.driverCapabilities.FSIsolation, tr.taskDir, tr.hookResources, tr, hookLogger))
+	}
+
 	// If the task has a CSI stanza, add the hook.
 	if task.CSIPluginConfig != nil {
 		tr.runnerHooks = append(tr.runnerHooks, newCSIPluginSupervisorHook(filepath.Join(tr.clientConfig.StateDir, "csi"), tr, tr, hookLogger))
@@ -95,13 +105,14 @@ func (tr *TaskRunner) initHooks() {
 	// If there are templates is enabled, add the hook
 	if len(task.Templates) != 0 {
 		tr.runnerHooks = append(tr.runnerHooks, newTemplateHook(&templateHookConfig{
-			logger:          hookLogger,
-			lifecycle:       tr,
-			events:          tr,
-			templates:       task.Templates,
-			clientConfig:    tr.clientConfig,
-			envBuilder:      tr.envBuilder,
-			consulNamespace: consulNamespace,
+			logger:           hookLogger,
+			lifecycle:        tr,
+			restartSequencer: tr.restartSequencer,
+			events:           tr,
+			templates:        task.Templates,
+			clientConfig:     tr.currentClientConfig,
+			envBuilder:       tr.envBuilder,
+			consulNamespace:  consulNamespace,
 		}))
 	}
 
@@ -151,6 +162,7 @@ func (tr *TaskRunner) initHooks() {
 		task:   tr.Task(),
 		consul: tr.consulServiceClient,
 		logger: hookLogger,
+		paused: tr.IsPaused,
 	}))
 
 	// If this task driver has remote capabilities, add the remote task
@@ -167,6 +179,9 @@ func (tr *TaskRunner) emitHookError(err error, hookName string) {
 	} else {
 		message := fmt.Sprintf("%s: %v", hookName, err)
 		taskEvent = structs.NewTaskEvent(structs.TaskHookFailed).SetMessage(message)
+		if code, ok := structs.PlacementFailureCode(err); ok {
+			taskEvent.Details[structs.TaskEventDetailPlacementFailure] = code
+		}
 	}
 
 	tr.EmitEvent(taskEvent)
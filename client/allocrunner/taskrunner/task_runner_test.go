@@ -2446,6 +2446,70 @@ func TestTaskRunner_VaultManager_Signal(t *testing.T) {
 
 }
 
+// TestTaskRunner_TerminationReason asserts the task state records why the
+// task terminated or failed to start.
+func TestTaskRunner_TerminationReason(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		config   map[string]interface{}
+		expected *structs.TaskTerminationReason
+	}{
+		{
+			name:   "exited",
+			config: map[string]interface{}{"exit_code": "3", "run_for": "1ns"},
+			expected: &structs.TaskTerminationReason{
+				Reason:    structs.TaskTerminationExited,
+				ExitCode:  3,
+				Subsystem: structs.TaskTerminationSubsystemDriver,
+			},
+		},
+		{
+			name:   "signaled",
+			config: map[string]interface{}{"exit_code": "137", "exit_signal": "9", "run_for": "1ns"},
+			expected: &structs.TaskTerminationReason{
+				Reason:    structs.TaskTerminationSignaled,
+				ExitCode:  137,
+				Signal:    9,
+				Subsystem: structs.TaskTerminationSubsystemDriver,
+			},
+		},
+		{
+			name:   "driver failure",
+			config: map[string]interface{}{"start_error": "driver broke"},
+			expected: &structs.TaskTerminationReason{
+				Reason:    structs.TaskTerminationDriverFailure,
+				Subsystem: structs.TaskTerminationSubsystemDriver,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.BatchAlloc()
+			rp := &structs.RestartPolicy{Attempts: 0, Mode: structs.RestartPolicyModeFail}
+			alloc.Job.TaskGroups[0].RestartPolicy = rp
+			task := alloc.Job.TaskGroups[0].Tasks[0]
+			task.RestartPolicy = rp
+			task.Driver = "mock_driver"
+			task.Config = tc.config
+
+			conf, cleanup := testTaskRunnerConfig(t, alloc, task.Name)
+			defer cleanup()
+
+			tr, err := NewTaskRunner(conf)
+			require.NoError(t, err)
+			defer tr.Kill(context.Background(), structs.NewTaskEvent("cleanup"))
+			tr.Run()
+
+			state := tr.TaskState()
+			require.Equal(t, structs.TaskStateDead, state.State)
+			require.Equal(t, tc.expected, state.TerminationReason)
+		})
+	}
+}
+
 // TestTaskRunner_UnregisterConsul_Retries asserts a task is unregistered from
 // Consul when waiting to be retried.
 func TestTaskRunner_UnregisterConsul_Retries(t *testing.T) {
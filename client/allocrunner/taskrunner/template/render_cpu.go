@@ -0,0 +1,500 @@
+package template
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	ctconf "github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/manager"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// renderProcessCommand is the hidden command of the nomad binary that runs a
+// consul-template runner for one of a task's templates in a process of its
+// own, so that a render using more than the client's max_render_cpu_time can
+// be stopped.
+const renderProcessCommand = "template-render"
+
+// renderRunner runs the consul-template runner of a task's templates, either
+// in the client or in a render process.
+type renderRunner interface {
+	Start()
+	Stop()
+	Errors() <-chan error
+	TemplateRenderedCh() <-chan struct{}
+	RenderEventCh() <-chan struct{}
+	RenderEvents() map[string]*manager.RenderEvent
+}
+
+// inProcessRunner runs the consul-template runner in the client.
+type inProcessRunner struct {
+	*manager.Runner
+}
+
+func (r *inProcessRunner) Errors() <-chan error {
+	return r.ErrCh
+}
+
+// renderProcessInput is the runner a render process runs, which it reads as
+// JSON from its stdin. The runner's config holds the task's Consul and Vault
+// tokens, which is why it isn't passed as arguments or in the environment.
+type renderProcessInput struct {
+	Config           *ctconf.Config
+	Env              map[string]string
+	MaxRenderCPUTime time.Duration
+}
+
+// renderProcessMessage is a line of JSON a render process writes to its
+// stdout.
+type renderProcessMessage struct {
+	// Rendered is set when the runner rendered a template, along with the
+	// runner's render events
+	Rendered bool
+	Events   map[string]*renderProcessEvent
+
+	// Error is the error that stopped the runner
+	Error string
+
+	// OverCPUTime is set when the process used more than the client's
+	// max_render_cpu_time to render its template, which stopped the runner
+	OverCPUTime bool
+}
+
+// renderProcessEvent is the part of a consul-template render event the
+// client uses.
+type renderProcessEvent struct {
+	MissingDeps     []string
+	Contents        []byte
+	WouldRender     bool
+	LastWouldRender time.Time
+	DidRender       bool
+	LastDidRender   time.Time
+	UpdatedAt       time.Time
+	ForQuiescence   bool
+}
+
+// renderProcess runs a consul-template runner for each of a task's templates
+// in a process of its own, which writes the rendered template and sends the
+// runner's render events back to the client. A process stops as soon as
+// rendering its template uses more than the client's max_render_cpu_time, as
+// consul-template renders can't otherwise be interrupted. Running a process
+// per template is what lets the client name the template that went over.
+type renderProcess struct {
+	inputs  map[string]*renderProcessInput
+	budget  time.Duration
+	configs map[string][]*ctconf.TemplateConfig
+	lookup  map[string][]*structs.Template
+
+	errCh         chan error
+	renderedCh    chan struct{}
+	renderEventCh chan struct{}
+
+	events     map[string]*manager.RenderEvent
+	eventsLock sync.RWMutex
+
+	cmds    []*exec.Cmd
+	stopped bool
+	cmdLock sync.Mutex
+}
+
+// newRenderProcess returns a render process running the templates of the
+// given runner config. The runner is the client's runner for the same config,
+// which isn't started and only maps the IDs of the templates to their
+// configs.
+func newRenderProcess(conf *ctconf.Config, runner *manager.Runner,
+	lookup map[string][]*structs.Template, budget time.Duration) *renderProcess {
+
+	configs := runner.TemplateConfigMapping()
+	ids := make(map[*ctconf.TemplateConfig]string)
+	for id, ctmpls := range configs {
+		for _, ctmpl := range ctmpls {
+			ids[ctmpl] = id
+		}
+	}
+
+	// Each process runs the templates of the config with the same ID
+	inputs := make(map[string]*renderProcessInput, len(configs))
+	for _, ctmpl := range *conf.Templates {
+		id, ok := ids[ctmpl]
+		if !ok {
+			continue
+		}
+		input, ok := inputs[id]
+		if !ok {
+			input = &renderProcessInput{
+				Config:           renderProcessConfig(conf),
+				Env:              runner.Env,
+				MaxRenderCPUTime: budget,
+			}
+			inputs[id] = input
+		}
+		*input.Config.Templates = append(*input.Config.Templates,
+			renderProcessTemplateConfig(ctmpl))
+	}
+
+	return &renderProcess{
+		inputs:        inputs,
+		budget:        budget,
+		configs:       configs,
+		lookup:        lookup,
+		errCh:         make(chan error, 1),
+		renderedCh:    make(chan struct{}, 1),
+		renderEventCh: make(chan struct{}, 1),
+		events:        make(map[string]*manager.RenderEvent),
+	}
+}
+
+// renderProcessConfig returns a copy of a runner config without its
+// templates, for a render process.
+func renderProcessConfig(conf *ctconf.Config) *ctconf.Config {
+	conf = conf.Copy()
+	conf.Templates = &ctconf.TemplateConfigs{}
+
+	// The signals of the config can't be decoded from JSON and aren't used,
+	// as the runner doesn't run commands, so the process uses the defaults
+	conf.KillSignal = nil
+	conf.ReloadSignal = nil
+	conf.Exec.KillSignal = nil
+	conf.Exec.ReloadSignal = nil
+	return conf
+}
+
+// renderProcessTemplateConfig returns a copy of a template config for a
+// render process.
+func renderProcessTemplateConfig(ctmpl *ctconf.TemplateConfig) *ctconf.TemplateConfig {
+	ctmpl = ctmpl.Copy()
+	ctmpl.Exec.KillSignal = nil
+	ctmpl.Exec.ReloadSignal = nil
+	return ctmpl
+}
+
+func (p *renderProcess) Errors() <-chan error {
+	return p.errCh
+}
+
+func (p *renderProcess) TemplateRenderedCh() <-chan struct{} {
+	return p.renderedCh
+}
+
+func (p *renderProcess) RenderEventCh() <-chan struct{} {
+	return p.renderEventCh
+}
+
+func (p *renderProcess) RenderEvents() map[string]*manager.RenderEvent {
+	p.eventsLock.RLock()
+	defer p.eventsLock.RUnlock()
+
+	events := make(map[string]*manager.RenderEvent, len(p.events))
+	for id, event := range p.events {
+		events[id] = event
+	}
+	return events
+}
+
+// Start runs the render processes until they exit or are stopped. Errors are
+// reported on the error channel, as with the consul-template runner.
+func (p *renderProcess) Start() {
+	var wg sync.WaitGroup
+	for id, input := range p.inputs {
+		wg.Add(1)
+		go func(id string, input *renderProcessInput) {
+			defer wg.Done()
+			p.run(id, input)
+		}(id, input)
+	}
+	wg.Wait()
+}
+
+// run runs the render process of the template with the given ID until it
+// exits or is stopped.
+func (p *renderProcess) run(id string, input *renderProcessInput) {
+	buf, err := json.Marshal(input)
+	if err != nil {
+		p.sendErr(fmt.Errorf("failed to encode template render process input: %v", err))
+		return
+	}
+
+	bin, err := os.Executable()
+	if err != nil {
+		p.sendErr(fmt.Errorf("failed to start template render process: %v", err))
+		return
+	}
+
+	// The process stops when its stdin is closed, so it doesn't outlive the
+	// client
+	stderr := &stderrTail{}
+	cmd := exec.Command(bin, renderProcessCommand)
+	cmd.Stderr = stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		p.sendErr(fmt.Errorf("failed to start template render process: %v", err))
+		return
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		p.sendErr(fmt.Errorf("failed to start template render process: %v", err))
+		return
+	}
+
+	p.cmdLock.Lock()
+	if p.stopped {
+		p.cmdLock.Unlock()
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		p.cmdLock.Unlock()
+		p.sendErr(fmt.Errorf("failed to start template render process: %v", err))
+		return
+	}
+	p.cmds = append(p.cmds, cmd)
+	p.cmdLock.Unlock()
+
+	reported := false
+	if _, err := stdin.Write(buf); err != nil {
+		p.sendErr(fmt.Errorf("failed to write template render process input: %v", err))
+		reported = true
+		_ = cmd.Process.Kill()
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var msg renderProcessMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if err := p.handle(id, &msg); err != nil {
+			p.sendErr(err)
+			reported = true
+		}
+	}
+	_, _ = io.Copy(io.Discard, stdout)
+	err = cmd.Wait()
+
+	p.cmdLock.Lock()
+	stopped := p.stopped
+	p.cmdLock.Unlock()
+	if !stopped && !reported {
+		p.sendErr(fmt.Errorf("template render process exited: %v: %s",
+			err, strings.TrimSpace(stderr.String())))
+	}
+}
+
+// handle handles a message of the render process of the template with the
+// given ID, returning the error that stopped its runner, if any.
+func (p *renderProcess) handle(id string, msg *renderProcessMessage) error {
+	if msg.OverCPUTime {
+		dest := ""
+		if tmpls := p.lookup[id]; len(tmpls) != 0 {
+			dest = tmpls[0].DestPath
+		}
+		return fmt.Errorf("template %q used more than the client's limit of %v of CPU time to render (max_render_cpu_time)",
+			dest, p.budget)
+	}
+	if msg.Error != "" {
+		return fmt.Errorf("%s", msg.Error)
+	}
+	if msg.Events == nil {
+		return nil
+	}
+
+	p.eventsLock.Lock()
+	for eid, e := range msg.Events {
+		missing := new(dep.Set)
+		for _, d := range e.MissingDeps {
+			missing.Add(missingDep(d))
+		}
+		p.events[eid] = &manager.RenderEvent{
+			MissingDeps:     missing,
+			TemplateConfigs: p.configs[eid],
+			Contents:        e.Contents,
+			WouldRender:     e.WouldRender,
+			LastWouldRender: e.LastWouldRender,
+			DidRender:       e.DidRender,
+			LastDidRender:   e.LastDidRender,
+			UpdatedAt:       e.UpdatedAt,
+			ForQuiescence:   e.ForQuiescence,
+		}
+	}
+	p.eventsLock.Unlock()
+
+	if msg.Rendered {
+		select {
+		case p.renderedCh <- struct{}{}:
+		default:
+		}
+	}
+	select {
+	case p.renderEventCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (p *renderProcess) sendErr(err error) {
+	select {
+	case p.errCh <- err:
+	default:
+	}
+}
+
+// Stop kills the render processes.
+func (p *renderProcess) Stop() {
+	p.cmdLock.Lock()
+	defer p.cmdLock.Unlock()
+
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	for _, cmd := range p.cmds {
+		_ = cmd.Process.Kill()
+	}
+}
+
+// renderStderrTailSize is how much of the end of a render process's stderr
+// is kept to report why the process exited.
+const renderStderrTailSize = 4096
+
+// stderrTail keeps the end of a render process's stderr, where the
+// consul-template runner logs for as long as the process runs.
+type stderrTail struct {
+	buf  []byte
+	lock sync.Mutex
+}
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - renderStderrTailSize; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (t *stderrTail) String() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return string(t.buf)
+}
+
+// missingDep is a dependency the render process is missing data for, of
+// which the client only knows the name.
+type missingDep string
+
+func (d missingDep) Fetch(*dep.ClientSet, *dep.QueryOptions) (interface{}, *dep.ResponseMetadata, error) {
+	return nil, nil, fmt.Errorf("%s is fetched by the template render process", string(d))
+}
+
+func (d missingDep) CanShare() bool { return false }
+func (d missingDep) String() string { return string(d) }
+func (d missingDep) Stop()          {}
+func (d missingDep) Type() dep.Type { return dep.TypeLocal }
+
+// RunRenderProcess runs the consul-template runner read from r as a render
+// process, writing its render events to w, until r is closed or rendering
+// its template uses more than the client's max_render_cpu_time.
+//
+// The CPU time of the process is bounded by its RLIMIT_CPU, which is
+// extended by max_render_cpu_time each time the runner sends a render event,
+// so that the limit applies to each render and not to the lifetime of the
+// process. Going over the limit makes the kernel send the process SIGXCPU.
+// RLIMIT_CPU counts all the CPU time of the process in whole seconds, so the
+// time spent fetching the template's data and encoding events since the last
+// event counts towards the render too.
+func RunRenderProcess(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	var input renderProcessInput
+	if err := dec.Decode(&input); err != nil {
+		return err
+	}
+	input.Config.Finalize()
+
+	overCh := make(chan os.Signal, 1)
+	if err := notifyRenderCPULimit(overCh); err != nil {
+		return err
+	}
+	if err := extendRenderCPULimit(input.MaxRenderCPUTime); err != nil {
+		return err
+	}
+
+	runner, err := manager.NewRunner(input.Config, false)
+	if err != nil {
+		return err
+	}
+	runner.Env = input.Env
+	go runner.Start()
+
+	closedCh := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, dec.Buffered())
+		_, _ = io.Copy(io.Discard, r)
+		close(closedCh)
+	}()
+
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-closedCh:
+			runner.Stop()
+			return nil
+		case err := <-runner.ErrCh:
+			return enc.Encode(&renderProcessMessage{Error: err.Error()})
+		case <-overCh:
+			return enc.Encode(&renderProcessMessage{OverCPUTime: true})
+		case <-runner.TemplateRenderedCh():
+			if err := extendRenderCPULimit(input.MaxRenderCPUTime); err != nil {
+				return err
+			}
+			if err := enc.Encode(&renderProcessMessage{
+				Rendered: true,
+				Events:   renderProcessEvents(runner.RenderEvents()),
+			}); err != nil {
+				return err
+			}
+		case <-runner.RenderEventCh():
+			if err := extendRenderCPULimit(input.MaxRenderCPUTime); err != nil {
+				return err
+			}
+			if err := enc.Encode(&renderProcessMessage{
+				Events: renderProcessEvents(runner.RenderEvents()),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// renderProcessEvents returns the render events of a runner to send to the
+// client.
+func renderProcessEvents(events map[string]*manager.RenderEvent) map[string]*renderProcessEvent {
+	result := make(map[string]*renderProcessEvent, len(events))
+	for id, event := range events {
+		e := &renderProcessEvent{
+			Contents:        event.Contents,
+			WouldRender:     event.WouldRender,
+			LastWouldRender: event.LastWouldRender,
+			DidRender:       event.DidRender,
+			LastDidRender:   event.LastDidRender,
+			UpdatedAt:       event.UpdatedAt,
+			ForQuiescence:   event.ForQuiescence,
+		}
+		if event.MissingDeps != nil {
+			for _, d := range event.MissingDeps.List() {
+				e.MissingDeps = append(e.MissingDeps, d.String())
+			}
+		}
+		result[id] = e
+	}
+	return result
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package template
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// renderCPULimitSupported is whether the CPU time of a render process can be
+// limited on this platform.
+const renderCPULimitSupported = false
+
+func notifyRenderCPULimit(ch chan<- os.Signal) error {
+	return fmt.Errorf("limiting the CPU time of template renders is only supported on Linux")
+}
+
+func extendRenderCPULimit(budget time.Duration) error {
+	return fmt.Errorf("limiting the CPU time of template renders is only supported on Linux")
+}
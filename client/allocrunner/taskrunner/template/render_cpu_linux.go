@@ -0,0 +1,43 @@
+//go:build linux
+// +build linux
+
+package template
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// renderCPULimitSupported is whether the CPU time of a render process can be
+// limited on this platform.
+const renderCPULimitSupported = true
+
+// notifyRenderCPULimit relays the SIGXCPU the kernel sends the process once
+// it goes over its RLIMIT_CPU to ch.
+func notifyRenderCPULimit(ch chan<- os.Signal) error {
+	signal.Notify(ch, syscall.SIGXCPU)
+	return nil
+}
+
+// extendRenderCPULimit sets the soft RLIMIT_CPU of the process to the CPU
+// time it has used so far plus budget. The limit is in whole seconds, so the
+// budget is rounded up to the next second.
+func extendRenderCPULimit(budget time.Duration) error {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return err
+	}
+	used := time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CPU, &limit); err != nil {
+		return err
+	}
+	limit.Cur = uint64((used + budget + time.Second - 1) / time.Second)
+	if limit.Cur > limit.Max {
+		limit.Cur = limit.Max
+	}
+	return syscall.Setrlimit(syscall.RLIMIT_CPU, &limit)
+}
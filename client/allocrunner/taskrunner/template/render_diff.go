@@ -0,0 +1,80 @@
+package template
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// renderDiffer logs how the rendered contents of templates change between
+// renders. It is only used from the template manager's run loop and is not
+// safe for concurrent use.
+type renderDiffer struct {
+	logger log.Logger
+
+	// mode is either config.TemplateRenderDiffsHash or
+	// config.TemplateRenderDiffsFull
+	mode string
+
+	// contents holds the last observed contents by template ID
+	contents map[string][]byte
+}
+
+// newRenderDiffer returns a renderDiffer for the given mode, or nil if render
+// diffs are disabled.
+func newRenderDiffer(logger log.Logger, mode string) *renderDiffer {
+	switch mode {
+	case config.TemplateRenderDiffsHash, config.TemplateRenderDiffsFull:
+	default:
+		return nil
+	}
+
+	return &renderDiffer{
+		logger:   logger.Named("render_diff"),
+		mode:     mode,
+		contents: make(map[string][]byte),
+	}
+}
+
+// observe records the rendered contents of a template and logs how they
+// changed if they differ from the previously observed contents. The first
+// observation of a template is not logged.
+func (d *renderDiffer) observe(id, dest string, contents []byte) {
+	if d == nil || contents == nil {
+		return
+	}
+
+	prev, ok := d.contents[id]
+	d.contents[id] = contents
+	if !ok || bytes.Equal(prev, contents) {
+		return
+	}
+
+	switch d.mode {
+	case config.TemplateRenderDiffsHash:
+		d.logger.Debug("template contents changed", "destination", dest,
+			"previous_sha256", hashContents(prev), "sha256", hashContents(contents))
+	case config.TemplateRenderDiffsFull:
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(prev)),
+			B:        difflib.SplitLines(string(contents)),
+			FromFile: "previous",
+			ToFile:   "rendered",
+			Context:  1,
+		})
+		if err != nil {
+			d.logger.Debug("failed to diff template contents", "destination", dest, "error", err)
+			return
+		}
+		d.logger.Debug("template contents changed", "destination", dest, "diff", diff)
+	}
+}
+
+func hashContents(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
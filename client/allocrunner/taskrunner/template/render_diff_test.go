@@ -0,0 +1,75 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testRenderDiffer(t *testing.T, mode string) (*renderDiffer, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := log.New(&log.LoggerOptions{
+		Level:  log.Debug,
+		Output: &buf,
+	})
+	d := newRenderDiffer(logger, mode)
+	require.NotNil(t, d)
+	return d, &buf
+}
+
+func TestRenderDiffer_Disabled(t *testing.T) {
+	require.Nil(t, newRenderDiffer(log.NewNullLogger(), ""))
+	require.Nil(t, newRenderDiffer(log.NewNullLogger(), "unknown"))
+
+	// A nil differ ignores observations
+	var d *renderDiffer
+	d.observe("id", "local/file", []byte("contents"))
+}
+
+func TestRenderDiffer_Diff(t *testing.T) {
+	d, buf := testRenderDiffer(t, config.TemplateRenderDiffsFull)
+
+	// The first render is not logged
+	d.observe("id", "local/app.conf", []byte("port = 80\nhost = a\n"))
+	require.Empty(t, buf.String())
+
+	// An unchanged re-render is not logged
+	d.observe("id", "local/app.conf", []byte("port = 80\nhost = a\n"))
+	require.Empty(t, buf.String())
+
+	// A change is logged as a diff
+	d.observe("id", "local/app.conf", []byte("port = 80\nhost = b\n"))
+	out := buf.String()
+	require.Contains(t, out, "template contents changed")
+	require.Contains(t, out, "local/app.conf")
+	require.Contains(t, out, "-host = a")
+	require.Contains(t, out, "+host = b")
+	require.NotContains(t, out, "-port = 80")
+}
+
+func TestRenderDiffer_Hash(t *testing.T) {
+	d, buf := testRenderDiffer(t, config.TemplateRenderDiffsHash)
+
+	d.observe("id", "secrets/token", []byte("secret-a"))
+	d.observe("id", "secrets/token", []byte("secret-a"))
+	require.Empty(t, buf.String())
+
+	d.observe("id", "secrets/token", []byte("secret-b"))
+	out := buf.String()
+	require.Contains(t, out, "template contents changed")
+	require.Contains(t, out, hashContents([]byte("secret-a")))
+	require.Contains(t, out, hashContents([]byte("secret-b")))
+	require.NotContains(t, out, "secret-b")
+}
+
+func TestRenderDiffer_PerTemplate(t *testing.T) {
+	d, buf := testRenderDiffer(t, config.TemplateRenderDiffsHash)
+
+	// Templates are tracked independently
+	d.observe("a", "local/a", []byte("one"))
+	d.observe("b", "local/b", []byte("two"))
+	require.Empty(t, buf.String())
+}
@@ -11,11 +11,13 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	ctconf "github.com/hashicorp/consul-template/config"
 	"github.com/hashicorp/consul-template/manager"
 	"github.com/hashicorp/consul-template/signals"
 	envparse "github.com/hashicorp/go-envparse"
+	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/interfaces"
 	"github.com/hashicorp/nomad/client/config"
@@ -41,6 +43,10 @@ const (
 var (
 	sourceEscapesErr = errors.New("template source path escapes alloc directory")
 	destEscapesErr   = errors.New("template destination path escapes alloc directory")
+
+	// executableRenderErr is returned when a template sets executable
+	// permissions without the client allowing it
+	executableRenderErr = errors.New("template permissions make the rendered file executable, which requires allow_executable_renders in the client template config")
 )
 
 // TaskTemplateManager is used to run a set of templates for a given task
@@ -51,13 +57,20 @@ type TaskTemplateManager struct {
 	// lookup allows looking up the set of Nomad templates by their consul-template ID
 	lookup map[string][]*structs.Template
 
-	// runner is the consul-template runner
-	runner *manager.Runner
+	// runner runs the consul-template runner, in the client or in a render
+	// process
+	runner renderRunner
 
 	// signals is a lookup map from the string representation of a signal to its
 	// actual signal
 	signals map[string]os.Signal
 
+	// renderDiffer logs changes to rendered templates. It is nil unless
+	// enabled in the client's template config.
+	renderDiffer *renderDiffer
+
+	logger log.Logger
+
 	// shutdownCh is used to signal and started goroutine to shutdown
 	shutdownCh chan struct{}
 
@@ -76,6 +89,11 @@ type TaskTemplateManagerConfig struct {
 	// run for
 	Lifecycle interfaces.TaskLifecycle
 
+	// RestartSequencer is used to restart the task in lifecycle order with
+	// the other tasks in the group. It may be nil, in which case templates
+	// with restart_order "lifecycle" restart the task directly.
+	RestartSequencer interfaces.TaskRestartSequencer
+
 	// Events is used to emit events for the task
 	Events interfaces.EventEmitter
 
@@ -85,6 +103,11 @@ type TaskTemplateManagerConfig struct {
 	// ClientConfig is the Nomad Client configuration
 	ClientConfig *config.Config
 
+	// Retry is the job's override of the client's template retry configs
+	// for the task, set by the retry blocks of its templates. It is merged
+	// over the client's configs, which clamp it. It may be nil.
+	Retry *config.RetryConfig
+
 	// ConsulNamespace is the Consul namespace for the task
 	ConsulNamespace string
 
@@ -102,6 +125,9 @@ type TaskTemplateManagerConfig struct {
 
 	// MaxTemplateEventRate is the maximum rate at which we should emit events.
 	MaxTemplateEventRate time.Duration
+
+	// Logger is used to log changes to rendered templates. It may be nil.
+	Logger log.Logger
 }
 
 // Validate validates the configuration.
@@ -127,17 +153,48 @@ func (c *TaskTemplateManagerConfig) Validate() error {
 	return nil
 }
 
+// vaultNamespace returns the Vault namespace the task's templates read
+// secrets from.
+func (c *TaskTemplateManagerConfig) vaultNamespace() string {
+	if c.VaultNamespace != "" {
+		return c.VaultNamespace
+	}
+	if c.ClientConfig.VaultConfig != nil {
+		return c.ClientConfig.VaultConfig.Namespace
+	}
+	return ""
+}
+
+// templateConfig returns the client's template config with the job's retry
+// override merged over it.
+func (c *TaskTemplateManagerConfig) templateConfig() *config.ClientTemplateConfig {
+	if c.Retry == nil {
+		return c.ClientConfig.TemplateConfig
+	}
+	return c.ClientConfig.TemplateConfig.WithJobRetry(c.Retry)
+}
+
 func NewTaskTemplateManager(config *TaskTemplateManagerConfig) (*TaskTemplateManager, error) {
 	// Check pre-conditions
 	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 
+	logger := config.Logger
+	if logger == nil {
+		logger = log.NewNullLogger()
+	}
+
 	tm := &TaskTemplateManager{
 		config:     config,
+		logger:     logger,
 		shutdownCh: make(chan struct{}),
 	}
 
+	if tc := config.ClientConfig.TemplateConfig; tc != nil && tc.RenderDiffs != "" {
+		tm.renderDiffer = newRenderDiffer(logger, tc.RenderDiffs)
+	}
+
 	// Parse the signals that we need
 	for _, tmpl := range config.Templates {
 		if tmpl.ChangeSignal == "" {
@@ -223,7 +280,8 @@ func (tm *TaskTemplateManager) run() {
 	close(tm.config.UnblockCh)
 
 	// If all our templates are change mode no-op, then we can exit here
-	if tm.allTemplatesNoop() {
+	// unless changes to them are being logged
+	if tm.allTemplatesNoop() && tm.renderDiffer == nil {
 		return
 	}
 
@@ -253,7 +311,7 @@ WAIT:
 		select {
 		case <-tm.shutdownCh:
 			return
-		case err, ok := <-tm.runner.ErrCh:
+		case err, ok := <-tm.runner.Errors():
 			if !ok {
 				continue
 			}
@@ -266,6 +324,16 @@ WAIT:
 			// A template has been rendered, figure out what to do
 			events := tm.runner.RenderEvents()
 
+			// Fail the task rather than run it with a template too large or
+			// not valid UTF-8
+			if err := tm.checkRenders(events); err != nil {
+				tm.config.Lifecycle.Kill(context.Background(),
+					structs.NewTaskEvent(structs.TaskKilling).
+						SetFailsTask().
+						SetDisplayMessage(fmt.Sprintf("Template failed: %v", err)))
+				continue
+			}
+
 			// Not all templates have been rendered yet
 			if len(events) < len(tm.lookup) {
 				continue
@@ -367,7 +435,7 @@ func (tm *TaskTemplateManager) handleTemplateRerenders(allRenderedTime time.Time
 		select {
 		case <-tm.shutdownCh:
 			return
-		case err, ok := <-tm.runner.ErrCh:
+		case err, ok := <-tm.runner.Errors():
 			if !ok {
 				continue
 			}
@@ -387,10 +455,20 @@ func (tm *TaskTemplateManager) onTemplateRendered(handledRenders map[string]time
 	var handling []string
 	signals := make(map[string]struct{})
 	restart := false
+	sequenced := false
 	var splay time.Duration
 
 	events := tm.runner.RenderEvents()
+	if err := tm.checkRenders(events); err != nil {
+		tm.config.Lifecycle.Kill(context.Background(),
+			structs.NewTaskEvent(structs.TaskKilling).
+				SetFailsTask().
+				SetDisplayMessage(fmt.Sprintf("Template failed: %v", err)))
+		return
+	}
+
 	for id, event := range events {
+		tm.renderDiffer.observe(id, tm.destination(id), event.Contents)
 
 		// First time through
 		if allRenderedTime.After(event.LastDidRender) || allRenderedTime.Equal(event.LastDidRender) {
@@ -430,6 +508,9 @@ func (tm *TaskTemplateManager) onTemplateRendered(handledRenders map[string]time
 				signals[tmpl.ChangeSignal] = struct{}{}
 			case structs.TemplateChangeModeRestart:
 				restart = true
+				if tmpl.RestartOrder == structs.TemplateRestartOrderLifecycle {
+					sequenced = true
+				}
 			case structs.TemplateChangeModeNoop:
 				continue
 			}
@@ -461,9 +542,13 @@ func (tm *TaskTemplateManager) onTemplateRendered(handledRenders map[string]time
 		}
 
 		if restart {
-			tm.config.Lifecycle.Restart(context.Background(),
-				structs.NewTaskEvent(structs.TaskRestartSignal).
-					SetDisplayMessage("Template with change_mode restart re-rendered"), false)
+			event := structs.NewTaskEvent(structs.TaskRestartSignal).
+				SetDisplayMessage("Template with change_mode restart re-rendered")
+			if sequenced && tm.config.RestartSequencer != nil {
+				tm.config.RestartSequencer.RequestRestart(event)
+			} else {
+				tm.config.Lifecycle.Restart(context.Background(), event, false)
+			}
 		} else if len(signals) != 0 {
 			var mErr multierror.Error
 			for signal := range signals {
@@ -490,6 +575,106 @@ func (tm *TaskTemplateManager) onTemplateRendered(handledRenders map[string]time
 
 }
 
+// checkRenders returns an error if a template failed one of the client's
+// checks of rendered templates.
+func (tm *TaskTemplateManager) checkRenders(events map[string]*manager.RenderEvent) error {
+	if err := tm.checkRenderSizes(events); err != nil {
+		return err
+	}
+	return tm.checkRenderedUTF8(events)
+}
+
+// checkRenderSizes returns an error if a template rendered more than the
+// client's max_render_size. consul-template writes templates as it renders
+// them, so the files of the templates that are too large are removed.
+func (tm *TaskTemplateManager) checkRenderSizes(events map[string]*manager.RenderEvent) error {
+	max := tm.config.templateConfig().MaxRenderSizeBytes
+	if max == nil || *max <= 0 {
+		return nil
+	}
+
+	var mErr multierror.Error
+	for id, event := range events {
+		size := int64(len(event.Contents))
+		if size <= *max {
+			continue
+		}
+
+		tm.removeRendered(event, "failed to remove template over the render size limit")
+		_ = multierror.Append(&mErr, fmt.Errorf(
+			"template %q rendered %d bytes, more than the client's limit of %d bytes (max_render_size)",
+			tm.destination(id), size, *max))
+	}
+
+	if len(mErr.Errors) == 1 {
+		return mErr.Errors[0]
+	}
+	return mErr.ErrorOrNil()
+}
+
+// checkRenderedUTF8 returns an error if the client validates rendered
+// templates as UTF-8 and a template rendered invalid UTF-8. The error names
+// the byte offset of the first invalid byte, and the offending templates are
+// removed.
+func (tm *TaskTemplateManager) checkRenderedUTF8(events map[string]*manager.RenderEvent) error {
+	if !tm.config.templateConfig().ValidateRenderedUTF8 {
+		return nil
+	}
+
+	var mErr multierror.Error
+	for id, event := range events {
+		offset := invalidUTF8Offset(event.Contents)
+		if offset < 0 {
+			continue
+		}
+
+		tm.removeRendered(event, "failed to remove template with invalid UTF-8")
+		_ = multierror.Append(&mErr, fmt.Errorf(
+			"template %q rendered invalid UTF-8 at byte offset %d (validate_rendered_utf8)",
+			tm.destination(id), offset))
+	}
+
+	if len(mErr.Errors) == 1 {
+		return mErr.Errors[0]
+	}
+	return mErr.ErrorOrNil()
+}
+
+// invalidUTF8Offset returns the offset of the first byte of b that isn't
+// valid UTF-8, or -1 if b is valid UTF-8.
+func invalidUTF8Offset(b []byte) int {
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+// removeRendered removes the files a render event's templates were rendered
+// to, logging msg if one can't be removed.
+func (tm *TaskTemplateManager) removeRendered(event *manager.RenderEvent, msg string) {
+	for _, ct := range event.TemplateConfigs {
+		if ct.Destination == nil {
+			continue
+		}
+		if err := os.Remove(*ct.Destination); err != nil && !os.IsNotExist(err) {
+			tm.logger.Warn(msg, "destination", *ct.Destination, "error", err)
+		}
+	}
+}
+
+// destination returns the destination path of the template with the given
+// consul-template ID, for logging.
+func (tm *TaskTemplateManager) destination(id string) string {
+	if tmpls := tm.lookup[id]; len(tmpls) != 0 {
+		return tmpls[0].DestPath
+	}
+	return ""
+}
+
 // allTemplatesNoop returns whether all the managed templates have change mode noop.
 func (tm *TaskTemplateManager) allTemplatesNoop() bool {
 	for _, tmpl := range tm.config.Templates {
@@ -502,10 +687,11 @@ func (tm *TaskTemplateManager) allTemplatesNoop() bool {
 }
 
 // templateRunner returns a consul-template runner for the given templates and a
-// lookup by destination to the template. If no templates are in the config, a
-// nil template runner and lookup is returned.
+// lookup by destination to the template. The runner is run in a render process
+// if the client limits the CPU time of renders. If no templates are in the
+// config, a nil template runner and lookup is returned.
 func templateRunner(config *TaskTemplateManagerConfig) (
-	*manager.Runner, map[string][]*structs.Template, error) {
+	renderRunner, map[string][]*structs.Template, error) {
 
 	if len(config.Templates) == 0 {
 		return nil, nil, nil
@@ -546,7 +732,17 @@ func templateRunner(config *TaskTemplateManagerConfig) (
 		}
 	}
 
-	return runner, lookup, nil
+	// Render in a process of its own if renders are limited, so that a
+	// render going over the limit can be stopped
+	if budget := config.templateConfig().MaxRenderCPUTime; budget != nil && *budget > 0 {
+		if renderCPULimitSupported {
+			return newRenderProcess(runnerConfig, runner, lookup, *budget), lookup, nil
+		}
+		if config.Logger != nil {
+			config.Logger.Warn("max_render_cpu_time is only supported on Linux, rendering templates without a CPU time limit")
+		}
+	}
+	return &inProcessRunner{runner}, lookup, nil
 }
 
 // maskProcessEnv masks away any environment variable not found in task env.
@@ -566,9 +762,15 @@ func maskProcessEnv(env map[string]string) map[string]string {
 // parseTemplateConfigs converts the tasks templates in the config into
 // consul-templates
 func parseTemplateConfigs(config *TaskTemplateManagerConfig) (map[*ctconf.TemplateConfig]*structs.Template, error) {
-	sandboxEnabled := !config.ClientConfig.TemplateConfig.DisableSandbox
+	templateConfig := config.templateConfig()
+	sandboxEnabled := !templateConfig.DisableSandbox
 	taskEnv := config.EnvBuilder.Build()
 
+	if max := templateConfig.MaxTemplatesPerTask; max != nil && *max > 0 && len(config.Templates) > *max {
+		return nil, fmt.Errorf("template %q exceeds the client's limit of %d templates per task (max_templates_per_task)",
+			config.Templates[*max].DestPath, *max)
+	}
+
 	ctmpls := make(map[*ctconf.TemplateConfig]*structs.Template, len(config.Templates))
 	for _, tmpl := range config.Templates {
 		var src, dest string
@@ -588,27 +790,19 @@ func parseTemplateConfigs(config *TaskTemplateManagerConfig) (map[*ctconf.Templa
 			}
 		}
 
-		ct := ctconf.DefaultTemplateConfig()
+		ct := templateConfig.ToConsulTemplateTemplateConfig(config.TaskDir)
 		ct.Source = &src
 		ct.Destination = &dest
 		ct.Contents = &tmpl.EmbeddedTmpl
 		ct.LeftDelim = &tmpl.LeftDelim
 		ct.RightDelim = &tmpl.RightDelim
-		ct.FunctionDenylist = config.ClientConfig.TemplateConfig.FunctionDenylist
-		if sandboxEnabled {
-			ct.SandboxPath = &config.TaskDir
-		}
-
-		if tmpl.Wait != nil {
-			if err := tmpl.Wait.Validate(); err != nil {
-				return nil, err
-			}
 
-			ct.Wait = &ctconf.WaitConfig{
-				Enabled: helper.BoolToPtr(true),
-				Min:     tmpl.Wait.Min,
-				Max:     tmpl.Wait.Max,
-			}
+		wait, err := templateConfig.TemplateWaitConfig(tmpl.Wait)
+		if err != nil {
+			return nil, err
+		}
+		if wait != nil {
+			ct.Wait = wait
 		}
 
 		// Set the permissions
@@ -618,6 +812,9 @@ func parseTemplateConfigs(config *TaskTemplateManagerConfig) (map[*ctconf.Templa
 				return nil, fmt.Errorf("Failed to parse %q as octal: %v", tmpl.Perms, err)
 			}
 			m := os.FileMode(v)
+			if m&0111 != 0 && !templateConfig.AllowExecutableRenders {
+				return nil, executableRenderErr
+			}
 			ct.Perms = &m
 		}
 		ct.Finalize()
@@ -634,69 +831,32 @@ func newRunnerConfig(config *TaskTemplateManagerConfig,
 	templateMapping map[*ctconf.TemplateConfig]*structs.Template) (*ctconf.Config, error) {
 
 	cc := config.ClientConfig
-	conf := ctconf.DefaultConfig()
+	conf, err := config.templateConfig().ToConsulTemplateConfig(config.vaultNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	// Gather the consul-template templates. The runner renders them in order,
+	// so templates are rendered after the templates they depend on.
+	sorted, err := structs.SortTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[*structs.Template]int, len(sorted))
+	for i, tmpl := range sorted {
+		order[tmpl] = i
+	}
 
-	// Gather the consul-template templates
 	flat := ctconf.TemplateConfigs(make([]*ctconf.TemplateConfig, 0, len(templateMapping)))
 	for ctmpl := range templateMapping {
 		local := ctmpl
 		flat = append(flat, local)
 	}
+	sort.SliceStable(flat, func(i, j int) bool {
+		return order[templateMapping[flat[i]]] < order[templateMapping[flat[j]]]
+	})
 	conf.Templates = &flat
 
-	// Set the amount of time to do a blocking query for.
-	if cc.TemplateConfig.BlockQueryWaitTime != nil {
-		conf.BlockQueryWaitTime = cc.TemplateConfig.BlockQueryWaitTime
-	}
-
-	// Set the stale-read threshold to allow queries to be served by followers
-	// if the last replicated data is within this bound.
-	if cc.TemplateConfig.MaxStale != nil {
-		conf.MaxStale = cc.TemplateConfig.MaxStale
-	}
-
-	// Set the minimum and maximum amount of time to wait for the cluster to reach
-	// a consistent state before rendering a template.
-	if cc.TemplateConfig.Wait != nil {
-		// If somehow the WaitConfig wasn't set correctly upstream, return an error.
-		var err error
-		err = cc.TemplateConfig.Wait.Validate()
-		if err != nil {
-			return nil, err
-		}
-		conf.Wait, err = cc.TemplateConfig.Wait.ToConsulTemplate()
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Make sure any template specific configuration set by the job author is within
-	// the bounds set by the operator.
-	if cc.TemplateConfig.WaitBounds != nil {
-		// If somehow the WaitBounds weren't set correctly upstream, return an error.
-		err := cc.TemplateConfig.WaitBounds.Validate()
-		if err != nil {
-			return nil, err
-		}
-
-		// Check and override with bounds
-		for _, tmpl := range *conf.Templates {
-			if tmpl.Wait == nil || !*tmpl.Wait.Enabled {
-				continue
-			}
-			if cc.TemplateConfig.WaitBounds.Min != nil {
-				if tmpl.Wait.Min != nil && *tmpl.Wait.Min < *cc.TemplateConfig.WaitBounds.Min {
-					tmpl.Wait.Min = &*cc.TemplateConfig.WaitBounds.Min
-				}
-			}
-			if cc.TemplateConfig.WaitBounds.Max != nil {
-				if tmpl.Wait.Max != nil && *tmpl.Wait.Max > *cc.TemplateConfig.WaitBounds.Max {
-					tmpl.Wait.Max = &*cc.TemplateConfig.WaitBounds.Max
-				}
-			}
-		}
-	}
-
 	// Set up the Consul config
 	if cc.ConsulConfig != nil {
 		conf.Consul.Address = &cc.ConsulConfig.Addr
@@ -731,19 +891,12 @@ func newRunnerConfig(config *TaskTemplateManagerConfig,
 				Password: &parts[1],
 			}
 		}
+	}
 
-		// Set the user-specified Consul RetryConfig
-		if cc.TemplateConfig.ConsulRetry != nil {
-			var err error
-			err = cc.TemplateConfig.ConsulRetry.Validate()
-			if err != nil {
-				return nil, err
-			}
-			conf.Consul.Retry, err = cc.TemplateConfig.ConsulRetry.ToConsulTemplate()
-			if err != nil {
-				return nil, err
-			}
-		}
+	// Get the Consul namespace from the client's template config, overriding
+	// the agent config.
+	if namespace := cc.TemplateConfig.ConsulNamespace; namespace != "" {
+		conf.Consul.Namespace = &namespace
 	}
 
 	// Get the Consul namespace from job/group config. This is the higher level
@@ -763,11 +916,8 @@ func newRunnerConfig(config *TaskTemplateManagerConfig,
 
 		// Set the Vault Namespace. Passed in Task config has
 		// highest precedence.
-		if config.ClientConfig.VaultConfig.Namespace != "" {
-			conf.Vault.Namespace = &config.ClientConfig.VaultConfig.Namespace
-		}
-		if config.VaultNamespace != "" {
-			conf.Vault.Namespace = &config.VaultNamespace
+		if namespace := config.vaultNamespace(); namespace != "" {
+			conf.Vault.Namespace = &namespace
 		}
 
 		if strings.HasPrefix(cc.VaultConfig.Addr, "https") || cc.VaultConfig.TLSCertFile != "" {
@@ -793,18 +943,6 @@ func newRunnerConfig(config *TaskTemplateManagerConfig,
 				ServerName: &emptyStr,
 			}
 		}
-
-		// Set the user-specified Vault RetryConfig
-		if cc.TemplateConfig.VaultRetry != nil {
-			var err error
-			if err = cc.TemplateConfig.VaultRetry.Validate(); err != nil {
-				return nil, err
-			}
-			conf.Vault.Retry, err = cc.TemplateConfig.VaultRetry.ToConsulTemplate()
-			if err != nil {
-				return nil, err
-			}
-		}
 	}
 
 	conf.Finalize()
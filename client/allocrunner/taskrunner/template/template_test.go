@@ -33,6 +33,19 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	// The template manager runs the test binary as its render process, in
+	// place of the nomad binary's template-render command
+	if len(os.Args) > 1 && os.Args[1] == renderProcessCommand {
+		if err := RunRenderProcess(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
 const (
 	// TestTaskName is the name of the injected task. It should appear in the
 	// environment variable $NOMAD_TASK_NAME
@@ -509,6 +522,7 @@ func TestTaskTemplateManager_Permissions(t *testing.T) {
 	}
 
 	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.config.TemplateConfig.AllowExecutableRenders = true
 	harness.start(t)
 	defer harness.stop()
 
@@ -531,6 +545,302 @@ func TestTaskTemplateManager_Permissions(t *testing.T) {
 	}
 }
 
+func TestTaskTemplateManager_Permissions_Executable(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		Perms string
+		Allow bool
+		Err   error
+	}{
+		{Perms: "644", Allow: false},
+		{Perms: "755", Allow: false, Err: executableRenderErr},
+		{Perms: "610", Allow: false, Err: executableRenderErr},
+		{Perms: "755", Allow: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s/allow=%v", tc.Perms, tc.Allow), func(t *testing.T) {
+			template := &structs.Template{
+				EmbeddedTmpl: "#!/bin/sh",
+				DestPath:     "run.sh",
+				ChangeMode:   structs.TemplateChangeModeNoop,
+				Perms:        tc.Perms,
+			}
+
+			harness := newTestHarness(t, []*structs.Template{template}, false, false)
+			harness.config.TemplateConfig.AllowExecutableRenders = tc.Allow
+			err := harness.startWithErr()
+			defer harness.stop()
+			if tc.Err != nil {
+				require.Equal(t, tc.Err, err)
+				return
+			}
+			require.NoError(t, err)
+
+			select {
+			case <-harness.mockHooks.UnblockCh:
+			case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+				t.Fatalf("Task unblock should have been called")
+			}
+
+			fi, err := os.Stat(filepath.Join(harness.taskDir, "run.sh"))
+			require.NoError(t, err)
+			require.Equal(t, tc.Perms, fmt.Sprintf("%o", fi.Mode().Perm()))
+		})
+	}
+}
+
+func TestTaskTemplateManager_MaxTemplatesPerTask(t *testing.T) {
+	t.Parallel()
+
+	templates := []*structs.Template{}
+	for _, dest := range []string{"a.txt", "b.txt", "c.txt"} {
+		templates = append(templates, &structs.Template{
+			EmbeddedTmpl: "hello",
+			DestPath:     dest,
+			ChangeMode:   structs.TemplateChangeModeNoop,
+		})
+	}
+
+	harness := newTestHarness(t, templates, false, false)
+	harness.config.TemplateConfig.MaxTemplatesPerTask = helper.IntToPtr(2)
+	err := harness.startWithErr()
+	defer harness.stop()
+	require.EqualError(t, err,
+		`template "c.txt" exceeds the client's limit of 2 templates per task (max_templates_per_task)`)
+
+	// Zero is unlimited
+	harness = newTestHarness(t, templates, false, false)
+	harness.config.TemplateConfig.MaxTemplatesPerTask = helper.IntToPtr(0)
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+}
+
+func TestTaskTemplateManager_MaxRenderSize(t *testing.T) {
+	t.Parallel()
+
+	small := &structs.Template{
+		EmbeddedTmpl: "hello",
+		DestPath:     "small.txt",
+		ChangeMode:   structs.TemplateChangeModeNoop,
+	}
+	large := &structs.Template{
+		EmbeddedTmpl: strings.Repeat("a", 1024),
+		DestPath:     "large.txt",
+		ChangeMode:   structs.TemplateChangeModeNoop,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{small, large}, false, false)
+	harness.config.TemplateConfig.MaxRenderSizeBytes = helper.Int64ToPtr(512)
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.KillCh:
+	case <-harness.mockHooks.UnblockCh:
+		t.Fatalf("Task unblock should not have been called")
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task kill should have been called")
+	}
+
+	event := harness.mockHooks.KillEvent
+	require.True(t, event.FailsTask)
+	require.Equal(t,
+		`Template failed: template "large.txt" rendered 1024 bytes, more than the client's limit of 512 bytes (max_render_size)`,
+		event.DisplayMessage)
+
+	// The template too large is removed
+	require.FileExists(t, filepath.Join(harness.taskDir, "small.txt"))
+	require.NoFileExists(t, filepath.Join(harness.taskDir, "large.txt"))
+}
+
+func TestTaskTemplateManager_MaxRenderCPUTime(t *testing.T) {
+	if !renderCPULimitSupported {
+		t.Skip("limiting the CPU time of renders is only supported on Linux")
+	}
+	t.Parallel()
+
+	fast := &structs.Template{
+		EmbeddedTmpl: `{{ range loop 10 }}{{ sha256Hex "nomad" }}{{ end }}`,
+		DestPath:     "fast.txt",
+		ChangeMode:   structs.TemplateChangeModeNoop,
+	}
+	slow := &structs.Template{
+		EmbeddedTmpl: `{{ range loop 1000000000 }}{{ sha256Hex "nomad" }}{{ end }}`,
+		DestPath:     "slow.txt",
+		ChangeMode:   structs.TemplateChangeModeNoop,
+	}
+
+	// A template rendering within the limit renders
+	harness := newTestHarness(t, []*structs.Template{fast}, false, false)
+	harness.config.TemplateConfig.MaxRenderCPUTime = helper.TimeToPtr(time.Second)
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-harness.mockHooks.KillCh:
+		t.Fatalf("Task kill should not have been called: %v", harness.mockHooks.KillEvent.DisplayMessage)
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+	require.FileExists(t, filepath.Join(harness.taskDir, "fast.txt"))
+
+	// A template going over the limit is stopped mid-render and fails the
+	// task, naming the template. The other template is rendered in a process
+	// of its own, which isn't stopped
+	harness2 := newTestHarness(t, []*structs.Template{slow, fast}, false, false)
+	harness2.config.TemplateConfig.MaxRenderCPUTime = helper.TimeToPtr(time.Second)
+	harness2.start(t)
+	defer harness2.stop()
+
+	select {
+	case <-harness2.mockHooks.KillCh:
+	case <-harness2.mockHooks.UnblockCh:
+		t.Fatalf("Task unblock should not have been called")
+	case <-time.After(time.Duration(10*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task kill should have been called")
+	}
+
+	event := harness2.mockHooks.KillEvent
+	require.True(t, event.FailsTask)
+	require.Equal(t,
+		`Template failed: template "slow.txt" used more than the client's limit of 1s of CPU time to render (max_render_cpu_time)`,
+		event.DisplayMessage)
+	require.NoFileExists(t, filepath.Join(harness2.taskDir, "slow.txt"))
+	require.FileExists(t, filepath.Join(harness2.taskDir, "fast.txt"))
+}
+
+func TestTaskTemplateManager_MaxRenderCPUTime_Data(t *testing.T) {
+	if !renderCPULimitSupported {
+		t.Skip("limiting the CPU time of renders is only supported on Linux")
+	}
+	t.Parallel()
+
+	// The template is only slow to render with the count read from a file,
+	// which is data the template's text doesn't show
+	harness := newTestHarness(t, nil, false, false)
+	countPath := filepath.Join(harness.taskDir, "count")
+	require.NoError(t, ioutil.WriteFile(countPath, []byte("10"), 0644))
+	harness.templates = []*structs.Template{{
+		EmbeddedTmpl: fmt.Sprintf(`{{ range loop (file %q | parseInt) }}{{ sha256Hex "nomad" }}{{ end }}`, countPath),
+		DestPath:     "data.txt",
+		ChangeMode:   structs.TemplateChangeModeRestart,
+	}}
+	harness.config.TemplateConfig.MaxRenderCPUTime = helper.TimeToPtr(time.Second)
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-harness.mockHooks.KillCh:
+		t.Fatalf("Task kill should not have been called: %v", harness.mockHooks.KillEvent.DisplayMessage)
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+	require.FileExists(t, filepath.Join(harness.taskDir, "data.txt"))
+
+	// Re-rendering the template with a count that makes it go over the limit
+	// fails the task, as the limit applies to every render
+	require.NoError(t, ioutil.WriteFile(countPath, []byte("1000000000"), 0644))
+
+	select {
+	case <-harness.mockHooks.KillCh:
+	case <-time.After(time.Duration(15*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task kill should have been called")
+	}
+	event := harness.mockHooks.KillEvent
+	require.True(t, event.FailsTask)
+	require.Equal(t,
+		`Template failed: template "data.txt" used more than the client's limit of 1s of CPU time to render (max_render_cpu_time)`,
+		event.DisplayMessage)
+}
+
+func TestTaskTemplateManager_ValidateRenderedUTF8(t *testing.T) {
+	t.Parallel()
+
+	valid := &structs.Template{
+		EmbeddedTmpl: "héllo wörld",
+		DestPath:     "valid.txt",
+		ChangeMode:   structs.TemplateChangeModeNoop,
+	}
+	invalid := &structs.Template{
+		EmbeddedTmpl: "héllo\xffwörld",
+		DestPath:     "invalid.txt",
+		ChangeMode:   structs.TemplateChangeModeNoop,
+	}
+
+	// Valid UTF-8 renders
+	harness := newTestHarness(t, []*structs.Template{valid}, false, false)
+	harness.config.TemplateConfig.ValidateRenderedUTF8 = true
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-harness.mockHooks.KillCh:
+		t.Fatalf("Task kill should not have been called: %v", harness.mockHooks.KillEvent.DisplayMessage)
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+	require.FileExists(t, filepath.Join(harness.taskDir, "valid.txt"))
+
+	// Invalid UTF-8 fails the task and is removed
+	harness = newTestHarness(t, []*structs.Template{valid, invalid}, false, false)
+	harness.config.TemplateConfig.ValidateRenderedUTF8 = true
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.KillCh:
+	case <-harness.mockHooks.UnblockCh:
+		t.Fatalf("Task unblock should not have been called")
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task kill should have been called")
+	}
+
+	event := harness.mockHooks.KillEvent
+	require.True(t, event.FailsTask)
+	require.Equal(t,
+		`Template failed: template "invalid.txt" rendered invalid UTF-8 at byte offset 6 (validate_rendered_utf8)`,
+		event.DisplayMessage)
+	require.FileExists(t, filepath.Join(harness.taskDir, "valid.txt"))
+	require.NoFileExists(t, filepath.Join(harness.taskDir, "invalid.txt"))
+
+	// Invalid UTF-8 renders when validation is disabled
+	harness = newTestHarness(t, []*structs.Template{invalid}, false, false)
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-harness.mockHooks.KillCh:
+		t.Fatalf("Task kill should not have been called")
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+	require.FileExists(t, filepath.Join(harness.taskDir, "invalid.txt"))
+}
+
+func TestInvalidUTF8Offset(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, -1, invalidUTF8Offset(nil))
+	require.Equal(t, -1, invalidUTF8Offset([]byte("héllo")))
+	require.Equal(t, 0, invalidUTF8Offset([]byte("\xffhello")))
+	require.Equal(t, 6, invalidUTF8Offset([]byte("héllo\xff")))
+
+	// A truncated multi-byte sequence is invalid from its first byte
+	require.Equal(t, 1, invalidUTF8Offset([]byte("a\xe2\x82")))
+}
+
 func TestTaskTemplateManager_Unblock_Static_NomadEnv(t *testing.T) {
 	t.Parallel()
 	// Make a template that will render immediately
@@ -2029,8 +2339,9 @@ func TestTaskTemplateManager_ClientTemplateConfig_Set(t *testing.T) {
 			&templateconfig.TemplateConfig{
 				Wait: &templateconfig.WaitConfig{
 					Enabled: helper.BoolToPtr(true),
-					Min:     helper.TimeToPtr(2 * time.Second),
-					Max:     helper.TimeToPtr(12 * time.Second),
+					// the client's min clamps the template's
+					Min: helper.TimeToPtr(5 * time.Second),
+					Max: helper.TimeToPtr(12 * time.Second),
 				},
 			},
 		},
@@ -2076,11 +2387,62 @@ func TestTaskTemplateManager_ClientTemplateConfig_Set(t *testing.T) {
 			&templateconfig.TemplateConfig{
 				Wait: &templateconfig.WaitConfig{
 					Enabled: helper.BoolToPtr(true),
-					Min:     helper.TimeToPtr(3 * time.Second),
+					Min:     helper.TimeToPtr(5 * time.Second),
 					Max:     helper.TimeToPtr(11 * time.Second),
 				},
 			},
 		},
+		{
+			"job-override",
+			&config.ClientTemplateConfig{
+				MaxStale:           helper.TimeToPtr(5 * time.Second),
+				BlockQueryWaitTime: helper.TimeToPtr(60 * time.Second),
+				Wait:               waitConfig.Copy(),
+				ConsulRetry:        retryConfig.Copy(),
+				VaultRetry:         retryConfig.Copy(),
+			},
+			&TaskTemplateManagerConfig{
+				ClientConfig: clientConfig,
+				VaultToken:   "token",
+				EnvBuilder:   taskenv.NewBuilder(clientConfig.Node, alloc, alloc.Job.TaskGroups[0].Tasks[0], clientConfig.Region),
+				Templates: []*structs.Template{
+					{
+						Wait: &structs.WaitConfig{
+							Min: helper.TimeToPtr(1 * time.Second),
+							Max: helper.TimeToPtr(8 * time.Second),
+						},
+					},
+				},
+				Retry: &config.RetryConfig{
+					Attempts: helper.IntToPtr(10),
+					Backoff:  helper.TimeToPtr(2 * time.Second),
+				},
+			},
+			&config.Config{
+				TemplateConfig: &config.ClientTemplateConfig{
+					MaxStale:           helper.TimeToPtr(5 * time.Second),
+					BlockQueryWaitTime: helper.TimeToPtr(60 * time.Second),
+					Wait:               waitConfig.Copy(),
+					ConsulRetry: &config.RetryConfig{
+						Attempts:   helper.IntToPtr(5),
+						Backoff:    helper.TimeToPtr(2 * time.Second),
+						MaxBackoff: helper.TimeToPtr(20 * time.Second),
+					},
+					VaultRetry: &config.RetryConfig{
+						Attempts:   helper.IntToPtr(5),
+						Backoff:    helper.TimeToPtr(2 * time.Second),
+						MaxBackoff: helper.TimeToPtr(20 * time.Second),
+					},
+				},
+			},
+			&templateconfig.TemplateConfig{
+				Wait: &templateconfig.WaitConfig{
+					Enabled: helper.BoolToPtr(true),
+					Min:     helper.TimeToPtr(5 * time.Second),
+					Max:     helper.TimeToPtr(8 * time.Second),
+				},
+			},
+		},
 	}
 
 	for _, _case := range cases {
@@ -2156,3 +2518,120 @@ func TestTaskTemplateManager_Template_Wait_Set(t *testing.T) {
 		require.Equal(t, 10*time.Second, *k.Wait.Max)
 	}
 }
+
+// TestTaskTemplateManager_VaultRetries asserts that the runner retries Vault
+// with the retry config of the task's Vault namespace.
+func TestTaskTemplateManager_VaultRetries(t *testing.T) {
+	t.Parallel()
+
+	c := config.DefaultConfig()
+	c.Node = mock.Node()
+	c.VaultConfig = &sconfig.VaultConfig{
+		Enabled:   helper.BoolToPtr(true),
+		Namespace: "default",
+	}
+	c.TemplateConfig.VaultRetry = &config.RetryConfig{Attempts: helper.IntToPtr(5)}
+	c.TemplateConfig.VaultRetries = map[string]*config.RetryConfig{
+		"ops": {Attempts: helper.IntToPtr(2)},
+	}
+
+	alloc := mock.Alloc()
+	ttmConfig := &TaskTemplateManagerConfig{
+		ClientConfig: c,
+		VaultToken:   "token",
+		EnvBuilder:   taskenv.NewBuilder(c.Node, alloc, alloc.Job.TaskGroups[0].Tasks[0], c.Region),
+	}
+
+	// The client's namespace has no entry and uses the default
+	runnerConfig, err := newRunnerConfig(ttmConfig, nil)
+	require.NoError(t, err)
+	require.Equal(t, "default", *runnerConfig.Vault.Namespace)
+	require.Equal(t, 5, *runnerConfig.Vault.Retry.Attempts)
+
+	// The task's namespace takes precedence
+	ttmConfig.VaultNamespace = "ops"
+	runnerConfig, err = newRunnerConfig(ttmConfig, nil)
+	require.NoError(t, err)
+	require.Equal(t, "ops", *runnerConfig.Vault.Namespace)
+	require.Equal(t, 2, *runnerConfig.Vault.Retry.Attempts)
+}
+
+// TestTaskTemplateManager_ConsulNamespace asserts the Consul namespace set in
+// the client's template config overrides the client's Consul namespace, and
+// is overridden by the task's.
+func TestTaskTemplateManager_ConsulNamespace(t *testing.T) {
+	t.Parallel()
+
+	c := config.DefaultConfig()
+	c.Node = mock.Node()
+	c.ConsulConfig = &sconfig.ConsulConfig{Namespace: "default"}
+
+	alloc := mock.Alloc()
+	ttmConfig := &TaskTemplateManagerConfig{
+		ClientConfig: c,
+		EnvBuilder:   taskenv.NewBuilder(c.Node, alloc, alloc.Job.TaskGroups[0].Tasks[0], c.Region),
+	}
+
+	runnerConfig, err := newRunnerConfig(ttmConfig, nil)
+	require.NoError(t, err)
+	require.Equal(t, "default", *runnerConfig.Consul.Namespace)
+
+	c.TemplateConfig.ConsulNamespace = "templates"
+	runnerConfig, err = newRunnerConfig(ttmConfig, nil)
+	require.NoError(t, err)
+	require.Equal(t, "templates", *runnerConfig.Consul.Namespace)
+
+	// The override applies without a client Consul config
+	c.ConsulConfig = nil
+	runnerConfig, err = newRunnerConfig(ttmConfig, nil)
+	require.NoError(t, err)
+	require.Equal(t, "templates", *runnerConfig.Consul.Namespace)
+
+	ttmConfig.ConsulNamespace = "web"
+	runnerConfig, err = newRunnerConfig(ttmConfig, nil)
+	require.NoError(t, err)
+	require.Equal(t, "web", *runnerConfig.Consul.Namespace)
+}
+
+// TestTaskTemplateManager_DependencyOrder asserts that templates are passed to
+// the runner after the templates they depend on, and that dependency cycles
+// are rejected.
+func TestTaskTemplateManager_DependencyOrder(t *testing.T) {
+	t.Parallel()
+
+	c := config.DefaultConfig()
+	c.Node = mock.Node()
+	c.TemplateConfig.DisableSandbox = true
+
+	alloc := mock.Alloc()
+
+	ttmConfig := &TaskTemplateManagerConfig{
+		ClientConfig: c,
+		VaultToken:   "token",
+		EnvBuilder:   taskenv.NewBuilder(c.Node, alloc, alloc.Job.TaskGroups[0].Tasks[0], c.Region),
+		TaskDir:      t.TempDir(),
+		Templates: []*structs.Template{
+			{EmbeddedTmpl: "app", DestPath: "local/app.conf", DependsOn: []string{"local/creds.env"}},
+			{EmbeddedTmpl: "creds", DestPath: "local/creds.env", DependsOn: []string{"local/base.env"}},
+			{EmbeddedTmpl: "base", DestPath: "local/base.env"},
+		},
+	}
+
+	templateMapping, err := parseTemplateConfigs(ttmConfig)
+	require.NoError(t, err)
+
+	runnerConfig, err := newRunnerConfig(ttmConfig, templateMapping)
+	require.NoError(t, err)
+
+	var contents []string
+	for _, ctmpl := range *runnerConfig.Templates {
+		contents = append(contents, *ctmpl.Contents)
+	}
+	require.Equal(t, []string{"base", "creds", "app"}, contents)
+
+	// Cycles are rejected
+	ttmConfig.Templates[2].DependsOn = []string{"local/app.conf"}
+	_, err = newRunnerConfig(ttmConfig, templateMapping)
+	require.EqualError(t, err,
+		"template dependency cycle: local/app.conf -> local/creds.env -> local/base.env -> local/app.conf")
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/template"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/taskenv"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -25,14 +26,20 @@ type templateHookConfig struct {
 	// lifecycle is used to interact with the task's lifecycle
 	lifecycle ti.TaskLifecycle
 
+	// restartSequencer orders template restarts with the other tasks in
+	// the group. It may be nil.
+	restartSequencer ti.TaskRestartSequencer
+
 	// events is used to emit events
 	events ti.EventEmitter
 
 	// templates is the set of templates we are managing
 	templates []*structs.Template
 
-	// clientConfig is the Nomad Client configuration
-	clientConfig *config.Config
+	// clientConfig returns the Nomad Client configuration. It's read each
+	// time a template manager is created, so that the manager uses the
+	// config as of the client's last reload.
+	clientConfig func() *config.Config
 
 	// envBuilder is the environment variable builder for the task.
 	envBuilder *taskenv.Builder
@@ -108,20 +115,47 @@ func (h *templateHook) Prestart(ctx context.Context, req *interfaces.TaskPrestar
 	return nil
 }
 
+// templateRetryConfig returns the retry config set by the retry blocks of a
+// task's templates, merged in order, or nil if none of them sets one.
+func templateRetryConfig(templates []*structs.Template) *config.RetryConfig {
+	var retry *config.RetryConfig
+	for _, tmpl := range templates {
+		if tmpl.Retry == nil {
+			continue
+		}
+
+		override := &config.RetryConfig{}
+		if tmpl.Retry.Attempts != nil {
+			override.Attempts = helper.IntToPtr(*tmpl.Retry.Attempts)
+		}
+		if tmpl.Retry.Backoff != nil {
+			override.Backoff = helper.TimeToPtr(*tmpl.Retry.Backoff)
+		}
+		if tmpl.Retry.MaxBackoff != nil {
+			override.MaxBackoff = helper.TimeToPtr(*tmpl.Retry.MaxBackoff)
+		}
+		retry = retry.Merge(override)
+	}
+	return retry
+}
+
 func (h *templateHook) newManager() (unblock chan struct{}, err error) {
 	unblock = make(chan struct{})
 	m, err := template.NewTaskTemplateManager(&template.TaskTemplateManagerConfig{
 		UnblockCh:            unblock,
 		Lifecycle:            h.config.lifecycle,
+		RestartSequencer:     h.config.restartSequencer,
 		Events:               h.config.events,
 		Templates:            h.config.templates,
-		ClientConfig:         h.config.clientConfig,
+		ClientConfig:         h.config.clientConfig(),
+		Retry:                templateRetryConfig(h.config.templates),
 		ConsulNamespace:      h.config.consulNamespace,
 		VaultToken:           h.vaultToken,
 		VaultNamespace:       h.vaultNamespace,
 		TaskDir:              h.taskDir,
 		EnvBuilder:           h.config.envBuilder,
 		MaxTemplateEventRate: template.DefaultMaxTemplateEventRate,
+		Logger:               h.logger,
 	})
 	if err != nil {
 		h.logger.Error("failed to create template manager", "error", err)
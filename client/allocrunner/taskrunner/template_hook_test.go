@@ -0,0 +1,62 @@
+package taskrunner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTaskRunner_TemplateHook_RetryConfig asserts the retry blocks of a task's
+// templates are merged in order into the retry config the hook hands to the
+// template manager, and that the client's config clamps it.
+func TestTaskRunner_TemplateHook_RetryConfig(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, templateRetryConfig(nil))
+	require.Nil(t, templateRetryConfig([]*structs.Template{{}, {}}))
+
+	templates := []*structs.Template{
+		{
+			Retry: &structs.TemplateRetryConfig{
+				Attempts: helper.IntToPtr(3),
+				Backoff:  helper.TimeToPtr(time.Second),
+			},
+		},
+		{},
+		{
+			Retry: &structs.TemplateRetryConfig{
+				Attempts:   helper.IntToPtr(20),
+				MaxBackoff: helper.TimeToPtr(30 * time.Second),
+			},
+		},
+	}
+
+	retry := templateRetryConfig(templates)
+	require.Equal(t, &config.RetryConfig{
+		Attempts:   helper.IntToPtr(20),
+		Backoff:    helper.TimeToPtr(time.Second),
+		MaxBackoff: helper.TimeToPtr(30 * time.Second),
+	}, retry)
+
+	// The templates' retry config must not alias the job's
+	*retry.Attempts = 1
+	require.Equal(t, 20, *templates[2].Retry.Attempts)
+
+	// The client's attempts clamp the job's
+	clientRetry := &config.RetryConfig{
+		Attempts:   helper.IntToPtr(5),
+		Backoff:    helper.TimeToPtr(250 * time.Millisecond),
+		MaxBackoff: helper.TimeToPtr(time.Minute),
+	}
+	tc := (&config.ClientTemplateConfig{
+		ConsulRetry: clientRetry.Copy(),
+		VaultRetry:  clientRetry.Copy(),
+	}).WithJobRetry(templateRetryConfig(templates))
+	require.Equal(t, 5, *tc.ConsulRetry.Attempts)
+	require.Equal(t, time.Second, *tc.ConsulRetry.Backoff)
+	require.Equal(t, 30*time.Second, *tc.VaultRetry.MaxBackoff)
+}
@@ -0,0 +1,131 @@
+package taskrunner
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	ti "github.com/hashicorp/nomad/client/allocrunner/taskrunner/interfaces"
+	"github.com/hashicorp/nomad/client/taskenv"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/drivers"
+)
+
+// tmpfsHook creates the task's tmpfs scratch directory before the task starts
+// and removes it, along with its content, once the task exits.
+type tmpfsHook struct {
+	// maxSizeMB is the client's max_task_tmpfs_mb
+	maxSizeMB int
+
+	fsi           drivers.FSIsolation
+	taskDir       *allocdir.TaskDir
+	hookResources *hookResources
+	eventEmitter  ti.EventEmitter
+
+	// warned is set once the memory warning event was emitted, so it isn't
+	// emitted again on every restart
+	warned bool
+
+	logger log.Logger
+}
+
+func newTmpfsHook(maxSizeMB int, fsi drivers.FSIsolation, taskDir *allocdir.TaskDir, hookResources *hookResources, eventEmitter ti.EventEmitter, logger log.Logger) *tmpfsHook {
+	h := &tmpfsHook{
+		maxSizeMB:     maxSizeMB,
+		fsi:           fsi,
+		taskDir:       taskDir,
+		hookResources: hookResources,
+		eventEmitter:  eventEmitter,
+	}
+	h.logger = logger.Named(h.Name())
+	return h
+}
+
+func (*tmpfsHook) Name() string {
+	return "tmpfs"
+}
+
+func (h *tmpfsHook) Prestart(ctx context.Context, req *interfaces.TaskPrestartRequest, resp *interfaces.TaskPrestartResponse) error {
+	tmpfs := req.Task.Tmpfs
+	if tmpfs == nil {
+		resp.Done = true
+		return nil
+	}
+
+	if tmpfs.SizeMB > h.maxSizeMB {
+		return fmt.Errorf("tmpfs of %d MB exceeds the client's max_task_tmpfs_mb of %d MB", tmpfs.SizeMB, h.maxSizeMB)
+	}
+
+	mode, err := tmpfs.FileMode()
+	if err != nil {
+		return err
+	}
+
+	// Tasks with image isolation run as a user of their image, which the
+	// client can't look up, so their tmpfs is owned by nobody
+	owner := req.Task.User
+	if h.fsi == drivers.FSIsolationImage {
+		owner = ""
+	}
+
+	if err := h.taskDir.BuildTmpfs(tmpfs.SizeMB, mode, owner); err != nil {
+		return fmt.Errorf("failed to build tmpfs: %v", err)
+	}
+
+	// Only image isolation needs the tmpfs mounted, the task directory is the
+	// root of chroots and tasks without isolation use the host path
+	dir := allocdir.TaskTmpfsContainerPath
+	switch h.fsi {
+	case drivers.FSIsolationNone:
+		dir = h.taskDir.TmpfsDir
+	case drivers.FSIsolationImage:
+		mount := &drivers.MountConfig{
+			TaskPath: allocdir.TaskTmpfsContainerPath,
+			HostPath: h.taskDir.TmpfsDir,
+		}
+		h.hookResources.setMounts(ensureMountpointInserted(h.hookResources.getMounts(), mount))
+	}
+	resp.Env = map[string]string{taskenv.TmpfsDir: dir}
+
+	if !h.warned {
+		h.warnMemoryLimit(req.Task, req.TaskResources)
+		h.warned = true
+	}
+
+	// The hook isn't done as the tmpfs is removed when the task exits and
+	// must be built again when it restarts
+	return nil
+}
+
+// warnMemoryLimit emits an event if the task's tmpfs and memory together
+// exceed its memory limit, as tmpfs pages are charged to the task and filling
+// the tmpfs may get the task OOM killed.
+func (h *tmpfsHook) warnMemoryLimit(task *structs.Task, resources *structs.AllocatedTaskResources) {
+	if task.Resources == nil || resources == nil {
+		return
+	}
+
+	limit := resources.Memory.MemoryMB
+	if resources.Memory.MemoryMaxMB > 0 {
+		limit = resources.Memory.MemoryMaxMB
+	}
+
+	usage := int64(task.Resources.MemoryMB + task.Tmpfs.SizeMB)
+	if usage <= limit {
+		return
+	}
+
+	msg := fmt.Sprintf("tmpfs of %d MB and memory of %d MB exceed the memory limit of %d MB, the task may be OOM killed when the tmpfs fills up",
+		task.Tmpfs.SizeMB, task.Resources.MemoryMB, limit)
+	h.logger.Warn("tmpfs may exceed the task's memory limit", "tmpfs_mb", task.Tmpfs.SizeMB, "memory_mb", task.Resources.MemoryMB, "limit_mb", limit)
+	h.eventEmitter.EmitEvent(structs.NewTaskEvent(structs.TaskTmpfsMemoryWarning).SetMessage(msg))
+}
+
+func (h *tmpfsHook) Exited(context.Context, *interfaces.TaskExitedRequest, *interfaces.TaskExitedResponse) error {
+	if err := h.taskDir.RemoveTmpfs(); err != nil {
+		h.logger.Error("failed to remove tmpfs", "error", err)
+	}
+	return nil
+}
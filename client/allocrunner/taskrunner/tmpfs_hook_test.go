@@ -0,0 +1,179 @@
+package taskrunner
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
+	"github.com/hashicorp/nomad/client/taskenv"
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/plugins/drivers"
+	"github.com/stretchr/testify/require"
+)
+
+// Statically assert the tmpfs hook implements the expected interfaces
+var _ interfaces.TaskPrestartHook = (*tmpfsHook)(nil)
+var _ interfaces.TaskExitedHook = (*tmpfsHook)(nil)
+
+func testTmpfsHook(t *testing.T, maxSizeMB int, fsi drivers.FSIsolation) (*tmpfsHook, *mockEmitter, *hookResources) {
+	dir := t.TempDir()
+	taskDir := &allocdir.TaskDir{
+		Dir:      dir,
+		TmpfsDir: filepath.Join(dir, allocdir.TaskTmpfs),
+	}
+	t.Cleanup(func() { taskDir.RemoveTmpfs() })
+
+	me := &mockEmitter{}
+	resources := &hookResources{}
+	h := newTmpfsHook(maxSizeMB, fsi, taskDir, resources, me, testlog.HCLogger(t))
+	return h, me, resources
+}
+
+// testTmpfsPrestartRequest returns a request for a task with 256 MB of memory
+// and the given tmpfs, allocated the given memory.
+func testTmpfsPrestartRequest(tmpfs *structs.TaskTmpfs, memoryMB, memoryMaxMB int64) *interfaces.TaskPrestartRequest {
+	return &interfaces.TaskPrestartRequest{
+		Task: &structs.Task{
+			Name:      "web",
+			Resources: &structs.Resources{MemoryMB: 256},
+			Tmpfs:     tmpfs,
+		},
+		TaskResources: &structs.AllocatedTaskResources{
+			Memory: structs.AllocatedMemoryResources{
+				MemoryMB:    memoryMB,
+				MemoryMaxMB: memoryMaxMB,
+			},
+		},
+	}
+}
+
+func TestTaskRunner_TmpfsHook_Build(t *testing.T) {
+	t.Parallel()
+
+	h, me, resources := testTmpfsHook(t, 64, drivers.FSIsolationNone)
+	req := testTmpfsPrestartRequest(&structs.TaskTmpfs{SizeMB: 16, Mode: "0750", CountMemory: false}, 256+16, 0)
+	resp := &interfaces.TaskPrestartResponse{}
+	require.NoError(t, h.Prestart(context.Background(), req, resp))
+
+	// The hook must rerun on restarts to rebuild the tmpfs
+	require.False(t, resp.Done)
+	require.Equal(t, map[string]string{taskenv.TmpfsDir: h.taskDir.TmpfsDir}, resp.Env)
+	require.Empty(t, resources.getMounts())
+	require.Empty(t, me.events)
+
+	fi, err := os.Stat(h.taskDir.TmpfsDir)
+	require.NoError(t, err)
+	require.True(t, fi.IsDir())
+	require.Equal(t, os.FileMode(0750), fi.Mode().Perm())
+
+	// The tmpfs and its content are removed when the task exits
+	require.NoError(t, ioutil.WriteFile(filepath.Join(h.taskDir.TmpfsDir, "weights"), []byte("secret"), 0600))
+	require.NoError(t, h.Exited(context.Background(), nil, nil))
+	_, err = os.Stat(h.taskDir.TmpfsDir)
+	require.True(t, os.IsNotExist(err))
+
+	// and built again when it restarts
+	resp = &interfaces.TaskPrestartResponse{}
+	require.NoError(t, h.Prestart(context.Background(), req, resp))
+	_, err = os.Stat(filepath.Join(h.taskDir.TmpfsDir, "weights"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestTaskRunner_TmpfsHook_Image(t *testing.T) {
+	t.Parallel()
+
+	h, _, resources := testTmpfsHook(t, 64, drivers.FSIsolationImage)
+	req := testTmpfsPrestartRequest(&structs.TaskTmpfs{SizeMB: 16, Mode: "0700", CountMemory: false}, 256+16, 0)
+
+	// Restarts don't mount the tmpfs twice
+	for i := 0; i < 2; i++ {
+		resp := &interfaces.TaskPrestartResponse{}
+		require.NoError(t, h.Prestart(context.Background(), req, resp))
+		require.Equal(t, map[string]string{taskenv.TmpfsDir: allocdir.TaskTmpfsContainerPath}, resp.Env)
+	}
+
+	require.Equal(t, []*drivers.MountConfig{{
+		TaskPath: allocdir.TaskTmpfsContainerPath,
+		HostPath: h.taskDir.TmpfsDir,
+	}}, resources.getMounts())
+}
+
+func TestTaskRunner_TmpfsHook_MaxSize(t *testing.T) {
+	t.Parallel()
+
+	// The tmpfs exceeds the client's limit
+	h, _, _ := testTmpfsHook(t, 64, drivers.FSIsolationChroot)
+	req := testTmpfsPrestartRequest(&structs.TaskTmpfs{SizeMB: 128, Mode: "0700"}, 256, 0)
+	err := h.Prestart(context.Background(), req, &interfaces.TaskPrestartResponse{})
+	require.EqualError(t, err, "tmpfs of 128 MB exceeds the client's max_task_tmpfs_mb of 64 MB")
+	_, err = os.Stat(h.taskDir.TmpfsDir)
+	require.True(t, os.IsNotExist(err))
+
+	// Task tmpfs are disabled on the client
+	h, _, _ = testTmpfsHook(t, 0, drivers.FSIsolationChroot)
+	req = testTmpfsPrestartRequest(&structs.TaskTmpfs{SizeMB: 16, Mode: "0700"}, 256, 0)
+	err = h.Prestart(context.Background(), req, &interfaces.TaskPrestartResponse{})
+	require.EqualError(t, err, "tmpfs of 16 MB exceeds the client's max_task_tmpfs_mb of 0 MB")
+}
+
+func TestTaskRunner_TmpfsHook_MemoryWarning(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		countMemory bool
+		memoryMB    int64
+		memoryMaxMB int64
+		warning     string
+	}{
+		{
+			name:        "counted against memory",
+			countMemory: true,
+			memoryMB:    256,
+			warning:     "tmpfs of 64 MB and memory of 256 MB exceed the memory limit of 256 MB, the task may be OOM killed when the tmpfs fills up",
+		},
+		{
+			name:        "counted against memory with enough oversubscription",
+			countMemory: true,
+			memoryMB:    256,
+			memoryMaxMB: 512,
+		},
+		{
+			name:        "counted against memory without enough oversubscription",
+			countMemory: true,
+			memoryMB:    256,
+			memoryMaxMB: 300,
+			warning:     "tmpfs of 64 MB and memory of 256 MB exceed the memory limit of 300 MB, the task may be OOM killed when the tmpfs fills up",
+		},
+		{
+			// The scheduler reserved the tmpfs in addition to the memory
+			name:     "reserved in addition to memory",
+			memoryMB: 256 + 64,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, me, _ := testTmpfsHook(t, 64, drivers.FSIsolationChroot)
+			req := testTmpfsPrestartRequest(&structs.TaskTmpfs{SizeMB: 64, Mode: "0700", CountMemory: tc.countMemory}, tc.memoryMB, tc.memoryMaxMB)
+
+			// The warning is only emitted once across restarts
+			for i := 0; i < 2; i++ {
+				require.NoError(t, h.Prestart(context.Background(), req, &interfaces.TaskPrestartResponse{}))
+			}
+
+			if tc.warning == "" {
+				require.Empty(t, me.events)
+				return
+			}
+			require.Len(t, me.events, 1)
+			require.Equal(t, structs.TaskTmpfsMemoryWarning, me.events[0].Type)
+			require.Equal(t, tc.warning, me.events[0].Message)
+		})
+	}
+}
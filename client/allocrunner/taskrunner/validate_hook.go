@@ -43,18 +43,17 @@ func validateTask(task *structs.Task, taskEnv *taskenv.TaskEnv, conf *config.Con
 	var mErr multierror.Error
 
 	// Validate the user
-	// COMPAT(1.0) uses inclusive language. blacklist is kept for backward compatilibity.
-	unallowedUsers := conf.ReadStringListAlternativeToMapDefault(
-		[]string{"user.denylist", "user.blacklist"},
-		config.DefaultUserDenylist,
-	)
-	checkDrivers := conf.ReadStringListToMapDefault("user.checked_drivers", config.DefaultUserCheckedDrivers)
-	if _, driverMatch := checkDrivers[task.Driver]; driverMatch {
+	if unallowedUsers, checked := conf.EffectiveUserDenylist(task.Driver); checked {
 		if _, unallowed := unallowedUsers[task.User]; unallowed {
 			mErr.Errors = append(mErr.Errors, fmt.Errorf("running as user %q is disallowed", task.User))
 		}
 	}
 
+	// Validate the requested chroot fragments are known to this client
+	if _, err := conf.TaskChrootEnv(task.ChrootExtras); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
 	// Validate the Service names once they're interpolated
 	for i, service := range task.Services {
 		name := taskEnv.ReplaceEnv(service.Name)
@@ -32,6 +32,42 @@ func TestTaskRunner_Validate_UserEnforcement(t *testing.T) {
 	task.Driver = "docker"
 	task.User = "root"
 	require.NoError(t, validateTask(task, taskEnv, conf))
+
+	// Allow root for java only, while the global denylist still applies to
+	// the other checked drivers.
+	conf.Options = map[string]string{"user.denylist.java": "Administrator"}
+	task.Driver = "java"
+	require.NoError(t, validateTask(task, taskEnv, conf))
+	task.User = "Administrator"
+	require.Error(t, validateTask(task, taskEnv, conf))
+
+	task.Driver = "exec"
+	task.User = "root"
+	err := validateTask(task, taskEnv, conf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `running as user "root" is disallowed`)
+}
+
+func TestTaskRunner_Validate_ChrootExtras(t *testing.T) {
+	t.Parallel()
+
+	taskEnv := taskenv.NewEmptyBuilder().Build()
+	conf := config.DefaultConfig()
+
+	task := &structs.Task{
+		Driver:       "exec",
+		ChrootExtras: []string{"zoneinfo", "ca-certificates"},
+	}
+	require.NoError(t, validateTask(task, taskEnv, conf))
+
+	task.ChrootExtras = append(task.ChrootExtras, "fonts")
+	err := validateTask(task, taskEnv, conf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown chroot_extras fragment "fonts"`)
+
+	// Operators may define their own fragments
+	conf.ChrootFragments = map[string][]string{"fonts": {"/usr/share/fonts"}}
+	require.NoError(t, validateTask(task, taskEnv, conf))
 }
 
 func TestTaskRunner_Validate_ServiceName(t *testing.T) {
@@ -112,7 +112,8 @@ func (h *volumeHook) prepareHostVolumes(req *interfaces.TaskPrestartRequest, vol
 	// if a host is restarted and loses the host volume configuration.
 	if err := validateHostVolumes(volumes, hostVolumes); err != nil {
 		h.logger.Error("Requested Host Volume does not exist", "existing", hostVolumes, "requested", volumes)
-		return nil, fmt.Errorf("host volume validation error: %v", err)
+		return nil, structs.NewNodePlacementError(structs.PlacementFailureMissingHostVolume,
+			fmt.Errorf("host volume validation error: %v", err))
 	}
 
 	hostVolumeMounts, err := h.hostVolumeMountConfigurations(req.Task.VolumeMounts, volumes, hostVolumes)
@@ -0,0 +1,312 @@
+package allocrunner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner"
+	ti "github.com/hashicorp/nomad/client/allocrunner/taskrunner/interfaces"
+	clientconfig "github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// templateRestartCoalesceWindow is how long the sequencer waits after
+	// the first restart request for the other tasks sharing a re-rendered
+	// template to request their restarts.
+	templateRestartCoalesceWindow = 1 * time.Second
+
+	// templateRestartPollInterval is how often the sequencer checks whether
+	// the tasks in a stage are running again.
+	templateRestartPollInterval = 250 * time.Millisecond
+)
+
+// Restart stages for lifecycle ordered restarts. Followers restart before
+// the tasks that depend on them and the group leader restarts after the
+// other running tasks. Poststop tasks only run once the main tasks have
+// stopped, so they restart last.
+const (
+	restartStagePrestart = iota
+	restartStageMain
+	restartStagePoststart
+	restartStageLeader
+	restartStagePoststop
+	numRestartStages
+)
+
+var restartStageNames = [numRestartStages]string{
+	restartStagePrestart:  "prestart",
+	restartStageMain:      "main",
+	restartStagePoststart: "poststart",
+	restartStageLeader:    "leader",
+	restartStagePoststop:  "poststop",
+}
+
+// sequencedTask is the subset of the task runner used by the
+// templateRestartSequencer.
+type sequencedTask interface {
+	Restart(ctx context.Context, event *structs.TaskEvent, failure bool) error
+	TaskState() *structs.TaskState
+	EmitEvent(event *structs.TaskEvent)
+}
+
+// templateRestartSequencer restarts tasks whose templates have
+// restart_order "lifecycle" in the order of their lifecycle within the task
+// group, waiting for the long-lived tasks of each stage to be running again
+// before restarting the next one.
+type templateRestartSequencer struct {
+	logger hclog.Logger
+
+	// stages maps task names to their restart stage
+	stages map[string]int
+
+	// longLived holds the tasks a stage waits for to be running again
+	// before the next stage restarts
+	longLived map[string]bool
+
+	// lookup returns the runner for a task, or nil if it does not exist
+	lookup func(name string) sequencedTask
+
+	stageTimeout   time.Duration
+	coalesceWindow time.Duration
+	pollInterval   time.Duration
+
+	// shutdownCh stops the sequencer when closed
+	shutdownCh <-chan struct{}
+
+	// pending holds the restart requests not yet handled, keyed by task
+	pending     map[string]*structs.TaskEvent
+	pendingLock sync.Mutex
+
+	// notifyCh is ticked when a new restart request is pending
+	notifyCh chan struct{}
+}
+
+func newTemplateRestartSequencer(logger hclog.Logger, tasks []*structs.Task,
+	lookup func(string) sequencedTask, stageTimeout time.Duration,
+	shutdownCh <-chan struct{}) *templateRestartSequencer {
+
+	if stageTimeout <= 0 {
+		stageTimeout = clientconfig.DefaultTemplateRestartStageTimeout
+	}
+
+	stages := make(map[string]int, len(tasks))
+	longLived := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		stages[task.Name] = restartStageFor(task)
+		longLived[task.Name] = isLongLived(task)
+	}
+
+	return &templateRestartSequencer{
+		logger:         logger.Named("template_restart_sequencer"),
+		stages:         stages,
+		longLived:      longLived,
+		lookup:         lookup,
+		stageTimeout:   stageTimeout,
+		coalesceWindow: templateRestartCoalesceWindow,
+		pollInterval:   templateRestartPollInterval,
+		shutdownCh:     shutdownCh,
+		pending:        make(map[string]*structs.TaskEvent),
+		notifyCh:       make(chan struct{}, 1),
+	}
+}
+
+// restartStageFor returns the stage in which the task is restarted.
+func restartStageFor(task *structs.Task) int {
+	if task.Leader {
+		return restartStageLeader
+	}
+	if task.Lifecycle == nil {
+		return restartStageMain
+	}
+	switch task.Lifecycle.Hook {
+	case structs.TaskLifecycleHookPrestart:
+		// Ephemeral prestart tasks have usually exited by the time a
+		// template re-renders, but if one is still running it restarts
+		// with the sidecars before the main tasks.
+		return restartStagePrestart
+	case structs.TaskLifecycleHookPoststop:
+		return restartStagePoststop
+	default:
+		return restartStagePoststart
+	}
+}
+
+// isLongLived returns true if the task keeps running alongside the main
+// tasks, so that its stage waits for it to be running again. Ephemeral
+// prestart and poststart tasks exit on their own, and poststop tasks only
+// run once the main tasks have stopped.
+func isLongLived(task *structs.Task) bool {
+	if task.Lifecycle == nil {
+		return true
+	}
+	return task.Lifecycle.Sidecar && task.Lifecycle.Hook != structs.TaskLifecycleHookPoststop
+}
+
+// hasLifecycleTemplates returns true if any task in the group has a template
+// that restarts in lifecycle order.
+func hasLifecycleTemplates(tasks []*structs.Task) bool {
+	for _, task := range tasks {
+		for _, tmpl := range task.Templates {
+			if tmpl.ChangeMode == structs.TemplateChangeModeRestart &&
+				tmpl.RestartOrder == structs.TemplateRestartOrderLifecycle {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forTask returns the TaskRestartSequencer handed to the named task's runner.
+func (s *templateRestartSequencer) forTask(name string) ti.TaskRestartSequencer {
+	return &taskRestartRequester{sequencer: s, task: name}
+}
+
+// requestRestart queues a restart of the task.
+func (s *templateRestartSequencer) requestRestart(name string, event *structs.TaskEvent) {
+	s.pendingLock.Lock()
+	s.pending[name] = event
+	s.pendingLock.Unlock()
+
+	select {
+	case s.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// run handles restart requests until the shutdown channel is closed.
+func (s *templateRestartSequencer) run() {
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-s.notifyCh:
+		}
+
+		// Give the other tasks sharing the template a chance to request
+		// their restart so they are ordered together.
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-time.After(s.coalesceWindow):
+		}
+
+		s.pendingLock.Lock()
+		pending := s.pending
+		s.pending = make(map[string]*structs.TaskEvent)
+		s.pendingLock.Unlock()
+
+		if len(pending) != 0 {
+			s.restart(pending)
+		}
+	}
+}
+
+// restart restarts the pending tasks stage by stage.
+func (s *templateRestartSequencer) restart(pending map[string]*structs.TaskEvent) {
+	var stages [numRestartStages][]string
+	for name := range pending {
+		stage := s.stages[name]
+		stages[stage] = append(stages[stage], name)
+	}
+
+	order := make([]string, 0, numRestartStages)
+	for i := range stages {
+		if len(stages[i]) == 0 {
+			continue
+		}
+		sort.Strings(stages[i])
+		order = append(order, fmt.Sprintf("%s [%s]",
+			restartStageNames[i], strings.Join(stages[i], ", ")))
+	}
+	s.logger.Debug("restarting tasks in lifecycle order", "order", order)
+
+	for i, names := range stages {
+		if len(names) == 0 {
+			continue
+		}
+
+		stageStart := time.Now()
+		restarted := make(map[string]sequencedTask, len(names))
+		for _, name := range names {
+			tr := s.lookup(name)
+			if tr == nil {
+				continue
+			}
+
+			tr.EmitEvent(structs.NewTaskEvent(structs.TaskRestartSequenced).
+				SetDisplayMessage(fmt.Sprintf("Restarting in %s stage of lifecycle ordered restart: %s",
+					restartStageNames[i], strings.Join(order, " -> "))))
+
+			err := tr.Restart(context.Background(), pending[name], false)
+			if err == taskrunner.ErrTaskNotRunning {
+				s.logger.Trace("skipping restart of task that is not running", "task", name)
+				continue
+			}
+			if err != nil {
+				s.logger.Warn("failed to restart task", "task", name, "error", err)
+				continue
+			}
+			if s.longLived[name] {
+				restarted[name] = tr
+			}
+		}
+
+		if !s.waitRunning(restarted, stageStart) {
+			return
+		}
+	}
+}
+
+// waitRunning waits for the restarted tasks to be running again. Tasks that
+// are not running within the stage timeout get an event and the sequence
+// moves on. It returns false if the sequencer was shut down.
+func (s *templateRestartSequencer) waitRunning(tasks map[string]sequencedTask, since time.Time) bool {
+	timer := time.NewTimer(s.stageTimeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for name, tr := range tasks {
+			state := tr.TaskState()
+			if state.State == structs.TaskStateRunning && state.StartedAt.After(since) {
+				delete(tasks, name)
+			}
+		}
+		if len(tasks) == 0 {
+			return true
+		}
+
+		select {
+		case <-s.shutdownCh:
+			return false
+		case <-timer.C:
+			for name, tr := range tasks {
+				s.logger.Warn("task not running after lifecycle ordered restart stage timeout",
+					"task", name, "timeout", s.stageTimeout)
+				tr.EmitEvent(structs.NewTaskEvent(structs.TaskRestartSequenced).
+					SetDisplayMessage(fmt.Sprintf("Task not running within %v of restart, continuing with next stage",
+						s.stageTimeout)))
+			}
+			return true
+		case <-ticker.C:
+		}
+	}
+}
+
+// taskRestartRequester implements TaskRestartSequencer for a single task.
+type taskRestartRequester struct {
+	sequencer *templateRestartSequencer
+	task      string
+}
+
+func (r *taskRestartRequester) RequestRestart(event *structs.TaskEvent) {
+	r.sequencer.requestRestart(r.task, event)
+}
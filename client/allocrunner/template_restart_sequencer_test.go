@@ -0,0 +1,329 @@
+package allocrunner
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/template"
+	clientconfig "github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/taskenv"
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// mockSequencedTask records restarts and optionally comes back up running
+type mockSequencedTask struct {
+	name     string
+	running  bool
+	restarts *[]string
+	lock     *sync.Mutex
+
+	state  *structs.TaskState
+	events []*structs.TaskEvent
+	killed *structs.TaskEvent
+}
+
+func (m *mockSequencedTask) Restart(_ context.Context, _ *structs.TaskEvent, _ bool) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	*m.restarts = append(*m.restarts, m.name)
+	if m.running {
+		m.state.State = structs.TaskStateRunning
+		m.state.StartedAt = time.Now()
+	} else {
+		m.state.State = structs.TaskStatePending
+	}
+	return nil
+}
+
+func (m *mockSequencedTask) TaskState() *structs.TaskState {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.state.Copy()
+}
+
+func (m *mockSequencedTask) EmitEvent(event *structs.TaskEvent) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.events = append(m.events, event)
+}
+
+func (m *mockSequencedTask) Signal(_ *structs.TaskEvent, _ string) error {
+	return nil
+}
+
+func (m *mockSequencedTask) Kill(_ context.Context, event *structs.TaskEvent) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.killed = event
+	return nil
+}
+
+func (m *mockSequencedTask) IsRunning() bool {
+	return false
+}
+
+func (m *mockSequencedTask) eventTypes() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	types := make([]string, 0, len(m.events))
+	for _, e := range m.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestTemplateRestartSequencer_StageFor(t *testing.T) {
+	cases := []struct {
+		name     string
+		task     *structs.Task
+		expected int
+	}{
+		{"main", &structs.Task{}, restartStageMain},
+		{"leader", &structs.Task{Leader: true}, restartStageLeader},
+		{"prestart sidecar", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPrestart, Sidecar: true}}, restartStagePrestart},
+		{"prestart ephemeral", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPrestart}}, restartStagePrestart},
+		{"poststart", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPoststart}}, restartStagePoststart},
+		{"poststop", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPoststop}}, restartStagePoststop},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, restartStageFor(tc.task))
+		})
+	}
+}
+
+func TestTemplateRestartSequencer_IsLongLived(t *testing.T) {
+	cases := []struct {
+		name     string
+		task     *structs.Task
+		expected bool
+	}{
+		{"main", &structs.Task{}, true},
+		{"prestart sidecar", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPrestart, Sidecar: true}}, true},
+		{"prestart ephemeral", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPrestart}}, false},
+		{"poststart sidecar", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPoststart, Sidecar: true}}, true},
+		{"poststart ephemeral", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPoststart}}, false},
+		{"poststop", &structs.Task{Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPoststop}}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, isLongLived(tc.task))
+		})
+	}
+}
+
+func TestTemplateRestartSequencer_LifecycleOrder(t *testing.T) {
+	tasks := []*structs.Task{
+		{Name: "leader", Leader: true},
+		{Name: "web"},
+		{Name: "logs", Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPoststart, Sidecar: true}},
+		{Name: "proxy", Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPrestart, Sidecar: true}},
+		{Name: "init", Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPrestart}},
+		{Name: "cleanup", Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPoststop}},
+	}
+
+	// The logs sidecar never comes back up, and the ephemeral init and
+	// cleanup tasks exit right away
+	running := map[string]bool{"leader": true, "web": true, "proxy": true}
+
+	var lock sync.Mutex
+	var restarts []string
+	runners := map[string]*mockSequencedTask{}
+	for _, task := range tasks {
+		runners[task.Name] = &mockSequencedTask{
+			name:     task.Name,
+			running:  running[task.Name],
+			restarts: &restarts,
+			lock:     &lock,
+			state:    structs.NewTaskState(),
+		}
+	}
+	lookup := func(name string) sequencedTask {
+		if tr, ok := runners[name]; ok {
+			return tr
+		}
+		return nil
+	}
+
+	shutdownCh := make(chan struct{})
+	defer close(shutdownCh)
+
+	seq := newTemplateRestartSequencer(testlog.HCLogger(t), tasks, lookup,
+		100*time.Millisecond, shutdownCh)
+	seq.coalesceWindow = 50 * time.Millisecond
+	seq.pollInterval = 10 * time.Millisecond
+	go seq.run()
+
+	// Request restarts in the opposite of lifecycle order
+	for _, name := range []string{"cleanup", "leader", "logs", "web", "proxy", "init"} {
+		seq.forTask(name).RequestRestart(structs.NewTaskEvent(structs.TaskRestartSignal))
+	}
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(restarts) == 6
+	}, 5*time.Second, 10*time.Millisecond)
+
+	lock.Lock()
+	require.Equal(t, []string{"init", "proxy", "web", "logs", "leader", "cleanup"}, restarts)
+	lock.Unlock()
+
+	// Every task gets an event describing the order; the sidecar that never
+	// became running gets a second event for the stage timeout. The
+	// ephemeral tasks aren't waited on.
+	require.Equal(t, []string{structs.TaskRestartSequenced}, runners["web"].eventTypes())
+	require.Equal(t, []string{structs.TaskRestartSequenced, structs.TaskRestartSequenced},
+		runners["logs"].eventTypes())
+	require.Equal(t, []string{structs.TaskRestartSequenced}, runners["init"].eventTypes())
+	require.Equal(t, []string{structs.TaskRestartSequenced}, runners["cleanup"].eventTypes())
+}
+
+// TestTemplateRestartSequencer_TemplateManager asserts that the template
+// managers of a group's tasks hand re-rendered templates with restart_order
+// "lifecycle" to the sequencer, which restarts the tasks stage by stage.
+func TestTemplateRestartSequencer_TemplateManager(t *testing.T) {
+	// The tasks render a shared file, which is polled for changes
+	src := filepath.Join(t.TempDir(), "shared.conf")
+	require.NoError(t, ioutil.WriteFile(src, []byte("v1"), 0644))
+
+	alloc := mock.Alloc()
+	tasks := []*structs.Task{
+		{Name: "leader", Leader: true},
+		{Name: "web"},
+		{Name: "proxy", Lifecycle: &structs.TaskLifecycleConfig{
+			Hook: structs.TaskLifecycleHookPrestart, Sidecar: true}},
+	}
+	for _, task := range tasks {
+		task.Templates = []*structs.Template{{
+			EmbeddedTmpl: fmt.Sprintf(`{{ file %q }}`, src),
+			DestPath:     "local/shared.conf",
+			ChangeMode:   structs.TemplateChangeModeRestart,
+			RestartOrder: structs.TemplateRestartOrderLifecycle,
+		}}
+	}
+	alloc.Job.TaskGroups[0].Tasks = tasks
+
+	var lock sync.Mutex
+	var restarts []string
+	runners := map[string]*mockSequencedTask{}
+	for _, task := range tasks {
+		runners[task.Name] = &mockSequencedTask{
+			name:     task.Name,
+			running:  true,
+			restarts: &restarts,
+			lock:     &lock,
+			state:    structs.NewTaskState(),
+		}
+	}
+	lookup := func(name string) sequencedTask {
+		if tr, ok := runners[name]; ok {
+			return tr
+		}
+		return nil
+	}
+
+	shutdownCh := make(chan struct{})
+	defer close(shutdownCh)
+
+	seq := newTemplateRestartSequencer(testlog.HCLogger(t), tasks, lookup,
+		time.Second, shutdownCh)
+	// The managers poll the file independently, so give them time to all
+	// see the change
+	seq.coalesceWindow = 3 * time.Second
+	seq.pollInterval = 10 * time.Millisecond
+	go seq.run()
+
+	conf := &clientconfig.Config{
+		Region: "global",
+		TemplateConfig: &clientconfig.ClientTemplateConfig{
+			DisableSandbox: true,
+		},
+	}
+
+	node := mock.Node()
+	for _, task := range tasks {
+		taskDir := t.TempDir()
+		envBuilder := taskenv.NewBuilder(node, alloc, task, "global")
+		envBuilder.SetClientTaskRoot(taskDir)
+
+		unblockCh := make(chan struct{})
+		tm, err := template.NewTaskTemplateManager(&template.TaskTemplateManagerConfig{
+			UnblockCh:            unblockCh,
+			Lifecycle:            runners[task.Name],
+			RestartSequencer:     seq.forTask(task.Name),
+			Events:               runners[task.Name],
+			Templates:            task.Templates,
+			ClientConfig:         conf,
+			TaskDir:              taskDir,
+			EnvBuilder:           envBuilder,
+			MaxTemplateEventRate: template.DefaultMaxTemplateEventRate,
+		})
+		require.NoError(t, err)
+		defer tm.Stop()
+
+		select {
+		case <-unblockCh:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("task %q not unblocked", task.Name)
+		}
+	}
+
+	// Change the shared file so that every task's template re-renders
+	require.NoError(t, ioutil.WriteFile(src, []byte("version 2"), 0644))
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(restarts) == 3
+	}, 15*time.Second, 50*time.Millisecond)
+
+	lock.Lock()
+	require.Equal(t, []string{"proxy", "web", "leader"}, restarts)
+	lock.Unlock()
+
+	// Each task's events describe the whole order and the task's own stage
+	order := "prestart [proxy] -> main [web] -> leader [leader]"
+	for name, stage := range map[string]string{"proxy": "prestart", "web": "main", "leader": "leader"} {
+		tr := runners[name]
+		tr.lock.Lock()
+		require.Nil(t, tr.killed, "task %q killed", name)
+		var sequenced []string
+		for _, e := range tr.events {
+			if e.Type == structs.TaskRestartSequenced {
+				sequenced = append(sequenced, e.DisplayMessage)
+			}
+		}
+		tr.lock.Unlock()
+
+		require.Len(t, sequenced, 1, "task %q events", name)
+		require.True(t, strings.HasPrefix(sequenced[0],
+			fmt.Sprintf("Restarting in %s stage of lifecycle ordered restart:", stage)), sequenced[0])
+		require.True(t, strings.HasSuffix(sequenced[0], order), sequenced[0])
+	}
+}
@@ -16,6 +16,7 @@ import (
 	nomadapi "github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -91,6 +92,10 @@ type Config struct {
 	// enabled.
 	MigrateToken string
 
+	// Bandwidth throttles migrations of remote alloc dirs to their share of
+	// the node's download bandwidth. It may be nil.
+	Bandwidth *bandwidth.Manager
+
 	Logger hclog.Logger
 }
 
@@ -123,6 +128,7 @@ func newMigratorForAlloc(c Config, tg *structs.TaskGroup, watchedAllocID string,
 		migrate:      migrate,
 		rpc:          c.RPC,
 		migrateToken: c.MigrateToken,
+		bandwidth:    c.Bandwidth,
 		logger:       logger,
 	}
 }
@@ -148,6 +154,7 @@ func newWatcherForAlloc(c Config, watchedAllocID string, m AllocRunnerMeta) Prev
 		config:       c.Config,
 		rpc:          c.RPC,
 		migrateToken: c.MigrateToken,
+		bandwidth:    c.Bandwidth,
 		logger:       logger,
 	}
 }
@@ -339,6 +346,10 @@ type remotePrevAlloc struct {
 	// migrateToken allows a client to migrate data in an ACL-protected remote
 	// volume
 	migrateToken string
+
+	// bandwidth throttles the migration to its share of the node's download
+	// bandwidth
+	bandwidth *bandwidth.Manager
 }
 
 // IsWaiting returns true if there's a concurrent call inside Wait
@@ -536,6 +547,7 @@ func (p *remotePrevAlloc) migrateAllocDir(ctx context.Context, nodeAddr string)
 		return nil, fmt.Errorf("error getting snapshot from previous alloc %q: %v", p.prevAllocID, err)
 	}
 
+	resp = p.bandwidth.Reader(ctx, bandwidth.ClassMigrate, resp)
 	if err := p.streamAllocDir(ctx, resp, prevAllocDir.AllocDir); err != nil {
 		prevAllocDir.Destroy()
 		return nil, err
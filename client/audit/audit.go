@@ -0,0 +1,315 @@
+// Package audit records the mutating calls made to a client's RPC endpoints,
+// such as garbage collecting or signalling allocations, so that operators can
+// review and replay what was done to a node.
+//
+// Entries are appended as JSON lines to a file under the client's state
+// directory. Once the file reaches its maximum size it is rotated, and only a
+// bounded number of rotated files are kept. Each entry is numbered so that
+// the log can be read a page at a time across rotations.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// FileName is the name of the file entries are appended to. Rotated
+	// files are suffixed with their generation, the oldest having the
+	// highest.
+	FileName = "audit.log"
+
+	// ResultOK is the result of a call that succeeded
+	ResultOK = "ok"
+
+	// ResultError is the result of a call that returned an error
+	ResultError = "error"
+)
+
+// Entry is the record of a single mutating call.
+type Entry struct {
+	// Index numbers the entries in the order they were recorded, starting
+	// at 1.
+	Index uint64 `json:"index"`
+
+	// Time is when the call returned
+	Time time.Time `json:"time"`
+
+	// Accessor is the accessor ID of the ACL token the call was made with.
+	// It is empty when ACLs are disabled.
+	Accessor string `json:"accessor,omitempty"`
+
+	// Endpoint is the RPC method called, such as "Allocations.Signal"
+	Endpoint string `json:"endpoint"`
+
+	// Params are the arguments of the call with their secrets redacted
+	Params map[string]interface{} `json:"params,omitempty"`
+
+	// Result is ResultOK or ResultError
+	Result string `json:"result"`
+
+	// Error is the error the call returned
+	Error string `json:"error,omitempty"`
+}
+
+// Log is an append-only audit log with size-based rotation. A nil Log
+// records nothing, so callers don't need to check whether auditing is
+// enabled.
+type Log struct {
+	dir      string
+	maxBytes int64
+	maxFiles int
+
+	file  *os.File
+	size  int64
+	index uint64
+	lock  sync.Mutex
+}
+
+// NewLog opens the audit log in dir, creating dir if needed. The log is
+// rotated once it reaches maxFileMB, and maxFiles files are kept including
+// the one being written.
+func NewLog(dir string, maxFileMB, maxFiles int) (*Log, error) {
+	if maxFileMB <= 0 {
+		return nil, fmt.Errorf("maximum audit file size must be positive, got %d MB", maxFileMB)
+	}
+	if maxFiles <= 0 {
+		return nil, fmt.Errorf("maximum audit file count must be positive, got %d", maxFiles)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %v", err)
+	}
+
+	l := &Log{
+		dir:      dir,
+		maxBytes: int64(maxFileMB) * 1024 * 1024,
+		maxFiles: maxFiles,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	index, err := l.lastIndex()
+	if err != nil {
+		l.file.Close()
+		return nil, err
+	}
+	l.index = index
+	return l, nil
+}
+
+// open opens the current file for appending. A trailing line torn by a crash
+// while it was written is truncated, so that the next entry doesn't continue
+// it.
+func (l *Log) open() error {
+	f, err := os.OpenFile(l.path(0), os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %v", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit file: %v", err)
+	}
+	size, err := completeSize(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to read audit file: %v", err)
+	}
+	if size < fi.Size() {
+		if err := f.Truncate(size); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to truncate torn audit entry: %v", err)
+		}
+	}
+	l.file = f
+	l.size = size
+	return nil
+}
+
+// completeSize returns the size of the file up to the end of its last
+// complete line.
+func completeSize(f *os.File) (int64, error) {
+	r := bufio.NewReader(f)
+	var size int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil
+		} else if err != nil {
+			return 0, err
+		}
+		size += int64(len(line))
+	}
+}
+
+// lastIndex returns the index of the newest entry kept, or 0 if there are
+// none.
+func (l *Log) lastIndex() (uint64, error) {
+	for gen := 0; gen < l.maxFiles; gen++ {
+		var last uint64
+		err := readEntries(l.path(gen), func(e *Entry) bool {
+			last = e.Index
+			return true
+		})
+		if err != nil {
+			return 0, err
+		}
+		if last > 0 {
+			return last, nil
+		}
+	}
+	return 0, nil
+}
+
+// path returns the path of the file of the given generation, 0 being the
+// current file.
+func (l *Log) path(generation int) string {
+	if generation == 0 {
+		return filepath.Join(l.dir, FileName)
+	}
+	return filepath.Join(l.dir, fmt.Sprintf("%s.%d", FileName, generation))
+}
+
+// Record numbers the entry and appends it to the log, first rotating the log
+// if the entry would take the current file over its maximum size.
+func (l *Log) Record(e *Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+
+	e.Index = l.index + 1
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %v", err)
+	}
+	line = append(line, '\n')
+
+	if l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %v", err)
+	}
+	l.index = e.Index
+	return nil
+}
+
+// rotate shifts every file to the next generation, dropping the oldest, and
+// opens a new current file. lock must be held.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit file: %v", err)
+	}
+	l.file = nil
+
+	if err := os.Remove(l.path(l.maxFiles - 1)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove oldest audit file: %v", err)
+	}
+	for gen := l.maxFiles - 2; gen >= 0; gen-- {
+		if err := os.Rename(l.path(gen), l.path(gen+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit file: %v", err)
+		}
+	}
+
+	return l.open()
+}
+
+// Entries returns the entries of every file kept, oldest first, starting at
+// the entry numbered start. At most perPage entries are returned if perPage
+// is positive, along with the index to start the next page at, which is 0
+// once there are no more entries.
+func (l *Log) Entries(start uint64, perPage int) ([]*Entry, uint64, error) {
+	if l == nil {
+		return nil, 0, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var entries []*Entry
+	var next uint64
+	for gen := l.maxFiles - 1; gen >= 0 && next == 0; gen-- {
+		err := readEntries(l.path(gen), func(e *Entry) bool {
+			if e.Index < start {
+				return true
+			}
+			if perPage > 0 && len(entries) == perPage {
+				next = e.Index
+				return false
+			}
+			entries = append(entries, e)
+			return true
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return entries, next, nil
+}
+
+// readEntries calls fn with each entry of a file in order until it returns
+// false. A file that doesn't exist has no entries, and a trailing line torn
+// by a crash is skipped.
+func readEntries(path string, fn func(*Entry) bool) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open audit file: %v", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read audit file: %v", err)
+		}
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("failed to decode audit entry in %s: %v", filepath.Base(path), err)
+		}
+		if !fn(&e) {
+			return nil
+		}
+	}
+}
+
+// Close closes the current file. Entries can still be read once closed, but
+// no more can be recorded.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,189 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_RecordEntries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	l, err := NewLog(dir, 1, 3)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Round(time.Second)
+	e := &Entry{
+		Time:     now,
+		Accessor: "accessor",
+		Endpoint: "Allocations.Signal",
+		Params:   map[string]interface{}{"AllocID": "alloc"},
+		Result:   ResultError,
+		Error:    "Unknown allocation",
+	}
+	require.NoError(t, l.Record(e))
+
+	entries, _, err := l.Entries(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []*Entry{e}, entries)
+
+	fi, err := os.Stat(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// Entries are kept across restarts and can be read once closed
+	require.NoError(t, l.Close())
+	require.Error(t, l.Record(e))
+	l, err = NewLog(dir, 1, 3)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Record(e))
+	entries, _, err = l.Entries(0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, uint64(2), entries[1].Index)
+}
+
+func TestLog_Rotate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	l, err := NewLog(dir, 1, 3)
+	require.NoError(t, err)
+	defer l.Close()
+
+	// Each entry takes up a little more than a quarter of a file, so a file
+	// holds 3 entries
+	padding := strings.Repeat("x", 256*1024)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, l.Record(&Entry{
+			Endpoint: fmt.Sprintf("Allocations.Signal%d", i),
+			Params:   map[string]interface{}{"Padding": padding},
+			Result:   ResultOK,
+		}))
+	}
+
+	for gen, suffix := range []string{"", ".1", ".2"} {
+		fi, err := os.Stat(filepath.Join(dir, FileName+suffix))
+		require.NoError(t, err, "generation %d", gen)
+		require.LessOrEqual(t, fi.Size(), int64(1024*1024))
+	}
+	_, err = os.Stat(filepath.Join(dir, FileName+".3"))
+	require.True(t, os.IsNotExist(err))
+
+	// The oldest entries were dropped with the oldest file
+	entries, _, err := l.Entries(0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 7)
+	for i, e := range entries {
+		require.Equal(t, fmt.Sprintf("Allocations.Signal%d", i+3), e.Endpoint)
+	}
+
+	// Pages span rotated files and start at the next token
+	entries, next, err := l.Entries(0, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, uint64(4), entries[0].Index)
+	require.Equal(t, uint64(6), next)
+
+	entries, next, err = l.Entries(next, 3)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "Allocations.Signal5", entries[0].Endpoint)
+	require.Equal(t, uint64(9), next)
+
+	entries, next, err = l.Entries(next, 3)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "Allocations.Signal9", entries[1].Endpoint)
+	require.Zero(t, next)
+}
+
+func TestLog_TornEntry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	l, err := NewLog(dir, 1, 3)
+	require.NoError(t, err)
+	require.NoError(t, l.Record(&Entry{Endpoint: "Allocations.Signal", Result: ResultOK}))
+
+	// Simulate a crash while an entry was written
+	f, err := os.OpenFile(filepath.Join(dir, FileName), os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"index":2,"endpoint":"Allocations.Res`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// The torn entry is skipped when reading
+	entries, _, err := l.Entries(0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NoError(t, l.Close())
+
+	// and truncated when reopening, so the next entry starts its own line
+	l, err = NewLog(dir, 1, 3)
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Record(&Entry{Endpoint: "Allocations.Restart", Result: ResultOK}))
+	entries, _, err = l.Entries(0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "Allocations.Restart", entries[1].Endpoint)
+	require.Equal(t, uint64(2), entries[1].Index)
+}
+
+func TestLog_Nil(t *testing.T) {
+	t.Parallel()
+
+	var l *Log
+	require.NoError(t, l.Record(&Entry{}))
+	entries, _, err := l.Entries(0, 0)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	require.NoError(t, l.Close())
+}
+
+func TestParams(t *testing.T) {
+	t.Parallel()
+
+	args := &structs.AllocSignalRequest{
+		AllocID: "alloc",
+		Task:    "web",
+		Signal:  "SIGHUP",
+		QueryOptions: structs.QueryOptions{
+			Region:     "global",
+			AuthToken:  "secret",
+			AllowStale: true,
+		},
+	}
+	params := Params(args)
+	require.Equal(t, "alloc", params["AllocID"])
+	require.Equal(t, "SIGHUP", params["Signal"])
+	require.Equal(t, "global", params["Region"])
+	require.Equal(t, Redacted, params["AuthToken"])
+	require.NotContains(t, params, "AllowStale")
+	require.NotContains(t, params, "MinQueryIndex")
+	require.NotContains(t, params, "Forwarded")
+
+	// Nested parameters are redacted too
+	nested := Params(map[string]interface{}{
+		"Secrets": map[string]string{"password": "hunter2"},
+		"VolumeCapabilities": []interface{}{
+			map[string]interface{}{"FSType": "ext4", "MountFlags": []string{"password=hunter2"}},
+		},
+	})
+	require.Equal(t, Redacted, nested["Secrets"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"FSType": "ext4", "MountFlags": Redacted},
+	}, nested["VolumeCapabilities"])
+
+	require.Nil(t, Params(nil))
+	require.Nil(t, Params("not an object"))
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"encoding/json"
+)
+
+// Redacted replaces the value of sensitive parameters
+const Redacted = "[REDACTED]"
+
+var (
+	// redactedParams are the parameters whose values are never written to
+	// the log, at any depth of the arguments
+	redactedParams = map[string]struct{}{
+		"AuthToken":  {},
+		"SecretID":   {},
+		"Secrets":    {},
+		"MountFlags": {},
+		"Token":      {},
+		"Password":   {},
+	}
+
+	// droppedParams are the query and write options that say nothing about
+	// what a call did, and would only clutter the log
+	droppedParams = map[string]struct{}{
+		"MinQueryIndex":    {},
+		"MaxQueryTime":     {},
+		"AllowStale":       {},
+		"Prefix":           {},
+		"PerPage":          {},
+		"NextToken":        {},
+		"Forwarded":        {},
+		"IdempotencyToken": {},
+	}
+)
+
+// Params returns the parameters of an RPC's arguments to record in an Entry,
+// with their sensitive values redacted. Arguments that aren't encoded as a
+// JSON object have no parameters.
+func Params(args interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+
+	buf, err := json.Marshal(args)
+	if err != nil {
+		return nil
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal(buf, &params); err != nil {
+		return nil
+	}
+
+	redact(params)
+	return params
+}
+
+// redact redacts and drops parameters of the map and of every map nested in
+// it.
+func redact(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, nested := range v {
+			if _, ok := droppedParams[k]; ok {
+				delete(v, k)
+				continue
+			}
+			if _, ok := redactedParams[k]; ok {
+				v[k] = Redacted
+				continue
+			}
+			redact(nested)
+		}
+	case []interface{}:
+		for _, nested := range v {
+			redact(nested)
+		}
+	}
+}
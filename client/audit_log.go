@@ -0,0 +1,86 @@
+package client
+
+import (
+	"time"
+
+	"github.com/hashicorp/nomad/client/audit"
+)
+
+// auditedRPCs annotates every method of the client's RPC endpoints, unary and
+// streaming, with whether it mutates the client and must be recorded in the
+// audit log. Every method must be listed, so that new endpoints are audited
+// deliberately rather than forgotten.
+var auditedRPCs = map[string]bool{
+	"Agent.Monitor": false,
+	"Agent.Profile": false,
+	"Agent.Host":    false,
+
+	"Allocations.GarbageCollectAll": true,
+	"Allocations.GarbageCollect":    true,
+	"Allocations.Signal":            true,
+	"Allocations.Pause":             true,
+	"Allocations.Resume":            true,
+	"Allocations.Restart":           true,
+	"Allocations.Exec":              true,
+	"Allocations.Stats":             false,
+	"Allocations.CSIMounts":         false,
+
+	"ClientStats.Stats": false,
+
+	"CSI.ControllerValidateVolume": false,
+	"CSI.ControllerAttachVolume":   true,
+	"CSI.ControllerDetachVolume":   true,
+	"CSI.ControllerCreateVolume":   true,
+	"CSI.ControllerDeleteVolume":   true,
+	"CSI.ControllerListVolumes":    false,
+	"CSI.ControllerCreateSnapshot": true,
+	"CSI.ControllerDeleteSnapshot": true,
+	"CSI.ControllerListSnapshots":  false,
+	"CSI.NodeDetachVolume":         true,
+
+	"FileSystem.List":   false,
+	"FileSystem.Stat":   false,
+	"FileSystem.Logs":   false,
+	"FileSystem.Stream": false,
+}
+
+// auditRPC records a mutating call to the audit log, if enabled. It is meant
+// to be deferred by the endpoint with a pointer to its named error result.
+// authToken is the secret ID the call was made with, or empty for calls made
+// by servers on their own behalf.
+func (c *Client) auditRPC(method, authToken string, args interface{}, errp *error) {
+	if c.auditLog == nil {
+		return
+	}
+
+	e := &audit.Entry{
+		Time:     time.Now().UTC(),
+		Endpoint: method,
+		Params:   audit.Params(args),
+		Result:   audit.ResultOK,
+	}
+	if errp != nil && *errp != nil {
+		e.Result = audit.ResultError
+		e.Error = (*errp).Error()
+	}
+
+	if authToken != "" {
+		token, err := c.ResolveSecretToken(authToken)
+		if err != nil {
+			e.Accessor = "unresolved"
+		} else if token != nil {
+			e.Accessor = token.AccessorID
+		}
+	}
+
+	if err := c.auditLog.Record(e); err != nil {
+		c.logger.Error("failed to record call in audit log", "method", method, "error", err)
+	}
+}
+
+// AuditEntries returns a page of the entries of the audit log, oldest first,
+// and the index of the entry starting the next page. It returns none if the
+// audit log is disabled.
+func (c *Client) AuditEntries(start uint64, perPage int) ([]*audit.Entry, uint64, error) {
+	return c.auditLog.Entries(start, perPage)
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/audit"
+	"github.com/hashicorp/nomad/client/config"
+	nstructs "github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+// clientRPCMethods returns the methods of the client's RPC endpoints, as
+// registered with the RPC server, and the type of each unary method.
+func clientRPCMethods(c *Client) (map[string]reflect.Type, []string) {
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+
+	unary := make(map[string]reflect.Type)
+	endpoints := reflect.ValueOf(c.endpoints)
+	for i := 0; i < endpoints.NumField(); i++ {
+		endpoint := endpoints.Field(i).Type()
+		for j := 0; j < endpoint.NumMethod(); j++ {
+			method := endpoint.Method(j)
+			if method.Type.NumIn() != 3 || method.Type.NumOut() != 1 || method.Type.Out(0) != errorType {
+				continue
+			}
+			unary[endpoint.Elem().Name()+"."+method.Name] = method.Type
+		}
+	}
+	return unary, c.streamingRpcs.Methods()
+}
+
+// TestClient_AuditedRPCs asserts that every RPC method of the client is
+// annotated, and that the mutating ones are recorded in the audit log.
+func TestClient_AuditedRPCs(t *testing.T) {
+	t.Parallel()
+
+	client, cleanup := TestClient(t, func(c *config.Config) {
+		c.Audit = &config.AuditConfig{Enabled: true}
+	})
+	defer cleanup()
+
+	unary, streaming := clientRPCMethods(client)
+	var methods []string
+	for method := range unary {
+		methods = append(methods, method)
+	}
+	methods = append(methods, streaming...)
+	sort.Strings(methods)
+
+	for _, method := range methods {
+		_, ok := auditedRPCs[method]
+		require.True(t, ok, "RPC method %s must be annotated in auditedRPCs", method)
+	}
+	require.Len(t, auditedRPCs, len(methods), "auditedRPCs annotates methods that don't exist")
+
+	// Every mutating call is recorded, whether it succeeds or not
+	var mutating []string
+	for _, method := range methods {
+		methodType, ok := unary[method]
+		if !ok {
+			continue
+		}
+		args := reflect.New(methodType.In(1).Elem()).Interface()
+		reply := reflect.New(methodType.In(2).Elem()).Interface()
+		_ = client.ClientRPC(method, args, reply)
+		if auditedRPCs[method] {
+			mutating = append(mutating, method)
+		}
+	}
+
+	entries, _, err := client.AuditEntries(0, 0)
+	require.NoError(t, err)
+	var recorded []string
+	for _, e := range entries {
+		recorded = append(recorded, e.Endpoint)
+		require.Contains(t, []string{audit.ResultOK, audit.ResultError}, e.Result)
+	}
+	require.Equal(t, mutating, recorded)
+}
+
+func TestClient_AuditRPC(t *testing.T) {
+	t.Parallel()
+
+	client, cleanup := TestClient(t, func(c *config.Config) {
+		c.Audit = &config.AuditConfig{Enabled: true}
+	})
+	defer cleanup()
+
+	req := &nstructs.AllocSignalRequest{
+		AllocID: "unknown",
+		Signal:  "SIGHUP",
+		QueryOptions: nstructs.QueryOptions{
+			AuthToken: "secret",
+		},
+	}
+	var resp nstructs.GenericResponse
+	err := client.ClientRPC("Allocations.Signal", req, &resp)
+	require.Error(t, err)
+
+	entries, _, err := client.AuditEntries(0, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	e := entries[0]
+	require.Equal(t, "Allocations.Signal", e.Endpoint)
+	require.Equal(t, audit.ResultError, e.Result)
+	require.Contains(t, e.Error, "Unknown allocation")
+	require.Equal(t, "unknown", e.Params["AllocID"])
+	require.Equal(t, audit.Redacted, e.Params["AuthToken"])
+
+	// Nothing is recorded when auditing is disabled
+	client2, cleanup2 := TestClient(t, nil)
+	defer cleanup2()
+	_ = client2.ClientRPC("Allocations.Signal", req, &resp)
+	entries, _, err = client2.AuditEntries(0, 0)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -28,12 +28,15 @@ import (
 	"github.com/hashicorp/nomad/client/allocrunner"
 	"github.com/hashicorp/nomad/client/allocrunner/interfaces"
 	arstate "github.com/hashicorp/nomad/client/allocrunner/state"
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/getter"
 	"github.com/hashicorp/nomad/client/allocwatcher"
+	"github.com/hashicorp/nomad/client/audit"
 	"github.com/hashicorp/nomad/client/config"
 	consulApi "github.com/hashicorp/nomad/client/consul"
 	"github.com/hashicorp/nomad/client/devicemanager"
 	"github.com/hashicorp/nomad/client/dynamicplugins"
 	"github.com/hashicorp/nomad/client/fingerprint"
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	"github.com/hashicorp/nomad/client/lib/cgutil"
 	"github.com/hashicorp/nomad/client/pluginmanager"
 	"github.com/hashicorp/nomad/client/pluginmanager/csimanager"
@@ -120,6 +123,18 @@ var (
 	batchFirstFingerprintsProcessingGrace = batchFirstFingerprintsTimeout + 5*time.Second
 )
 
+func init() {
+	// COMPAT(1.0) uses inclusive language. whitelist/blacklist are kept for
+	// backward compatibility.
+	config.RegisterOptionKeys(
+		"driver.allowlist", "driver.whitelist",
+		"driver.denylist", "driver.blacklist",
+		"fingerprint.allowlist", "fingerprint.whitelist",
+		"fingerprint.denylist", "fingerprint.blacklist",
+		"csi.cleanup_leaked_mounts", "csi.max_concurrent_ops", "csi.prioritize_ops",
+	)
+}
+
 // ClientStatsReporter exposes all the APIs related to resource usage of a Nomad
 // Client
 type ClientStatsReporter interface {
@@ -146,11 +161,14 @@ type AllocRunner interface {
 	Restore() error
 	Run()
 	StatsReporter() interfaces.AllocStatsReporter
+	CSIMounts() map[string]*cstructs.AllocCSIMount
 	Update(*structs.Allocation)
 	WaitCh() <-chan struct{}
 	DestroyCh() <-chan struct{}
 	ShutdownCh() <-chan struct{}
 	Signal(taskName, signal string) error
+	Pause(taskName, pausedBy string) error
+	Resume(taskName, resumedBy string) error
 	GetTaskEventHandler(taskName string) drivermanager.EventHandler
 	PersistState() error
 
@@ -263,6 +281,10 @@ type Client struct {
 	// in the node automatically
 	garbageCollector *AllocGarbageCollector
 
+	// stateDirReserve keeps disk space free for the client's state when the
+	// state dir shares a filesystem with the alloc dir. Nil if it doesn't.
+	stateDirReserve *stateDirReserve
+
 	// clientACLResolver holds the ACL resolution state
 	clientACLResolver
 
@@ -277,6 +299,22 @@ type Client struct {
 	// csimanager is responsible for managing csi plugins.
 	csimanager csimanager.Manager
 
+	// csiOpScheduler bounds the CSI volume claims and mounts running
+	// concurrently across all allocations
+	csiOpScheduler *csimanager.OpScheduler
+
+	// csiCapacityBudget bounds the total capacity of the CSI volumes
+	// mounted across all allocations
+	csiCapacityBudget *csimanager.CapacityBudget
+
+	// csiWriteClaims tracks the write claims on CSI volumes held across all
+	// allocations
+	csiWriteClaims *csimanager.WriteClaimTracker
+
+	// csiClaimBatch records whether the servers support batched CSI volume
+	// claims, so that allocations stop trying batches once one is rejected
+	csiClaimBatch *csimanager.ClaimBatchSupport
+
 	// devicemanger is responsible for managing device plugins.
 	devicemanager devicemanager.Manager
 
@@ -299,6 +337,10 @@ type Client struct {
 	serversContactedCh   chan struct{}
 	serversContactedOnce sync.Once
 
+	// allocsRestoredCh is closed once allocations have been restored from
+	// the state DB.
+	allocsRestoredCh chan struct{}
+
 	// dynamicRegistry provides access to plugins that are dynamically registered
 	// with a nomad client. Currently only used for CSI.
 	dynamicRegistry dynamicplugins.Registry
@@ -306,6 +348,42 @@ type Client struct {
 	// cpusetManager configures cpusets on supported platforms
 	cpusetManager cgutil.CpusetManager
 
+	// diskIOCollector reads the block device I/O stats of tasks on Linux
+	diskIOCollector *cgutil.DiskIOCollector
+
+	// artifactChecksumPolicy decides whether task artifacts must specify a
+	// checksum. Its exemptions are updated when the config is reloaded.
+	artifactChecksumPolicy *getter.ChecksumPolicy
+
+	// bandwidth shares the node's download bandwidth between artifact
+	// downloads and migrations. It is nil when the bandwidth isn't limited.
+	bandwidth *bandwidth.Manager
+
+	// reloadLock serializes config reloads and their rollbacks
+	reloadLock sync.Mutex
+
+	// reloadIndex is incremented by every applied reload, so that rolling
+	// back a reload that has since been superseded can be skipped. It is
+	// guarded by reloadLock.
+	reloadIndex uint64
+
+	// reloadOutcomes are the outcomes of the last applied reload for each
+	// reloadable subsystem. It is guarded by configLock.
+	reloadOutcomes config.ReloadOutcomes
+
+	// reloadMonitor watches the health of the client after a staged reload.
+	// It is nil when no reload is being observed.
+	reloadMonitor     *reloadMonitor
+	reloadMonitorLock sync.Mutex
+
+	// placementFailures remembers the jobs whose allocations failed on this
+	// node for a reason that will recur
+	placementFailures *placementFailureCache
+
+	// auditLog records mutating calls to the client's endpoints. It is nil
+	// when auditing is disabled.
+	auditLog *audit.Log
+
 	// EnterpriseClient is used to set and check enterprise features for clients
 	EnterpriseClient *EnterpriseClient
 }
@@ -314,7 +392,7 @@ var (
 	// noServersErr is returned by the RPC method when the client has no
 	// configured servers. This is used to trigger Consul discovery if
 	// enabled.
-	noServersErr = errors.New("no servers")
+	noServersErr = structs.ErrNoServers
 )
 
 // NewClient is used to create a new client from the given configuration.
@@ -365,10 +443,15 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulProxie
 		invalidAllocs:        make(map[string]struct{}),
 		serversContactedCh:   make(chan struct{}),
 		serversContactedOnce: sync.Once{},
+		allocsRestoredCh:     make(chan struct{}),
 		cpusetManager:        cgutil.NewCpusetManager(cfg.CgroupParent, logger.Named("cpuset_manager")),
+		placementFailures:    newPlacementFailureCache(cfg.PlacementFailureCacheTTL, cfg.PlacementFailureCacheSize),
 		EnterpriseClient:     newEnterpriseClient(logger),
 	}
 
+	// Bound the connections kept open to the servers
+	c.connPool.SetMaxConns(cfg.MaxServerConnections)
+
 	c.batchNodeUpdates = newBatchNodeUpdates(
 		c.updateNodeFromDriver,
 		c.updateNodeFromDevices,
@@ -419,6 +502,7 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulProxie
 	fingerprintManager := NewFingerprintManager(
 		c.configCopy.PluginSingletonLoader, c.GetConfig, c.configCopy.Node,
 		c.shutdownCh, c.updateNodeFromFingerprint, c.logger)
+	fingerprintManager.errorHook = c.recordFingerprintError
 
 	c.pluginManagers = pluginmanager.New(c.logger)
 
@@ -433,14 +517,28 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulProxie
 	blocklistDrivers := cfg.ReadStringListToMap("driver.denylist", "driver.blacklist")
 
 	// Setup the csi manager
+	_, maxNodeMounts := cfg.CSIVolumeLimits()
 	csiConfig := &csimanager.Config{
 		Logger:                c.logger,
 		DynamicRegistry:       c.dynamicRegistry,
 		UpdateNodeCSIInfoFunc: c.batchNodeUpdates.updateNodeFromCSI,
 		TriggerNodeEvent:      c.triggerNodeEvent,
+		MaxNodeMounts:         maxNodeMounts,
+
+		DegradedPluginMountDelay: cfg.CSIDegradedPluginClaimDelay,
+	}
+	if cfg.ReadBoolDefault("csi.cleanup_leaked_mounts", true) {
+		csiConfig.AllocsRestoredCh = c.allocsRestoredCh
+		csiConfig.LiveAllocs = c.liveAllocIDs
 	}
 	csiManager := csimanager.New(csiConfig)
 	c.csimanager = csiManager
+	c.csiOpScheduler = csimanager.NewOpScheduler(
+		cfg.ReadIntDefault("csi.max_concurrent_ops", 0),
+		cfg.ReadBoolDefault("csi.prioritize_ops", true))
+	c.csiCapacityBudget = csimanager.NewCapacityBudget(cfg.MaxCSIMountedCapacityGB)
+	c.csiWriteClaims = csimanager.NewWriteClaimTracker()
+	c.csiClaimBatch = csimanager.NewClaimBatchSupport()
 	c.pluginManagers.RegisterAndRun(csiManager.PluginManager())
 
 	// Setup the driver manager
@@ -453,6 +551,7 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulProxie
 		State:               c.stateDB,
 		AllowedDrivers:      allowlistDrivers,
 		BlockedDrivers:      blocklistDrivers,
+		HealthThreshold:     c.configCopy.DriverHealthThreshold,
 	}
 	drvManager := drivermanager.New(driverConfig)
 	c.drivermanager = drvManager
@@ -487,14 +586,23 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulProxie
 	statsCollector := stats.NewHostStatsCollector(c.logger, c.config.AllocDir, c.devicemanager.AllStats)
 	c.hostStatsCollector = statsCollector
 
+	// Reserve disk space for the client's state if allocations can fill
+	// its filesystem
+	c.stateDirReserve = newStateDirReserve(c.logger, cfg)
+	c.emitStateDirReserveEvent()
+
 	// Add the garbage collector
+	gcDiskThreshold, gcInodeThreshold := cfg.EffectiveGCThresholds()
 	gcConfig := &GCConfig{
-		MaxAllocs:           cfg.GCMaxAllocs,
-		DiskUsageThreshold:  cfg.GCDiskUsageThreshold,
-		InodeUsageThreshold: cfg.GCInodeUsageThreshold,
-		Interval:            cfg.GCInterval,
-		ParallelDestroys:    cfg.GCParallelDestroys,
-		ReservedDiskMB:      cfg.Node.Reserved.DiskMB,
+		MaxAllocs:             cfg.GCMaxAllocs,
+		MaxAllocsPerNamespace: cfg.GCMaxAllocsPerNamespace,
+		MaxAllocAge:           cfg.GCMaxAllocAge,
+		DiskUsageThreshold:    gcDiskThreshold,
+		InodeUsageThreshold:   gcInodeThreshold,
+		Interval:              cfg.GCInterval,
+		ParallelDestroys:      cfg.GCParallelDestroys,
+		ReservedDiskMB:        cfg.Node.Reserved.DiskMB,
+		StateDirReserveMB:     c.stateDirReserve.MB(),
 	}
 	c.garbageCollector = NewAllocGarbageCollector(c.logger, statsCollector, c, gcConfig)
 	go c.garbageCollector.Run()
@@ -549,6 +657,15 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulProxie
 			"https://github.com/hashicorp/nomad/issues")
 		return nil, fmt.Errorf("failed to restore state")
 	}
+	close(c.allocsRestoredCh)
+
+	// Release the bridge network IPs leased to allocations that no longer
+	// exist, now that the restored allocations are known
+	if runtime.GOOS == "linux" {
+		ipamReconciler := newIPAMReconciler(logger, cfg.GCInterval,
+			cfg.BridgeNetworkIPAMGCDryRun, c.liveAllocIDs, c.shutdownCh)
+		c.shutdownGroup.Go(ipamReconciler.run)
+	}
 
 	// Begin periodic snapshotting of state.
 	c.shutdownGroup.Go(c.periodicSnapshot)
@@ -564,6 +681,12 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulProxie
 	// Start collecting stats
 	c.shutdownGroup.Go(c.emitStats)
 
+	// Every part of the client reading options has registered them by now,
+	// so the options nothing reads are likely misspelled
+	for _, key := range cfg.UnusedOptions() {
+		logger.Warn("unknown client option has no effect, it may be misspelled", "option", key)
+	}
+
 	c.logger.Info("started client", "node_id", c.NodeID())
 	return c, nil
 }
@@ -576,6 +699,22 @@ func (c *Client) Ready() <-chan struct{} {
 // init is used to initialize the client and perform any setup
 // needed before we begin starting its various components.
 func (c *Client) init() error {
+	// Resolve the dynamic port range so the node never advertises an empty
+	// range that no dynamic ports could be allocated from
+	minPort, maxPort, err := c.config.DynamicPortRange()
+	if err != nil {
+		return fmt.Errorf("invalid dynamic port range: %v", err)
+	}
+	c.config.MinDynamicPort, c.config.MaxDynamicPort = minPort, maxPort
+
+	// Render the bridge network's custom CNI config once, so that a broken
+	// config fails here rather than every allocation using the network
+	bridgeCNIConfig, err := allocrunner.LoadBridgeNetworkCNIConfig(c.config)
+	if err != nil {
+		return err
+	}
+	c.config.BridgeNetworkCNIConfig = bridgeCNIConfig
+
 	// Ensure the state dir exists if we have one
 	if c.config.StateDir != "" {
 		if err := os.MkdirAll(c.config.StateDir, 0700); err != nil {
@@ -616,6 +755,15 @@ func (c *Client) init() error {
 
 	c.stateDB = db
 
+	// Open the audit log
+	if conf := c.config.Audit; conf != nil && conf.Enabled {
+		l, err := audit.NewLog(filepath.Join(c.config.StateDir, "audit"), conf.MaxFileSizeMB(), conf.MaxFileCount())
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %v", err)
+		}
+		c.auditLog = l
+	}
+
 	// Ensure the alloc dir exists if we have one
 	if c.config.AllocDir != "" {
 		if err := os.MkdirAll(c.config.AllocDir, 0711); err != nil {
@@ -654,6 +802,7 @@ func (c *Client) init() error {
 		"max", c.config.MaxDynamicPort,
 		"reserved", reserved,
 	)
+	c.logger.Debug("using network configuration", "summary", c.config.NetworkSummary())
 
 	// Ensure cgroups are created on linux platform
 	if runtime.GOOS == "linux" && c.cpusetManager != nil {
@@ -665,6 +814,25 @@ func (c *Client) init() error {
 			c.cpusetManager = cgutil.NoopCpusetManager()
 		}
 	}
+
+	// Task disk I/O stats are read from cgroups on linux
+	if runtime.GOOS == "linux" {
+		c.diskIOCollector = cgutil.NewDiskIOCollector()
+	}
+
+	c.artifactChecksumPolicy = getter.NewChecksumPolicy(
+		c.config.ArtifactRequireChecksum, c.config.ArtifactChecksumExemptPrefixes)
+
+	weights, err := bandwidth.ParseWeights(c.config.NodeDownloadBandwidthWeights)
+	if err != nil {
+		return fmt.Errorf("invalid node_download_bandwidth_weights: %v", err)
+	}
+	c.bandwidth = bandwidth.NewManager(c.logger, c.config.NodeDownloadBandwidthMbps, weights)
+
+	// Tasks requesting a chroot fragment get only the paths that exist here
+	for name, paths := range c.config.MissingChrootFragmentPaths() {
+		c.logger.Debug("chroot fragment paths not found on host", "fragment", name, "paths", paths)
+	}
 	return nil
 }
 
@@ -701,21 +869,6 @@ func (c *Client) reloadTLSConnections(newConfig *nconfig.TLSConfig) error {
 	return nil
 }
 
-// Reload allows a client to reload its configuration on the fly
-func (c *Client) Reload(newConfig *config.Config) error {
-	shouldReloadTLS, err := tlsutil.ShouldReloadRPCConnections(c.config.TLSConfig, newConfig.TLSConfig)
-	if err != nil {
-		c.logger.Error("error parsing TLS configuration", "error", err)
-		return err
-	}
-
-	if shouldReloadTLS {
-		return c.reloadTLSConnections(newConfig.TLSConfig)
-	}
-
-	return nil
-}
-
 // Leave is used to prepare the client to leave the cluster
 func (c *Client) Leave() error {
 	// TODO
@@ -810,6 +963,9 @@ func (c *Client) Shutdown() error {
 
 	// One final save state
 	c.saveState()
+	if err := c.auditLog.Close(); err != nil {
+		c.logger.Warn("failed to close audit log", "error", err)
+	}
 	return c.stateDB.Close()
 }
 
@@ -853,6 +1009,29 @@ func (c *Client) SignalAllocation(allocID, task, signal string) error {
 	return ar.Signal(task, signal)
 }
 
+// PauseAllocation freezes the processes of an allocation's task, or of all of
+// its tasks if task is empty. pausedBy identifies who paused them.
+func (c *Client) PauseAllocation(allocID, task, pausedBy string) error {
+	ar, err := c.getAllocRunner(allocID)
+	if err != nil {
+		return err
+	}
+
+	return ar.Pause(task, pausedBy)
+}
+
+// ResumeAllocation thaws the processes of an allocation's paused task, or of
+// all of its paused tasks if task is empty. resumedBy identifies who resumed
+// them.
+func (c *Client) ResumeAllocation(allocID, task, resumedBy string) error {
+	ar, err := c.getAllocRunner(allocID)
+	if err != nil {
+		return err
+	}
+
+	return ar.Resume(task, resumedBy)
+}
+
 // CollectAllocation garbage collects a single allocation on a node. Returns
 // true if alloc was found and garbage collected; otherwise false.
 func (c *Client) CollectAllocation(allocID string) bool {
@@ -997,6 +1176,16 @@ func (c *Client) GetAllocFS(allocID string) (allocdir.AllocDirFS, error) {
 	return ar.GetAllocDir(), nil
 }
 
+// GetAllocCSIMounts returns where the CSI volumes of an allocation are mounted
+// on this client, by the alias of their volume request.
+func (c *Client) GetAllocCSIMounts(allocID string) (map[string]*cstructs.AllocCSIMount, error) {
+	ar, err := c.getAllocRunner(allocID)
+	if err != nil {
+		return nil, err
+	}
+	return ar.CSIMounts(), nil
+}
+
 // GetAllocState returns a copy of an allocation's state on this client. It
 // returns either an AllocState or an unknown allocation error.
 func (c *Client) GetAllocState(allocID string) (*arstate.State, error) {
@@ -1134,25 +1323,33 @@ func (c *Client) restoreState() error {
 
 		c.configLock.RLock()
 		arConf := &allocrunner.Config{
-			Alloc:               alloc,
-			Logger:              c.logger,
-			ClientConfig:        c.configCopy,
-			StateDB:             c.stateDB,
-			StateUpdater:        c,
-			DeviceStatsReporter: c,
-			Consul:              c.consulService,
-			ConsulSI:            c.tokensClient,
-			ConsulProxies:       c.consulProxies,
-			Vault:               c.vaultClient,
-			PrevAllocWatcher:    prevAllocWatcher,
-			PrevAllocMigrator:   prevAllocMigrator,
-			DynamicRegistry:     c.dynamicRegistry,
-			CSIManager:          c.csimanager,
-			CpusetManager:       c.cpusetManager,
-			DeviceManager:       c.devicemanager,
-			DriverManager:       c.drivermanager,
-			ServersContactedCh:  c.serversContactedCh,
-			RPCClient:           c,
+			Alloc:                  alloc,
+			Logger:                 c.logger,
+			ClientConfig:           c.configCopy,
+			GetClientConfig:        c.GetConfig,
+			StateDB:                c.stateDB,
+			StateUpdater:           c,
+			DeviceStatsReporter:    c,
+			Consul:                 c.consulService,
+			ConsulSI:               c.tokensClient,
+			ConsulProxies:          c.consulProxies,
+			Vault:                  c.vaultClient,
+			PrevAllocWatcher:       prevAllocWatcher,
+			PrevAllocMigrator:      prevAllocMigrator,
+			DynamicRegistry:        c.dynamicRegistry,
+			CSIManager:             c.csimanager,
+			CSIOpScheduler:         c.csiOpScheduler,
+			CSICapacityBudget:      c.csiCapacityBudget,
+			CSIWriteClaims:         c.csiWriteClaims,
+			CSIClaimBatch:          c.csiClaimBatch,
+			CpusetManager:          c.cpusetManager,
+			DiskIOCollector:        c.diskIOCollector,
+			ArtifactChecksumPolicy: c.artifactChecksumPolicy,
+			Bandwidth:              c.bandwidth,
+			DeviceManager:          c.devicemanager,
+			DriverManager:          c.drivermanager,
+			ServersContactedCh:     c.serversContactedCh,
+			RPCClient:              c,
 		}
 		c.configLock.RUnlock()
 
@@ -1284,6 +1481,25 @@ func (c *Client) getAllocRunners() map[string]AllocRunner {
 	return runners
 }
 
+// liveAllocIDs returns the IDs of all allocations the client knows about,
+// including those that failed to restore.
+func (c *Client) liveAllocIDs() map[string]struct{} {
+	c.allocLock.RLock()
+	ids := make(map[string]struct{}, len(c.allocs))
+	for id := range c.allocs {
+		ids[id] = struct{}{}
+	}
+	c.allocLock.RUnlock()
+
+	c.invalidAllocsLock.Lock()
+	for id := range c.invalidAllocs {
+		ids[id] = struct{}{}
+	}
+	c.invalidAllocsLock.Unlock()
+
+	return ids
+}
+
 // NumAllocs returns the number of un-GC'd allocs this client has. Used to
 // fulfill the AllocCounter interface for the GC.
 func (c *Client) NumAllocs() int {
@@ -1417,11 +1633,16 @@ func (c *Client) setupNode() error {
 	}
 	if node.HostVolumes == nil {
 		if l := len(c.config.HostVolumes); l != 0 {
+			warnings, err := c.config.CheckHostVolumePaths()
+			if err != nil {
+				return err
+			}
+			if warnings != nil {
+				c.logger.Warn("registering host volumes with missing paths", "warnings", warnings)
+			}
+
 			node.HostVolumes = make(map[string]*structs.ClientHostVolumeConfig, l)
 			for k, v := range c.config.HostVolumes {
-				if _, err := os.Stat(v.Path); err != nil {
-					return fmt.Errorf("failed to validate volume %s, err: %v", v.Name, err)
-				}
 				node.HostVolumes[k] = v.Copy()
 			}
 		}
@@ -1929,6 +2150,14 @@ func (c *Client) updateNodeStatus() error {
 // AllocStateUpdated asynchronously updates the server with the current state
 // of an allocations and its tasks.
 func (c *Client) AllocStateUpdated(alloc *structs.Allocation) {
+	// Count setup failures against a staged config reload
+	c.recordAllocSetupFailure(alloc)
+
+	// Remember failures that would recur for every allocation of the job
+	if alloc.ClientStatus == structs.AllocClientStatusFailed {
+		c.recordPlacementFailure(alloc)
+	}
+
 	if alloc.Terminated() {
 		// Terminated, mark for GC if we're still tracking this alloc
 		// runner. If it's not being tracked that means the server has
@@ -2292,12 +2521,41 @@ func (c *Client) runAllocs(update *allocUpdates) {
 		errs++
 	}
 
+	// Reject new allocations while the disk space reserved for the client's
+	// state is in use
+	var reserveErr error
+	if len(diff.added) > 0 {
+		reserveErr = c.checkStateDirReserve()
+	}
+
 	// Start the new allocations
 	for _, add := range diff.added {
+		if reserveErr != nil {
+			metrics.IncrCounterWithLabels([]string{"client", "state_dir", "rejected_allocations"}, 1, c.labels())
+			if add.ClientStatus != structs.AllocClientStatusFailed {
+				c.handleInvalidAllocs(add, reserveErr)
+			}
+			continue
+		}
+
+		// Reject allocations of jobs that recently failed on this node for
+		// a reason that would fail them too
+		if failure := c.placementFailures.lookup(add); failure != nil {
+			c.logger.Warn("rejecting allocation of job that recently failed on this node",
+				"alloc_id", add.ID, "job_id", add.JobID, "placement_failure", failure.Code)
+			if add.ClientStatus != structs.AllocClientStatusFailed {
+				c.handleInvalidAllocs(add, failure.rejectionErr())
+			}
+			continue
+		}
+
 		migrateToken := update.migrateTokens[add.ID]
 		if err := c.addAlloc(add, migrateToken); err != nil {
 			c.logger.Error("error adding alloc", "error", err, "alloc_id", add.ID)
 			errs++
+			if code, ok := structs.PlacementFailureCode(err); ok {
+				c.placementFailures.record(add, code, err.Error())
+			}
 			// We mark the alloc as failed and send an update to the server
 			// We track the fact that creating an allocrunner failed so that we don't send updates again
 			if add.ClientStatus != structs.AllocClientStatusFailed {
@@ -2351,6 +2609,7 @@ func makeFailedAlloc(add *structs.Allocation, err error) *structs.Allocation {
 	if taskGroup == nil {
 		return stripped
 	}
+	code, isPlacementFailure := structs.PlacementFailureCode(err)
 	for _, task := range taskGroup.Tasks {
 		ts, ok := stripped.TaskStates[task.Name]
 		if !ok {
@@ -2360,10 +2619,42 @@ func makeFailedAlloc(add *structs.Allocation, err error) *structs.Allocation {
 		if ts.FinishedAt.IsZero() {
 			ts.FinishedAt = failTime
 		}
+
+		// Let the servers know the allocation was rejected, so it's replaced
+		// even once its reschedule policy's attempts are used up
+		if structs.IsAllocRejected(err) {
+			event := structs.NewTaskEvent(structs.TaskSetupFailure).
+				SetSetupError(err).
+				SetFailsTask()
+			event.Details[structs.TaskEventDetailRejected] = "true"
+			ts.Events = append(ts.Events, event)
+		}
+
+		// Let the servers know the allocation failed because of this node
+		if isPlacementFailure {
+			event := structs.NewTaskEvent(structs.TaskSetupFailure).
+				SetSetupError(err).
+				SetFailsTask()
+			event.Details[structs.TaskEventDetailPlacementFailure] = code
+			ts.Events = append(ts.Events, event)
+		}
 	}
 	return stripped
 }
 
+// recordPlacementFailure remembers the failure of alloc if any of its tasks
+// failed for a reason that will recur for every allocation of its job.
+func (c *Client) recordPlacementFailure(alloc *structs.Allocation) {
+	for _, state := range alloc.TaskStates {
+		for _, event := range state.Events {
+			if code, ok := event.Details[structs.TaskEventDetailPlacementFailure]; ok {
+				c.placementFailures.record(alloc, code, event.DisplayMessage)
+				return
+			}
+		}
+	}
+}
+
 // removeAlloc is invoked when we should remove an allocation because it has
 // been removed by the server.
 func (c *Client) removeAlloc(allocID string) {
@@ -2447,6 +2738,7 @@ func (c *Client) addAlloc(alloc *structs.Allocation, migrateToken string) error
 		RPC:              c,
 		Config:           c.configCopy,
 		MigrateToken:     migrateToken,
+		Bandwidth:        c.bandwidth,
 		Logger:           c.logger,
 	}
 	prevAllocWatcher, prevAllocMigrator := allocwatcher.NewAllocWatcher(watcherConfig)
@@ -2456,24 +2748,32 @@ func (c *Client) addAlloc(alloc *structs.Allocation, migrateToken string) error
 	// we don't have to do a copy.
 	c.configLock.RLock()
 	arConf := &allocrunner.Config{
-		Alloc:               alloc,
-		Logger:              c.logger,
-		ClientConfig:        c.configCopy,
-		StateDB:             c.stateDB,
-		Consul:              c.consulService,
-		ConsulProxies:       c.consulProxies,
-		ConsulSI:            c.tokensClient,
-		Vault:               c.vaultClient,
-		StateUpdater:        c,
-		DeviceStatsReporter: c,
-		PrevAllocWatcher:    prevAllocWatcher,
-		PrevAllocMigrator:   prevAllocMigrator,
-		DynamicRegistry:     c.dynamicRegistry,
-		CSIManager:          c.csimanager,
-		CpusetManager:       c.cpusetManager,
-		DeviceManager:       c.devicemanager,
-		DriverManager:       c.drivermanager,
-		RPCClient:           c,
+		Alloc:                  alloc,
+		Logger:                 c.logger,
+		ClientConfig:           c.configCopy,
+		GetClientConfig:        c.GetConfig,
+		StateDB:                c.stateDB,
+		Consul:                 c.consulService,
+		ConsulProxies:          c.consulProxies,
+		ConsulSI:               c.tokensClient,
+		Vault:                  c.vaultClient,
+		StateUpdater:           c,
+		DeviceStatsReporter:    c,
+		PrevAllocWatcher:       prevAllocWatcher,
+		PrevAllocMigrator:      prevAllocMigrator,
+		DynamicRegistry:        c.dynamicRegistry,
+		CSIManager:             c.csimanager,
+		CSIOpScheduler:         c.csiOpScheduler,
+		CSICapacityBudget:      c.csiCapacityBudget,
+		CSIWriteClaims:         c.csiWriteClaims,
+		CSIClaimBatch:          c.csiClaimBatch,
+		CpusetManager:          c.cpusetManager,
+		DiskIOCollector:        c.diskIOCollector,
+		ArtifactChecksumPolicy: c.artifactChecksumPolicy,
+		Bandwidth:              c.bandwidth,
+		DeviceManager:          c.devicemanager,
+		DriverManager:          c.drivermanager,
+		RPCClient:              c,
 	}
 	c.configLock.RUnlock()
 
@@ -2840,7 +3140,11 @@ func (c *Client) emitStats() {
 		select {
 		case <-next.C:
 			err := c.hostStatsCollector.Collect()
+
+			// The interval can be changed by a reload
+			c.configLock.RLock()
 			next.Reset(c.config.StatsCollectionInterval)
+			c.configLock.RUnlock()
 			if err != nil {
 				c.logger.Warn("error fetching host resource usage stats", "error", err)
 			} else if c.config.PublishNodeMetrics {
@@ -3001,6 +3305,14 @@ func (c *Client) emitClientMetrics() {
 	metrics.SetGaugeWithLabels([]string{"client", "allocations", "pending"}, float32(pending), labels)
 	metrics.SetGaugeWithLabels([]string{"client", "allocations", "running"}, float32(running), labels)
 	metrics.SetGaugeWithLabels([]string{"client", "allocations", "terminal"}, float32(terminal), labels)
+
+	if c.stateDirReserve != nil {
+		var reserveBlocking float32
+		if c.stateDirReserve.Blocking() {
+			reserveBlocking = 1
+		}
+		metrics.SetGaugeWithLabels([]string{"client", "state_dir", "reserve_blocking"}, reserveBlocking, labels)
+	}
 }
 
 // labels takes the base labels and appends the node state
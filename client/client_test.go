@@ -758,6 +758,42 @@ func TestClient_Init(t *testing.T) {
 	}
 }
 
+func TestClient_Init_DynamicPortRange(t *testing.T) {
+	t.Parallel()
+
+	newClient := func(min, max int) *Client {
+		config := config.DefaultConfig()
+		config.AllocDir = filepath.Join(t.TempDir(), "alloc")
+		config.StateDir = t.TempDir()
+		config.StateDBFactory = cstate.GetStateDBFactory(true)
+		config.Node = mock.Node()
+		config.MinDynamicPort = min
+		config.MaxDynamicPort = max
+		return &Client{
+			config: config,
+			logger: testlog.HCLogger(t),
+		}
+	}
+
+	// The default range is used when none is configured
+	client := newClient(0, 0)
+	require.NoError(t, client.init())
+	require.Equal(t, structs.DefaultMinDynamicPort, client.config.MinDynamicPort)
+	require.Equal(t, structs.DefaultMaxDynamicPort, client.config.MaxDynamicPort)
+
+	// An inverted range is rejected rather than allocating no ports
+	client = newClient(32000, 20000)
+	err := client.init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid dynamic port range")
+
+	// A range that is inverted by a default bound is rejected
+	client = newClient(0, 15000)
+	err = client.init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "set min_dynamic_port to at most 15000")
+}
+
 func TestClient_BlockedAllocations(t *testing.T) {
 	t.Parallel()
 
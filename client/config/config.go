@@ -4,17 +4,25 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	humanize "github.com/dustin/go-humanize"
 	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/nomad/client/lib/bandwidth"
 	"github.com/hashicorp/nomad/client/lib/cgutil"
 	"github.com/hashicorp/nomad/command/agent/host"
 
 	log "github.com/hashicorp/go-hclog"
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/nomad/client/state"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/helper/pluginutils/loader"
@@ -63,7 +71,54 @@ var (
 		"/run/systemd/resolve": "/run/systemd/resolve",
 	}
 
+	// DefaultChrootFragments maps the names tasks may list in chroot_extras
+	// to the host paths embedded in the task's chroot for them. They matter
+	// when the chroot has been narrowed with chroot_env and no longer
+	// includes all of /usr or /etc.
+	DefaultChrootFragments = map[string][]string{
+		"ca-certificates": {
+			"/etc/ssl/certs",
+			"/etc/pki/tls/certs",
+			"/etc/ca-certificates",
+			"/usr/share/ca-certificates",
+		},
+		"locales": {
+			"/usr/lib/locale",
+			"/usr/share/locale",
+			"/usr/share/i18n",
+		},
+		"zoneinfo": {
+			"/usr/share/zoneinfo",
+		},
+	}
+
 	DefaultTemplateMaxStale = 5 * time.Second
+
+	// DefaultTemplateRestartStageTimeout is the default amount of time to
+	// wait for the tasks in one stage of a lifecycle ordered template restart
+	// to be running again before moving to the next stage.
+	DefaultTemplateRestartStageTimeout = 1 * time.Minute
+
+	// DefaultCSIVolumeMountTimeout is the default amount of time the client
+	// waits for a CSI node plugin to mount a single volume.
+	DefaultCSIVolumeMountTimeout = 2 * time.Minute
+
+	// DefaultMaxFreezeDuration is the default longest time a task may stay
+	// paused before it's resumed automatically.
+	DefaultMaxFreezeDuration = 5 * time.Minute
+
+	// DefaultCSIMaxVolumesPerAlloc is the default maximum number of CSI
+	// volumes a single allocation may request.
+	DefaultCSIMaxVolumesPerAlloc = 16
+
+	// DefaultCSIMaxNodeMounts is the default maximum number of CSI volume
+	// mount paths published on the node at once.
+	DefaultCSIMaxNodeMounts = 256
+
+	// DefaultStateDirReserveMB is the default disk space kept free for the
+	// client's state when the state dir shares a filesystem with the alloc
+	// dir.
+	DefaultStateDirReserveMB = 256
 )
 
 // RPCHandler can be provided to the Client if there is a local server
@@ -118,9 +173,19 @@ type Config struct {
 	// used.
 	MaxKillTimeout time.Duration
 
+	// MaxFreezeDuration is the longest a task may stay paused. Paused tasks
+	// are resumed automatically once it has passed.
+	MaxFreezeDuration time.Duration
+
 	// Servers is a list of known server addresses. These are as "host:port"
 	Servers []string
 
+	// MaxServerConnections is the maximum number of servers the client keeps
+	// connections open to at once. The client rotates among its servers, and
+	// the least recently used connection is closed when a new one is needed.
+	// Zero is unlimited.
+	MaxServerConnections int
+
 	// RPCHandler can be provided to avoid network traffic if the
 	// server is running locally.
 	RPCHandler RPCHandler
@@ -146,6 +211,21 @@ type Config struct {
 	// task's chroot.
 	ChrootEnv map[string]string
 
+	// ChrootEnvMergeDefaults merges the ChrootEnv entries over
+	// DefaultChrootEnv instead of replacing it. An entry with an empty
+	// destination omits the default path.
+	ChrootEnvMergeDefaults bool
+
+	// ChrootFragments maps chroot_extras names to the host paths embedded
+	// in the chroot of tasks listing them. Entries are merged over
+	// DefaultChrootFragments, replacing a default of the same name.
+	ChrootFragments map[string][]string
+
+	// EnvDenylistPerNamespace maps namespaces to the host environment
+	// variables withheld from their tasks, in addition to the env.denylist
+	// option. The list of the empty namespace applies to every namespace.
+	EnvDenylistPerNamespace map[string][]string
+
 	// Options provides arbitrary key-value configuration for nomad internals,
 	// like fingerprinters and drivers. The format is:
 	//
@@ -192,10 +272,28 @@ type Config struct {
 	// beyond which the Nomad client triggers GC of the terminal allocations
 	GCInodeUsageThreshold float64
 
+	// StateDirReserveMB is the disk space in MB kept free for the client's
+	// state when the state dir shares a filesystem with the alloc dir. The
+	// garbage collector lowers GCDiskUsageThreshold by the reserve and new
+	// allocations are rejected while less than the reserve is free. Zero
+	// disables the reserve.
+	StateDirReserveMB int
+
 	// GCMaxAllocs is the maximum number of allocations a node can have
 	// before garbage collection is triggered.
 	GCMaxAllocs int
 
+	// GCMaxAllocsPerNamespace is the maximum number of terminal allocations
+	// of each listed namespace a node can have. The terminal allocations of a
+	// namespace over its limit are garbage collected first, even if the node
+	// is below its other thresholds.
+	GCMaxAllocsPerNamespace map[string]int
+
+	// GCMaxAllocAge is the time after which terminal allocations are garbage
+	// collected, even if the node is below its other thresholds. Zero
+	// disables garbage collection by age.
+	GCMaxAllocAge time.Duration
+
 	// LogLevel is the level of the logs to putout
 	LogLevel string
 
@@ -257,9 +355,29 @@ type Config struct {
 	// notation
 	BridgeNetworkAllocSubnet string
 
+	// BridgeNetworkCNIConfigPath is the path of a CNI config list template
+	// the bridge network is configured with instead of the built-in config.
+	// The bridge name, alloc subnet and iptables admin chain are substituted
+	// into the template.
+	BridgeNetworkCNIConfigPath string
+
+	// BridgeNetworkCNIConfig is the custom CNI config of the bridge network,
+	// rendered from BridgeNetworkCNIConfigPath by the client when it starts.
+	BridgeNetworkCNIConfig []byte
+
+	// BridgeNetworkIPAMGCDryRun logs the IPAM leases of the bridge network
+	// held by allocations that no longer exist on the node instead of
+	// releasing them.
+	BridgeNetworkIPAMGCDryRun bool
+
 	// HostVolumes is a map of the configured host volumes by name.
 	HostVolumes map[string]*structs.ClientHostVolumeConfig
 
+	// HostVolumeAllowMissingReadOnly logs a warning for read-only host
+	// volumes whose path is missing when the client starts, rather than
+	// failing to start.
+	HostVolumeAllowMissingReadOnly bool
+
 	// HostNetworks is a map of the conigured host networks by name.
 	HostNetworks map[string]*structs.ClientHostNetworkConfig
 
@@ -270,7 +388,10 @@ type Config struct {
 	// is when a network loadbalancer is utilizing direct server return and the destination
 	// address of incomming packets does not match the IP address of the host interface.
 	//
-	// This configuration is only considered if no host networks are defined.
+	// This configuration applies to the default host network whether or not other host
+	// networks are defined. Host networks can bind the wildcard address with BindWildcard.
+	// The agent only sets it alongside host networks when bind_wildcard_default_host_network
+	// is set explicitly.
 	BindWildcardDefaultHostNetwork bool
 
 	// CgroupParent is the parent cgroup Nomad should use when managing any cgroup subsystems.
@@ -279,6 +400,410 @@ type Config struct {
 
 	// ReservableCores if set overrides the set of reservable cores reported in fingerprinting.
 	ReservableCores []uint16
+
+	// CSIVolumeMountTimeout is the maximum amount of time to wait for a CSI
+	// node plugin to mount a single volume for an allocation.
+	CSIVolumeMountTimeout time.Duration
+
+	// CSIPluginRegistrationWait is how long an allocation waits for a CSI
+	// node plugin that hasn't registered on the node yet before failing to
+	// claim or mount its volumes, within the claim and mount timeouts. Zero
+	// fails immediately.
+	CSIPluginRegistrationWait time.Duration
+
+	// CSIDegradedPluginClaimDelay is the minimum delay between the CSI volume
+	// mounts of a node plugin while it is degraded, in which case the mounts
+	// of all of the allocations on the node are made one at a time. Zero
+	// disables the backpressure.
+	CSIDegradedPluginClaimDelay time.Duration
+
+	// CSIVolumeHealthCheck fails the mount of a CSI volume early when the
+	// volume returned by its claim is reported as unhealthy, rather than
+	// discovering an unavailable backend midway through the mount.
+	CSIVolumeHealthCheck bool
+
+	// CSIMountMetadata writes a file describing the allocation next to each
+	// of its CSI volume mounts, so that external tools can find the
+	// allocation using a mount.
+	CSIMountMetadata bool
+
+	// CSIClaimTopology sends the topologies the node's CSI plugins
+	// fingerprinted with each volume claim, and fails the mount of a claimed
+	// volume that isn't accessible from the topology of its plugin.
+	CSIClaimTopology bool
+
+	// CSIClaimRetry configures the retries of CSI volume claims failing
+	// with transient errors, such as while the servers elect a leader.
+	// Unset fields default to those of DefaultCSIClaimRetry.
+	CSIClaimRetry *RetryConfig
+
+	// CSIUnpublishRetry configures the retries of the requests releasing an
+	// allocation's CSI volume claims when it stops. Unset fields default to
+	// those of DefaultCSIUnpublishRetry.
+	CSIUnpublishRetry *RetryConfig
+
+	// CSIClaimLabelEnv maps the names of labels attached to CSI volume
+	// claims to the environment variables of the client their values are
+	// read from. Unset environment variables are not attached.
+	CSIClaimLabelEnv map[string]string
+
+	// CSIMaxVolumesPerAlloc is the maximum number of CSI volumes a single
+	// allocation may request. Allocations requesting more fail before any
+	// volume is claimed.
+	CSIMaxVolumesPerAlloc int
+
+	// CSIMaxNodeMounts is the maximum number of CSI volume mount paths
+	// published on the node at once, across all node plugins.
+	CSIMaxNodeMounts int
+
+	// MaxCSIMountedCapacityGB is the maximum total capacity, in gigabytes,
+	// of the CSI volumes mounted on the node at once. Claims that would
+	// exceed it are rejected. Zero doesn't limit the mounted capacity.
+	MaxCSIMountedCapacityGB int
+
+	// CSIIdempotencyKeys persists each allocation's CSI volume claims before
+	// they are sent, along with whether they were released, so that a
+	// restarted client never releases a claim twice nor leaves an interrupted
+	// claim behind. It's enforced by the client alone, as servers don't
+	// deduplicate claim and unpublish requests.
+	CSIIdempotencyKeys bool
+
+	// MaxTaskTmpfsMB is the maximum size, in megabytes, of the tmpfs scratch
+	// directory a task on the node may request. Zero disables task tmpfs.
+	MaxTaskTmpfsMB int
+
+	// DisableOptionEnvInterpolation disables the expansion of environment
+	// variable references in Options values, so that values are used as
+	// written.
+	DisableOptionEnvInterpolation bool
+
+	// ArtifactRequireChecksum rejects task artifacts that do not specify a
+	// checksum.
+	ArtifactRequireChecksum bool
+
+	// ArtifactChecksumExemptPrefixes are artifact URL prefixes that may be
+	// downloaded without a checksum even when checksums are required.
+	ArtifactChecksumExemptPrefixes []string
+
+	// NodeDownloadBandwidthMbps limits the combined bandwidth in Mbps of
+	// artifact downloads and migrations of previous allocations' data. Image
+	// pulls aren't throttled. Zero disables the limit.
+	NodeDownloadBandwidthMbps int
+
+	// NodeDownloadBandwidthWeights are the relative shares of the download
+	// bandwidth of the "artifact" and "migrate" classes of traffic
+	// when they contend for it. Classes without a weight default to 1.
+	NodeDownloadBandwidthWeights map[string]int
+
+	// CoreDumps configures the collection of core files dumped by crashed
+	// tasks.
+	CoreDumps *CoreDumpConfig
+
+	// ReloadObservationWindow is how long the client watches its health
+	// after a config reload. When set, reloads are staged: the new config is
+	// dry-run before it is applied, and it is rolled back to the previous
+	// config if failures spike during the window.
+	ReloadObservationWindow time.Duration
+
+	// ReloadRollbackThreshold is the number of failures observed during the
+	// ReloadObservationWindow that rolls back a reload.
+	ReloadRollbackThreshold int
+
+	// PlacementFailureCacheTTL is how long the client remembers that a task
+	// group failed to be placed on it for a reason that will recur,
+	// rejecting further allocations of the same version of the task group
+	// in the meantime. Zero disables the cache.
+	PlacementFailureCacheTTL time.Duration
+
+	// PlacementFailureCacheSize is the maximum number of placement failures
+	// remembered.
+	PlacementFailureCacheSize int
+
+	// DriverHealthThresholds configures, for each driver, when failed task
+	// starts mark the driver degraded. Drivers that aren't listed use the
+	// default threshold.
+	DriverHealthThresholds map[string]*DriverHealthThreshold
+
+	// Audit configures the audit log of mutating calls to the client's
+	// endpoints.
+	Audit *AuditConfig
+}
+
+const (
+	// DefaultCoreDumpMaxSizeMB is the default size at which core files are
+	// truncated.
+	DefaultCoreDumpMaxSizeMB = 512
+
+	// DefaultCoreDumpRetain is the default number of core files kept for
+	// each task.
+	DefaultCoreDumpRetain = 3
+
+	// DefaultAuditMaxFileMB is the default size at which audit log files
+	// are rotated.
+	DefaultAuditMaxFileMB = 10
+
+	// DefaultAuditMaxFiles is the default number of audit log files kept.
+	DefaultAuditMaxFiles = 5
+
+	// DefaultReloadRollbackThreshold is the default number of failures
+	// observed after a staged reload that rolls it back.
+	DefaultReloadRollbackThreshold = 3
+
+	// DefaultPlacementFailureCacheSize is the default maximum number of
+	// placement failures remembered.
+	DefaultPlacementFailureCacheSize = 256
+
+	// DefaultDriverHealthWindow is the default window over which the task
+	// starts of a driver are tracked.
+	DefaultDriverHealthWindow = 5 * time.Minute
+
+	// DefaultDriverHealthMaxFailures is the default number of failed task
+	// starts within the window that marks a driver degraded.
+	DefaultDriverHealthMaxFailures = 3
+
+	// DefaultDriverHealthRecoveryPeriod is the default time a degraded
+	// driver's task starts must not fail for before it is no longer
+	// degraded.
+	DefaultDriverHealthRecoveryPeriod = 10 * time.Minute
+
+	// DefaultBridgeNetworkName is the name of the bridge created in bridge
+	// networking mode when BridgeNetworkName is unset.
+	DefaultBridgeNetworkName = "nomad"
+
+	// DefaultBridgeNetworkAllocSubnet is the subnet addresses are allocated
+	// from in bridge networking mode when BridgeNetworkAllocSubnet is unset.
+	DefaultBridgeNetworkAllocSubnet = "172.26.64.0/20" // end 172.26.79.255
+
+	// DefaultCNIPath is the path CNI plugins are searched in when CNIPath and
+	// the CNI_PATH environment variable are unset.
+	DefaultCNIPath = "/opt/cni/bin"
+
+	// DefaultCNIConfigDir is the default directory of the CNI network
+	// configurations.
+	DefaultCNIConfigDir = "/opt/cni/config"
+
+	// DefaultCNIInterfacePrefix is the prefix of the network interfaces CNI
+	// creates when CNIInterfacePrefix is unset.
+	DefaultCNIInterfacePrefix = "eth"
+)
+
+// DriverHealthThreshold configures when the failed task starts of a driver
+// mark it degraded. A degraded driver is still healthy, but the scheduler
+// prefers other nodes for the tasks that use it.
+type DriverHealthThreshold struct {
+	// Driver is the name of the driver the threshold applies to.
+	Driver string `hcl:",key"`
+
+	// Window is the rolling window over which task starts are tracked.
+	// Defaults to DefaultDriverHealthWindow.
+	Window    time.Duration `hcl:"-"`
+	WindowHCL string        `hcl:"window" json:"-"`
+
+	// MaxFailures is the number of failed task starts within the window
+	// that marks the driver degraded. Defaults to
+	// DefaultDriverHealthMaxFailures.
+	MaxFailures int `hcl:"max_failures"`
+
+	// RecoveryPeriod is how long task starts must not fail for before a
+	// degraded driver is no longer degraded. Defaults to
+	// DefaultDriverHealthRecoveryPeriod.
+	RecoveryPeriod    time.Duration `hcl:"-"`
+	RecoveryPeriodHCL string        `hcl:"recovery_period" json:"-"`
+}
+
+// DefaultDriverHealthThreshold returns the threshold used for drivers that
+// aren't configured.
+func DefaultDriverHealthThreshold() *DriverHealthThreshold {
+	return &DriverHealthThreshold{
+		Window:         DefaultDriverHealthWindow,
+		MaxFailures:    DefaultDriverHealthMaxFailures,
+		RecoveryPeriod: DefaultDriverHealthRecoveryPeriod,
+	}
+}
+
+// Copy returns a copy of the DriverHealthThreshold.
+func (t *DriverHealthThreshold) Copy() *DriverHealthThreshold {
+	if t == nil {
+		return nil
+	}
+	nt := *t
+	return &nt
+}
+
+// Merge merges two DriverHealthThresholds. Non-zero values of b take
+// precedence.
+func (t *DriverHealthThreshold) Merge(b *DriverHealthThreshold) *DriverHealthThreshold {
+	if t == nil {
+		return b.Copy()
+	}
+
+	result := *t
+	if b == nil {
+		return &result
+	}
+	if b.Driver != "" {
+		result.Driver = b.Driver
+	}
+	if b.Window != 0 {
+		result.Window = b.Window
+	}
+	if b.WindowHCL != "" {
+		result.WindowHCL = b.WindowHCL
+	}
+	if b.MaxFailures != 0 {
+		result.MaxFailures = b.MaxFailures
+	}
+	if b.RecoveryPeriod != 0 {
+		result.RecoveryPeriod = b.RecoveryPeriod
+	}
+	if b.RecoveryPeriodHCL != "" {
+		result.RecoveryPeriodHCL = b.RecoveryPeriodHCL
+	}
+	return &result
+}
+
+// DriverHealthThreshold returns the threshold at which failed task starts
+// mark the driver degraded, using the defaults for the fields that aren't
+// configured.
+func (c *Config) DriverHealthThreshold(driver string) *DriverHealthThreshold {
+	threshold := DefaultDriverHealthThreshold().Merge(c.DriverHealthThresholds[driver])
+	threshold.Driver = driver
+	return threshold
+}
+
+// CoreDumpConfig configures the collection of core files dumped by tasks run
+// by the exec, java and raw_exec drivers.
+type CoreDumpConfig struct {
+	// Enabled sets the core file size limit of tasks and collects the core
+	// files they dump.
+	Enabled bool `hcl:"enabled"`
+
+	// MaxSizeMB is the size at which core files are truncated. Defaults to
+	// DefaultCoreDumpMaxSizeMB.
+	MaxSizeMB int `hcl:"max_size_mb"`
+
+	// Dir is the directory core files are collected into, relative to the
+	// allocation directory, under a directory for each task. If empty, core
+	// files are collected into the cores directory of the task's local
+	// directory.
+	Dir string `hcl:"dir"`
+
+	// Retain is the number of core files kept for each task, after which
+	// the oldest are removed. Defaults to DefaultCoreDumpRetain.
+	Retain int `hcl:"retain"`
+}
+
+// Copy returns a copy of the CoreDumpConfig.
+func (c *CoreDumpConfig) Copy() *CoreDumpConfig {
+	if c == nil {
+		return nil
+	}
+	nc := *c
+	return &nc
+}
+
+// Merge merges two CoreDumpConfigs. Non-zero values of b take precedence.
+func (c *CoreDumpConfig) Merge(b *CoreDumpConfig) *CoreDumpConfig {
+	if c == nil {
+		return b.Copy()
+	}
+
+	result := *c
+	if b == nil {
+		return &result
+	}
+	if b.Enabled {
+		result.Enabled = true
+	}
+	if b.MaxSizeMB != 0 {
+		result.MaxSizeMB = b.MaxSizeMB
+	}
+	if b.Dir != "" {
+		result.Dir = b.Dir
+	}
+	if b.Retain != 0 {
+		result.Retain = b.Retain
+	}
+	return &result
+}
+
+// MaxSizeBytes returns the size in bytes at which core files are truncated.
+func (c *CoreDumpConfig) MaxSizeBytes() int64 {
+	if c.MaxSizeMB <= 0 {
+		return DefaultCoreDumpMaxSizeMB * 1024 * 1024
+	}
+	return int64(c.MaxSizeMB) * 1024 * 1024
+}
+
+// RetainCount returns the number of core files kept for each task.
+func (c *CoreDumpConfig) RetainCount() int {
+	if c.Retain <= 0 {
+		return DefaultCoreDumpRetain
+	}
+	return c.Retain
+}
+
+// AuditConfig configures the audit log of mutating calls to the client's
+// endpoints, such as signalling allocations or detaching CSI volumes.
+type AuditConfig struct {
+	// Enabled records mutating calls to the audit log in the client's state
+	// directory.
+	Enabled bool `hcl:"enabled"`
+
+	// MaxFileMB is the size at which audit log files are rotated. Defaults
+	// to DefaultAuditMaxFileMB.
+	MaxFileMB int `hcl:"max_file_mb"`
+
+	// MaxFiles is the number of audit log files kept, after which the
+	// oldest is removed. Defaults to DefaultAuditMaxFiles.
+	MaxFiles int `hcl:"max_files"`
+}
+
+// Copy returns a copy of the AuditConfig.
+func (c *AuditConfig) Copy() *AuditConfig {
+	if c == nil {
+		return nil
+	}
+	nc := *c
+	return &nc
+}
+
+// Merge merges two AuditConfigs. Non-zero values of b take precedence.
+func (c *AuditConfig) Merge(b *AuditConfig) *AuditConfig {
+	if c == nil {
+		return b.Copy()
+	}
+
+	result := *c
+	if b == nil {
+		return &result
+	}
+	if b.Enabled {
+		result.Enabled = true
+	}
+	if b.MaxFileMB != 0 {
+		result.MaxFileMB = b.MaxFileMB
+	}
+	if b.MaxFiles != 0 {
+		result.MaxFiles = b.MaxFiles
+	}
+	return &result
+}
+
+// MaxFileSizeMB returns the size in MB at which audit log files are rotated.
+func (c *AuditConfig) MaxFileSizeMB() int {
+	if c.MaxFileMB <= 0 {
+		return DefaultAuditMaxFileMB
+	}
+	return c.MaxFileMB
+}
+
+// MaxFileCount returns the number of audit log files kept.
+func (c *AuditConfig) MaxFileCount() int {
+	if c.MaxFiles <= 0 {
+		return DefaultAuditMaxFiles
+	}
+	return c.MaxFiles
 }
 
 // ClientTemplateConfig is configuration on the client specific to template
@@ -292,11 +817,21 @@ type ClientTemplateConfig struct {
 	// v0.25.0 - function_blacklist is kept for compatibility
 	FunctionBlacklist []string `hcl:"function_blacklist"`
 
+	// FunctionAllowlist enables only the listed functions in
+	// consul-template, disabling all others. It may not be set together
+	// with FunctionDenylist.
+	FunctionAllowlist []string `hcl:"function_allowlist"`
+
 	// DisableSandbox allows templates to access arbitrary files on the
 	// client host. By default templates can access files only within
 	// the task directory.
 	DisableSandbox bool `hcl:"disable_file_sandbox"`
 
+	// AllowExecutableRenders allows templates to set permissions that make
+	// the rendered file executable. By default such templates are rejected,
+	// since rendering a script that the task then runs is a security risk.
+	AllowExecutableRenders bool `hcl:"allow_executable_renders"`
+
 	// This is the maximum interval to allow "stale" data. By default, only the
 	// Consul leader will respond to queries; any requests to a follower will
 	// forward to the leader. In large clusters with many requests, this is not as
@@ -344,8 +879,71 @@ type ClientTemplateConfig struct {
 	// to wait for the cluster to become available, as is customary in distributed
 	// systems.
 	VaultRetry *RetryConfig `hcl:"vault_retry,optional"`
+
+	// VaultRetries overrides VaultRetry for the Vault namespaces templates
+	// read secrets from, so that Vault clusters served under different
+	// namespaces can be retried differently. It is keyed by namespace, and
+	// each entry is layered over VaultRetry. Namespaces without an entry use
+	// VaultRetry.
+	VaultRetries map[string]*RetryConfig `hcl:"vault_retries,optional"`
+
+	// This controls the retry behavior when an error is returned from Nomad,
+	// for templates using Nomad native lookups such as Nomad services. The
+	// vendored consul-template doesn't make Nomad lookups yet, so it's only
+	// validated and has no effect until it does.
+	NomadRetry *RetryConfig `hcl:"nomad_retry,optional"`
+
+	// RestartStageTimeout is the maximum amount of time to wait for the tasks
+	// in one stage of a lifecycle ordered template restart to be running
+	// again before the next stage is restarted.
+	RestartStageTimeout    *time.Duration `hcl:"-"`
+	RestartStageTimeoutHCL string         `hcl:"restart_stage_timeout,optional" json:"-"`
+
+	// RenderDiffs logs how a template's rendered contents changed when it
+	// re-renders, to help identify noisy dependencies. It may be "hash" to
+	// log a hash of the contents before and after the change, or "diff" to
+	// log a line diff of the contents. Changes are logged at the debug level.
+	RenderDiffs string `hcl:"render_diffs,optional"`
+
+	// MaxRenderSizeBytes is the largest a template may render, in bytes.
+	// Tasks rendering a larger template fail. Zero is unlimited.
+	MaxRenderSizeBytes *int64 `hcl:"-"`
+	MaxRenderSizeHCL   string `hcl:"max_render_size,optional" json:"-"`
+
+	// MaxRenderCPUTime is the most CPU time rendering a template may use.
+	// Each template is rendered in a separate process whose RLIMIT_CPU stops
+	// it when a render uses more, failing the task. RLIMIT_CPU counts all the
+	// process's CPU time, including fetching the template's data, in whole
+	// seconds, so this is rounded up and can't be under a second. Only
+	// supported on Linux. Zero is unlimited.
+	MaxRenderCPUTime    *time.Duration `hcl:"-"`
+	MaxRenderCPUTimeHCL string         `hcl:"max_render_cpu_time,optional" json:"-"`
+
+	// MaxTemplatesPerTask is the most templates a task may have. Tasks with
+	// more templates fail. Zero is unlimited.
+	MaxTemplatesPerTask *int `hcl:"max_templates_per_task,optional"`
+
+	// ValidateRenderedUTF8 fails tasks rendering a template that isn't valid
+	// UTF-8, such as a binary secret written to a text file.
+	ValidateRenderedUTF8 bool `hcl:"validate_rendered_utf8,optional"`
+
+	// ConsulNamespace is the Consul namespace templates read from when the
+	// task's group doesn't set one, overriding the namespace of the client's
+	// Consul configuration. This allows templates to read from a namespace
+	// other than the one the client registers services in.
+	ConsulNamespace string `hcl:"consul_namespace,optional"`
 }
 
+const (
+	// TemplateRenderDiffsHash logs a hash of a template's contents before
+	// and after it re-renders.
+	TemplateRenderDiffsHash = "hash"
+
+	// TemplateRenderDiffsFull logs a line diff of a template's contents when
+	// it re-renders. The diff may include secrets read by the template.
+	TemplateRenderDiffsFull = "diff"
+)
+
 // Copy returns a deep copy of a ClientTemplateConfig
 func (c *ClientTemplateConfig) Copy() *ClientTemplateConfig {
 	if c == nil {
@@ -355,19 +953,24 @@ func (c *ClientTemplateConfig) Copy() *ClientTemplateConfig {
 	nc := new(ClientTemplateConfig)
 	*nc = *c
 	nc.FunctionDenylist = helper.CopySliceString(nc.FunctionDenylist)
+	nc.FunctionAllowlist = helper.CopySliceString(nc.FunctionAllowlist)
 
 	if c.BlockQueryWaitTime != nil {
-		nc.BlockQueryWaitTime = &*c.BlockQueryWaitTime
+		nc.BlockQueryWaitTime = helper.TimeToPtr(*c.BlockQueryWaitTime)
 	}
 
 	if c.MaxStale != nil {
-		nc.MaxStale = &*c.MaxStale
+		nc.MaxStale = helper.TimeToPtr(*c.MaxStale)
 	}
 
 	if c.Wait != nil {
 		nc.Wait = c.Wait.Copy()
 	}
 
+	if c.WaitBounds != nil {
+		nc.WaitBounds = c.WaitBounds.Copy()
+	}
+
 	if c.ConsulRetry != nil {
 		nc.ConsulRetry = c.ConsulRetry.Copy()
 	}
@@ -376,6 +979,33 @@ func (c *ClientTemplateConfig) Copy() *ClientTemplateConfig {
 		nc.VaultRetry = c.VaultRetry.Copy()
 	}
 
+	if c.NomadRetry != nil {
+		nc.NomadRetry = c.NomadRetry.Copy()
+	}
+
+	if c.VaultRetries != nil {
+		nc.VaultRetries = make(map[string]*RetryConfig, len(c.VaultRetries))
+		for namespace, retry := range c.VaultRetries {
+			nc.VaultRetries[namespace] = retry.Copy()
+		}
+	}
+
+	if c.RestartStageTimeout != nil {
+		nc.RestartStageTimeout = helper.TimeToPtr(*c.RestartStageTimeout)
+	}
+
+	if c.MaxRenderSizeBytes != nil {
+		nc.MaxRenderSizeBytes = helper.Int64ToPtr(*c.MaxRenderSizeBytes)
+	}
+
+	if c.MaxRenderCPUTime != nil {
+		nc.MaxRenderCPUTime = helper.TimeToPtr(*c.MaxRenderCPUTime)
+	}
+
+	if c.MaxTemplatesPerTask != nil {
+		nc.MaxTemplatesPerTask = helper.IntToPtr(*c.MaxTemplatesPerTask)
+	}
+
 	return nc
 }
 
@@ -383,17 +1013,17 @@ func (c *ClientTemplateConfig) Copy() *ClientTemplateConfig {
 // instance, and then overrides those values with the instance to merge with.
 func (c *ClientTemplateConfig) Merge(b *ClientTemplateConfig) *ClientTemplateConfig {
 	if c == nil {
-		return b
+		return b.Copy()
 	}
 
-	result := *c
+	result := c.Copy()
 
 	if b == nil {
-		return &result
+		return result
 	}
 
 	if b.BlockQueryWaitTime != nil {
-		result.BlockQueryWaitTime = b.BlockQueryWaitTime
+		result.BlockQueryWaitTime = helper.TimeToPtr(*b.BlockQueryWaitTime)
 	}
 	if b.BlockQueryWaitTimeHCL != "" {
 		result.BlockQueryWaitTimeHCL = b.BlockQueryWaitTimeHCL
@@ -403,27 +1033,28 @@ func (c *ClientTemplateConfig) Merge(b *ClientTemplateConfig) *ClientTemplateCon
 		result.ConsulRetry = result.ConsulRetry.Merge(b.ConsulRetry)
 	}
 
-	result.DisableSandbox = b.DisableSandbox
+	if b.DisableSandbox {
+		result.DisableSandbox = true
+	}
 
-	// Maintain backward compatibility for older clients
-	if len(b.FunctionBlacklist) > 0 {
-		for _, fn := range b.FunctionBlacklist {
-			if !helper.SliceStringContains(result.FunctionBlacklist, fn) {
-				result.FunctionBlacklist = append(result.FunctionBlacklist, fn)
-			}
-		}
+	if b.AllowExecutableRenders {
+		result.AllowExecutableRenders = true
 	}
 
-	if len(b.FunctionDenylist) > 0 {
-		for _, fn := range b.FunctionDenylist {
-			if !helper.SliceStringContains(result.FunctionDenylist, fn) {
-				result.FunctionDenylist = append(result.FunctionDenylist, fn)
-			}
-		}
+	// Maintain backward compatibility for older clients
+	result.FunctionBlacklist = mergeFunctionLists(result.FunctionBlacklist, b.FunctionBlacklist)
+
+	// An allowlist replaces any denylist it is layered on top of, such as
+	// the default denylist, so that it can be set on its own
+	if len(b.FunctionAllowlist) > 0 {
+		result.FunctionAllowlist = mergeFunctionLists(result.FunctionAllowlist, b.FunctionAllowlist)
+		result.FunctionDenylist = helper.CopySliceString(b.FunctionDenylist)
+	} else {
+		result.FunctionDenylist = mergeFunctionLists(result.FunctionDenylist, b.FunctionDenylist)
 	}
 
 	if b.MaxStale != nil {
-		result.MaxStale = b.MaxStale
+		result.MaxStale = helper.TimeToPtr(*b.MaxStale)
 	}
 
 	if b.MaxStaleHCL != "" {
@@ -442,119 +1073,410 @@ func (c *ClientTemplateConfig) Merge(b *ClientTemplateConfig) *ClientTemplateCon
 		result.VaultRetry = result.VaultRetry.Merge(b.VaultRetry)
 	}
 
-	return &result
+	if b.NomadRetry != nil {
+		result.NomadRetry = result.NomadRetry.Merge(b.NomadRetry)
+	}
+
+	if len(b.VaultRetries) > 0 {
+		if result.VaultRetries == nil {
+			result.VaultRetries = make(map[string]*RetryConfig, len(b.VaultRetries))
+		}
+		for namespace, retry := range b.VaultRetries {
+			result.VaultRetries[namespace] = result.VaultRetries[namespace].Merge(retry)
+		}
+	}
+
+	if b.RestartStageTimeout != nil {
+		result.RestartStageTimeout = helper.TimeToPtr(*b.RestartStageTimeout)
+	}
+
+	if b.RestartStageTimeoutHCL != "" {
+		result.RestartStageTimeoutHCL = b.RestartStageTimeoutHCL
+	}
+
+	if b.RenderDiffs != "" {
+		result.RenderDiffs = b.RenderDiffs
+	}
+
+	if b.MaxRenderSizeBytes != nil {
+		result.MaxRenderSizeBytes = helper.Int64ToPtr(*b.MaxRenderSizeBytes)
+	}
+
+	if b.MaxRenderSizeHCL != "" {
+		result.MaxRenderSizeHCL = b.MaxRenderSizeHCL
+	}
+
+	if b.MaxRenderCPUTime != nil {
+		result.MaxRenderCPUTime = helper.TimeToPtr(*b.MaxRenderCPUTime)
+	}
+
+	if b.MaxRenderCPUTimeHCL != "" {
+		result.MaxRenderCPUTimeHCL = b.MaxRenderCPUTimeHCL
+	}
+
+	if b.MaxTemplatesPerTask != nil {
+		result.MaxTemplatesPerTask = helper.IntToPtr(*b.MaxTemplatesPerTask)
+	}
+
+	if b.ValidateRenderedUTF8 {
+		result.ValidateRenderedUTF8 = true
+	}
+
+	if b.ConsulNamespace != "" {
+		result.ConsulNamespace = b.ConsulNamespace
+	}
+
+	return result
+}
+
+// mergeFunctionLists returns the union of two lists of template functions,
+// without duplicates. a is copied rather than appended to, so that merging
+// never modifies the receiver's list.
+func mergeFunctionLists(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+
+	result := helper.CopySliceString(a)
+	for _, fn := range b {
+		if !helper.SliceStringContains(result, fn) {
+			result = append(result, fn)
+		}
+	}
+	return result
+}
+
+// Equals returns the result of reflect.DeepEqual
+func (c *ClientTemplateConfig) Equals(other *ClientTemplateConfig) bool {
+	return reflect.DeepEqual(c, other)
 }
 
+// IsEmpty returns true if the receiver has no fields set.
 func (c *ClientTemplateConfig) IsEmpty() bool {
 	if c == nil {
 		return true
 	}
 
-	return c.BlockQueryWaitTime == nil &&
+	return !c.DisableSandbox &&
+		!c.AllowExecutableRenders &&
+		len(c.FunctionDenylist) == 0 &&
+		len(c.FunctionBlacklist) == 0 &&
+		c.BlockQueryWaitTime == nil &&
 		c.BlockQueryWaitTimeHCL == "" &&
 		c.MaxStale == nil &&
 		c.MaxStaleHCL == "" &&
 		c.Wait.IsEmpty() &&
+		c.WaitBounds.IsEmpty() &&
 		c.ConsulRetry.IsEmpty() &&
-		c.VaultRetry.IsEmpty()
+		c.VaultRetry.IsEmpty() &&
+		len(c.VaultRetries) == 0 &&
+		c.NomadRetry.IsEmpty() &&
+		c.RestartStageTimeout == nil &&
+		c.RestartStageTimeoutHCL == "" &&
+		c.RenderDiffs == "" &&
+		c.MaxRenderSizeBytes == nil &&
+		c.MaxRenderSizeHCL == "" &&
+		c.MaxRenderCPUTime == nil &&
+		c.MaxRenderCPUTimeHCL == "" &&
+		c.MaxTemplatesPerTask == nil &&
+		!c.ValidateRenderedUTF8 &&
+		c.ConsulNamespace == "" &&
+		len(c.FunctionAllowlist) == 0
 }
 
-// WaitConfig is mirrored from templateconfig.WaitConfig because we need to handle
-// the HCL conversion which happens in agent.ParseConfigFile
-// NOTE: Since Consul Template requires pointers, this type uses pointers to fields
-// which is inconsistent with how Nomad typically works. This decision was made
-// to maintain parity with the external subsystem, not to establish a new standard.
-type WaitConfig struct {
-	Min    *time.Duration `hcl:"-"`
-	MinHCL string         `hcl:"min,optional" json:"-"`
-	Max    *time.Duration `hcl:"-"`
-	MaxHCL string         `hcl:"max,optional" json:"-"`
+// consulTemplateFunctions are the functions consul-template provides to
+// templates, which a FunctionAllowlist is applied to.
+var consulTemplateFunctions = []string{
+	// API functions
+	"datacenters", "file", "key", "keyExists", "keyOrDefault", "ls",
+	"safeLs", "node", "nodes", "secret", "secrets", "service", "connect",
+	"services", "tree", "safeTree", "caRoots", "caLeaf",
+
+	// Scratch
+	"scratch",
+
+	// Helper functions
+	"base64Decode", "base64Encode", "base64URLDecode", "base64URLEncode",
+	"byKey", "byTag", "contains", "containsAll", "containsAny",
+	"containsNone", "containsNotAll", "env", "executeTemplate", "explode",
+	"explodeMap", "in", "indent", "loop", "join", "trimSpace", "parseBool",
+	"parseFloat", "parseInt", "parseJSON", "parseUint", "parseYAML",
+	"plugin", "regexReplaceAll", "regexMatch", "replaceAll", "sha256Hex",
+	"timestamp", "toLower", "toJSON", "toJSONPretty", "toTitle", "toTOML",
+	"toUpper", "toYAML", "split", "byMeta", "sockaddr",
+
+	// Math functions
+	"add", "subtract", "multiply", "divide", "modulo", "minimum", "maximum",
 }
 
-// Copy returns a deep copy of the receiver.
-func (wc *WaitConfig) Copy() *WaitConfig {
-	if wc == nil {
+// Validate returns an error if both a FunctionAllowlist and FunctionDenylist
+// are set, if the FunctionAllowlist contains an unknown function, or if a
+// render limit is negative.
+func (c *ClientTemplateConfig) Validate() error {
+	if c == nil {
 		return nil
 	}
 
-	nwc := new(WaitConfig)
-
-	if wc.Min != nil {
-		nwc.Min = &*wc.Min
+	if c.MaxRenderSizeBytes != nil && *c.MaxRenderSizeBytes < 0 {
+		return errors.New("max_render_size cannot be negative")
 	}
-
-	if wc.Max != nil {
-		nwc.Max = &*wc.Max
+	if c.MaxRenderCPUTime != nil && *c.MaxRenderCPUTime < 0 {
+		return errors.New("max_render_cpu_time cannot be negative")
+	}
+	// RLIMIT_CPU is in whole seconds, so shorter limits can't be enforced
+	if c.MaxRenderCPUTime != nil && *c.MaxRenderCPUTime > 0 && *c.MaxRenderCPUTime < time.Second {
+		return errors.New("max_render_cpu_time must be at least 1s")
+	}
+	if c.MaxTemplatesPerTask != nil && *c.MaxTemplatesPerTask < 0 {
+		return errors.New("max_templates_per_task cannot be negative")
 	}
 
-	return wc
-}
-
-// Equals returns the result of reflect.DeepEqual
-func (wc *WaitConfig) Equals(other *WaitConfig) bool {
-	return reflect.DeepEqual(wc, other)
-}
+	if len(c.FunctionAllowlist) == 0 {
+		return nil
+	}
 
-// IsEmpty returns true if the receiver only contains an instance with no fields set.
-func (wc *WaitConfig) IsEmpty() bool {
-	if wc == nil {
-		return true
+	if len(c.FunctionDenylist) > 0 || len(c.FunctionBlacklist) > 0 {
+		return errors.New("function_allowlist and function_denylist cannot both be set")
 	}
-	return wc.Equals(&WaitConfig{})
-}
 
-// Validate returns an error  if the receiver is nil or empty or if Min is greater
-// than Max the user specified Max.
-func (wc *WaitConfig) Validate() error {
-	// If the config is nil or empty return false so that it is never assigned.
-	if wc == nil || wc.IsEmpty() {
-		return errors.New("wait config is nil or empty")
+	for _, fn := range c.FunctionAllowlist {
+		if !helper.SliceStringContains(consulTemplateFunctions, fn) {
+			return fmt.Errorf("function_allowlist contains unknown function %q", fn)
+		}
 	}
+	return nil
+}
 
-	// If min is nil, return
-	if wc.Min == nil {
+// ConsulTemplateFunctionDenylist returns the functions consul-template
+// should deny. If a FunctionAllowlist is set, every function it doesn't list
+// is denied.
+func (c *ClientTemplateConfig) ConsulTemplateFunctionDenylist() []string {
+	if c == nil {
 		return nil
 	}
+	if len(c.FunctionAllowlist) == 0 {
+		return c.FunctionDenylist
+	}
 
-	// If min isn't nil, make sure Max is less than Min.
-	if wc.Max != nil {
-		if *wc.Min > *wc.Max {
-			return fmt.Errorf("wait config min %d is greater than max %d", *wc.Min, *wc.Max)
+	denylist := make([]string, 0, len(consulTemplateFunctions))
+	for _, fn := range consulTemplateFunctions {
+		if !helper.SliceStringContains(c.FunctionAllowlist, fn) {
+			denylist = append(denylist, fn)
 		}
 	}
-
-	// Otherwise, return nil. Consul Template will set a Max based off of Min.
-	return nil
+	return denylist
 }
 
-// Merge merges two WaitConfigs. The passed instance always takes precedence.
-func (wc *WaitConfig) Merge(b *WaitConfig) *WaitConfig {
-	if wc == nil {
-		return b
+// VaultRetryFor returns the retry config for templates reading secrets from
+// the Vault namespace, layering the namespace's entry in VaultRetries over
+// VaultRetry. It falls back to VaultRetry if the namespace has no entry.
+func (c *ClientTemplateConfig) VaultRetryFor(namespace string) *RetryConfig {
+	if c == nil {
+		return nil
 	}
 
-	result := *wc
-	if b == nil {
-		return &result
+	if retry, ok := c.VaultRetries[namespace]; ok {
+		return c.VaultRetry.Merge(retry)
 	}
+	return c.VaultRetry.Copy()
+}
 
-	if b.Min != nil {
-		result.Min = &*b.Min
+// ToConsulTemplateConfig converts the client's template config to a
+// consul-template config for templates reading secrets from the Vault
+// namespace. Unlike the converters of its sub-configs, which are only called
+// for the sub-configs that are set, an error is returned if any set
+// sub-config is invalid. The function denylist and sandbox apply to each
+// template rather than to the config, and are converted by
+// ToConsulTemplateTemplateConfig.
+func (c *ClientTemplateConfig) ToConsulTemplateConfig(vaultNamespace string) (*config.Config, error) {
+	conf := config.DefaultConfig()
+	if c == nil {
+		return conf, nil
 	}
 
-	if b.MinHCL != "" {
-		result.MinHCL = b.MinHCL
+	// Set the amount of time to do a blocking query for.
+	if c.BlockQueryWaitTime != nil {
+		conf.BlockQueryWaitTime = helper.TimeToPtr(*c.BlockQueryWaitTime)
 	}
 
-	if b.Max != nil {
-		result.Max = &*b.Max
+	// Set the stale-read threshold to allow queries to be served by followers
+	// if the last replicated data is within this bound.
+	if c.MaxStale != nil {
+		conf.MaxStale = helper.TimeToPtr(*c.MaxStale)
+	}
+
+	// Set the minimum and maximum amount of time to wait for the cluster to
+	// reach a consistent state before rendering a template.
+	if c.Wait != nil {
+		wait, err := c.Wait.ToConsulTemplate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid wait config: %v", err)
+		}
+		conf.Wait = wait
+	}
+
+	if c.ConsulRetry != nil {
+		retry, err := c.ConsulRetry.ToConsulTemplate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid consul_retry config: %v", err)
+		}
+		conf.Consul.Retry = retry
+	}
+
+	if vaultRetry := c.VaultRetryFor(vaultNamespace); vaultRetry != nil {
+		retry, err := vaultRetry.ToConsulTemplate()
+		if err != nil {
+			if _, ok := c.VaultRetries[vaultNamespace]; ok {
+				return nil, fmt.Errorf("invalid vault_retries config for namespace %q: %v", vaultNamespace, err)
+			}
+			return nil, fmt.Errorf("invalid vault_retry config: %v", err)
+		}
+		conf.Vault.Retry = retry
+	}
+
+	// consul-template doesn't yet query Nomad itself, so there is no client
+	// for the Nomad retry config to be set on. It's still validated so that
+	// invalid configs are rejected like the others.
+	if c.NomadRetry != nil {
+		if err := c.NomadRetry.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid nomad_retry config: %v", err)
+		}
+	}
+
+	return conf, nil
+}
+
+// WithJobRetry returns a copy of the config with a job's retry config merged
+// over it, clamped by the client's as MergeTemplateRetry describes. The job's
+// retry config applies to the Consul, Vault, and Nomad retries. A job's wait
+// configs are set on each template, and merged by TemplateWaitConfig.
+func (c *ClientTemplateConfig) WithJobRetry(retry *RetryConfig) *ClientTemplateConfig {
+	if c == nil {
+		c = &ClientTemplateConfig{}
+	}
+
+	nc := c.Copy()
+	if retry != nil {
+		nc.ConsulRetry = MergeTemplateRetry(c.ConsulRetry, retry)
+		nc.VaultRetry = MergeTemplateRetry(c.VaultRetry, retry)
+		nc.NomadRetry = MergeTemplateRetry(c.NomadRetry, retry)
+		for namespace := range c.VaultRetries {
+			nc.VaultRetries[namespace] = MergeTemplateRetry(c.VaultRetryFor(namespace), retry)
+		}
+	}
+	return nc
+}
+
+// ToConsulTemplateTemplateConfig returns the consul-template config each
+// template of a task starts from, denying the functions the client denies.
+// Unless the sandbox is disabled, templates are sandboxed to sandboxPath.
+func (c *ClientTemplateConfig) ToConsulTemplateTemplateConfig(sandboxPath string) *config.TemplateConfig {
+	ct := config.DefaultTemplateConfig()
+	ct.FunctionDenylist = helper.CopySliceString(c.ConsulTemplateFunctionDenylist())
+	if c == nil || !c.DisableSandbox {
+		ct.SandboxPath = helper.StringToPtr(sandboxPath)
+	}
+	return ct
+}
+
+// WaitConfig is mirrored from templateconfig.WaitConfig because we need to handle
+// the HCL conversion which happens in agent.ParseConfigFile
+// NOTE: Since Consul Template requires pointers, this type uses pointers to fields
+// which is inconsistent with how Nomad typically works. This decision was made
+// to maintain parity with the external subsystem, not to establish a new standard.
+type WaitConfig struct {
+	Min    *time.Duration `hcl:"-"`
+	MinHCL string         `hcl:"min,optional" json:"-"`
+	Max    *time.Duration `hcl:"-"`
+	MaxHCL string         `hcl:"max,optional" json:"-"`
+}
+
+// Copy returns a deep copy of the receiver.
+func (wc *WaitConfig) Copy() *WaitConfig {
+	if wc == nil {
+		return nil
+	}
+
+	nwc := new(WaitConfig)
+	*nwc = *wc
+
+	// Now copy pointer values
+	if wc.Min != nil {
+		nwc.Min = helper.TimeToPtr(*wc.Min)
+	}
+
+	if wc.Max != nil {
+		nwc.Max = helper.TimeToPtr(*wc.Max)
+	}
+
+	return nwc
+}
+
+// Equals returns the result of reflect.DeepEqual
+func (wc *WaitConfig) Equals(other *WaitConfig) bool {
+	return reflect.DeepEqual(wc, other)
+}
+
+// IsEmpty returns true if the receiver only contains an instance with no fields set.
+func (wc *WaitConfig) IsEmpty() bool {
+	if wc == nil {
+		return true
+	}
+	return wc.Equals(&WaitConfig{})
+}
+
+// Validate returns an error  if the receiver is nil or empty or if Min is greater
+// than Max the user specified Max.
+func (wc *WaitConfig) Validate() error {
+	// If the config is nil or empty return false so that it is never assigned.
+	if wc == nil || wc.IsEmpty() {
+		return errors.New("wait config is nil or empty")
+	}
+
+	// If min is nil, return
+	if wc.Min == nil {
+		return nil
+	}
+
+	// If min isn't nil, make sure Max is less than Min.
+	if wc.Max != nil {
+		if *wc.Min > *wc.Max {
+			return fmt.Errorf("wait config min %d is greater than max %d", *wc.Min, *wc.Max)
+		}
+	}
+
+	// Otherwise, return nil. Consul Template will set a Max based off of Min.
+	return nil
+}
+
+// Merge merges two WaitConfigs. The passed instance always takes precedence.
+func (wc *WaitConfig) Merge(b *WaitConfig) *WaitConfig {
+	if wc == nil {
+		return b.Copy()
+	}
+
+	result := wc.Copy()
+	if b == nil {
+		return result
+	}
+
+	if b.Min != nil {
+		result.Min = helper.TimeToPtr(*b.Min)
+	}
+
+	if b.MinHCL != "" {
+		result.MinHCL = b.MinHCL
+	}
+
+	if b.Max != nil {
+		result.Max = helper.TimeToPtr(*b.Max)
 	}
 
 	if b.MaxHCL != "" {
 		result.MaxHCL = b.MaxHCL
 	}
 
-	return &result
+	return result
 }
 
 // ToConsulTemplate converts a client WaitConfig instance to a consul-template WaitConfig
@@ -570,16 +1492,88 @@ func (wc *WaitConfig) ToConsulTemplate() (*config.WaitConfig, error) {
 	result := &config.WaitConfig{Enabled: helper.BoolToPtr(true)}
 
 	if wc.Min != nil {
-		result.Min = wc.Min
+		result.Min = helper.TimeToPtr(*wc.Min)
 	}
 
 	if wc.Max != nil {
-		result.Max = wc.Max
+		result.Max = helper.TimeToPtr(*wc.Max)
 	}
 
 	return result, nil
 }
 
+// TemplateWaitConfig resolves the consul-template wait config of a single
+// template. The template's wait config is merged over the client's as
+// MergeTemplateWait describes, and is kept within the client's wait bounds.
+// When neither is set nil is returned, and the template renders without
+// waiting.
+func (c *ClientTemplateConfig) TemplateWaitConfig(tmplWait *structs.WaitConfig) (*config.WaitConfig, error) {
+	var wait *WaitConfig
+	if c != nil && c.Wait != nil {
+		wait = c.Wait.Copy()
+	}
+
+	if tmplWait != nil {
+		if err := tmplWait.Validate(); err != nil {
+			return nil, err
+		}
+
+		override := &WaitConfig{}
+		if tmplWait.Min != nil {
+			override.Min = helper.TimeToPtr(*tmplWait.Min)
+		}
+		if tmplWait.Max != nil {
+			override.Max = helper.TimeToPtr(*tmplWait.Max)
+		}
+		wait = MergeTemplateWait(wait, override)
+
+		// Keep the template's wait within the bounds set by the operator
+		if c != nil && c.WaitBounds != nil {
+			if err := c.WaitBounds.Validate(); err != nil {
+				return nil, err
+			}
+			bounds := c.WaitBounds
+			if bounds.Min != nil && wait.Min != nil && *wait.Min < *bounds.Min {
+				wait.Min = helper.TimeToPtr(*bounds.Min)
+			}
+			if bounds.Max != nil && wait.Max != nil && *wait.Max > *bounds.Max {
+				wait.Max = helper.TimeToPtr(*bounds.Max)
+			}
+			if bounds.Max != nil && wait.Min != nil && *wait.Min > *bounds.Max {
+				wait.Min = helper.TimeToPtr(*bounds.Max)
+			}
+		}
+	}
+
+	if wait.IsEmpty() {
+		return nil, nil
+	}
+	return wait.ToConsulTemplate()
+}
+
+// MergeTemplateWait merges a job's wait config over the client's, returning
+// the wait config for the job's templates. The client's config acts as a
+// clamp: the job may tighten the bounds, but its Min can't go below the
+// client's Min. Max is raised to Min when it ends up below it, such as when
+// the job's Min is above the client's Max.
+func MergeTemplateWait(client, job *WaitConfig) *WaitConfig {
+	result := client.Merge(job)
+	if result == nil || client == nil {
+		return result
+	}
+
+	if client.Min != nil && result.Min != nil && *result.Min < *client.Min {
+		result.Min = helper.TimeToPtr(*client.Min)
+		result.MinHCL = client.MinHCL
+	}
+	if result.Min != nil && result.Max != nil && *result.Max < *result.Min {
+		result.Max = helper.TimeToPtr(*result.Min)
+		result.MaxHCL = result.MinHCL
+	}
+
+	return result
+}
+
 // RetryConfig is mirrored from templateconfig.WaitConfig because we need to handle
 // the HCL indirection to support mapping in agent.ParseConfigFile.
 // NOTE: Since Consul Template requires pointers, this type uses pointers to fields
@@ -603,6 +1597,7 @@ type RetryConfig struct {
 	MaxBackoffHCL string         `hcl:"max_backoff,optional" json:"-"`
 }
 
+// Copy returns a deep copy of the receiver.
 func (rc *RetryConfig) Copy() *RetryConfig {
 	if rc == nil {
 		return nil
@@ -613,13 +1608,13 @@ func (rc *RetryConfig) Copy() *RetryConfig {
 
 	// Now copy pointer values
 	if rc.Attempts != nil {
-		nrc.Attempts = &*rc.Attempts
+		nrc.Attempts = helper.IntToPtr(*rc.Attempts)
 	}
 	if rc.Backoff != nil {
-		nrc.Backoff = &*rc.Backoff
+		nrc.Backoff = helper.TimeToPtr(*rc.Backoff)
 	}
 	if rc.MaxBackoff != nil {
-		nrc.MaxBackoff = &*rc.MaxBackoff
+		nrc.MaxBackoff = helper.TimeToPtr(*rc.MaxBackoff)
 	}
 
 	return nrc
@@ -673,20 +1668,20 @@ func (rc *RetryConfig) Validate() error {
 // Merge merges two RetryConfigs. The passed instance always takes precedence.
 func (rc *RetryConfig) Merge(b *RetryConfig) *RetryConfig {
 	if rc == nil {
-		return b
+		return b.Copy()
 	}
 
-	result := *rc
+	result := rc.Copy()
 	if b == nil {
-		return &result
+		return result
 	}
 
 	if b.Attempts != nil {
-		result.Attempts = &*b.Attempts
+		result.Attempts = helper.IntToPtr(*b.Attempts)
 	}
 
 	if b.Backoff != nil {
-		result.Backoff = &*b.Backoff
+		result.Backoff = helper.TimeToPtr(*b.Backoff)
 	}
 
 	if b.BackoffHCL != "" {
@@ -694,14 +1689,64 @@ func (rc *RetryConfig) Merge(b *RetryConfig) *RetryConfig {
 	}
 
 	if b.MaxBackoff != nil {
-		result.MaxBackoff = &*b.MaxBackoff
+		result.MaxBackoff = helper.TimeToPtr(*b.MaxBackoff)
 	}
 
 	if b.MaxBackoffHCL != "" {
 		result.MaxBackoffHCL = b.MaxBackoffHCL
 	}
 
-	return &result
+	return result
+}
+
+// MergeTemplateRetry merges a job's retry config over the client's, returning
+// the retry config for the job's templates. The client's config acts as a
+// clamp: the job can't make more Attempts than the client. As 0 Attempts
+// retries without limit, the job can't set it unless the client does.
+func MergeTemplateRetry(client, job *RetryConfig) *RetryConfig {
+	result := client.Merge(job)
+	if result == nil || client == nil {
+		return result
+	}
+
+	if client.Attempts != nil && *client.Attempts > 0 && result.Attempts != nil &&
+		(*result.Attempts == 0 || *result.Attempts > *client.Attempts) {
+		result.Attempts = helper.IntToPtr(*client.Attempts)
+	}
+
+	return result
+}
+
+// DefaultTemplateNomadRetry returns the default retry config for templates
+// using Nomad native lookups, which matches consul-template's defaults.
+func DefaultTemplateNomadRetry() *RetryConfig {
+	return &RetryConfig{
+		Attempts:   helper.IntToPtr(config.DefaultRetryAttempts),
+		Backoff:    helper.TimeToPtr(config.DefaultRetryBackoff),
+		MaxBackoff: helper.TimeToPtr(config.DefaultRetryMaxBackoff),
+	}
+}
+
+// DefaultCSIClaimRetry returns the default retry config for CSI volume
+// claims, which retries for about 30 seconds before failing the allocation.
+func DefaultCSIClaimRetry() *RetryConfig {
+	return &RetryConfig{
+		Attempts:   helper.IntToPtr(5),
+		Backoff:    helper.TimeToPtr(1 * time.Second),
+		MaxBackoff: helper.TimeToPtr(16 * time.Second),
+	}
+}
+
+// DefaultCSIUnpublishRetry returns the default retry config for releasing
+// CSI volume claims when an allocation stops. Claims left behind are only
+// released by the servers' periodic reconciliation, so it retries for about
+// a minute.
+func DefaultCSIUnpublishRetry() *RetryConfig {
+	return &RetryConfig{
+		Attempts:   helper.IntToPtr(6),
+		Backoff:    helper.TimeToPtr(1 * time.Second),
+		MaxBackoff: helper.TimeToPtr(30 * time.Second),
+	}
 }
 
 // ToConsulTemplate converts a client RetryConfig instance to a consul-template RetryConfig
@@ -713,15 +1758,15 @@ func (rc *RetryConfig) ToConsulTemplate() (*config.RetryConfig, error) {
 	result := &config.RetryConfig{Enabled: helper.BoolToPtr(true)}
 
 	if rc.Attempts != nil {
-		result.Attempts = rc.Attempts
+		result.Attempts = helper.IntToPtr(*rc.Attempts)
 	}
 
 	if rc.Backoff != nil {
-		result.Backoff = rc.Backoff
+		result.Backoff = helper.TimeToPtr(*rc.Backoff)
 	}
 
 	if rc.MaxBackoff != nil {
-		result.MaxBackoff = &*rc.MaxBackoff
+		result.MaxBackoff = helper.TimeToPtr(*rc.MaxBackoff)
 	}
 
 	return result, nil
@@ -732,136 +1777,1229 @@ func (c *Config) Copy() *Config {
 	*nc = *c
 	nc.Node = nc.Node.Copy()
 	nc.Servers = helper.CopySliceString(nc.Servers)
+	nc.ArtifactChecksumExemptPrefixes = helper.CopySliceString(nc.ArtifactChecksumExemptPrefixes)
 	nc.Options = helper.CopyMapStringString(nc.Options)
+	nc.ChrootEnv = helper.CopyMapStringString(nc.ChrootEnv)
+	nc.ChrootFragments = helper.CopyMapStringSliceString(nc.ChrootFragments)
+	nc.EnvDenylistPerNamespace = helper.CopyMapStringSliceString(nc.EnvDenylistPerNamespace)
+	nc.CSIClaimLabelEnv = helper.CopyMapStringString(nc.CSIClaimLabelEnv)
+	nc.GCMaxAllocsPerNamespace = helper.CopyMapStringInt(nc.GCMaxAllocsPerNamespace)
+	nc.NodeDownloadBandwidthWeights = helper.CopyMapStringInt(nc.NodeDownloadBandwidthWeights)
+	if c.DriverHealthThresholds != nil {
+		nc.DriverHealthThresholds = make(map[string]*DriverHealthThreshold, len(c.DriverHealthThresholds))
+		for driver, threshold := range c.DriverHealthThresholds {
+			nc.DriverHealthThresholds[driver] = threshold.Copy()
+		}
+	}
 	nc.HostVolumes = structs.CopyMapStringClientHostVolumeConfig(nc.HostVolumes)
+	nc.HostNetworks = structs.CopyMapStringClientHostNetworkConfig(nc.HostNetworks)
+	nc.TLSConfig = c.TLSConfig.Copy()
+	if nc.TLSConfig != nil {
+		// Share the keyloader so certificates reloaded by the agent are
+		// used by every copy of the config
+		nc.TLSConfig.KeyLoader = c.TLSConfig.GetKeyLoader()
+	}
 	nc.ConsulConfig = c.ConsulConfig.Copy()
 	nc.VaultConfig = c.VaultConfig.Copy()
 	nc.TemplateConfig = c.TemplateConfig.Copy()
+	nc.CoreDumps = c.CoreDumps.Copy()
+	nc.Audit = c.Audit.Copy()
+	nc.CSIClaimRetry = c.CSIClaimRetry.Copy()
+	nc.CSIUnpublishRetry = c.CSIUnpublishRetry.Copy()
 	if c.ReservableCores != nil {
 		nc.ReservableCores = make([]uint16, len(c.ReservableCores))
 		copy(nc.ReservableCores, c.ReservableCores)
 	}
+	if c.BridgeNetworkCNIConfig != nil {
+		nc.BridgeNetworkCNIConfig = make([]byte, len(c.BridgeNetworkCNIConfig))
+		copy(nc.BridgeNetworkCNIConfig, c.BridgeNetworkCNIConfig)
+	}
 	return nc
 }
 
-// DefaultConfig returns the default configuration
-func DefaultConfig() *Config {
-	return &Config{
-		Version:                 version.GetVersion(),
-		VaultConfig:             structsc.DefaultVaultConfig(),
-		ConsulConfig:            structsc.DefaultConsulConfig(),
-		LogOutput:               os.Stderr,
-		Region:                  "global",
-		StatsCollectionInterval: 1 * time.Second,
-		TLSConfig:               &structsc.TLSConfig{},
-		LogLevel:                "DEBUG",
-		GCInterval:              1 * time.Minute,
-		GCParallelDestroys:      2,
-		GCDiskUsageThreshold:    80,
-		GCInodeUsageThreshold:   70,
-		GCMaxAllocs:             50,
-		NoHostUUID:              true,
-		DisableRemoteExec:       false,
-		TemplateConfig: &ClientTemplateConfig{
-			FunctionDenylist: []string{"plugin"},
-			DisableSandbox:   false,
-		},
-		RPCHoldTimeout:     5 * time.Second,
-		CNIPath:            "/opt/cni/bin",
-		CNIConfigDir:       "/opt/cni/config",
-		CNIInterfacePrefix: "eth",
-		HostNetworks:       map[string]*structs.ClientHostNetworkConfig{},
-		CgroupParent:       cgutil.DefaultCgroupParent,
-		MaxDynamicPort:     structs.DefaultMinDynamicPort,
-		MinDynamicPort:     structs.DefaultMaxDynamicPort,
-	}
+// ReloadableFields are the names of the Config fields applied by a client
+// reload. Changes to the other fields only take effect once the client is
+// restarted.
+var ReloadableFields = []string{
+	"GCInterval",
+	"GCMaxAllocs",
+	"StatsCollectionInterval",
+	"TemplateConfig",
 }
 
-// Read returns the specified configuration value or "".
-func (c *Config) Read(id string) string {
-	return c.Options[id]
+// RestartRequiredFields are the names of the Config fields whose changes a
+// client reload reports as requiring a restart.
+var RestartRequiredFields = []string{
+	"StateDir",
+	"AllocDir",
+	"CgroupParent",
 }
 
-// ReadDefault returns the specified configuration value, or the specified
-// default value if none is set.
-func (c *Config) ReadDefault(id string, defaultValue string) string {
-	return c.ReadAlternativeDefault([]string{id}, defaultValue)
+// ReloadableCopyFrom copies the ReloadableFields of newCfg into the config.
+func (c *Config) ReloadableCopyFrom(newCfg *Config) {
+	c.GCInterval = newCfg.GCInterval
+	c.GCMaxAllocs = newCfg.GCMaxAllocs
+	c.StatsCollectionInterval = newCfg.StatsCollectionInterval
+	c.TemplateConfig = newCfg.TemplateConfig.Copy()
 }
 
-// ReadAlternativeDefault returns the specified configuration value, or the
-// specified value if none is set.
-func (c *Config) ReadAlternativeDefault(ids []string, defaultValue string) string {
-	for _, id := range ids {
-		val, ok := c.Options[id]
-		if ok {
-			return val
+// RestartRequiredChanges returns the names of the RestartRequiredFields that
+// differ between the config and newCfg.
+func (c *Config) RestartRequiredChanges(newCfg *Config) []string {
+	var changed []string
+	prev, next := reflect.ValueOf(c).Elem(), reflect.ValueOf(newCfg).Elem()
+	for _, name := range RestartRequiredFields {
+		if !reflect.DeepEqual(prev.FieldByName(name).Interface(), next.FieldByName(name).Interface()) {
+			changed = append(changed, name)
 		}
 	}
-
-	return defaultValue
+	return changed
 }
 
-// ReadBool parses the specified option as a boolean.
-func (c *Config) ReadBool(id string) (bool, error) {
-	val, ok := c.Options[id]
-	if !ok {
-		return false, fmt.Errorf("Specified config is missing from options")
-	}
-	bval, err := strconv.ParseBool(val)
-	if err != nil {
-		return false, fmt.Errorf("Failed to parse %s as bool: %s", val, err)
-	}
-	return bval, nil
-}
+const (
+	// ReloadRestarted is the outcome of a subsystem that a reload restarted
+	// to apply its new config.
+	ReloadRestarted = "restarted"
 
-// ReadBoolDefault tries to parse the specified option as a boolean. If there is
-// an error in parsing, the default option is returned.
-func (c *Config) ReadBoolDefault(id string, defaultValue bool) bool {
-	val, err := c.ReadBool(id)
-	if err != nil {
-		return defaultValue
+	// ReloadUpdated is the outcome of a subsystem whose config a reload
+	// changed in place, without restarting it.
+	ReloadUpdated = "updated"
+
+	// ReloadUnchanged is the outcome of a subsystem whose config a reload
+	// left as it was.
+	ReloadUnchanged = "unchanged"
+)
+
+// ReloadOutcomes maps the reloadable subsystems of the client to the outcome
+// of a reload for them: ReloadRestarted, ReloadUpdated or ReloadUnchanged.
+type ReloadOutcomes map[string]string
+
+// Set records the outcome of a subsystem, which is ReloadUnchanged unless it
+// changed.
+func (o ReloadOutcomes) Set(subsystem string, changed bool, outcome string) {
+	if !changed {
+		outcome = ReloadUnchanged
 	}
-	return val
+	o[subsystem] = outcome
 }
 
-// ReadInt parses the specified option as a int.
-func (c *Config) ReadInt(id string) (int, error) {
-	val, ok := c.Options[id]
-	if !ok {
-		return 0, fmt.Errorf("Specified config is missing from options")
-	}
-	ival, err := strconv.Atoi(val)
-	if err != nil {
-		return 0, fmt.Errorf("Failed to parse %s as int: %s", val, err)
+// String returns the outcomes sorted by subsystem, such as
+// "gc: restarted, tls: unchanged".
+func (o ReloadOutcomes) String() string {
+	subsystems := make([]string, 0, len(o))
+	for subsystem := range o {
+		subsystems = append(subsystems, subsystem)
 	}
-	return ival, nil
-}
+	sort.Strings(subsystems)
 
-// ReadIntDefault tries to parse the specified option as a int. If there is
-// an error in parsing, the default option is returned.
-func (c *Config) ReadIntDefault(id string, defaultValue int) int {
-	val, err := c.ReadInt(id)
-	if err != nil {
-		return defaultValue
+	outcomes := make([]string, 0, len(subsystems))
+	for _, subsystem := range subsystems {
+		outcomes = append(outcomes, subsystem+": "+o[subsystem])
 	}
-	return val
+	return strings.Join(outcomes, ", ")
 }
 
-// ReadDuration parses the specified option as a duration.
-func (c *Config) ReadDuration(id string) (time.Duration, error) {
-	val, ok := c.Options[id]
-	if !ok {
-		return time.Duration(0), fmt.Errorf("Specified config is missing from options")
+// Merge merges two client configurations. It first copies the receiver and
+// then overrides those values with the non-zero values of the passed config.
+// The HostVolumes, HostNetworks, Options, ChrootEnv, ChrootFragments,
+// EnvDenylistPerNamespace, CSIClaimLabelEnv, GCMaxAllocsPerNamespace and
+// NodeDownloadBandwidthWeights maps are merged by key
+// and boolean fields can only be enabled, not disabled, by the passed config.
+func (c *Config) Merge(b *Config) *Config {
+	if c == nil {
+		if b == nil {
+			return nil
+		}
+		return b.Copy()
 	}
-	dval, err := time.ParseDuration(val)
-	if err != nil {
-		return time.Duration(0), fmt.Errorf("Failed to parse %s as time duration: %s", val, err)
+
+	result := c.Copy()
+	if b == nil {
+		return result
 	}
-	return dval, nil
-}
 
-// ReadDurationDefault tries to parse the specified option as a duration. If there is
-// an error in parsing, the default option is returned.
+	if b.DevMode {
+		result.DevMode = true
+	}
+	if b.EnableDebug {
+		result.EnableDebug = true
+	}
+	if b.StateDir != "" {
+		result.StateDir = b.StateDir
+	}
+	if b.AllocDir != "" {
+		result.AllocDir = b.AllocDir
+	}
+	if b.LogOutput != nil {
+		result.LogOutput = b.LogOutput
+	}
+	if b.Logger != nil {
+		result.Logger = b.Logger
+	}
+	if b.Region != "" {
+		result.Region = b.Region
+	}
+	if b.NetworkInterface != "" {
+		result.NetworkInterface = b.NetworkInterface
+	}
+	if b.NetworkSpeed != 0 {
+		result.NetworkSpeed = b.NetworkSpeed
+	}
+	if b.CpuCompute != 0 {
+		result.CpuCompute = b.CpuCompute
+	}
+	if b.MemoryMB != 0 {
+		result.MemoryMB = b.MemoryMB
+	}
+	if b.MaxKillTimeout != 0 {
+		result.MaxKillTimeout = b.MaxKillTimeout
+	}
+	if b.MaxFreezeDuration != 0 {
+		result.MaxFreezeDuration = b.MaxFreezeDuration
+	}
+	if len(b.Servers) != 0 {
+		result.Servers = helper.CopySliceString(b.Servers)
+	}
+	if b.MaxServerConnections != 0 {
+		result.MaxServerConnections = b.MaxServerConnections
+	}
+	if b.RPCHandler != nil {
+		result.RPCHandler = b.RPCHandler
+	}
+	if b.Node != nil {
+		result.Node = b.Node.Copy()
+	}
+	if b.ClientMaxPort != 0 {
+		result.ClientMaxPort = b.ClientMaxPort
+	}
+	if b.ClientMinPort != 0 {
+		result.ClientMinPort = b.ClientMinPort
+	}
+	if b.MaxDynamicPort != 0 {
+		result.MaxDynamicPort = b.MaxDynamicPort
+	}
+	if b.MinDynamicPort != 0 {
+		result.MinDynamicPort = b.MinDynamicPort
+	}
+
+	if len(b.ChrootEnv) != 0 {
+		if result.ChrootEnv == nil {
+			result.ChrootEnv = make(map[string]string, len(b.ChrootEnv))
+		} else {
+			// The receiver's copy shares the map with the receiver
+			result.ChrootEnv = helper.CopyMapStringString(result.ChrootEnv)
+		}
+		for k, v := range b.ChrootEnv {
+			result.ChrootEnv[k] = v
+		}
+	}
+	if b.ChrootEnvMergeDefaults {
+		result.ChrootEnvMergeDefaults = true
+	}
+
+	if len(b.ChrootFragments) != 0 {
+		if result.ChrootFragments == nil {
+			result.ChrootFragments = make(map[string][]string, len(b.ChrootFragments))
+		} else {
+			result.ChrootFragments = helper.CopyMapStringSliceString(result.ChrootFragments)
+		}
+		for k, v := range b.ChrootFragments {
+			result.ChrootFragments[k] = helper.CopySliceString(v)
+		}
+	}
+
+	if len(b.EnvDenylistPerNamespace) != 0 {
+		if result.EnvDenylistPerNamespace == nil {
+			result.EnvDenylistPerNamespace = make(map[string][]string, len(b.EnvDenylistPerNamespace))
+		} else {
+			result.EnvDenylistPerNamespace = helper.CopyMapStringSliceString(result.EnvDenylistPerNamespace)
+		}
+		for k, v := range b.EnvDenylistPerNamespace {
+			result.EnvDenylistPerNamespace[k] = helper.CopySliceString(v)
+		}
+	}
+
+	if len(b.Options) != 0 {
+		if result.Options == nil {
+			result.Options = make(map[string]string, len(b.Options))
+		}
+		for k, v := range b.Options {
+			result.Options[k] = v
+		}
+	}
+
+	if b.Version != nil {
+		result.Version = b.Version
+	}
+
+	if b.ConsulConfig != nil {
+		if result.ConsulConfig == nil {
+			result.ConsulConfig = b.ConsulConfig.Copy()
+		} else {
+			result.ConsulConfig = result.ConsulConfig.Merge(b.ConsulConfig)
+		}
+	}
+	if b.VaultConfig != nil {
+		if result.VaultConfig == nil {
+			result.VaultConfig = b.VaultConfig.Copy()
+		} else {
+			result.VaultConfig = result.VaultConfig.Merge(b.VaultConfig)
+		}
+	}
+
+	if b.StatsCollectionInterval != 0 {
+		result.StatsCollectionInterval = b.StatsCollectionInterval
+	}
+	if b.PublishNodeMetrics {
+		result.PublishNodeMetrics = true
+	}
+	if b.PublishAllocationMetrics {
+		result.PublishAllocationMetrics = true
+	}
+
+	if b.TLSConfig != nil {
+		if result.TLSConfig == nil {
+			result.TLSConfig = b.TLSConfig.Copy()
+		} else {
+			result.TLSConfig = result.TLSConfig.Merge(b.TLSConfig)
+		}
+	}
+
+	if b.GCInterval != 0 {
+		result.GCInterval = b.GCInterval
+	}
+	if b.GCParallelDestroys != 0 {
+		result.GCParallelDestroys = b.GCParallelDestroys
+	}
+	if b.GCDiskUsageThreshold != 0 {
+		result.GCDiskUsageThreshold = b.GCDiskUsageThreshold
+	}
+	if b.GCInodeUsageThreshold != 0 {
+		result.GCInodeUsageThreshold = b.GCInodeUsageThreshold
+	}
+	if b.StateDirReserveMB != 0 {
+		result.StateDirReserveMB = b.StateDirReserveMB
+	}
+	if b.GCMaxAllocs != 0 {
+		result.GCMaxAllocs = b.GCMaxAllocs
+	}
+	if len(b.GCMaxAllocsPerNamespace) != 0 {
+		if result.GCMaxAllocsPerNamespace == nil {
+			result.GCMaxAllocsPerNamespace = make(map[string]int, len(b.GCMaxAllocsPerNamespace))
+		}
+		for ns, max := range b.GCMaxAllocsPerNamespace {
+			result.GCMaxAllocsPerNamespace[ns] = max
+		}
+	}
+	if b.GCMaxAllocAge != 0 {
+		result.GCMaxAllocAge = b.GCMaxAllocAge
+	}
+	if b.LogLevel != "" {
+		result.LogLevel = b.LogLevel
+	}
+	if b.NoHostUUID {
+		result.NoHostUUID = true
+	}
+	if b.ACLEnabled {
+		result.ACLEnabled = true
+	}
+	if b.ACLTokenTTL != 0 {
+		result.ACLTokenTTL = b.ACLTokenTTL
+	}
+	if b.ACLPolicyTTL != 0 {
+		result.ACLPolicyTTL = b.ACLPolicyTTL
+	}
+	if b.DisableRemoteExec {
+		result.DisableRemoteExec = true
+	}
+
+	if b.TemplateConfig != nil {
+		result.TemplateConfig = result.TemplateConfig.Merge(b.TemplateConfig.Copy())
+	}
+
+	if b.RPCHoldTimeout != 0 {
+		result.RPCHoldTimeout = b.RPCHoldTimeout
+	}
+	if b.PluginLoader != nil {
+		result.PluginLoader = b.PluginLoader
+	}
+	if b.PluginSingletonLoader != nil {
+		result.PluginSingletonLoader = b.PluginSingletonLoader
+	}
+	if b.StateDBFactory != nil {
+		result.StateDBFactory = b.StateDBFactory
+	}
+	if b.CNIPath != "" {
+		result.CNIPath = b.CNIPath
+	}
+	if b.CNIConfigDir != "" {
+		result.CNIConfigDir = b.CNIConfigDir
+	}
+	if b.CNIInterfacePrefix != "" {
+		result.CNIInterfacePrefix = b.CNIInterfacePrefix
+	}
+	if b.BridgeNetworkName != "" {
+		result.BridgeNetworkName = b.BridgeNetworkName
+	}
+	if b.BridgeNetworkAllocSubnet != "" {
+		result.BridgeNetworkAllocSubnet = b.BridgeNetworkAllocSubnet
+	}
+	if b.BridgeNetworkCNIConfigPath != "" {
+		result.BridgeNetworkCNIConfigPath = b.BridgeNetworkCNIConfigPath
+	}
+	if b.BridgeNetworkIPAMGCDryRun {
+		result.BridgeNetworkIPAMGCDryRun = true
+	}
+
+	if b.HostVolumeAllowMissingReadOnly {
+		result.HostVolumeAllowMissingReadOnly = true
+	}
+
+	if len(b.HostVolumes) != 0 {
+		if result.HostVolumes == nil {
+			result.HostVolumes = make(map[string]*structs.ClientHostVolumeConfig, len(b.HostVolumes))
+		}
+		for k, v := range b.HostVolumes {
+			result.HostVolumes[k] = v.Copy()
+		}
+	}
+
+	if len(b.HostNetworks) != 0 {
+		if result.HostNetworks == nil {
+			result.HostNetworks = make(map[string]*structs.ClientHostNetworkConfig, len(b.HostNetworks))
+		}
+		for k, v := range b.HostNetworks {
+			result.HostNetworks[k] = v.Copy()
+		}
+	}
+
+	if b.BindWildcardDefaultHostNetwork {
+		result.BindWildcardDefaultHostNetwork = true
+	}
+	if b.CgroupParent != "" {
+		result.CgroupParent = b.CgroupParent
+	}
+	if len(b.ReservableCores) != 0 {
+		result.ReservableCores = make([]uint16, len(b.ReservableCores))
+		copy(result.ReservableCores, b.ReservableCores)
+	}
+	if b.CSIVolumeMountTimeout != 0 {
+		result.CSIVolumeMountTimeout = b.CSIVolumeMountTimeout
+	}
+	if b.CSIPluginRegistrationWait != 0 {
+		result.CSIPluginRegistrationWait = b.CSIPluginRegistrationWait
+	}
+	if b.CSIDegradedPluginClaimDelay != 0 {
+		result.CSIDegradedPluginClaimDelay = b.CSIDegradedPluginClaimDelay
+	}
+	if b.CSIVolumeHealthCheck {
+		result.CSIVolumeHealthCheck = true
+	}
+	if b.CSIMountMetadata {
+		result.CSIMountMetadata = true
+	}
+	if b.CSIClaimTopology {
+		result.CSIClaimTopology = true
+	}
+	if b.CSIClaimRetry != nil {
+		result.CSIClaimRetry = result.CSIClaimRetry.Merge(b.CSIClaimRetry)
+	}
+	if b.CSIUnpublishRetry != nil {
+		result.CSIUnpublishRetry = result.CSIUnpublishRetry.Merge(b.CSIUnpublishRetry)
+	}
+	if len(b.CSIClaimLabelEnv) != 0 {
+		if result.CSIClaimLabelEnv == nil {
+			result.CSIClaimLabelEnv = make(map[string]string, len(b.CSIClaimLabelEnv))
+		}
+		for k, v := range b.CSIClaimLabelEnv {
+			result.CSIClaimLabelEnv[k] = v
+		}
+	}
+	if b.CSIMaxVolumesPerAlloc != 0 {
+		result.CSIMaxVolumesPerAlloc = b.CSIMaxVolumesPerAlloc
+	}
+	if b.CSIMaxNodeMounts != 0 {
+		result.CSIMaxNodeMounts = b.CSIMaxNodeMounts
+	}
+	if b.MaxCSIMountedCapacityGB != 0 {
+		result.MaxCSIMountedCapacityGB = b.MaxCSIMountedCapacityGB
+	}
+	if b.CSIIdempotencyKeys {
+		result.CSIIdempotencyKeys = true
+	}
+	if b.MaxTaskTmpfsMB != 0 {
+		result.MaxTaskTmpfsMB = b.MaxTaskTmpfsMB
+	}
+	if b.DisableOptionEnvInterpolation {
+		result.DisableOptionEnvInterpolation = true
+	}
+	if b.ArtifactRequireChecksum {
+		result.ArtifactRequireChecksum = true
+	}
+	if len(b.ArtifactChecksumExemptPrefixes) != 0 {
+		result.ArtifactChecksumExemptPrefixes = helper.CopySliceString(b.ArtifactChecksumExemptPrefixes)
+	}
+	if b.NodeDownloadBandwidthMbps != 0 {
+		result.NodeDownloadBandwidthMbps = b.NodeDownloadBandwidthMbps
+	}
+	if len(b.NodeDownloadBandwidthWeights) != 0 {
+		if result.NodeDownloadBandwidthWeights == nil {
+			result.NodeDownloadBandwidthWeights = make(map[string]int, len(b.NodeDownloadBandwidthWeights))
+		}
+		for class, weight := range b.NodeDownloadBandwidthWeights {
+			result.NodeDownloadBandwidthWeights[class] = weight
+		}
+	}
+	if b.CoreDumps != nil {
+		result.CoreDumps = result.CoreDumps.Merge(b.CoreDumps)
+	}
+	if b.Audit != nil {
+		result.Audit = result.Audit.Merge(b.Audit)
+	}
+	if b.ReloadObservationWindow != 0 {
+		result.ReloadObservationWindow = b.ReloadObservationWindow
+	}
+	if b.ReloadRollbackThreshold != 0 {
+		result.ReloadRollbackThreshold = b.ReloadRollbackThreshold
+	}
+	if b.PlacementFailureCacheTTL != 0 {
+		result.PlacementFailureCacheTTL = b.PlacementFailureCacheTTL
+	}
+	if b.PlacementFailureCacheSize != 0 {
+		result.PlacementFailureCacheSize = b.PlacementFailureCacheSize
+	}
+	if len(b.DriverHealthThresholds) != 0 {
+		thresholds := make(map[string]*DriverHealthThreshold,
+			len(result.DriverHealthThresholds)+len(b.DriverHealthThresholds))
+		for driver, threshold := range result.DriverHealthThresholds {
+			thresholds[driver] = threshold
+		}
+		for driver, threshold := range b.DriverHealthThresholds {
+			thresholds[driver] = thresholds[driver].Merge(threshold)
+		}
+		result.DriverHealthThresholds = thresholds
+	}
+
+	return result
+}
+
+// cniInterfacePrefixRe matches valid prefixes for the names of the network
+// interfaces created by CNI. The interface index is appended to the prefix,
+// and Linux limits interface names to 15 characters.
+var cniInterfacePrefixRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,12}$`)
+
+// Validate returns an error listing every problem with the configuration.
+func (c *Config) Validate() error {
+	var mErr multierror.Error
+	addErr := func(format string, args ...interface{}) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := c.DynamicPortRange(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	for _, threshold := range []struct {
+		name  string
+		value float64
+	}{
+		{"gc_disk_usage_threshold", c.GCDiskUsageThreshold},
+		{"gc_inode_usage_threshold", c.GCInodeUsageThreshold},
+	} {
+		if threshold.value < 0 || threshold.value > 100 {
+			addErr("%s must be between 0 and 100, got %v", threshold.name, threshold.value)
+		}
+	}
+
+	if c.StateDirReserveMB < 0 {
+		addErr("state_dir_reserve_mb must not be negative, got %d", c.StateDirReserveMB)
+	}
+
+	if c.GCParallelDestroys < 0 {
+		addErr("gc_parallel_destroys must not be negative, got %d", c.GCParallelDestroys)
+	}
+	if c.MaxServerConnections < 0 {
+		addErr("max_server_connections must not be negative, got %d", c.MaxServerConnections)
+	}
+	if c.GCMaxAllocs < 0 {
+		addErr("gc_max_allocs must not be negative, got %d", c.GCMaxAllocs)
+	}
+	for ns, max := range c.GCMaxAllocsPerNamespace {
+		if max < 0 {
+			addErr("gc_max_allocs_per_namespace for namespace %q must not be negative, got %d", ns, max)
+		}
+	}
+	if c.ReloadRollbackThreshold < 0 {
+		addErr("reload_rollback_threshold must not be negative, got %d", c.ReloadRollbackThreshold)
+	}
+	if c.CSIMaxVolumesPerAlloc < 0 {
+		addErr("csi_max_volumes_per_alloc must not be negative, got %d", c.CSIMaxVolumesPerAlloc)
+	}
+	if c.CSIMaxNodeMounts < 0 {
+		addErr("csi_max_node_mounts must not be negative, got %d", c.CSIMaxNodeMounts)
+	}
+	if c.MaxCSIMountedCapacityGB < 0 {
+		addErr("max_csi_mounted_capacity_gb must not be negative, got %d", c.MaxCSIMountedCapacityGB)
+	}
+	if c.MaxTaskTmpfsMB < 0 {
+		addErr("max_task_tmpfs_mb must not be negative, got %d", c.MaxTaskTmpfsMB)
+	}
+	if c.PlacementFailureCacheSize < 0 {
+		addErr("placement_failure_cache_size must not be negative, got %d", c.PlacementFailureCacheSize)
+	}
+	if c.NodeDownloadBandwidthMbps < 0 {
+		addErr("node_download_bandwidth_mbps must not be negative, got %d", c.NodeDownloadBandwidthMbps)
+	}
+	if _, err := bandwidth.ParseWeights(c.NodeDownloadBandwidthWeights); err != nil {
+		addErr("node_download_bandwidth_weights: %v", err)
+	}
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"max_kill_timeout", c.MaxKillTimeout},
+		{"max_freeze_duration", c.MaxFreezeDuration},
+		{"telemetry collection_interval", c.StatsCollectionInterval},
+		{"gc_interval", c.GCInterval},
+		{"gc_max_alloc_age", c.GCMaxAllocAge},
+		{"acl token_ttl", c.ACLTokenTTL},
+		{"acl policy_ttl", c.ACLPolicyTTL},
+		{"rpc_hold_timeout", c.RPCHoldTimeout},
+		{"csi_volume_mount_timeout", c.CSIVolumeMountTimeout},
+		{"csi_plugin_registration_wait", c.CSIPluginRegistrationWait},
+		{"csi_degraded_plugin_claim_delay", c.CSIDegradedPluginClaimDelay},
+		{"reload_observation_window", c.ReloadObservationWindow},
+		{"placement_failure_cache_ttl", c.PlacementFailureCacheTTL},
+	} {
+		if d.value < 0 {
+			addErr("%s must not be negative, got %v", d.name, d.value)
+		}
+	}
+
+	if err := c.TemplateConfig.Validate(); err != nil {
+		addErr("template: %v", err)
+	}
+
+	for name, network := range c.HostNetworks {
+		for _, cidr := range network.AllCIDRs() {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				addErr("host_network %q has an invalid cidr: %v", name, err)
+			}
+		}
+	}
+
+	if c.CSIClaimRetry != nil {
+		if c.CSIClaimRetry.Attempts != nil && *c.CSIClaimRetry.Attempts < 0 {
+			addErr("csi_claim_retry attempts must not be negative, got %d", *c.CSIClaimRetry.Attempts)
+		}
+		if err := DefaultCSIClaimRetry().Merge(c.CSIClaimRetry).Validate(); err != nil {
+			addErr("csi_claim_retry: %v", err)
+		}
+	}
+
+	if c.CSIUnpublishRetry != nil {
+		if c.CSIUnpublishRetry.Attempts != nil && *c.CSIUnpublishRetry.Attempts < 0 {
+			addErr("csi_unpublish_retry attempts must not be negative, got %d", *c.CSIUnpublishRetry.Attempts)
+		}
+		if err := DefaultCSIUnpublishRetry().Merge(c.CSIUnpublishRetry).Validate(); err != nil {
+			addErr("csi_unpublish_retry: %v", err)
+		}
+	}
+
+	if c.CoreDumps != nil {
+		if c.CoreDumps.MaxSizeMB < 0 {
+			addErr("core_dumps max_size_mb must not be negative, got %d", c.CoreDumps.MaxSizeMB)
+		}
+		if c.CoreDumps.Retain < 0 {
+			addErr("core_dumps retain must not be negative, got %d", c.CoreDumps.Retain)
+		}
+		if dir := filepath.Clean(c.CoreDumps.Dir); filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+			addErr("core_dumps dir %q must be relative to the allocation directory", c.CoreDumps.Dir)
+		}
+	}
+
+	if c.Audit != nil {
+		if c.Audit.MaxFileMB < 0 {
+			addErr("audit max_file_mb must not be negative, got %d", c.Audit.MaxFileMB)
+		}
+		if c.Audit.MaxFiles < 0 {
+			addErr("audit max_files must not be negative, got %d", c.Audit.MaxFiles)
+		}
+	}
+
+	for driver, threshold := range c.DriverHealthThresholds {
+		if threshold == nil {
+			continue
+		}
+		if threshold.Window < 0 {
+			addErr("driver_health %q window must not be negative, got %v", driver, threshold.Window)
+		}
+		if threshold.MaxFailures < 0 {
+			addErr("driver_health %q max_failures must not be negative, got %d", driver, threshold.MaxFailures)
+		}
+		if threshold.RecoveryPeriod < 0 {
+			addErr("driver_health %q recovery_period must not be negative, got %v", driver, threshold.RecoveryPeriod)
+		}
+	}
+
+	if c.BridgeNetworkAllocSubnet != "" {
+		if _, _, err := net.ParseCIDR(c.BridgeNetworkAllocSubnet); err != nil {
+			addErr("bridge_network_subnet %q is not a valid CIDR: %v", c.BridgeNetworkAllocSubnet, err)
+		}
+	}
+
+	if c.BridgeNetworkCNIConfigPath != "" && !filepath.IsAbs(c.BridgeNetworkCNIConfigPath) {
+		addErr("bridge_network_cni_config_path %q must be absolute", c.BridgeNetworkCNIConfigPath)
+	}
+
+	if c.CNIInterfacePrefix != "" && !cniInterfacePrefixRe.MatchString(c.CNIInterfacePrefix) {
+		addErr("cni_interface_prefix %q must be at most 12 letters, digits, '_', '.' or '-'",
+			c.CNIInterfacePrefix)
+	}
+
+	names := make([]string, 0, len(c.HostVolumes))
+	for name := range c.HostVolumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		vol := c.HostVolumes[name]
+		if vol == nil {
+			continue
+		}
+		if !filepath.IsAbs(vol.Path) {
+			addErr("host volume %q path %q must be absolute", name, vol.Path)
+			continue
+		}
+
+		// Host volumes must not overlap the directories the client
+		// manages, as tasks writing to them could corrupt client state or
+		// other allocations
+		for _, dir := range []struct{ name, path string }{
+			{"alloc_dir", c.AllocDir},
+			{"state_dir", c.StateDir},
+		} {
+			if pathsOverlap(vol.Path, dir.path) {
+				addErr("host volume %q path %q overlaps %s %q", name, vol.Path, dir.name, dir.path)
+			}
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// CheckHostVolumePaths checks that the path of each host volume exists. The
+// path may be a directory or a single file, such as a socket. It returns the
+// warnings for the read-only volumes whose path is missing when
+// HostVolumeAllowMissingReadOnly is set, and an error for the other volumes
+// whose path is missing.
+func (c *Config) CheckHostVolumePaths() (warnings error, err error) {
+	var mWarn, mErr multierror.Error
+
+	names := make([]string, 0, len(c.HostVolumes))
+	for name := range c.HostVolumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		vol := c.HostVolumes[name]
+		if vol == nil {
+			continue
+		}
+
+		_, err := os.Stat(vol.Path)
+		switch {
+		case os.IsNotExist(err) && vol.ReadOnly && c.HostVolumeAllowMissingReadOnly:
+			mWarn.Errors = append(mWarn.Errors, fmt.Errorf(
+				"host volume %q path %q does not exist", name, vol.Path))
+		case err != nil:
+			mErr.Errors = append(mErr.Errors, fmt.Errorf(
+				"failed to validate volume %s, err: %v", name, err))
+		}
+	}
+
+	return mWarn.ErrorOrNil(), mErr.ErrorOrNil()
+}
+
+// NetworkSummary returns a single line summarizing the client's effective
+// network configuration for logging and diagnostics: the bridge network, the
+// CNI paths and interface prefix, and the host networks. Unset values are
+// reported with the defaults they resolve to.
+func (c *Config) NetworkSummary() string {
+	bridgeName := c.BridgeNetworkName
+	if bridgeName == "" {
+		bridgeName = DefaultBridgeNetworkName
+	}
+	bridgeSubnet := c.BridgeNetworkAllocSubnet
+	if bridgeSubnet == "" {
+		bridgeSubnet = DefaultBridgeNetworkAllocSubnet
+	}
+	cniPath := c.CNIPath
+	if cniPath == "" {
+		if cniPath = os.Getenv("CNI_PATH"); cniPath == "" {
+			cniPath = DefaultCNIPath
+		}
+	}
+	cniConfigDir := c.CNIConfigDir
+	if cniConfigDir == "" {
+		cniConfigDir = DefaultCNIConfigDir
+	}
+	cniPrefix := c.CNIInterfacePrefix
+	if cniPrefix == "" {
+		cniPrefix = DefaultCNIInterfacePrefix
+	}
+
+	names := make([]string, 0, len(c.HostNetworks))
+	for name, network := range c.HostNetworks {
+		if network != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	networks := make([]string, 0, len(names))
+	for _, name := range names {
+		network := c.HostNetworks[name]
+		networks = append(networks, fmt.Sprintf("%s(cidr=%q interface=%q reserved_ports=%q)",
+			name, strings.Join(network.AllCIDRs(), ","), network.Interface, network.ReservedPorts))
+	}
+
+	return fmt.Sprintf(
+		"bridge_network_name=%q bridge_network_subnet=%q cni_path=%q cni_config_dir=%q cni_interface_prefix=%q host_networks=[%s]",
+		bridgeName, bridgeSubnet, cniPath, cniConfigDir, cniPrefix, strings.Join(networks, " "))
+}
+
+// gcStatsIntervalRatio is how many times shorter than the stats collection
+// interval the GC interval can be before Warnings flags it.
+const gcStatsIntervalRatio = 2
+
+// Warnings returns an error listing the valid but likely problematic parts of
+// the configuration.
+func (c *Config) Warnings() error {
+	var mErr multierror.Error
+
+	// The GC's disk thresholds are checked against the host stats, which
+	// are stale for most of the GC runs when they're collected much less
+	// often
+	if c.GCInterval > 0 && c.GCInterval*gcStatsIntervalRatio < c.StatsCollectionInterval {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf(
+			"gc_interval (%v) is much shorter than telemetry collection_interval (%v): garbage collection may act on stale disk usage",
+			c.GCInterval, c.StatsCollectionInterval))
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// pathsOverlap returns true if either path is equal to or inside the other.
+// Empty paths never overlap.
+func pathsOverlap(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return pathContains(a, b) || pathContains(b, a)
+}
+
+// pathContains returns true if path is equal to or inside dir.
+func pathContains(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// DefaultConfig returns the default configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Version:                 version.GetVersion(),
+		VaultConfig:             structsc.DefaultVaultConfig(),
+		ConsulConfig:            structsc.DefaultConsulConfig(),
+		LogOutput:               os.Stderr,
+		Region:                  "global",
+		StatsCollectionInterval: 1 * time.Second,
+		TLSConfig:               &structsc.TLSConfig{},
+		LogLevel:                "DEBUG",
+		GCInterval:              1 * time.Minute,
+		GCParallelDestroys:      2,
+		GCDiskUsageThreshold:    80,
+		GCInodeUsageThreshold:   70,
+		StateDirReserveMB:       DefaultStateDirReserveMB,
+		GCMaxAllocs:             50,
+		NoHostUUID:              true,
+		DisableRemoteExec:       false,
+		TemplateConfig: &ClientTemplateConfig{
+			FunctionDenylist: []string{"plugin"},
+			DisableSandbox:   false,
+			NomadRetry:       DefaultTemplateNomadRetry(),
+		},
+		RPCHoldTimeout:     5 * time.Second,
+		MaxFreezeDuration:  DefaultMaxFreezeDuration,
+		CNIPath:            DefaultCNIPath,
+		CNIConfigDir:       DefaultCNIConfigDir,
+		CNIInterfacePrefix: DefaultCNIInterfacePrefix,
+		HostNetworks:       map[string]*structs.ClientHostNetworkConfig{},
+		CgroupParent:       cgutil.DefaultCgroupParent,
+		MaxDynamicPort:     structs.DefaultMaxDynamicPort,
+		MinDynamicPort:     structs.DefaultMinDynamicPort,
+
+		CSIVolumeMountTimeout: DefaultCSIVolumeMountTimeout,
+		CSIMaxVolumesPerAlloc: DefaultCSIMaxVolumesPerAlloc,
+		CSIMaxNodeMounts:      DefaultCSIMaxNodeMounts,
+	}
+}
+
+// CSIVolumeLimits returns the maximum number of CSI volumes an allocation may
+// request and the maximum number of CSI volume mount paths published on the
+// node, using the defaults for unset limits.
+func (c *Config) CSIVolumeLimits() (int, int) {
+	perAlloc, nodeMounts := c.CSIMaxVolumesPerAlloc, c.CSIMaxNodeMounts
+	if perAlloc <= 0 {
+		perAlloc = DefaultCSIMaxVolumesPerAlloc
+	}
+	if nodeMounts <= 0 {
+		nodeMounts = DefaultCSIMaxNodeMounts
+	}
+	return perAlloc, nodeMounts
+}
+
+// DynamicPortRange returns the inclusive range of ports that dynamic ports
+// are allocated from. An unset bound defaults to structs.DefaultMinDynamicPort
+// or structs.DefaultMaxDynamicPort. An error is returned if either bound is
+// not a valid port or the range is inverted, since no dynamic ports could be
+// allocated from it.
+func (c *Config) DynamicPortRange() (int, int, error) {
+	for _, port := range []struct {
+		name  string
+		value int
+	}{
+		{"min_dynamic_port", c.MinDynamicPort},
+		{"max_dynamic_port", c.MaxDynamicPort},
+	} {
+		if port.value < 0 || port.value > 65535 {
+			return 0, 0, fmt.Errorf("%s must be between 0 and 65535, got %d", port.name, port.value)
+		}
+	}
+
+	min, max := c.MinDynamicPort, c.MaxDynamicPort
+	if min == 0 {
+		min = structs.DefaultMinDynamicPort
+	}
+	if max == 0 {
+		max = structs.DefaultMaxDynamicPort
+	}
+
+	if min > max {
+		switch {
+		case c.MinDynamicPort == 0:
+			return 0, 0, fmt.Errorf("max_dynamic_port %d is less than the default min_dynamic_port %d; set min_dynamic_port to at most %d",
+				max, min, max)
+		case c.MaxDynamicPort == 0:
+			return 0, 0, fmt.Errorf("min_dynamic_port %d is greater than the default max_dynamic_port %d; set max_dynamic_port to at least %d",
+				min, max, min)
+		default:
+			return 0, 0, fmt.Errorf("min_dynamic_port %d is greater than max_dynamic_port %d; swap the two values",
+				min, max)
+		}
+	}
+	return min, max, nil
+}
+
+// EffectiveGCThresholds returns the disk and inode usage thresholds used by
+// the garbage collector. Validate rejects thresholds outside of [0, 100], so
+// they're passed through as configured.
+func (c *Config) EffectiveGCThresholds() (disk, inode float64) {
+	return c.GCDiskUsageThreshold, c.GCInodeUsageThreshold
+}
+
+// optionEnvPrefix prefixes a strict environment variable reference in an
+// option value, written as ${env:VAR}.
+const optionEnvPrefix = "env:"
+
+// envVarNameRe matches a valid environment variable name.
+var envVarNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ExpandOptions returns a copy of options with ${VAR}, $VAR and ${env:VAR}
+// references in the values expanded using lookup, which is usually
+// os.LookupEnv. Keys are never expanded. A literal dollar sign is written as
+// $$. References of the form ${VAR} and $VAR to unset variables expand to the
+// empty string and the sorted names of those variables are returned so that
+// callers can warn about them. A ${env:VAR} reference to an unset variable, or
+// a malformed one such as a nested reference, is an error naming the option.
+func ExpandOptions(options map[string]string, lookup func(string) (string, bool)) (map[string]string, []string, error) {
+	if options == nil {
+		return nil, nil, nil
+	}
+
+	// Expand the options in a stable order so that errors are too
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var mErr multierror.Error
+	unset := make(map[string]struct{})
+	expanded := make(map[string]string, len(options))
+	for _, k := range keys {
+		expanded[k] = os.Expand(options[k], func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+
+			if !strings.HasPrefix(name, optionEnvPrefix) {
+				val, ok := lookup(name)
+				if !ok {
+					unset[name] = struct{}{}
+				}
+				return val
+			}
+
+			envName := strings.TrimPrefix(name, optionEnvPrefix)
+			if !envVarNameRe.MatchString(envName) {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf(
+					"option %q has an invalid environment variable reference ${%s}", k, name))
+				return ""
+			}
+			val, ok := lookup(envName)
+			if !ok {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf(
+					"option %q references unset environment variable %q", k, envName))
+			}
+			return val
+		})
+	}
+	if err := mErr.ErrorOrNil(); err != nil {
+		return nil, nil, err
+	}
+
+	var unsetNames []string
+	for name := range unset {
+		unsetNames = append(unsetNames, name)
+	}
+	sort.Strings(unsetNames)
+	return expanded, unsetNames, nil
+}
+
+// Read returns the specified configuration value or "".
+func (c *Config) Read(id string) string {
+	return c.Options[id]
+}
+
+// ReadDefault returns the specified configuration value, or the specified
+// default value if none is set.
+func (c *Config) ReadDefault(id string, defaultValue string) string {
+	return c.ReadAlternativeDefault([]string{id}, defaultValue)
+}
+
+// ReadAlternativeDefault returns the specified configuration value, or the
+// specified value if none is set.
+func (c *Config) ReadAlternativeDefault(ids []string, defaultValue string) string {
+	if val, ok := c.readAlternative(ids); ok {
+		return val
+	}
+
+	return defaultValue
+}
+
+// readAlternative returns the value of the first of the specified options
+// that is set, and whether any is.
+func (c *Config) readAlternative(ids []string) (string, bool) {
+	for _, id := range ids {
+		val, ok := c.Options[id]
+		if ok {
+			return val, true
+		}
+	}
+
+	return "", false
+}
+
+// ReadBool parses the specified option as a boolean.
+func (c *Config) ReadBool(id string) (bool, error) {
+	val, ok := c.Options[id]
+	if !ok {
+		return false, fmt.Errorf("Specified config is missing from options")
+	}
+	bval, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("Failed to parse %s as bool: %s", val, err)
+	}
+	return bval, nil
+}
+
+// ReadBoolDefault tries to parse the specified option as a boolean. If there is
+// an error in parsing, the default option is returned.
+func (c *Config) ReadBoolDefault(id string, defaultValue bool) bool {
+	val, err := c.ReadBool(id)
+	if err != nil {
+		return defaultValue
+	}
+	return val
+}
+
+// ReadInt parses the specified option as a int.
+func (c *Config) ReadInt(id string) (int, error) {
+	val, ok := c.Options[id]
+	if !ok {
+		return 0, fmt.Errorf("Specified config is missing from options")
+	}
+	ival, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse %s as int: %s", val, err)
+	}
+	return ival, nil
+}
+
+// ReadIntDefault tries to parse the specified option as a int. If there is
+// an error in parsing, the default option is returned.
+func (c *Config) ReadIntDefault(id string, defaultValue int) int {
+	val, err := c.ReadInt(id)
+	if err != nil {
+		return defaultValue
+	}
+	return val
+}
+
+// ReadFloat parses the specified option as a float.
+func (c *Config) ReadFloat(id string) (float64, error) {
+	return c.ReadFloatAlternative([]string{id})
+}
+
+// ReadFloatAlternative parses the first of the specified options that is set
+// as a float.
+func (c *Config) ReadFloatAlternative(ids []string) (float64, error) {
+	val, ok := c.readAlternative(ids)
+	if !ok {
+		return 0, fmt.Errorf("Specified config is missing from options")
+	}
+	fval, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse %s as float: %s", val, err)
+	}
+	return fval, nil
+}
+
+// ReadFloatDefault tries to parse the specified option as a float. If there is
+// an error in parsing, the default option is returned.
+func (c *Config) ReadFloatDefault(id string, defaultValue float64) float64 {
+	return c.ReadFloatAlternativeDefault([]string{id}, defaultValue)
+}
+
+// ReadFloatAlternativeDefault tries to parse the first of the specified
+// options that is set as a float. If none is set or there is an error in
+// parsing, the default option is returned.
+func (c *Config) ReadFloatAlternativeDefault(ids []string, defaultValue float64) float64 {
+	val, err := c.ReadFloatAlternative(ids)
+	if err != nil {
+		return defaultValue
+	}
+	return val
+}
+
+// ReadBytesSize parses the specified option as a size in bytes. Humanized
+// sizes such as "10GB" or "512 MiB" are accepted, as well as plain numbers of
+// bytes.
+func (c *Config) ReadBytesSize(id string) (uint64, error) {
+	return c.ReadBytesSizeAlternative([]string{id})
+}
+
+// ReadBytesSizeAlternative parses the first of the specified options that is
+// set as a size in bytes.
+func (c *Config) ReadBytesSizeAlternative(ids []string) (uint64, error) {
+	val, ok := c.readAlternative(ids)
+	if !ok {
+		return 0, fmt.Errorf("Specified config is missing from options")
+	}
+	bval, err := humanize.ParseBytes(strings.TrimSpace(val))
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse %s as bytes size: %s", val, err)
+	}
+	return bval, nil
+}
+
+// ReadBytesSizeDefault tries to parse the specified option as a size in
+// bytes. If there is an error in parsing, the default option is returned.
+func (c *Config) ReadBytesSizeDefault(id string, defaultValue uint64) uint64 {
+	return c.ReadBytesSizeAlternativeDefault([]string{id}, defaultValue)
+}
+
+// ReadBytesSizeAlternativeDefault tries to parse the first of the specified
+// options that is set as a size in bytes. If none is set or there is an error
+// in parsing, the default option is returned.
+func (c *Config) ReadBytesSizeAlternativeDefault(ids []string, defaultValue uint64) uint64 {
+	val, err := c.ReadBytesSizeAlternative(ids)
+	if err != nil {
+		return defaultValue
+	}
+	return val
+}
+
+// ReadDuration parses the specified option as a duration. Days and weeks are
+// accepted in addition to the units of time.ParseDuration, such as "1w3d12h".
+func (c *Config) ReadDuration(id string) (time.Duration, error) {
+	val, ok := c.Options[id]
+	if !ok {
+		return time.Duration(0), fmt.Errorf("Specified config is missing from options")
+	}
+	dval, err := parseDuration(val)
+	if err != nil {
+		return time.Duration(0), fmt.Errorf("Failed to parse %s as time duration: %s", val, err)
+	}
+	return dval, nil
+}
+
+// parseDuration parses a duration the same way as time.ParseDuration, but
+// also accepts days ("d") and weeks ("w"). These are rewritten as the
+// equivalent number of hours before the duration is parsed.
+func parseDuration(s string) (time.Duration, error) {
+	var expanded strings.Builder
+	rest := s
+	if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+		expanded.WriteByte(rest[0])
+		rest = rest[1:]
+	}
+
+	for rest != "" {
+		// Each component is a number followed by its unit
+		i := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i == -1 {
+			i = len(rest)
+		}
+		j := strings.IndexFunc(rest[i:], func(r rune) bool {
+			return (r >= '0' && r <= '9') || r == '.'
+		})
+		if j == -1 {
+			j = len(rest) - i
+		}
+		num, unit := rest[:i], rest[i:i+j]
+		rest = rest[i+j:]
+
+		var multiplier time.Duration
+		switch unit {
+		case "d":
+			multiplier = 24
+		case "w":
+			multiplier = 24 * 7
+		default:
+			expanded.WriteString(num + unit)
+			continue
+		}
+
+		// Parse the value as hours so that fractions are exact
+		hours, err := time.ParseDuration(num + "h")
+		if err != nil || hours > math.MaxInt64/multiplier {
+			return 0, fmt.Errorf("time: invalid duration %q", s)
+		}
+		expanded.WriteString((hours * multiplier).String())
+	}
+
+	return time.ParseDuration(expanded.String())
+}
+
+// ReadDurationDefault tries to parse the specified option as a duration. If there is
+// an error in parsing, the default option is returned.
 func (c *Config) ReadDurationDefault(id string, defaultValue time.Duration) time.Duration {
 	val, err := c.ReadDuration(id)
 	if err != nil {
@@ -892,6 +3030,156 @@ func (c *Config) ReadStringListAlternativeToMapDefault(keys []string, defaultVal
 	return splitValue(val)
 }
 
+// ReadStringSlice parses the first of the specified options that is set as a
+// comma separated list. Unlike ReadStringListToMap the order of the elements
+// is preserved. Elements are trimmed and empty elements are dropped, so an
+// empty or unset option returns an empty list.
+func (c *Config) ReadStringSlice(keys ...string) []string {
+	val := c.ReadAlternativeDefault(keys, "")
+
+	list := []string{}
+	for _, e := range strings.Split(val, ",") {
+		if trimmed := strings.TrimSpace(e); trimmed != "" {
+			list = append(list, trimmed)
+		}
+	}
+	return list
+}
+
+// EffectiveEnvDenylist returns the host environment variables withheld from
+// the tasks of the namespace: the union of the "env.denylist" option, which
+// defaults to DefaultEnvDenylist, and the EnvDenylistPerNamespace lists of
+// the namespace and of the empty namespace.
+func (c *Config) EffectiveEnvDenylist(namespace string) []string {
+	// COMPAT(1.0) using inclusive language, blacklist is kept for backward compatibility.
+	denylist := strings.Split(c.ReadAlternativeDefault(
+		[]string{"env.denylist", "env.blacklist"},
+		DefaultEnvDenylist,
+	), ",")
+
+	seen := make(map[string]struct{}, len(denylist))
+	for _, env := range denylist {
+		seen[env] = struct{}{}
+	}
+
+	namespaces := []string{""}
+	if namespace != "" {
+		namespaces = append(namespaces, namespace)
+	}
+	for _, ns := range namespaces {
+		for _, env := range c.EnvDenylistPerNamespace[ns] {
+			if _, ok := seen[env]; ok {
+				continue
+			}
+			seen[env] = struct{}{}
+			denylist = append(denylist, env)
+		}
+	}
+	return denylist
+}
+
+// ReadUserDenylistFor returns the users that tasks using the driver may not
+// run as. The "user.denylist.<driver>" option overrides the "user.denylist"
+// option for the driver, which defaults to DefaultUserDenylist.
+func (c *Config) ReadUserDenylistFor(driver string) map[string]struct{} {
+	// COMPAT(1.0) uses inclusive language. blacklist is kept for backward compatilibity.
+	return c.ReadStringListAlternativeToMapDefault(
+		[]string{"user.denylist." + driver, "user.denylist", "user.blacklist"},
+		DefaultUserDenylist,
+	)
+}
+
+// EffectiveUserDenylist returns the users that tasks using the driver may not
+// run as, as read by ReadUserDenylistFor, and whether the denylist is applied
+// to tasks using the driver. The "user.checked_drivers" option defaults to
+// DefaultUserCheckedDrivers.
+func (c *Config) EffectiveUserDenylist(driver string) (map[string]struct{}, bool) {
+	denylist := c.ReadUserDenylistFor(driver)
+	checkedDrivers := c.ReadStringListToMapDefault("user.checked_drivers", DefaultUserCheckedDrivers)
+	_, checked := checkedDrivers[driver]
+	return denylist, checked
+}
+
+// EffectiveChrootFragments returns the chroot fragments tasks may request,
+// the operator's ChrootFragments merged over DefaultChrootFragments.
+func (c *Config) EffectiveChrootFragments() map[string][]string {
+	fragments := make(map[string][]string, len(DefaultChrootFragments)+len(c.ChrootFragments))
+	for name, paths := range DefaultChrootFragments {
+		fragments[name] = paths
+	}
+	for name, paths := range c.ChrootFragments {
+		fragments[name] = paths
+	}
+	return fragments
+}
+
+// EffectiveChrootEnv returns the chroot embedded in the directory of tasks:
+// the ChrootEnv, or DefaultChrootEnv if unset. If ChrootEnvMergeDefaults is
+// set, the ChrootEnv entries are merged over DefaultChrootEnv instead, and an
+// entry with an empty destination omits the default path.
+func (c *Config) EffectiveChrootEnv() map[string]string {
+	if !c.ChrootEnvMergeDefaults {
+		if len(c.ChrootEnv) > 0 {
+			return c.ChrootEnv
+		}
+		return DefaultChrootEnv
+	}
+
+	chroot := helper.CopyMapStringString(DefaultChrootEnv)
+	for src, dst := range c.ChrootEnv {
+		if dst == "" {
+			delete(chroot, src)
+			continue
+		}
+		chroot[src] = dst
+	}
+	return chroot
+}
+
+// TaskChrootEnv returns the chroot for a task listing the given
+// chroot_extras: the EffectiveChrootEnv plus the paths of each fragment. An
+// error naming the available fragments is returned if an extra is unknown.
+func (c *Config) TaskChrootEnv(extras []string) (map[string]string, error) {
+	chroot := c.EffectiveChrootEnv()
+	if len(extras) == 0 {
+		return chroot, nil
+	}
+
+	fragments := c.EffectiveChrootFragments()
+	result := helper.CopyMapStringString(chroot)
+	for _, extra := range extras {
+		paths, ok := fragments[extra]
+		if !ok {
+			available := make([]string, 0, len(fragments))
+			for name := range fragments {
+				available = append(available, name)
+			}
+			sort.Strings(available)
+			return nil, fmt.Errorf("unknown chroot_extras fragment %q, available fragments: %s",
+				extra, strings.Join(available, ", "))
+		}
+		for _, path := range paths {
+			result[path] = path
+		}
+	}
+	return result, nil
+}
+
+// MissingChrootFragmentPaths returns the paths of each chroot fragment that
+// don't exist on this host. They are skipped when building the chroot of a
+// task requesting the fragment.
+func (c *Config) MissingChrootFragmentPaths() map[string][]string {
+	missing := make(map[string][]string)
+	for name, paths := range c.EffectiveChrootFragments() {
+		for _, path := range paths {
+			if _, err := os.Stat(path); err != nil {
+				missing[name] = append(missing[name], path)
+			}
+		}
+	}
+	return missing
+}
+
 // splitValue parses the value as a comma separated list.
 func splitValue(val string) map[string]struct{} {
 	list := make(map[string]struct{})
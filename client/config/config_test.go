@@ -1,11 +1,20 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/consul-template/config"
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/hashicorp/nomad/nomad/structs"
+	structsc "github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/stretchr/testify/require"
 )
 
@@ -42,6 +51,710 @@ func TestConfigReadDefault(t *testing.T) {
 	}
 }
 
+func TestConfigReadFloat(t *testing.T) {
+	config := Config{}
+
+	_, err := config.ReadFloat("ratio")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing")
+
+	config.Options = map[string]string{
+		"ratio":   "1.5",
+		"integer": "2",
+		"invalid": "one and a half",
+	}
+
+	actual, err := config.ReadFloat("ratio")
+	require.NoError(t, err)
+	require.Equal(t, 1.5, actual)
+
+	actual, err = config.ReadFloat("integer")
+	require.NoError(t, err)
+	require.Equal(t, 2.0, actual)
+
+	_, err = config.ReadFloat("invalid")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Failed to parse")
+}
+
+func TestConfigReadFloatDefault(t *testing.T) {
+	config := Config{}
+
+	require.Equal(t, 0.75, config.ReadFloatDefault("ratio", 0.75))
+
+	config.Options = map[string]string{
+		"ratio":   "1.5",
+		"invalid": "one and a half",
+	}
+	require.Equal(t, 1.5, config.ReadFloatDefault("ratio", 0.75))
+	require.Equal(t, 0.75, config.ReadFloatDefault("invalid", 0.75))
+}
+
+func TestConfigReadFloat_Values(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected float64
+		Err      bool
+	}{
+		{Value: "0.5", Expected: 0.5},
+		{Value: "-2", Expected: -2},
+		{Value: "1e3", Expected: 1000},
+		{Value: " 1.5 ", Expected: 1.5},
+		{Value: "\t3\n", Expected: 3},
+		{Value: "", Err: true},
+		{Value: "   ", Err: true},
+		{Value: "1.5.5", Err: true},
+		{Value: "1,5", Err: true},
+		{Value: "1.5GHz", Err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%q", tc.Value), func(t *testing.T) {
+			config := Config{Options: map[string]string{"ratio": tc.Value}}
+			actual, err := config.ReadFloat("ratio")
+			if tc.Err {
+				require.Error(t, err)
+				require.Equal(t, 0.25, config.ReadFloatDefault("ratio", 0.25))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Expected, actual)
+			require.Equal(t, tc.Expected, config.ReadFloatDefault("ratio", 0.25))
+		})
+	}
+}
+
+func TestConfigReadFloatAlternative(t *testing.T) {
+	config := Config{}
+
+	_, err := config.ReadFloatAlternative([]string{"cpu.ratio", "cpu.ratio_old"})
+	require.Error(t, err)
+	require.Equal(t, 0.25, config.ReadFloatAlternativeDefault([]string{"cpu.ratio", "cpu.ratio_old"}, 0.25))
+
+	// The alternative is read when the first option isn't set
+	config.Options = map[string]string{"cpu.ratio_old": "0.5"}
+	actual, err := config.ReadFloatAlternative([]string{"cpu.ratio", "cpu.ratio_old"})
+	require.NoError(t, err)
+	require.Equal(t, 0.5, actual)
+
+	// The first option set wins, even if it is malformed
+	config.Options["cpu.ratio"] = "half"
+	_, err = config.ReadFloatAlternative([]string{"cpu.ratio", "cpu.ratio_old"})
+	require.Error(t, err)
+	require.Equal(t, 0.25, config.ReadFloatAlternativeDefault([]string{"cpu.ratio", "cpu.ratio_old"}, 0.25))
+
+	config.Options["cpu.ratio"] = "0.75"
+	require.Equal(t, 0.75, config.ReadFloatAlternativeDefault([]string{"cpu.ratio", "cpu.ratio_old"}, 0.25))
+}
+
+func TestConfigReadBytesSize(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected uint64
+		Err      bool
+	}{
+		{Value: "1024", Expected: 1024},
+		{Value: "10GB", Expected: 10 * 1000 * 1000 * 1000},
+		{Value: "10 GiB", Expected: 10 * 1024 * 1024 * 1024},
+		{Value: "512mb", Expected: 512 * 1000 * 1000},
+		{Value: "1.5 KiB", Expected: 1536},
+		{Value: " 2MiB ", Expected: 2 * 1024 * 1024},
+		{Value: "", Err: true},
+		{Value: "  ", Err: true},
+		{Value: "GB", Err: true},
+		{Value: "10 parsecs", Err: true},
+		{Value: "-1GB", Err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%q", tc.Value), func(t *testing.T) {
+			config := Config{Options: map[string]string{"disk": tc.Value}}
+			actual, err := config.ReadBytesSize("disk")
+			if tc.Err {
+				require.Error(t, err)
+				require.Equal(t, uint64(42), config.ReadBytesSizeDefault("disk", 42))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Expected, actual)
+			require.Equal(t, tc.Expected, config.ReadBytesSizeDefault("disk", 42))
+		})
+	}
+}
+
+func TestConfigReadBytesSizeAlternative(t *testing.T) {
+	config := Config{}
+
+	_, err := config.ReadBytesSize("disk")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing")
+	require.Equal(t, uint64(42), config.ReadBytesSizeAlternativeDefault([]string{"disk", "disk_old"}, 42))
+
+	config.Options = map[string]string{"disk_old": "1KB"}
+	actual, err := config.ReadBytesSizeAlternative([]string{"disk", "disk_old"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), actual)
+
+	config.Options["disk"] = "lots"
+	require.Equal(t, uint64(42), config.ReadBytesSizeAlternativeDefault([]string{"disk", "disk_old"}, 42))
+
+	config.Options["disk"] = "2KB"
+	require.Equal(t, uint64(2000), config.ReadBytesSizeAlternativeDefault([]string{"disk", "disk_old"}, 42))
+}
+
+func TestConfigReadStringSlice(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected []string
+	}{
+		{Value: "", Expected: []string{}},
+		{Value: "   ", Expected: []string{}},
+		{Value: "a", Expected: []string{"a"}},
+		{Value: "c,a,b", Expected: []string{"c", "a", "b"}},
+		{Value: " c , a ,b ", Expected: []string{"c", "a", "b"}},
+		{Value: "a,,b,", Expected: []string{"a", "b"}},
+		{Value: "a,a", Expected: []string{"a", "a"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%q", tc.Value), func(t *testing.T) {
+			config := Config{Options: map[string]string{"list": tc.Value}}
+			require.Equal(t, tc.Expected, config.ReadStringSlice("list"))
+		})
+	}
+}
+
+func TestConfigReadStringSlice_Alternative(t *testing.T) {
+	config := Config{}
+	require.Equal(t, []string{}, config.ReadStringSlice("list", "list_old"))
+
+	config.Options = map[string]string{"list_old": "b,a"}
+	require.Equal(t, []string{"b", "a"}, config.ReadStringSlice("list", "list_old"))
+
+	// An empty first option is still the one read
+	config.Options["list"] = ""
+	require.Equal(t, []string{}, config.ReadStringSlice("list", "list_old"))
+}
+
+func TestConfigReadDuration(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected time.Duration
+		Err      bool
+	}{
+		{Value: "90s", Expected: 90 * time.Second},
+		{Value: "1h30m", Expected: 90 * time.Minute},
+		{Value: "500ms", Expected: 500 * time.Millisecond},
+		{Value: "7d", Expected: 168 * time.Hour},
+		{Value: "2w", Expected: 336 * time.Hour},
+		{Value: "1.5d", Expected: 36 * time.Hour},
+		{Value: "1w3d12h", Expected: 252 * time.Hour},
+		{Value: "1d30m", Expected: 24*time.Hour + 30*time.Minute},
+		{Value: "-1d", Expected: -24 * time.Hour},
+		{Value: "", Err: true},
+		{Value: "d", Err: true},
+		{Value: "7", Err: true},
+		{Value: "1dd", Err: true},
+		{Value: "1.5.5d", Err: true},
+		{Value: "1y", Err: true},
+		{Value: "1d-2h", Err: true},
+		{Value: "99999999999w", Err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Value, func(t *testing.T) {
+			config := Config{Options: map[string]string{"interval": tc.Value}}
+			actual, err := config.ReadDuration("interval")
+			if tc.Err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Expected, actual)
+		})
+	}
+}
+
+func TestConfigReadDurationDefault(t *testing.T) {
+	config := Config{}
+
+	require.Equal(t, time.Minute, config.ReadDurationDefault("interval", time.Minute))
+
+	config.Options = map[string]string{
+		"interval": "1w",
+		"invalid":  "1 week",
+	}
+	require.Equal(t, 168*time.Hour, config.ReadDurationDefault("interval", time.Minute))
+	require.Equal(t, time.Minute, config.ReadDurationDefault("invalid", time.Minute))
+}
+
+func TestExpandOptions(t *testing.T) {
+	env := map[string]string{
+		"DOCKER_HOST": "unix:///var/run/docker.sock",
+		"EMPTY":       "",
+		"DIR":         "/opt",
+	}
+	lookup := func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
+
+	cases := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"no references", "plain", "plain"},
+		{"braced", "${DOCKER_HOST}", "unix:///var/run/docker.sock"},
+		{"unbraced", "$DIR/plugins", "/opt/plugins"},
+		{"embedded", "path=${DIR}/bin:$DIR/sbin", "path=/opt/bin:/opt/sbin"},
+		{"empty", "x${EMPTY}y", "xy"},
+		{"escaped", "cost $$5", "cost $5"},
+		{"escaped reference", "$${DIR}", "${DIR}"},
+		{"escaped then reference", "$$$DIR", "$/opt"},
+		{"unset", "a${UNSET}b", "ab"},
+		{"env", "${env:DOCKER_HOST}", "unix:///var/run/docker.sock"},
+		{"env embedded", "${env:DIR}/bin:${DIR}/sbin", "/opt/bin:/opt/sbin"},
+		{"env empty", "x${env:EMPTY}y", "xy"},
+		{"env escaped", "$${env:UNSET}", "${env:UNSET}"},
+		{"env escaped then reference", "$$${env:DIR}", "$/opt"},
+		{"env unbraced", "$env:DIR", ":DIR"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expanded, _, err := ExpandOptions(map[string]string{"key": tc.value}, lookup)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, expanded["key"])
+		})
+	}
+}
+
+func TestExpandOptions_EnvErrors(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "SET" {
+			return "value", true
+		}
+		return "", false
+	}
+
+	cases := []struct {
+		name   string
+		value  string
+		errMsg string
+	}{
+		{
+			"unset",
+			"${env:MISSING}",
+			`option "key" references unset environment variable "MISSING"`,
+		},
+		{
+			"nested",
+			"${env:${env:SET}}",
+			`option "key" has an invalid environment variable reference ${env:${env:SET}`,
+		},
+		{
+			"nested name",
+			"${env:PREFIX_${SET}}",
+			`option "key" has an invalid environment variable reference ${env:PREFIX_${SET}`,
+		},
+		{
+			"empty name",
+			"${env:}",
+			`option "key" has an invalid environment variable reference ${env:}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expanded, _, err := ExpandOptions(map[string]string{"key": tc.value}, lookup)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.errMsg)
+			require.Nil(t, expanded)
+		})
+	}
+
+	// Every invalid option is reported, and keys are never expanded
+	_, _, err := ExpandOptions(map[string]string{
+		"a":              "${env:MISSING_A}",
+		"b":              "${env:MISSING_B}",
+		"${env:MISSING}": "${env:SET}",
+	}, lookup)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `option "a" references unset environment variable "MISSING_A"`)
+	require.Contains(t, err.Error(), `option "b" references unset environment variable "MISSING_B"`)
+	require.NotContains(t, err.Error(), `"MISSING"`)
+}
+
+func TestExpandOptions_Unset(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "SET" {
+			return "value", true
+		}
+		return "", false
+	}
+
+	options := map[string]string{
+		"a": "${SET}",
+		"b": "$ZED and ${ALPHA}",
+		"c": "${ALPHA}",
+		"d": "$${ESCAPED}",
+	}
+	expanded, unset, err := ExpandOptions(options, lookup)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ALPHA", "ZED"}, unset)
+	require.Equal(t, map[string]string{
+		"a": "value",
+		"b": " and ",
+		"c": "",
+		"d": "${ESCAPED}",
+	}, expanded)
+
+	// The original options are not modified
+	require.Equal(t, "${SET}", options["a"])
+
+	expanded, unset, err = ExpandOptions(nil, lookup)
+	require.NoError(t, err)
+	require.Nil(t, expanded)
+	require.Empty(t, unset)
+}
+
+func TestConfig_EffectiveGCThresholds(t *testing.T) {
+	cases := []struct {
+		Name          string
+		Disk          float64
+		Inode         float64
+		ExpectedDisk  float64
+		ExpectedInode float64
+	}{
+		{
+			"in-range",
+			80,
+			70,
+			80,
+			70,
+		},
+		{
+			"lower-boundary",
+			0,
+			0,
+			0,
+			0,
+		},
+		{
+			"upper-boundary",
+			100,
+			100,
+			100,
+			100,
+		},
+	}
+
+	for _, _case := range cases {
+		t.Run(_case.Name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Logger = testlog.HCLogger(t)
+			config.GCDiskUsageThreshold = _case.Disk
+			config.GCInodeUsageThreshold = _case.Inode
+
+			require.NoError(t, config.Validate())
+
+			disk, inode := config.EffectiveGCThresholds()
+			require.Equal(t, _case.ExpectedDisk, disk)
+			require.Equal(t, _case.ExpectedInode, inode)
+		})
+	}
+}
+
+func TestConfig_EffectiveUserDenylist(t *testing.T) {
+	cases := []struct {
+		Name             string
+		Options          map[string]string
+		Driver           string
+		ExpectedDenylist []string
+		ExpectedChecked  bool
+	}{
+		{
+			"default-checked",
+			nil,
+			"exec",
+			[]string{"root", "Administrator"},
+			true,
+		},
+		{
+			"default-unchecked",
+			nil,
+			"docker",
+			[]string{"root", "Administrator"},
+			false,
+		},
+		{
+			"denylist",
+			map[string]string{"user.denylist": "root, nobody"},
+			"java",
+			[]string{"root", "nobody"},
+			true,
+		},
+		{
+			"blacklist",
+			map[string]string{"user.blacklist": "nobody"},
+			"qemu",
+			[]string{"nobody"},
+			true,
+		},
+		{
+			"denylist-over-blacklist",
+			map[string]string{"user.denylist": "root", "user.blacklist": "nobody"},
+			"exec",
+			[]string{"root"},
+			true,
+		},
+		{
+			"checked-drivers",
+			map[string]string{"user.checked_drivers": "docker,raw_exec"},
+			"docker",
+			[]string{"root", "Administrator"},
+			true,
+		},
+		{
+			"checked-drivers-replace-defaults",
+			map[string]string{"user.checked_drivers": "docker,raw_exec"},
+			"exec",
+			[]string{"root", "Administrator"},
+			false,
+		},
+		{
+			"driver-denylist",
+			map[string]string{"user.denylist": "root", "user.denylist.java": "nobody"},
+			"java",
+			[]string{"nobody"},
+			true,
+		},
+		{
+			"driver-denylist-other-driver",
+			map[string]string{"user.denylist": "root", "user.denylist.java": "nobody"},
+			"exec",
+			[]string{"root"},
+			true,
+		},
+		{
+			"driver-denylist-empty",
+			map[string]string{"user.denylist.java": ""},
+			"java",
+			[]string{},
+			true,
+		},
+	}
+
+	for _, _case := range cases {
+		t.Run(_case.Name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Options = _case.Options
+
+			denylist, checked := config.EffectiveUserDenylist(_case.Driver)
+			require.Equal(t, _case.ExpectedChecked, checked)
+			require.Len(t, denylist, len(_case.ExpectedDenylist))
+			for _, user := range _case.ExpectedDenylist {
+				require.Contains(t, denylist, user)
+			}
+		})
+	}
+}
+
+func TestConfig_EffectiveEnvDenylist(t *testing.T) {
+	config := DefaultConfig()
+	config.Options = map[string]string{"env.denylist": "CONSUL_TOKEN,VAULT_TOKEN"}
+
+	// Without per-namespace lists only the option applies
+	require.Equal(t, []string{"CONSUL_TOKEN", "VAULT_TOKEN"}, config.EffectiveEnvDenylist("untrusted"))
+
+	config.EnvDenylistPerNamespace = map[string][]string{
+		"":          {"GITHUB_TOKEN", "VAULT_TOKEN"},
+		"untrusted": {"AWS_SECRET_ACCESS_KEY", "GITHUB_TOKEN"},
+	}
+
+	// Namespaces get the union of the option and of the lists of the empty
+	// namespace and of their own
+	require.Equal(t, []string{"CONSUL_TOKEN", "VAULT_TOKEN", "GITHUB_TOKEN", "AWS_SECRET_ACCESS_KEY"},
+		config.EffectiveEnvDenylist("untrusted"))
+	require.Equal(t, []string{"CONSUL_TOKEN", "VAULT_TOKEN", "GITHUB_TOKEN"},
+		config.EffectiveEnvDenylist("default"))
+	require.Equal(t, []string{"CONSUL_TOKEN", "VAULT_TOKEN", "GITHUB_TOKEN"},
+		config.EffectiveEnvDenylist(""))
+
+	// The default denylist applies when the option isn't set
+	config.Options = nil
+	denylist := config.EffectiveEnvDenylist("untrusted")
+	require.Subset(t, denylist, strings.Split(DefaultEnvDenylist, ","))
+	require.Subset(t, denylist, []string{"GITHUB_TOKEN", "AWS_SECRET_ACCESS_KEY"})
+
+	// The lists survive Copy and Merge without being shared
+	nc := config.Copy()
+	require.Equal(t, config.EnvDenylistPerNamespace, nc.EnvDenylistPerNamespace)
+	nc.EnvDenylistPerNamespace["untrusted"][0] = "CHANGED"
+	require.Equal(t, "AWS_SECRET_ACCESS_KEY", config.EnvDenylistPerNamespace["untrusted"][0])
+
+	merged := config.Merge(&Config{EnvDenylistPerNamespace: map[string][]string{
+		"untrusted": {"GOOGLE_APPLICATION_CREDENTIALS"},
+		"batch":     {"AWS_SECRET_ACCESS_KEY"},
+	}})
+	require.Equal(t, map[string][]string{
+		"":          {"GITHUB_TOKEN", "VAULT_TOKEN"},
+		"untrusted": {"GOOGLE_APPLICATION_CREDENTIALS"},
+		"batch":     {"AWS_SECRET_ACCESS_KEY"},
+	}, merged.EnvDenylistPerNamespace)
+	require.Len(t, config.EnvDenylistPerNamespace, 2)
+}
+
+func TestConfig_TaskChrootEnv(t *testing.T) {
+	config := DefaultConfig()
+	config.ChrootEnv = map[string]string{"/bin": "/bin"}
+	config.ChrootFragments = map[string][]string{
+		"zoneinfo": {"/opt/zoneinfo"},
+		"fonts":    {"/usr/share/fonts"},
+	}
+
+	// Without extras the client's chroot is used as is
+	chroot, err := config.TaskChrootEnv(nil)
+	require.NoError(t, err)
+	require.Equal(t, config.ChrootEnv, chroot)
+
+	// Fragments are added to a copy of the chroot, and operator fragments
+	// replace the defaults of the same name
+	chroot, err = config.TaskChrootEnv([]string{"zoneinfo", "fonts", "locales"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"/bin":              "/bin",
+		"/opt/zoneinfo":     "/opt/zoneinfo",
+		"/usr/share/fonts":  "/usr/share/fonts",
+		"/usr/lib/locale":   "/usr/lib/locale",
+		"/usr/share/locale": "/usr/share/locale",
+		"/usr/share/i18n":   "/usr/share/i18n",
+	}, chroot)
+	require.Len(t, config.ChrootEnv, 1)
+
+	// Unknown fragments list the available ones
+	_, err = config.TaskChrootEnv([]string{"zoneinfo", "java"})
+	require.EqualError(t, err, `unknown chroot_extras fragment "java", available fragments: ca-certificates, fonts, locales, zoneinfo`)
+
+	// The default chroot is used when none is configured
+	config.ChrootEnv = nil
+	chroot, err = config.TaskChrootEnv([]string{"zoneinfo"})
+	require.NoError(t, err)
+	require.Contains(t, chroot, "/usr")
+	require.Contains(t, chroot, "/opt/zoneinfo")
+	require.NotContains(t, DefaultChrootEnv, "/opt/zoneinfo")
+}
+
+func TestConfig_CheckHostVolumePaths(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	missing := filepath.Join(dir, "missing")
+
+	// Directories and single files, such as sockets, are both accepted
+	c := DefaultConfig()
+	c.HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"data": {Name: "data", Path: dir},
+		"file": {Name: "file", Path: file},
+	}
+	warnings, err := c.CheckHostVolumePaths()
+	require.NoError(t, err)
+	require.NoError(t, warnings)
+
+	// Missing paths are rejected
+	c.HostVolumes["missing"] = &structs.ClientHostVolumeConfig{Name: "missing", Path: missing, ReadOnly: true}
+	warnings, err = c.CheckHostVolumePaths()
+	require.NoError(t, warnings)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to validate volume missing")
+
+	// Missing read-only paths may only be warned about
+	c.HostVolumeAllowMissingReadOnly = true
+	warnings, err = c.CheckHostVolumePaths()
+	require.Error(t, warnings)
+	require.Contains(t, warnings.Error(), fmt.Sprintf("host volume \"missing\" path %q does not exist", missing))
+	require.NoError(t, err)
+
+	// Missing writable paths are still rejected
+	c.HostVolumes["missing"].ReadOnly = false
+	warnings, err = c.CheckHostVolumePaths()
+	require.NoError(t, warnings)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to validate volume missing")
+}
+
+func TestConfig_NetworkSummary(t *testing.T) {
+	t.Setenv("CNI_PATH", "")
+
+	// Unset values are reported with their defaults
+	c := &Config{}
+	require.Equal(t,
+		`bridge_network_name="nomad" bridge_network_subnet="172.26.64.0/20" cni_path="/opt/cni/bin" cni_config_dir="/opt/cni/config" cni_interface_prefix="eth" host_networks=[]`,
+		c.NetworkSummary())
+
+	// The CNI_PATH environment variable is used when cni_path is unset
+	t.Setenv("CNI_PATH", "/usr/lib/cni")
+	require.Contains(t, c.NetworkSummary(), `cni_path="/usr/lib/cni"`)
+
+	c = DefaultConfig()
+	c.BridgeNetworkName = "br0"
+	c.BridgeNetworkAllocSubnet = "10.10.0.0/16"
+	c.CNIPath = "/opt/cni/bin:/usr/local/cni"
+	c.CNIConfigDir = "/etc/cni"
+	c.CNIInterfacePrefix = "cni"
+	c.HostNetworks = map[string]*structs.ClientHostNetworkConfig{
+		"public":  {Name: "public", CIDR: "203.0.113.0/24", ReservedPorts: "22"},
+		"private": {Name: "private", Interface: "eth1"},
+		"bonded":  {Name: "bonded", CIDRs: []string{"10.0.0.0/8", "fd00::/8"}, Interface: "bond*"},
+	}
+	require.Equal(t,
+		`bridge_network_name="br0" bridge_network_subnet="10.10.0.0/16" cni_path="/opt/cni/bin:/usr/local/cni" cni_config_dir="/etc/cni" cni_interface_prefix="cni" `+
+			`host_networks=[bonded(cidr="10.0.0.0/8,fd00::/8" interface="bond*" reserved_ports="") private(cidr="" interface="eth1" reserved_ports="") public(cidr="203.0.113.0/24" interface="" reserved_ports="22")]`,
+		c.NetworkSummary())
+}
+
+func TestConfig_EffectiveChrootEnv(t *testing.T) {
+	config := DefaultConfig()
+
+	// The default chroot is used when none is configured
+	require.Equal(t, DefaultChrootEnv, config.EffectiveChrootEnv())
+
+	// The configured chroot replaces the default one
+	config.ChrootEnv = map[string]string{
+		"/opt/myapp/bin": "/bin",
+		"/usr":           "/opt/usr",
+		"/lib64":         "",
+	}
+	require.Equal(t, config.ChrootEnv, config.EffectiveChrootEnv())
+
+	// Unless it's merged over the default one, overriding and omitting
+	// default paths
+	config.ChrootEnvMergeDefaults = true
+	chroot := config.EffectiveChrootEnv()
+	require.Len(t, chroot, len(DefaultChrootEnv))
+	require.Equal(t, "/bin", chroot["/opt/myapp/bin"])
+	require.Equal(t, "/opt/usr", chroot["/usr"])
+	require.Equal(t, "/etc", chroot["/etc"])
+	require.NotContains(t, chroot, "/lib64")
+
+	// Omitting a path that isn't a default does nothing
+	config.ChrootEnv = map[string]string{"/opt": ""}
+	require.Equal(t, DefaultChrootEnv, config.EffectiveChrootEnv())
+
+	// Neither the default nor the configured chroot are modified
+	require.Equal(t, "/usr", DefaultChrootEnv["/usr"])
+	require.Contains(t, DefaultChrootEnv, "/lib64")
+
+	// Tasks' chroots are built from the merged chroot
+	config.ChrootEnv = map[string]string{"/lib64": ""}
+	chroot, err := config.TaskChrootEnv([]string{"zoneinfo"})
+	require.NoError(t, err)
+	require.NotContains(t, chroot, "/lib64")
+	require.Contains(t, chroot, "/usr/share/zoneinfo")
+	require.Contains(t, chroot, "/bin")
+}
+
 func mockWaitConfig() *WaitConfig {
 	return &WaitConfig{
 		Min: helper.TimeToPtr(5 * time.Second),
@@ -49,252 +762,1466 @@ func mockWaitConfig() *WaitConfig {
 	}
 }
 
-func TestWaitConfig_Copy(t *testing.T) {
+func TestConfig_Copy_HostNetworksAndTLS(t *testing.T) {
+	c := DefaultConfig()
+	c.HostNetworks = map[string]*structs.ClientHostNetworkConfig{
+		"public": {
+			Name:      "public",
+			CIDR:      "10.0.0.0/8",
+			CIDRs:     []string{"fd00::/8"},
+			Interface: "eth0",
+		},
+	}
+	c.TLSConfig = &structsc.TLSConfig{
+		EnableRPC: true,
+		CAFile:    "ca.pem",
+	}
+
+	nc := c.Copy()
+	require.Equal(t, c.HostNetworks, nc.HostNetworks)
+	require.NotSame(t, c.HostNetworks["public"], nc.HostNetworks["public"])
+	require.NotSame(t, c.TLSConfig, nc.TLSConfig)
+	require.Same(t, c.TLSConfig.GetKeyLoader(), nc.TLSConfig.GetKeyLoader())
+
+	// Mutating the copy must not change the source
+	nc.HostNetworks["public"].CIDR = "192.168.0.0/16"
+	nc.HostNetworks["public"].CIDRs[0] = "2001:db8::/32"
+	nc.HostNetworks["private"] = &structs.ClientHostNetworkConfig{Name: "private"}
+	nc.TLSConfig.EnableRPC = false
+	nc.TLSConfig.CAFile = "other.pem"
+
+	require.Len(t, c.HostNetworks, 1)
+	require.Equal(t, "10.0.0.0/8", c.HostNetworks["public"].CIDR)
+	require.Equal(t, []string{"fd00::/8"}, c.HostNetworks["public"].CIDRs)
+	require.True(t, c.TLSConfig.EnableRPC)
+	require.Equal(t, "ca.pem", c.TLSConfig.CAFile)
+}
+
+func TestConfig_ReloadableCopyFrom(t *testing.T) {
+	c := DefaultConfig()
+	newCfg := DefaultConfig()
+	newCfg.GCInterval = 5 * time.Minute
+	newCfg.GCMaxAllocs = 10
+	newCfg.StatsCollectionInterval = 30 * time.Second
+	newCfg.TemplateConfig.FunctionDenylist = []string{"env"}
+	newCfg.StateDir = "/var/lib/nomad/client"
+	newCfg.MaxKillTimeout = time.Hour
+
+	c.ReloadableCopyFrom(newCfg)
+
+	// Only the reloadable fields are copied
+	v, nv := reflect.ValueOf(c).Elem(), reflect.ValueOf(newCfg).Elem()
+	for _, name := range ReloadableFields {
+		require.Equalf(t, nv.FieldByName(name).Interface(), v.FieldByName(name).Interface(),
+			"reloadable field %s was not copied", name)
+	}
+	require.Equal(t, DefaultConfig().StateDir, c.StateDir)
+	require.Equal(t, DefaultConfig().MaxKillTimeout, c.MaxKillTimeout)
+
+	require.NotSame(t, newCfg.TemplateConfig, c.TemplateConfig)
+	newCfg.TemplateConfig.FunctionDenylist[0] = "plugin"
+	require.Equal(t, []string{"env"}, c.TemplateConfig.FunctionDenylist)
+}
+
+func TestConfig_RestartRequiredChanges(t *testing.T) {
+	c := DefaultConfig()
+	require.Empty(t, c.RestartRequiredChanges(c.Copy()))
+
+	newCfg := c.Copy()
+	newCfg.StateDir = "/var/lib/nomad/client"
+	newCfg.CgroupParent = "nomad.slice"
+	newCfg.GCInterval = time.Hour
+	require.Equal(t, []string{"StateDir", "CgroupParent"}, c.RestartRequiredChanges(newCfg))
+}
+
+// TestConfig_Copy_DeepCopiesFields fails when a pointer, map or slice field
+// of Config is shared between a config and its copy, so that new fields
+// aren't left out of Copy.
+func TestConfig_Copy_DeepCopiesFields(t *testing.T) {
+	// Fields deliberately shared by copies of the config
+	shared := map[string]string{
+		"Version": "build information that is never modified",
+	}
+
+	c := new(Config)
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if _, ok := shared[v.Type().Field(i).Name]; !ok {
+			fillReference(v.Field(i))
+		}
+	}
+
+	nc := c.Copy()
+	nv := reflect.ValueOf(nc).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if _, ok := shared[name]; ok {
+			continue
+		}
+		require.Falsef(t, sharesReference(v.Field(i), nv.Field(i)),
+			"Config.Copy shares field %s with the original: deep-copy it or list it as shared", name)
+	}
+}
+
+// fillReference sets a pointer, map or slice to a non-nil value holding one
+// element, so that Copy has something to copy. Other kinds are left as is.
+func fillReference(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		fillReference(elem)
+		m.SetMapIndex(reflect.New(v.Type().Key()).Elem(), elem)
+		v.Set(m)
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fillReference(s.Index(0))
+		v.Set(s)
+	}
+}
+
+// sharesReference returns whether a pointer, map or slice, or the pointers
+// it holds, are the same in a and b.
+func sharesReference(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if a.IsNil() || b.IsNil() || a.Pointer() != b.Pointer() {
+			break
+		}
+		return true
+	default:
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Map:
+		iter := a.MapRange()
+		for iter.Next() {
+			if bv := b.MapIndex(iter.Key()); bv.IsValid() && sharesReference(iter.Value(), bv) {
+				return true
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			if sharesReference(a.Index(i), b.Index(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestConfig_Merge_Scalars(t *testing.T) {
+	c := DefaultConfig()
+	c.Region = "global"
+	c.StateDir = "/var/lib/nomad"
+	c.CpuCompute = 1000
+
+	c.CSIClaimRetry = &RetryConfig{Attempts: helper.IntToPtr(3)}
+
+	b := &Config{
+		Region:                   "east",
+		CpuCompute:               2000,
+		CSIVolumeMountTimeout:    time.Minute,
+		CSIClaimRetry:            &RetryConfig{Backoff: helper.TimeToPtr(time.Second)},
+		PublishAllocationMetrics: true,
+
+		CSIDegradedPluginClaimDelay: 5 * time.Second,
+		CSIPluginRegistrationWait:   30 * time.Second,
+		CSIVolumeHealthCheck:        true,
+		CSIMountMetadata:            true,
+		CSIClaimTopology:            true,
+	}
+
+	result := c.Merge(b)
+	require.Equal(t, "east", result.Region)
+	require.Equal(t, 2000, result.CpuCompute)
+	require.Equal(t, time.Minute, result.CSIVolumeMountTimeout)
+	require.Equal(t, 5*time.Second, result.CSIDegradedPluginClaimDelay)
+	require.Equal(t, 30*time.Second, result.CSIPluginRegistrationWait)
+	require.True(t, result.CSIVolumeHealthCheck)
+	require.True(t, result.CSIMountMetadata)
+	require.True(t, result.CSIClaimTopology)
+	require.Equal(t, &RetryConfig{
+		Attempts: helper.IntToPtr(3),
+		Backoff:  helper.TimeToPtr(time.Second),
+	}, result.CSIClaimRetry)
+	require.True(t, result.PublishAllocationMetrics)
+
+	// Zero values in b do not override
+	require.Equal(t, "/var/lib/nomad", result.StateDir)
+	require.Equal(t, c.MaxKillTimeout, result.MaxKillTimeout)
+	require.Equal(t, c.GCInterval, result.GCInterval)
+
+	// The receiver is not modified
+	require.Equal(t, "global", c.Region)
+	require.Equal(t, 1000, c.CpuCompute)
+	require.False(t, c.PublishAllocationMetrics)
+}
+
+func TestConfig_Merge_Maps(t *testing.T) {
+	c := DefaultConfig()
+	c.Options = map[string]string{"a": "1", "b": "2"}
+	c.ChrootEnv = map[string]string{"/bin": "/bin"}
+	c.HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"data": {Name: "data", Path: "/data"},
+	}
+	c.HostNetworks = map[string]*structs.ClientHostNetworkConfig{
+		"public": {Name: "public", Interface: "eth0"},
+	}
+	c.TemplateConfig.MaxStale = helper.TimeToPtr(time.Second)
+
+	b := &Config{
+		Options:   map[string]string{"b": "3", "c": "4"},
+		ChrootEnv: map[string]string{"/usr": "/usr"},
+		HostVolumes: map[string]*structs.ClientHostVolumeConfig{
+			"data": {Name: "data", Path: "/other"},
+			"logs": {Name: "logs", Path: "/logs"},
+		},
+		HostNetworks: map[string]*structs.ClientHostNetworkConfig{
+			"private": {Name: "private", Interface: "eth1"},
+		},
+		TemplateConfig: &ClientTemplateConfig{
+			FunctionDenylist:    []string{"env"},
+			BlockQueryWaitTime:  helper.TimeToPtr(time.Minute),
+			RestartStageTimeout: helper.TimeToPtr(time.Minute),
+		},
+	}
+
+	result := c.Merge(b)
+	require.Equal(t, map[string]string{"a": "1", "b": "3", "c": "4"}, result.Options)
+	require.Equal(t, map[string]string{"/bin": "/bin", "/usr": "/usr"}, result.ChrootEnv)
+	require.Len(t, result.HostVolumes, 2)
+	require.Equal(t, "/other", result.HostVolumes["data"].Path)
+	require.Equal(t, "/logs", result.HostVolumes["logs"].Path)
+	require.Len(t, result.HostNetworks, 2)
+	require.Equal(t, "eth0", result.HostNetworks["public"].Interface)
+	require.Equal(t, "eth1", result.HostNetworks["private"].Interface)
+
+	// TemplateConfig uses the ClientTemplateConfig merge semantics
+	require.Equal(t, time.Second, *result.TemplateConfig.MaxStale)
+	require.Equal(t, time.Minute, *result.TemplateConfig.BlockQueryWaitTime)
+	require.Equal(t, time.Minute, *result.TemplateConfig.RestartStageTimeout)
+	require.Contains(t, result.TemplateConfig.FunctionDenylist, "env")
+	require.Contains(t, result.TemplateConfig.FunctionDenylist, "plugin")
+
+	// The receiver's maps are not modified
+	require.Len(t, c.Options, 2)
+	require.Equal(t, "2", c.Options["b"])
+	require.Len(t, c.ChrootEnv, 1)
+	require.Equal(t, "/data", c.HostVolumes["data"].Path)
+	require.Len(t, c.HostNetworks, 1)
+	require.Nil(t, c.TemplateConfig.BlockQueryWaitTime)
+}
+
+func TestConfig_DriverHealthThreshold(t *testing.T) {
+	c := DefaultConfig()
+	c.DriverHealthThresholds = map[string]*DriverHealthThreshold{
+		"docker": {Driver: "docker", MaxFailures: 10},
+	}
+
+	docker := c.DriverHealthThreshold("docker")
+	require.Equal(t, 10, docker.MaxFailures)
+	require.Equal(t, DefaultDriverHealthWindow, docker.Window)
+	require.Equal(t, DefaultDriverHealthRecoveryPeriod, docker.RecoveryPeriod)
+	require.Equal(t, DefaultDriverHealthWindow, c.DriverHealthThreshold("exec").Window)
+
+	// The resolved threshold doesn't share the configured one
+	docker.MaxFailures = 1
+	require.Equal(t, 10, c.DriverHealthThresholds["docker"].MaxFailures)
+
+	other := &Config{DriverHealthThresholds: map[string]*DriverHealthThreshold{
+		"docker": {Driver: "docker", Window: time.Minute},
+		"exec":   {Driver: "exec", MaxFailures: 1},
+	}}
+	merged := c.Merge(other)
+	require.Equal(t, 10, merged.DriverHealthThresholds["docker"].MaxFailures)
+	require.Equal(t, time.Minute, merged.DriverHealthThresholds["docker"].Window)
+	require.Equal(t, 1, merged.DriverHealthThresholds["exec"].MaxFailures)
+	require.Zero(t, c.DriverHealthThresholds["docker"].Window)
+}
+
+func TestConfig_Merge_Nil(t *testing.T) {
+	c := DefaultConfig()
+	c.Options = map[string]string{"a": "1"}
+
+	result := c.Merge(nil)
+	require.Equal(t, c, result)
+	require.NotSame(t, c, result)
+
+	var nilConfig *Config
+	result = nilConfig.Merge(c)
+	require.Equal(t, c, result)
+	require.NotSame(t, c, result)
+
+	require.Nil(t, nilConfig.Merge(nil))
+}
+
+func TestWaitConfig_Copy(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Wait     *WaitConfig
+		Expected *WaitConfig
+	}{
+		{
+			"fully-populated",
+			mockWaitConfig(),
+			&WaitConfig{
+				Min: helper.TimeToPtr(5 * time.Second),
+				Max: helper.TimeToPtr(10 * time.Second),
+			},
+		},
+		{
+			"min-only",
+			&WaitConfig{
+				Min: helper.TimeToPtr(5 * time.Second),
+			},
+			&WaitConfig{
+				Min: helper.TimeToPtr(5 * time.Second),
+			},
+		},
+		{
+			"max-only",
+			&WaitConfig{
+				Max: helper.TimeToPtr(5 * time.Second),
+			},
+			&WaitConfig{
+				Max: helper.TimeToPtr(5 * time.Second),
+			},
+		},
+	}
+
+	for _, _case := range cases {
+		t.Run(_case.Name, func(t *testing.T) {
+			result := _case.Expected.Equals(_case.Wait.Copy())
+			if !result {
+				t.Logf("\nExpected %v\n   Found %v", _case.Expected, result)
+			}
+			require.True(t, result)
+		})
+	}
+}
+
+func TestWaitConfig_Copy_NilFields(t *testing.T) {
+	// Exercise every combination of set and unset pointer fields.
+	for mask := 0; mask < 1<<2; mask++ {
+		wc := &WaitConfig{}
+		if mask&1 != 0 {
+			wc.Min = helper.TimeToPtr(5 * time.Second)
+		}
+		if mask&2 != 0 {
+			wc.Max = helper.TimeToPtr(10 * time.Second)
+		}
+
+		t.Run(fmt.Sprintf("mask-%02b", mask), func(t *testing.T) {
+			var cp *WaitConfig
+			require.NotPanics(t, func() { cp = wc.Copy() })
+			require.True(t, wc.Equals(cp))
+			require.NotSame(t, wc, cp)
+
+			if wc.Min != nil {
+				require.NotSame(t, wc.Min, cp.Min)
+				*cp.Min = time.Minute
+				require.Equal(t, 5*time.Second, *wc.Min)
+			} else {
+				require.Nil(t, cp.Min)
+			}
+
+			if wc.Max != nil {
+				require.NotSame(t, wc.Max, cp.Max)
+				*cp.Max = time.Minute
+				require.Equal(t, 10*time.Second, *wc.Max)
+			} else {
+				require.Nil(t, cp.Max)
+			}
+		})
+	}
+}
+
+func TestWaitConfig_Merge_Isolation(t *testing.T) {
+	a := &WaitConfig{Min: helper.TimeToPtr(5 * time.Second)}
+	b := &WaitConfig{Max: helper.TimeToPtr(10 * time.Second)}
+
+	// Mutating a merged config leaves both inputs untouched
+	merged := a.Merge(b)
+	*merged.Min = time.Minute
+	*merged.Max = time.Minute
+	require.Equal(t, 5*time.Second, *a.Min)
+	require.Equal(t, 10*time.Second, *b.Max)
+
+	var nilConfig *WaitConfig
+	for _, merged := range []*WaitConfig{nilConfig.Merge(b), b.Merge(nil)} {
+		require.NotSame(t, b, merged)
+		*merged.Max = time.Minute
+		require.Equal(t, 10*time.Second, *b.Max)
+	}
+
+	// Neither is the consul-template config sharing its fields
+	ct, err := b.Merge(a).ToConsulTemplate()
+	require.NoError(t, err)
+	*ct.Min = time.Minute
+	require.Equal(t, 5*time.Second, *a.Min)
+}
+
+func TestWaitConfig_IsEmpty(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Wait     *WaitConfig
+		Expected bool
+	}{
+		{
+			"is-nil",
+			nil,
+			true,
+		},
+		{
+			"is-empty",
+			&WaitConfig{},
+			true,
+		},
+		{
+			"is-not-empty",
+			&WaitConfig{
+				Min: helper.TimeToPtr(10 * time.Second),
+			},
+			false,
+		},
+	}
+
+	for _, _case := range cases {
+		t.Run(_case.Name, func(t *testing.T) {
+			require.Equal(t, _case.Expected, _case.Wait.IsEmpty())
+		})
+	}
+}
+
+func TestWaitConfig_IsEqual(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Wait     *WaitConfig
+		Other    *WaitConfig
+		Expected bool
+	}{
+		{
+			"are-equal",
+			mockWaitConfig(),
+			&WaitConfig{
+				Min: helper.TimeToPtr(5 * time.Second),
+				Max: helper.TimeToPtr(10 * time.Second),
+			},
+			true,
+		},
+		{
+			"min-different",
+			mockWaitConfig(),
+			&WaitConfig{
+				Min: helper.TimeToPtr(4 * time.Second),
+				Max: helper.TimeToPtr(10 * time.Second),
+			},
+			false,
+		},
+		{
+			"max-different",
+			mockWaitConfig(),
+			&WaitConfig{
+				Min: helper.TimeToPtr(5 * time.Second),
+				Max: helper.TimeToPtr(9 * time.Second),
+			},
+			false,
+		},
+	}
+
+	for _, _case := range cases {
+		t.Run(_case.Name, func(t *testing.T) {
+			require.Equal(t, _case.Expected, _case.Wait.Equals(_case.Other))
+		})
+	}
+}
+
+func TestWaitConfig_IsValid(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Retry    *WaitConfig
+		Expected string
+	}{
+		{
+			"is-valid",
+			&WaitConfig{
+				Min: helper.TimeToPtr(5 * time.Second),
+				Max: helper.TimeToPtr(10 * time.Second),
+			},
+			"",
+		},
+		{
+			"is-nil",
+			nil,
+			"is nil",
+		},
+		{
+			"is-empty",
+			&WaitConfig{},
+			"or empty",
+		},
+		{
+			"min-greater-than-max",
+			&WaitConfig{
+				Min: helper.TimeToPtr(10 * time.Second),
+				Max: helper.TimeToPtr(5 * time.Second),
+			},
+			"greater than",
+		},
+		{
+			"max-not-set",
+			&WaitConfig{
+				Min: helper.TimeToPtr(10 * time.Second),
+			},
+			"",
+		},
+	}
+
+	for _, _case := range cases {
+		t.Run(_case.Name, func(t *testing.T) {
+			if _case.Expected == "" {
+				require.Nil(t, _case.Retry.Validate())
+			} else {
+				err := _case.Retry.Validate()
+				require.Contains(t, err.Error(), _case.Expected)
+			}
+		})
+	}
+}
+
+func TestClientTemplateConfig_Merge(t *testing.T) {
+	a := &ClientTemplateConfig{
+		FunctionDenylist:   []string{"plugin", "executeTemplate"},
+		BlockQueryWaitTime: helper.TimeToPtr(5 * time.Minute),
+		MaxStale:           helper.TimeToPtr(10 * time.Second),
+		Wait: &WaitConfig{
+			Min: helper.TimeToPtr(5 * time.Second),
+			Max: helper.TimeToPtr(10 * time.Second),
+		},
+		ConsulRetry: &RetryConfig{Attempts: helper.IntToPtr(5)},
+	}
+	b := &ClientTemplateConfig{
+		FunctionDenylist: []string{"executeTemplate", "env", "env"},
+		DisableSandbox:   true,
+		MaxStale:         helper.TimeToPtr(time.Minute),
+		Wait: &WaitConfig{
+			Max: helper.TimeToPtr(20 * time.Second),
+		},
+		VaultRetry: &RetryConfig{Backoff: helper.TimeToPtr(time.Second)},
+	}
+
+	merged := a.Merge(b)
+
+	// Denylists are unioned without duplicates, and the receiver's list is
+	// left unchanged
+	require.Equal(t, []string{"plugin", "executeTemplate", "env"}, merged.FunctionDenylist)
+	require.Equal(t, []string{"plugin", "executeTemplate"}, a.FunctionDenylist)
+
+	// Pointer fields set in b take precedence, and are otherwise kept
+	require.Equal(t, 5*time.Minute, *merged.BlockQueryWaitTime)
+	require.Equal(t, time.Minute, *merged.MaxStale)
+	require.Equal(t, 5*time.Second, *merged.Wait.Min)
+	require.Equal(t, 20*time.Second, *merged.Wait.Max)
+	require.Equal(t, 5, *merged.ConsulRetry.Attempts)
+	require.Equal(t, time.Second, *merged.VaultRetry.Backoff)
+	require.Equal(t, 10*time.Second, *a.MaxStale)
+
+	// DisableSandbox can be enabled but an unset value doesn't disable it
+	require.True(t, merged.DisableSandbox)
+	require.True(t, merged.Merge(&ClientTemplateConfig{}).DisableSandbox)
+
+	// Merging with nil
+	var nilConfig *ClientTemplateConfig
+	require.Equal(t, b, nilConfig.Merge(b))
+	require.Equal(t, a, a.Merge(nil))
+}
+
+func TestClientTemplateConfig_FunctionAllowlist(t *testing.T) {
+	c := &ClientTemplateConfig{
+		FunctionAllowlist: []string{"key", "service", "toJSON"},
+	}
+	require.NoError(t, c.Validate())
+
+	denylist := c.ConsulTemplateFunctionDenylist()
+	require.Len(t, denylist, len(consulTemplateFunctions)-3)
+	require.Contains(t, denylist, "plugin")
+	require.Contains(t, denylist, "env")
+	require.NotContains(t, denylist, "key")
+	require.NotContains(t, denylist, "service")
+	require.NotContains(t, denylist, "toJSON")
+
+	// The allowlist doesn't depend on the sandbox: allowing file keeps it
+	// sandboxed, and disabling the sandbox doesn't allow other functions
+	c.FunctionAllowlist = append(c.FunctionAllowlist, "file")
+	ct := c.ToConsulTemplateTemplateConfig("/alloc/task")
+	require.NotContains(t, ct.FunctionDenylist, "file")
+	require.Contains(t, ct.FunctionDenylist, "env")
+	require.Equal(t, "/alloc/task", *ct.SandboxPath)
+
+	c.DisableSandbox = true
+	ct = c.ToConsulTemplateTemplateConfig("/alloc/task")
+	require.Equal(t, c.ConsulTemplateFunctionDenylist(), ct.FunctionDenylist)
+	require.Contains(t, ct.FunctionDenylist, "env")
+	require.Nil(t, ct.SandboxPath)
+
+	c = &ClientTemplateConfig{FunctionAllowlist: []string{"key"}, DisableSandbox: true}
+	require.NoError(t, c.Validate())
+	require.Contains(t, c.ToConsulTemplateTemplateConfig("/alloc/task").FunctionDenylist, "file")
+
+	// Without an allowlist the denylist is used as is
+	c = &ClientTemplateConfig{FunctionDenylist: []string{"plugin"}}
+	require.NoError(t, c.Validate())
+	require.Equal(t, []string{"plugin"}, c.ConsulTemplateFunctionDenylist())
+}
+
+func TestClientTemplateConfig_Validate(t *testing.T) {
+	var nilConfig *ClientTemplateConfig
+	require.NoError(t, nilConfig.Validate())
+
+	c := &ClientTemplateConfig{
+		FunctionAllowlist: []string{"key"},
+		FunctionDenylist:  []string{"plugin"},
+	}
+	err := c.Validate()
+	require.EqualError(t, err, "function_allowlist and function_denylist cannot both be set")
+
+	c = &ClientTemplateConfig{FunctionAllowlist: []string{"key", "nope"}}
+	err = c.Validate()
+	require.EqualError(t, err, `function_allowlist contains unknown function "nope"`)
+
+	c = &ClientTemplateConfig{MaxRenderSizeBytes: helper.Int64ToPtr(-1)}
+	require.EqualError(t, c.Validate(), "max_render_size cannot be negative")
+
+	c = &ClientTemplateConfig{MaxTemplatesPerTask: helper.IntToPtr(-1)}
+	require.EqualError(t, c.Validate(), "max_templates_per_task cannot be negative")
+
+	c = &ClientTemplateConfig{MaxRenderCPUTime: helper.TimeToPtr(-time.Second)}
+	require.EqualError(t, c.Validate(), "max_render_cpu_time cannot be negative")
+
+	c = &ClientTemplateConfig{MaxRenderCPUTime: helper.TimeToPtr(500 * time.Millisecond)}
+	require.EqualError(t, c.Validate(), "max_render_cpu_time must be at least 1s")
+
+	c = &ClientTemplateConfig{
+		MaxRenderSizeBytes:  helper.Int64ToPtr(0),
+		MaxRenderCPUTime:    helper.TimeToPtr(0),
+		MaxTemplatesPerTask: helper.IntToPtr(0),
+	}
+	require.NoError(t, c.Validate())
+
+	// The conflict is reported when validating the client config
+	conf := DefaultConfig()
+	conf.TemplateConfig.FunctionAllowlist = []string{"key"}
+	err = conf.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "function_allowlist and function_denylist cannot both be set")
+}
+
+func TestClientTemplateConfig_Merge_Partial(t *testing.T) {
 	cases := []struct {
 		Name     string
-		Wait     *WaitConfig
+		Target   *ClientTemplateConfig
+		Other    *ClientTemplateConfig
+		Expected *ClientTemplateConfig
+	}{
+		{
+			"nil-both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"nil-target",
+			nil,
+			&ClientTemplateConfig{DisableSandbox: true},
+			&ClientTemplateConfig{DisableSandbox: true},
+		},
+		{
+			"nil-other",
+			&ClientTemplateConfig{FunctionDenylist: []string{"plugin"}},
+			nil,
+			&ClientTemplateConfig{FunctionDenylist: []string{"plugin"}},
+		},
+		{
+			"denylist-only",
+			&ClientTemplateConfig{
+				FunctionDenylist: []string{"plugin"},
+				MaxStale:         helper.TimeToPtr(time.Minute),
+			},
+			&ClientTemplateConfig{FunctionDenylist: []string{"env"}},
+			&ClientTemplateConfig{
+				FunctionDenylist: []string{"plugin", "env"},
+				MaxStale:         helper.TimeToPtr(time.Minute),
+			},
+		},
+		{
+			"wait-max-only",
+			&ClientTemplateConfig{
+				BlockQueryWaitTime: helper.TimeToPtr(time.Minute),
+				Wait: &WaitConfig{
+					Min: helper.TimeToPtr(5 * time.Second),
+					Max: helper.TimeToPtr(10 * time.Second),
+				},
+			},
+			&ClientTemplateConfig{
+				Wait: &WaitConfig{Max: helper.TimeToPtr(20 * time.Second)},
+			},
+			&ClientTemplateConfig{
+				BlockQueryWaitTime: helper.TimeToPtr(time.Minute),
+				Wait: &WaitConfig{
+					Min: helper.TimeToPtr(5 * time.Second),
+					Max: helper.TimeToPtr(20 * time.Second),
+				},
+			},
+		},
+		{
+			"allow-executable-renders",
+			&ClientTemplateConfig{MaxStale: helper.TimeToPtr(time.Minute)},
+			&ClientTemplateConfig{AllowExecutableRenders: true},
+			&ClientTemplateConfig{
+				MaxStale:               helper.TimeToPtr(time.Minute),
+				AllowExecutableRenders: true,
+			},
+		},
+		{
+			"retry-only",
+			&ClientTemplateConfig{
+				ConsulRetry: &RetryConfig{Attempts: helper.IntToPtr(5)},
+				VaultRetry:  &RetryConfig{Attempts: helper.IntToPtr(3)},
+				NomadRetry:  &RetryConfig{Attempts: helper.IntToPtr(2)},
+			},
+			&ClientTemplateConfig{
+				VaultRetry: &RetryConfig{Backoff: helper.TimeToPtr(time.Second)},
+				NomadRetry: &RetryConfig{MaxBackoff: helper.TimeToPtr(time.Minute)},
+			},
+			&ClientTemplateConfig{
+				ConsulRetry: &RetryConfig{Attempts: helper.IntToPtr(5)},
+				VaultRetry: &RetryConfig{
+					Attempts: helper.IntToPtr(3),
+					Backoff:  helper.TimeToPtr(time.Second),
+				},
+				NomadRetry: &RetryConfig{
+					Attempts:   helper.IntToPtr(2),
+					MaxBackoff: helper.TimeToPtr(time.Minute),
+				},
+			},
+		},
+		{
+			"render-limits",
+			&ClientTemplateConfig{
+				MaxRenderSizeBytes:  helper.Int64ToPtr(1024),
+				MaxTemplatesPerTask: helper.IntToPtr(10),
+			},
+			&ClientTemplateConfig{MaxTemplatesPerTask: helper.IntToPtr(0)},
+			&ClientTemplateConfig{
+				MaxRenderSizeBytes:  helper.Int64ToPtr(1024),
+				MaxTemplatesPerTask: helper.IntToPtr(0),
+			},
+		},
+		{
+			"validate-utf8",
+			&ClientTemplateConfig{ValidateRenderedUTF8: true},
+			&ClientTemplateConfig{MaxTemplatesPerTask: helper.IntToPtr(10)},
+			&ClientTemplateConfig{
+				ValidateRenderedUTF8: true,
+				MaxTemplatesPerTask:  helper.IntToPtr(10),
+			},
+		},
+		{
+			"consul-namespace",
+			&ClientTemplateConfig{ConsulNamespace: "default"},
+			&ClientTemplateConfig{ConsulNamespace: "templates"},
+			&ClientTemplateConfig{ConsulNamespace: "templates"},
+		},
+		{
+			"consul-namespace-kept",
+			&ClientTemplateConfig{ConsulNamespace: "templates"},
+			&ClientTemplateConfig{MaxStale: helper.TimeToPtr(time.Minute)},
+			&ClientTemplateConfig{
+				ConsulNamespace: "templates",
+				MaxStale:        helper.TimeToPtr(time.Minute),
+			},
+		},
+	}
+
+	for _, _case := range cases {
+		t.Run(_case.Name, func(t *testing.T) {
+			merged := _case.Target.Merge(_case.Other)
+			require.Equal(t, _case.Expected, merged)
+			require.True(t, _case.Expected.Equals(merged))
+		})
+	}
+
+	// Merging into a nil receiver doesn't share the other config
+	other := &ClientTemplateConfig{Wait: &WaitConfig{Min: helper.TimeToPtr(time.Second)}}
+	var nilConfig *ClientTemplateConfig
+	merged := nilConfig.Merge(other)
+	*merged.Wait.Min = time.Minute
+	require.Equal(t, time.Second, *other.Wait.Min)
+}
+
+func TestClientTemplateConfig_Copy_Isolation(t *testing.T) {
+	c := &ClientTemplateConfig{
+		FunctionDenylist:     []string{"plugin"},
+		BlockQueryWaitTime:   helper.TimeToPtr(time.Minute),
+		MaxStale:             helper.TimeToPtr(time.Second),
+		Wait:                 &WaitConfig{Min: helper.TimeToPtr(time.Second)},
+		WaitBounds:           &WaitConfig{Max: helper.TimeToPtr(time.Minute)},
+		ConsulRetry:          &RetryConfig{Attempts: helper.IntToPtr(5)},
+		VaultRetry:           &RetryConfig{Attempts: helper.IntToPtr(3)},
+		VaultRetries:         map[string]*RetryConfig{"ops": {Attempts: helper.IntToPtr(1)}},
+		NomadRetry:           &RetryConfig{Attempts: helper.IntToPtr(2)},
+		RestartStageTimeout:  helper.TimeToPtr(time.Minute),
+		MaxRenderSizeBytes:   helper.Int64ToPtr(1024),
+		MaxRenderCPUTime:     helper.TimeToPtr(time.Second),
+		MaxTemplatesPerTask:  helper.IntToPtr(10),
+		ConsulNamespace:      "templates",
+		ValidateRenderedUTF8: true,
+	}
+
+	// Mutating a copy, or a config merged from it, leaves it untouched
+	for _, cp := range []*ClientTemplateConfig{c.Copy(), c.Merge(nil), c.Merge(&ClientTemplateConfig{})} {
+		require.True(t, c.Equals(cp))
+
+		cp.FunctionDenylist[0] = "env"
+		*cp.BlockQueryWaitTime = time.Hour
+		*cp.MaxStale = time.Hour
+		*cp.Wait.Min = time.Hour
+		*cp.WaitBounds.Max = time.Hour
+		*cp.ConsulRetry.Attempts = 10
+		*cp.VaultRetry.Attempts = 10
+		*cp.VaultRetries["ops"].Attempts = 10
+		cp.VaultRetries["dev"] = &RetryConfig{}
+		*cp.NomadRetry.Attempts = 10
+		*cp.RestartStageTimeout = time.Hour
+		*cp.MaxRenderSizeBytes = 1
+		*cp.MaxRenderCPUTime = time.Hour
+		*cp.MaxTemplatesPerTask = 1
+		cp.ConsulNamespace = "default"
+		cp.ValidateRenderedUTF8 = false
+
+		require.Equal(t, []string{"plugin"}, c.FunctionDenylist)
+		require.Equal(t, time.Minute, *c.BlockQueryWaitTime)
+		require.Equal(t, time.Second, *c.MaxStale)
+		require.Equal(t, time.Second, *c.Wait.Min)
+		require.Equal(t, time.Minute, *c.WaitBounds.Max)
+		require.Equal(t, 5, *c.ConsulRetry.Attempts)
+		require.Equal(t, 3, *c.VaultRetry.Attempts)
+		require.Len(t, c.VaultRetries, 1)
+		require.Equal(t, 1, *c.VaultRetries["ops"].Attempts)
+		require.Equal(t, 2, *c.NomadRetry.Attempts)
+		require.Equal(t, time.Minute, *c.RestartStageTimeout)
+		require.Equal(t, int64(1024), *c.MaxRenderSizeBytes)
+		require.Equal(t, time.Second, *c.MaxRenderCPUTime)
+		require.Equal(t, 10, *c.MaxTemplatesPerTask)
+		require.Equal(t, "templates", c.ConsulNamespace)
+		require.True(t, c.ValidateRenderedUTF8)
+	}
+
+	// Fields taken from the merged config aren't shared either
+	var nilConfig *ClientTemplateConfig
+	merged := nilConfig.Merge(c)
+	*merged.MaxStale = time.Hour
+	merged = (&ClientTemplateConfig{}).Merge(c)
+	*merged.BlockQueryWaitTime = time.Hour
+	*merged.VaultRetries["ops"].Attempts = 10
+	require.Equal(t, time.Second, *c.MaxStale)
+	require.Equal(t, time.Minute, *c.BlockQueryWaitTime)
+	require.Equal(t, 1, *c.VaultRetries["ops"].Attempts)
+}
+
+func TestClientTemplateConfig_VaultRetryFor(t *testing.T) {
+	c := &ClientTemplateConfig{
+		VaultRetry: &RetryConfig{
+			Attempts: helper.IntToPtr(5),
+			Backoff:  helper.TimeToPtr(time.Second),
+		},
+		VaultRetries: map[string]*RetryConfig{
+			"ops": {Attempts: helper.IntToPtr(2)},
+		},
+	}
+
+	// A namespace's entry is layered over the default
+	retry := c.VaultRetryFor("ops")
+	require.Equal(t, 2, *retry.Attempts)
+	require.Equal(t, time.Second, *retry.Backoff)
+
+	// Namespaces without an entry fall back to the default
+	for _, namespace := range []string{"", "dev"} {
+		retry = c.VaultRetryFor(namespace)
+		require.Equal(t, 5, *retry.Attempts)
+		require.Equal(t, time.Second, *retry.Backoff)
+	}
+
+	// The returned config is a copy
+	*retry.Attempts = 10
+	*c.VaultRetryFor("ops").Backoff = time.Hour
+	require.Equal(t, 5, *c.VaultRetry.Attempts)
+	require.Equal(t, time.Second, *c.VaultRetry.Backoff)
+
+	// Without a default only namespaces with an entry have a retry config
+	c.VaultRetry = nil
+	require.Equal(t, 2, *c.VaultRetryFor("ops").Attempts)
+	require.Nil(t, c.VaultRetryFor("dev"))
+	var nilConfig *ClientTemplateConfig
+	require.Nil(t, nilConfig.VaultRetryFor("ops"))
+
+	// Entries are merged by namespace
+	merged := c.Merge(&ClientTemplateConfig{
+		VaultRetries: map[string]*RetryConfig{
+			"ops": {Backoff: helper.TimeToPtr(time.Minute)},
+			"dev": {Attempts: helper.IntToPtr(7)},
+		},
+	})
+	require.Equal(t, 2, *merged.VaultRetries["ops"].Attempts)
+	require.Equal(t, time.Minute, *merged.VaultRetries["ops"].Backoff)
+	require.Equal(t, 7, *merged.VaultRetries["dev"].Attempts)
+	require.Len(t, c.VaultRetries, 1)
+
+	// The namespace's retry config is converted to consul-template's
+	conf, err := merged.ToConsulTemplateConfig("ops")
+	require.NoError(t, err)
+	require.Equal(t, 2, *conf.Vault.Retry.Attempts)
+	require.Equal(t, time.Minute, *conf.Vault.Retry.Backoff)
+
+	merged.VaultRetries["ops"].Backoff = helper.TimeToPtr(time.Hour)
+	_, err = merged.ToConsulTemplateConfig("ops")
+	require.EqualError(t, err, `invalid vault_retries config for namespace "ops": `+
+		`retry config backoff 3600000000000 is greater than default max_backoff 60000000000`)
+}
+
+func TestClientTemplateConfig_IsEmpty(t *testing.T) {
+	var nilConfig *ClientTemplateConfig
+	require.True(t, nilConfig.IsEmpty())
+	require.True(t, (&ClientTemplateConfig{}).IsEmpty())
+	require.True(t, (&ClientTemplateConfig{Wait: &WaitConfig{}}).IsEmpty())
+
+	// A config setting only one field is not empty
+	for _, c := range []*ClientTemplateConfig{
+		{DisableSandbox: true},
+		{AllowExecutableRenders: true},
+		{FunctionDenylist: []string{"plugin"}},
+		{FunctionBlacklist: []string{"plugin"}},
+		{WaitBounds: &WaitConfig{Min: helper.TimeToPtr(time.Second)}},
+		{MaxStale: helper.TimeToPtr(time.Second)},
+		{VaultRetries: map[string]*RetryConfig{"ops": {}}},
+		{ConsulNamespace: "templates"},
+	} {
+		require.False(t, c.IsEmpty(), "%#v", c)
+	}
+}
+
+func TestClientTemplateConfig_Merge_FunctionAllowlist(t *testing.T) {
+	// An allowlist replaces the denylist it is layered on
+	defaults := &ClientTemplateConfig{FunctionDenylist: []string{"plugin"}}
+	merged := defaults.Merge(&ClientTemplateConfig{FunctionAllowlist: []string{"key"}})
+	require.Equal(t, []string{"key"}, merged.FunctionAllowlist)
+	require.Empty(t, merged.FunctionDenylist)
+	require.NoError(t, merged.Validate())
+	require.False(t, merged.IsEmpty())
+
+	// Setting both in the same config is still a conflict
+	merged = defaults.Merge(&ClientTemplateConfig{
+		FunctionAllowlist: []string{"key"},
+		FunctionDenylist:  []string{"env"},
+	})
+	require.Equal(t, []string{"env"}, merged.FunctionDenylist)
+	require.Error(t, merged.Validate())
+}
+
+func TestWaitConfig_Merge(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Target   *WaitConfig
+		Other    *WaitConfig
 		Expected *WaitConfig
 	}{
 		{
-			"fully-populated",
+			"all-fields",
 			mockWaitConfig(),
 			&WaitConfig{
-				Min: helper.TimeToPtr(5 * time.Second),
-				Max: helper.TimeToPtr(10 * time.Second),
+				Min: helper.TimeToPtr(4 * time.Second),
+				Max: helper.TimeToPtr(9 * time.Second),
+			},
+			&WaitConfig{
+				Min: helper.TimeToPtr(4 * time.Second),
+				Max: helper.TimeToPtr(9 * time.Second),
 			},
 		},
 		{
 			"min-only",
+			mockWaitConfig(),
 			&WaitConfig{
-				Min: helper.TimeToPtr(5 * time.Second),
+				Min: helper.TimeToPtr(4 * time.Second),
+				Max: helper.TimeToPtr(10 * time.Second),
 			},
 			&WaitConfig{
-				Min: helper.TimeToPtr(5 * time.Second),
+				Min: helper.TimeToPtr(4 * time.Second),
+				Max: helper.TimeToPtr(10 * time.Second),
 			},
 		},
 		{
 			"max-only",
+			mockWaitConfig(),
 			&WaitConfig{
-				Max: helper.TimeToPtr(5 * time.Second),
+				Min: helper.TimeToPtr(5 * time.Second),
+				Max: helper.TimeToPtr(9 * time.Second),
 			},
 			&WaitConfig{
-				Max: helper.TimeToPtr(5 * time.Second),
+				Min: helper.TimeToPtr(5 * time.Second),
+				Max: helper.TimeToPtr(9 * time.Second),
 			},
 		},
 	}
 
 	for _, _case := range cases {
 		t.Run(_case.Name, func(t *testing.T) {
-			result := _case.Expected.Equals(_case.Wait.Copy())
+			merged := _case.Target.Merge(_case.Other)
+			result := _case.Expected.Equals(merged)
 			if !result {
-				t.Logf("\nExpected %v\n   Found %v", _case.Expected, result)
+				t.Logf("\nExpected %v\n   Found %v", _case.Expected, merged)
 			}
 			require.True(t, result)
 		})
 	}
 }
 
-func TestWaitConfig_IsEmpty(t *testing.T) {
+func TestWaitConfig_ToConsulTemplate(t *testing.T) {
+	expected := config.WaitConfig{
+		Enabled: helper.BoolToPtr(true),
+		Min:     helper.TimeToPtr(5 * time.Second),
+		Max:     helper.TimeToPtr(10 * time.Second),
+	}
+
+	clientWaitConfig := &WaitConfig{
+		Min: helper.TimeToPtr(5 * time.Second),
+		Max: helper.TimeToPtr(10 * time.Second),
+	}
+
+	actual, err := clientWaitConfig.ToConsulTemplate()
+	require.NoError(t, err)
+	require.Equal(t, *expected.Min, *actual.Min)
+	require.Equal(t, *expected.Max, *actual.Max)
+}
+
+func TestClientTemplateConfig_ToConsulTemplateConfig(t *testing.T) {
+	c := &ClientTemplateConfig{
+		FunctionDenylist:   []string{"plugin"},
+		BlockQueryWaitTime: helper.TimeToPtr(time.Minute),
+		MaxStale:           helper.TimeToPtr(5 * time.Second),
+		Wait: &WaitConfig{
+			Min: helper.TimeToPtr(2 * time.Second),
+			Max: helper.TimeToPtr(10 * time.Second),
+		},
+		ConsulRetry: &RetryConfig{
+			Attempts:   helper.IntToPtr(5),
+			Backoff:    helper.TimeToPtr(time.Second),
+			MaxBackoff: helper.TimeToPtr(time.Minute),
+		},
+		VaultRetry: &RetryConfig{
+			Attempts: helper.IntToPtr(3),
+			Backoff:  helper.TimeToPtr(2 * time.Second),
+		},
+	}
+
+	conf, err := c.ToConsulTemplateConfig("")
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, *conf.BlockQueryWaitTime)
+	require.Equal(t, 5*time.Second, *conf.MaxStale)
+	require.True(t, *conf.Wait.Enabled)
+	require.Equal(t, 2*time.Second, *conf.Wait.Min)
+	require.Equal(t, 10*time.Second, *conf.Wait.Max)
+	require.True(t, *conf.Consul.Retry.Enabled)
+	require.Equal(t, 5, *conf.Consul.Retry.Attempts)
+	require.Equal(t, time.Second, *conf.Consul.Retry.Backoff)
+	require.Equal(t, time.Minute, *conf.Consul.Retry.MaxBackoff)
+	require.True(t, *conf.Vault.Retry.Enabled)
+	require.Equal(t, 3, *conf.Vault.Retry.Attempts)
+	require.Equal(t, 2*time.Second, *conf.Vault.Retry.Backoff)
+	require.Nil(t, conf.Vault.Retry.MaxBackoff)
+
+	// The converted config doesn't share the client's fields
+	*conf.BlockQueryWaitTime = time.Hour
+	*conf.MaxStale = time.Hour
+	require.Equal(t, time.Minute, *c.BlockQueryWaitTime)
+	require.Equal(t, 5*time.Second, *c.MaxStale)
+
+	// Templates deny the client's functions and are sandboxed to the task
+	ct := c.ToConsulTemplateTemplateConfig("/alloc/task")
+	require.Equal(t, []string{"plugin"}, ct.FunctionDenylist)
+	require.Equal(t, "/alloc/task", *ct.SandboxPath)
+
+	c.DisableSandbox = true
+	ct = c.ToConsulTemplateTemplateConfig("/alloc/task")
+	require.Nil(t, ct.SandboxPath)
+
+	// Unset fields keep the consul-template defaults
+	var nilConfig *ClientTemplateConfig
+	for _, c := range []*ClientTemplateConfig{nilConfig, {}} {
+		conf, err := c.ToConsulTemplateConfig("")
+		require.NoError(t, err)
+		require.Equal(t, config.DefaultConfig(), conf)
+
+		ct := c.ToConsulTemplateTemplateConfig("/alloc/task")
+		require.Empty(t, ct.FunctionDenylist)
+		require.Equal(t, "/alloc/task", *ct.SandboxPath)
+	}
+}
+
+func TestClientTemplateConfig_ToConsulTemplateConfig_Invalid(t *testing.T) {
 	cases := []struct {
-		Name     string
-		Wait     *WaitConfig
-		Expected bool
+		Name        string
+		Config      *ClientTemplateConfig
+		ExpectedErr string
 	}{
 		{
-			"is-nil",
-			nil,
-			true,
+			Name:        "empty-wait",
+			Config:      &ClientTemplateConfig{Wait: &WaitConfig{}},
+			ExpectedErr: "invalid wait config: wait config is empty",
 		},
 		{
-			"is-empty",
-			&WaitConfig{},
-			true,
+			Name: "invalid-wait",
+			Config: &ClientTemplateConfig{
+				Wait: &WaitConfig{
+					Min: helper.TimeToPtr(10 * time.Second),
+					Max: helper.TimeToPtr(5 * time.Second),
+				},
+			},
+			ExpectedErr: "invalid wait config: wait config min",
 		},
 		{
-			"is-not-empty",
-			&WaitConfig{
-				Min: helper.TimeToPtr(10 * time.Second),
+			Name:        "empty-consul-retry",
+			Config:      &ClientTemplateConfig{ConsulRetry: &RetryConfig{}},
+			ExpectedErr: "invalid consul_retry config",
+		},
+		{
+			Name: "invalid-consul-retry",
+			Config: &ClientTemplateConfig{
+				ConsulRetry: &RetryConfig{
+					Backoff:    helper.TimeToPtr(time.Minute),
+					MaxBackoff: helper.TimeToPtr(time.Second),
+				},
 			},
-			false,
+			ExpectedErr: "invalid consul_retry config",
+		},
+		{
+			Name: "invalid-vault-retry",
+			Config: &ClientTemplateConfig{
+				VaultRetry: &RetryConfig{
+					Backoff:    helper.TimeToPtr(time.Minute),
+					MaxBackoff: helper.TimeToPtr(time.Second),
+				},
+			},
+			ExpectedErr: "invalid vault_retry config",
+		},
+		{
+			Name:        "empty-nomad-retry",
+			Config:      &ClientTemplateConfig{NomadRetry: &RetryConfig{}},
+			ExpectedErr: "invalid nomad_retry config",
+		},
+		{
+			Name: "invalid-nomad-retry",
+			Config: &ClientTemplateConfig{
+				NomadRetry: &RetryConfig{
+					Backoff:    helper.TimeToPtr(time.Minute),
+					MaxBackoff: helper.TimeToPtr(time.Second),
+				},
+			},
+			ExpectedErr: "invalid nomad_retry config",
 		},
 	}
 
-	for _, _case := range cases {
-		t.Run(_case.Name, func(t *testing.T) {
-			require.Equal(t, _case.Expected, _case.Wait.IsEmpty())
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			conf, err := tc.Config.ToConsulTemplateConfig("")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.ExpectedErr)
+			require.Nil(t, conf)
 		})
 	}
 }
 
-func TestWaitConfig_IsEqual(t *testing.T) {
+func TestClientTemplateConfig_TemplateWaitConfig(t *testing.T) {
+	clientWait := &WaitConfig{
+		Min: helper.TimeToPtr(5 * time.Second),
+		Max: helper.TimeToPtr(10 * time.Second),
+	}
+
 	cases := []struct {
-		Name     string
-		Wait     *WaitConfig
-		Other    *WaitConfig
-		Expected bool
+		Name         string
+		ClientConfig *ClientTemplateConfig
+		TemplateWait *structs.WaitConfig
+		ExpectedMin  time.Duration
+		ExpectedMax  time.Duration
+		ExpectedErr  string
 	}{
 		{
-			"are-equal",
-			mockWaitConfig(),
-			&WaitConfig{
-				Min: helper.TimeToPtr(5 * time.Second),
-				Max: helper.TimeToPtr(10 * time.Second),
+			Name:         "default",
+			ClientConfig: &ClientTemplateConfig{},
+		},
+		{
+			Name:         "client",
+			ClientConfig: &ClientTemplateConfig{Wait: clientWait.Copy()},
+			ExpectedMin:  5 * time.Second,
+			ExpectedMax:  10 * time.Second,
+		},
+		{
+			Name:         "template",
+			ClientConfig: &ClientTemplateConfig{},
+			TemplateWait: &structs.WaitConfig{
+				Min: helper.TimeToPtr(2 * time.Second),
+				Max: helper.TimeToPtr(12 * time.Second),
 			},
-			true,
+			ExpectedMin: 2 * time.Second,
+			ExpectedMax: 12 * time.Second,
 		},
 		{
-			"min-different",
-			mockWaitConfig(),
-			&WaitConfig{
-				Min: helper.TimeToPtr(4 * time.Second),
-				Max: helper.TimeToPtr(10 * time.Second),
+			Name:         "template-over-client",
+			ClientConfig: &ClientTemplateConfig{Wait: clientWait.Copy()},
+			TemplateWait: &structs.WaitConfig{
+				Max: helper.TimeToPtr(8 * time.Second),
 			},
-			false,
+			ExpectedMin: 5 * time.Second,
+			ExpectedMax: 8 * time.Second,
 		},
 		{
-			"max-different",
-			mockWaitConfig(),
-			&WaitConfig{
-				Min: helper.TimeToPtr(5 * time.Second),
-				Max: helper.TimeToPtr(9 * time.Second),
+			Name:         "template-min-clamped-by-client",
+			ClientConfig: &ClientTemplateConfig{Wait: clientWait.Copy()},
+			TemplateWait: &structs.WaitConfig{
+				Min: helper.TimeToPtr(2 * time.Second),
 			},
-			false,
+			ExpectedMin: 5 * time.Second,
+			ExpectedMax: 10 * time.Second,
+		},
+		{
+			Name: "template-within-bounds",
+			ClientConfig: &ClientTemplateConfig{
+				Wait: &WaitConfig{Max: helper.TimeToPtr(10 * time.Second)},
+				WaitBounds: &WaitConfig{
+					Min: helper.TimeToPtr(3 * time.Second),
+					Max: helper.TimeToPtr(11 * time.Second),
+				},
+			},
+			TemplateWait: &structs.WaitConfig{
+				Min: helper.TimeToPtr(2 * time.Second),
+				Max: helper.TimeToPtr(12 * time.Second),
+			},
+			ExpectedMin: 3 * time.Second,
+			ExpectedMax: 11 * time.Second,
+		},
+		{
+			Name: "template-min-above-bounds",
+			ClientConfig: &ClientTemplateConfig{
+				Wait: &WaitConfig{Max: helper.TimeToPtr(10 * time.Second)},
+				WaitBounds: &WaitConfig{
+					Min: helper.TimeToPtr(3 * time.Second),
+					Max: helper.TimeToPtr(11 * time.Second),
+				},
+			},
+			TemplateWait: &structs.WaitConfig{
+				Min: helper.TimeToPtr(20 * time.Second),
+			},
+			ExpectedMin: 11 * time.Second,
+			ExpectedMax: 11 * time.Second,
+		},
+		{
+			Name:         "template-min-greater-than-max",
+			ClientConfig: &ClientTemplateConfig{},
+			TemplateWait: &structs.WaitConfig{
+				Min: helper.TimeToPtr(10 * time.Second),
+				Max: helper.TimeToPtr(5 * time.Second),
+			},
+			ExpectedErr: "greater than",
+		},
+		{
+			Name:         "template-min-greater-than-client-max",
+			ClientConfig: &ClientTemplateConfig{Wait: clientWait.Copy()},
+			TemplateWait: &structs.WaitConfig{
+				Min: helper.TimeToPtr(20 * time.Second),
+			},
+			ExpectedMin: 20 * time.Second,
+			ExpectedMax: 20 * time.Second,
 		},
 	}
 
 	for _, _case := range cases {
 		t.Run(_case.Name, func(t *testing.T) {
-			require.Equal(t, _case.Expected, _case.Wait.Equals(_case.Other))
+			actual, err := _case.ClientConfig.TemplateWaitConfig(_case.TemplateWait)
+			if _case.ExpectedErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), _case.ExpectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if _case.ExpectedMin == 0 && _case.ExpectedMax == 0 {
+				require.Nil(t, actual)
+				return
+			}
+			require.True(t, *actual.Enabled)
+			require.Equal(t, _case.ExpectedMin, *actual.Min)
+			require.Equal(t, _case.ExpectedMax, *actual.Max)
 		})
 	}
+
+	// The client's wait config is not modified by a template's
+	c := &ClientTemplateConfig{Wait: clientWait.Copy()}
+	_, err := c.TemplateWaitConfig(&structs.WaitConfig{Min: helper.TimeToPtr(time.Second)})
+	require.NoError(t, err)
+	require.Equal(t, clientWait, c.Wait)
 }
 
-func TestWaitConfig_IsValid(t *testing.T) {
+func TestMergeTemplateWait(t *testing.T) {
+	client := &WaitConfig{
+		Min: helper.TimeToPtr(5 * time.Second),
+		Max: helper.TimeToPtr(30 * time.Second),
+	}
+
 	cases := []struct {
-		Name     string
-		Retry    *WaitConfig
-		Expected string
+		Name        string
+		Client      *WaitConfig
+		Job         *WaitConfig
+		ExpectedMin *time.Duration
+		ExpectedMax *time.Duration
 	}{
 		{
-			"is-valid",
-			&WaitConfig{
-				Min: helper.TimeToPtr(5 * time.Second),
-				Max: helper.TimeToPtr(10 * time.Second),
-			},
-			"",
+			Name:        "no job override",
+			Client:      client,
+			ExpectedMin: helper.TimeToPtr(5 * time.Second),
+			ExpectedMax: helper.TimeToPtr(30 * time.Second),
+		},
+		{
+			Name:        "no client config",
+			Job:         &WaitConfig{Min: helper.TimeToPtr(time.Second)},
+			ExpectedMin: helper.TimeToPtr(time.Second),
+		},
+		{
+			Name:        "job tightens bounds",
+			Client:      client,
+			Job:         &WaitConfig{Min: helper.TimeToPtr(10 * time.Second), Max: helper.TimeToPtr(20 * time.Second)},
+			ExpectedMin: helper.TimeToPtr(10 * time.Second),
+			ExpectedMax: helper.TimeToPtr(20 * time.Second),
 		},
 		{
-			"is-nil",
-			nil,
-			"is nil",
+			Name:        "job min clamped to client min",
+			Client:      client,
+			Job:         &WaitConfig{Min: helper.TimeToPtr(time.Second)},
+			ExpectedMin: helper.TimeToPtr(5 * time.Second),
+			ExpectedMax: helper.TimeToPtr(30 * time.Second),
 		},
 		{
-			"is-empty",
-			&WaitConfig{},
-			"or empty",
+			Name:        "job min equal to client min",
+			Client:      client,
+			Job:         &WaitConfig{Min: helper.TimeToPtr(5 * time.Second)},
+			ExpectedMin: helper.TimeToPtr(5 * time.Second),
+			ExpectedMax: helper.TimeToPtr(30 * time.Second),
 		},
 		{
-			"min-greater-than-max",
-			&WaitConfig{
-				Min: helper.TimeToPtr(10 * time.Second),
-				Max: helper.TimeToPtr(5 * time.Second),
-			},
-			"greater than",
+			Name:        "job min above client max raises max",
+			Client:      client,
+			Job:         &WaitConfig{Min: helper.TimeToPtr(time.Minute)},
+			ExpectedMin: helper.TimeToPtr(time.Minute),
+			ExpectedMax: helper.TimeToPtr(time.Minute),
 		},
 		{
-			"max-not-set",
-			&WaitConfig{
-				Min: helper.TimeToPtr(10 * time.Second),
-			},
-			"",
+			Name:        "job max below clamped min raises max",
+			Client:      client,
+			Job:         &WaitConfig{Min: helper.TimeToPtr(time.Second), Max: helper.TimeToPtr(2 * time.Second)},
+			ExpectedMin: helper.TimeToPtr(5 * time.Second),
+			ExpectedMax: helper.TimeToPtr(5 * time.Second),
 		},
 	}
 
 	for _, _case := range cases {
 		t.Run(_case.Name, func(t *testing.T) {
-			if _case.Expected == "" {
-				require.Nil(t, _case.Retry.Validate())
-			} else {
-				err := _case.Retry.Validate()
-				require.Contains(t, err.Error(), _case.Expected)
-			}
+			actual := MergeTemplateWait(_case.Client, _case.Job)
+			require.Equal(t, _case.ExpectedMin, actual.Min)
+			require.Equal(t, _case.ExpectedMax, actual.Max)
 		})
 	}
+
+	// Neither config is modified
+	require.Equal(t, 5*time.Second, *client.Min)
+	require.Nil(t, MergeTemplateWait(nil, nil))
 }
 
-func TestWaitConfig_Merge(t *testing.T) {
+func TestMergeTemplateRetry(t *testing.T) {
+	client := &RetryConfig{
+		Attempts: helper.IntToPtr(5),
+		Backoff:  helper.TimeToPtr(time.Second),
+	}
+
 	cases := []struct {
-		Name     string
-		Target   *WaitConfig
-		Other    *WaitConfig
-		Expected *WaitConfig
+		Name             string
+		Client           *RetryConfig
+		Job              *RetryConfig
+		ExpectedAttempts *int
+		ExpectedBackoff  *time.Duration
 	}{
 		{
-			"all-fields",
-			mockWaitConfig(),
-			&WaitConfig{
-				Min: helper.TimeToPtr(4 * time.Second),
-				Max: helper.TimeToPtr(9 * time.Second),
-			},
-			&WaitConfig{
-				Min: helper.TimeToPtr(4 * time.Second),
-				Max: helper.TimeToPtr(9 * time.Second),
-			},
+			Name:             "no job override",
+			Client:           client,
+			ExpectedAttempts: helper.IntToPtr(5),
+			ExpectedBackoff:  helper.TimeToPtr(time.Second),
 		},
 		{
-			"min-only",
-			mockWaitConfig(),
-			&WaitConfig{
-				Min: helper.TimeToPtr(4 * time.Second),
-				Max: helper.TimeToPtr(10 * time.Second),
-			},
-			&WaitConfig{
-				Min: helper.TimeToPtr(4 * time.Second),
-				Max: helper.TimeToPtr(10 * time.Second),
-			},
+			Name:             "job lowers attempts",
+			Client:           client,
+			Job:              &RetryConfig{Attempts: helper.IntToPtr(2), Backoff: helper.TimeToPtr(2 * time.Second)},
+			ExpectedAttempts: helper.IntToPtr(2),
+			ExpectedBackoff:  helper.TimeToPtr(2 * time.Second),
 		},
 		{
-			"max-only",
-			mockWaitConfig(),
-			&WaitConfig{
-				Min: helper.TimeToPtr(5 * time.Second),
-				Max: helper.TimeToPtr(9 * time.Second),
-			},
-			&WaitConfig{
-				Min: helper.TimeToPtr(5 * time.Second),
-				Max: helper.TimeToPtr(9 * time.Second),
-			},
+			Name:             "job attempts clamped to client attempts",
+			Client:           client,
+			Job:              &RetryConfig{Attempts: helper.IntToPtr(10)},
+			ExpectedAttempts: helper.IntToPtr(5),
+			ExpectedBackoff:  helper.TimeToPtr(time.Second),
+		},
+		{
+			Name:             "job unlimited attempts clamped to client attempts",
+			Client:           client,
+			Job:              &RetryConfig{Attempts: helper.IntToPtr(0)},
+			ExpectedAttempts: helper.IntToPtr(5),
+			ExpectedBackoff:  helper.TimeToPtr(time.Second),
+		},
+		{
+			Name:             "client unlimited attempts",
+			Client:           &RetryConfig{Attempts: helper.IntToPtr(0)},
+			Job:              &RetryConfig{Attempts: helper.IntToPtr(10)},
+			ExpectedAttempts: helper.IntToPtr(10),
 		},
 	}
 
 	for _, _case := range cases {
 		t.Run(_case.Name, func(t *testing.T) {
-			merged := _case.Target.Merge(_case.Other)
-			result := _case.Expected.Equals(merged)
-			if !result {
-				t.Logf("\nExpected %v\n   Found %v", _case.Expected, merged)
-			}
-			require.True(t, result)
+			actual := MergeTemplateRetry(_case.Client, _case.Job)
+			require.Equal(t, _case.ExpectedAttempts, actual.Attempts)
+			require.Equal(t, _case.ExpectedBackoff, actual.Backoff)
 		})
 	}
+
+	// Neither config is modified
+	require.Equal(t, 5, *client.Attempts)
+	require.Nil(t, MergeTemplateRetry(nil, nil))
 }
 
-func TestWaitConfig_ToConsulTemplate(t *testing.T) {
-	expected := config.WaitConfig{
-		Enabled: helper.BoolToPtr(true),
-		Min:     helper.TimeToPtr(5 * time.Second),
-		Max:     helper.TimeToPtr(10 * time.Second),
+func TestClientTemplateConfig_WithJobRetry(t *testing.T) {
+	c := &ClientTemplateConfig{
+		ConsulRetry: &RetryConfig{Attempts: helper.IntToPtr(3)},
 	}
 
-	clientWaitConfig := &WaitConfig{
-		Min: helper.TimeToPtr(5 * time.Second),
-		Max: helper.TimeToPtr(10 * time.Second),
-	}
+	nc := c.WithJobRetry(&RetryConfig{Attempts: helper.IntToPtr(6)})
+	require.Equal(t, 3, *nc.ConsulRetry.Attempts)
+	require.Equal(t, 6, *nc.VaultRetry.Attempts)
+	require.Equal(t, 6, *nc.NomadRetry.Attempts)
 
-	actual, err := clientWaitConfig.ToConsulTemplate()
-	require.NoError(t, err)
-	require.Equal(t, *expected.Min, *actual.Min)
-	require.Equal(t, *expected.Max, *actual.Max)
+	// The client's config is not modified
+	require.Nil(t, c.VaultRetry)
+	require.Nil(t, c.NomadRetry)
 }
 
 func mockRetryConfig() *RetryConfig {
@@ -381,6 +2308,87 @@ func TestRetryConfig_Copy(t *testing.T) {
 	}
 }
 
+func TestRetryConfig_Copy_NilFields(t *testing.T) {
+	// Exercise every combination of set and unset pointer fields.
+	for mask := 0; mask < 1<<3; mask++ {
+		rc := &RetryConfig{}
+		if mask&1 != 0 {
+			rc.Attempts = helper.IntToPtr(5)
+		}
+		if mask&2 != 0 {
+			rc.Backoff = helper.TimeToPtr(5 * time.Second)
+		}
+		if mask&4 != 0 {
+			rc.MaxBackoff = helper.TimeToPtr(10 * time.Second)
+		}
+
+		t.Run(fmt.Sprintf("mask-%03b", mask), func(t *testing.T) {
+			var cp *RetryConfig
+			require.NotPanics(t, func() { cp = rc.Copy() })
+			require.True(t, rc.Equals(cp))
+			require.NotSame(t, rc, cp)
+
+			if rc.Attempts != nil {
+				require.NotSame(t, rc.Attempts, cp.Attempts)
+				*cp.Attempts = 1
+				require.Equal(t, 5, *rc.Attempts)
+			} else {
+				require.Nil(t, cp.Attempts)
+			}
+
+			if rc.Backoff != nil {
+				require.NotSame(t, rc.Backoff, cp.Backoff)
+				*cp.Backoff = time.Minute
+				require.Equal(t, 5*time.Second, *rc.Backoff)
+			} else {
+				require.Nil(t, cp.Backoff)
+			}
+
+			if rc.MaxBackoff != nil {
+				require.NotSame(t, rc.MaxBackoff, cp.MaxBackoff)
+				*cp.MaxBackoff = time.Minute
+				require.Equal(t, 10*time.Second, *rc.MaxBackoff)
+			} else {
+				require.Nil(t, cp.MaxBackoff)
+			}
+		})
+	}
+}
+
+func TestRetryConfig_Merge_Isolation(t *testing.T) {
+	a := &RetryConfig{Attempts: helper.IntToPtr(5)}
+	b := &RetryConfig{
+		Backoff:    helper.TimeToPtr(time.Second),
+		MaxBackoff: helper.TimeToPtr(time.Minute),
+	}
+
+	// Mutating a merged config leaves both inputs untouched
+	merged := a.Merge(b)
+	*merged.Attempts = 10
+	*merged.Backoff = time.Hour
+	*merged.MaxBackoff = time.Hour
+	require.Equal(t, 5, *a.Attempts)
+	require.Equal(t, time.Second, *b.Backoff)
+	require.Equal(t, time.Minute, *b.MaxBackoff)
+
+	var nilConfig *RetryConfig
+	for _, merged := range []*RetryConfig{nilConfig.Merge(b), b.Merge(nil)} {
+		require.NotSame(t, b, merged)
+		*merged.Backoff = time.Hour
+		require.Equal(t, time.Second, *b.Backoff)
+	}
+
+	// Neither is the consul-template config sharing its fields
+	ct, err := a.Merge(b).ToConsulTemplate()
+	require.NoError(t, err)
+	*ct.Attempts = 10
+	*ct.Backoff = time.Hour
+	*ct.MaxBackoff = time.Hour
+	require.Equal(t, 5, *a.Attempts)
+	require.Equal(t, time.Second, *b.Backoff)
+	require.Equal(t, time.Minute, *b.MaxBackoff)
+}
+
 func TestRetryConfig_IsEmpty(t *testing.T) {
 	cases := []struct {
 		Name     string
@@ -658,3 +2666,427 @@ func TestRetryConfig_ToConsulTemplate(t *testing.T) {
 	require.Equal(t, *expected.Backoff, *actual.Backoff)
 	require.Equal(t, *expected.MaxBackoff, *actual.MaxBackoff)
 }
+
+func TestConfig_Validate(t *testing.T) {
+	require.NoError(t, DefaultConfig().Validate())
+	require.NoError(t, (&Config{}).Validate())
+
+	cases := []struct {
+		name      string
+		modify    func(*Config)
+		expectErr string
+	}{
+		{
+			name: "dynamic ports swapped",
+			modify: func(c *Config) {
+				c.MinDynamicPort, c.MaxDynamicPort = 32000, 20000
+			},
+			expectErr: "min_dynamic_port 32000 is greater than max_dynamic_port 20000",
+		},
+		{
+			name:      "dynamic port out of range",
+			modify:    func(c *Config) { c.MaxDynamicPort = 70000 },
+			expectErr: "max_dynamic_port must be between 0 and 65535, got 70000",
+		},
+		{
+			name: "invalid host network cidrs entry",
+			modify: func(c *Config) {
+				c.HostNetworks = map[string]*structs.ClientHostNetworkConfig{
+					"public": {Name: "public", CIDRs: []string{"10.0.0.0/8", "nope"}},
+				}
+			},
+			expectErr: `host_network "public" has an invalid cidr: invalid CIDR address: nope`,
+		},
+		{
+			name: "invalid comma-separated host network cidr",
+			modify: func(c *Config) {
+				c.HostNetworks = map[string]*structs.ClientHostNetworkConfig{
+					"public": {Name: "public", CIDR: "10.0.0.0/8, 300.0.0.0/8"},
+				}
+			},
+			expectErr: `host_network "public" has an invalid cidr: invalid CIDR address: 300.0.0.0/8`,
+		},
+		{
+			name:      "disk threshold out of range",
+			modify:    func(c *Config) { c.GCDiskUsageThreshold = 300 },
+			expectErr: "gc_disk_usage_threshold must be between 0 and 100, got 300",
+		},
+		{
+			name:      "inode threshold negative",
+			modify:    func(c *Config) { c.GCInodeUsageThreshold = -1 },
+			expectErr: "gc_inode_usage_threshold must be between 0 and 100, got -1",
+		},
+		{
+			name:      "negative state dir reserve",
+			modify:    func(c *Config) { c.StateDirReserveMB = -1 },
+			expectErr: "state_dir_reserve_mb must not be negative, got -1",
+		},
+		{
+			name:      "negative parallel destroys",
+			modify:    func(c *Config) { c.GCParallelDestroys = -2 },
+			expectErr: "gc_parallel_destroys must not be negative, got -2",
+		},
+		{
+			name:      "negative download bandwidth",
+			modify:    func(c *Config) { c.NodeDownloadBandwidthMbps = -1 },
+			expectErr: "node_download_bandwidth_mbps must not be negative, got -1",
+		},
+		{
+			name:      "negative task tmpfs size",
+			modify:    func(c *Config) { c.MaxTaskTmpfsMB = -1 },
+			expectErr: "max_task_tmpfs_mb must not be negative, got -1",
+		},
+		{
+			name:      "absolute core dump dir",
+			modify:    func(c *Config) { c.CoreDumps = &CoreDumpConfig{Dir: "/var/cores"} },
+			expectErr: `core_dumps dir "/var/cores" must be relative to the allocation directory`,
+		},
+		{
+			name:      "core dump dir outside the alloc dir",
+			modify:    func(c *Config) { c.CoreDumps = &CoreDumpConfig{Dir: "cores/../../cores"} },
+			expectErr: `core_dumps dir "cores/../../cores" must be relative to the allocation directory`,
+		},
+		{
+			name: "unknown download bandwidth class",
+			modify: func(c *Config) {
+				c.NodeDownloadBandwidthWeights = map[string]int{"backup": 1}
+			},
+			expectErr: `node_download_bandwidth_weights: unknown bandwidth class "backup"`,
+		},
+		{
+			name:      "negative max server connections",
+			modify:    func(c *Config) { c.MaxServerConnections = -1 },
+			expectErr: "max_server_connections must not be negative, got -1",
+		},
+		{
+			name: "negative driver health max failures",
+			modify: func(c *Config) {
+				c.DriverHealthThresholds = map[string]*DriverHealthThreshold{
+					"docker": {Driver: "docker", MaxFailures: -1},
+				}
+			},
+			expectErr: `driver_health "docker" max_failures must not be negative`,
+		},
+		{
+			name:      "negative namespace max allocs",
+			modify:    func(c *Config) { c.GCMaxAllocsPerNamespace = map[string]int{"batch": -1} },
+			expectErr: `gc_max_allocs_per_namespace for namespace "batch" must not be negative, got -1`,
+		},
+		{
+			name:      "negative duration",
+			modify:    func(c *Config) { c.GCInterval = -time.Second },
+			expectErr: "gc_interval must not be negative, got -1s",
+		},
+		{
+			name:      "invalid bridge subnet",
+			modify:    func(c *Config) { c.BridgeNetworkAllocSubnet = "172.26.64.0" },
+			expectErr: `bridge_network_subnet "172.26.64.0" is not a valid CIDR`,
+		},
+		{
+			name:      "relative bridge cni config path",
+			modify:    func(c *Config) { c.BridgeNetworkCNIConfigPath = "nomad.conflist" },
+			expectErr: `bridge_network_cni_config_path "nomad.conflist" must be absolute`,
+		},
+		{
+			name:      "invalid cni interface prefix",
+			modify:    func(c *Config) { c.CNIInterfacePrefix = "eth/" },
+			expectErr: `cni_interface_prefix "eth/"`,
+		},
+		{
+			name:      "cni interface prefix too long",
+			modify:    func(c *Config) { c.CNIInterfacePrefix = "averylongprefix" },
+			expectErr: `cni_interface_prefix "averylongprefix"`,
+		},
+		{
+			name: "relative host volume",
+			modify: func(c *Config) {
+				c.HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+					"vol": {Name: "vol", Path: "data/vol"},
+				}
+			},
+			expectErr: `host volume "vol" path "data/vol" must be absolute`,
+		},
+		{
+			name:      "negative csi claim retry attempts",
+			modify:    func(c *Config) { c.CSIClaimRetry = &RetryConfig{Attempts: helper.IntToPtr(-1)} },
+			expectErr: "csi_claim_retry attempts must not be negative, got -1",
+		},
+		{
+			name: "csi claim retry backoff over default max",
+			modify: func(c *Config) {
+				c.CSIClaimRetry = &RetryConfig{Backoff: helper.TimeToPtr(time.Minute)}
+			},
+			expectErr: "csi_claim_retry: retry config backoff",
+		},
+		{
+			name:      "negative csi unpublish retry attempts",
+			modify:    func(c *Config) { c.CSIUnpublishRetry = &RetryConfig{Attempts: helper.IntToPtr(-2)} },
+			expectErr: "csi_unpublish_retry attempts must not be negative, got -2",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tc.modify(c)
+			err := c.Validate()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.expectErr)
+		})
+	}
+
+	// Every problem is reported
+	c := DefaultConfig()
+	c.GCDiskUsageThreshold = 300
+	c.GCParallelDestroys = -1
+	c.BridgeNetworkAllocSubnet = "nonsense"
+	err := c.Validate()
+	require.Error(t, err)
+	require.Len(t, err.(*multierror.Error).Errors, 3)
+}
+
+func TestConfig_DynamicPortRange(t *testing.T) {
+	cases := []struct {
+		name      string
+		min       int
+		max       int
+		expectMin int
+		expectMax int
+		expectErr string
+	}{
+		{
+			name:      "unset",
+			expectMin: structs.DefaultMinDynamicPort,
+			expectMax: structs.DefaultMaxDynamicPort,
+		},
+		{
+			name:      "default config",
+			min:       DefaultConfig().MinDynamicPort,
+			max:       DefaultConfig().MaxDynamicPort,
+			expectMin: structs.DefaultMinDynamicPort,
+			expectMax: structs.DefaultMaxDynamicPort,
+		},
+		{
+			name:      "only min set",
+			min:       25000,
+			expectMin: 25000,
+			expectMax: structs.DefaultMaxDynamicPort,
+		},
+		{
+			name:      "single port",
+			min:       25000,
+			max:       25000,
+			expectMin: 25000,
+			expectMax: 25000,
+		},
+		{
+			name:      "inverted",
+			min:       32000,
+			max:       20000,
+			expectErr: "min_dynamic_port 32000 is greater than max_dynamic_port 20000; swap the two values",
+		},
+		{
+			name:      "max below default min",
+			max:       15000,
+			expectErr: "max_dynamic_port 15000 is less than the default min_dynamic_port 20000",
+		},
+		{
+			name:      "min above default max",
+			min:       40000,
+			expectErr: "min_dynamic_port 40000 is greater than the default max_dynamic_port 32000",
+		},
+		{
+			name:      "negative",
+			min:       -1,
+			expectErr: "min_dynamic_port must be between 0 and 65535, got -1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Config{MinDynamicPort: tc.min, MaxDynamicPort: tc.max}
+			min, max, err := c.DynamicPortRange()
+			if tc.expectErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectMin, min)
+			require.Equal(t, tc.expectMax, max)
+		})
+	}
+}
+
+func TestConfig_Warnings(t *testing.T) {
+	require.NoError(t, DefaultConfig().Warnings())
+	require.NoError(t, (&Config{}).Warnings())
+
+	cases := []struct {
+		name          string
+		gcInterval    time.Duration
+		statsInterval time.Duration
+		expectWarning bool
+	}{
+		{
+			name:          "gc slower than stats",
+			gcInterval:    time.Minute,
+			statsInterval: time.Second,
+		},
+		{
+			name:          "equal intervals",
+			gcInterval:    10 * time.Second,
+			statsInterval: 10 * time.Second,
+		},
+		{
+			name:          "gc at the ratio",
+			gcInterval:    5 * time.Second,
+			statsInterval: 10 * time.Second,
+		},
+		{
+			name:          "gc much faster than stats",
+			gcInterval:    time.Second,
+			statsInterval: 10 * time.Second,
+			expectWarning: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.GCInterval = tc.gcInterval
+			c.StatsCollectionInterval = tc.statsInterval
+
+			err := c.Warnings()
+			if !tc.expectWarning {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(),
+				"gc_interval (1s) is much shorter than telemetry collection_interval (10s)")
+		})
+	}
+}
+
+func TestConfig_Validate_HostVolumes(t *testing.T) {
+	cases := []struct {
+		name      string
+		path      string
+		expectErr string
+	}{
+		{
+			name: "disjoint",
+			path: "/srv/data",
+		},
+		{
+			name: "sibling with common prefix",
+			path: "/var/nomad/allocations",
+		},
+		{
+			name:      "equal to alloc dir",
+			path:      "/var/nomad/alloc",
+			expectErr: `host volume "vol" path "/var/nomad/alloc" overlaps alloc_dir "/var/nomad/alloc"`,
+		},
+		{
+			name:      "inside alloc dir",
+			path:      "/var/nomad/alloc/shared",
+			expectErr: `overlaps alloc_dir "/var/nomad/alloc"`,
+		},
+		{
+			name:      "inside state dir",
+			path:      "/var/nomad/client/../client/db",
+			expectErr: `overlaps state_dir "/var/nomad/client"`,
+		},
+		{
+			name:      "contains both",
+			path:      "/var/nomad/",
+			expectErr: `overlaps state_dir`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.AllocDir = "/var/nomad/alloc"
+			c.StateDir = "/var/nomad/client"
+			c.HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+				"vol": {Name: "vol", Path: tc.path},
+			}
+
+			err := c.Validate()
+			if tc.expectErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectErr)
+			}
+		})
+	}
+
+	// Unset directories don't overlap anything
+	c := DefaultConfig()
+	c.HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"vol": {Name: "vol", Path: "/var/nomad/alloc"},
+	}
+	require.NoError(t, c.Validate())
+}
+
+func TestCoreDumpConfig_Merge(t *testing.T) {
+	var nilConfig *CoreDumpConfig
+	b := &CoreDumpConfig{Enabled: true, MaxSizeMB: 64}
+	merged := nilConfig.Merge(b)
+	require.Equal(t, b, merged)
+	require.NotSame(t, b, merged)
+
+	a := &CoreDumpConfig{Dir: "cores", Retain: 5, MaxSizeMB: 128}
+	require.Equal(t, &CoreDumpConfig{
+		Enabled:   true,
+		MaxSizeMB: 64,
+		Dir:       "cores",
+		Retain:    5,
+	}, a.Merge(b))
+	require.Equal(t, a, a.Merge(nil))
+
+	// Defaults apply to unset values
+	require.Equal(t, int64(DefaultCoreDumpMaxSizeMB*1024*1024), (&CoreDumpConfig{}).MaxSizeBytes())
+	require.Equal(t, DefaultCoreDumpRetain, (&CoreDumpConfig{}).RetainCount())
+	require.Equal(t, int64(128*1024*1024), a.MaxSizeBytes())
+	require.Equal(t, 5, a.RetainCount())
+}
+
+func TestAuditConfig_Merge(t *testing.T) {
+	var nilConfig *AuditConfig
+	b := &AuditConfig{Enabled: true, MaxFiles: 2}
+	merged := nilConfig.Merge(b)
+	require.Equal(t, b, merged)
+	require.NotSame(t, b, merged)
+
+	a := &AuditConfig{MaxFileMB: 20, MaxFiles: 8}
+	require.Equal(t, &AuditConfig{
+		Enabled:   true,
+		MaxFileMB: 20,
+		MaxFiles:  2,
+	}, a.Merge(b))
+	require.Equal(t, a, a.Merge(nil))
+
+	// Defaults apply to unset values
+	require.Equal(t, DefaultAuditMaxFileMB, (&AuditConfig{}).MaxFileSizeMB())
+	require.Equal(t, DefaultAuditMaxFiles, (&AuditConfig{}).MaxFileCount())
+	require.Equal(t, 20, a.MaxFileSizeMB())
+	require.Equal(t, 8, a.MaxFileCount())
+
+	c := DefaultConfig()
+	c.Audit = &AuditConfig{MaxFileMB: -1}
+	require.Error(t, c.Validate())
+}
+
+func TestReloadOutcomes(t *testing.T) {
+	outcomes := make(ReloadOutcomes)
+	require.Empty(t, outcomes.String())
+
+	outcomes.Set("tls", false, ReloadRestarted)
+	outcomes.Set("gc", true, ReloadRestarted)
+	outcomes.Set("template", true, ReloadUpdated)
+	require.Equal(t, "gc: restarted, template: updated, tls: unchanged", outcomes.String())
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// optionsLock guards the registered option keys and prefixes
+	optionsLock sync.RWMutex
+
+	// optionKeys are the option keys registered by their consumers
+	optionKeys = make(map[string]struct{})
+
+	// optionPrefixes are the option key prefixes registered by their
+	// consumers, such as drivers reading every option of their subtree
+	optionPrefixes = make(map[string]struct{})
+)
+
+// RegisterOptionKeys registers option keys read by a fingerprinter, driver or
+// other part of the client, so that they aren't reported by UnusedOptions.
+// It is meant to be called from the init function of the package reading the
+// options.
+func RegisterOptionKeys(keys ...string) {
+	optionsLock.Lock()
+	defer optionsLock.Unlock()
+
+	for _, key := range keys {
+		optionKeys[key] = struct{}{}
+	}
+}
+
+// RegisterOptionPrefixes registers option key prefixes, such as "docker.",
+// whose keys are all read by a driver or other part of the client, so that
+// they aren't reported by UnusedOptions. It is meant to be called from the
+// init function of the package reading the options.
+func RegisterOptionPrefixes(prefixes ...string) {
+	optionsLock.Lock()
+	defer optionsLock.Unlock()
+
+	for _, prefix := range prefixes {
+		optionPrefixes[prefix] = struct{}{}
+	}
+}
+
+// UnusedOptions returns the sorted keys of the options that were neither
+// registered nor fall under a registered prefix. They are likely to be
+// misspelled, as nothing reads them.
+func (c *Config) UnusedOptions() []string {
+	optionsLock.RLock()
+	defer optionsLock.RUnlock()
+
+	var unused []string
+	for key := range c.Options {
+		if !isRegisteredOption(key) {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// isRegisteredOption returns whether the option key or one of its prefixes
+// was registered. optionsLock must be held.
+func isRegisteredOption(key string) bool {
+	if _, ok := optionKeys[key]; ok {
+		return true
+	}
+	for prefix := range optionPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	RegisterOptionKeys("user.denylist", "user.blacklist", "user.checked_drivers")
+	RegisterOptionPrefixes("user.denylist.")
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_UnusedOptions(t *testing.T) {
+	RegisterOptionKeys("test.fingerprint.network.disallow_link_local")
+	RegisterOptionPrefixes("test.driver.docker.")
+
+	config := &Config{Options: map[string]string{
+		// Registered keys and the subtree of registered prefixes aren't
+		// reported
+		"test.fingerprint.network.disallow_link_local": "true",
+		"test.driver.docker.volumes.enabled":           "true",
+		"test.driver.docker.auth.config":               "/etc/docker.json",
+		"user.checked_drivers":                         "exec",
+
+		// Misspelled keys are reported
+		"test.fingerprint.network.dissallow_link_local": "true",
+		"test.driver.dockr.volumes.enabled":             "true",
+
+		// Prefixes only match at their start, including the trailing dot
+		"test.driver.docker":                        "true",
+		"prefix.test.driver.docker.volumes.enabled": "true",
+	}}
+
+	require.Equal(t, []string{
+		"prefix.test.driver.docker.volumes.enabled",
+		"test.driver.docker",
+		"test.driver.dockr.volumes.enabled",
+		"test.fingerprint.network.dissallow_link_local",
+	}, config.UnusedOptions())
+
+	require.Empty(t, (&Config{}).UnusedOptions())
+}
@@ -83,8 +83,9 @@ func (c *CSI) ControllerValidateVolume(req *structs.ClientCSIControllerValidateV
 // 2. Call ControllerPublishVolume on the CSI Plugin to trigger a remote attachment
 //
 // In the future this may be expanded to request dynamic secrets for attachment.
-func (c *CSI) ControllerAttachVolume(req *structs.ClientCSIControllerAttachVolumeRequest, resp *structs.ClientCSIControllerAttachVolumeResponse) error {
+func (c *CSI) ControllerAttachVolume(req *structs.ClientCSIControllerAttachVolumeRequest, resp *structs.ClientCSIControllerAttachVolumeResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "csi_controller", "publish_volume"}, time.Now())
+	defer c.c.auditRPC("CSI.ControllerAttachVolume", "", req, &err)
 	plugin, err := c.findControllerPlugin(req.PluginID)
 	if err != nil {
 		// the server's view of the plugin health is stale, so let it know it
@@ -131,8 +132,9 @@ func (c *CSI) ControllerAttachVolume(req *structs.ClientCSIControllerAttachVolum
 
 // ControllerDetachVolume is used to detach a volume from a CSI Cluster from
 // the storage node provided in the request.
-func (c *CSI) ControllerDetachVolume(req *structs.ClientCSIControllerDetachVolumeRequest, resp *structs.ClientCSIControllerDetachVolumeResponse) error {
+func (c *CSI) ControllerDetachVolume(req *structs.ClientCSIControllerDetachVolumeRequest, resp *structs.ClientCSIControllerDetachVolumeResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "csi_controller", "unpublish_volume"}, time.Now())
+	defer c.c.auditRPC("CSI.ControllerDetachVolume", "", req, &err)
 	plugin, err := c.findControllerPlugin(req.PluginID)
 	if err != nil {
 		// the server's view of the plugin health is stale, so let it know it
@@ -178,8 +180,9 @@ func (c *CSI) ControllerDetachVolume(req *structs.ClientCSIControllerDetachVolum
 	return err
 }
 
-func (c *CSI) ControllerCreateVolume(req *structs.ClientCSIControllerCreateVolumeRequest, resp *structs.ClientCSIControllerCreateVolumeResponse) error {
+func (c *CSI) ControllerCreateVolume(req *structs.ClientCSIControllerCreateVolumeRequest, resp *structs.ClientCSIControllerCreateVolumeResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "csi_controller", "create_volume"}, time.Now())
+	defer c.c.auditRPC("CSI.ControllerCreateVolume", "", req, &err)
 
 	plugin, err := c.findControllerPlugin(req.PluginID)
 	if err != nil {
@@ -219,8 +222,9 @@ func (c *CSI) ControllerCreateVolume(req *structs.ClientCSIControllerCreateVolum
 	return nil
 }
 
-func (c *CSI) ControllerDeleteVolume(req *structs.ClientCSIControllerDeleteVolumeRequest, resp *structs.ClientCSIControllerDeleteVolumeResponse) error {
+func (c *CSI) ControllerDeleteVolume(req *structs.ClientCSIControllerDeleteVolumeRequest, resp *structs.ClientCSIControllerDeleteVolumeResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "csi_controller", "delete_volume"}, time.Now())
+	defer c.c.auditRPC("CSI.ControllerDeleteVolume", "", req, &err)
 
 	plugin, err := c.findControllerPlugin(req.PluginID)
 	if err != nil {
@@ -311,8 +315,9 @@ func (c *CSI) ControllerListVolumes(req *structs.ClientCSIControllerListVolumesR
 	return nil
 }
 
-func (c *CSI) ControllerCreateSnapshot(req *structs.ClientCSIControllerCreateSnapshotRequest, resp *structs.ClientCSIControllerCreateSnapshotResponse) error {
+func (c *CSI) ControllerCreateSnapshot(req *structs.ClientCSIControllerCreateSnapshotRequest, resp *structs.ClientCSIControllerCreateSnapshotResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "csi_controller", "create_snapshot"}, time.Now())
+	defer c.c.auditRPC("CSI.ControllerCreateSnapshot", "", req, &err)
 
 	plugin, err := c.findControllerPlugin(req.PluginID)
 	if err != nil {
@@ -354,8 +359,9 @@ func (c *CSI) ControllerCreateSnapshot(req *structs.ClientCSIControllerCreateSna
 	return nil
 }
 
-func (c *CSI) ControllerDeleteSnapshot(req *structs.ClientCSIControllerDeleteSnapshotRequest, resp *structs.ClientCSIControllerDeleteSnapshotResponse) error {
+func (c *CSI) ControllerDeleteSnapshot(req *structs.ClientCSIControllerDeleteSnapshotRequest, resp *structs.ClientCSIControllerDeleteSnapshotResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "csi_controller", "delete_snapshot"}, time.Now())
+	defer c.c.auditRPC("CSI.ControllerDeleteSnapshot", "", req, &err)
 
 	plugin, err := c.findControllerPlugin(req.PluginID)
 	if err != nil {
@@ -442,8 +448,9 @@ func (c *CSI) ControllerListSnapshots(req *structs.ClientCSIControllerListSnapsh
 
 // NodeDetachVolume is used to detach a volume from a CSI Cluster from
 // the storage node provided in the request.
-func (c *CSI) NodeDetachVolume(req *structs.ClientCSINodeDetachVolumeRequest, resp *structs.ClientCSINodeDetachVolumeResponse) error {
+func (c *CSI) NodeDetachVolume(req *structs.ClientCSINodeDetachVolumeRequest, resp *structs.ClientCSINodeDetachVolumeResponse) (err error) {
 	defer metrics.MeasureSince([]string{"client", "csi_node", "detach_volume"}, time.Now())
+	defer c.c.auditRPC("CSI.NodeDetachVolume", "", req, &err)
 
 	// The following block of validation checks should not be reached on a
 	// real Nomad cluster. They serve as a defensive check before forwarding
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/config"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 )
 
@@ -23,23 +24,26 @@ func init() {
 	// Initialize the list of available fingerprinters per platform.  Each
 	// platform defines its own list of available fingerprinters.
 	initPlatformFingerprints(hostFingerprinters)
+
+	config.RegisterOptionKeys(TightenNetworkTimeoutsConfig, networkDisallowLinkLocalOption)
 }
 
 var (
 	// hostFingerprinters contains the host fingerprints which are available for a
 	// given platform.
 	hostFingerprinters = map[string]Factory{
-		"arch":    NewArchFingerprint,
-		"consul":  NewConsulFingerprint,
-		"cni":     NewCNIFingerprint,
-		"cpu":     NewCPUFingerprint,
-		"host":    NewHostFingerprint,
-		"memory":  NewMemoryFingerprint,
-		"network": NewNetworkFingerprint,
-		"nomad":   NewNomadFingerprint,
-		"signal":  NewSignalFingerprint,
-		"storage": NewStorageFingerprint,
-		"vault":   NewVaultFingerprint,
+		"arch":        NewArchFingerprint,
+		"consul":      NewConsulFingerprint,
+		"cni":         NewCNIFingerprint,
+		"cpu":         NewCPUFingerprint,
+		"host":        NewHostFingerprint,
+		"host_volume": NewHostVolumeFingerprint,
+		"memory":      NewMemoryFingerprint,
+		"network":     NewNetworkFingerprint,
+		"nomad":       NewNomadFingerprint,
+		"signal":      NewSignalFingerprint,
+		"storage":     NewStorageFingerprint,
+		"vault":       NewVaultFingerprint,
 	}
 
 	// envFingerprinters contains the fingerprints that are environment specific.
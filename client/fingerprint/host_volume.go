@@ -0,0 +1,70 @@
+package fingerprint
+
+import (
+	"strconv"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+// HostVolumeFingerprint publishes the total and free bytes of the filesystem
+// of each host volume as node attributes, so that the capacity of the volumes
+// is visible to the scheduler and operators.
+type HostVolumeFingerprint struct {
+	logger  log.Logger
+	storage StorageFingerprint
+
+	// volumes are the names of the volumes fingerprinted by the last run,
+	// whose attributes are removed once they're no longer fingerprinted
+	volumes map[string]struct{}
+}
+
+// NewHostVolumeFingerprint returns a new host volume fingerprinter
+func NewHostVolumeFingerprint(logger log.Logger) Fingerprint {
+	return &HostVolumeFingerprint{
+		logger:  logger.Named("host_volume"),
+		volumes: make(map[string]struct{}),
+	}
+}
+
+func (f *HostVolumeFingerprint) Fingerprint(req *FingerprintRequest, resp *FingerprintResponse) error {
+	volumes := make(map[string]struct{}, len(req.Config.HostVolumes))
+	for name, vol := range req.Config.HostVolumes {
+		if vol == nil {
+			continue
+		}
+
+		_, total, free, err := f.storage.diskFree(vol.Path)
+		if err != nil {
+			f.logger.Debug("failed to determine disk space of host volume",
+				"volume", name, "path", vol.Path, "error", err)
+			continue
+		}
+
+		resp.AddAttribute(hostVolumeAttribute(name, "bytestotal"), strconv.FormatUint(total, 10))
+		resp.AddAttribute(hostVolumeAttribute(name, "bytesfree"), strconv.FormatUint(free, 10))
+		volumes[name] = struct{}{}
+	}
+
+	for name := range f.volumes {
+		if _, ok := volumes[name]; !ok {
+			resp.RemoveAttribute(hostVolumeAttribute(name, "bytestotal"))
+			resp.RemoveAttribute(hostVolumeAttribute(name, "bytesfree"))
+		}
+	}
+	f.volumes = volumes
+
+	resp.Detected = len(volumes) != 0
+	return nil
+}
+
+// Periodic refreshes the capacity of the host volumes on the same interval as
+// the other periodic fingerprints.
+func (f *HostVolumeFingerprint) Periodic() (bool, time.Duration) {
+	return true, interval * time.Second
+}
+
+// hostVolumeAttribute returns the name of a node attribute of a host volume.
+func hostVolumeAttribute(volume, name string) string {
+	return "unique.host_volume." + volume + "." + name
+}
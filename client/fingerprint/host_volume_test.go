@@ -0,0 +1,51 @@
+package fingerprint
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostVolumeFingerprint(t *testing.T) {
+	fp := NewHostVolumeFingerprint(testlog.HCLogger(t))
+	periodic, _ := fp.Periodic()
+	require.True(t, periodic)
+
+	conf := &config.Config{
+		HostVolumes: map[string]*structs.ClientHostVolumeConfig{
+			"data":    {Name: "data", Path: t.TempDir()},
+			"missing": {Name: "missing", Path: filepath.Join(t.TempDir(), "missing"), ReadOnly: true},
+		},
+	}
+	node := &structs.Node{Attributes: make(map[string]string)}
+
+	var resp FingerprintResponse
+	require.NoError(t, fp.Fingerprint(&FingerprintRequest{Config: conf, Node: node}, &resp))
+	require.True(t, resp.Detected)
+
+	total, err := strconv.ParseUint(resp.Attributes["unique.host_volume.data.bytestotal"], 10, 64)
+	require.NoError(t, err)
+	free, err := strconv.ParseUint(resp.Attributes["unique.host_volume.data.bytesfree"], 10, 64)
+	require.NoError(t, err)
+	require.NotZero(t, total)
+	require.LessOrEqual(t, free, total)
+
+	// Volumes with a missing path have no capacity
+	require.NotContains(t, resp.Attributes, "unique.host_volume.missing.bytestotal")
+	require.NotContains(t, resp.Attributes, "unique.host_volume.missing.bytesfree")
+
+	// The attributes of volumes no longer configured are removed
+	delete(conf.HostVolumes, "data")
+	resp = FingerprintResponse{}
+	require.NoError(t, fp.Fingerprint(&FingerprintRequest{Config: conf, Node: node}, &resp))
+	require.False(t, resp.Detected)
+	require.Equal(t, map[string]string{
+		"unique.host_volume.data.bytestotal": "",
+		"unique.host_volume.data.bytesfree":  "",
+	}, resp.Attributes)
+}
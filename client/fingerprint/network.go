@@ -3,6 +3,7 @@ package fingerprint
 import (
 	"fmt"
 	"net"
+	"path"
 	"strings"
 
 	log "github.com/hashicorp/go-hclog"
@@ -137,6 +138,11 @@ func (f *NetworkFingerprint) Fingerprint(req *FingerprintRequest, resp *Fingerpr
 
 func (f *NetworkFingerprint) createNodeNetworkResources(ifaces []net.Interface, disallowLinkLocal bool, conf *config.Config) ([]*structs.NodeNetworkResource, error) {
 	nets := make([]*structs.NodeNetworkResource, 0)
+
+	// picked are the host networks registered with the first matching
+	// address of each family that already have one, by name and family
+	picked := make(map[string]bool)
+
 	for _, iface := range ifaces {
 		speed := f.linkSpeed(iface.Name)
 		if speed == 0 {
@@ -171,6 +177,7 @@ func (f *NetworkFingerprint) createNodeNetworkResources(ifaces []net.Interface,
 			} else {
 				family = structs.NodeNetworkAF_IPv6
 			}
+			linkLocal := ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
 			for _, alias := range deriveAddressAliases(iface, ip, conf) {
 				newAddr := structs.NodeNetworkAddress{
 					Address: ip.String(),
@@ -180,10 +187,21 @@ func (f *NetworkFingerprint) createNodeNetworkResources(ifaces []net.Interface,
 
 				if hostNetwork, ok := conf.HostNetworks[alias]; ok {
 					newAddr.ReservedPorts = hostNetwork.ReservedPorts
+
+					// Link-local addresses are never picked, so that
+					// they don't shadow the routable address of the
+					// same family on another interface
+					if picksFirstAddress(hostNetwork) {
+						key := alias + "/" + string(family)
+						if linkLocal || picked[key] {
+							continue
+						}
+						picked[key] = true
+					}
 				}
 
 				if newAddr.Alias != "" {
-					if ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+					if linkLocal {
 						linkLocalAddrs = append(linkLocalAddrs, newAddr)
 					} else {
 						networkAddrs = append(networkAddrs, newAddr)
@@ -212,8 +230,8 @@ func (f *NetworkFingerprint) createNodeNetworkResources(ifaces []net.Interface,
 func deriveAddressAliases(iface net.Interface, addr net.IP, config *config.Config) (aliases []string) {
 	for name, conf := range config.HostNetworks {
 		var cidrMatch, ifaceMatch bool
-		if conf.CIDR != "" {
-			for _, cidr := range strings.Split(conf.CIDR, ",") {
+		if cidrs := conf.AllCIDRs(); len(cidrs) != 0 {
+			for _, cidr := range cidrs {
 				_, ipnet, err := net.ParseCIDR(cidr)
 				if err != nil {
 					continue
@@ -233,7 +251,7 @@ func deriveAddressAliases(iface net.Interface, addr net.IP, config *config.Confi
 				continue
 			}
 
-			if ifaceName == iface.Name {
+			if interfaceMatches(ifaceName, iface.Name) {
 				ifaceMatch = true
 			}
 		} else {
@@ -262,6 +280,31 @@ func deriveAddressAliases(iface net.Interface, addr net.IP, config *config.Confi
 	return
 }
 
+// interfaceMatches returns whether the name of an interface matches the
+// interface of a host network, which may be a glob pattern such as "eth*".
+func interfaceMatches(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// picksFirstAddress returns whether the host network is registered with only
+// the first matching address of each family, because it sets cidrs or its
+// interface is a glob pattern. Host networks that only set cidr and an
+// interface name are registered with every matching address.
+func picksFirstAddress(conf *structs.ClientHostNetworkConfig) bool {
+	if len(conf.CIDRs) != 0 {
+		return true
+	}
+	if conf.Interface == "" {
+		return false
+	}
+	ifaceName, err := template.Parse(conf.Interface)
+	return err == nil && strings.ContainsAny(ifaceName, "*?[")
+}
+
 // createNetworkResources creates network resources for every IP
 func (f *NetworkFingerprint) createNetworkResources(throughput int, intf *net.Interface, disallowLinkLocal bool) ([]*structs.NetworkResource, error) {
 	// Find the interface with the name
@@ -576,3 +576,137 @@ func TestNetworkFingerPrint_HostNetworkReservedPorts(t *testing.T) {
 		})
 	}
 }
+
+// A fake network detector which simulates dual-stack interfaces, including a
+// bonded one
+type NetworkInterfaceDetectorDualStack struct {
+}
+
+func (n *NetworkInterfaceDetectorDualStack) Interfaces() ([]net.Interface, error) {
+	return []net.Interface{
+		{Index: 1, Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+		{Index: 2, Name: "eth0", Flags: net.FlagUp},
+		{Index: 3, Name: "eth1", Flags: net.FlagUp},
+		{Index: 4, Name: "bond0", Flags: net.FlagUp},
+	}, nil
+}
+
+func (n *NetworkInterfaceDetectorDualStack) InterfaceByName(name string) (*net.Interface, error) {
+	ifaces, _ := n.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Name == name {
+			return &iface, nil
+		}
+	}
+	return nil, fmt.Errorf("No device with name %v found", name)
+}
+
+func (n *NetworkInterfaceDetectorDualStack) Addrs(intf *net.Interface) ([]net.Addr, error) {
+	// Addresses keep their host part, as they do on real interfaces
+	addrs := func(cidrs ...string) []net.Addr {
+		var addrs []net.Addr
+		for _, cidr := range cidrs {
+			ip, ipnet, _ := net.ParseCIDR(cidr)
+			addrs = append(addrs, &net.IPNet{IP: ip, Mask: ipnet.Mask})
+		}
+		return addrs
+	}
+
+	switch intf.Name {
+	case "lo":
+		return addrs("127.0.0.1/8"), nil
+	case "eth0":
+		return addrs("10.0.0.5/24", "10.0.0.6/24", "2001:db8:1::5/64", "fe80::1/64"), nil
+	case "eth1":
+		return addrs("10.1.0.5/24", "fe80::2/64", "2001:db8:2::5/64"), nil
+	case "bond0":
+		return addrs("192.168.1.5/24", "fe80::3/64"), nil
+	}
+	return nil, fmt.Errorf("Can't find addresses for device: %v", intf.Name)
+}
+
+func TestNetworkFingerPrint_HostNetworkMatching(t *testing.T) {
+	testCases := []struct {
+		name        string
+		hostNetwork *structs.ClientHostNetworkConfig
+		expected    []string
+	}{
+		{
+			name:        "single cidr",
+			hostNetwork: &structs.ClientHostNetworkConfig{CIDR: "10.0.0.0/8"},
+			expected:    []string{"eth0 10.0.0.5", "eth0 10.0.0.6", "eth1 10.1.0.5"},
+		},
+		{
+			name:        "interface name",
+			hostNetwork: &structs.ClientHostNetworkConfig{Interface: "eth1"},
+			expected:    []string{"eth1 10.1.0.5", "eth1 2001:db8:2::5"},
+		},
+		{
+			name:        "interface glob",
+			hostNetwork: &structs.ClientHostNetworkConfig{Interface: "eth*"},
+			expected:    []string{"eth0 10.0.0.5", "eth0 2001:db8:1::5"},
+		},
+		{
+			name:        "interface glob with cidr",
+			hostNetwork: &structs.ClientHostNetworkConfig{Interface: "eth*", CIDR: "10.1.0.0/16"},
+			expected:    []string{"eth1 10.1.0.5"},
+		},
+		{
+			name:        "bonded interface",
+			hostNetwork: &structs.ClientHostNetworkConfig{Interface: "bond?"},
+			expected:    []string{"bond0 192.168.1.5"},
+		},
+		{
+			name: "multiple cidrs dual-stack",
+			hostNetwork: &structs.ClientHostNetworkConfig{
+				CIDRs: []string{"192.168.0.0/16", "10.1.0.0/16", "2001:db8:2::/48"},
+			},
+			expected: []string{"eth1 10.1.0.5", "eth1 2001:db8:2::5"},
+		},
+		{
+			name: "cidr and cidrs",
+			hostNetwork: &structs.ClientHostNetworkConfig{
+				CIDR:  "192.168.0.0/16",
+				CIDRs: []string{"2001:db8:1::/48"},
+			},
+			expected: []string{"bond0 192.168.1.5", "eth0 2001:db8:1::5"},
+		},
+		{
+			name: "no match",
+			hostNetwork: &structs.ClientHostNetworkConfig{
+				Interface: "wlan*",
+				CIDRs:     []string{"10.0.0.0/8"},
+			},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := &NetworkFingerprint{
+				logger:            testlog.HCLogger(t),
+				interfaceDetector: &NetworkInterfaceDetectorDualStack{},
+			}
+			tc.hostNetwork.Name = "public"
+			cfg := &config.Config{
+				NetworkInterface: "lo",
+				HostNetworks:     map[string]*structs.ClientHostNetworkConfig{"public": tc.hostNetwork},
+			}
+
+			request := &FingerprintRequest{Config: cfg, Node: &structs.Node{Attributes: map[string]string{}}}
+			var response FingerprintResponse
+			require.NoError(t, f.Fingerprint(request, &response))
+
+			got := []string{}
+			for _, network := range response.NodeResources.NodeNetworks {
+				for _, address := range network.Addresses {
+					if address.Alias == "public" {
+						got = append(got, network.Device+" "+address.Address)
+					}
+				}
+			}
+			sort.Strings(got)
+			require.Equal(t, tc.expected, got)
+		})
+	}
+}
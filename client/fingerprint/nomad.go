@@ -1,6 +1,8 @@
 package fingerprint
 
 import (
+	"strconv"
+
 	log "github.com/hashicorp/go-hclog"
 )
 
@@ -20,6 +22,15 @@ func (f *NomadFingerprint) Fingerprint(req *FingerprintRequest, resp *Fingerprin
 	resp.AddAttribute("nomad.advertise.address", req.Node.HTTPAddr)
 	resp.AddAttribute("nomad.version", req.Config.Version.VersionNumber())
 	resp.AddAttribute("nomad.revision", req.Config.Version.Revision)
+	if req.Config.ArtifactRequireChecksum {
+		resp.AddAttribute("artifact.checksum_required", "true")
+	}
+	perAlloc, nodeMounts := req.Config.CSIVolumeLimits()
+	resp.AddAttribute("csi.max_volumes_per_alloc", strconv.Itoa(perAlloc))
+	resp.AddAttribute("csi.max_node_mounts", strconv.Itoa(nodeMounts))
+	if req.Config.MaxTaskTmpfsMB > 0 {
+		resp.AddAttribute("tmpfs.max_task_mb", strconv.Itoa(req.Config.MaxTaskTmpfsMB))
+	}
 	resp.Detected = true
 	return nil
 }
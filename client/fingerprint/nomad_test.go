@@ -52,4 +52,57 @@ func TestNomadFingerprint(t *testing.T) {
 	if response.Attributes["nomad.advertise.address"] != h {
 		t.Fatalf("incorrect advertise address")
 	}
+
+	if _, ok := response.Attributes["artifact.checksum_required"]; ok {
+		t.Fatalf("checksum requirement should not be advertised")
+	}
+
+	if response.Attributes["csi.max_volumes_per_alloc"] != "16" {
+		t.Fatalf("expected default CSI volumes per alloc limit, got %q",
+			response.Attributes["csi.max_volumes_per_alloc"])
+	}
+
+	if response.Attributes["csi.max_node_mounts"] != "256" {
+		t.Fatalf("expected default CSI node mounts limit, got %q",
+			response.Attributes["csi.max_node_mounts"])
+	}
+
+	if _, ok := response.Attributes["tmpfs.max_task_mb"]; ok {
+		t.Fatalf("disabled task tmpfs should not be advertised")
+	}
+
+	c.ArtifactRequireChecksum = true
+	response = FingerprintResponse{}
+	if err := f.Fingerprint(request, &response); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if response.Attributes["artifact.checksum_required"] != "true" {
+		t.Fatalf("expected checksum requirement to be advertised")
+	}
+
+	c.CSIMaxVolumesPerAlloc = 4
+	c.CSIMaxNodeMounts = 32
+	response = FingerprintResponse{}
+	if err := f.Fingerprint(request, &response); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if response.Attributes["csi.max_volumes_per_alloc"] != "4" {
+		t.Fatalf("expected configured CSI volumes per alloc limit")
+	}
+
+	if response.Attributes["csi.max_node_mounts"] != "32" {
+		t.Fatalf("expected configured CSI node mounts limit")
+	}
+
+	c.MaxTaskTmpfsMB = 512
+	response = FingerprintResponse{}
+	if err := f.Fingerprint(request, &response); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if response.Attributes["tmpfs.max_task_mb"] != "512" {
+		t.Fatalf("expected configured task tmpfs limit")
+	}
 }
@@ -24,6 +24,9 @@ type FingerprintManager struct {
 	// associated node
 	updateNodeAttributes func(*fingerprint.FingerprintResponse) *structs.Node
 
+	// errorHook, if set, is called with the errors returned by fingerprinters
+	errorHook func(name string, err error)
+
 	reloadableFps map[string]fingerprint.ReloadableFingerprint
 
 	logger log.Logger
@@ -192,6 +195,9 @@ func (fm *FingerprintManager) fingerprint(name string, f fingerprint.Fingerprint
 	fm.nodeLock.Unlock()
 
 	if err != nil {
+		if fm.errorHook != nil {
+			fm.errorHook(name, err)
+		}
 		return false, err
 	}
 
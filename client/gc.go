@@ -16,16 +16,70 @@ const (
 	MB = 1024 * 1024
 )
 
+// The gc_reason logged for each collected allocation, identifying the
+// trigger that caused it to be collected.
+const (
+	gcReasonDiskThreshold      = "disk_threshold"
+	gcReasonInodeThreshold     = "inode_threshold"
+	gcReasonMaxAllocs          = "max_allocs"
+	gcReasonNamespaceMaxAllocs = "namespace_max_allocs"
+	gcReasonMaxAllocAge        = "max_alloc_age"
+	gcReasonNewAllocs          = "new_allocs"
+	gcReasonNewAllocsDisk      = "new_allocs_disk"
+	gcReasonForced             = "forced"
+	gcReasonForcedAll          = "forced_all"
+)
+
+// gcReason is why an allocation was garbage collected
+type gcReason struct {
+	// code is the trigger that caused the collection, one of the
+	// gcReason constants
+	code string
+
+	// desc describes the trigger in detail
+	desc string
+
+	// diskStats is the usage of the alloc dir when the collection was
+	// decided, if the decision depended on it
+	diskStats *stats.DiskStats
+}
+
+// logArgs returns the key/value pairs the reason is logged with.
+func (r *gcReason) logArgs() []interface{} {
+	args := []interface{}{"gc_reason", r.code, "reason", r.desc}
+	if r.diskStats != nil {
+		args = append(args,
+			"disk_used_percent", fmt.Sprintf("%.1f", r.diskStats.UsedPercent),
+			"inodes_used_percent", fmt.Sprintf("%.1f", r.diskStats.InodesUsedPercent))
+	}
+	return args
+}
+
 // GCConfig allows changing the behaviour of the garbage collector
 type GCConfig struct {
 	// MaxAllocs is the maximum number of allocations to track before a GC
 	// is triggered.
-	MaxAllocs           int
+	MaxAllocs int
+
+	// MaxAllocsPerNamespace is the maximum number of terminal allocations of
+	// each listed namespace to track. The allocations of a namespace over
+	// its limit are collected before any others.
+	MaxAllocsPerNamespace map[string]int
+
+	// MaxAllocAge is the age after which terminal allocations are collected
+	// regardless of usage. Zero disables collection by age.
+	MaxAllocAge time.Duration
+
 	DiskUsageThreshold  float64
 	InodeUsageThreshold float64
 	Interval            time.Duration
 	ReservedDiskMB      int
 	ParallelDestroys    int
+
+	// StateDirReserveMB is the disk space kept free for the client's state
+	// when it shares a filesystem with the alloc dir. DiskUsageThreshold is
+	// lowered by the reserve's share of the disk.
+	StateDirReserveMB int
 }
 
 // AllocCounter is used by AllocGarbageCollector to discover how many un-GC'd
@@ -36,7 +90,8 @@ type AllocCounter interface {
 
 // AllocGarbageCollector garbage collects terminated allocations on a node
 type AllocGarbageCollector struct {
-	config *GCConfig
+	config     *GCConfig
+	configLock sync.RWMutex
 
 	// allocRunners marked for GC
 	allocRunners *IndexedGCAllocPQ
@@ -57,6 +112,9 @@ type AllocGarbageCollector struct {
 	// triggerCh is ticked by the Trigger method to cause a GC
 	triggerCh chan struct{}
 
+	// reloadCh is ticked by the SetConfig method to reset the GC interval
+	reloadCh chan struct{}
+
 	logger hclog.Logger
 }
 
@@ -80,6 +138,7 @@ func NewAllocGarbageCollector(logger hclog.Logger, statsCollector stats.NodeStat
 		destroyCh:      make(chan struct{}, config.ParallelDestroys),
 		shutdownCh:     make(chan struct{}),
 		triggerCh:      make(chan struct{}, 1),
+		reloadCh:       make(chan struct{}, 1),
 	}
 
 	return gc
@@ -87,22 +146,51 @@ func NewAllocGarbageCollector(logger hclog.Logger, statsCollector stats.NodeStat
 
 // Run the periodic garbage collector.
 func (a *AllocGarbageCollector) Run() {
-	ticker := time.NewTicker(a.config.Interval)
+	ticker := time.NewTicker(a.getConfig().Interval)
 	for {
 		select {
 		case <-a.triggerCh:
 		case <-ticker.C:
+		case <-a.reloadCh:
+			ticker.Reset(a.getConfig().Interval)
+			continue
 		case <-a.shutdownCh:
 			ticker.Stop()
 			return
 		}
 
+		a.collectAgedAllocs()
+
 		if err := a.keepUsageBelowThreshold(); err != nil {
 			a.logger.Error("error garbage collecting allocations", "error", err)
 		}
 	}
 }
 
+// getConfig returns the garbage collector's current config.
+func (a *AllocGarbageCollector) getConfig() *GCConfig {
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	return a.config
+}
+
+// SetConfig replaces the garbage collector's config, resetting the interval
+// of the periodic garbage collection. The number of parallel destroys can't
+// be changed.
+func (a *AllocGarbageCollector) SetConfig(config *GCConfig) {
+	a.configLock.Lock()
+	c := *config
+	c.ParallelDestroys = a.config.ParallelDestroys
+	a.config = &c
+	a.configLock.Unlock()
+
+	select {
+	case a.reloadCh <- struct{}{}:
+	default:
+		// already reloading
+	}
+}
+
 // Trigger forces the garbage collector to run.
 func (a *AllocGarbageCollector) Trigger() {
 	select {
@@ -129,35 +217,51 @@ func (a *AllocGarbageCollector) keepUsageBelowThreshold() error {
 
 		// See if we are below thresholds for used disk space and inode usage
 		diskStats := a.statsCollector.Stats().AllocDirStats
-		reason := ""
+		var reason *gcReason
 		logf := a.logger.Warn
 
 		liveAllocs := a.allocCounter.NumAllocs()
 
+		namespace, namespaceReason := a.namespaceOverLimit()
+
+		diskThreshold := a.diskUsageThreshold(diskStats)
+
 		switch {
-		case diskStats.UsedPercent > a.config.DiskUsageThreshold:
-			reason = fmt.Sprintf("disk usage of %.0f is over gc threshold of %.0f",
-				diskStats.UsedPercent, a.config.DiskUsageThreshold)
-		case diskStats.InodesUsedPercent > a.config.InodeUsageThreshold:
-			reason = fmt.Sprintf("inode usage of %.0f is over gc threshold of %.0f",
-				diskStats.InodesUsedPercent, a.config.InodeUsageThreshold)
-		case liveAllocs > a.config.MaxAllocs:
+		case diskStats.UsedPercent > diskThreshold:
+			reason = &gcReason{
+				code: gcReasonDiskThreshold,
+				desc: fmt.Sprintf("disk usage of %.0f is over gc threshold of %.0f",
+					diskStats.UsedPercent, diskThreshold),
+			}
+		case diskStats.InodesUsedPercent > a.getConfig().InodeUsageThreshold:
+			reason = &gcReason{
+				code: gcReasonInodeThreshold,
+				desc: fmt.Sprintf("inode usage of %.0f is over gc threshold of %.0f",
+					diskStats.InodesUsedPercent, a.getConfig().InodeUsageThreshold),
+			}
+		case liveAllocs > a.getConfig().MaxAllocs:
 			// if we're unable to gc, don't WARN until at least 2x over limit
-			if liveAllocs < (a.config.MaxAllocs * 2) {
+			if liveAllocs < (a.getConfig().MaxAllocs * 2) {
 				logf = a.logger.Info
 			}
-			reason = fmt.Sprintf("number of allocations (%d) is over the limit (%d)", liveAllocs, a.config.MaxAllocs)
+			reason = &gcReason{
+				code: gcReasonMaxAllocs,
+				desc: fmt.Sprintf("number of allocations (%d) is over the limit (%d)", liveAllocs, a.getConfig().MaxAllocs),
+			}
+		case namespace != "":
+			reason = &gcReason{code: gcReasonNamespaceMaxAllocs, desc: namespaceReason}
 		}
 
-		if reason == "" {
+		if reason == nil {
 			// No reason to gc, exit
 			break
 		}
+		reason.diskStats = diskStats
 
 		// Collect an allocation
-		gcAlloc := a.allocRunners.Pop()
+		gcAlloc := a.popCandidate()
 		if gcAlloc == nil {
-			logf("garbage collection skipped because no terminal allocations", "reason", reason)
+			logf("garbage collection skipped because no terminal allocations", reason.logArgs()...)
 			break
 		}
 
@@ -167,11 +271,56 @@ func (a *AllocGarbageCollector) keepUsageBelowThreshold() error {
 	return nil
 }
 
+// diskUsageThreshold returns the disk usage threshold lowered by the share
+// of the disk reserved for the client's state.
+func (a *AllocGarbageCollector) diskUsageThreshold(diskStats *stats.DiskStats) float64 {
+	threshold := a.getConfig().DiskUsageThreshold
+	if a.getConfig().StateDirReserveMB <= 0 || diskStats == nil || diskStats.Size == 0 {
+		return threshold
+	}
+
+	threshold -= float64(uint64(a.getConfig().StateDirReserveMB)*MB) / float64(diskStats.Size) * 100
+	if threshold < 0 {
+		return 0
+	}
+	return threshold
+}
+
+// collectAgedAllocs garbage collects the allocations that have been terminal
+// for longer than the max alloc age.
+func (a *AllocGarbageCollector) collectAgedAllocs() {
+	if a.getConfig().MaxAllocAge <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-a.getConfig().MaxAllocAge)
+	for {
+		select {
+		case <-a.shutdownCh:
+			return
+		default:
+		}
+
+		gcAlloc := a.allocRunners.PopOlderThan(cutoff)
+		if gcAlloc == nil {
+			return
+		}
+
+		reason := &gcReason{
+			code: gcReasonMaxAllocAge,
+			desc: fmt.Sprintf("terminal for %s, over the max alloc age (%s)",
+				time.Since(gcAlloc.timeStamp).Round(time.Second), a.getConfig().MaxAllocAge),
+		}
+		a.destroyAllocRunner(gcAlloc.allocID, gcAlloc.allocRunner, reason)
+	}
+}
+
 // destroyAllocRunner is used to destroy an allocation runner. It will acquire a
 // lock to restrict parallelism and then destroy the alloc runner, returning
 // once the allocation has been destroyed.
-func (a *AllocGarbageCollector) destroyAllocRunner(allocID string, ar AllocRunner, reason string) {
-	a.logger.Info("garbage collecting allocation", "alloc_id", allocID, "reason", reason)
+func (a *AllocGarbageCollector) destroyAllocRunner(allocID string, ar AllocRunner, reason *gcReason) {
+	a.logger.Info("garbage collecting allocation",
+		append([]interface{}{"alloc_id", allocID}, reason.logArgs()...)...)
 
 	// Acquire the destroy lock
 	select {
@@ -206,7 +355,8 @@ func (a *AllocGarbageCollector) Collect(allocID string) bool {
 		return false
 	}
 
-	a.destroyAllocRunner(allocID, gcAlloc.allocRunner, "forced collection")
+	a.destroyAllocRunner(allocID, gcAlloc.allocRunner,
+		&gcReason{code: gcReasonForced, desc: "forced collection"})
 	return true
 }
 
@@ -224,7 +374,8 @@ func (a *AllocGarbageCollector) CollectAll() {
 			return
 		}
 
-		go a.destroyAllocRunner(gcAlloc.allocID, gcAlloc.allocRunner, "forced full node collection")
+		go a.destroyAllocRunner(gcAlloc.allocID, gcAlloc.allocRunner,
+			&gcReason{code: gcReasonForcedAll, desc: "forced full node collection"})
 	}
 }
 
@@ -237,7 +388,7 @@ func (a *AllocGarbageCollector) MakeRoomFor(allocations []*structs.Allocation) e
 	}
 
 	// GC allocs until below the max limit + the new allocations
-	max := a.config.MaxAllocs - len(allocations)
+	max := a.getConfig().MaxAllocs - len(allocations)
 	for a.allocCounter.NumAllocs() > max {
 		select {
 		case <-a.shutdownCh:
@@ -245,7 +396,7 @@ func (a *AllocGarbageCollector) MakeRoomFor(allocations []*structs.Allocation) e
 		default:
 		}
 
-		gcAlloc := a.allocRunners.Pop()
+		gcAlloc := a.popCandidate()
 		if gcAlloc == nil {
 			// It's fine if we can't lower below the limit here as
 			// we'll keep trying to drop below the limit with each
@@ -254,7 +405,10 @@ func (a *AllocGarbageCollector) MakeRoomFor(allocations []*structs.Allocation) e
 		}
 
 		// Destroy the alloc runner and wait until it exits
-		a.destroyAllocRunner(gcAlloc.allocID, gcAlloc.allocRunner, fmt.Sprintf("new allocations and over max (%d)", a.config.MaxAllocs))
+		a.destroyAllocRunner(gcAlloc.allocID, gcAlloc.allocRunner, &gcReason{
+			code: gcReasonNewAllocs,
+			desc: fmt.Sprintf("new allocations and over max (%d)", a.getConfig().MaxAllocs),
+		})
 	}
 
 	totalResource := &structs.AllocatedSharedResources{}
@@ -271,10 +425,11 @@ func (a *AllocGarbageCollector) MakeRoomFor(allocations []*structs.Allocation) e
 	// we don't need to garbage collect terminated allocations
 	if hostStats := a.statsCollector.Stats(); hostStats != nil {
 		var availableForAllocations uint64
-		if hostStats.AllocDirStats.Available < uint64(a.config.ReservedDiskMB*MB) {
+		reservedDisk := uint64((a.getConfig().ReservedDiskMB + a.getConfig().StateDirReserveMB) * MB)
+		if hostStats.AllocDirStats.Available < reservedDisk {
 			availableForAllocations = 0
 		} else {
-			availableForAllocations = hostStats.AllocDirStats.Available - uint64(a.config.ReservedDiskMB*MB)
+			availableForAllocations = hostStats.AllocDirStats.Available - reservedDisk
 		}
 		if uint64(totalResource.DiskMB*MB) < availableForAllocations {
 			return nil
@@ -310,7 +465,7 @@ func (a *AllocGarbageCollector) MakeRoomFor(allocations []*structs.Allocation) e
 			}
 		}
 
-		gcAlloc := a.allocRunners.Pop()
+		gcAlloc := a.popCandidate()
 		if gcAlloc == nil {
 			break
 		}
@@ -327,7 +482,11 @@ func (a *AllocGarbageCollector) MakeRoomFor(allocations []*structs.Allocation) e
 		}
 
 		// Destroy the alloc runner and wait until it exits
-		a.destroyAllocRunner(gcAlloc.allocID, ar, fmt.Sprintf("freeing %d MB for new allocations", allocDiskMB))
+		a.destroyAllocRunner(gcAlloc.allocID, ar, &gcReason{
+			code:      gcReasonNewAllocsDisk,
+			desc:      fmt.Sprintf("freeing %d MB for new allocations", allocDiskMB),
+			diskStats: allocDirStats,
+		})
 
 		diskCleared += allocDiskMB
 	}
@@ -338,7 +497,45 @@ func (a *AllocGarbageCollector) MakeRoomFor(allocations []*structs.Allocation) e
 func (a *AllocGarbageCollector) MarkForCollection(allocID string, ar AllocRunner) {
 	if a.allocRunners.Push(allocID, ar) {
 		a.logger.Info("marking allocation for GC", "alloc_id", allocID)
+
+		// Collect the namespace's allocations without waiting for the next
+		// periodic GC
+		if namespace, _ := a.namespaceOverLimit(); namespace != "" {
+			a.Trigger()
+		}
+	}
+}
+
+// namespaceOverLimit returns the namespace furthest over its limit of
+// terminal allocations along with the reason to collect it, or an empty
+// namespace if no namespace is over its limit.
+func (a *AllocGarbageCollector) namespaceOverLimit() (string, string) {
+	namespace, reason, excess := "", "", 0
+	for ns, max := range a.getConfig().MaxAllocsPerNamespace {
+		n := a.allocRunners.NamespaceLength(ns)
+		if n <= max {
+			continue
+		}
+
+		// Break ties by name so that collection is deterministic
+		if n-max > excess || (n-max == excess && ns < namespace) {
+			namespace, excess = ns, n-max
+			reason = fmt.Sprintf("number of terminal allocations in namespace %q (%d) is over the limit (%d)", ns, n, max)
+		}
+	}
+	return namespace, reason
+}
+
+// popCandidate returns the next allocation to collect. The oldest allocation
+// of the namespace furthest over its limit is collected first, otherwise the
+// oldest allocation is.
+func (a *AllocGarbageCollector) popCandidate() *GCAlloc {
+	if namespace, _ := a.namespaceOverLimit(); namespace != "" {
+		if gcAlloc := a.allocRunners.PopNamespace(namespace); gcAlloc != nil {
+			return gcAlloc
+		}
 	}
+	return a.allocRunners.Pop()
 }
 
 // GCAlloc wraps an allocation runner and an index enabling it to be used within
@@ -346,6 +543,7 @@ func (a *AllocGarbageCollector) MarkForCollection(allocID string, ar AllocRunner
 type GCAlloc struct {
 	timeStamp   time.Time
 	allocID     string
+	namespace   string
 	allocRunner AllocRunner
 	index       int
 }
@@ -388,13 +586,17 @@ type IndexedGCAllocPQ struct {
 	index map[string]*GCAlloc
 	heap  GCAllocPQImpl
 
+	// namespaces is the number of allocations queued in each namespace
+	namespaces map[string]int
+
 	pqLock sync.Mutex
 }
 
 func NewIndexedGCAllocPQ() *IndexedGCAllocPQ {
 	return &IndexedGCAllocPQ{
-		index: make(map[string]*GCAlloc),
-		heap:  make(GCAllocPQImpl, 0),
+		index:      make(map[string]*GCAlloc),
+		heap:       make(GCAllocPQImpl, 0),
+		namespaces: make(map[string]int),
 	}
 }
 
@@ -409,15 +611,43 @@ func (i *IndexedGCAllocPQ) Push(allocID string, ar AllocRunner) bool {
 		return false
 	}
 	gcAlloc := &GCAlloc{
-		timeStamp:   time.Now(),
+		timeStamp:   terminalTime(ar),
 		allocID:     allocID,
+		namespace:   ar.Alloc().Namespace,
 		allocRunner: ar,
 	}
 	i.index[allocID] = gcAlloc
+	i.namespaces[gcAlloc.namespace]++
 	heap.Push(&i.heap, gcAlloc)
 	return true
 }
 
+// terminalTime returns when the alloc runner's alloc became terminal: the
+// time its last task finished, or else the time the alloc was last modified.
+// Allocs restored when the client restarts keep the age they had rather than
+// starting over.
+func terminalTime(ar AllocRunner) time.Time {
+	var finished time.Time
+	if state := ar.AllocState(); state != nil {
+		for _, ts := range state.TaskStates {
+			if ts != nil && ts.FinishedAt.After(finished) {
+				finished = ts.FinishedAt
+			}
+		}
+	}
+	if finished.IsZero() {
+		if modified := ar.Alloc().ModifyTime; modified != 0 {
+			finished = time.Unix(0, modified)
+		}
+	}
+
+	now := time.Now()
+	if finished.IsZero() || finished.After(now) {
+		return now
+	}
+	return finished
+}
+
 func (i *IndexedGCAllocPQ) Pop() *GCAlloc {
 	i.pqLock.Lock()
 	defer i.pqLock.Unlock()
@@ -428,9 +658,51 @@ func (i *IndexedGCAllocPQ) Pop() *GCAlloc {
 
 	gcAlloc := heap.Pop(&i.heap).(*GCAlloc)
 	delete(i.index, gcAlloc.allocRunner.Alloc().ID)
+	i.removeNamespaceLocked(gcAlloc.namespace)
+	return gcAlloc
+}
+
+// PopOlderThan removes and returns the oldest alloc if it became terminal
+// before cutoff. Returns nil otherwise.
+func (i *IndexedGCAllocPQ) PopOlderThan(cutoff time.Time) *GCAlloc {
+	i.pqLock.Lock()
+	defer i.pqLock.Unlock()
+
+	if len(i.heap) == 0 || !i.heap[0].timeStamp.Before(cutoff) {
+		return nil
+	}
+
+	gcAlloc := heap.Pop(&i.heap).(*GCAlloc)
+	delete(i.index, gcAlloc.allocID)
+	i.removeNamespaceLocked(gcAlloc.namespace)
 	return gcAlloc
 }
 
+// PopNamespace removes and returns the oldest alloc of the namespace. Returns
+// nil if the namespace has no allocs.
+func (i *IndexedGCAllocPQ) PopNamespace(namespace string) *GCAlloc {
+	i.pqLock.Lock()
+	defer i.pqLock.Unlock()
+
+	var oldest *GCAlloc
+	for _, gcAlloc := range i.heap {
+		if gcAlloc.namespace != namespace {
+			continue
+		}
+		if oldest == nil || gcAlloc.timeStamp.Before(oldest.timeStamp) {
+			oldest = gcAlloc
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+
+	heap.Remove(&i.heap, oldest.index)
+	delete(i.index, oldest.allocID)
+	i.removeNamespaceLocked(oldest.namespace)
+	return oldest
+}
+
 // Remove alloc from GC. Returns nil if alloc doesn't exist.
 func (i *IndexedGCAllocPQ) Remove(allocID string) *GCAlloc {
 	i.pqLock.Lock()
@@ -439,6 +711,7 @@ func (i *IndexedGCAllocPQ) Remove(allocID string) *GCAlloc {
 	if gcAlloc, ok := i.index[allocID]; ok {
 		heap.Remove(&i.heap, gcAlloc.index)
 		delete(i.index, allocID)
+		i.removeNamespaceLocked(gcAlloc.namespace)
 		return gcAlloc
 	}
 
@@ -451,3 +724,21 @@ func (i *IndexedGCAllocPQ) Length() int {
 
 	return len(i.heap)
 }
+
+// NamespaceLength returns the number of allocs of the namespace in the PQ.
+func (i *IndexedGCAllocPQ) NamespaceLength(namespace string) int {
+	i.pqLock.Lock()
+	defer i.pqLock.Unlock()
+
+	return i.namespaces[namespace]
+}
+
+// removeNamespaceLocked decrements the number of allocs of the namespace.
+// i.pqLock must be held.
+func (i *IndexedGCAllocPQ) removeNamespaceLocked(namespace string) {
+	if i.namespaces[namespace] <= 1 {
+		delete(i.namespaces, namespace)
+		return
+	}
+	i.namespaces[namespace]--
+}
@@ -2,9 +2,12 @@ package client
 
 import (
 	"fmt"
+	"io/ioutil"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad/client/allocrunner"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/stats"
@@ -80,6 +83,41 @@ func TestIndexedGCAllocPQ(t *testing.T) {
 	}
 }
 
+func TestIndexedGCAllocPQ_Namespace(t *testing.T) {
+	t.Parallel()
+	pq := NewIndexedGCAllocPQ()
+
+	newRunner := func(namespace string) AllocRunner {
+		alloc := mock.Alloc()
+		alloc.Namespace = namespace
+		ar, cleanup := allocrunner.TestAllocRunnerFromAlloc(t, alloc)
+		t.Cleanup(cleanup)
+		pq.Push(alloc.ID, ar)
+		return ar
+	}
+	ar1 := newRunner("default")
+	ar2 := newRunner("batch")
+	ar3 := newRunner("batch")
+	newRunner("batch")
+
+	require.Equal(t, 1, pq.NamespaceLength("default"))
+	require.Equal(t, 3, pq.NamespaceLength("batch"))
+	require.Zero(t, pq.NamespaceLength("other"))
+
+	// The oldest alloc of the namespace is popped, even if older allocs of
+	// other namespaces are queued
+	require.Equal(t, ar2, pq.PopNamespace("batch").allocRunner)
+	require.Equal(t, 2, pq.NamespaceLength("batch"))
+	require.Nil(t, pq.PopNamespace("other"))
+
+	// Removing and popping allocs updates the namespace counts
+	require.NotNil(t, pq.Remove(ar3.Alloc().ID))
+	require.Equal(t, 1, pq.NamespaceLength("batch"))
+	require.Equal(t, ar1, pq.Pop().allocRunner)
+	require.Zero(t, pq.NamespaceLength("default"))
+	require.Equal(t, 1, pq.Length())
+}
+
 // MockAllocCounter implements AllocCounter interface.
 type MockAllocCounter struct {
 	allocs int
@@ -532,6 +570,226 @@ func TestAllocGarbageCollector_UsageBelowThreshold(t *testing.T) {
 	}
 }
 
+func TestAllocGarbageCollector_MaxAllocsPerNamespace(t *testing.T) {
+	t.Parallel()
+	logger := testlog.HCLogger(t)
+	statsCollector := &MockStatsCollector{}
+	conf := gcConfig()
+	conf.MaxAllocsPerNamespace = map[string]int{"batch": 1, "dev": 1}
+	gc := NewAllocGarbageCollector(logger, statsCollector, &MockAllocCounter{}, conf)
+
+	newRunner := func(namespace string) AllocRunner {
+		alloc := mock.Alloc()
+		alloc.Namespace = namespace
+		ar, cleanup := allocrunner.TestAllocRunnerFromAlloc(t, alloc)
+		t.Cleanup(cleanup)
+		go ar.Run()
+		gc.MarkForCollection(alloc.ID, ar)
+		return ar
+	}
+	older := newRunner("default")
+	dev1 := newRunner("dev")
+	batch1 := newRunner("batch")
+	batch2 := newRunner("batch")
+	dev2 := newRunner("dev")
+	batch3 := newRunner("batch")
+	exitAllocRunner(older, dev1, batch1, batch2, dev2, batch3)
+
+	// Usage is below every node wide threshold
+	statsCollector.availableValues = []uint64{1000}
+	statsCollector.usedPercents = []float64{20}
+	statsCollector.inodePercents = []float64{10}
+
+	require.NoError(t, gc.keepUsageBelowThreshold())
+
+	// Only the oldest allocs of the namespaces over their limit were
+	// collected
+	for _, ar := range []AllocRunner{dev1, batch1, batch2} {
+		select {
+		case <-ar.DestroyCh():
+		default:
+			t.Fatalf("expected alloc %s to be collected", ar.Alloc().ID)
+		}
+	}
+	for _, ar := range []AllocRunner{older, dev2, batch3} {
+		gcAlloc := gc.allocRunners.Pop()
+		require.NotNil(t, gcAlloc)
+		require.Equal(t, ar.Alloc().ID, gcAlloc.allocID)
+	}
+}
+
+func TestAllocGarbageCollector_MaxAllocsPerNamespace_Order(t *testing.T) {
+	t.Parallel()
+	logger := testlog.HCLogger(t)
+	conf := gcConfig()
+	conf.MaxAllocsPerNamespace = map[string]int{"batch": 1, "dev": 1}
+	gc := NewAllocGarbageCollector(logger, &MockStatsCollector{}, &MockAllocCounter{}, conf)
+
+	newRunner := func(namespace string) AllocRunner {
+		alloc := mock.Alloc()
+		alloc.Namespace = namespace
+		ar, cleanup := allocrunner.TestAllocRunnerFromAlloc(t, alloc)
+		t.Cleanup(cleanup)
+		gc.MarkForCollection(alloc.ID, ar)
+		return ar
+	}
+	older := newRunner("default")
+	dev1 := newRunner("dev")
+	batch1 := newRunner("batch")
+	batch2 := newRunner("batch")
+	dev2 := newRunner("dev")
+	batch3 := newRunner("batch")
+
+	// The namespace furthest over its limit is collected first, ties are
+	// broken by name, and the oldest alloc is collected once no namespace
+	// is over its limit
+	expected := []AllocRunner{batch1, batch2, dev1, older, dev2, batch3}
+	for _, ar := range expected {
+		gcAlloc := gc.popCandidate()
+		require.NotNil(t, gcAlloc)
+		require.Equal(t, ar.Alloc().ID, gcAlloc.allocID)
+	}
+	require.Nil(t, gc.popCandidate())
+}
+
+func TestAllocGarbageCollector_MaxAllocAge(t *testing.T) {
+	t.Parallel()
+	logger := testlog.HCLogger(t)
+	conf := gcConfig()
+	conf.MaxAllocAge = time.Hour
+	gc := NewAllocGarbageCollector(logger, &MockStatsCollector{}, &MockAllocCounter{}, conf)
+
+	newRunner := func(age time.Duration) AllocRunner {
+		ar, cleanup := allocrunner.TestAllocRunnerFromAlloc(t, mock.Alloc())
+		t.Cleanup(cleanup)
+		go ar.Run()
+		gc.MarkForCollection(ar.Alloc().ID, ar)
+		gc.allocRunners.index[ar.Alloc().ID].timeStamp = time.Now().Add(-age)
+		return ar
+	}
+	old1 := newRunner(3 * time.Hour)
+	old2 := newRunner(2 * time.Hour)
+	recent := newRunner(time.Minute)
+	exitAllocRunner(old1, old2, recent)
+
+	// Only the allocs over the max age are collected, without any usage
+	// being collected
+	gc.collectAgedAllocs()
+	for _, ar := range []AllocRunner{old1, old2} {
+		select {
+		case <-ar.DestroyCh():
+		default:
+			t.Fatalf("expected alloc %s to be collected", ar.Alloc().ID)
+		}
+	}
+	require.Equal(t, 1, gc.allocRunners.Length())
+	require.Nil(t, gc.allocRunners.PopOlderThan(time.Now().Add(-time.Hour)))
+
+	// Nothing is collected by age when it is disabled
+	gc.config.MaxAllocAge = 0
+	gc.allocRunners.index[recent.Alloc().ID].timeStamp = time.Now().Add(-24 * time.Hour)
+	gc.collectAgedAllocs()
+	require.Equal(t, 1, gc.allocRunners.Length())
+}
+
+func TestAllocGarbageCollector_MaxAllocAge_Restored(t *testing.T) {
+	t.Parallel()
+	logger := testlog.HCLogger(t)
+	conf := gcConfig()
+	conf.MaxAllocAge = time.Hour
+	gc := NewAllocGarbageCollector(logger, &MockStatsCollector{}, &MockAllocCounter{}, conf)
+
+	// The allocs are restored by a client that just started, so they're
+	// marked for collection now
+	newRunner := func(alloc *structs.Allocation) AllocRunner {
+		ar, cleanup := allocrunner.TestAllocRunnerFromAlloc(t, alloc)
+		t.Cleanup(cleanup)
+		gc.MarkForCollection(alloc.ID, ar)
+		go ar.Run()
+		return ar
+	}
+
+	// The age of an alloc whose tasks ran is taken from when its last task
+	// finished
+	finished := mock.Alloc()
+	finished.ClientStatus = structs.AllocClientStatusComplete
+	finished.TaskStates = map[string]*structs.TaskState{
+		"web": {
+			State:      structs.TaskStateDead,
+			StartedAt:  time.Now().Add(-4 * time.Hour),
+			FinishedAt: time.Now().Add(-3 * time.Hour),
+		},
+	}
+	old := newRunner(finished)
+
+	// Otherwise from when the alloc was last modified
+	stopped := mock.Alloc()
+	stopped.ModifyTime = time.Now().Add(-2 * time.Hour).UnixNano()
+	unstarted := newRunner(stopped)
+
+	recent := mock.Alloc()
+	recent.ModifyTime = time.Now().Add(-time.Minute).UnixNano()
+	newRunner(recent)
+
+	gcAlloc := gc.allocRunners.index[finished.ID]
+	require.WithinDuration(t, time.Now().Add(-3*time.Hour), gcAlloc.timeStamp, time.Minute)
+
+	exitAllocRunner(old, unstarted)
+	gc.collectAgedAllocs()
+	for _, ar := range []AllocRunner{old, unstarted} {
+		select {
+		case <-ar.DestroyCh():
+		default:
+			t.Fatalf("expected alloc %s to be collected", ar.Alloc().ID)
+		}
+	}
+	require.Equal(t, 1, gc.allocRunners.Length())
+	require.NotNil(t, gc.allocRunners.Remove(recent.ID))
+}
+
+func TestAllocGarbageCollector_SetConfig(t *testing.T) {
+	t.Parallel()
+	logger := testlog.HCLogger(t)
+	conf := gcConfig()
+	conf.Interval = time.Hour
+	conf.MaxAllocAge = time.Minute
+	conf.ParallelDestroys = 2
+	statsCollector := &MockStatsCollector{
+		availableValues: []uint64{10 * 1024 * MB},
+		usedPercents:    []float64{0},
+		inodePercents:   []float64{0},
+	}
+	gc := NewAllocGarbageCollector(logger, statsCollector, &MockAllocCounter{}, conf)
+	go gc.Run()
+	defer gc.Stop()
+
+	ar, cleanup := allocrunner.TestAllocRunnerFromAlloc(t, mock.Alloc())
+	defer cleanup()
+	go ar.Run()
+	exitAllocRunner(ar)
+	gc.MarkForCollection(ar.Alloc().ID, ar)
+	gc.allocRunners.index[ar.Alloc().ID].timeStamp = time.Now().Add(-time.Hour)
+
+	// The aged alloc is collected once the new interval elapses, rather
+	// than after the hour the collector started with
+	newConf := gcConfig()
+	newConf.Interval = 10 * time.Millisecond
+	newConf.MaxAllocAge = time.Minute
+	gc.SetConfig(newConf)
+
+	testutil.WaitForResult(func() (bool, error) {
+		if n := gc.allocRunners.Length(); n != 0 {
+			return false, fmt.Errorf("expected alloc to be collected, %d left", n)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// The parallelism the collector was created with is kept
+	require.Equal(t, 2, gc.getConfig().ParallelDestroys)
+}
+
 func TestAllocGarbageCollector_UsedPercentThreshold(t *testing.T) {
 	t.Parallel()
 	logger := testlog.HCLogger(t)
@@ -572,3 +830,205 @@ func TestAllocGarbageCollector_UsedPercentThreshold(t *testing.T) {
 		t.Fatalf("gcAlloc: %v", gcAlloc)
 	}
 }
+
+// gcReasonSink records the gc_reason and usage logged for each collected
+// allocation.
+type gcReasonSink struct {
+	lock    sync.Mutex
+	reasons map[string]map[string]interface{}
+}
+
+func (s *gcReasonSink) Accept(_ string, _ hclog.Level, msg string, args ...interface{}) {
+	if msg != "garbage collecting allocation" {
+		return
+	}
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[args[i].(string)] = args[i+1]
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.reasons[fields["alloc_id"].(string)] = fields
+}
+
+func (s *gcReasonSink) reason(allocID string) map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.reasons[allocID]
+}
+
+func TestAllocGarbageCollector_Reasons(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		modify       func(*GCConfig, *MockStatsCollector, *MockAllocCounter)
+		collect      func(*AllocGarbageCollector, AllocRunner)
+		expected     string
+		expectedDisk string
+	}{
+		{
+			name: "disk threshold",
+			modify: func(_ *GCConfig, sc *MockStatsCollector, _ *MockAllocCounter) {
+				sc.availableValues = []uint64{1000, 800}
+				sc.usedPercents = []float64{85, 60}
+				sc.inodePercents = []float64{50, 30}
+			},
+			collect: func(gc *AllocGarbageCollector, _ AllocRunner) {
+				require.NoError(t, gc.keepUsageBelowThreshold())
+			},
+			expected:     gcReasonDiskThreshold,
+			expectedDisk: "85.0",
+		},
+		{
+			name: "inode threshold",
+			modify: func(_ *GCConfig, sc *MockStatsCollector, _ *MockAllocCounter) {
+				sc.availableValues = []uint64{1000, 800}
+				sc.usedPercents = []float64{60, 60}
+				sc.inodePercents = []float64{90, 30}
+			},
+			collect: func(gc *AllocGarbageCollector, _ AllocRunner) {
+				require.NoError(t, gc.keepUsageBelowThreshold())
+			},
+			expected:     gcReasonInodeThreshold,
+			expectedDisk: "60.0",
+		},
+		{
+			name: "max allocs",
+			modify: func(conf *GCConfig, sc *MockStatsCollector, ac *MockAllocCounter) {
+				conf.MaxAllocs = 1
+				ac.allocs = 2
+				sc.availableValues = []uint64{1000}
+				sc.usedPercents = []float64{20}
+				sc.inodePercents = []float64{10}
+			},
+			collect: func(gc *AllocGarbageCollector, _ AllocRunner) {
+				require.NoError(t, gc.keepUsageBelowThreshold())
+			},
+			expected:     gcReasonMaxAllocs,
+			expectedDisk: "20.0",
+		},
+		{
+			name: "namespace max allocs",
+			modify: func(conf *GCConfig, sc *MockStatsCollector, _ *MockAllocCounter) {
+				conf.MaxAllocsPerNamespace = map[string]int{"default": 0}
+				sc.availableValues = []uint64{1000}
+				sc.usedPercents = []float64{20}
+				sc.inodePercents = []float64{10}
+			},
+			collect: func(gc *AllocGarbageCollector, _ AllocRunner) {
+				require.NoError(t, gc.keepUsageBelowThreshold())
+			},
+			expected:     gcReasonNamespaceMaxAllocs,
+			expectedDisk: "20.0",
+		},
+		{
+			name: "max alloc age",
+			modify: func(conf *GCConfig, _ *MockStatsCollector, _ *MockAllocCounter) {
+				conf.MaxAllocAge = time.Nanosecond
+			},
+			collect: func(gc *AllocGarbageCollector, _ AllocRunner) {
+				time.Sleep(time.Millisecond)
+				gc.collectAgedAllocs()
+			},
+			expected: gcReasonMaxAllocAge,
+		},
+		{
+			name: "new allocs",
+			modify: func(conf *GCConfig, _ *MockStatsCollector, ac *MockAllocCounter) {
+				conf.MaxAllocs = 1
+				ac.allocs = 2
+			},
+			collect: func(gc *AllocGarbageCollector, _ AllocRunner) {
+				require.NoError(t, gc.MakeRoomFor([]*structs.Allocation{mock.Alloc()}))
+			},
+			expected: gcReasonNewAllocs,
+		},
+		{
+			name: "new allocs disk",
+			modify: func(_ *GCConfig, sc *MockStatsCollector, _ *MockAllocCounter) {
+				sc.availableValues = []uint64{0, 0}
+				sc.usedPercents = []float64{70, 70}
+				sc.inodePercents = []float64{10, 10}
+			},
+			collect: func(gc *AllocGarbageCollector, _ AllocRunner) {
+				require.NoError(t, gc.MakeRoomFor([]*structs.Allocation{mock.Alloc()}))
+			},
+			expected:     gcReasonNewAllocsDisk,
+			expectedDisk: "70.0",
+		},
+		{
+			name: "forced",
+			collect: func(gc *AllocGarbageCollector, ar AllocRunner) {
+				require.True(t, gc.Collect(ar.Alloc().ID))
+			},
+			expected: gcReasonForced,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sink := &gcReasonSink{reasons: make(map[string]map[string]interface{})}
+			logger := hclog.NewInterceptLogger(&hclog.LoggerOptions{Output: ioutil.Discard})
+			logger.RegisterSink(sink)
+
+			conf := gcConfig()
+			statsCollector := &MockStatsCollector{}
+			allocCounter := &MockAllocCounter{}
+			if tc.modify != nil {
+				tc.modify(conf, statsCollector, allocCounter)
+			}
+			gc := NewAllocGarbageCollector(logger, statsCollector, allocCounter, conf)
+
+			ar, cleanup := allocrunner.TestAllocRunnerFromAlloc(t, mock.Alloc())
+			defer cleanup()
+			go ar.Run()
+			gc.MarkForCollection(ar.Alloc().ID, ar)
+			exitAllocRunner(ar)
+
+			tc.collect(gc, ar)
+
+			reason := sink.reason(ar.Alloc().ID)
+			require.NotNil(t, reason, "expected alloc to be collected")
+			require.Equal(t, tc.expected, reason["gc_reason"])
+			require.NotEmpty(t, reason["reason"])
+			if tc.expectedDisk == "" {
+				require.NotContains(t, reason, "disk_used_percent")
+			} else {
+				require.Equal(t, tc.expectedDisk, reason["disk_used_percent"])
+				require.Contains(t, reason, "inodes_used_percent")
+			}
+		})
+	}
+}
+
+func TestAllocGarbageCollector_DiskUsageThreshold_StateDirReserve(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		reserveMB int
+		size      uint64
+		expected  float64
+	}{
+		{name: "no reserve", reserveMB: 0, size: 1000 * MB, expected: 80},
+		{name: "reserve lowers threshold", reserveMB: 100, size: 1000 * MB, expected: 70},
+		{name: "reserve larger than threshold", reserveMB: 900, size: 1000 * MB, expected: 0},
+		{name: "unknown disk size", reserveMB: 100, size: 0, expected: 80},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := gcConfig()
+			conf.StateDirReserveMB = tc.reserveMB
+			gc := NewAllocGarbageCollector(testlog.HCLogger(t), &MockStatsCollector{}, &MockAllocCounter{}, conf)
+
+			threshold := gc.diskUsageThreshold(&stats.DiskStats{Size: tc.size})
+			require.InDelta(t, tc.expected, threshold, 0.001)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// defaultIPAMDataDir is where the host-local IPAM plugin stores the leases of
+// the Nomad bridge network. Each lease is a file named for the leased IP,
+// holding the ID of the container it's leased to, which is the allocation ID.
+const defaultIPAMDataDir = "/var/lib/cni/networks/nomad"
+
+// ipamReconciler releases the IPAM leases of the bridge network held by
+// allocations that no longer exist on the node. Leases are released when an
+// allocation's network is torn down, but are left behind if the client or
+// host crashes first, and would eventually exhaust the bridge subnet.
+//
+// The network namespaces of such allocations are gone, so the leases can't be
+// released with a CNI DEL and are removed from the store directly, while
+// holding the store's lock like the host-local plugin does.
+type ipamReconciler struct {
+	// dataDir is the host-local IPAM store of the bridge network
+	dataDir string
+
+	// interval is the time between reconciliations
+	interval time.Duration
+
+	// dryRun logs orphaned leases instead of releasing them
+	dryRun bool
+
+	// inUse returns the IDs of the containers that may hold leases. Any
+	// lease held by a container not in the set is released.
+	inUse func() map[string]struct{}
+
+	logger     hclog.Logger
+	shutdownCh <-chan struct{}
+}
+
+func newIPAMReconciler(logger hclog.Logger, interval time.Duration, dryRun bool,
+	inUse func() map[string]struct{}, shutdownCh <-chan struct{}) *ipamReconciler {
+	return &ipamReconciler{
+		dataDir:    defaultIPAMDataDir,
+		interval:   interval,
+		dryRun:     dryRun,
+		inUse:      inUse,
+		logger:     logger.Named("ipam_gc"),
+		shutdownCh: shutdownCh,
+	}
+}
+
+// run reconciles the leases immediately and then every interval until the
+// client shuts down.
+func (r *ipamReconciler) run() {
+	r.reconcile()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.reconcile()
+		case <-r.shutdownCh:
+			return
+		}
+	}
+}
+
+// reconcile releases the leases held by containers that aren't in use, and
+// returns the orphaned leases it found.
+func (r *ipamReconciler) reconcile() []string {
+	unlock, err := lockIPAMStore(r.dataDir)
+	if err != nil {
+		// The store is created by the first allocation using the bridge
+		if !os.IsNotExist(err) {
+			r.logger.Warn("failed to lock IPAM store", "dir", r.dataDir, "error", err)
+		}
+		return nil
+	}
+	defer unlock()
+
+	leases, err := r.leases()
+	if err != nil {
+		r.logger.Warn("failed to list IPAM leases", "dir", r.dataDir, "error", err)
+		return nil
+	}
+
+	// The containers in use are listed after the leases, so that a lease
+	// acquired by an allocation that was just added is never seen without
+	// its allocation. Allocations are added before their network is set up.
+	inUse := r.inUse()
+
+	orphans := []string{}
+	for ip, id := range leases {
+		if _, ok := inUse[id]; ok {
+			continue
+		}
+		orphans = append(orphans, ip)
+
+		if r.dryRun {
+			r.logger.Info("found orphaned IPAM lease", "ip", ip, "alloc_id", id, "dry_run", true)
+			continue
+		}
+		if err := os.Remove(filepath.Join(r.dataDir, ip)); err != nil && !os.IsNotExist(err) {
+			r.logger.Warn("failed to release orphaned IPAM lease", "ip", ip, "alloc_id", id, "error", err)
+			continue
+		}
+		r.logger.Info("released orphaned IPAM lease", "ip", ip, "alloc_id", id)
+		metrics.IncrCounter([]string{"client", "cni", "ipam", "released_leases"}, 1)
+	}
+
+	metrics.SetGauge([]string{"client", "cni", "ipam", "orphaned_leases"}, float32(len(orphans)))
+	return orphans
+}
+
+// leases returns the container ID each IP in the store is leased to. Files
+// that aren't named for an IP, such as the store's lock and the last
+// reserved IP, are skipped.
+func (r *ipamReconciler) leases() (map[string]string, error) {
+	entries, err := ioutil.ReadDir(r.dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || net.ParseIP(entry.Name()) == nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(r.dataDir, entry.Name()))
+		if err != nil {
+			r.logger.Warn("failed to read IPAM lease", "ip", entry.Name(), "error", err)
+			continue
+		}
+
+		// Newer versions of the plugin write the interface name after the
+		// container ID
+		id := strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0])
+		if id == "" {
+			continue
+		}
+		leases[entry.Name()] = id
+	}
+	return leases, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package client
+
+import "errors"
+
+// lockIPAMStore is only supported on Linux, where bridge networking is.
+func lockIPAMStore(dir string) (func(), error) {
+	return nil, errors.New("bridge networking is only supported on Linux")
+}
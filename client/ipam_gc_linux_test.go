@@ -0,0 +1,76 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/stretchr/testify/require"
+)
+
+// testIPAMReconciler returns an ipamReconciler for a fixture IPAM store
+// holding a lease for each container ID, in the formats written by old and
+// new versions of the host-local plugin.
+func testIPAMReconciler(t *testing.T, dryRun bool, inUse ...string) (*ipamReconciler, string) {
+	dir, err := ioutil.TempDir("", "nomad-ipam")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	writeFile := func(name, content string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	writeFile("172.26.64.2", "live")
+	writeFile("172.26.64.3", "dead\r\neth0")
+	writeFile("172.26.64.4", "gone")
+	writeFile("last_reserved_ip.0", "172.26.64.4")
+
+	r := newIPAMReconciler(testlog.HCLogger(t), time.Minute, dryRun, func() map[string]struct{} {
+		ids := make(map[string]struct{}, len(inUse))
+		for _, id := range inUse {
+			ids[id] = struct{}{}
+		}
+		return ids
+	}, nil)
+	r.dataDir = dir
+	return r, dir
+}
+
+func TestIPAMReconciler_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	r, dir := testIPAMReconciler(t, false, "live")
+
+	orphans := r.reconcile()
+	require.ElementsMatch(t, []string{"172.26.64.3", "172.26.64.4"}, orphans)
+
+	require.FileExists(t, filepath.Join(dir, "172.26.64.2"))
+	require.NoFileExists(t, filepath.Join(dir, "172.26.64.3"))
+	require.NoFileExists(t, filepath.Join(dir, "172.26.64.4"))
+	require.FileExists(t, filepath.Join(dir, "last_reserved_ip.0"))
+	require.FileExists(t, filepath.Join(dir, "lock"))
+
+	// Nothing is left to release
+	require.Empty(t, r.reconcile())
+}
+
+func TestIPAMReconciler_DryRun(t *testing.T) {
+	t.Parallel()
+
+	r, dir := testIPAMReconciler(t, true, "live", "gone")
+
+	require.Equal(t, []string{"172.26.64.3"}, r.reconcile())
+	require.FileExists(t, filepath.Join(dir, "172.26.64.3"))
+}
+
+func TestIPAMReconciler_NoStore(t *testing.T) {
+	t.Parallel()
+
+	r, dir := testIPAMReconciler(t, false)
+	r.dataDir = filepath.Join(dir, "missing")
+
+	require.Empty(t, r.reconcile())
+	require.NoDirExists(t, r.dataDir)
+}
@@ -0,0 +1,290 @@
+// Package bandwidth shares a node's download bandwidth between the classes of
+// traffic a client generates: artifact downloads and migrations of previous
+// allocations' data. Image pulls aren't covered, as drivers' daemons download
+// images outside of the client.
+//
+// Each class has a token bucket refilled at its share of the bandwidth. The
+// bandwidth is split between the classes currently streaming in proportion
+// to their weights, so the share of an idle class is redistributed to the
+// others until it streams again.
+package bandwidth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// Class is a class of download traffic sharing the node's bandwidth.
+type Class string
+
+const (
+	// ClassArtifact is the traffic of task artifact downloads
+	ClassArtifact Class = "artifact"
+
+	// ClassMigrate is the traffic of previous allocations' data migrated
+	// from other nodes
+	ClassMigrate Class = "migrate"
+)
+
+// Classes are the classes of traffic sharing the bandwidth.
+var Classes = []Class{ClassArtifact, ClassMigrate}
+
+const (
+	// DefaultWeight is the weight of the classes not given one
+	DefaultWeight = 1
+
+	// chunkSize bounds the bytes read at once, so that a single read
+	// doesn't reserve a large share of the bandwidth ahead of other classes
+	chunkSize = 32 * 1024
+
+	// burst is how long a streaming class can bank unused bandwidth for
+	burst = time.Second
+)
+
+// ParseWeights returns the weights of the classes named by weights. Classes
+// without a weight default to DefaultWeight.
+func ParseWeights(weights map[string]int) (map[Class]int, error) {
+	known := make(map[Class]struct{}, len(Classes))
+	for _, class := range Classes {
+		known[class] = struct{}{}
+	}
+
+	parsed := make(map[Class]int, len(Classes))
+	for _, class := range Classes {
+		parsed[class] = DefaultWeight
+	}
+
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		class, weight := Class(name), weights[name]
+		if _, ok := known[class]; !ok {
+			return nil, fmt.Errorf("unknown bandwidth class %q, must be one of %s",
+				name, strings.Join(classNames(), ", "))
+		}
+		if weight <= 0 {
+			return nil, fmt.Errorf("weight of bandwidth class %q must be positive, got %d", name, weight)
+		}
+		parsed[class] = weight
+	}
+	return parsed, nil
+}
+
+func classNames() []string {
+	names := make([]string, len(Classes))
+	for i, class := range Classes {
+		names[i] = string(class)
+	}
+	return names
+}
+
+// Clock is the source of time of a Manager.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// bucket is the token bucket of a class.
+type bucket struct {
+	weight int
+
+	// streams is the number of open readers of the class. The class only
+	// gets a share of the bandwidth while it has streams.
+	streams int
+
+	// tokens are the bytes the class can read without waiting. They go
+	// negative when reads are reserved ahead of the refill.
+	tokens float64
+	last   time.Time
+
+	// throttled is the total time reads of the class waited for tokens
+	throttled time.Duration
+}
+
+// Manager shares a node's download bandwidth between the classes of traffic.
+// A nil Manager doesn't limit the bandwidth.
+type Manager struct {
+	clock  Clock
+	logger hclog.Logger
+
+	// bytesPerSec is the bandwidth shared by the classes
+	bytesPerSec float64
+
+	lock    sync.Mutex
+	buckets map[Class]*bucket
+}
+
+// NewManager returns a Manager sharing mbps megabits per second between the
+// classes by weight. It returns nil if mbps isn't positive, disabling the
+// limit.
+func NewManager(logger hclog.Logger, mbps int, weights map[Class]int) *Manager {
+	if mbps <= 0 {
+		return nil
+	}
+
+	m := &Manager{
+		clock:       realClock{},
+		logger:      logger.Named("bandwidth"),
+		bytesPerSec: float64(mbps) * 1000 * 1000 / 8,
+		buckets:     make(map[Class]*bucket, len(Classes)),
+	}
+	for _, class := range Classes {
+		weight, ok := weights[class]
+		if !ok || weight <= 0 {
+			weight = DefaultWeight
+		}
+		m.buckets[class] = &bucket{weight: weight}
+	}
+	return m
+}
+
+// Rate returns the bytes per second the class can currently read.
+func (m *Manager) Rate(class Class) float64 {
+	if m == nil {
+		return 0
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.rateLocked(class)
+}
+
+// rateLocked returns the share of the bandwidth of the class, split between
+// the streaming classes and the class itself. m.lock must be held.
+func (m *Manager) rateLocked(class Class) float64 {
+	total := 0
+	for c, b := range m.buckets {
+		if b.streams > 0 || c == class {
+			total += b.weight
+		}
+	}
+	return m.bytesPerSec * float64(m.buckets[class].weight) / float64(total)
+}
+
+// settleLocked refills the buckets of the streaming classes at the rates in
+// effect since they were last refilled. It must be called before the set of
+// streaming classes changes. m.lock must be held.
+func (m *Manager) settleLocked(now time.Time) {
+	for class, b := range m.buckets {
+		if b.streams > 0 {
+			rate := m.rateLocked(class)
+			b.tokens += rate * now.Sub(b.last).Seconds()
+			if max := rate * burst.Seconds(); b.tokens > max {
+				b.tokens = max
+			}
+		}
+		b.last = now
+	}
+}
+
+// open counts a stream of the class, giving it a share of the bandwidth.
+func (m *Manager) open(class Class) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.settleLocked(m.clock.Now())
+	m.buckets[class].streams++
+}
+
+// close releases a stream of the class. Once the class has no streams its
+// share is redistributed to the other classes, and it stops banking tokens.
+func (m *Manager) close(class Class) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.settleLocked(m.clock.Now())
+
+	b := m.buckets[class]
+	b.streams--
+	if b.streams == 0 && b.tokens > 0 {
+		b.tokens = 0
+	}
+}
+
+// reserve takes n bytes from the bucket of the class, returning how long to
+// wait before they are available.
+func (m *Manager) reserve(class Class, n int) time.Duration {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.settleLocked(m.clock.Now())
+
+	b := m.buckets[class]
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	wait := time.Duration(-b.tokens / m.rateLocked(class) * float64(time.Second))
+	b.throttled += wait
+	metrics.IncrCounterWithLabels([]string{"client", "bandwidth", "throttled_ms"},
+		float32(wait.Milliseconds()), []metrics.Label{{Name: "class", Value: string(class)}})
+	return wait
+}
+
+// Reader returns a reader of r whose reads are throttled to the class's share
+// of the bandwidth. The class shares the bandwidth until the reader is
+// closed. Cancelling ctx interrupts reads waiting for bandwidth.
+func (m *Manager) Reader(ctx context.Context, class Class, r io.ReadCloser) io.ReadCloser {
+	if m == nil {
+		return r
+	}
+
+	if _, ok := m.buckets[class]; !ok {
+		m.logger.Warn("not throttling stream of unknown class", "class", class)
+		return r
+	}
+
+	m.open(class)
+	return &reader{ctx: ctx, m: m, class: class, r: r}
+}
+
+// reader throttles the reads of a stream to its class's share of the
+// bandwidth.
+type reader struct {
+	ctx   context.Context
+	m     *Manager
+	class Class
+	r     io.ReadCloser
+
+	closeOnce sync.Once
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+
+	n, err := r.r.Read(p)
+	if n == 0 {
+		return n, err
+	}
+
+	if wait := r.m.reserve(r.class, n); wait > 0 {
+		select {
+		case <-r.m.clock.After(wait):
+		case <-r.ctx.Done():
+			return n, r.ctx.Err()
+		}
+	}
+	return n, err
+}
+
+func (r *reader) Close() error {
+	r.closeOnce.Do(func() { r.m.close(r.class) })
+	return r.r.Close()
+}
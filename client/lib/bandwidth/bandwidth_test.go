@@ -0,0 +1,165 @@
+package bandwidth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a Clock whose time only moves when set by the test, or when
+// waited on if advance is set.
+type fakeClock struct {
+	now     time.Time
+	advance bool
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	if c.advance {
+		c.now = c.now.Add(d)
+		ch <- c.now
+	}
+	return ch
+}
+
+// testManager returns a Manager sharing 8 Mbps, a million bytes per second,
+// using a fake clock.
+func testManager(t *testing.T, weights map[Class]int) (*Manager, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	m := NewManager(testlog.HCLogger(t), 8, weights)
+	m.clock = clock
+	return m, clock
+}
+
+func TestParseWeights(t *testing.T) {
+	weights, err := ParseWeights(nil)
+	require.NoError(t, err)
+	require.Equal(t, map[Class]int{ClassArtifact: 1, ClassMigrate: 1}, weights)
+
+	weights, err = ParseWeights(map[string]int{"artifact": 3})
+	require.NoError(t, err)
+	require.Equal(t, map[Class]int{ClassArtifact: 3, ClassMigrate: 1}, weights)
+
+	_, err = ParseWeights(map[string]int{"backup": 1})
+	require.EqualError(t, err, `unknown bandwidth class "backup", must be one of artifact, migrate`)
+
+	_, err = ParseWeights(map[string]int{"migrate": 0})
+	require.EqualError(t, err, `weight of bandwidth class "migrate" must be positive, got 0`)
+}
+
+func TestManager_Disabled(t *testing.T) {
+	m := NewManager(testlog.HCLogger(t), 0, nil)
+	require.Nil(t, m)
+
+	r := ioutil.NopCloser(bytes.NewReader([]byte("data")))
+	require.Equal(t, r, m.Reader(context.Background(), ClassArtifact, r))
+	require.Zero(t, m.Rate(ClassArtifact))
+}
+
+func TestManager_Reader(t *testing.T) {
+	m, clock := testManager(t, nil)
+	clock.advance = true
+
+	// A single stream reads at the full bandwidth
+	data := make([]byte, 4*1000*1000)
+	r := m.Reader(context.Background(), ClassArtifact, ioutil.NopCloser(bytes.NewReader(data)))
+	n, err := io.Copy(ioutil.Discard, r)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), n)
+	require.NoError(t, r.Close())
+
+	elapsed := clock.now.Sub(time.Unix(0, 0))
+	require.InDelta(t, 4*time.Second, elapsed, float64(50*time.Millisecond))
+	require.Equal(t, elapsed, m.buckets[ClassArtifact].throttled)
+	require.Zero(t, m.buckets[ClassArtifact].streams)
+
+	// Closing twice only releases the stream once
+	require.NoError(t, r.Close())
+	require.Zero(t, m.buckets[ClassArtifact].streams)
+}
+
+func TestManager_Reader_Cancel(t *testing.T) {
+	m, _ := testManager(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The clock never fires, so the read only returns once cancelled
+	data := make([]byte, 2*chunkSize)
+	r := m.Reader(ctx, ClassMigrate, ioutil.NopCloser(bytes.NewReader(data)))
+	defer r.Close()
+	n, err := r.Read(data)
+	require.Equal(t, chunkSize, n)
+	require.Equal(t, context.Canceled, err)
+}
+
+// simulate reads chunks from the streams of the given classes until the
+// clock reaches end, as if each stream read again as soon as its wait was
+// over. It returns the bytes read by each class.
+func simulate(m *Manager, clock *fakeClock, end time.Time, classes ...Class) map[Class]int {
+	ready := make(map[Class]time.Time, len(classes))
+	for _, class := range classes {
+		ready[class] = clock.now
+	}
+
+	read := make(map[Class]int, len(classes))
+	for {
+		// The stream ready first reads next
+		next := classes[0]
+		for _, class := range classes[1:] {
+			if ready[class].Before(ready[next]) {
+				next = class
+			}
+		}
+		if !ready[next].Before(end) {
+			return read
+		}
+
+		clock.now = ready[next]
+		wait := m.reserve(next, chunkSize)
+		read[next] += chunkSize
+		ready[next] = clock.now.Add(wait)
+	}
+}
+
+func TestManager_FairShare(t *testing.T) {
+	m, clock := testManager(t, map[Class]int{ClassArtifact: 3, ClassMigrate: 1})
+	start := clock.now
+
+	// Contending classes share the bandwidth by weight
+	m.open(ClassArtifact)
+	m.open(ClassMigrate)
+	require.Equal(t, 750000.0, m.Rate(ClassArtifact))
+	require.Equal(t, 250000.0, m.Rate(ClassMigrate))
+
+	read := simulate(m, clock, start.Add(time.Minute), ClassArtifact, ClassMigrate)
+	require.InEpsilon(t, 45*1000*1000, read[ClassArtifact], 0.01)
+	require.InEpsilon(t, 15*1000*1000, read[ClassMigrate], 0.01)
+	require.NotZero(t, m.buckets[ClassArtifact].throttled)
+	require.NotZero(t, m.buckets[ClassMigrate].throttled)
+
+	// A class streaming on its own gets the full bandwidth once the other
+	// finishes
+	m.close(ClassMigrate)
+	require.Equal(t, 1000000.0, m.Rate(ClassArtifact))
+
+	read = simulate(m, clock, clock.now.Add(10*time.Second), ClassArtifact)
+	require.InEpsilon(t, 10*1000*1000, read[ClassArtifact], 0.01)
+
+	// A class streaming again takes its share back
+	m.open(ClassMigrate)
+	require.Equal(t, 750000.0, m.Rate(ClassArtifact))
+	require.Equal(t, 250000.0, m.Rate(ClassMigrate))
+
+	read = simulate(m, clock, clock.now.Add(time.Minute), ClassArtifact, ClassMigrate)
+	require.InEpsilon(t, 45*1000*1000, read[ClassArtifact], 0.01)
+	require.InEpsilon(t, 15*1000*1000, read[ClassMigrate], 0.01)
+}
@@ -0,0 +1,296 @@
+package cgutil
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+)
+
+var (
+	// DiskIOMeasuredStats is the list of measured stats when reading block
+	// device I/O from a cgroup
+	DiskIOMeasuredStats = []string{"Read Bytes", "Write Bytes", "Read Ops", "Write Ops"}
+)
+
+// DiskIOCollector reads the block device I/O stats of a cgroup. It supports
+// both the cgroups v2 io controller and the cgroups v1 blkio controller.
+//
+// Device numbers are resolved to device names through sysfs and cached for
+// the lifetime of the collector, so a single collector should be shared.
+type DiskIOCollector struct {
+	// cgroupRoot is the mount point of the cgroup filesystem
+	cgroupRoot string
+
+	// procRoot and sysRoot are the mount points of procfs and sysfs
+	procRoot string
+	sysRoot  string
+
+	// devNames caches device names by "major:minor" device number
+	devNames     map[string]string
+	devNamesLock sync.Mutex
+
+	// agentCgroup is the block I/O cgroup of the agent, which tasks that
+	// aren't given a block I/O cgroup of their own share with it
+	agentCgroup     string
+	agentCgroupErr  error
+	agentCgroupOnce sync.Once
+}
+
+// NewDiskIOCollector returns a DiskIOCollector for the host's cgroups.
+func NewDiskIOCollector() *DiskIOCollector {
+	return newDiskIOCollector("/sys/fs/cgroup", "/proc", "/sys")
+}
+
+func newDiskIOCollector(cgroupRoot, procRoot, sysRoot string) *DiskIOCollector {
+	return &DiskIOCollector{
+		cgroupRoot: cgroupRoot,
+		procRoot:   procRoot,
+		sysRoot:    sysRoot,
+		devNames:   make(map[string]string),
+	}
+}
+
+// unified returns true if the cgroup filesystem is mounted in cgroups v2
+// unified mode.
+func (c *DiskIOCollector) unified() bool {
+	_, err := os.Stat(filepath.Join(c.cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// CgroupForPid returns the cgroup of the process that holds its block device
+// I/O stats, relative to the cgroup mount point.
+func (c *DiskIOCollector) CgroupForPid(pid string) (string, error) {
+	f, err := os.Open(filepath.Join(c.procRoot, pid, "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	unified := c.unified()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines are of the form hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if unified {
+			if parts[0] == "0" && parts[1] == "" {
+				return parts[2], nil
+			}
+			continue
+		}
+
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "blkio" {
+				return parts[2], nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("no block I/O cgroup found for pid %s", pid)
+}
+
+// TaskCgroup returns true if the block I/O cgroup is specific to a task,
+// rather than the cgroup of the agent or one of its parents. Tasks of drivers
+// that only place them in some of the cgroup controllers, such as raw_exec,
+// share the agent's block I/O cgroup, whose stats are those of the agent and
+// all of the tasks in it.
+func (c *DiskIOCollector) TaskCgroup(cgroup string) bool {
+	c.agentCgroupOnce.Do(func() {
+		c.agentCgroup, c.agentCgroupErr = c.CgroupForPid("self")
+	})
+	if c.agentCgroupErr != nil {
+		return false
+	}
+
+	cgroup = strings.TrimSuffix(cgroup, "/")
+	if cgroup == "" {
+		return false
+	}
+	return cgroup != c.agentCgroup && !strings.HasPrefix(c.agentCgroup, cgroup+"/")
+}
+
+// Collect returns the block device I/O stats of the cgroup, which is relative
+// to the cgroup mount point.
+func (c *DiskIOCollector) Collect(cgroup string) (*cstructs.DiskIOStats, error) {
+	var devices map[string]*cstructs.DiskIOStats
+	var err error
+	if c.unified() {
+		devices, err = c.readIOStat(filepath.Join(c.cgroupRoot, cgroup, "io.stat"))
+	} else {
+		devices, err = c.readBlkio(filepath.Join(c.cgroupRoot, "blkio", cgroup))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &cstructs.DiskIOStats{
+		Devices:  make(map[string]*cstructs.DiskIOStats, len(devices)),
+		Measured: DiskIOMeasuredStats,
+	}
+	for num, dev := range devices {
+		dev.Measured = DiskIOMeasuredStats
+		stats.ReadBytes += dev.ReadBytes
+		stats.WriteBytes += dev.WriteBytes
+		stats.ReadOps += dev.ReadOps
+		stats.WriteOps += dev.WriteOps
+		stats.Devices[c.deviceName(num)] = dev
+	}
+
+	return stats, nil
+}
+
+// readIOStat parses a cgroups v2 io.stat file, which has a line per device
+// of the form "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 ...".
+func (c *DiskIOCollector) readIOStat(path string) (map[string]*cstructs.DiskIOStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	devices := make(map[string]*cstructs.DiskIOStats)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		dev := &cstructs.DiskIOStats{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %q in %s: %v", field, path, err)
+			}
+			switch kv[0] {
+			case "rbytes":
+				dev.ReadBytes = v
+			case "wbytes":
+				dev.WriteBytes = v
+			case "rios":
+				dev.ReadOps = v
+			case "wios":
+				dev.WriteOps = v
+			}
+		}
+		devices[fields[0]] = dev
+	}
+
+	return devices, scanner.Err()
+}
+
+// readBlkio parses the cgroups v1 blkio throttling stats in the cgroup
+// directory. Both files have lines per device and operation of the form
+// "8:0 Read 1459200" and a final "Total" line that is ignored.
+func (c *DiskIOCollector) readBlkio(dir string) (map[string]*cstructs.DiskIOStats, error) {
+	devices := make(map[string]*cstructs.DiskIOStats)
+
+	read := func(file string, set func(dev *cstructs.DiskIOStats, op string, v uint64)) error {
+		path := filepath.Join(dir, file)
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 3 {
+				continue
+			}
+			v, err := strconv.ParseUint(fields[2], 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse %q in %s: %v", scanner.Text(), path, err)
+			}
+
+			dev, ok := devices[fields[0]]
+			if !ok {
+				dev = &cstructs.DiskIOStats{}
+				devices[fields[0]] = dev
+			}
+			set(dev, fields[1], v)
+		}
+		return scanner.Err()
+	}
+
+	err := read("blkio.throttle.io_service_bytes", func(dev *cstructs.DiskIOStats, op string, v uint64) {
+		switch op {
+		case "Read":
+			dev.ReadBytes = v
+		case "Write":
+			dev.WriteBytes = v
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = read("blkio.throttle.io_serviced", func(dev *cstructs.DiskIOStats, op string, v uint64) {
+		switch op {
+		case "Read":
+			dev.ReadOps = v
+		case "Write":
+			dev.WriteOps = v
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// deviceName resolves a "major:minor" device number to the name of the
+// block device, falling back to the device number if it cannot be resolved.
+func (c *DiskIOCollector) deviceName(num string) string {
+	c.devNamesLock.Lock()
+	defer c.devNamesLock.Unlock()
+
+	if name, ok := c.devNames[num]; ok {
+		return name
+	}
+
+	name := blockDeviceName(c.sysRoot, num)
+	c.devNames[num] = name
+	return name
+}
+
+// BlockDeviceName resolves a "major:minor" device number to the name of the
+// host's block device, falling back to the device number if it cannot be
+// resolved. Unlike a DiskIOCollector, it doesn't cache the name.
+func BlockDeviceName(num string) string {
+	return blockDeviceName("/sys", num)
+}
+
+func blockDeviceName(sysRoot, num string) string {
+	f, err := os.Open(filepath.Join(sysRoot, "dev", "block", num, "uevent"))
+	if err != nil {
+		return num
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v := strings.TrimPrefix(scanner.Text(), "DEVNAME="); v != scanner.Text() {
+			return v
+		}
+	}
+	return num
+}
@@ -0,0 +1,197 @@
+package cgutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFixture writes a fixture file, creating its parent directories
+func writeFixture(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+// setupDiskIOFixtures returns a collector reading from fixture cgroup, proc
+// and sys trees. The sys tree only names the 8:0 device.
+func setupDiskIOFixtures(t *testing.T, unified bool) *DiskIOCollector {
+	root, err := ioutil.TempDir("", "nomad-diskio")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	cgroupRoot := filepath.Join(root, "cgroup")
+	procRoot := filepath.Join(root, "proc")
+	sysRoot := filepath.Join(root, "sys")
+
+	writeFixture(t, filepath.Join(sysRoot, "dev", "block", "8:0", "uevent"),
+		"MAJOR=8\nMINOR=0\nDEVNAME=sda\nDEVTYPE=disk\n")
+
+	if unified {
+		writeFixture(t, filepath.Join(cgroupRoot, "cgroup.controllers"), "cpuset cpu io memory pids\n")
+		writeFixture(t, filepath.Join(cgroupRoot, "nomad.slice", "task.scope", "io.stat"),
+			"8:0 rbytes=1024 wbytes=4096 rios=2 wios=8 dbytes=0 dios=0\n"+
+				"259:0 rbytes=512 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n")
+		writeFixture(t, filepath.Join(procRoot, "1234", "cgroup"), "0::/nomad.slice/task.scope\n")
+	} else {
+		dir := filepath.Join(cgroupRoot, "blkio", "nomad", "task")
+		writeFixture(t, filepath.Join(dir, "blkio.throttle.io_service_bytes"),
+			"8:0 Read 1024\n8:0 Write 4096\n8:0 Sync 4096\n8:0 Async 1024\n8:0 Total 5120\n"+
+				"259:0 Read 512\n259:0 Write 0\n259:0 Sync 0\n259:0 Async 512\n259:0 Total 512\n"+
+				"Total 5632\n")
+		writeFixture(t, filepath.Join(dir, "blkio.throttle.io_serviced"),
+			"8:0 Read 2\n8:0 Write 8\n8:0 Sync 8\n8:0 Async 2\n8:0 Total 10\n"+
+				"259:0 Read 1\n259:0 Write 0\n259:0 Sync 0\n259:0 Async 1\n259:0 Total 1\n"+
+				"Total 11\n")
+		writeFixture(t, filepath.Join(procRoot, "1234", "cgroup"),
+			"12:pids:/nomad/task\n11:blkio:/nomad/task\n10:cpu,cpuacct:/nomad/task\n")
+	}
+
+	return newDiskIOCollector(cgroupRoot, procRoot, sysRoot)
+}
+
+func TestDiskIOCollector(t *testing.T) {
+	cases := []struct {
+		name           string
+		unified        bool
+		expectedCgroup string
+	}{
+		{
+			name:           "cgroups v1",
+			expectedCgroup: "/nomad/task",
+		},
+		{
+			name:           "cgroups v2",
+			unified:        true,
+			expectedCgroup: "/nomad.slice/task.scope",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := setupDiskIOFixtures(t, tc.unified)
+
+			cgroup, err := c.CgroupForPid("1234")
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedCgroup, cgroup)
+
+			stats, err := c.Collect(cgroup)
+			require.NoError(t, err)
+
+			require.Equal(t, uint64(1536), stats.ReadBytes)
+			require.Equal(t, uint64(4096), stats.WriteBytes)
+			require.Equal(t, uint64(3), stats.ReadOps)
+			require.Equal(t, uint64(8), stats.WriteOps)
+			require.Equal(t, DiskIOMeasuredStats, stats.Measured)
+
+			// Unnamed devices fall back to their device number
+			require.Len(t, stats.Devices, 2)
+			require.Equal(t, uint64(1024), stats.Devices["sda"].ReadBytes)
+			require.Equal(t, uint64(8), stats.Devices["sda"].WriteOps)
+			require.Equal(t, uint64(512), stats.Devices["259:0"].ReadBytes)
+		})
+	}
+}
+
+func TestDiskIOCollector_CgroupForPid_Missing(t *testing.T) {
+	c := setupDiskIOFixtures(t, false)
+
+	_, err := c.CgroupForPid("4321")
+	require.Error(t, err)
+}
+
+func TestDiskIOCollector_TaskCgroup(t *testing.T) {
+	cases := []struct {
+		name     string
+		agent    string
+		cgroup   string
+		expected bool
+	}{
+		{
+			name:     "task cgroup",
+			agent:    "0::/system.slice/nomad.service\n",
+			cgroup:   "/nomad.slice/task.scope",
+			expected: true,
+		},
+		{
+			name:   "agent cgroup",
+			agent:  "0::/system.slice/nomad.service\n",
+			cgroup: "/system.slice/nomad.service",
+		},
+		{
+			name:   "parent of the agent cgroup",
+			agent:  "0::/system.slice/nomad.service\n",
+			cgroup: "/system.slice",
+		},
+		{
+			name:   "root cgroup",
+			agent:  "0::/system.slice/nomad.service\n",
+			cgroup: "/",
+		},
+		{
+			name:     "sibling with a common prefix",
+			agent:    "0::/system.slice/nomad.service\n",
+			cgroup:   "/system.slice/nomad.service-task",
+			expected: true,
+		},
+		{
+			name:   "unknown agent cgroup",
+			cgroup: "/nomad.slice/task.scope",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := setupDiskIOFixtures(t, true)
+			if tc.agent != "" {
+				writeFixture(t, filepath.Join(c.procRoot, "self", "cgroup"), tc.agent)
+			}
+			require.Equal(t, tc.expected, c.TaskCgroup(tc.cgroup))
+		})
+	}
+}
+
+func TestDiskIOCollector_DeviceNameCached(t *testing.T) {
+	c := setupDiskIOFixtures(t, true)
+	require.Equal(t, "sda", c.deviceName("8:0"))
+
+	// Removing the sysfs entry does not change the cached name
+	require.NoError(t, os.RemoveAll(filepath.Join(c.sysRoot, "dev")))
+	require.Equal(t, "sda", c.deviceName("8:0"))
+}
+
+func TestDiskIOStats_Add(t *testing.T) {
+	a := &cstructs.DiskIOStats{
+		ReadBytes: 1,
+		Devices: map[string]*cstructs.DiskIOStats{
+			"sda": {ReadBytes: 1},
+		},
+	}
+	b := &cstructs.DiskIOStats{
+		ReadBytes:  2,
+		WriteBytes: 3,
+		Devices: map[string]*cstructs.DiskIOStats{
+			"sda": {ReadBytes: 2},
+			"sdb": {WriteBytes: 3},
+		},
+	}
+
+	ru := &cstructs.ResourceUsage{
+		MemoryStats: &cstructs.MemoryStats{},
+		CpuStats:    &cstructs.CpuStats{},
+	}
+	ru.Add(&cstructs.ResourceUsage{DiskIOStats: a})
+	ru.Add(&cstructs.ResourceUsage{DiskIOStats: b})
+
+	require.Equal(t, uint64(3), ru.DiskIOStats.ReadBytes)
+	require.Equal(t, uint64(3), ru.DiskIOStats.WriteBytes)
+	require.Equal(t, uint64(3), ru.DiskIOStats.Devices["sda"].ReadBytes)
+	require.Equal(t, uint64(3), ru.DiskIOStats.Devices["sdb"].WriteBytes)
+
+	// The added stats are not modified
+	require.Equal(t, uint64(1), a.Devices["sda"].ReadBytes)
+}
@@ -0,0 +1,119 @@
+package cgutil
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Freezer pauses and resumes the processes of a cgroup. It supports both the
+// cgroups v2 cgroup.freeze interface and the cgroups v1 freezer controller.
+type Freezer struct {
+	// cgroupRoot is the mount point of the cgroup filesystem
+	cgroupRoot string
+
+	// procRoot is the mount point of procfs
+	procRoot string
+}
+
+// NewFreezer returns a Freezer for the host's cgroups.
+func NewFreezer() *Freezer {
+	return newFreezer("/sys/fs/cgroup", "/proc")
+}
+
+func newFreezer(cgroupRoot, procRoot string) *Freezer {
+	return &Freezer{
+		cgroupRoot: cgroupRoot,
+		procRoot:   procRoot,
+	}
+}
+
+// unified returns true if the cgroup filesystem is mounted in cgroups v2
+// unified mode.
+func (f *Freezer) unified() bool {
+	_, err := os.Stat(filepath.Join(f.cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// CgroupForPid returns the cgroup of the process that can be frozen,
+// relative to the cgroup mount point.
+func (f *Freezer) CgroupForPid(pid string) (string, error) {
+	file, err := os.Open(filepath.Join(f.procRoot, pid, "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	unified := f.unified()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines are of the form hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if unified {
+			if parts[0] == "0" && parts[1] == "" {
+				return parts[2], nil
+			}
+			continue
+		}
+
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "freezer" {
+				return parts[2], nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("no freezer cgroup found for pid %s", pid)
+}
+
+// Freeze pauses the processes of the cgroup, which is relative to the cgroup
+// mount point. The cgroup the Nomad client runs in, and its ancestors, are
+// never frozen.
+func (f *Freezer) Freeze(cgroup string) error {
+	self, err := f.CgroupForPid("self")
+	if err != nil {
+		return fmt.Errorf("failed to find the client's cgroup: %v", err)
+	}
+	if containsCgroup(cgroup, self) {
+		return fmt.Errorf("cgroup %s contains the client's cgroup", cgroup)
+	}
+
+	if f.unified() {
+		return f.write(cgroup, "cgroup.freeze", "1")
+	}
+	return f.write(cgroup, "freezer.state", "FROZEN")
+}
+
+// Thaw resumes the processes of a frozen cgroup, which is relative to the
+// cgroup mount point.
+func (f *Freezer) Thaw(cgroup string) error {
+	if f.unified() {
+		return f.write(cgroup, "cgroup.freeze", "0")
+	}
+	return f.write(cgroup, "freezer.state", "THAWED")
+}
+
+func (f *Freezer) write(cgroup, file, value string) error {
+	dir := filepath.Join(f.cgroupRoot, cgroup)
+	if !f.unified() {
+		dir = filepath.Join(f.cgroupRoot, "freezer", cgroup)
+	}
+	return ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+}
+
+// containsCgroup returns whether the cgroup is other or one of its ancestors.
+func containsCgroup(cgroup, other string) bool {
+	cgroup = filepath.Clean("/" + cgroup)
+	other = filepath.Clean("/" + other)
+	return cgroup == "/" || cgroup == other || strings.HasPrefix(other, cgroup+"/")
+}
@@ -0,0 +1,98 @@
+package cgutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setupFreezerFixtures returns a freezer using fixture cgroup and proc trees,
+// where the client runs in the nomad cgroup and a task in its task cgroup.
+func setupFreezerFixtures(t *testing.T, unified bool) (*Freezer, string) {
+	root, err := ioutil.TempDir("", "nomad-freezer")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	cgroupRoot := filepath.Join(root, "cgroup")
+	procRoot := filepath.Join(root, "proc")
+
+	if unified {
+		writeFixture(t, filepath.Join(cgroupRoot, "cgroup.controllers"), "cpuset cpu io memory pids\n")
+		writeFixture(t, filepath.Join(cgroupRoot, "nomad.slice", "task.scope", "cgroup.freeze"), "0\n")
+		writeFixture(t, filepath.Join(procRoot, "self", "cgroup"), "0::/nomad.slice/client.scope\n")
+		writeFixture(t, filepath.Join(procRoot, "1234", "cgroup"), "0::/nomad.slice/task.scope\n")
+	} else {
+		writeFixture(t, filepath.Join(cgroupRoot, "freezer", "nomad", "task", "freezer.state"), "THAWED\n")
+		writeFixture(t, filepath.Join(procRoot, "self", "cgroup"), "12:pids:/nomad\n7:freezer:/nomad\n")
+		writeFixture(t, filepath.Join(procRoot, "1234", "cgroup"), "12:pids:/nomad/task\n7:freezer:/nomad/task\n")
+	}
+
+	return newFreezer(cgroupRoot, procRoot), cgroupRoot
+}
+
+func TestFreezer(t *testing.T) {
+	cases := []struct {
+		name    string
+		unified bool
+		cgroup  string
+		file    string
+		frozen  string
+		thawed  string
+	}{
+		{
+			name:   "v1",
+			cgroup: "/nomad/task",
+			file:   filepath.Join("freezer", "nomad", "task", "freezer.state"),
+			frozen: "FROZEN",
+			thawed: "THAWED",
+		},
+		{
+			name:    "v2",
+			unified: true,
+			cgroup:  "/nomad.slice/task.scope",
+			file:    filepath.Join("nomad.slice", "task.scope", "cgroup.freeze"),
+			frozen:  "1",
+			thawed:  "0",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			freezer, cgroupRoot := setupFreezerFixtures(t, tc.unified)
+
+			cgroup, err := freezer.CgroupForPid("1234")
+			require.NoError(t, err)
+			require.Equal(t, tc.cgroup, cgroup)
+
+			state := func() string {
+				b, err := ioutil.ReadFile(filepath.Join(cgroupRoot, tc.file))
+				require.NoError(t, err)
+				return string(b)
+			}
+
+			require.NoError(t, freezer.Freeze(cgroup))
+			require.Equal(t, tc.frozen, state())
+
+			require.NoError(t, freezer.Thaw(cgroup))
+			require.Equal(t, tc.thawed, state())
+
+			// The client's own cgroup and its ancestors are never frozen
+			self, err := freezer.CgroupForPid("self")
+			require.NoError(t, err)
+			require.Error(t, freezer.Freeze(self))
+			require.Error(t, freezer.Freeze(filepath.Dir(self)))
+			require.Error(t, freezer.Freeze("/"))
+		})
+	}
+}
+
+func TestFreezer_CgroupForPid_NotFound(t *testing.T) {
+	freezer, _ := setupFreezerFixtures(t, false)
+	writeFixture(t, filepath.Join(freezer.procRoot, "5678", "cgroup"), "12:pids:/nomad/task\n")
+
+	_, err := freezer.CgroupForPid("5678")
+	require.EqualError(t, err, "no freezer cgroup found for pid 5678")
+}
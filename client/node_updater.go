@@ -230,7 +230,7 @@ func (c *Client) updateNodeFromDriverLocked(name string, info *structs.DriverInf
 	} else {
 		oldVal := c.config.Node.Drivers[name]
 		// The driver info has already been set, fix it up
-		if oldVal.Detected != info.Detected {
+		if oldVal.Detected != info.Detected || oldVal.Degraded != info.Degraded {
 			hasChanged = true
 		}
 
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// placementFailureKey identifies the failures of a version of a job's task
+// group with a given code
+type placementFailureKey struct {
+	namespace  string
+	jobID      string
+	jobVersion uint64
+	taskGroup  string
+	code       string
+}
+
+// newPlacementFailureKey returns the key of the failures of alloc's version
+// of its task group. A new version of the job may fix the failure, so it
+// isn't rejected for the failures of the previous one.
+func newPlacementFailureKey(alloc *structs.Allocation, code string) placementFailureKey {
+	key := placementFailureKey{
+		namespace: alloc.Namespace,
+		jobID:     alloc.JobID,
+		taskGroup: alloc.TaskGroup,
+		code:      code,
+	}
+	if alloc.Job != nil {
+		key.jobVersion = alloc.Job.Version
+	}
+	return key
+}
+
+// matches returns whether the key is for the same version of alloc's task
+// group, regardless of the code.
+func (k placementFailureKey) matches(alloc *structs.Allocation) bool {
+	return k == newPlacementFailureKey(alloc, k.code)
+}
+
+// placementFailure is an allocation that failed on this node for a reason
+// that will recur for every allocation of its version of its task group.
+type placementFailure struct {
+	Code    string
+	Reason  string
+	AllocID string
+	Expires time.Time
+}
+
+// rejectionErr returns the error an allocation of the same task group is
+// rejected with while the failure is cached.
+func (f *placementFailure) rejectionErr() error {
+	return structs.NewNodePlacementError(f.Code, fmt.Errorf(
+		"allocation %s of the same task group failed on this node: %s", f.AllocID, f.Reason))
+}
+
+// placementFailureCache remembers the task groups whose allocations failed on
+// this node for a reason that will recur, so that further allocations of the
+// same version of the task group can be rejected until the failure expires
+// without paying their setup cost.
+type placementFailureCache struct {
+	ttl  time.Duration
+	size int
+
+	entries map[placementFailureKey]*placementFailure
+	lock    sync.Mutex
+}
+
+// newPlacementFailureCache returns a cache remembering failures for ttl. A
+// ttl of zero disables the cache.
+func newPlacementFailureCache(ttl time.Duration, size int) *placementFailureCache {
+	if size <= 0 {
+		size = config.DefaultPlacementFailureCacheSize
+	}
+	return &placementFailureCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[placementFailureKey]*placementFailure),
+	}
+}
+
+// record remembers that alloc failed with the given placement failure code.
+func (c *placementFailureCache) record(alloc *structs.Allocation, code, reason string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	c.pruneLocked(now)
+
+	key := newPlacementFailureKey(alloc, code)
+	if existing, ok := c.entries[key]; ok && existing.AllocID == alloc.ID {
+		// The same failure is reported with every update of the allocation
+		return
+	}
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evictLocked()
+	}
+	c.entries[key] = &placementFailure{
+		Code:    code,
+		Reason:  reason,
+		AllocID: alloc.ID,
+		Expires: now.Add(c.ttl),
+	}
+}
+
+// lookup returns an unexpired failure of alloc's version of its task group,
+// or nil if there is none.
+func (c *placementFailureCache) lookup(alloc *structs.Allocation) *placementFailure {
+	if c.ttl <= 0 {
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	var found *placementFailure
+	for key, failure := range c.entries {
+		if !key.matches(alloc) || !now.Before(failure.Expires) {
+			continue
+		}
+		if found == nil || failure.Expires.After(found.Expires) {
+			found = failure
+		}
+	}
+	if found == nil {
+		return nil
+	}
+
+	failure := *found
+	return &failure
+}
+
+// clearCode forgets every failure with the given code, once the config that
+// caused them has changed. It returns the number of failures forgotten.
+func (c *placementFailureCache) clearCode(code string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cleared := 0
+	for key := range c.entries {
+		if key.code == code {
+			delete(c.entries, key)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// pruneLocked removes expired failures. c.lock must be held.
+func (c *placementFailureCache) pruneLocked(now time.Time) {
+	for key, failure := range c.entries {
+		if !now.Before(failure.Expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// evictLocked removes the failure that expires soonest. c.lock must be held.
+func (c *placementFailureCache) evictLocked() {
+	var evict placementFailureKey
+	var soonest time.Time
+	for key, failure := range c.entries {
+		if soonest.IsZero() || failure.Expires.Before(soonest) {
+			evict = key
+			soonest = failure.Expires
+		}
+	}
+	delete(c.entries, evict)
+}
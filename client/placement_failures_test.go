@@ -0,0 +1,163 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementFailureCache(t *testing.T) {
+	t.Parallel()
+
+	cache := newPlacementFailureCache(time.Minute, 2)
+
+	alloc := mock.Alloc()
+	require.Nil(t, cache.lookup(alloc))
+
+	cache.record(alloc, structs.PlacementFailureMissingHostVolume, "missing data")
+
+	// Allocations of the same job are rejected
+	other := mock.Alloc()
+	other.JobID = alloc.JobID
+	failure := cache.lookup(other)
+	require.NotNil(t, failure)
+	require.Equal(t, structs.PlacementFailureMissingHostVolume, failure.Code)
+	require.Equal(t, alloc.ID, failure.AllocID)
+
+	err := failure.rejectionErr()
+	require.Contains(t, err.Error(), "missing data")
+	code, ok := structs.PlacementFailureCode(err)
+	require.True(t, ok)
+	require.Equal(t, structs.PlacementFailureMissingHostVolume, code)
+
+	// Allocations of other jobs, task groups or job versions are not
+	require.Nil(t, cache.lookup(mock.Alloc()))
+
+	otherGroup := other.Copy()
+	otherGroup.TaskGroup = "api"
+	require.Nil(t, cache.lookup(otherGroup))
+
+	newVersion := other.Copy()
+	newVersion.Job.Version++
+	require.Nil(t, cache.lookup(newVersion))
+
+	// The oldest failure is evicted once the cache is full
+	second, third := mock.Alloc(), mock.Alloc()
+	cache.record(second, structs.PlacementFailureDriverUnavailable, "no driver")
+	cache.record(third, structs.PlacementFailureDriverUnavailable, "no driver")
+	require.Nil(t, cache.lookup(alloc))
+	require.NotNil(t, cache.lookup(second))
+	require.NotNil(t, cache.lookup(third))
+
+	// Failures are forgotten by code
+	require.Equal(t, 2, cache.clearCode(structs.PlacementFailureDriverUnavailable))
+	require.Nil(t, cache.lookup(second))
+}
+
+func TestPlacementFailureCache_Expiry(t *testing.T) {
+	t.Parallel()
+
+	cache := newPlacementFailureCache(time.Minute, 0)
+	alloc := mock.Alloc()
+	cache.record(alloc, structs.PlacementFailureMissingHostVolume, "missing data")
+
+	for _, failure := range cache.entries {
+		failure.Expires = time.Now().Add(-time.Second)
+	}
+	require.Nil(t, cache.lookup(alloc))
+
+	// Expired failures are pruned on the next record
+	cache.record(mock.Alloc(), structs.PlacementFailureMissingHostVolume, "missing data")
+	require.Len(t, cache.entries, 1)
+}
+
+func TestPlacementFailureCache_Disabled(t *testing.T) {
+	t.Parallel()
+
+	cache := newPlacementFailureCache(0, 0)
+	alloc := mock.Alloc()
+	cache.record(alloc, structs.PlacementFailureMissingHostVolume, "missing data")
+	require.Nil(t, cache.lookup(alloc))
+}
+
+func TestClient_PlacementFailure_Rejected(t *testing.T) {
+	t.Parallel()
+
+	s1, _, cleanupS1 := testServer(t, nil)
+	defer cleanupS1()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	c1, cleanupC1 := TestClient(t, func(c *config.Config) {
+		c.DevMode = false
+		c.RPCHandler = s1
+		c.PlacementFailureCacheTTL = time.Minute
+	})
+	defer cleanupC1()
+
+	waitTilNodeReady(c1, t)
+
+	// Both allocations of the job require a driver the node lacks
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Driver = "no_such_driver"
+	newAlloc := func() *structs.Allocation {
+		alloc := mock.Alloc()
+		alloc.NodeID = c1.Node().ID
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.ClientStatus = structs.AllocClientStatusPending
+		return alloc
+	}
+	alloc1, alloc2 := newAlloc(), newAlloc()
+
+	state := s1.State()
+	require.NoError(t, state.UpsertJob(structs.MsgTypeTestSetup, 100, job))
+	require.NoError(t, state.UpsertJobSummary(101, mock.JobSummary(job.ID)))
+	require.NoError(t, state.UpsertAllocs(structs.MsgTypeTestSetup, 102,
+		[]*structs.Allocation{alloc1, alloc2}))
+
+	// The first allocation fails to set up and its failure is remembered
+	c1.runAllocs(&allocUpdates{pulled: map[string]*structs.Allocation{alloc1.ID: alloc1}})
+	failure := c1.placementFailures.lookup(alloc2)
+	require.NotNil(t, failure)
+	require.Equal(t, structs.PlacementFailureDriverUnavailable, failure.Code)
+
+	// The second allocation is rejected without being run
+	c1.runAllocs(&allocUpdates{pulled: map[string]*structs.Allocation{
+		alloc1.ID: alloc1,
+		alloc2.ID: alloc2,
+	}})
+
+	testutil.WaitForResult(func() (bool, error) {
+		alloc, err := s1.State().AllocByID(nil, alloc2.ID)
+		if err != nil {
+			return false, err
+		}
+		if alloc.ClientStatus != structs.AllocClientStatusFailed {
+			return false, fmt.Errorf("expected failed client status, got %v", alloc.ClientStatus)
+		}
+		for _, ts := range alloc.TaskStates {
+			for _, event := range ts.Events {
+				if event.Details[structs.TaskEventDetailPlacementFailure] == structs.PlacementFailureDriverUnavailable {
+					return true, nil
+				}
+			}
+		}
+		return false, fmt.Errorf("expected placement failure task event")
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	c1.allocLock.RLock()
+	require.Nil(t, c1.allocs[alloc2.ID])
+	c1.allocLock.RUnlock()
+
+	c1.invalidAllocsLock.Lock()
+	defer c1.invalidAllocsLock.Unlock()
+	require.Contains(t, c1.invalidAllocs, alloc2.ID)
+}
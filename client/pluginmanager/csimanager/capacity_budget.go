@@ -0,0 +1,180 @@
+package csimanager
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ErrCapacityBudgetExceeded is returned by CapacityBudget.Reserve when
+// mounting a volume would exceed the total capacity of the CSI volumes the
+// node may have mounted. The mount can be retried once other volumes have
+// been unmounted.
+var ErrCapacityBudgetExceeded = errors.New("node has reached its limit of mounted CSI volume capacity")
+
+// ErrCapacityUnknown is returned by CapacityBudget.Reserve for a volume whose
+// capacity can't be bounded, because it has neither a capacity nor a
+// requested maximum capacity.
+var ErrCapacityUnknown = errors.New("CSI volume capacity is unknown")
+
+// capacityBudgetError is returned when a volume is rejected by the
+// CapacityBudget. It is recoverable, and wraps ErrCapacityBudgetExceeded so
+// callers can check for it with errors.Is.
+type capacityBudgetError struct {
+	volumeID  string
+	requested int64
+	used      int64
+	max       int64
+}
+
+func (e *capacityBudgetError) Error() string {
+	return fmt.Sprintf("%v: volume %s needs %d GB with %d of %d GB mounted",
+		ErrCapacityBudgetExceeded, e.volumeID, bytesToGB(e.requested), bytesToGB(e.used), bytesToGB(e.max))
+}
+
+func (e *capacityBudgetError) Unwrap() error {
+	return ErrCapacityBudgetExceeded
+}
+
+func (e *capacityBudgetError) IsRecoverable() bool {
+	return true
+}
+
+// CapacityBudget bounds the total capacity of the CSI volumes mounted on the
+// node, and is shared by the allocations mounting them. A volume mounted by
+// several allocations counts against the budget once. A nil CapacityBudget
+// doesn't limit capacity.
+type CapacityBudget struct {
+	max     int64
+	used    int64
+	volumes map[string]*budgetedVolume
+	lock    sync.Mutex
+}
+
+// budgetedVolume is a volume counted against the CapacityBudget, and the
+// allocations that reserved it.
+type budgetedVolume struct {
+	bytes  int64
+	allocs map[string]struct{}
+}
+
+// NewCapacityBudget returns a CapacityBudget limiting the mounted capacity to
+// maxGB gigabytes, or nil if maxGB isn't positive.
+func NewCapacityBudget(maxGB int) *CapacityBudget {
+	if maxGB <= 0 {
+		return nil
+	}
+	return &CapacityBudget{
+		max:     int64(maxGB) * bytesPerGB,
+		volumes: make(map[string]*budgetedVolume),
+	}
+}
+
+// Reserve counts the volume's capacity against the budget for the allocation,
+// or returns a capacityBudgetError if it would exceed the budget. Volumes
+// without a capacity are counted by their requested maximum capacity, and
+// volumes without either are rejected with ErrCapacityUnknown, since they
+// could be of any size.
+func (b *CapacityBudget) Reserve(allocID string, vol *structs.CSIVolume) error {
+	if b == nil {
+		return nil
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	key := vol.Namespace + "/" + vol.ID
+	if v, ok := b.volumes[key]; ok {
+		v.allocs[allocID] = struct{}{}
+		return nil
+	}
+
+	bytes := volumeCapacity(vol)
+	if bytes <= 0 {
+		metrics.IncrCounterWithLabels([]string{"client", "csi", "limit_rejections"}, 1,
+			[]metrics.Label{{Name: "limit", Value: "mounted_capacity"}})
+		return fmt.Errorf("%w: volume %s has no capacity or requested maximum capacity", ErrCapacityUnknown, vol.ID)
+	}
+	if b.used+bytes > b.max {
+		metrics.IncrCounterWithLabels([]string{"client", "csi", "limit_rejections"}, 1,
+			[]metrics.Label{{Name: "limit", Value: "mounted_capacity"}})
+		return &capacityBudgetError{volumeID: vol.ID, requested: bytes, used: b.used, max: b.max}
+	}
+
+	b.used += bytes
+	b.volumes[key] = &budgetedVolume{
+		bytes:  bytes,
+		allocs: map[string]struct{}{allocID: {}},
+	}
+	return nil
+}
+
+// Release stops counting the allocation's volumes against the budget, once no
+// other allocation has reserved them.
+func (b *CapacityBudget) Release(allocID string) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for key, v := range b.volumes {
+		delete(v.allocs, allocID)
+		if len(v.allocs) == 0 {
+			b.used -= v.bytes
+			delete(b.volumes, key)
+		}
+	}
+}
+
+// ReleaseVolume stops counting the volume against the budget for the
+// allocation, once no other allocation has reserved it.
+func (b *CapacityBudget) ReleaseVolume(allocID string, vol *structs.CSIVolume) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	key := vol.Namespace + "/" + vol.ID
+	v, ok := b.volumes[key]
+	if !ok {
+		return
+	}
+	delete(v.allocs, allocID)
+	if len(v.allocs) == 0 {
+		b.used -= v.bytes
+		delete(b.volumes, key)
+	}
+}
+
+// Used returns the capacity in bytes counted against the budget.
+func (b *CapacityBudget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.used
+}
+
+const bytesPerGB = 1024 * 1024 * 1024
+
+func bytesToGB(bytes int64) int64 {
+	return bytes / bytesPerGB
+}
+
+// volumeCapacity returns the volume's capacity, or the most it may grow to if
+// its capacity isn't known, or 0 if it's unbounded.
+func volumeCapacity(vol *structs.CSIVolume) int64 {
+	if vol.Capacity > 0 {
+		return vol.Capacity
+	}
+	return vol.RequestedCapacityMax
+}
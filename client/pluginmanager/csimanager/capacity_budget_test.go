@@ -0,0 +1,72 @@
+package csimanager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func testBudgetVolume(id string, gb int64) *structs.CSIVolume {
+	vol := structs.NewCSIVolume(id, 0)
+	vol.Namespace = structs.DefaultNamespace
+	vol.Capacity = gb * bytesPerGB
+	return vol
+}
+
+func TestCapacityBudget(t *testing.T) {
+	b := NewCapacityBudget(10)
+
+	require.NoError(t, b.Reserve("alloc1", testBudgetVolume("vol0", 4)))
+	require.NoError(t, b.Reserve("alloc1", testBudgetVolume("vol1", 4)))
+	require.Equal(t, int64(8*bytesPerGB), b.Used())
+
+	// Volumes shared between allocations count once
+	require.NoError(t, b.Reserve("alloc2", testBudgetVolume("vol1", 4)))
+	require.Equal(t, int64(8*bytesPerGB), b.Used())
+
+	// Volumes crossing the budget are rejected with a recoverable error
+	err := b.Reserve("alloc2", testBudgetVolume("vol2", 4))
+	require.True(t, errors.Is(err, ErrCapacityBudgetExceeded))
+	require.True(t, structs.IsRecoverable(err))
+	require.Equal(t, int64(8*bytesPerGB), b.Used())
+
+	// Volumes without a known capacity count their requested maximum
+	vol := testBudgetVolume("vol3", 0)
+	vol.RequestedCapacityMin = 1 * bytesPerGB
+	vol.RequestedCapacityMax = 2 * bytesPerGB
+	require.NoError(t, b.Reserve("alloc2", vol))
+	require.Equal(t, int64(10*bytesPerGB), b.Used())
+
+	// Volumes without a capacity or a requested maximum can't be bounded
+	vol = testBudgetVolume("vol4", 0)
+	vol.RequestedCapacityMin = 1 * bytesPerGB
+	err = b.Reserve("alloc2", vol)
+	require.True(t, errors.Is(err, ErrCapacityUnknown))
+	require.False(t, structs.IsRecoverable(err))
+	require.Equal(t, int64(10*bytesPerGB), b.Used())
+
+	// A shared volume stays counted until every allocation releases it
+	b.Release("alloc1")
+	require.Equal(t, int64(6*bytesPerGB), b.Used())
+	require.NoError(t, b.Reserve("alloc2", testBudgetVolume("vol2", 4)))
+	require.Equal(t, int64(10*bytesPerGB), b.Used())
+
+	// Releasing a single volume keeps the allocation's other volumes
+	b.ReleaseVolume("alloc2", testBudgetVolume("vol2", 4))
+	require.Equal(t, int64(6*bytesPerGB), b.Used())
+	b.ReleaseVolume("alloc2", testBudgetVolume("unknown", 4))
+	require.Equal(t, int64(6*bytesPerGB), b.Used())
+
+	b.Release("alloc2")
+	require.Zero(t, b.Used())
+
+	// A nil budget doesn't limit capacity
+	b = NewCapacityBudget(0)
+	require.Nil(t, b)
+	require.NoError(t, b.Reserve("alloc1", testBudgetVolume("vol0", 1024)))
+	b.Release("alloc1")
+	b.ReleaseVolume("alloc1", testBudgetVolume("vol0", 1024))
+	require.Zero(t, b.Used())
+}
@@ -0,0 +1,32 @@
+package csimanager
+
+import "sync/atomic"
+
+// ClaimBatchSupport records whether the servers support batched volume
+// claims. It's shared by the CSI hooks of every allocation on the node, so
+// that once the servers reject a batch, the claims of every allocation are
+// sent one at a time without trying a batch first. Servers upgraded to
+// support batches are only used once the client restarts. A nil
+// ClaimBatchSupport always tries batches.
+type ClaimBatchSupport struct {
+	unsupported int32
+}
+
+// NewClaimBatchSupport returns a ClaimBatchSupport assuming the servers
+// support batched claims until one is rejected.
+func NewClaimBatchSupport() *ClaimBatchSupport {
+	return &ClaimBatchSupport{}
+}
+
+// Supported returns whether batched claims should be tried.
+func (s *ClaimBatchSupport) Supported() bool {
+	return s == nil || atomic.LoadInt32(&s.unsupported) == 0
+}
+
+// SetUnsupported records that the servers rejected a batch of claims.
+func (s *ClaimBatchSupport) SetUnsupported() {
+	if s == nil {
+		return
+	}
+	atomic.StoreInt32(&s.unsupported, 1)
+}
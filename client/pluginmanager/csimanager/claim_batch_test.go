@@ -0,0 +1,19 @@
+package csimanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimBatchSupport(t *testing.T) {
+	s := NewClaimBatchSupport()
+	require.True(t, s.Supported())
+	s.SetUnsupported()
+	require.False(t, s.Supported())
+
+	// A nil ClaimBatchSupport always tries batches
+	var n *ClaimBatchSupport
+	n.SetUnsupported()
+	require.True(t, n.Supported())
+}
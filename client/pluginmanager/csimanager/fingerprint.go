@@ -36,6 +36,11 @@ type pluginFingerprinter struct {
 	// is started. Removing this bool will require storing a cache of recent successful
 	// results that can be used by subscribers of the `hadFirstSuccessfulFingerprintCh`.
 	requiresStaging bool
+
+	// accessibleTopology is the topology the node plugin reported for the
+	// node on its first successful fingerprint, which doesn't change while
+	// the plugin runs.
+	accessibleTopology *structs.CSITopology
 }
 
 func (p *pluginFingerprinter) fingerprint(ctx context.Context) *structs.CSIInfo {
@@ -74,6 +79,7 @@ func (p *pluginFingerprinter) fingerprint(ctx context.Context) *structs.CSIInfo
 			p.hadFirstSuccessfulFingerprint = true
 			if p.fingerprintNode {
 				p.requiresStaging = info.NodeInfo.RequiresNodeStageVolume
+				p.accessibleTopology = info.NodeInfo.AccessibleTopology.Copy()
 			}
 			close(p.hadFirstSuccessfulFingerprintCh)
 		}
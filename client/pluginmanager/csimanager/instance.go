@@ -20,6 +20,12 @@ type instanceManager struct {
 
 	updater UpdateNodeCSIInfoFunc
 
+	// allocsRestoredCh is closed once the client has restored its
+	// allocations, after which liveAllocs can be used to find the volume
+	// mounts of allocations that no longer exist. Either may be nil.
+	allocsRestoredCh <-chan struct{}
+	liveAllocs       LiveAllocsFunc
+
 	shutdownCtx         context.Context
 	shutdownCtxCancelFn context.CancelFunc
 	shutdownCh          chan struct{}
@@ -40,10 +46,19 @@ type instanceManager struct {
 	volumeManager        *volumeManager
 	volumeManagerSetupCh chan struct{}
 
+	// mountLimiter is shared by the volume managers of all node plugins to
+	// bound the mount paths published on the node
+	mountLimiter *mountLimiter
+
+	// health is shared by the volume managers of all node plugins to track
+	// which plugins are degraded
+	health *pluginHealth
+
 	client csi.CSIPlugin
 }
 
-func newInstanceManager(logger hclog.Logger, eventer TriggerNodeEvent, updater UpdateNodeCSIInfoFunc, p *dynamicplugins.PluginInfo) *instanceManager {
+func newInstanceManager(logger hclog.Logger, eventer TriggerNodeEvent, updater UpdateNodeCSIInfoFunc,
+	allocsRestoredCh <-chan struct{}, liveAllocs LiveAllocsFunc, p *dynamicplugins.PluginInfo) *instanceManager {
 	ctx, cancelFn := context.WithCancel(context.Background())
 	logger = logger.Named(p.Name)
 	return &instanceManager{
@@ -52,6 +67,9 @@ func newInstanceManager(logger hclog.Logger, eventer TriggerNodeEvent, updater U
 		info:    p,
 		updater: updater,
 
+		allocsRestoredCh: allocsRestoredCh,
+		liveAllocs:       liveAllocs,
+
 		fp: &pluginFingerprinter{
 			logger:                          logger.Named("fingerprinter"),
 			info:                            p,
@@ -97,10 +115,33 @@ func (i *instanceManager) setupVolumeManager() {
 		return
 	case <-i.fp.hadFirstSuccessfulFingerprintCh:
 		i.volumeManager = newVolumeManager(i.logger, i.eventer, i.client, i.mountPoint, i.containerMountPoint, i.fp.requiresStaging)
+		i.volumeManager.mountLimiter = i.mountLimiter
+		i.volumeManager.health = i.health
+		i.volumeManager.pluginID = i.info.Name
+		i.volumeManager.topology = i.fp.accessibleTopology
 		i.logger.Debug("volume manager setup complete")
 		close(i.volumeManagerSetupCh)
+	}
+
+	i.reconcileLeakedMounts()
+}
+
+// reconcileLeakedMounts unpublishes volumes mounted for allocations that no
+// longer exist on the client, once the client has restored its allocations.
+func (i *instanceManager) reconcileLeakedMounts() {
+	if i.allocsRestoredCh == nil || i.liveAllocs == nil {
 		return
 	}
+
+	select {
+	case <-i.shutdownCtx.Done():
+		return
+	case <-i.allocsRestoredCh:
+	}
+
+	if err := i.volumeManager.reconcileAllocMounts(i.shutdownCtx, i.liveAllocs); err != nil {
+		i.logger.Warn("failed to clean up leaked volume mounts", "error", err)
+	}
 }
 
 // VolumeMounter returns the volume manager that is configured for the given plugin
@@ -78,3 +78,33 @@ func TestInstanceManager_Shutdown(t *testing.T) {
 	}, 1*time.Second, 10*time.Millisecond)
 
 }
+
+// TestInstanceManager_VolumeMounterTopology asserts the volume mounter of a
+// node plugin reports the topology of its first successful fingerprint.
+func TestInstanceManager_VolumeMounterTopology(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	client, im := setupTestNodeInstanceManager(t)
+	im.info.Type = dynamicplugins.PluginTypeCSINode
+	im.shutdownCtx = ctx
+	im.volumeManagerSetupCh = make(chan struct{})
+
+	client.NextPluginGetCapabilitiesResponse = &csi.PluginCapabilitySet{}
+	client.NextNodeGetInfoResponse = &csi.NodeGetInfoResponse{
+		NodeID: "foo",
+		AccessibleTopology: &csi.Topology{
+			Segments: map[string]string{"zone": "us-east-1a"},
+		},
+	}
+	client.NextNodeGetCapabilitiesResponse = &csi.NodeCapabilitySet{}
+	client.NextPluginProbeResponse = true
+
+	go im.setupVolumeManager()
+	im.fp.fingerprint(ctx)
+
+	mounter, err := im.VolumeMounter(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &structs.CSITopology{
+		Segments: map[string]string{"zone": "us-east-1a"},
+	}, mounter.NodeTopology())
+}
@@ -2,12 +2,24 @@ package csimanager
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/hashicorp/nomad/client/pluginmanager"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
+var (
+	// ErrPluginNotRegistered is returned by MounterForPlugin when the plugin
+	// has never registered on this node, so waiting for it won't help.
+	ErrPluginNotRegistered = errors.New("plugin has not registered on this node")
+
+	// ErrPluginUnavailable is returned by MounterForPlugin when the plugin
+	// has registered on this node but is not currently running, such as
+	// while its allocation restarts.
+	ErrPluginUnavailable = errors.New("plugin is temporarily unavailable on this node")
+)
+
 type MountInfo struct {
 	Source   string
 	IsDevice bool
@@ -43,6 +55,15 @@ func (u *UsageOptions) ToFS() string {
 type VolumeMounter interface {
 	MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *UsageOptions, publishContext map[string]string) (*MountInfo, error)
 	UnmountVolume(ctx context.Context, volID, remoteID, allocID string, usageOpts *UsageOptions) error
+
+	// RestoreVolume tracks the mount of a volume made for the allocation
+	// before the client restarted as in use, without calling the plugin. It
+	// returns an error if the volume is no longer mounted there.
+	RestoreVolume(vol *structs.CSIVolume, alloc *structs.Allocation, usageOpts *UsageOptions, mountInfo *MountInfo) error
+
+	// NodeTopology returns the accessible topology the plugin fingerprinted
+	// for the node, or nil if it didn't report one.
+	NodeTopology() *structs.CSITopology
 }
 
 type Manager interface {
@@ -50,9 +71,27 @@ type Manager interface {
 	PluginManager() pluginmanager.PluginManager
 
 	// MounterForPlugin returns a VolumeMounter for the plugin ID associated
-	// with the volume.	Returns an error if this plugin isn't registered.
+	// with the volume. Returns an error wrapping ErrPluginNotRegistered or
+	// ErrPluginUnavailable if this plugin isn't registered.
 	MounterForPlugin(ctx context.Context, pluginID string) (VolumeMounter, error)
 
+	// DegradedPlugins returns the IDs of the node plugins that are running
+	// but have recently failed enough volume operations that callers should
+	// reduce the load they put on them. A degraded plugin recovers once it
+	// stops failing for a while.
+	DegradedPlugins() []string
+
+	// NodeTopologies returns the accessible topology each of the node
+	// plugins reported for the node on its latest fingerprint, by plugin ID.
+	NodeTopologies() map[string]*structs.CSITopology
+
+	// ValidateVolumes reports whether each CSI volume requested by the task
+	// group of the allocation could be claimed and mounted on this node,
+	// without claiming or mounting it. vols are the volumes of the requests,
+	// keyed by request name. The result maps each request name to nil if
+	// its volume could be used, or to the reason it can't.
+	ValidateVolumes(alloc *structs.Allocation, vols map[string]*structs.CSIVolume) map[string]error
+
 	// Shutdown shuts down the Manager and unmounts any locally attached volumes.
 	Shutdown()
 }
@@ -21,12 +21,34 @@ const defaultPluginResyncPeriod = 30 * time.Second
 type UpdateNodeCSIInfoFunc func(string, *structs.CSIInfo)
 type TriggerNodeEvent func(*structs.NodeEvent)
 
+// LiveAllocsFunc returns the IDs of the allocations known to the client
+type LiveAllocsFunc func() map[string]struct{}
+
 type Config struct {
 	Logger                hclog.Logger
 	DynamicRegistry       dynamicplugins.Registry
 	UpdateNodeCSIInfoFunc UpdateNodeCSIInfoFunc
 	PluginResyncPeriod    time.Duration
 	TriggerNodeEvent      TriggerNodeEvent
+
+	// AllocsRestoredCh is closed once the client has restored its
+	// allocations from state. Node plugins wait for it before unmounting
+	// volumes left behind by allocations that no longer exist. If nil,
+	// leaked mounts are not reconciled.
+	AllocsRestoredCh <-chan struct{}
+
+	// LiveAllocs returns the allocations whose volume mounts must be kept
+	// when reconciling leaked mounts.
+	LiveAllocs LiveAllocsFunc
+
+	// MaxNodeMounts is the maximum number of volume mount paths published
+	// on the node at once. Zero doesn't limit mounts.
+	MaxNodeMounts int
+
+	// DegradedPluginMountDelay is the minimum time between the volume mounts
+	// of a degraded node plugin, which are made one at a time across the
+	// node. Zero doesn't throttle the mounts of degraded plugins.
+	DegradedPluginMountDelay time.Duration
 }
 
 // New returns a new PluginManager that will handle managing CSI plugins from
@@ -44,9 +66,17 @@ func New(config *Config) Manager {
 		registry:  config.DynamicRegistry,
 		instances: make(map[string]map[string]*instanceManager),
 
+		seenNodePlugins: make(map[string]struct{}),
+		nodePlugins:     make(map[string]*structs.CSIInfo),
+
 		updateNodeCSIInfoFunc: config.UpdateNodeCSIInfoFunc,
 		pluginResyncPeriod:    config.PluginResyncPeriod,
 
+		allocsRestoredCh: config.AllocsRestoredCh,
+		liveAllocs:       config.LiveAllocs,
+		mountLimiter:     newMountLimiter(config.MaxNodeMounts),
+		health:           newPluginHealth(config.DegradedPluginMountDelay),
+
 		shutdownCtx:         ctx,
 		shutdownCtxCancelFn: cancelFn,
 		shutdownCh:          make(chan struct{}),
@@ -65,6 +95,29 @@ type csiManager struct {
 
 	updateNodeCSIInfoFunc UpdateNodeCSIInfoFunc
 
+	// allocsRestoredCh and liveAllocs are handed to node plugin instances
+	// to reconcile volume mounts leaked by allocations that no longer exist
+	allocsRestoredCh <-chan struct{}
+	liveAllocs       LiveAllocsFunc
+
+	// mountLimiter bounds the volume mount paths published on the node
+	// across all node plugins
+	mountLimiter *mountLimiter
+
+	// health tracks which node plugins are degraded from the outcome of
+	// their volume operations
+	health *pluginHealth
+
+	// seenNodePlugins is the set of node plugins that have registered on
+	// this node since the client started, whether or not they are running
+	seenNodePlugins map[string]struct{}
+	seenLock        sync.RWMutex
+
+	// nodePlugins is the latest fingerprint of each running node plugin,
+	// used to validate volume requests without a plugin RPC
+	nodePlugins    map[string]*structs.CSIInfo
+	nodePluginLock sync.RWMutex
+
 	shutdownCtx         context.Context
 	shutdownCtxCancelFn context.CancelFunc
 	shutdownCh          chan struct{}
@@ -77,17 +130,79 @@ func (c *csiManager) PluginManager() pluginmanager.PluginManager {
 func (c *csiManager) MounterForPlugin(ctx context.Context, pluginID string) (VolumeMounter, error) {
 	nodePlugins, hasAnyNodePlugins := c.instances["csi-node"]
 	if !hasAnyNodePlugins {
-		return nil, fmt.Errorf("no storage node plugins found")
+		return nil, &pluginNotFoundError{
+			msg:    "no storage node plugins found",
+			reason: c.pluginNotFoundErr(pluginID),
+		}
 	}
 
 	mgr, hasPlugin := nodePlugins[pluginID]
 	if !hasPlugin {
-		return nil, fmt.Errorf("plugin %s for type csi-node not found", pluginID)
+		return nil, &pluginNotFoundError{
+			msg:    fmt.Sprintf("plugin %s for type csi-node not found", pluginID),
+			reason: c.pluginNotFoundErr(pluginID),
+		}
 	}
 
 	return mgr.VolumeMounter(ctx)
 }
 
+func (c *csiManager) DegradedPlugins() []string {
+	return c.health.degradedPlugins()
+}
+
+func (c *csiManager) NodeTopologies() map[string]*structs.CSITopology {
+	c.nodePluginLock.RLock()
+	defer c.nodePluginLock.RUnlock()
+
+	topologies := make(map[string]*structs.CSITopology, len(c.nodePlugins))
+	for name, info := range c.nodePlugins {
+		if info == nil || info.NodeInfo == nil || info.NodeInfo.AccessibleTopology == nil {
+			continue
+		}
+		topologies[name] = info.NodeInfo.AccessibleTopology.Copy()
+	}
+	return topologies
+}
+
+// pluginNotFoundError is returned by MounterForPlugin when a node plugin has
+// no running instance manager. Its reason is either ErrPluginNotRegistered or
+// ErrPluginUnavailable, which callers can check with errors.Is without the
+// reason changing the message returned to RPC callers.
+type pluginNotFoundError struct {
+	msg    string
+	reason error
+}
+
+func (e *pluginNotFoundError) Error() string {
+	return e.msg
+}
+
+func (e *pluginNotFoundError) Unwrap() error {
+	return e.reason
+}
+
+// pluginNotFoundErr returns the error for a node plugin that has no running
+// instance manager, depending on whether the plugin has ever registered on
+// this node.
+func (c *csiManager) pluginNotFoundErr(pluginID string) error {
+	c.seenLock.RLock()
+	_, seen := c.seenNodePlugins[pluginID]
+	c.seenLock.RUnlock()
+	if seen {
+		return ErrPluginUnavailable
+	}
+
+	// The plugin may have registered but not yet been picked up by the run
+	// loop
+	for _, plugin := range c.registry.ListPlugins("csi-node") {
+		if plugin.Name == pluginID {
+			return ErrPluginUnavailable
+		}
+	}
+	return ErrPluginNotRegistered
+}
+
 // Run starts a plugin manager and should return early
 func (c *csiManager) Run() {
 	go c.runLoop()
@@ -169,7 +284,17 @@ func (c *csiManager) ensureInstance(plugin *dynamicplugins.PluginInfo) {
 	instances := c.instancesForType(ptype)
 	if _, ok := instances[name]; !ok {
 		c.logger.Debug("detected new CSI plugin", "name", name, "type", ptype)
-		mgr := newInstanceManager(c.logger, c.eventer, c.updateNodeCSIInfoFunc, plugin)
+		updater := c.updateNodeCSIInfoFunc
+		if ptype == "csi-node" {
+			c.seenLock.Lock()
+			c.seenNodePlugins[name] = struct{}{}
+			c.seenLock.Unlock()
+			updater = c.updateNodePluginInfo
+		}
+		mgr := newInstanceManager(c.logger, c.eventer, updater,
+			c.allocsRestoredCh, c.liveAllocs, plugin)
+		mgr.mountLimiter = c.mountLimiter
+		mgr.health = c.health
 		instances[name] = mgr
 		mgr.run()
 	}
@@ -186,6 +311,29 @@ func (c *csiManager) ensureNoInstance(plugin *dynamicplugins.PluginInfo) {
 		mgr.shutdown()
 		delete(instances, name)
 	}
+	if ptype == "csi-node" {
+		c.nodePluginLock.Lock()
+		delete(c.nodePlugins, name)
+		c.nodePluginLock.Unlock()
+	}
+}
+
+// updateNodePluginInfo records the fingerprint of a node plugin before
+// updating the node with it.
+func (c *csiManager) updateNodePluginInfo(name string, info *structs.CSIInfo) {
+	c.nodePluginLock.Lock()
+	c.nodePlugins[name] = info.Copy()
+	c.nodePluginLock.Unlock()
+
+	c.updateNodeCSIInfoFunc(name, info)
+}
+
+// nodePluginInfo returns the latest fingerprint of a running node plugin, or
+// nil if the plugin isn't running.
+func (c *csiManager) nodePluginInfo(name string) *structs.CSIInfo {
+	c.nodePluginLock.RLock()
+	defer c.nodePluginLock.RUnlock()
+	return c.nodePlugins[name]
 }
 
 // Get the instance managers table for a specific plugin type,
@@ -1,6 +1,7 @@
 package csimanager
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -159,3 +160,70 @@ func TestManager_MultiplePlugins(t *testing.T) {
 		return !ok
 	}, 5*time.Second, 10*time.Millisecond)
 }
+
+func TestManager_MounterForPlugin_NotFound(t *testing.T) {
+	registry := dynamicplugins.NewRegistry(nil,
+		map[string]dynamicplugins.PluginDispenser{
+			"csi-node": func(*dynamicplugins.PluginInfo) (interface{}, error) {
+				return nil, nil
+			},
+		})
+	defer registry.Shutdown()
+
+	cfg := &Config{
+		Logger:                testlog.HCLogger(t),
+		DynamicRegistry:       registry,
+		UpdateNodeCSIInfoFunc: func(string, *structs.CSIInfo) {},
+	}
+	pm := New(cfg).(*csiManager)
+
+	// A plugin that has never registered can't be waited for
+	_, err := pm.MounterForPlugin(context.Background(), "my-plugin")
+	require.ErrorIs(t, err, ErrPluginNotRegistered)
+
+	// A plugin in the registry that the run loop hasn't picked up yet is
+	// only temporarily unavailable
+	require.NoError(t, registry.RegisterPlugin(&dynamicplugins.PluginInfo{
+		Name:           "my-plugin",
+		Type:           "csi-node",
+		ConnectionInfo: &dynamicplugins.PluginConnectionInfo{},
+	}))
+	_, err = pm.MounterForPlugin(context.Background(), "my-plugin")
+	require.ErrorIs(t, err, ErrPluginUnavailable)
+
+	// Once it deregisters, a plugin the run loop never picked up is no
+	// longer registered
+	require.NoError(t, registry.DeregisterPlugin("csi-node", "my-plugin"))
+	_, err = pm.MounterForPlugin(context.Background(), "my-plugin")
+	require.ErrorIs(t, err, ErrPluginNotRegistered)
+
+	// A plugin the manager has seen stays temporarily unavailable after it
+	// deregisters, such as while its allocation restarts
+	pm.seenNodePlugins["my-plugin"] = struct{}{}
+	_, err = pm.MounterForPlugin(context.Background(), "my-plugin")
+	require.ErrorIs(t, err, ErrPluginUnavailable)
+}
+
+func TestManager_NodeTopologies(t *testing.T) {
+	cfg := &Config{
+		Logger:                testlog.HCLogger(t),
+		DynamicRegistry:       setupRegistry(),
+		UpdateNodeCSIInfoFunc: func(string, *structs.CSIInfo) {},
+	}
+	pm := New(cfg).(*csiManager)
+	require.Empty(t, pm.NodeTopologies())
+
+	topology := &structs.CSITopology{Segments: map[string]string{"zone": "us-east-1a"}}
+	pm.updateNodePluginInfo("with-topology", &structs.CSIInfo{
+		NodeInfo: &structs.CSINodeInfo{ID: "i-1", AccessibleTopology: topology},
+	})
+	pm.updateNodePluginInfo("without-topology", &structs.CSIInfo{
+		NodeInfo: &structs.CSINodeInfo{ID: "i-1"},
+	})
+
+	// The topologies are those of the latest fingerprints, and are copies
+	topologies := pm.NodeTopologies()
+	require.Equal(t, map[string]*structs.CSITopology{"with-topology": topology}, topologies)
+	topologies["with-topology"].Segments["zone"] = "us-east-1b"
+	require.Equal(t, "us-east-1a", pm.NodeTopologies()["with-topology"].Segments["zone"])
+}
@@ -0,0 +1,93 @@
+package csimanager
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+// ErrNodeMountLimit is returned by MountVolume when the node already has the
+// maximum number of CSI volume mount paths published. The mount can be
+// retried once other volumes have been unmounted.
+var ErrNodeMountLimit = errors.New("node has reached its limit of published CSI volume mount paths")
+
+// mountLimitError is returned when a mount is rejected by the mountLimiter.
+// It is recoverable, and wraps ErrNodeMountLimit so callers can check for it
+// with errors.Is.
+type mountLimitError struct {
+	max int
+}
+
+func (e *mountLimitError) Error() string {
+	return fmt.Sprintf("%v (%d)", ErrNodeMountLimit, e.max)
+}
+
+func (e *mountLimitError) Unwrap() error {
+	return ErrNodeMountLimit
+}
+
+func (e *mountLimitError) IsRecoverable() bool {
+	return true
+}
+
+// mountLimiter bounds the number of volume mount paths published on the node
+// across all of its node plugins. A nil mountLimiter doesn't limit mounts.
+type mountLimiter struct {
+	max    int
+	mounts map[string]struct{}
+	lock   sync.Mutex
+}
+
+func newMountLimiter(max int) *mountLimiter {
+	return &mountLimiter{
+		max:    max,
+		mounts: make(map[string]struct{}),
+	}
+}
+
+// reserve counts the mount path against the limit. It returns whether the
+// path was newly reserved, as a path may be mounted again while it is still
+// published, or a mountLimitError if the node is at its limit.
+func (l *mountLimiter) reserve(path string) (bool, error) {
+	if l == nil {
+		return false, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, ok := l.mounts[path]; ok {
+		return false, nil
+	}
+	if l.max > 0 && len(l.mounts) >= l.max {
+		metrics.IncrCounterWithLabels([]string{"client", "csi", "limit_rejections"}, 1,
+			[]metrics.Label{{Name: "limit", Value: "node_mounts"}})
+		return false, &mountLimitError{max: l.max}
+	}
+	l.mounts[path] = struct{}{}
+	return true, nil
+}
+
+// release stops counting the mount path against the limit.
+func (l *mountLimiter) release(path string) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.mounts, path)
+}
+
+// count returns the number of mount paths counted against the limit.
+func (l *mountLimiter) count() int {
+	if l == nil {
+		return 0
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.mounts)
+}
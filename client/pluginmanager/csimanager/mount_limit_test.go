@@ -0,0 +1,47 @@
+package csimanager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountLimiter(t *testing.T) {
+	l := newMountLimiter(2)
+
+	reserved, err := l.reserve("a")
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// Paths that are already published don't count again
+	reserved, err = l.reserve("a")
+	require.NoError(t, err)
+	require.False(t, reserved)
+
+	reserved, err = l.reserve("b")
+	require.NoError(t, err)
+	require.True(t, reserved)
+	require.Equal(t, 2, l.count())
+
+	// Mounts over the limit are rejected with a recoverable error
+	reserved, err = l.reserve("c")
+	require.False(t, reserved)
+	require.True(t, errors.Is(err, ErrNodeMountLimit))
+	require.True(t, structs.IsRecoverable(err))
+
+	// Once a path is released another can be mounted
+	l.release("a")
+	reserved, err = l.reserve("c")
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// A nil limiter doesn't limit mounts
+	var unlimited *mountLimiter
+	reserved, err = unlimited.reserve("a")
+	require.NoError(t, err)
+	require.False(t, reserved)
+	unlimited.release("a")
+	require.Zero(t, unlimited.count())
+}
@@ -0,0 +1,154 @@
+package csimanager
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// OpScheduler bounds the number of CSI volume operations, such as claims and
+// mounts, that run concurrently on the node. It is shared by all of the
+// allocations on the node.
+//
+// When the limit is reached, waiting operations are started in order of
+// priority, highest first, and then in the order they were requested. If
+// prioritization is disabled they are started strictly in request order.
+type OpScheduler struct {
+	// limit is the maximum number of concurrent operations. A limit of zero
+	// or less does not bound operations.
+	limit int
+
+	// prioritize orders waiting operations by priority before request order
+	prioritize bool
+
+	running int
+	seq     uint64
+	waiting opQueue
+	lock    sync.Mutex
+}
+
+// NewOpScheduler returns an OpScheduler that runs at most limit operations
+// concurrently.
+func NewOpScheduler(limit int, prioritize bool) *OpScheduler {
+	return &OpScheduler{
+		limit:      limit,
+		prioritize: prioritize,
+	}
+}
+
+// Acquire blocks until an operation with the given priority may run or the
+// context is done. The returned function must be called once the operation
+// completes. A nil OpScheduler does not bound operations.
+func (s *OpScheduler) Acquire(ctx context.Context, priority int) (func(), error) {
+	if s == nil || s.limit <= 0 {
+		return func() {}, nil
+	}
+
+	s.lock.Lock()
+	if s.running < s.limit && s.waiting.Len() == 0 {
+		s.running++
+		s.lock.Unlock()
+		return s.releaseFunc(), nil
+	}
+
+	s.seq++
+	op := &waitingOp{
+		priority: priority,
+		seq:      s.seq,
+		ready:    make(chan struct{}),
+	}
+	if !s.prioritize {
+		op.priority = 0
+	}
+	heap.Push(&s.waiting, op)
+	s.lock.Unlock()
+
+	select {
+	case <-op.ready:
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// The operation may have been started while the context was canceled,
+	// in which case its slot is handed on.
+	select {
+	case <-op.ready:
+		s.running--
+		s.dispatchLocked()
+	default:
+		heap.Remove(&s.waiting, op.index)
+	}
+	return nil, ctx.Err()
+}
+
+// releaseFunc returns a function that releases a running operation's slot
+// exactly once.
+func (s *OpScheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			s.running--
+			s.dispatchLocked()
+		})
+	}
+}
+
+// dispatchLocked starts waiting operations while there is capacity. The lock
+// must be held.
+func (s *OpScheduler) dispatchLocked() {
+	for s.running < s.limit && s.waiting.Len() > 0 {
+		op := heap.Pop(&s.waiting).(*waitingOp)
+		s.running++
+		close(op.ready)
+	}
+}
+
+// waitingOp is an operation waiting in the OpScheduler's queue
+type waitingOp struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+
+	// index is the position of the operation in the heap
+	index int
+}
+
+// opQueue is a heap of waiting operations ordered by descending priority and
+// then ascending sequence number.
+type opQueue []*waitingOp
+
+func (q opQueue) Len() int { return len(q) }
+
+func (q opQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q opQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *opQueue) Push(x interface{}) {
+	op := x.(*waitingOp)
+	op.index = len(*q)
+	*q = append(*q, op)
+}
+
+func (q *opQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	op := old[n-1]
+	old[n-1] = nil
+	op.index = -1
+	*q = old[:n-1]
+	return op
+}
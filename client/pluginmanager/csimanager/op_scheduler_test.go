@@ -0,0 +1,157 @@
+package csimanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// waitQueued blocks until the scheduler has n operations waiting
+func waitQueued(t *testing.T, s *OpScheduler, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return s.waiting.Len() == n
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestOpScheduler_Ordering(t *testing.T) {
+	priorities := []int{10, 50, 90, 50, 10}
+
+	cases := []struct {
+		name       string
+		prioritize bool
+		expected   []string
+	}{
+		{
+			name:       "prioritized",
+			prioritize: true,
+			expected:   []string{"2-p90", "1-p50", "3-p50", "0-p10", "4-p10"},
+		},
+		{
+			name:     "fifo",
+			expected: []string{"0-p10", "1-p50", "2-p90", "3-p50", "4-p10"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewOpScheduler(1, tc.prioritize)
+
+			// Hold the only slot so every other operation queues
+			release, err := s.Acquire(context.Background(), 0)
+			require.NoError(t, err)
+
+			var lock sync.Mutex
+			var order []string
+			errCh := make(chan error, len(priorities))
+			for i, priority := range priorities {
+				name := fmt.Sprintf("%d-p%d", i, priority)
+				priority := priority
+				go func() {
+					release, err := s.Acquire(context.Background(), priority)
+					if err != nil {
+						errCh <- err
+						return
+					}
+					lock.Lock()
+					order = append(order, name)
+					lock.Unlock()
+					release()
+					errCh <- nil
+				}()
+				waitQueued(t, s, i+1)
+			}
+
+			release()
+			for range priorities {
+				require.NoError(t, <-errCh)
+			}
+			require.Equal(t, tc.expected, order)
+		})
+	}
+}
+
+func TestOpScheduler_Limit(t *testing.T) {
+	s := NewOpScheduler(2, true)
+
+	r1, err := s.Acquire(context.Background(), 0)
+	require.NoError(t, err)
+	r2, err := s.Acquire(context.Background(), 0)
+	require.NoError(t, err)
+
+	acquired := make(chan func())
+	errCh := make(chan error, 1)
+	go func() {
+		r, err := s.Acquire(context.Background(), 0)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		acquired <- r
+	}()
+	waitQueued(t, s, 1)
+
+	// Releasing twice only frees a single slot
+	r1()
+	r1()
+	var r3 func()
+	select {
+	case r3 = <-acquired:
+	case err := <-errCh:
+		t.Fatalf("failed to acquire: %v", err)
+	}
+
+	s.lock.Lock()
+	require.Equal(t, 2, s.running)
+	s.lock.Unlock()
+
+	r2()
+	r3()
+	s.lock.Lock()
+	require.Equal(t, 0, s.running)
+	s.lock.Unlock()
+}
+
+func TestOpScheduler_Canceled(t *testing.T) {
+	s := NewOpScheduler(1, true)
+
+	release, err := s.Acquire(context.Background(), 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, 100)
+		errCh <- err
+	}()
+	waitQueued(t, s, 1)
+
+	cancel()
+	require.ErrorIs(t, <-errCh, context.Canceled)
+	waitQueued(t, s, 0)
+
+	// The canceled operation does not hold the slot once it is released
+	release()
+	release, err = s.Acquire(context.Background(), 0)
+	require.NoError(t, err)
+	release()
+}
+
+func TestOpScheduler_Unlimited(t *testing.T) {
+	var nilScheduler *OpScheduler
+	release, err := nilScheduler.Acquire(context.Background(), 0)
+	require.NoError(t, err)
+	release()
+
+	s := NewOpScheduler(0, true)
+	for i := 0; i < 10; i++ {
+		_, err := s.Acquire(context.Background(), 0)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,179 @@
+package csimanager
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// pluginHealthWindow is how long a failed node plugin operation counts
+	// towards degrading the plugin.
+	pluginHealthWindow = 5 * time.Minute
+
+	// pluginHealthMaxFailures is the number of failed operations within the
+	// window after which a node plugin is degraded.
+	pluginHealthMaxFailures = 3
+
+	// pluginHealthRecoveryPeriod is how long a degraded node plugin must go
+	// without failing an operation before it is healthy again.
+	pluginHealthRecoveryPeriod = 2 * time.Minute
+)
+
+// pluginHealth tracks the outcome of the volume operations of each node
+// plugin, to report the plugins that are still running but failing often
+// enough that they are degraded. The mounts of a degraded plugin are
+// throttled across all of the allocations on the node, to reduce the load
+// put on it. A nil pluginHealth reports every plugin as healthy.
+type pluginHealth struct {
+	window       time.Duration
+	maxFailures  int
+	recoveryTime time.Duration
+
+	// mountDelay is the minimum time between the mounts of a degraded
+	// plugin, which are made one at a time. Zero doesn't throttle mounts.
+	mountDelay time.Duration
+
+	// throttles serialize the mounts of each degraded plugin
+	throttles map[string]*OpScheduler
+
+	// failures are the times of the failures of each plugin within the
+	// window, oldest first
+	failures map[string][]time.Time
+
+	// degraded are the plugins that are degraded, and the time of their
+	// latest failure
+	degraded map[string]time.Time
+
+	now  func() time.Time
+	lock sync.Mutex
+}
+
+func newPluginHealth(mountDelay time.Duration) *pluginHealth {
+	return &pluginHealth{
+		window:       pluginHealthWindow,
+		maxFailures:  pluginHealthMaxFailures,
+		recoveryTime: pluginHealthRecoveryPeriod,
+		mountDelay:   mountDelay,
+		throttles:    make(map[string]*OpScheduler),
+		failures:     make(map[string][]time.Time),
+		degraded:     make(map[string]time.Time),
+		now:          time.Now,
+	}
+}
+
+// record records the outcome of a volume operation of the plugin. Errors that
+// aren't the plugin's fault, such as the node's mount limit being reached or
+// the operation being canceled, are ignored.
+func (h *pluginHealth) record(pluginID string, err error) {
+	if h == nil || err == nil || !isPluginFailure(err) {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+	failures := append(h.pruneLocked(pluginID, now), now)
+	h.failures[pluginID] = failures
+	if len(failures) >= h.maxFailures {
+		h.degraded[pluginID] = now
+	} else if _, ok := h.degraded[pluginID]; ok {
+		h.degraded[pluginID] = now
+	}
+}
+
+// degradedPlugins returns the IDs of the degraded plugins, sorted. A degraded
+// plugin is healthy again once it has gone the recovery period without
+// failing.
+func (h *pluginHealth) degradedPlugins() []string {
+	if h == nil {
+		return nil
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+	var plugins []string
+	for pluginID := range h.degraded {
+		if h.isDegradedLocked(pluginID, now) {
+			plugins = append(plugins, pluginID)
+		}
+	}
+	sort.Strings(plugins)
+	return plugins
+}
+
+// throttleMount blocks until a mount of the plugin may start or ctx is done.
+// While the plugin is degraded its mounts start one at a time, each at least
+// the mount delay after the previous one finished. The returned function must
+// be called once the mount finishes.
+func (h *pluginHealth) throttleMount(ctx context.Context, pluginID string) (func(), error) {
+	if h == nil || h.mountDelay <= 0 {
+		return func() {}, nil
+	}
+
+	h.lock.Lock()
+	if !h.isDegradedLocked(pluginID, h.now()) {
+		h.lock.Unlock()
+		return func() {}, nil
+	}
+	throttle, ok := h.throttles[pluginID]
+	if !ok {
+		throttle = NewOpScheduler(1, false)
+		h.throttles[pluginID] = throttle
+	}
+	h.lock.Unlock()
+
+	release, err := throttle.Acquire(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return func() { time.AfterFunc(h.mountDelay, release) }, nil
+}
+
+func (h *pluginHealth) isDegradedLocked(pluginID string, now time.Time) bool {
+	lastFailure, ok := h.degraded[pluginID]
+	if !ok {
+		return false
+	}
+	if now.Sub(lastFailure) < h.recoveryTime {
+		return true
+	}
+
+	// Mounts already waiting on the plugin's throttle still start in turn
+	delete(h.degraded, pluginID)
+	delete(h.failures, pluginID)
+	delete(h.throttles, pluginID)
+	return false
+}
+
+// pruneLocked drops the failures of the plugin that fell out of the window
+// and returns the remaining ones.
+func (h *pluginHealth) pruneLocked(pluginID string, now time.Time) []time.Time {
+	failures := h.failures[pluginID]
+	i := 0
+	for ; i < len(failures); i++ {
+		if now.Sub(failures[i]) < h.window {
+			break
+		}
+	}
+	return failures[i:]
+}
+
+// isPluginFailure returns whether the error of a volume operation counts
+// against the health of the plugin.
+func isPluginFailure(err error) bool {
+	switch {
+	case errors.Is(err, ErrNodeMountLimit),
+		errors.Is(err, structs.ErrCSIClientRPCIgnorable),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package csimanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginHealth(t *testing.T) {
+	h := newPluginHealth(0)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	failed := errors.New("rpc error: code = Unavailable")
+
+	// Successes and failures that aren't the plugin's fault are ignored
+	for i := 0; i < pluginHealthMaxFailures; i++ {
+		h.record("foo", nil)
+		h.record("foo", &mountLimitError{max: 1})
+		h.record("foo", context.Canceled)
+	}
+	require.Empty(t, h.degradedPlugins())
+
+	// Failures that fall out of the window don't degrade the plugin
+	for i := 0; i < pluginHealthMaxFailures-1; i++ {
+		h.record("foo", failed)
+	}
+	now = now.Add(pluginHealthWindow)
+	h.record("foo", failed)
+	require.Empty(t, h.degradedPlugins())
+
+	for i := 0; i < pluginHealthMaxFailures-1; i++ {
+		h.record("foo", failed)
+	}
+	require.Equal(t, []string{"foo"}, h.degradedPlugins())
+
+	// Failing again while degraded extends the recovery
+	now = now.Add(pluginHealthRecoveryPeriod - time.Second)
+	h.record("foo", failed)
+	now = now.Add(pluginHealthRecoveryPeriod - time.Second)
+	require.Equal(t, []string{"foo"}, h.degradedPlugins())
+
+	// The plugin recovers once it has stopped failing for a while, and
+	// starts over from no failures
+	now = now.Add(time.Second)
+	require.Empty(t, h.degradedPlugins())
+	h.record("foo", failed)
+	require.Empty(t, h.degradedPlugins())
+
+	// A nil tracker reports every plugin as healthy
+	var nilHealth *pluginHealth
+	nilHealth.record("foo", failed)
+	require.Nil(t, nilHealth.degradedPlugins())
+}
+
+func TestPluginHealth_ThrottleMount(t *testing.T) {
+	delay := 100 * time.Millisecond
+	h := newPluginHealth(delay)
+	for i := 0; i < pluginHealthMaxFailures; i++ {
+		h.record("foo", errors.New("rpc error: code = Unavailable"))
+	}
+	require.Equal(t, []string{"foo"}, h.degradedPlugins())
+
+	// The mounts of healthy plugins aren't throttled
+	release, err := h.throttleMount(context.Background(), "bar")
+	require.NoError(t, err)
+	release2, err := h.throttleMount(context.Background(), "bar")
+	require.NoError(t, err)
+	release()
+	release2()
+
+	// The mounts of a degraded plugin are made one at a time
+	release, err = h.throttleMount(context.Background(), "foo")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), delay)
+	defer cancel()
+	_, err = h.throttleMount(ctx, "foo")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The next mount starts once the delay has passed since the previous
+	// one finished
+	start := time.Now()
+	release()
+	release, err = h.throttleMount(context.Background(), "foo")
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), delay)
+	release()
+
+	// Mounts aren't throttled without a delay
+	h = newPluginHealth(0)
+	for i := 0; i < pluginHealthMaxFailures; i++ {
+		h.record("foo", errors.New("rpc error: code = Unavailable"))
+	}
+	release, err = h.throttleMount(context.Background(), "foo")
+	require.NoError(t, err)
+	release2, err = h.throttleMount(context.Background(), "foo")
+	require.NoError(t, err)
+	release()
+	release2()
+}
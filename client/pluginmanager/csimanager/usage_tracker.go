@@ -67,3 +67,17 @@ func (v *volumeUsageTracker) Free(allocID, volID string, usage *UsageOptions) bo
 	allocs := v.allocsForKey(key)
 	return len(allocs) == 0
 }
+
+// InUse returns true if any allocation uses the volume with the usage
+// options staged under usageDir, as named by UsageOptions.ToFS.
+func (v *volumeUsageTracker) InUse(volID, usageDir string) bool {
+	v.stateMu.Lock()
+	defer v.stateMu.Unlock()
+
+	for key := range v.state {
+		if key.id == volID && key.usageOpts.ToFS() == usageDir {
+			return true
+		}
+	}
+	return false
+}
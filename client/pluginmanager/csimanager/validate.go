@@ -0,0 +1,92 @@
+package csimanager
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (c *csiManager) ValidateVolumes(alloc *structs.Allocation, vols map[string]*structs.CSIVolume) map[string]error {
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		return nil
+	}
+
+	results := make(map[string]error)
+	for name, req := range tg.Volumes {
+		if req.Type != structs.VolumeTypeCSI {
+			continue
+		}
+		results[name] = c.validateVolume(alloc, req, vols[name])
+	}
+	return results
+}
+
+// validateVolume returns why the volume of a request couldn't be claimed and
+// mounted for alloc, making the same checks as claiming and mounting it would
+// against the volume and the node plugin's latest fingerprint.
+func (c *csiManager) validateVolume(alloc *structs.Allocation, req *structs.VolumeRequest, vol *structs.CSIVolume) error {
+	if vol == nil {
+		source := req.Source
+		if req.PerAlloc {
+			source = source + structs.AllocSuffix(alloc.Name)
+		}
+		return fmt.Errorf("volume %s not found", source)
+	}
+
+	info := c.nodePluginInfo(vol.PluginID)
+	if info == nil {
+		return &pluginNotFoundError{
+			msg:    fmt.Sprintf("plugin %s for type csi-node not found", vol.PluginID),
+			reason: c.pluginNotFoundErr(vol.PluginID),
+		}
+	}
+	if !info.Healthy {
+		return fmt.Errorf("plugin %s for type csi-node is unhealthy: %s",
+			vol.PluginID, info.HealthDescription)
+	}
+
+	if !volumeHasCapability(vol, req.AttachmentMode, req.AccessMode) {
+		return fmt.Errorf("volume %s does not support attachment mode %q with access mode %q",
+			vol.ID, req.AttachmentMode, req.AccessMode)
+	}
+
+	if req.ReadOnly {
+		if !vol.ReadSchedulable() {
+			return fmt.Errorf("volume %s is unschedulable for reads", vol.ID)
+		}
+		return nil
+	}
+	if !vol.WriteSchedulable() {
+		return fmt.Errorf("volume %s is unschedulable for writes", vol.ID)
+	}
+	if !vol.WriteFreeClaims() {
+		// Claims held by the same job don't block the allocation
+		for _, a := range vol.WriteAllocs {
+			if a != nil && (a.Namespace != alloc.Namespace || a.JobID != alloc.JobID) {
+				return fmt.Errorf("volume %s max claim reached", vol.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// volumeHasCapability returns whether the volume can be used with the given
+// attachment and access modes.
+func volumeHasCapability(vol *structs.CSIVolume, attachmentMode structs.CSIVolumeAttachmentMode, accessMode structs.CSIVolumeAccessMode) bool {
+	if len(vol.RequestedCapabilities) == 0 {
+		// COMPAT: volumes registered prior to 1.1.0 only have the modes of
+		// their current claims
+		return (vol.AttachmentMode == structs.CSIVolumeAttachmentModeUnknown ||
+			vol.AttachmentMode == attachmentMode) &&
+			(vol.AccessMode == structs.CSIVolumeAccessModeUnknown ||
+				vol.AccessMode == accessMode)
+	}
+
+	for _, cap := range vol.RequestedCapabilities {
+		if cap.AttachmentMode == attachmentMode && cap.AccessMode == accessMode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package csimanager
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/client/dynamicplugins"
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_ValidateVolumes(t *testing.T) {
+	registry := setupRegistry()
+	defer registry.Shutdown()
+
+	pm := New(&Config{
+		Logger:                testlog.HCLogger(t),
+		DynamicRegistry:       registry,
+		UpdateNodeCSIInfoFunc: func(string, *structs.CSIInfo) {},
+	}).(*csiManager)
+
+	pm.updateNodePluginInfo("healthy-plugin", &structs.CSIInfo{
+		PluginID: "healthy-plugin",
+		Healthy:  true,
+		NodeInfo: &structs.CSINodeInfo{},
+	})
+	pm.updateNodePluginInfo("unhealthy-plugin", &structs.CSIInfo{
+		PluginID:          "unhealthy-plugin",
+		HealthDescription: "failed fingerprinting",
+		NodeInfo:          &structs.CSINodeInfo{},
+	})
+
+	newVolume := func(pluginID string) *structs.CSIVolume {
+		vol := mock.CSIVolume(&structs.CSIPlugin{ID: pluginID})
+		vol.Schedulable = true
+		vol.AccessMode = structs.CSIVolumeAccessModeUnknown
+		vol.AttachmentMode = structs.CSIVolumeAttachmentModeUnknown
+		vol.RequestedCapabilities = []*structs.CSIVolumeCapability{{
+			AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+			AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		}}
+		return vol
+	}
+
+	cases := []struct {
+		name      string
+		readOnly  bool
+		modify    func(*structs.CSIVolume, *structs.Allocation) *structs.CSIVolume
+		expectErr string
+	}{
+		{
+			name: "satisfiable",
+		},
+		{
+			name:     "satisfiable read only",
+			readOnly: true,
+		},
+		{
+			name: "claimed by the same job",
+			modify: func(vol *structs.CSIVolume, alloc *structs.Allocation) *structs.CSIVolume {
+				other := mock.Alloc()
+				other.JobID = alloc.JobID
+				vol.AccessMode = structs.CSIVolumeAccessModeSingleNodeWriter
+				vol.WriteAllocs[other.ID] = other
+				vol.WriteClaims[other.ID] = &structs.CSIVolumeClaim{AllocationID: other.ID}
+				return vol
+			},
+		},
+		{
+			name:      "volume not found",
+			modify:    func(*structs.CSIVolume, *structs.Allocation) *structs.CSIVolume { return nil },
+			expectErr: "volume vol0 not found",
+		},
+		{
+			name: "plugin not running",
+			modify: func(vol *structs.CSIVolume, alloc *structs.Allocation) *structs.CSIVolume {
+				vol.PluginID = "missing-plugin"
+				return vol
+			},
+			expectErr: "plugin missing-plugin for type csi-node not found",
+		},
+		{
+			name: "plugin unhealthy",
+			modify: func(vol *structs.CSIVolume, alloc *structs.Allocation) *structs.CSIVolume {
+				vol.PluginID = "unhealthy-plugin"
+				return vol
+			},
+			expectErr: "plugin unhealthy-plugin for type csi-node is unhealthy: failed fingerprinting",
+		},
+		{
+			name: "capability mismatch",
+			modify: func(vol *structs.CSIVolume, alloc *structs.Allocation) *structs.CSIVolume {
+				vol.RequestedCapabilities[0].AttachmentMode = structs.CSIVolumeAttachmentModeBlockDevice
+				return vol
+			},
+			expectErr: "does not support attachment mode",
+		},
+		{
+			name: "unschedulable",
+			modify: func(vol *structs.CSIVolume, alloc *structs.Allocation) *structs.CSIVolume {
+				vol.Schedulable = false
+				return vol
+			},
+			expectErr: "unschedulable for writes",
+		},
+		{
+			name: "claimed by another job",
+			modify: func(vol *structs.CSIVolume, alloc *structs.Allocation) *structs.CSIVolume {
+				other := mock.Alloc()
+				other.JobID = "other"
+				vol.AccessMode = structs.CSIVolumeAccessModeSingleNodeWriter
+				vol.WriteAllocs[other.ID] = other
+				vol.WriteClaims[other.ID] = &structs.CSIVolumeClaim{AllocationID: other.ID}
+				return vol
+			},
+			expectErr: "max claim reached",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := mock.Alloc()
+			alloc.Job.TaskGroups[0].Volumes = map[string]*structs.VolumeRequest{
+				"data": {
+					Name:           "data",
+					Type:           structs.VolumeTypeCSI,
+					Source:         "vol0",
+					ReadOnly:       tc.readOnly,
+					AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+					AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+				},
+				"host": {
+					Name:   "host",
+					Type:   structs.VolumeTypeHost,
+					Source: "shared",
+				},
+			}
+
+			vol := newVolume("healthy-plugin")
+			if tc.modify != nil {
+				vol = tc.modify(vol, alloc)
+			}
+
+			results := pm.ValidateVolumes(alloc, map[string]*structs.CSIVolume{"data": vol})
+			require.Len(t, results, 1)
+			require.Contains(t, results, "data")
+			if tc.expectErr == "" {
+				require.NoError(t, results["data"])
+			} else {
+				require.Error(t, results["data"])
+				require.Contains(t, results["data"].Error(), tc.expectErr)
+			}
+		})
+	}
+
+	// Plugins that stop running can't satisfy requests
+	pm.ensureNoInstance(&dynamicplugins.PluginInfo{Name: "healthy-plugin", Type: "csi-node"})
+	require.Nil(t, pm.nodePluginInfo("healthy-plugin"))
+}
@@ -2,8 +2,10 @@ package csimanager
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -23,8 +25,30 @@ const (
 	DefaultMountActionTimeout = 2 * time.Minute
 	StagingDirName            = "staging"
 	AllocSpecificDirName      = "per-alloc"
+
+	// MountMetadataFileName is the name of the file next to the target of an
+	// allocation's volume mount that describes the allocation, when the client
+	// writes mount metadata for external tools.
+	MountMetadataFileName = "nomad-alloc.json"
+
+	// mountStateFileSuffix is appended to the target of an allocation's
+	// volume mount to name the file recording how the volume was published,
+	// so that the mount can be cleaned up after the allocation is gone.
+	mountStateFileSuffix = ".nomad-mount.json"
 )
 
+// mountState is recorded next to the target of a volume mount before the
+// volume is published. The client's state of an allocation is deleted when
+// the allocation is destroyed, so this is all that's left to unmount volumes
+// leaked by allocations that no longer exist.
+type mountState struct {
+	RemoteID string `json:"remote_id"`
+
+	// StagingPath is the path, as seen by the plugin, the volume was staged
+	// at. It's empty if the plugin doesn't require staging.
+	StagingPath string `json:"staging_path,omitempty"`
+}
+
 // volumeManager handles the state of attached volumes for a given CSI Plugin.
 //
 // volumeManagers outlive the lifetime of a given allocation as volumes may be
@@ -50,6 +74,19 @@ type volumeManager struct {
 	// requiresStaging shows whether the plugin requires that the volume manager
 	// calls NodeStageVolume and NodeUnstageVolume RPCs during setup and teardown
 	requiresStaging bool
+
+	// mountLimiter bounds the mount paths published on the node across all
+	// node plugins. If nil, mounts are not limited.
+	mountLimiter *mountLimiter
+
+	// health records the outcome of the plugin's mounts and unmounts under
+	// pluginID. If nil, they are not recorded.
+	health   *pluginHealth
+	pluginID string
+
+	// topology is the accessible topology the plugin fingerprinted for the
+	// node, if any
+	topology *structs.CSITopology
 }
 
 func newVolumeManager(logger hclog.Logger, eventer TriggerNodeEvent, plugin csi.CSIPlugin, rootDir, containerRootDir string, requiresStaging bool) *volumeManager {
@@ -213,6 +250,16 @@ func (v *volumeManager) publishVolume(ctx context.Context, vol *structs.CSIVolum
 		return nil, err
 	}
 
+	// Record the mount before publishing, so that it can be cleaned up even
+	// if the client stops while the volume is being published
+	err = writeMountState(hostTargetPath, &mountState{
+		RemoteID:    vol.RemoteID(),
+		StagingPath: pluginStagingPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// CSI NodePublishVolume errors for timeout, codes.Unavailable and
 	// codes.ResourceExhausted are retried; all other errors are fatal.
 	err = v.plugin.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{
@@ -243,7 +290,16 @@ func (v *volumeManager) MountVolume(ctx context.Context, vol *structs.CSIVolume,
 	logger := v.logger.With("volume_id", vol.ID, "alloc_id", alloc.ID)
 	ctx = hclog.WithContext(ctx, logger)
 
-	if v.requiresStaging {
+	release, err := v.health.throttleMount(ctx, v.pluginID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	target := v.targetForVolume(v.mountRoot, vol.ID, alloc.ID, usage)
+	reserved, err := v.mountLimiter.reserve(target)
+
+	if err == nil && v.requiresStaging {
 		err = v.stageVolume(ctx, vol, usage, publishContext)
 	}
 
@@ -253,6 +309,8 @@ func (v *volumeManager) MountVolume(ctx context.Context, vol *structs.CSIVolume,
 
 	if err == nil {
 		v.usageTracker.Claim(alloc.ID, vol.ID, usage)
+	} else if reserved {
+		v.mountLimiter.release(target)
 	}
 
 	event := structs.NewNodeEvent().
@@ -267,10 +325,40 @@ func (v *volumeManager) MountVolume(ctx context.Context, vol *structs.CSIVolume,
 	}
 
 	v.eventer(event)
+	v.health.record(v.pluginID, err)
 
 	return mountInfo, err
 }
 
+func (v *volumeManager) NodeTopology() *structs.CSITopology {
+	return v.topology
+}
+
+// RestoreVolume tracks the mount of a volume published for the allocation
+// before the client restarted, so that unmounting it later only unstages the
+// volume once no other allocation uses it.
+func (v *volumeManager) RestoreVolume(vol *structs.CSIVolume, alloc *structs.Allocation, usage *UsageOptions, mountInfo *MountInfo) error {
+	target := v.targetForVolume(v.mountRoot, vol.ID, alloc.ID, usage)
+	if mountInfo == nil || mountInfo.Source != target {
+		return fmt.Errorf("volume (%s) was not mounted at %s", vol.ID, target)
+	}
+
+	m := mount.New()
+	isNotMount, err := m.IsNotAMountPoint(target)
+	if err != nil {
+		return fmt.Errorf("mount point detection failed for volume (%s): %v", vol.ID, err)
+	}
+	if isNotMount {
+		return fmt.Errorf("volume (%s) is no longer mounted at %s", vol.ID, target)
+	}
+
+	if _, err := v.mountLimiter.reserve(target); err != nil {
+		return err
+	}
+	v.usageTracker.Claim(alloc.ID, vol.ID, usage)
+	return nil
+}
+
 // unstageVolume is the inverse operation of `stageVolume` and must be called
 // once for each staging path that a volume has been staged under.
 // It is safe to call multiple times and a plugin is required to return OK if
@@ -279,7 +367,12 @@ func (v *volumeManager) unstageVolume(ctx context.Context, volID, remoteID strin
 	logger := hclog.FromContext(ctx)
 	logger.Trace("Unstaging volume")
 	stagingPath := v.stagingDirForVolume(v.containerMountPoint, volID, usage)
+	return v.unstageTarget(ctx, remoteID, stagingPath)
+}
 
+// unstageTarget unstages the volume from the given staging path, as seen by
+// the plugin.
+func (v *volumeManager) unstageTarget(ctx context.Context, remoteID, stagingPath string) error {
 	// CSI NodeUnstageVolume errors for timeout, codes.Unavailable and
 	// codes.ResourceExhausted are retried; all other errors are fatal.
 	return v.plugin.NodeUnstageVolume(ctx,
@@ -306,7 +399,52 @@ func combineErrors(maybeErrs ...error) error {
 
 func (v *volumeManager) unpublishVolume(ctx context.Context, volID, remoteID, allocID string, usage *UsageOptions) error {
 	pluginTargetPath := v.targetForVolume(v.containerMountPoint, volID, allocID, usage)
+	hostTargetPath := v.targetForVolume(v.mountRoot, volID, allocID, usage)
+	err := v.unpublishTarget(ctx, remoteID, pluginTargetPath, hostTargetPath)
+	if err == nil || errors.Is(err, structs.ErrCSIClientRPCIgnorable) {
+		removeMountState(hostTargetPath)
+	}
+	return err
+}
+
+// writeMountState records how the volume is published at the host target
+// path.
+func writeMountState(hostTargetPath string, state *mountState) error {
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(hostTargetPath+mountStateFileSuffix, buf, 0600); err != nil {
+		return fmt.Errorf("failed to record volume mount: %v", err)
+	}
+	return nil
+}
+
+// readMountState reads how the volume is published at the host target path.
+func readMountState(hostTargetPath string) (*mountState, error) {
+	buf, err := ioutil.ReadFile(hostTargetPath + mountStateFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	var state mountState
+	if err := json.Unmarshal(buf, &state); err != nil {
+		return nil, err
+	}
+	if state.RemoteID == "" {
+		return nil, fmt.Errorf("no remote ID recorded")
+	}
+	return &state, nil
+}
 
+// removeMountState removes the record of the volume published at the host
+// target path, once the volume is unpublished.
+func removeMountState(hostTargetPath string) {
+	os.Remove(hostTargetPath + mountStateFileSuffix)
+}
+
+// unpublishTarget unpublishes the volume from the given target path, as seen
+// by the plugin and by the host, and removes the host target directory.
+func (v *volumeManager) unpublishTarget(ctx context.Context, remoteID, pluginTargetPath, hostTargetPath string) error {
 	// CSI NodeUnpublishVolume errors for timeout, codes.Unavailable and
 	// codes.ResourceExhausted are retried; all other errors are fatal.
 	rpcErr := v.plugin.NodeUnpublishVolume(ctx, remoteID, pluginTargetPath,
@@ -315,7 +453,6 @@ func (v *volumeManager) unpublishVolume(ctx context.Context, volID, remoteID, al
 		grpc_retry.WithBackoff(grpc_retry.BackoffExponential(100*time.Millisecond)),
 	)
 
-	hostTargetPath := v.targetForVolume(v.mountRoot, volID, allocID, usage)
 	if _, err := os.Stat(hostTargetPath); os.IsNotExist(err) {
 		if rpcErr != nil && strings.Contains(rpcErr.Error(), "no mount point") {
 			// host target path was already destroyed, nothing to do here.
@@ -347,6 +484,7 @@ func (v *volumeManager) UnmountVolume(ctx context.Context, volID, remoteID, allo
 	err = v.unpublishVolume(ctx, volID, remoteID, allocID, usage)
 
 	if err == nil || errors.Is(err, structs.ErrCSIClientRPCIgnorable) {
+		v.mountLimiter.release(v.targetForVolume(v.mountRoot, volID, allocID, usage))
 		canRelease := v.usageTracker.Free(allocID, volID, usage)
 		if v.requiresStaging && canRelease {
 			err = v.unstageVolume(ctx, volID, remoteID, usage)
@@ -365,6 +503,165 @@ func (v *volumeManager) UnmountVolume(ctx context.Context, volID, remoteID, allo
 	}
 
 	v.eventer(event)
+	v.health.record(v.pluginID, err)
 
 	return err
 }
+
+// reconcileAllocMounts unmounts the volumes in the per-alloc directories of
+// allocations that are not live. These are left behind when the client stops
+// before an allocation's CSI hook can unmount its volumes and the allocation
+// is gone by the time the client restarts.
+//
+// The remote ID and staging path of a leaked volume are read from the mount
+// state recorded next to its target when it was published. Targets without a
+// readable record are left mounted, as the plugin can't be told which volume
+// to unmount. Staged volumes are unstaged once none of their targets are in
+// use.
+func (v *volumeManager) reconcileAllocMounts(ctx context.Context, liveAllocs LiveAllocsFunc) error {
+	allocRoot := filepath.Join(v.mountRoot, AllocSpecificDirName)
+
+	// List the directories before fetching the live allocations, so that
+	// an allocation mounting volumes concurrently is always seen as live.
+	allocDirs, err := ioutil.ReadDir(allocRoot)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list allocation volume directories: %v", err)
+	}
+	live := liveAllocs()
+
+	// unpublished are the mount states of the unpublished volumes by their
+	// staging directory, which are unstaged once all the leaked targets are
+	// unpublished
+	unpublished := make(map[leakedStagingKey]*mountState)
+
+	var mErr *multierror.Error
+	for _, allocDir := range allocDirs {
+		allocID := allocDir.Name()
+		if _, ok := live[allocID]; ok || !allocDir.IsDir() {
+			continue
+		}
+
+		allocPath := filepath.Join(allocRoot, allocID)
+		volDirs, err := ioutil.ReadDir(allocPath)
+		if err != nil {
+			mErr = multierror.Append(mErr, err)
+			continue
+		}
+
+		for _, volDir := range volDirs {
+			volID := volDir.Name()
+			volPath := filepath.Join(allocPath, volID)
+			usageDirs, err := ioutil.ReadDir(volPath)
+			if err != nil {
+				mErr = multierror.Append(mErr, err)
+				continue
+			}
+
+			for _, usageDir := range usageDirs {
+				if !usageDir.IsDir() {
+					name := usageDir.Name()
+					switch {
+					case name == MountMetadataFileName:
+						os.Remove(filepath.Join(volPath, name))
+					case strings.HasSuffix(name, mountStateFileSuffix):
+						// Records of targets that are already gone are
+						// left behind by failed publishes
+						target := filepath.Join(volPath, strings.TrimSuffix(name, mountStateFileSuffix))
+						if _, err := os.Stat(target); os.IsNotExist(err) {
+							os.Remove(filepath.Join(volPath, name))
+						}
+					}
+					continue
+				}
+
+				hostTargetPath := filepath.Join(volPath, usageDir.Name())
+				state, err := readMountState(hostTargetPath)
+				if err != nil {
+					v.logger.Warn("not unmounting volume leaked by allocation that no longer exists, as its mount wasn't recorded",
+						"volume_id", volID, "alloc_id", allocID, "error", err)
+					continue
+				}
+
+				err = v.unmountLeaked(ctx, volID, state.RemoteID, allocID, usageDir.Name())
+				if err != nil {
+					mErr = multierror.Append(mErr, err)
+					continue
+				}
+				removeMountState(hostTargetPath)
+				if state.StagingPath != "" {
+					unpublished[leakedStagingKey{volID: volID, usageDir: usageDir.Name()}] = state
+				}
+			}
+
+			// Only removes the directories once they are empty
+			os.Remove(volPath)
+		}
+		os.Remove(allocPath)
+	}
+
+	for key, state := range unpublished {
+		if v.usageTracker.InUse(key.volID, key.usageDir) {
+			continue
+		}
+		if err := v.unstageLeaked(ctx, key.volID, state); err != nil {
+			mErr = multierror.Append(mErr, err)
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// leakedStagingKey identifies the staging directory of a leaked volume, by
+// the volume's ID and the usage directory named by UsageOptions.ToFS.
+type leakedStagingKey struct {
+	volID    string
+	usageDir string
+}
+
+// unmountLeaked unpublishes a single volume target left behind by an
+// allocation that no longer exists.
+func (v *volumeManager) unmountLeaked(ctx context.Context, volID, remoteID, allocID, usageDir string) error {
+	logger := v.logger.With("volume_id", volID, "alloc_id", allocID)
+	logger.Info("unmounting volume leaked by allocation that no longer exists")
+
+	pluginTargetPath := filepath.Join(v.containerMountPoint, AllocSpecificDirName, allocID, volID, usageDir)
+	hostTargetPath := filepath.Join(v.mountRoot, AllocSpecificDirName, allocID, volID, usageDir)
+
+	err := v.unpublishTarget(hclog.WithContext(ctx, logger), remoteID, pluginTargetPath, hostTargetPath)
+	if errors.Is(err, structs.ErrCSIClientRPCIgnorable) {
+		err = nil
+	}
+
+	event := structs.NewNodeEvent().
+		SetSubsystem(structs.NodeEventSubsystemStorage).
+		SetMessage("Unmount leaked volume").
+		AddDetail("volume_id", volID).
+		AddDetail("alloc_id", allocID)
+	if err == nil {
+		event.AddDetail("success", "true")
+	} else {
+		event.AddDetail("success", "false")
+		event.AddDetail("error", err.Error())
+	}
+	v.eventer(event)
+
+	if err != nil {
+		return fmt.Errorf("failed to unmount volume %q of allocation %q: %v", volID, allocID, err)
+	}
+	return nil
+}
+
+// unstageLeaked unstages a volume whose leaked targets have been unpublished,
+// from the staging path recorded in its mount state.
+func (v *volumeManager) unstageLeaked(ctx context.Context, volID string, state *mountState) error {
+	logger := v.logger.With("volume_id", volID)
+	logger.Info("unstaging volume leaked by allocations that no longer exist")
+
+	if err := v.unstageTarget(hclog.WithContext(ctx, logger), state.RemoteID, state.StagingPath); err != nil {
+		return fmt.Errorf("failed to unstage volume %q: %v", volID, err)
+	}
+	return nil
+}
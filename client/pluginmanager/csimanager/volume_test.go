@@ -3,10 +3,13 @@ package csimanager
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/nomad/helper/mount"
 	"github.com/hashicorp/nomad/helper/testlog"
@@ -467,6 +470,43 @@ func TestVolumeManager_unpublishVolume(t *testing.T) {
 	}
 }
 
+func TestVolumeManager_MountState(t *testing.T) {
+	if !checkMountSupport() {
+		t.Skip("mount point detection not supported for this platform")
+	}
+	t.Parallel()
+
+	tmpPath := tmpDir(t)
+	defer os.RemoveAll(tmpPath)
+
+	csiFake := &csifake.Client{}
+	eventer := func(e *structs.NodeEvent) {}
+	manager := newVolumeManager(testlog.HCLogger(t), eventer, csiFake, tmpPath, tmpPath, true)
+	ctx := context.Background()
+
+	vol := &structs.CSIVolume{ID: "foo", ExternalID: "foo-remote"}
+	alloc := structs.MockAlloc()
+	usage := &UsageOptions{
+		AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+	}
+	target := manager.targetForVolume(tmpPath, vol.ID, alloc.ID, usage)
+
+	// Publishing the volume records how it was published
+	_, err := manager.publishVolume(ctx, vol, alloc, usage, nil)
+	require.NoError(t, err)
+	state, err := readMountState(target)
+	require.NoError(t, err)
+	require.Equal(t, &mountState{
+		RemoteID:    "foo-remote",
+		StagingPath: manager.stagingDirForVolume(tmpPath, vol.ID, usage),
+	}, state)
+
+	// Unpublishing it removes the record
+	require.NoError(t, manager.unpublishVolume(ctx, vol.ID, vol.RemoteID(), alloc.ID, usage))
+	require.NoFileExists(t, target+mountStateFileSuffix)
+}
+
 func TestVolumeManager_MountVolumeEvents(t *testing.T) {
 	if !checkMountSupport() {
 		t.Skip("mount point detection not supported for this platform")
@@ -528,3 +568,196 @@ func TestVolumeManager_MountVolumeEvents(t *testing.T) {
 	require.Equal(t, "vol", e.Details["volume_id"])
 	require.Equal(t, "true", e.Details["success"])
 }
+
+func TestVolumeManager_MountLimit(t *testing.T) {
+	if !checkMountSupport() {
+		t.Skip("mount point detection not supported for this platform")
+	}
+	t.Parallel()
+
+	tmpPath := tmpDir(t)
+	defer os.RemoveAll(tmpPath)
+
+	manager := newVolumeManager(testlog.HCLogger(t), func(*structs.NodeEvent) {},
+		&csifake.Client{}, tmpPath, tmpPath, true)
+	manager.mountLimiter = newMountLimiter(1)
+
+	ctx := context.Background()
+	vol := &structs.CSIVolume{ID: "vol", Namespace: "ns"}
+	usage := &UsageOptions{
+		AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		AccessMode:     structs.CSIVolumeAccessModeMultiNodeMultiWriter,
+	}
+	alloc1, alloc2 := mock.Alloc(), mock.Alloc()
+
+	// Failed mounts don't count towards the limit
+	_, err := manager.MountVolume(ctx, vol, alloc1,
+		&UsageOptions{AccessMode: usage.AccessMode}, map[string]string{})
+	require.Error(t, err)
+	require.Zero(t, manager.mountLimiter.count())
+
+	_, err = manager.MountVolume(ctx, vol, alloc1, usage, map[string]string{})
+	require.NoError(t, err)
+
+	_, err = manager.MountVolume(ctx, vol, alloc2, usage, map[string]string{})
+	require.True(t, errors.Is(err, ErrNodeMountLimit))
+
+	require.NoError(t, manager.UnmountVolume(ctx, vol.ID, vol.RemoteID(), alloc1.ID, usage))
+	require.Zero(t, manager.mountLimiter.count())
+
+	_, err = manager.MountVolume(ctx, vol, alloc2, usage, map[string]string{})
+	require.NoError(t, err)
+}
+
+func TestVolumeManager_DegradedPluginMounts(t *testing.T) {
+	if !checkMountSupport() {
+		t.Skip("mount point detection not supported for this platform")
+	}
+	t.Parallel()
+
+	tmpPath := tmpDir(t)
+	defer os.RemoveAll(tmpPath)
+
+	var events []*structs.NodeEvent
+	eventer := func(e *structs.NodeEvent) {
+		events = append(events, e)
+	}
+
+	delay := 100 * time.Millisecond
+	manager := newVolumeManager(testlog.HCLogger(t), eventer, &csifake.Client{}, tmpPath, tmpPath, true)
+	manager.health = newPluginHealth(delay)
+	manager.pluginID = "foo"
+	for i := 0; i < pluginHealthMaxFailures; i++ {
+		manager.health.record("foo", errors.New("rpc error: code = Unavailable"))
+	}
+
+	ctx := context.Background()
+	vol := &structs.CSIVolume{ID: "vol", Namespace: "ns"}
+	usage := &UsageOptions{
+		AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		AccessMode:     structs.CSIVolumeAccessModeMultiNodeMultiWriter,
+	}
+
+	// The mounts of the degraded plugin are spaced by the delay, whichever
+	// allocation they're for
+	start := time.Now()
+	_, err := manager.MountVolume(ctx, vol, mock.Alloc(), usage, map[string]string{})
+	require.NoError(t, err)
+	_, err = manager.MountVolume(ctx, vol, mock.Alloc(), usage, map[string]string{})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), delay)
+	require.Len(t, events, 2)
+
+	// A mount cancelled while it waits doesn't reach the plugin
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = manager.MountVolume(cancelCtx, vol, mock.Alloc(), usage, map[string]string{})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Len(t, events, 2)
+}
+
+func TestVolumeManager_RestoreVolume(t *testing.T) {
+	if !checkMountSupport() {
+		t.Skip("mount point detection not supported for this platform")
+	}
+	t.Parallel()
+
+	tmpPath := tmpDir(t)
+	defer os.RemoveAll(tmpPath)
+
+	manager := newVolumeManager(testlog.HCLogger(t), func(*structs.NodeEvent) {},
+		&csifake.Client{}, tmpPath, tmpPath, true)
+
+	vol := &structs.CSIVolume{ID: "vol", Namespace: "ns"}
+	usage := &UsageOptions{
+		AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		AccessMode:     structs.CSIVolumeAccessModeMultiNodeMultiWriter,
+	}
+	alloc := mock.Alloc()
+	target := manager.targetForVolume(tmpPath, vol.ID, alloc.ID, usage)
+
+	// Mounts recorded at another target aren't restored
+	err := manager.RestoreVolume(vol, alloc, usage, &MountInfo{Source: "/elsewhere"})
+	require.EqualError(t, err, fmt.Sprintf("volume (vol) was not mounted at %s", target))
+
+	// Neither are mounts that are gone
+	require.NoError(t, os.MkdirAll(target, 0700))
+	err = manager.RestoreVolume(vol, alloc, usage, &MountInfo{Source: target})
+	require.EqualError(t, err, fmt.Sprintf("volume (vol) is no longer mounted at %s", target))
+	require.Zero(t, manager.mountLimiter.count())
+}
+
+func TestVolumeManager_reconcileAllocMounts(t *testing.T) {
+	t.Parallel()
+
+	tmpPath := tmpDir(t)
+	defer os.RemoveAll(tmpPath)
+
+	usage := &UsageOptions{
+		AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+		AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+	}
+	liveAlloc := structs.MockAlloc()
+	leakedAlloc := structs.MockAlloc()
+	unknownAlloc := structs.MockAlloc()
+
+	csiFake := &csifake.Client{}
+	var events []*structs.NodeEvent
+	eventer := func(e *structs.NodeEvent) {
+		events = append(events, e)
+	}
+	manager := newVolumeManager(testlog.HCLogger(t), eventer, csiFake, tmpPath, tmpPath, true)
+
+	// The live allocation still uses the staged volume "foo"
+	liveTarget := manager.targetForVolume(tmpPath, "foo", liveAlloc.ID, usage)
+	manager.usageTracker.Claim(liveAlloc.ID, "foo", usage)
+	leakedFooTarget := manager.targetForVolume(tmpPath, "foo", leakedAlloc.ID, usage)
+	leakedBarTarget := manager.targetForVolume(tmpPath, "bar", leakedAlloc.ID, usage)
+	unknownTarget := manager.targetForVolume(tmpPath, "baz", unknownAlloc.ID, usage)
+	for _, target := range []string{liveTarget, leakedFooTarget, leakedBarTarget, unknownTarget} {
+		require.NoError(t, os.MkdirAll(target, 0700))
+	}
+
+	// The leaked allocation's mounts were recorded when they were
+	// published, while the mount of unknownAlloc wasn't
+	for _, volID := range []string{"foo", "bar"} {
+		require.NoError(t, writeMountState(
+			manager.targetForVolume(tmpPath, volID, leakedAlloc.ID, usage),
+			&mountState{
+				RemoteID:    volID + "-remote",
+				StagingPath: manager.stagingDirForVolume(tmpPath, volID, usage),
+			}))
+	}
+
+	// The mount metadata of the leaked allocation isn't a mount, and is
+	// removed along with its directories
+	leakedMetadata := filepath.Join(filepath.Dir(leakedFooTarget), MountMetadataFileName)
+	require.NoError(t, ioutil.WriteFile(leakedMetadata, []byte("{}"), 0600))
+
+	liveAllocs := func() map[string]struct{} {
+		return map[string]struct{}{liveAlloc.ID: {}}
+	}
+	require.NoError(t, manager.reconcileAllocMounts(context.Background(), liveAllocs))
+
+	// Only the mounts of the leaked allocation are unpublished and their
+	// directories removed, using the remote IDs it recorded
+	require.Equal(t, int64(2), csiFake.NodeUnpublishVolumeCallCount)
+	require.Equal(t, "foo-remote", csiFake.PrevNodeUnpublishVolumeID)
+	require.DirExists(t, liveTarget)
+	require.NoDirExists(t, filepath.Join(tmpPath, AllocSpecificDirName, leakedAlloc.ID))
+
+	// The mount whose remote ID is unknown is left in place
+	require.DirExists(t, unknownTarget)
+
+	// Only the volume no allocation uses anymore is unstaged, from its
+	// recorded staging path
+	require.Equal(t, int64(1), csiFake.NodeUnstageVolumeCallCount)
+	require.Equal(t, "bar-remote", csiFake.PrevNodeUnstageVolumeID)
+	require.Equal(t, manager.stagingDirForVolume(tmpPath, "bar", usage), csiFake.PrevNodeUnstageStagingPath)
+
+	require.Len(t, events, 2)
+	for _, e := range events {
+		require.Equal(t, leakedAlloc.ID, e.Details["alloc_id"])
+		require.Equal(t, "true", e.Details["success"])
+	}
+}
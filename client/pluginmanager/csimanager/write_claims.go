@@ -0,0 +1,135 @@
+package csimanager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ErrVolumeWriteClaimed is returned by WriteClaimTracker.Claim when another
+// allocation on the node already holds a write claim on a single-writer
+// volume.
+var ErrVolumeWriteClaimed = errors.New("volume is already claimed for writing by another allocation on this node")
+
+// writeClaimConflictError is returned when a write claim is rejected by the
+// WriteClaimTracker. It wraps ErrVolumeWriteClaimed so callers can check for
+// it with errors.Is.
+type writeClaimConflictError struct {
+	volumeID string
+	allocIDs []string
+}
+
+func (e *writeClaimConflictError) Error() string {
+	return fmt.Sprintf("%v: volume %s is claimed for writing by allocation %s",
+		ErrVolumeWriteClaimed, e.volumeID, e.allocIDs[0])
+}
+
+func (e *writeClaimConflictError) Unwrap() error {
+	return ErrVolumeWriteClaimed
+}
+
+// WriteClaimTracker tracks the write claims that the allocations on the node
+// hold on CSI volumes, and is shared by the allocations claiming them. It
+// rejects a write claim on a single-writer volume already claimed for writing
+// by another allocation on the node, so that two allocations never write to
+// the same volume from one node. A nil WriteClaimTracker doesn't track
+// claims.
+type WriteClaimTracker struct {
+	// claims maps the namespaced volume IDs to the allocations holding a
+	// write claim on them
+	claims map[string]map[string]struct{}
+	lock   sync.Mutex
+}
+
+// NewWriteClaimTracker returns an empty WriteClaimTracker.
+func NewWriteClaimTracker() *WriteClaimTracker {
+	return &WriteClaimTracker{
+		claims: make(map[string]map[string]struct{}),
+	}
+}
+
+// Claim records the allocation's write claim on the volume, or returns a
+// writeClaimConflictError if the volume's access mode allows a single writer
+// and another allocation already holds a write claim on it. Claims with a
+// multi-writer or unknown access mode never conflict. Claiming a volume the
+// allocation already holds is a noop.
+func (w *WriteClaimTracker) Claim(allocID, namespace, volumeID string, mode structs.CSIVolumeAccessMode) error {
+	if w == nil {
+		return nil
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	key := namespace + "/" + volumeID
+	allocs, ok := w.claims[key]
+	if !ok {
+		allocs = make(map[string]struct{})
+		w.claims[key] = allocs
+	}
+
+	if isSingleWriter(mode) {
+		var holders []string
+		for id := range allocs {
+			if id != allocID {
+				holders = append(holders, id)
+			}
+		}
+		if len(holders) > 0 {
+			sort.Strings(holders)
+			return &writeClaimConflictError{volumeID: volumeID, allocIDs: holders}
+		}
+	}
+
+	allocs[allocID] = struct{}{}
+	return nil
+}
+
+// Release drops the allocation's write claims.
+func (w *WriteClaimTracker) Release(allocID string) {
+	if w == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for key, allocs := range w.claims {
+		delete(allocs, allocID)
+		if len(allocs) == 0 {
+			delete(w.claims, key)
+		}
+	}
+}
+
+// ReleaseVolume drops the allocation's write claim on the volume.
+func (w *WriteClaimTracker) ReleaseVolume(allocID, namespace, volumeID string) {
+	if w == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	key := namespace + "/" + volumeID
+	allocs, ok := w.claims[key]
+	if !ok {
+		return
+	}
+	delete(allocs, allocID)
+	if len(allocs) == 0 {
+		delete(w.claims, key)
+	}
+}
+
+func isSingleWriter(mode structs.CSIVolumeAccessMode) bool {
+	switch mode {
+	case structs.CSIVolumeAccessModeSingleNodeWriter,
+		structs.CSIVolumeAccessModeMultiNodeSingleWriter:
+		return true
+	}
+	return false
+}
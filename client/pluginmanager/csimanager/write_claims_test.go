@@ -0,0 +1,62 @@
+package csimanager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteClaimTracker(t *testing.T) {
+	w := NewWriteClaimTracker()
+	ns := structs.DefaultNamespace
+
+	require.NoError(t, w.Claim("alloc1", ns, "vol0", structs.CSIVolumeAccessModeSingleNodeWriter))
+
+	// Claiming a volume again from the same allocation doesn't conflict
+	require.NoError(t, w.Claim("alloc1", ns, "vol0", structs.CSIVolumeAccessModeSingleNodeWriter))
+
+	// Another allocation can't claim the single-writer volume for writing
+	err := w.Claim("alloc2", ns, "vol0", structs.CSIVolumeAccessModeSingleNodeWriter)
+	require.True(t, errors.Is(err, ErrVolumeWriteClaimed))
+	require.EqualError(t, err, "volume is already claimed for writing by another allocation on this node: "+
+		"volume vol0 is claimed for writing by allocation alloc1")
+
+	err = w.Claim("alloc2", ns, "vol0", structs.CSIVolumeAccessModeMultiNodeSingleWriter)
+	require.True(t, errors.Is(err, ErrVolumeWriteClaimed))
+
+	// Volumes in other namespaces or with another ID don't conflict
+	require.NoError(t, w.Claim("alloc2", "other", "vol0", structs.CSIVolumeAccessModeSingleNodeWriter))
+	require.NoError(t, w.Claim("alloc2", ns, "vol1", structs.CSIVolumeAccessModeSingleNodeWriter))
+
+	// Multi-writer volumes, or volumes with an unknown access mode, can be
+	// claimed for writing by several allocations
+	require.NoError(t, w.Claim("alloc1", ns, "vol2", structs.CSIVolumeAccessModeMultiNodeMultiWriter))
+	require.NoError(t, w.Claim("alloc2", ns, "vol2", structs.CSIVolumeAccessModeMultiNodeMultiWriter))
+	require.NoError(t, w.Claim("alloc1", ns, "vol3", structs.CSIVolumeAccessModeUnknown))
+	require.NoError(t, w.Claim("alloc2", ns, "vol3", structs.CSIVolumeAccessModeUnknown))
+
+	// Once the first allocation releases its claims, the volume can be
+	// claimed by the other
+	w.Release("alloc1")
+	require.NoError(t, w.Claim("alloc2", ns, "vol0", structs.CSIVolumeAccessModeSingleNodeWriter))
+
+	// Releasing a single volume keeps the allocation's other claims
+	w.ReleaseVolume("alloc2", ns, "vol0")
+	require.NoError(t, w.Claim("alloc1", ns, "vol0", structs.CSIVolumeAccessModeSingleNodeWriter))
+	err = w.Claim("alloc1", ns, "vol1", structs.CSIVolumeAccessModeSingleNodeWriter)
+	require.True(t, errors.Is(err, ErrVolumeWriteClaimed))
+
+	w.Release("alloc1")
+	w.Release("alloc2")
+	require.Empty(t, w.claims)
+}
+
+func TestWriteClaimTracker_Nil(t *testing.T) {
+	var w *WriteClaimTracker
+	require.NoError(t, w.Claim("alloc1", structs.DefaultNamespace, "vol0", structs.CSIVolumeAccessModeSingleNodeWriter))
+	require.NoError(t, w.Claim("alloc2", structs.DefaultNamespace, "vol0", structs.CSIVolumeAccessModeSingleNodeWriter))
+	w.Release("alloc1")
+	w.ReleaseVolume("alloc1", structs.DefaultNamespace, "vol0")
+}
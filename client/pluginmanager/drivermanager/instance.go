@@ -3,10 +3,12 @@ package drivermanager
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/helper/pluginutils/loader"
 	"github.com/hashicorp/nomad/helper/pluginutils/singleton"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -53,6 +55,10 @@ type instanceManagerConfig struct {
 
 	// EventHandlerFactory is used to fetch a task event handler
 	EventHandlerFactory TaskEventHandlerFactory
+
+	// HealthThreshold configures when failed task starts mark the driver
+	// degraded
+	HealthThreshold *config.DriverHealthThreshold
 }
 
 // instanceManager is used to manage a single driver plugin
@@ -107,6 +113,16 @@ type instanceManager struct {
 	// lastHealthState is the last known health fingerprinted by the manager
 	lastHealthState   drivers.HealthState
 	lastHealthStateMu sync.Mutex
+
+	// lastFingerprint is the last fingerprint received from the driver. The
+	// node is updated from it when the task starts of the driver degrade or
+	// recover.
+	lastFingerprint   *drivers.Fingerprint
+	lastFingerprintMu sync.Mutex
+
+	// startHealth tracks the latency and failures of the driver's task
+	// starts
+	startHealth *startHealth
 }
 
 // newInstanceManager returns a new driver instance manager. It is expected that
@@ -127,6 +143,7 @@ func newInstanceManager(c *instanceManagerConfig) *instanceManager {
 		updateNodeFromDriver: c.UpdateNodeFromDriver,
 		eventHandlerFactory:  c.EventHandlerFactory,
 		firstFingerprintCh:   make(chan struct{}),
+		startHealth:          newStartHealth(c.HealthThreshold),
 	}
 
 	go i.run()
@@ -358,18 +375,10 @@ func (i *instanceManager) handleFingerprintError() {
 
 // handleFingerprint updates the node with the current fingerprint status
 func (i *instanceManager) handleFingerprint(fp *drivers.Fingerprint) {
-	attrs := make(map[string]string, len(fp.Attributes))
-	for key, attr := range fp.Attributes {
-		attrs[key] = attr.GoString()
-	}
-	di := &structs.DriverInfo{
-		Attributes:        attrs,
-		Detected:          fp.Health != drivers.HealthStateUndetected,
-		Healthy:           fp.Health == drivers.HealthStateHealthy,
-		HealthDescription: fp.HealthDescription,
-		UpdateTime:        time.Now(),
-	}
-	i.updateNodeFromDriver(i.id.Name, di)
+	i.lastFingerprintMu.Lock()
+	i.lastFingerprint = fp
+	i.updateNodeLocked()
+	i.lastFingerprintMu.Unlock()
 
 	// log detected/undetected state changes after the initial fingerprint
 	i.lastHealthStateMu.Lock()
@@ -389,6 +398,69 @@ func (i *instanceManager) handleFingerprint(fp *drivers.Fingerprint) {
 	}
 }
 
+// updateNodeLocked updates the node from the last fingerprint and the health
+// of the driver's task starts. i.lastFingerprintMu must be held.
+func (i *instanceManager) updateNodeLocked() {
+	fp := i.lastFingerprint
+	if fp == nil {
+		return
+	}
+
+	now := time.Now()
+	attrs := make(map[string]string, len(fp.Attributes)+2)
+	for key, attr := range fp.Attributes {
+		attrs[key] = attr.GoString()
+	}
+	di := &structs.DriverInfo{
+		Attributes:        attrs,
+		Detected:          fp.Health != drivers.HealthStateUndetected,
+		Healthy:           fp.Health == drivers.HealthStateHealthy,
+		HealthDescription: fp.HealthDescription,
+		UpdateTime:        now,
+	}
+
+	status := i.startHealth.status(now)
+	if status.Started {
+		// An empty value removes the attributes from the node once the task
+		// starts have left the window
+		p95Attr := fmt.Sprintf("driver.%s.start_p95_ms", i.id.Name)
+		failuresAttr := fmt.Sprintf("driver.%s.start_failures", i.id.Name)
+		if status.Starts > 0 {
+			attrs[p95Attr] = strconv.FormatInt(status.P95.Milliseconds(), 10)
+			attrs[failuresAttr] = strconv.Itoa(status.Failures)
+		} else {
+			attrs[p95Attr] = ""
+			attrs[failuresAttr] = ""
+		}
+	}
+	if di.Healthy && status.Degraded {
+		di.Degraded = true
+		di.HealthDescription = fmt.Sprintf("Driver degraded: %d of %d task starts failed in the last %s",
+			status.Failures, status.Starts, i.startHealth.threshold.Window)
+	}
+
+	i.updateNodeFromDriver(i.id.Name, di)
+}
+
+// recordTaskStart records the outcome of a task start, updating the node if
+// it degraded or recovered the driver.
+func (i *instanceManager) recordTaskStart(latency time.Duration, err error) {
+	if !i.startHealth.record(time.Now(), latency, err != nil) {
+		return
+	}
+
+	i.lastFingerprintMu.Lock()
+	defer i.lastFingerprintMu.Unlock()
+
+	status := i.startHealth.status(time.Now())
+	if status.Degraded {
+		i.logger.Warn("driver degraded by failed task starts", "failures", status.Failures, "starts", status.Starts)
+	} else {
+		i.logger.Info("driver recovered from failed task starts")
+	}
+	i.updateNodeLocked()
+}
+
 // getLastHealth returns the most recent HealthState from fingerprinting
 func (i *instanceManager) getLastHealth() drivers.HealthState {
 	i.lastHealthStateMu.Lock()
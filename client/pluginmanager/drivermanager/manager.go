@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
+	metrics "github.com/armon/go-metrics"
 	log "github.com/hashicorp/go-hclog"
 	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/pluginmanager"
 	"github.com/hashicorp/nomad/client/pluginmanager/drivermanager/state"
 	"github.com/hashicorp/nomad/helper/pluginutils/loader"
@@ -27,6 +30,10 @@ type Manager interface {
 	// Dispense returns a drivers.DriverPlugin for the given driver plugin name
 	// handling reattaching to an existing driver if available
 	Dispense(driver string) (drivers.DriverPlugin, error)
+
+	// RecordTaskStart records how long a StartTask call of the driver took
+	// and whether it failed, tracking the health of the driver's task starts
+	RecordTaskStart(driver string, latency time.Duration, err error)
 }
 
 // TaskExecHandler is function to be called for executing commands in a task
@@ -92,6 +99,10 @@ type Config struct {
 
 	// BlockedDrivers if set will not allow the given driver plugins to start
 	BlockedDrivers map[string]struct{}
+
+	// HealthThreshold returns when failed task starts mark a driver
+	// degraded. If not set the default threshold is used.
+	HealthThreshold func(driver string) *config.DriverHealthThreshold
 }
 
 // manager is used to manage a set of driver plugins
@@ -131,6 +142,9 @@ type manager struct {
 	allowedDrivers map[string]struct{}
 	blockedDrivers map[string]struct{}
 
+	// healthThreshold returns when failed task starts mark a driver degraded
+	healthThreshold func(driver string) *config.DriverHealthThreshold
+
 	// readyCh is ticked once at the end of Run()
 	readyCh chan struct{}
 }
@@ -151,6 +165,7 @@ func New(c *Config) *manager {
 		reattachConfigs:     make(map[loader.PluginID]*pstructs.ReattachConfig),
 		allowedDrivers:      c.AllowedDrivers,
 		blockedDrivers:      c.BlockedDrivers,
+		healthThreshold:     c.HealthThreshold,
 		readyCh:             make(chan struct{}),
 	}
 }
@@ -200,6 +215,7 @@ func (m *manager) Run() {
 			ID:                   &id,
 			UpdateNodeFromDriver: m.updater,
 			EventHandlerFactory:  m.eventHandlerFactory,
+			HealthThreshold:      m.driverHealthThreshold(id.Name),
 		})
 
 		m.instancesMu.Lock()
@@ -382,6 +398,31 @@ func (m *manager) Dispense(d string) (drivers.DriverPlugin, error) {
 	return nil, ErrDriverNotFound
 }
 
+func (m *manager) RecordTaskStart(d string, latency time.Duration, err error) {
+	labels := []metrics.Label{{Name: "driver", Value: d}}
+	metrics.AddSampleWithLabels([]string{"client", "driver", "start_latency"},
+		float32(latency.Milliseconds()), labels)
+	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"client", "driver", "start_failures"}, 1, labels)
+	}
+
+	m.instancesMu.RLock()
+	instance, ok := m.instances[d]
+	m.instancesMu.RUnlock()
+	if ok {
+		instance.recordTaskStart(latency, err)
+	}
+}
+
+// driverHealthThreshold returns when failed task starts mark the driver
+// degraded.
+func (m *manager) driverHealthThreshold(name string) *config.DriverHealthThreshold {
+	if m.healthThreshold == nil {
+		return config.DefaultDriverHealthThreshold()
+	}
+	return m.healthThreshold(name)
+}
+
 func (m *manager) isDriverBlocked(name string) bool {
 	// Block drivers that are not in the allowed list if it is set.
 	if _, ok := m.allowedDrivers[name]; len(m.allowedDrivers) > 0 && !ok {
@@ -0,0 +1,150 @@
+package drivermanager
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+)
+
+// maxStartSamples bounds the number of task starts tracked for a driver
+// within its window.
+const maxStartSamples = 1024
+
+// startSample is the outcome of a single StartTask call
+type startSample struct {
+	time    time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// startHealthStatus summarizes the task starts of a driver within its window
+type startHealthStatus struct {
+	// Started is whether the driver has started any task
+	Started bool
+
+	Starts   int
+	Failures int
+	P95      time.Duration
+	Degraded bool
+}
+
+// startHealth tracks the latency and failures of a driver's task starts over
+// a rolling window. The driver is degraded once the failures within the
+// window reach the threshold, and stays degraded until its task starts
+// haven't failed for the recovery period, so that it doesn't flap while
+// failures hover around the threshold.
+type startHealth struct {
+	threshold *config.DriverHealthThreshold
+
+	samples     []startSample
+	started     bool
+	degraded    bool
+	lastFailure time.Time
+
+	// recovered is when the driver last stopped being degraded. Failures
+	// before it don't count towards degrading the driver again.
+	recovered time.Time
+
+	lock sync.Mutex
+}
+
+func newStartHealth(threshold *config.DriverHealthThreshold) *startHealth {
+	if threshold == nil {
+		threshold = config.DefaultDriverHealthThreshold()
+	}
+	return &startHealth{
+		threshold: threshold,
+	}
+}
+
+// record adds the outcome of a task start. It returns whether the driver's
+// degraded state changed.
+func (h *startHealth) record(now time.Time, latency time.Duration, failed bool) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.pruneLocked(now)
+	if len(h.samples) >= maxStartSamples {
+		h.samples = h.samples[1:]
+	}
+	h.samples = append(h.samples, startSample{time: now, latency: latency, failed: failed})
+	h.started = true
+	if failed {
+		h.lastFailure = now
+	}
+
+	return h.updateLocked(now)
+}
+
+// status returns the task starts within the window and whether the driver is
+// degraded, clearing the degraded state if the driver has recovered.
+func (h *startHealth) status(now time.Time) startHealthStatus {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.pruneLocked(now)
+	h.updateLocked(now)
+
+	status := startHealthStatus{
+		Started:  h.started,
+		Starts:   len(h.samples),
+		Failures: h.failuresSinceLocked(time.Time{}),
+		Degraded: h.degraded,
+	}
+	if len(h.samples) == 0 {
+		return status
+	}
+
+	latencies := make([]time.Duration, len(h.samples))
+	for i, sample := range h.samples {
+		latencies[i] = sample.latency
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	status.P95 = latencies[(len(latencies)*95+99)/100-1]
+	return status
+}
+
+// updateLocked marks the driver degraded once the failures within the window
+// reach the threshold, and clears it after the recovery period. It returns
+// whether the degraded state changed. h.lock must be held.
+func (h *startHealth) updateLocked(now time.Time) bool {
+	if !h.degraded {
+		if h.threshold.MaxFailures <= 0 || h.failuresSinceLocked(h.recovered) < h.threshold.MaxFailures {
+			return false
+		}
+		h.degraded = true
+		return true
+	}
+
+	if now.Sub(h.lastFailure) < h.threshold.RecoveryPeriod {
+		return false
+	}
+	h.degraded = false
+	h.recovered = now
+	return true
+}
+
+// failuresSinceLocked returns the number of failed task starts within the
+// window since the given time. h.lock must be held.
+func (h *startHealth) failuresSinceLocked(since time.Time) int {
+	failures := 0
+	for _, sample := range h.samples {
+		if sample.failed && sample.time.After(since) {
+			failures++
+		}
+	}
+	return failures
+}
+
+// pruneLocked removes the task starts that have left the window. h.lock must
+// be held.
+func (h *startHealth) pruneLocked(now time.Time) {
+	cutoff := now.Add(-h.threshold.Window)
+	i := 0
+	for i < len(h.samples) && h.samples[i].time.Before(cutoff) {
+		i++
+	}
+	h.samples = h.samples[i:]
+}
@@ -0,0 +1,93 @@
+package drivermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartHealth_Degraded(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	h := newStartHealth(&config.DriverHealthThreshold{
+		Window:         time.Minute,
+		MaxFailures:    2,
+		RecoveryPeriod: 5 * time.Minute,
+	})
+	now := time.Now()
+
+	require.False(h.record(now, time.Second, false))
+	require.False(h.record(now.Add(time.Second), time.Second, true))
+	require.False(h.status(now.Add(time.Second)).Degraded)
+
+	// The driver is degraded once the failures reach the threshold
+	require.True(h.record(now.Add(2*time.Second), time.Second, true))
+	status := h.status(now.Add(2 * time.Second))
+	require.True(status.Degraded)
+	require.Equal(3, status.Starts)
+	require.Equal(2, status.Failures)
+
+	// It stays degraded after the failures leave the window, until the
+	// recovery period has passed since the last failure
+	later := now.Add(2 * time.Minute)
+	require.False(h.record(later, time.Second, false))
+	status = h.status(later)
+	require.True(status.Degraded)
+	require.Equal(1, status.Starts)
+	require.Zero(status.Failures)
+
+	recovered := now.Add(2*time.Second + 5*time.Minute)
+	require.False(h.status(recovered).Degraded)
+
+	// Failures from before the driver recovered don't degrade it again
+	h = newStartHealth(&config.DriverHealthThreshold{
+		Window:         time.Hour,
+		MaxFailures:    2,
+		RecoveryPeriod: time.Minute,
+	})
+	require.False(h.record(now, time.Second, true))
+	require.True(h.record(now, time.Second, true))
+	require.True(h.record(now.Add(2*time.Minute), time.Second, false))
+	require.False(h.record(now.Add(3*time.Minute), time.Second, true))
+	require.True(h.record(now.Add(4*time.Minute), time.Second, true))
+}
+
+func TestStartHealth_Disabled(t *testing.T) {
+	t.Parallel()
+
+	h := newStartHealth(&config.DriverHealthThreshold{Window: time.Minute})
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		require.False(t, h.record(now, time.Second, true))
+	}
+	require.False(t, h.status(now).Degraded)
+}
+
+func TestStartHealth_P95(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	h := newStartHealth(nil)
+	now := time.Now()
+	require.False(h.status(now).Started)
+
+	for i := 1; i <= 100; i++ {
+		h.record(now, time.Duration(i)*time.Millisecond, false)
+	}
+	status := h.status(now)
+	require.True(status.Started)
+	require.Equal(100, status.Starts)
+	require.Equal(95*time.Millisecond, status.P95)
+
+	h.record(now, time.Second, false)
+	require.Equal(96*time.Millisecond, h.status(now).P95)
+
+	// Task starts that have left the window are forgotten
+	status = h.status(now.Add(config.DefaultDriverHealthWindow + time.Second))
+	require.True(status.Started)
+	require.Zero(status.Starts)
+	require.Zero(status.P95)
+}
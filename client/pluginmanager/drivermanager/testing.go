@@ -6,6 +6,7 @@ package drivermanager
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad/helper/pluginutils/catalog"
@@ -48,5 +49,7 @@ func (m *testManager) Dispense(driver string) (drivers.DriverPlugin, error) {
 	return d, nil
 }
 
+func (m *testManager) RecordTaskStart(driver string, latency time.Duration, err error) {}
+
 func (m *testManager) RegisterEventHandler(driver, taskID string, handler EventHandler) {}
 func (m *testManager) DeregisterEventHandler(driver, taskID string)                     {}
@@ -0,0 +1,418 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/fingerprint"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/helper/tlsutil"
+	"github.com/hashicorp/nomad/nomad/structs"
+	nconfig "github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+const (
+	// reloadSignalAllocSetup is the health signal counted when an
+	// allocation fails to set up after a staged reload
+	reloadSignalAllocSetup = "alloc_setup_failure"
+
+	// reloadSignalFingerprint is the health signal counted when a
+	// fingerprinter fails after a staged reload
+	reloadSignalFingerprint = "fingerprint_error"
+)
+
+// reloadableConfig is the part of the client's configuration that can be
+// changed by a reload. The previous reloadableConfig is retained while a
+// staged reload is observed so that the reload can be rolled back.
+type reloadableConfig struct {
+	tlsConfig                      *nconfig.TLSConfig
+	artifactChecksumExemptPrefixes []string
+	hostVolumes                    map[string]*structs.ClientHostVolumeConfig
+	hostNetworks                   map[string]*structs.ClientHostNetworkConfig
+
+	// fields holds the config.ReloadableFields
+	fields *config.Config
+}
+
+func newReloadableConfig(cfg *config.Config) *reloadableConfig {
+	fields := new(config.Config)
+	fields.ReloadableCopyFrom(cfg)
+
+	return &reloadableConfig{
+		tlsConfig:                      cfg.TLSConfig,
+		artifactChecksumExemptPrefixes: helper.CopySliceString(cfg.ArtifactChecksumExemptPrefixes),
+		hostVolumes:                    structs.CopyMapStringClientHostVolumeConfig(cfg.HostVolumes),
+		hostNetworks:                   structs.CopyMapStringClientHostNetworkConfig(cfg.HostNetworks),
+		fields:                         fields,
+	}
+}
+
+// reloadMonitor counts the failures observed during the observation window
+// of a staged reload.
+type reloadMonitor struct {
+	// index is the reloadIndex of the reload being observed
+	index uint64
+
+	// previous is the config the reload is rolled back to
+	previous *reloadableConfig
+
+	threshold int
+	started   time.Time
+	failures  map[string]int
+
+	// allocs are the allocations whose setup failures have been counted
+	allocs map[string]struct{}
+
+	timer *time.Timer
+}
+
+// total returns the number of failures observed across all signals.
+func (m *reloadMonitor) total() int {
+	total := 0
+	for _, n := range m.failures {
+		total += n
+	}
+	return total
+}
+
+// Reload allows a client to reload its configuration on the fly. When a
+// reload observation window is configured the reload is staged: the new
+// config is dry-run before it is applied, and the reload is rolled back if
+// failures spike during the window. Host volume paths are checked on every
+// reload.
+func (c *Client) Reload(newConfig *config.Config) error {
+	c.reloadLock.Lock()
+	defer c.reloadLock.Unlock()
+
+	staged := newConfig.ReloadObservationWindow > 0
+	var err error
+	if staged {
+		err = c.dryRunReload(newConfig)
+	} else {
+		err = c.checkReloadHostVolumes(newConfig)
+	}
+	if err != nil {
+		c.triggerNodeEvent(newReloadEvent("Config reload rejected").
+			AddDetail("error", err.Error()))
+		return fmt.Errorf("config reload rejected: %v", err)
+	}
+
+	if changed := c.GetConfig().RestartRequiredChanges(newConfig); len(changed) > 0 {
+		c.logger.Warn("config changes require a client restart to take effect", "fields", changed)
+	}
+
+	// A new reload supersedes the one being observed
+	c.stopReloadMonitor()
+
+	previous := newReloadableConfig(c.config)
+	if err := c.applyReloadableConfig(newReloadableConfig(newConfig)); err != nil {
+		if staged {
+			if rerr := c.applyReloadableConfig(previous); rerr != nil {
+				c.logger.Error("failed to restore config after failed reload", "error", rerr)
+			}
+		}
+		return err
+	}
+	c.reloadIndex++
+	outcomes := c.LastReloadOutcomes()
+	c.logger.Debug("config reloaded", "subsystems", outcomes.String())
+	c.triggerNodeEvent(newReloadEvent("Config reloaded").
+		AddDetail("subsystems", outcomes.String()))
+
+	if staged {
+		c.startReloadMonitor(previous, newConfig)
+	}
+	return nil
+}
+
+// dryRunReload validates a new config without applying it, including the
+// checks that are otherwise only made by the subsystems that use it.
+func (c *Client) dryRunReload(newConfig *config.Config) error {
+	if err := newConfig.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.checkReloadHostVolumes(newConfig); err != nil {
+		return err
+	}
+
+	for name, network := range newConfig.HostNetworks {
+		for _, cidr := range network.AllCIDRs() {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("failed to validate host network %s, err: %v", name, err)
+			}
+		}
+		if _, err := structs.ParsePortRanges(network.ReservedPorts); err != nil {
+			return fmt.Errorf("failed to validate host network %s reserved ports, err: %v", name, err)
+		}
+	}
+
+	// Load the CNI configs the same way they are fingerprinted
+	var resp fingerprint.FingerprintResponse
+	req := &fingerprint.FingerprintRequest{Config: newConfig, Node: c.Node().Copy()}
+	if err := fingerprint.NewCNIFingerprint(c.logger).Fingerprint(req, &resp); err != nil {
+		return fmt.Errorf("failed to validate CNI config, err: %v", err)
+	}
+
+	return nil
+}
+
+// checkReloadHostVolumes checks the host volume paths of a new config the
+// same way they are checked when the node is registered.
+func (c *Client) checkReloadHostVolumes(newConfig *config.Config) error {
+	warnings, err := newConfig.CheckHostVolumePaths()
+	if err != nil {
+		return err
+	}
+	if warnings != nil {
+		c.logger.Warn("reloading host volumes with missing paths", "warnings", warnings)
+	}
+	return nil
+}
+
+// applyReloadableConfig updates the reloadable subsystems of the client to
+// match rc, and records whether each of them was restarted, updated or left
+// unchanged.
+func (c *Client) applyReloadableConfig(rc *reloadableConfig) error {
+	outcomes := make(config.ReloadOutcomes)
+
+	// Artifacts downloaded after the reload use the new exemptions
+	outcomes.Set("artifacts",
+		c.artifactChecksumPolicy.SetExemptPrefixes(rc.artifactChecksumExemptPrefixes),
+		config.ReloadUpdated)
+
+	c.configLock.Lock()
+
+	// Alloc runners hold the config copy and read it without the lock, so
+	// the reload is applied to a fresh copy that replaces it rather than to
+	// the shared one. Runners pick up the new copy through GetConfig.
+	c.configCopy = c.configCopy.Copy()
+
+	volumesChanged := !reflect.DeepEqual(c.config.HostVolumes, rc.hostVolumes)
+	networksChanged := !reflect.DeepEqual(c.config.HostNetworks, rc.hostNetworks)
+	outcomes.Set("host_volumes", volumesChanged, config.ReloadUpdated)
+	outcomes.Set("host_networks", networksChanged, config.ReloadUpdated)
+	if volumesChanged || networksChanged {
+		c.config.HostVolumes = structs.CopyMapStringClientHostVolumeConfig(rc.hostVolumes)
+		c.config.HostNetworks = structs.CopyMapStringClientHostNetworkConfig(rc.hostNetworks)
+		c.configCopy.HostVolumes = structs.CopyMapStringClientHostVolumeConfig(rc.hostVolumes)
+		c.configCopy.HostNetworks = structs.CopyMapStringClientHostNetworkConfig(rc.hostNetworks)
+		c.config.Node.HostVolumes = structs.CopyMapStringClientHostVolumeConfig(rc.hostVolumes)
+		c.config.Node.HostNetworks = structs.CopyMapStringClientHostNetworkConfig(rc.hostNetworks)
+		c.updateNodeLocked()
+
+		// Allocations that failed for a missing host volume may now fit
+		if n := c.placementFailures.clearCode(structs.PlacementFailureMissingHostVolume); n > 0 {
+			c.logger.Debug("cleared placement failures after host volumes changed", "cleared", n)
+		}
+	}
+
+	// The host stats collector uses the new interval from its next
+	// collection, and allocations use the new template config from their
+	// next template render setup
+	outcomes.Set("stats",
+		c.config.StatsCollectionInterval != rc.fields.StatsCollectionInterval,
+		config.ReloadUpdated)
+	outcomes.Set("template",
+		!reflect.DeepEqual(c.config.TemplateConfig, rc.fields.TemplateConfig),
+		config.ReloadUpdated)
+	c.config.ReloadableCopyFrom(rc.fields)
+	c.configCopy.ReloadableCopyFrom(rc.fields)
+	c.configLock.Unlock()
+
+	outcomes.Set("gc", c.reloadGCConfig(rc.fields), config.ReloadRestarted)
+
+	shouldReloadTLS, err := tlsutil.ShouldReloadRPCConnections(c.config.TLSConfig, rc.tlsConfig)
+	if err != nil {
+		c.logger.Error("error parsing TLS configuration", "error", err)
+		c.setReloadOutcomes(outcomes)
+		return err
+	}
+	outcomes.Set("tls", shouldReloadTLS, config.ReloadRestarted)
+	c.setReloadOutcomes(outcomes)
+
+	if shouldReloadTLS {
+		return c.reloadTLSConnections(rc.tlsConfig)
+	}
+
+	return nil
+}
+
+// setReloadOutcomes records the outcomes of the reload just applied.
+func (c *Client) setReloadOutcomes(outcomes config.ReloadOutcomes) {
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+	c.reloadOutcomes = outcomes
+}
+
+// LastReloadOutcomes returns whether the last config reload restarted,
+// updated or left unchanged each reloadable subsystem of the client, or nil
+// if the config was never reloaded. A reload rolled back is reported with
+// the outcomes of the rollback.
+func (c *Client) LastReloadOutcomes() config.ReloadOutcomes {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	if c.reloadOutcomes == nil {
+		return nil
+	}
+
+	outcomes := make(config.ReloadOutcomes, len(c.reloadOutcomes))
+	for subsystem, outcome := range c.reloadOutcomes {
+		outcomes[subsystem] = outcome
+	}
+	return outcomes
+}
+
+// reloadGCConfig updates the garbage collector if its interval or max allocs
+// changed, and returns whether it did. Updating the garbage collector
+// restarts its collection interval.
+func (c *Client) reloadGCConfig(cfg *config.Config) bool {
+	gcConfig := *c.garbageCollector.getConfig()
+	if gcConfig.Interval == cfg.GCInterval && gcConfig.MaxAllocs == cfg.GCMaxAllocs {
+		return false
+	}
+
+	c.logger.Debug("reloading garbage collector config",
+		"gc_interval", cfg.GCInterval, "gc_max_allocs", cfg.GCMaxAllocs)
+	gcConfig.Interval = cfg.GCInterval
+	gcConfig.MaxAllocs = cfg.GCMaxAllocs
+	c.garbageCollector.SetConfig(&gcConfig)
+	return true
+}
+
+// startReloadMonitor starts observing the health of the client after a
+// staged reload. c.reloadLock must be held.
+func (c *Client) startReloadMonitor(previous *reloadableConfig, newConfig *config.Config) {
+	threshold := newConfig.ReloadRollbackThreshold
+	if threshold <= 0 {
+		threshold = config.DefaultReloadRollbackThreshold
+	}
+
+	m := &reloadMonitor{
+		index:     c.reloadIndex,
+		previous:  previous,
+		threshold: threshold,
+		started:   time.Now(),
+		failures:  make(map[string]int),
+		allocs:    make(map[string]struct{}),
+	}
+
+	c.reloadMonitorLock.Lock()
+	defer c.reloadMonitorLock.Unlock()
+	c.reloadMonitor = m
+	m.timer = time.AfterFunc(newConfig.ReloadObservationWindow, func() {
+		c.reloadMonitorLock.Lock()
+		defer c.reloadMonitorLock.Unlock()
+		if c.reloadMonitor == m {
+			c.reloadMonitor = nil
+			c.logger.Info("config reload kept after observation window",
+				"failures", m.total())
+		}
+	})
+}
+
+// stopReloadMonitor stops observing the current staged reload, if any.
+func (c *Client) stopReloadMonitor() {
+	c.reloadMonitorLock.Lock()
+	defer c.reloadMonitorLock.Unlock()
+	if m := c.reloadMonitor; m != nil {
+		m.timer.Stop()
+		c.reloadMonitor = nil
+	}
+}
+
+// recordFingerprintError counts a fingerprinting error against the staged
+// reload being observed.
+func (c *Client) recordFingerprintError(name string, err error) {
+	c.reloadMonitorLock.Lock()
+	defer c.reloadMonitorLock.Unlock()
+	if m := c.reloadMonitor; m != nil {
+		c.logger.Debug("fingerprint error observed after config reload",
+			"fingerprinter", name, "error", err)
+		c.recordReloadFailureLocked(m, reloadSignalFingerprint)
+	}
+}
+
+// recordAllocSetupFailure counts an allocation that failed to set up against
+// the staged reload being observed. Each allocation is only counted once.
+func (c *Client) recordAllocSetupFailure(alloc *structs.Allocation) {
+	c.reloadMonitorLock.Lock()
+	defer c.reloadMonitorLock.Unlock()
+
+	m := c.reloadMonitor
+	if m == nil {
+		return
+	}
+	if _, ok := m.allocs[alloc.ID]; ok {
+		return
+	}
+	if !allocSetupFailedSince(alloc, m.started) {
+		return
+	}
+	m.allocs[alloc.ID] = struct{}{}
+	c.recordReloadFailureLocked(m, reloadSignalAllocSetup)
+}
+
+// recordReloadFailureLocked counts a failure of the given signal and rolls
+// back the reload once the threshold is reached. c.reloadMonitorLock must be
+// held.
+func (c *Client) recordReloadFailureLocked(m *reloadMonitor, signal string) {
+	m.failures[signal]++
+	failures := m.total()
+	if failures < m.threshold {
+		return
+	}
+
+	m.timer.Stop()
+	c.reloadMonitor = nil
+	go c.rollbackReload(m, signal, failures)
+}
+
+// rollbackReload restores the config that was in use before the reload
+// observed by m, unless it has since been superseded by another reload.
+func (c *Client) rollbackReload(m *reloadMonitor, signal string, failures int) {
+	c.reloadLock.Lock()
+	defer c.reloadLock.Unlock()
+
+	if c.reloadIndex != m.index {
+		c.logger.Debug("skipping rollback of superseded config reload")
+		return
+	}
+
+	c.logger.Warn("rolling back config reload", "signal", signal, "failures", failures)
+	if err := c.applyReloadableConfig(m.previous); err != nil {
+		c.logger.Error("failed to roll back config reload", "error", err)
+		c.triggerNodeEvent(newReloadEvent("Config reload rollback failed").
+			AddDetail("signal", signal).
+			AddDetail("error", err.Error()))
+		return
+	}
+	c.reloadIndex++
+
+	c.triggerNodeEvent(newReloadEvent("Config reload rolled back").
+		AddDetail("signal", signal).
+		AddDetail("failures", strconv.Itoa(failures)))
+}
+
+// allocSetupFailedSince returns whether any task of alloc failed to set up
+// since the given time.
+func allocSetupFailedSince(alloc *structs.Allocation, since time.Time) bool {
+	for _, state := range alloc.TaskStates {
+		for _, event := range state.Events {
+			if event.Type == structs.TaskSetupFailure && event.Time >= since.UnixNano() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newReloadEvent(msg string) *structs.NodeEvent {
+	return structs.NewNodeEvent().
+		SetSubsystem(structs.NodeEventSubsystemConfig).
+		SetMessage(msg)
+}
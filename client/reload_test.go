@@ -0,0 +1,305 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/client/allocrunner"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// testStagedReloadConfig returns a copy of the client's config that adds a
+// host volume and stages reloads.
+func testStagedReloadConfig(t *testing.T, c *Client, window time.Duration) *config.Config {
+	newConfig := c.GetConfig().Copy()
+	newConfig.HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"data": {Name: "data", Path: t.TempDir()},
+	}
+	newConfig.ReloadObservationWindow = window
+	newConfig.ReloadRollbackThreshold = 2
+	return newConfig
+}
+
+func TestClient_Reload_Staged_Rejected(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	newConfig := testStagedReloadConfig(t, c, time.Minute)
+	newConfig.HostVolumes["data"].Path = "/does/not/exist"
+
+	err := c.Reload(newConfig)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to validate volume data")
+
+	// Nothing was applied
+	require.Empty(t, c.Node().HostVolumes)
+	require.Nil(t, c.reloadMonitor)
+}
+
+func TestClient_Reload_HostVolumePaths(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	// Host volume paths are checked without staging the reload
+	newConfig := testStagedReloadConfig(t, c, 0)
+	newConfig.HostVolumes["data"].Path = "/does/not/exist"
+
+	err := c.Reload(newConfig)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to validate volume data")
+	require.Empty(t, c.Node().HostVolumes)
+	require.Empty(t, c.GetConfig().HostVolumes)
+}
+
+func TestClient_Reload_HostNetworkCIDRs(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	// A comma-separated cidr is checked block by block
+	newConfig := testStagedReloadConfig(t, c, time.Minute)
+	newConfig.HostNetworks = map[string]*structs.ClientHostNetworkConfig{
+		"public": {Name: "public", CIDR: "10.0.0.0/8, fd00::/8"},
+	}
+	require.NoError(t, c.Reload(newConfig))
+
+	// And so are the entries of cidrs
+	newConfig = testStagedReloadConfig(t, c, time.Minute)
+	newConfig.HostNetworks = map[string]*structs.ClientHostNetworkConfig{
+		"public": {Name: "public", CIDRs: []string{"10.0.0.0/8", "nope"}},
+	}
+	err := c.Reload(newConfig)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid cidr")
+	require.Equal(t, "10.0.0.0/8, fd00::/8", c.GetConfig().HostNetworks["public"].CIDR)
+}
+
+func TestClient_Reload_Staged_Rollback(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	require.NoError(t, c.Reload(testStagedReloadConfig(t, c, time.Minute)))
+	require.Contains(t, c.Node().HostVolumes, "data")
+	require.NotNil(t, c.reloadMonitor)
+
+	// A failure below the threshold keeps the reload
+	c.recordFingerprintError("cni", fmt.Errorf("broken"))
+	require.Contains(t, c.Node().HostVolumes, "data")
+
+	// An allocation that failed to set up after the reload reaches the
+	// threshold, and is only counted once
+	alloc := &structs.Allocation{
+		ID: "alloc1",
+		TaskStates: map[string]*structs.TaskState{
+			"web": {
+				Events: []*structs.TaskEvent{
+					structs.NewTaskEvent(structs.TaskSetupFailure),
+				},
+			},
+		},
+	}
+	c.recordAllocSetupFailure(alloc)
+	c.recordAllocSetupFailure(alloc)
+
+	testutil.WaitForResult(func() (bool, error) {
+		if vols := c.Node().HostVolumes; len(vols) != 0 {
+			return false, fmt.Errorf("expected host volumes to be rolled back, got %v", vols)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+	require.Empty(t, c.GetConfig().HostVolumes)
+
+	c.reloadMonitorLock.Lock()
+	defer c.reloadMonitorLock.Unlock()
+	require.Nil(t, c.reloadMonitor)
+}
+
+func TestClient_Reload_Staged_Kept(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	require.NoError(t, c.Reload(testStagedReloadConfig(t, c, 10*time.Millisecond)))
+
+	// Failures after the observation window are not counted
+	testutil.WaitForResult(func() (bool, error) {
+		c.reloadMonitorLock.Lock()
+		defer c.reloadMonitorLock.Unlock()
+		return c.reloadMonitor == nil, fmt.Errorf("reload still observed")
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+	c.recordFingerprintError("cni", fmt.Errorf("broken"))
+	c.recordFingerprintError("cni", fmt.Errorf("broken"))
+	require.Contains(t, c.Node().HostVolumes, "data")
+}
+
+func TestClient_Reload_Staged_Superseded(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	require.NoError(t, c.Reload(testStagedReloadConfig(t, c, time.Minute)))
+	c.reloadMonitorLock.Lock()
+	m := c.reloadMonitor
+	c.reloadMonitorLock.Unlock()
+
+	// A rollback of a reload that has since been superseded is skipped
+	newConfig := c.GetConfig().Copy()
+	require.NoError(t, c.Reload(newConfig))
+	c.rollbackReload(m, reloadSignalFingerprint, 2)
+	require.Equal(t, newConfig.HostVolumes, c.GetConfig().HostVolumes)
+}
+
+func TestAllocSetupFailedSince(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	event := structs.NewTaskEvent(structs.TaskSetupFailure)
+	event.Time = now.Add(-time.Minute).UnixNano()
+	alloc := &structs.Allocation{
+		TaskStates: map[string]*structs.TaskState{
+			"web": {Events: []*structs.TaskEvent{event}},
+		},
+	}
+
+	require.True(t, allocSetupFailedSince(alloc, now.Add(-time.Hour)))
+	require.False(t, allocSetupFailedSince(alloc, now))
+}
+
+func TestClient_Reload_ReloadableFields(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	newConfig := c.GetConfig().Copy()
+	newConfig.GCInterval = 3 * time.Minute
+	newConfig.GCMaxAllocs = 7
+	newConfig.StatsCollectionInterval = 20 * time.Second
+	newConfig.TemplateConfig = &config.ClientTemplateConfig{DisableSandbox: true}
+	newConfig.StateDir = "/does/not/exist"
+	require.NoError(t, c.Reload(newConfig))
+
+	// The garbage collector uses the new interval and limit
+	gcConfig := c.garbageCollector.getConfig()
+	require.Equal(t, 3*time.Minute, gcConfig.Interval)
+	require.Equal(t, 7, gcConfig.MaxAllocs)
+
+	// New allocations see the reloaded fields, but not the ones that
+	// require a restart
+	conf := c.GetConfig()
+	require.Equal(t, 20*time.Second, conf.StatsCollectionInterval)
+	require.True(t, conf.TemplateConfig.DisableSandbox)
+	require.NotEqual(t, "/does/not/exist", conf.StateDir)
+}
+
+func TestClient_Reload_ReplacesConfigCopy(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+
+	// Alloc runners read the copy they were given without the config lock,
+	// so a reload must not modify it
+	previous := c.GetConfig()
+	previousTemplate := previous.TemplateConfig.Copy()
+
+	newConfig := previous.Copy()
+	newConfig.TemplateConfig = &config.ClientTemplateConfig{DisableSandbox: true}
+	require.NoError(t, c.Reload(newConfig))
+
+	require.Equal(t, previousTemplate, previous.TemplateConfig)
+	require.NotSame(t, previous, c.GetConfig())
+	require.True(t, c.GetConfig().TemplateConfig.DisableSandbox)
+}
+
+func TestClient_Reload_GCInterval(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, func(c *config.Config) {
+		c.GCInterval = time.Hour
+		c.GCMaxAllocAge = time.Minute
+	})
+	defer cleanup()
+
+	ar, arCleanup := allocrunner.TestAllocRunnerFromAlloc(t, mock.Alloc())
+	defer arCleanup()
+	go ar.Run()
+	exitAllocRunner(ar)
+	c.garbageCollector.MarkForCollection(ar.Alloc().ID, ar)
+	c.garbageCollector.allocRunners.index[ar.Alloc().ID].timeStamp = time.Now().Add(-time.Hour)
+
+	// The aged alloc is collected once the reloaded interval elapses,
+	// rather than after the hour the client started with
+	newConfig := c.GetConfig().Copy()
+	newConfig.GCInterval = 10 * time.Millisecond
+	require.NoError(t, c.Reload(newConfig))
+
+	testutil.WaitForResult(func() (bool, error) {
+		if n := c.garbageCollector.allocRunners.Length(); n != 0 {
+			return false, fmt.Errorf("expected alloc to be collected, %d left", n)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestClient_Reload_Outcomes(t *testing.T) {
+	t.Parallel()
+
+	c, cleanup := TestClient(t, nil)
+	defer cleanup()
+	require.Nil(t, c.LastReloadOutcomes())
+
+	newConfig := c.GetConfig().Copy()
+	newConfig.GCInterval = 3 * time.Minute
+	newConfig.TemplateConfig = &config.ClientTemplateConfig{DisableSandbox: true}
+	newConfig.HostVolumes = map[string]*structs.ClientHostVolumeConfig{
+		"data": {Name: "data", Path: t.TempDir()},
+	}
+	require.NoError(t, c.Reload(newConfig))
+
+	// Only the subsystems whose config changed are restarted or updated
+	require.Equal(t, config.ReloadOutcomes{
+		"artifacts":     config.ReloadUnchanged,
+		"gc":            config.ReloadRestarted,
+		"host_networks": config.ReloadUnchanged,
+		"host_volumes":  config.ReloadUpdated,
+		"stats":         config.ReloadUnchanged,
+		"template":      config.ReloadUpdated,
+		"tls":           config.ReloadUnchanged,
+	}, c.LastReloadOutcomes())
+
+	// Reloading the same config again changes nothing
+	require.NoError(t, c.Reload(newConfig.Copy()))
+	for subsystem, outcome := range c.LastReloadOutcomes() {
+		require.Equal(t, config.ReloadUnchanged, outcome, "subsystem %q", subsystem)
+	}
+
+	newConfig = newConfig.Copy()
+	newConfig.StatsCollectionInterval = 20 * time.Second
+	newConfig.ArtifactChecksumExemptPrefixes = []string{"https://registry.internal/"}
+	require.NoError(t, c.Reload(newConfig))
+	require.Equal(t,
+		"artifacts: updated, gc: unchanged, host_networks: unchanged, host_volumes: unchanged, "+
+			"stats: updated, template: unchanged, tls: unchanged",
+		c.LastReloadOutcomes().String())
+}
@@ -54,7 +54,8 @@ func (c *Client) RPC(method string, args interface{}, reply interface{}) error {
 
 	// We will try to automatically retry requests that fail due to things like server unavailability
 	// but instead of retrying forever, lets have a solid upper-bound
-	deadline := time.Now()
+	start := time.Now()
+	deadline := start
 
 	// A reasonable amount of time for leader election. Note when servers forward() our RPC requests
 	// to the leader they may also allow for an RPCHoldTimeout while waiting for leader election.
@@ -104,7 +105,7 @@ TRY:
 			info.SetTimeToBlock(0)
 			return c.RPC(method, args, reply)
 		}
-		c.rpcLogger.Error("error performing RPC to server, deadline exceeded, cannot retry", "error", rpcErr, "rpc", method, "server", server.Addr)
+		c.rpcHoldTimeoutExceeded(method, server, time.Since(start), rpcErr)
 		return rpcErr
 	}
 
@@ -128,6 +129,18 @@ TRY:
 	return rpcErr
 }
 
+// rpcHoldTimeoutExceeded reports an RPC that failed after being held for its
+// hold timeout. no_leader is logged so that the failure can be correlated
+// with a leader election on the servers.
+func (c *Client) rpcHoldTimeoutExceeded(method string, server *servers.Server, held time.Duration, rpcErr error) {
+	c.rpcLogger.Error("error performing RPC to server, deadline exceeded, cannot retry",
+		"error", rpcErr, "rpc", method, "server", server.Addr,
+		"held", held, "hold_timeout", c.config.RPCHoldTimeout,
+		"no_leader", structs.IsErrNoLeader(rpcErr))
+	metrics.IncrCounterWithLabels([]string{"client", "rpc", "hold_timeout_exceeded"}, 1,
+		[]metrics.Label{{Name: "method", Value: method}})
+}
+
 // canRetry returns true if the given situation is safe for a retry.
 func canRetry(args interface{}, err error) bool {
 	// No leader errors are always safe to retry since no state could have
@@ -1,9 +1,14 @@
 package client
 
 import (
+	"encoding/json"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -113,3 +118,100 @@ func TestRpc_streamingRpcConn_badEndpoint_TLS(t *testing.T) {
 	require.NotNil(err)
 	require.Contains(err.Error(), "Unknown rpc method: \"Bogus\"")
 }
+
+// logCollector collects the JSON log lines written to it.
+type logCollector struct {
+	lines []map[string]interface{}
+	lock  sync.Mutex
+}
+
+func (l *logCollector) Write(p []byte) (int, error) {
+	var line map[string]interface{}
+	if err := json.Unmarshal(p, &line); err != nil {
+		return 0, err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lines = append(l.lines, line)
+	return len(p), nil
+}
+
+// find returns the first line logged with the message and fields.
+func (l *logCollector) find(msg string, fields map[string]interface{}) map[string]interface{} {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+LINES:
+	for _, line := range l.lines {
+		if line["@message"] != msg {
+			continue
+		}
+		for k, v := range fields {
+			if line[k] != v {
+				continue LINES
+			}
+		}
+		return line
+	}
+	return nil
+}
+
+func TestRpc_HoldTimeoutExceeded(t *testing.T) {
+	// Not parallel as the test replaces the global metrics sink
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	metricsConf := metrics.DefaultConfig("nomad")
+	metricsConf.EnableHostname = false
+	metricsConf.EnableRuntimeMetrics = false
+	_, err := metrics.NewGlobal(metricsConf, sink)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		// Put back the blackhole sink the global metrics start with
+		metrics.NewGlobal(metricsConf, &metrics.BlackholeSink{})
+	})
+
+	// The server never elects a leader, so it holds RPCs and fails them
+	s1, cleanupS1 := nomad.TestServer(t, func(c *nomad.Config) {
+		c.BootstrapExpect = 3
+		c.RPCHoldTimeout = 10 * time.Millisecond
+	})
+	defer cleanupS1()
+
+	logs := &logCollector{}
+	c, cleanupC := TestClient(t, func(c *config.Config) {
+		c.Servers = []string{s1.GetConfig().RPCAddr.String()}
+		c.RPCHoldTimeout = 100 * time.Millisecond
+		c.Logger.RegisterSink(hclog.NewSinkAdapter(&hclog.LoggerOptions{
+			Level:      hclog.Error,
+			Output:     logs,
+			JSONFormat: true,
+		}))
+	})
+	defer cleanupC()
+
+	req := &structs.NodeSpecificRequest{
+		NodeID:       c.NodeID(),
+		QueryOptions: structs.QueryOptions{Region: c.Region()},
+	}
+	var resp structs.SingleNodeResponse
+	err = c.RPC("Node.GetNode", req, &resp)
+	require.Error(t, err)
+	require.True(t, structs.IsErrNoLeader(err))
+
+	line := logs.find("error performing RPC to server, deadline exceeded, cannot retry",
+		map[string]interface{}{"rpc": "Node.GetNode"})
+	require.NotNil(t, line)
+	require.Equal(t, true, line["no_leader"])
+	require.Contains(t, line, "held")
+	require.Contains(t, line, "hold_timeout")
+
+	var count int
+	for _, interval := range sink.Data() {
+		interval.RLock()
+		if counter, ok := interval.Counters["nomad.client.rpc.hold_timeout_exceeded;method=Node.GetNode"]; ok {
+			count += counter.Count
+		}
+		interval.RUnlock()
+	}
+	require.Equal(t, 1, count)
+}
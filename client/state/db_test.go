@@ -11,7 +11,9 @@ import (
 	trstate "github.com/hashicorp/nomad/client/allocrunner/taskrunner/state"
 	dmstate "github.com/hashicorp/nomad/client/devicemanager/state"
 	"github.com/hashicorp/nomad/client/dynamicplugins"
+	"github.com/hashicorp/nomad/client/pluginmanager/csimanager"
 	driverstate "github.com/hashicorp/nomad/client/pluginmanager/drivermanager/state"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/helper/testlog"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -306,6 +308,40 @@ func TestStateDB_TaskState(t *testing.T) {
 
 // TestStateDB_DeviceManager asserts the behavior of device manager state related StateDB
 // methods.
+// TestStateDB_CSIVolumes asserts the behavior of the CSI volume related
+// StateDB methods.
+func TestStateDB_CSIVolumes(t *testing.T) {
+	t.Parallel()
+
+	testDB(t, func(t *testing.T, db StateDB) {
+		alloc := mock.Alloc()
+
+		// Getting nonexistent state should return nils
+		vols, err := db.GetCSIVolumes(alloc.ID)
+		require.NoError(t, err)
+		require.Nil(t, vols)
+
+		// Putting volumes requires the alloc to exist
+		require.NoError(t, db.PutAllocation(alloc))
+		expected := map[string]*cstructs.CSIVolumeState{
+			"vol0": cstructs.NewCSIVolumeState(
+				&structs.CSIVolume{ID: "testvol0", PluginID: "plugin0", Capacity: 1024},
+				&csimanager.MountInfo{Source: "/mnt/testvol0"}),
+		}
+		require.NoError(t, db.PutCSIVolumes(alloc.ID, expected))
+
+		vols, err = db.GetCSIVolumes(alloc.ID)
+		require.NoError(t, err)
+		require.Equal(t, expected, vols)
+
+		// Clearing the volumes should work
+		require.NoError(t, db.PutCSIVolumes(alloc.ID, nil))
+		vols, err = db.GetCSIVolumes(alloc.ID)
+		require.NoError(t, err)
+		require.Empty(t, vols)
+	})
+}
+
 func TestStateDB_DeviceManager(t *testing.T) {
 	t.Parallel()
 
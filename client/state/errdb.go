@@ -7,6 +7,7 @@ import (
 	dmstate "github.com/hashicorp/nomad/client/devicemanager/state"
 	"github.com/hashicorp/nomad/client/dynamicplugins"
 	driverstate "github.com/hashicorp/nomad/client/pluginmanager/drivermanager/state"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -48,6 +49,14 @@ func (m *ErrDB) PutNetworkStatus(allocID string, ns *structs.AllocNetworkStatus,
 	return fmt.Errorf("Error!")
 }
 
+func (m *ErrDB) GetCSIVolumes(allocID string) (map[string]*cstructs.CSIVolumeState, error) {
+	return nil, fmt.Errorf("Error!")
+}
+
+func (m *ErrDB) PutCSIVolumes(allocID string, vols map[string]*cstructs.CSIVolumeState) error {
+	return fmt.Errorf("Error!")
+}
+
 func (m *ErrDB) GetTaskRunnerState(allocID string, taskName string) (*state.LocalState, *structs.TaskState, error) {
 	return nil, nil, fmt.Errorf("Error!")
 }
@@ -5,6 +5,7 @@ import (
 	dmstate "github.com/hashicorp/nomad/client/devicemanager/state"
 	"github.com/hashicorp/nomad/client/dynamicplugins"
 	driverstate "github.com/hashicorp/nomad/client/pluginmanager/drivermanager/state"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -38,6 +39,12 @@ type StateDB interface {
 	GetNetworkStatus(allocID string) (*structs.AllocNetworkStatus, error)
 	PutNetworkStatus(allocID string, ns *structs.AllocNetworkStatus, opts ...WriteOption) error
 
+	// Get/Put CSIVolumes get and put the CSI volumes the allocation has
+	// claimed and mounted, by the alias of their volume request. They may
+	// be nil.
+	GetCSIVolumes(allocID string) (map[string]*cstructs.CSIVolumeState, error)
+	PutCSIVolumes(allocID string, vols map[string]*cstructs.CSIVolumeState) error
+
 	// GetTaskRunnerState returns the LocalState and TaskState for a
 	// TaskRunner. Either state may be nil if it is not found, but if an
 	// error is encountered only the error will be non-nil.
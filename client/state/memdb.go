@@ -8,6 +8,7 @@ import (
 	dmstate "github.com/hashicorp/nomad/client/devicemanager/state"
 	"github.com/hashicorp/nomad/client/dynamicplugins"
 	driverstate "github.com/hashicorp/nomad/client/pluginmanager/drivermanager/state"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -23,6 +24,9 @@ type MemDB struct {
 	// alloc_id -> value
 	networkStatus map[string]*structs.AllocNetworkStatus
 
+	// alloc_id -> value
+	csiVolumes map[string]map[string]*cstructs.CSIVolumeState
+
 	// alloc_id -> task_name -> value
 	localTaskState map[string]map[string]*state.LocalState
 	taskState      map[string]map[string]*structs.TaskState
@@ -47,6 +51,7 @@ func NewMemDB(logger hclog.Logger) *MemDB {
 		allocs:         make(map[string]*structs.Allocation),
 		deployStatus:   make(map[string]*structs.AllocDeploymentStatus),
 		networkStatus:  make(map[string]*structs.AllocNetworkStatus),
+		csiVolumes:     make(map[string]map[string]*cstructs.CSIVolumeState),
 		localTaskState: make(map[string]map[string]*state.LocalState),
 		taskState:      make(map[string]map[string]*structs.TaskState),
 		logger:         logger,
@@ -106,6 +111,19 @@ func (m *MemDB) PutNetworkStatus(allocID string, ns *structs.AllocNetworkStatus,
 	return nil
 }
 
+func (m *MemDB) GetCSIVolumes(allocID string) (map[string]*cstructs.CSIVolumeState, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.csiVolumes[allocID], nil
+}
+
+func (m *MemDB) PutCSIVolumes(allocID string, vols map[string]*cstructs.CSIVolumeState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.csiVolumes[allocID] = vols
+	return nil
+}
+
 func (m *MemDB) GetTaskRunnerState(allocID string, taskName string) (*state.LocalState, *structs.TaskState, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -5,6 +5,7 @@ import (
 	dmstate "github.com/hashicorp/nomad/client/devicemanager/state"
 	"github.com/hashicorp/nomad/client/dynamicplugins"
 	driverstate "github.com/hashicorp/nomad/client/pluginmanager/drivermanager/state"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -43,6 +44,14 @@ func (n NoopDB) PutNetworkStatus(allocID string, ds *structs.AllocNetworkStatus,
 	return nil
 }
 
+func (n NoopDB) GetCSIVolumes(allocID string) (map[string]*cstructs.CSIVolumeState, error) {
+	return nil, nil
+}
+
+func (n NoopDB) PutCSIVolumes(allocID string, vols map[string]*cstructs.CSIVolumeState) error {
+	return nil
+}
+
 func (n NoopDB) GetTaskRunnerState(allocID string, taskName string) (*state.LocalState, *structs.TaskState, error) {
 	return nil, nil, nil
 }
@@ -13,6 +13,7 @@ import (
 	dmstate "github.com/hashicorp/nomad/client/devicemanager/state"
 	"github.com/hashicorp/nomad/client/dynamicplugins"
 	driverstate "github.com/hashicorp/nomad/client/pluginmanager/drivermanager/state"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/helper/boltdd"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -28,6 +29,7 @@ allocations/
    |--> alloc          -> allocEntry{*structs.Allocation}
 	 |--> deploy_status  -> deployStatusEntry{*structs.AllocDeploymentStatus}
 	 |--> network_status -> networkStatusEntry{*structs.AllocNetworkStatus}
+	 |--> csi_volumes    -> csiVolumesEntry{map[string]*cstructs.CSIVolumeState}
    |--> task-<name>/
       |--> local_state -> *trstate.LocalState # Local-only state
       |--> task_state  -> *structs.TaskState  # Sync'd to servers
@@ -74,6 +76,10 @@ var (
 	// stored under
 	allocNetworkStatusKey = []byte("network_status")
 
+	// allocCSIVolumesKey is the key the allocation's claimed and mounted
+	// CSI volumes are stored under
+	allocCSIVolumesKey = []byte("csi_volumes")
+
 	// allocations -> $allocid -> task-$taskname -> the keys below
 	taskLocalStateKey = []byte("local_state")
 	taskStateKey      = []byte("task_state")
@@ -372,6 +378,60 @@ func (s *BoltStateDB) GetNetworkStatus(allocID string) (*structs.AllocNetworkSta
 	return entry.NetworkStatus, nil
 }
 
+// csiVolumesEntry wraps values for CSIVolumes keys.
+type csiVolumesEntry struct {
+	Volumes map[string]*cstructs.CSIVolumeState
+}
+
+// PutCSIVolumes stores the CSI volumes an allocation has claimed and
+// mounted or returns an error.
+func (s *BoltStateDB) PutCSIVolumes(allocID string, vols map[string]*cstructs.CSIVolumeState) error {
+	return s.db.Update(func(tx *boltdd.Tx) error {
+		allocBkt, err := getAllocationBucket(tx, allocID)
+		if err != nil {
+			return err
+		}
+
+		entry := csiVolumesEntry{
+			Volumes: vols,
+		}
+		return allocBkt.Put(allocCSIVolumesKey, &entry)
+	})
+}
+
+// GetCSIVolumes retrieves the CSI volumes an allocation has claimed and
+// mounted or returns an error.
+func (s *BoltStateDB) GetCSIVolumes(allocID string) (map[string]*cstructs.CSIVolumeState, error) {
+	var entry csiVolumesEntry
+
+	err := s.db.View(func(tx *boltdd.Tx) error {
+		allAllocsBkt := tx.Bucket(allocationsBucketName)
+		if allAllocsBkt == nil {
+			// No state, return
+			return nil
+		}
+
+		allocBkt := allAllocsBkt.Bucket([]byte(allocID))
+		if allocBkt == nil {
+			// No state for alloc, return
+			return nil
+		}
+
+		return allocBkt.Get(allocCSIVolumesKey, &entry)
+	})
+
+	// It's valid for this field to be nil/missing
+	if boltdd.IsErrNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.Volumes, nil
+}
+
 // GetTaskRunnerState returns the LocalState and TaskState for a
 // TaskRunner. LocalState or TaskState will be nil if they do not exist.
 //
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/stats"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// stateDirReserve keeps disk space free for the client's state when the
+// state dir shares a filesystem with the alloc dir, so that allocations
+// filling the disk can't fail the writes of the state database. The garbage
+// collector lowers its disk usage threshold by the reserve, and new
+// allocations are rejected while less than the reserve is free.
+type stateDirReserve struct {
+	// reserveMB is the disk space kept free for the client's state
+	reserveMB int
+
+	// blocking is whether new allocations are rejected
+	blocking bool
+	lock     sync.Mutex
+}
+
+// newStateDirReserve returns the reserve for the client's state, or nil if
+// the state dir is on its own filesystem or no reserve is configured.
+func newStateDirReserve(logger hclog.Logger, cfg *config.Config) *stateDirReserve {
+	if cfg.StateDirReserveMB <= 0 || cfg.StateDir == "" || cfg.AllocDir == "" {
+		return nil
+	}
+
+	shared, err := sameFilesystem(cfg.StateDir, cfg.AllocDir)
+	if err != nil {
+		logger.Warn("failed to determine if the state dir and alloc dir share a filesystem", "error", err)
+		return nil
+	}
+	if !shared {
+		return nil
+	}
+
+	logger.Warn("state dir shares a filesystem with the alloc dir, allocations filling the disk could prevent the client from saving its state; "+
+		"keeping disk space free for the client's state",
+		"state_dir", cfg.StateDir, "alloc_dir", cfg.AllocDir, "state_dir_reserve_mb", cfg.StateDirReserveMB)
+	return &stateDirReserve{reserveMB: cfg.StateDirReserveMB}
+}
+
+// MB returns the disk space reserved for the client's state, which is zero
+// for a nil reserve.
+func (r *stateDirReserve) MB() int {
+	if r == nil {
+		return 0
+	}
+	return r.reserveMB
+}
+
+// Blocking returns whether new allocations are rejected.
+func (r *stateDirReserve) Blocking() bool {
+	if r == nil {
+		return false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.blocking
+}
+
+// check returns whether rejecting allocations changed since the last check,
+// and an error if less than the reserve is free according to the alloc
+// dir's disk stats.
+func (r *stateDirReserve) check(diskStats *stats.DiskStats) (bool, error) {
+	if r == nil || diskStats == nil {
+		return false, nil
+	}
+
+	blocking := diskStats.Available < uint64(r.reserveMB)*MB
+	r.lock.Lock()
+	changed := r.blocking != blocking
+	r.blocking = blocking
+	r.lock.Unlock()
+
+	if !blocking {
+		return changed, nil
+	}
+	return changed, structs.NewAllocRejectedError(fmt.Errorf(
+		"node has %d MB of free disk space, less than the %d MB reserved for the client's state",
+		diskStats.Available/MB, r.reserveMB))
+}
+
+// emitStateDirReserveEvent emits the node event recording the detection of
+// a state dir sharing the alloc dir's filesystem.
+func (c *Client) emitStateDirReserveEvent() {
+	if c.stateDirReserve == nil {
+		return
+	}
+	metrics.IncrCounter([]string{"client", "state_dir", "shared_filesystem"}, 1)
+	c.triggerNodeEvent(structs.NewNodeEvent().
+		SetSubsystem(structs.NodeEventSubsystemStorage).
+		SetMessage("State dir shares a filesystem with the alloc dir, reserving disk space for client state").
+		AddDetail("state_dir_reserve_mb", strconv.Itoa(c.stateDirReserve.MB())))
+}
+
+// checkStateDirReserve returns an error rejecting new allocations while less
+// than the state dir reserve is free, emitting node events when rejection
+// starts and stops.
+func (c *Client) checkStateDirReserve() error {
+	if c.stateDirReserve == nil {
+		return nil
+	}
+
+	var diskStats *stats.DiskStats
+	if hostStats := c.hostStatsCollector.Stats(); hostStats != nil {
+		diskStats = hostStats.AllocDirStats
+	}
+
+	changed, err := c.stateDirReserve.check(diskStats)
+	if changed {
+		msg := "Accepting new allocations, free disk space is above the state dir reserve"
+		if err != nil {
+			msg = "Rejecting new allocations, free disk space is below the state dir reserve"
+			c.logger.Error("rejecting new allocations to keep disk space free for the client's state", "error", err)
+		} else {
+			c.logger.Info("accepting new allocations, free disk space is above the state dir reserve")
+		}
+		c.triggerNodeEvent(structs.NewNodeEvent().
+			SetSubsystem(structs.NodeEventSubsystemStorage).
+			SetMessage(msg).
+			AddDetail("state_dir_reserve_mb", strconv.Itoa(c.stateDirReserve.MB())))
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/stats"
+	"github.com/hashicorp/nomad/helper/testlog"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateDirReserve_New(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DefaultConfig()
+	cfg.StateDir = t.TempDir()
+	cfg.AllocDir = t.TempDir()
+
+	// Temporary dirs share a filesystem
+	reserve := newStateDirReserve(testlog.HCLogger(t), cfg)
+	require.NotNil(t, reserve)
+	require.Equal(t, config.DefaultStateDirReserveMB, reserve.MB())
+
+	// A zero reserve disables it
+	cfg.StateDirReserveMB = 0
+	require.Nil(t, newStateDirReserve(testlog.HCLogger(t), cfg))
+
+	// A nil reserve reserves nothing and never blocks
+	var nilReserve *stateDirReserve
+	require.Zero(t, nilReserve.MB())
+	require.False(t, nilReserve.Blocking())
+}
+
+func TestStateDirReserve_Check(t *testing.T) {
+	t.Parallel()
+
+	reserve := &stateDirReserve{reserveMB: 100}
+
+	changed, err := reserve.check(&stats.DiskStats{Available: 200 * MB})
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.False(t, reserve.Blocking())
+
+	changed, err = reserve.check(&stats.DiskStats{Available: 50 * MB})
+	require.EqualError(t, err, "node has 50 MB of free disk space, less than the 100 MB reserved for the client's state")
+	require.True(t, changed)
+	require.True(t, reserve.Blocking())
+
+	// Allocations rejected for the reserve are replaced by the scheduler
+	require.True(t, structs.IsAllocRejected(err))
+	require.True(t, makeFailedAlloc(mock.Alloc(), err).RejectedByClient())
+
+	changed, err = reserve.check(&stats.DiskStats{Available: 40 * MB})
+	require.Error(t, err)
+	require.False(t, changed)
+
+	changed, err = reserve.check(&stats.DiskStats{Available: 150 * MB})
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.False(t, reserve.Blocking())
+
+	// Missing stats never block
+	changed, err = reserve.check(nil)
+	require.NoError(t, err)
+	require.False(t, changed)
+}
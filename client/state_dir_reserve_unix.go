@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package client
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// sameFilesystem returns whether the paths are on the same filesystem.
+func sameFilesystem(a, b string) (bool, error) {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+
+	aStat, aOk := aInfo.Sys().(*syscall.Stat_t)
+	bStat, bOk := bInfo.Sys().(*syscall.Stat_t)
+	if !aOk || !bOk {
+		return false, fmt.Errorf("failed to read the devices of %s and %s", a, b)
+	}
+	return aStat.Dev == bStat.Dev, nil
+}
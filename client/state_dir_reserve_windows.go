@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package client
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// sameFilesystem returns whether the paths are on the same volume.
+func sameFilesystem(a, b string) (bool, error) {
+	return strings.EqualFold(filepath.VolumeName(a), filepath.VolumeName(b)), nil
+}
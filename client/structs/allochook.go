@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/hashicorp/nomad/client/pluginmanager/csimanager"
+	"github.com/hashicorp/nomad/nomad/structs"
 )
 
 // AllocHookResources contains data that is provided by AllocRunner Hooks for
@@ -11,9 +12,21 @@ import (
 type AllocHookResources struct {
 	CSIMounts map[string]*csimanager.MountInfo
 
+	// CSIMountSources describes the volume mounted under each alias of
+	// CSIMounts
+	CSIMountSources map[string]*CSIMountSource
+
 	mu sync.RWMutex
 }
 
+// CSIMountSource describes the CSI volume mounted for an allocation under a
+// volume request's alias.
+type CSIMountSource struct {
+	VolumeID   string
+	PluginID   string
+	AccessMode structs.CSIVolumeAccessMode
+}
+
 func (a *AllocHookResources) GetCSIMounts() map[string]*csimanager.MountInfo {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
@@ -27,3 +40,59 @@ func (a *AllocHookResources) SetCSIMounts(m map[string]*csimanager.MountInfo) {
 
 	a.CSIMounts = m
 }
+
+func (a *AllocHookResources) GetCSIMountSources() map[string]*CSIMountSource {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.CSIMountSources
+}
+
+func (a *AllocHookResources) SetCSIMountSources(m map[string]*CSIMountSource) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.CSIMountSources = m
+}
+
+// CSIVolumeState records a CSI volume an allocation has claimed and mounted.
+// It's persisted so that a restored allocation reuses its claim and mount
+// instead of claiming and mounting the volume again.
+type CSIVolumeState struct {
+	// Volume is a stub of the claimed volume, with the fields needed to
+	// unmount it and release its claim
+	Volume *structs.CSIVolume
+
+	// MountInfo is the volume's mount. It is nil for a volume whose claim is
+	// recorded before it is mounted.
+	MountInfo *csimanager.MountInfo
+
+	// Unpublished is set once the volume's claim has been released, so that
+	// a restored allocation doesn't release it again
+	Unpublished bool
+
+	// RemovedRequest is the request of a volume an in-place update removed
+	// from the task group. The volume stays claimed and mounted until the
+	// allocation stops, as its tasks may still use it.
+	RemovedRequest *structs.VolumeRequest
+
+	// ClaimIndex is the order the allocation claimed the volume in, so that
+	// a restored allocation that is drained still releases its volumes in
+	// the reverse order of their claims. It is 0 in the state of clients
+	// that didn't record it.
+	ClaimIndex int
+}
+
+// NewCSIVolumeState returns the state of a claimed and mounted volume.
+func NewCSIVolumeState(vol *structs.CSIVolume, mountInfo *csimanager.MountInfo) *CSIVolumeState {
+	return &CSIVolumeState{
+		Volume: &structs.CSIVolume{
+			ID:         vol.ID,
+			Namespace:  vol.Namespace,
+			ExternalID: vol.ExternalID,
+			PluginID:   vol.PluginID,
+			Capacity:   vol.Capacity,
+		},
+		MountInfo: mountInfo,
+	}
+}
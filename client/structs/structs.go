@@ -201,6 +201,36 @@ type AllocStatsResponse struct {
 	structs.QueryMeta
 }
 
+// AllocCSIMountsRequest is used to request where the CSI volumes of a given
+// allocation are mounted on the host
+type AllocCSIMountsRequest struct {
+	// AllocID is the allocation to retrieve the mounts of
+	AllocID string
+
+	structs.QueryOptions
+}
+
+// AllocCSIMountsResponse is used to return the CSI volume mounts of a given
+// allocation, by the alias of their volume request.
+type AllocCSIMountsResponse struct {
+	Mounts map[string]*AllocCSIMount
+	structs.QueryMeta
+}
+
+// AllocCSIMount describes where a CSI volume of an allocation is mounted on
+// the host.
+type AllocCSIMount struct {
+	VolumeID   string
+	PluginID   string
+	AccessMode structs.CSIVolumeAccessMode
+
+	// HostPath is the path the volume is mounted at on the host
+	HostPath string
+
+	// IsDevice is set when the volume is published as a block device
+	IsDevice bool
+}
+
 // MemoryStats holds memory usage related stats
 type MemoryStats struct {
 	RSS            uint64
@@ -259,16 +289,59 @@ func (cs *CpuStats) Add(other *CpuStats) {
 	cs.Measured = joinStringSet(cs.Measured, other.Measured)
 }
 
+// DiskIOStats holds block device I/O stats read from a cgroup
+type DiskIOStats struct {
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadOps    uint64
+	WriteOps   uint64
+
+	// Devices holds the stats of each block device, keyed by device name
+	Devices map[string]*DiskIOStats
+
+	// A list of fields whose values were actually sampled
+	Measured []string
+}
+
+func (ds *DiskIOStats) Add(other *DiskIOStats) {
+	if other == nil {
+		return
+	}
+
+	ds.ReadBytes += other.ReadBytes
+	ds.WriteBytes += other.WriteBytes
+	ds.ReadOps += other.ReadOps
+	ds.WriteOps += other.WriteOps
+	ds.Measured = joinStringSet(ds.Measured, other.Measured)
+
+	for name, dev := range other.Devices {
+		if ds.Devices == nil {
+			ds.Devices = make(map[string]*DiskIOStats, len(other.Devices))
+		}
+		if ds.Devices[name] == nil {
+			ds.Devices[name] = &DiskIOStats{}
+		}
+		ds.Devices[name].Add(dev)
+	}
+}
+
 // ResourceUsage holds information related to cpu and memory stats
 type ResourceUsage struct {
 	MemoryStats *MemoryStats
 	CpuStats    *CpuStats
+	DiskIOStats *DiskIOStats
 	DeviceStats []*device.DeviceGroupStats
 }
 
 func (ru *ResourceUsage) Add(other *ResourceUsage) {
 	ru.MemoryStats.Add(other.MemoryStats)
 	ru.CpuStats.Add(other.CpuStats)
+	if other.DiskIOStats != nil {
+		if ru.DiskIOStats == nil {
+			ru.DiskIOStats = &DiskIOStats{}
+		}
+		ru.DiskIOStats.Add(other.DiskIOStats)
+	}
 	ru.DeviceStats = append(ru.DeviceStats, other.DeviceStats...)
 }
 
@@ -31,6 +31,10 @@ const (
 	// directory where it can store sensitive data.
 	SecretsDir = "NOMAD_SECRETS_DIR"
 
+	// TmpfsDir is the environment variable with the path to the tasks tmpfs
+	// scratch directory, if it requested one.
+	TmpfsDir = "NOMAD_TMPFS_DIR"
+
 	// MemLimit is the environment variable with the tasks memory limit in MBs.
 	MemLimit = "NOMAD_MEMORY_LIMIT"
 
@@ -38,6 +38,9 @@ type VaultClient interface {
 	// GetConsulACL fetches the Consul ACL token required for the task
 	GetConsulACL(string, string) (*vaultapi.Secret, error)
 
+	// ReadSecret reads the secret at a path using the given token
+	ReadSecret(token, path string) (*vaultapi.Secret, error)
+
 	// RenewToken renews a token with the given increment and adds it to
 	// the min-heap for periodic renewal.
 	RenewToken(string, int) (<-chan error, error)
@@ -277,6 +280,28 @@ func (c *vaultClient) GetConsulACL(token, path string) (*vaultapi.Secret, error)
 	return c.client.Logical().Read(path)
 }
 
+// ReadSecret reads from vault the secret at the path, such as a KV entry,
+// using the supplied token.
+func (c *vaultClient) ReadSecret(token, path string) (*vaultapi.Secret, error) {
+	if !c.config.IsEnabled() {
+		return nil, fmt.Errorf("vault client not enabled")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("missing token")
+	}
+	if path == "" {
+		return nil, fmt.Errorf("missing secret path")
+	}
+
+	c.lock.Lock()
+	defer c.unlockAndUnset()
+
+	// Use the token supplied to interact with vault
+	c.client.SetToken(token)
+
+	return c.client.Logical().Read(path)
+}
+
 // RenewToken renews the supplied token for a given duration (in seconds) and
 // adds it to the min-heap so that it is renewed periodically by the renewal
 // loop. Any error returned during renewal will be written to a buffered
@@ -31,6 +31,10 @@ type MockVaultClient struct {
 	// a token is generated and returned
 	DeriveTokenFn func(a *structs.Allocation, tasks []string) (map[string]string, error)
 
+	// ReadSecretFn allows the caller to control the ReadSecret function. If
+	// not set no secret is returned
+	ReadSecretFn func(token, path string) (*vaultapi.Secret, error)
+
 	mu sync.Mutex
 }
 
@@ -117,6 +121,16 @@ func (vc *MockVaultClient) Stop() {}
 
 func (vc *MockVaultClient) GetConsulACL(string, string) (*vaultapi.Secret, error) { return nil, nil }
 
+func (vc *MockVaultClient) ReadSecret(token, path string) (*vaultapi.Secret, error) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if vc.ReadSecretFn != nil {
+		return vc.ReadSecretFn(token, path)
+	}
+	return nil, nil
+}
+
 // StoppedTokens tracks the tokens that have stopped renewing
 func (vc *MockVaultClient) StoppedTokens() []string {
 	vc.mu.Lock()
@@ -26,6 +26,7 @@ import (
 	"github.com/hashicorp/nomad/client/state"
 	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/command/agent/event"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/helper/pluginutils/loader"
 	"github.com/hashicorp/nomad/helper/uuid"
 	"github.com/hashicorp/nomad/nomad"
@@ -487,6 +488,13 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 		return nil, err
 	}
 
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if err := c.Warnings(); err != nil {
+		a.logger.Warn("client configuration has warnings", "warnings", err)
+	}
+
 	return c, nil
 }
 
@@ -522,6 +530,21 @@ func (a *Agent) finalizeClientConfig(c *clientconfig.Config) error {
 	c.PluginLoader = a.pluginLoader
 	c.PluginSingletonLoader = a.pluginSingletonLoader
 
+	// Expand environment variable references in client options. This is
+	// also done when the config is reloaded, so that the options always
+	// reflect the agent's current environment.
+	if !c.DisableOptionEnvInterpolation {
+		options, unset, err := clientconfig.ExpandOptions(c.Options, os.LookupEnv)
+		if err != nil {
+			return fmt.Errorf("failed to expand client options: %v", err)
+		}
+		if len(unset) > 0 {
+			a.logger.Warn("client options reference unset environment variables",
+				"variables", strings.Join(unset, ","))
+		}
+		c.Options = options
+	}
+
 	// Log deprecation messages about Consul related configuration in client
 	// options
 	var invalidConsulKeys []string
@@ -551,6 +574,7 @@ func convertClientConfig(agentConfig *Config) (*clientconfig.Config, error) {
 	}
 
 	conf.Servers = agentConfig.Client.Servers
+	conf.MaxServerConnections = agentConfig.Client.MaxServerConnections
 	conf.LogLevel = agentConfig.LogLevel
 	conf.DevMode = agentConfig.DevMode
 	conf.EnableDebug = agentConfig.EnableDebug
@@ -572,6 +596,9 @@ func convertClientConfig(agentConfig *Config) (*clientconfig.Config, error) {
 		conf.NetworkInterface = agentConfig.Client.NetworkInterface
 	}
 	conf.ChrootEnv = agentConfig.Client.ChrootEnv
+	conf.ChrootEnvMergeDefaults = agentConfig.Client.ChrootEnvMergeDefaults
+	conf.ChrootFragments = helper.CopyMapStringSliceString(agentConfig.Client.ChrootFragments)
+	conf.EnvDenylistPerNamespace = helper.CopyMapStringSliceString(agentConfig.Client.EnvDenylistPerNamespace)
 	conf.Options = agentConfig.Client.Options
 	if agentConfig.Client.NetworkSpeed != 0 {
 		conf.NetworkSpeed = agentConfig.Client.NetworkSpeed
@@ -589,6 +616,13 @@ func convertClientConfig(agentConfig *Config) (*clientconfig.Config, error) {
 		}
 		conf.MaxKillTimeout = dur
 	}
+	if agentConfig.Client.MaxFreezeDuration != "" {
+		dur, err := time.ParseDuration(agentConfig.Client.MaxFreezeDuration)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing max freeze duration: %s", err)
+		}
+		conf.MaxFreezeDuration = dur
+	}
 	conf.ClientMaxPort = uint(agentConfig.Client.ClientMaxPort)
 	conf.ClientMinPort = uint(agentConfig.Client.ClientMinPort)
 	conf.MaxDynamicPort = agentConfig.Client.MaxDynamicPort
@@ -597,6 +631,14 @@ func convertClientConfig(agentConfig *Config) (*clientconfig.Config, error) {
 
 	if agentConfig.Client.TemplateConfig != nil {
 		conf.TemplateConfig = agentConfig.Client.TemplateConfig.Copy()
+
+		switch conf.TemplateConfig.RenderDiffs {
+		case "", clientconfig.TemplateRenderDiffsHash, clientconfig.TemplateRenderDiffsFull:
+		default:
+			return nil, fmt.Errorf("invalid template render_diffs %q: must be %q or %q",
+				conf.TemplateConfig.RenderDiffs, clientconfig.TemplateRenderDiffsHash,
+				clientconfig.TemplateRenderDiffsFull)
+		}
 	}
 
 	hvMap := make(map[string]*structs.ClientHostVolumeConfig, len(agentConfig.Client.HostVolumes))
@@ -604,6 +646,7 @@ func convertClientConfig(agentConfig *Config) (*clientconfig.Config, error) {
 		hvMap[v.Name] = v
 	}
 	conf.HostVolumes = hvMap
+	conf.HostVolumeAllowMissingReadOnly = agentConfig.Client.HostVolumeAllowMissingReadOnly
 
 	// Setup the node
 	conf.Node = new(structs.Node)
@@ -669,7 +712,12 @@ func convertClientConfig(agentConfig *Config) (*clientconfig.Config, error) {
 	conf.GCParallelDestroys = agentConfig.Client.GCParallelDestroys
 	conf.GCDiskUsageThreshold = agentConfig.Client.GCDiskUsageThreshold
 	conf.GCInodeUsageThreshold = agentConfig.Client.GCInodeUsageThreshold
+	if agentConfig.Client.StateDirReserveMB != nil {
+		conf.StateDirReserveMB = *agentConfig.Client.StateDirReserveMB
+	}
 	conf.GCMaxAllocs = agentConfig.Client.GCMaxAllocs
+	conf.GCMaxAllocsPerNamespace = helper.CopyMapStringInt(agentConfig.Client.GCMaxAllocsPerNamespace)
+	conf.GCMaxAllocAge = agentConfig.Client.GCMaxAllocAge
 	if agentConfig.Client.NoHostUUID != nil {
 		conf.NoHostUUID = *agentConfig.Client.NoHostUUID
 	} else {
@@ -687,11 +735,21 @@ func convertClientConfig(agentConfig *Config) (*clientconfig.Config, error) {
 	conf.CNIConfigDir = agentConfig.Client.CNIConfigDir
 	conf.BridgeNetworkName = agentConfig.Client.BridgeNetworkName
 	conf.BridgeNetworkAllocSubnet = agentConfig.Client.BridgeNetworkSubnet
+	conf.BridgeNetworkCNIConfigPath = agentConfig.Client.BridgeNetworkCNIConfigPath
+	conf.BridgeNetworkIPAMGCDryRun = agentConfig.Client.BridgeNetworkIPAMGCDryRun
 
 	for _, hn := range agentConfig.Client.HostNetworks {
 		conf.HostNetworks[hn.Name] = hn
 	}
-	conf.BindWildcardDefaultHostNetwork = agentConfig.Client.BindWildcardDefaultHostNetwork
+
+	// Unless it's set, the default host network only binds the wildcard
+	// address when no host networks are defined, as it did before it could
+	// alongside host networks
+	if agentConfig.Client.BindWildcardDefaultHostNetwork != nil {
+		conf.BindWildcardDefaultHostNetwork = *agentConfig.Client.BindWildcardDefaultHostNetwork
+	} else {
+		conf.BindWildcardDefaultHostNetwork = len(conf.HostNetworks) == 0
+	}
 
 	conf.CgroupParent = agentConfig.Client.CgroupParent
 	if agentConfig.Client.ReserveableCores != "" {
@@ -702,6 +760,81 @@ func convertClientConfig(agentConfig *Config) (*clientconfig.Config, error) {
 		conf.ReservableCores = cores.ToSlice()
 	}
 
+	if agentConfig.Client.CSIVolumeMountTimeout != "" {
+		dur, err := time.ParseDuration(agentConfig.Client.CSIVolumeMountTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing csi_volume_mount_timeout: %s", err)
+		}
+		conf.CSIVolumeMountTimeout = dur
+	}
+	if agentConfig.Client.CSIPluginRegistrationWait != "" {
+		dur, err := time.ParseDuration(agentConfig.Client.CSIPluginRegistrationWait)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing csi_plugin_registration_wait: %s", err)
+		}
+		conf.CSIPluginRegistrationWait = dur
+	}
+	if agentConfig.Client.CSIDegradedPluginClaimDelay != "" {
+		dur, err := time.ParseDuration(agentConfig.Client.CSIDegradedPluginClaimDelay)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing csi_degraded_plugin_claim_delay: %s", err)
+		}
+		conf.CSIDegradedPluginClaimDelay = dur
+	}
+	conf.CSIVolumeHealthCheck = agentConfig.Client.CSIVolumeHealthCheck
+	conf.CSIMountMetadata = agentConfig.Client.CSIMountMetadata
+	conf.CSIClaimTopology = agentConfig.Client.CSIClaimTopology
+	conf.CSIClaimRetry = agentConfig.Client.CSIClaimRetry.Copy()
+	conf.CSIUnpublishRetry = agentConfig.Client.CSIUnpublishRetry.Copy()
+	if agentConfig.Client.CSIMaxVolumesPerAlloc != 0 {
+		conf.CSIMaxVolumesPerAlloc = agentConfig.Client.CSIMaxVolumesPerAlloc
+	}
+	if agentConfig.Client.CSIMaxNodeMounts != 0 {
+		conf.CSIMaxNodeMounts = agentConfig.Client.CSIMaxNodeMounts
+	}
+	if agentConfig.Client.MaxCSIMountedCapacityGB != 0 {
+		conf.MaxCSIMountedCapacityGB = agentConfig.Client.MaxCSIMountedCapacityGB
+	}
+	conf.CSIIdempotencyKeys = agentConfig.Client.CSIIdempotencyKeys
+	if agentConfig.Client.MaxTaskTmpfsMB != 0 {
+		conf.MaxTaskTmpfsMB = agentConfig.Client.MaxTaskTmpfsMB
+	}
+
+	if agentConfig.Client.ReloadObservationWindow != "" {
+		dur, err := time.ParseDuration(agentConfig.Client.ReloadObservationWindow)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing reload_observation_window: %s", err)
+		}
+		conf.ReloadObservationWindow = dur
+	}
+	conf.ReloadRollbackThreshold = agentConfig.Client.ReloadRollbackThreshold
+
+	if agentConfig.Client.PlacementFailureCacheTTL != "" {
+		dur, err := time.ParseDuration(agentConfig.Client.PlacementFailureCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing placement_failure_cache_ttl: %s", err)
+		}
+		conf.PlacementFailureCacheTTL = dur
+	}
+	conf.PlacementFailureCacheSize = agentConfig.Client.PlacementFailureCacheSize
+
+	conf.CSIClaimLabelEnv = helper.CopyMapStringString(agentConfig.Client.CSIClaimLabelEnv)
+
+	conf.ArtifactRequireChecksum = agentConfig.Client.ArtifactRequireChecksum
+	conf.DisableOptionEnvInterpolation = agentConfig.Client.DisableOptionEnvInterpolation
+	conf.ArtifactChecksumExemptPrefixes = agentConfig.Client.ArtifactChecksumExemptPrefixes
+	conf.NodeDownloadBandwidthMbps = agentConfig.Client.NodeDownloadBandwidthMbps
+	conf.NodeDownloadBandwidthWeights = helper.CopyMapStringInt(agentConfig.Client.NodeDownloadBandwidthWeights)
+	conf.CoreDumps = agentConfig.Client.CoreDumps.Copy()
+	conf.Audit = agentConfig.Client.Audit.Copy()
+
+	if len(agentConfig.Client.DriverHealth) != 0 {
+		conf.DriverHealthThresholds = make(map[string]*clientconfig.DriverHealthThreshold, len(agentConfig.Client.DriverHealth))
+		for _, threshold := range agentConfig.Client.DriverHealth {
+			conf.DriverHealthThresholds[threshold.Driver] = threshold.Copy()
+		}
+	}
+
 	return conf, nil
 }
 
@@ -115,6 +115,10 @@ func (s *HTTPServer) AgentSelfRequest(resp http.ResponseWriter, req *http.Reques
 		self.Config.Telemetry.CirconusAPIToken = "<redacted>"
 	}
 
+	// Client options are expanded into the client's config only, so values
+	// sourced from the environment are returned as their ${env:VAR}
+	// references rather than the values themselves.
+
 	return self, nil
 }
 
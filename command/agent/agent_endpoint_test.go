@@ -93,6 +93,27 @@ func TestHTTP_AgentSelf(t *testing.T) {
 	})
 }
 
+func TestHTTP_AgentSelf_OptionEnvInterpolation(t *testing.T) {
+	t.Setenv("NOMAD_TEST_OPTION_SECRET", "hunter2")
+
+	httpTest(t, func(c *Config) {
+		c.Client.Options = map[string]string{
+			"docker.auth.config": "${env:NOMAD_TEST_OPTION_SECRET}",
+		}
+	}, func(s *TestAgent) {
+		require.Equal(t, "hunter2", s.client.GetConfig().Options["docker.auth.config"])
+
+		req, err := http.NewRequest("GET", "/v1/agent/self", nil)
+		require.NoError(t, err)
+		obj, err := s.Server.AgentSelfRequest(httptest.NewRecorder(), req)
+		require.NoError(t, err)
+
+		// Values sourced from the environment are not exposed
+		self := obj.(agentSelf)
+		require.Equal(t, "${env:NOMAD_TEST_OPTION_SECRET}", self.Config.Client.Options["docker.auth.config"])
+	})
+}
+
 func TestHTTP_AgentSelf_ACL(t *testing.T) {
 	t.Parallel()
 	require := require.New(t)
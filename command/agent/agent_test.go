@@ -521,6 +521,52 @@ func TestAgent_ClientConfig(t *testing.T) {
 	}
 }
 
+func TestAgent_ClientConfig_BindWildcardDefaultHostNetwork(t *testing.T) {
+	t.Parallel()
+
+	hostNetwork := &structs.ClientHostNetworkConfig{Name: "private", CIDR: "192.168.0.0/16"}
+	cases := []struct {
+		name         string
+		hostNetworks []*structs.ClientHostNetworkConfig
+		bindWildcard *bool
+		expected     bool
+	}{
+		{
+			name:     "unset without host networks",
+			expected: true,
+		},
+		{
+			name:         "unset with host networks",
+			hostNetworks: []*structs.ClientHostNetworkConfig{hostNetwork},
+			expected:     false,
+		},
+		{
+			name:         "set with host networks",
+			hostNetworks: []*structs.ClientHostNetworkConfig{hostNetwork},
+			bindWildcard: helper.BoolToPtr(true),
+			expected:     true,
+		},
+		{
+			name:         "disabled",
+			bindWildcard: helper.BoolToPtr(false),
+			expected:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := DefaultConfig()
+			conf.Client.Enabled = true
+			conf.Client.HostNetworks = tc.hostNetworks
+			conf.Client.BindWildcardDefaultHostNetwork = tc.bindWildcard
+			a := &Agent{config: conf}
+			c, err := a.clientConfig()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, c.BindWildcardDefaultHostNetwork)
+		})
+	}
+}
+
 func TestAgent_ClientConfig_ReservedCores(t *testing.T) {
 	t.Parallel()
 	conf := DefaultConfig()
@@ -534,6 +580,40 @@ func TestAgent_ClientConfig_ReservedCores(t *testing.T) {
 	require.Exactly(t, []uint16{0, 2, 3}, c.Node.ReservedResources.Cpu.ReservedCpuCores)
 }
 
+func TestAgent_ClientConfig_OptionEnvInterpolation(t *testing.T) {
+	t.Setenv("NOMAD_TEST_OPTION_ENDPOINT", "unix:///run/docker.sock")
+
+	newAgent := func(options map[string]string, disable bool) *Agent {
+		conf := DefaultConfig()
+		conf.Client.Enabled = true
+		conf.Client.Options = options
+		conf.Client.DisableOptionEnvInterpolation = disable
+		return &Agent{config: conf}
+	}
+
+	options := map[string]string{"docker.endpoint": "${env:NOMAD_TEST_OPTION_ENDPOINT}"}
+	a := newAgent(options, false)
+	c, err := a.clientConfig()
+	require.NoError(t, err)
+	require.Equal(t, "unix:///run/docker.sock", c.Options["docker.endpoint"])
+
+	// The agent config keeps the reference rather than the value
+	require.Equal(t, "${env:NOMAD_TEST_OPTION_ENDPOINT}", a.config.Client.Options["docker.endpoint"])
+
+	// A reference to an unset variable fails to build the client config
+	a = newAgent(map[string]string{"docker.endpoint": "${env:NOMAD_TEST_OPTION_UNSET}"}, false)
+	_, err = a.clientConfig()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `"docker.endpoint"`)
+	require.Contains(t, err.Error(), `"NOMAD_TEST_OPTION_UNSET"`)
+
+	// Interpolation can be disabled
+	a = newAgent(map[string]string{"docker.endpoint": "${env:NOMAD_TEST_OPTION_UNSET}"}, true)
+	c, err = a.clientConfig()
+	require.NoError(t, err)
+	require.Equal(t, "${env:NOMAD_TEST_OPTION_UNSET}", c.Options["docker.endpoint"])
+}
+
 // Clients should inherit telemetry configuration
 func TestAgent_Client_TelemetryConfiguration(t *testing.T) {
 	assert := assert.New(t)
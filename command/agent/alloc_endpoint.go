@@ -180,6 +180,8 @@ func (s *HTTPServer) ClientAllocRequest(resp http.ResponseWriter, req *http.Requ
 	switch tokens[1] {
 	case "stats":
 		return s.allocStats(allocID, resp, req)
+	case "csi-mounts":
+		return s.allocCSIMounts(allocID, resp, req)
 	case "exec":
 		return s.allocExec(allocID, resp, req)
 	case "snapshot":
@@ -193,6 +195,10 @@ func (s *HTTPServer) ClientAllocRequest(resp http.ResponseWriter, req *http.Requ
 		return s.allocGC(allocID, resp, req)
 	case "signal":
 		return s.allocSignal(allocID, resp, req)
+	case "pause":
+		return s.allocPause(allocID, "Pause", resp, req)
+	case "resume":
+		return s.allocPause(allocID, "Resume", resp, req)
 	}
 
 	return nil, CodedError(404, resourceNotFoundErr)
@@ -349,6 +355,48 @@ func (s *HTTPServer) allocSignal(allocID string, resp http.ResponseWriter, req *
 	return reply, rpcErr
 }
 
+// allocPause pauses or resumes the allocation's tasks with the Pause or Resume
+// RPC named by method.
+func (s *HTTPServer) allocPause(allocID, method string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if !(req.Method == "POST" || req.Method == "PUT") {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	// Build the request and parse the ACL token
+	args := structs.AllocPauseRequest{}
+	if req.ContentLength != 0 {
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, fmt.Sprintf("Failed to decode body: %v", err))
+		}
+	}
+	s.parse(resp, req, &args.QueryOptions.Region, &args.QueryOptions)
+	args.AllocID = allocID
+
+	// Determine the handler to use
+	useLocalClient, useClientRPC, useServerRPC := s.rpcHandlerForAlloc(allocID)
+
+	// Make the RPC
+	var reply structs.GenericResponse
+	var rpcErr error
+	if useLocalClient {
+		rpcErr = s.agent.Client().ClientRPC("Allocations."+method, &args, &reply)
+	} else if useClientRPC {
+		rpcErr = s.agent.Client().RPC("ClientAllocations."+method, &args, &reply)
+	} else if useServerRPC {
+		rpcErr = s.agent.Server().RPC("ClientAllocations."+method, &args, &reply)
+	} else {
+		rpcErr = CodedError(400, "No local Node and node_id not provided")
+	}
+
+	if rpcErr != nil {
+		if structs.IsErrNoNodeConn(rpcErr) || structs.IsErrUnknownAllocation(rpcErr) {
+			rpcErr = CodedError(404, rpcErr.Error())
+		}
+	}
+
+	return reply, rpcErr
+}
+
 func (s *HTTPServer) allocSnapshot(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	var secret string
 	s.parseToken(req, &secret)
@@ -401,6 +449,39 @@ func (s *HTTPServer) allocStats(allocID string, resp http.ResponseWriter, req *h
 	return reply.Stats, rpcErr
 }
 
+func (s *HTTPServer) allocCSIMounts(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+
+	// Build the request and parse the ACL token
+	args := cstructs.AllocCSIMountsRequest{
+		AllocID: allocID,
+	}
+	s.parse(resp, req, &args.QueryOptions.Region, &args.QueryOptions)
+
+	// Determine the handler to use
+	useLocalClient, useClientRPC, useServerRPC := s.rpcHandlerForAlloc(allocID)
+
+	// Make the RPC
+	var reply cstructs.AllocCSIMountsResponse
+	var rpcErr error
+	if useLocalClient {
+		rpcErr = s.agent.Client().ClientRPC("Allocations.CSIMounts", &args, &reply)
+	} else if useClientRPC {
+		rpcErr = s.agent.Client().RPC("ClientAllocations.CSIMounts", &args, &reply)
+	} else if useServerRPC {
+		rpcErr = s.agent.Server().RPC("ClientAllocations.CSIMounts", &args, &reply)
+	} else {
+		rpcErr = CodedError(400, "No local Node and node_id not provided")
+	}
+
+	if rpcErr != nil {
+		if structs.IsErrNoNodeConn(rpcErr) || structs.IsErrUnknownAllocation(rpcErr) {
+			rpcErr = CodedError(404, rpcErr.Error())
+		}
+	}
+
+	return reply.Mounts, rpcErr
+}
+
 func (s *HTTPServer) allocExec(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Build the request and parse the ACL token
 	task := req.URL.Query().Get("task")
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ClientAuditRequest returns the entries of the local client's audit log,
+// oldest first, a page at a time if per_page is set. The log records secrets
+// and the actions of every token, so it can only be read with a management
+// token.
+func (s *HTTPServer) ClientAuditRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != http.MethodGet {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	client := s.agent.Client()
+	if client == nil {
+		return nil, clientNotRunning
+	}
+
+	var secret string
+	s.parseToken(req, &secret)
+
+	// Check management permissions
+	if aclObj, err := client.ResolveToken(secret); err != nil {
+		return nil, err
+	} else if aclObj != nil && !aclObj.IsManagement() {
+		return nil, structs.ErrPermissionDenied
+	}
+
+	var args structs.QueryOptions
+	parsePagination(req, &args)
+
+	// The next token is the index of the first entry of the page
+	var start uint64
+	if args.NextToken != "" {
+		var err error
+		start, err = strconv.ParseUint(args.NextToken, 10, 64)
+		if err != nil {
+			return nil, CodedError(400, fmt.Sprintf("Invalid next_token %q", args.NextToken))
+		}
+	}
+
+	entries, next, err := client.AuditEntries(start, int(args.PerPage))
+	if err != nil {
+		return nil, CodedError(500, err.Error())
+	}
+	if next > 0 {
+		setNextToken(resp, strconv.FormatUint(next, 10))
+	}
+	return entries, nil
+}
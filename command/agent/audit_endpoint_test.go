@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/client/audit"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP_ClientAuditRequest(t *testing.T) {
+	t.Parallel()
+	httpTest(t, func(c *Config) {
+		c.Client.Audit = &config.AuditConfig{Enabled: true}
+	}, func(s *TestAgent) {
+		// A mutating call through the client's HTTP API is recorded
+		req, err := http.NewRequest("PUT", "/v1/client/gc", nil)
+		require.NoError(t, err)
+		_, err = s.Server.ClientGCRequest(httptest.NewRecorder(), req)
+		require.NoError(t, err)
+
+		req, err = http.NewRequest("GET", "/v1/client/audit", nil)
+		require.NoError(t, err)
+		obj, err := s.Server.ClientAuditRequest(httptest.NewRecorder(), req)
+		require.NoError(t, err)
+		entries := obj.([]*audit.Entry)
+		require.Len(t, entries, 1)
+		require.Equal(t, "Allocations.GarbageCollectAll", entries[0].Endpoint)
+		require.Equal(t, audit.ResultOK, entries[0].Result)
+
+		// The log can be paged through
+		req, err = http.NewRequest("PUT", "/v1/client/gc", nil)
+		require.NoError(t, err)
+		_, err = s.Server.ClientGCRequest(httptest.NewRecorder(), req)
+		require.NoError(t, err)
+
+		req, err = http.NewRequest("GET", "/v1/client/audit?per_page=1", nil)
+		require.NoError(t, err)
+		respW := httptest.NewRecorder()
+		obj, err = s.Server.ClientAuditRequest(respW, req)
+		require.NoError(t, err)
+		require.Len(t, obj.([]*audit.Entry), 1)
+		require.Equal(t, "2", respW.Header().Get("X-Nomad-NextToken"))
+
+		req, err = http.NewRequest("GET", "/v1/client/audit?per_page=1&next_token=2", nil)
+		require.NoError(t, err)
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.ClientAuditRequest(respW, req)
+		require.NoError(t, err)
+		entries = obj.([]*audit.Entry)
+		require.Len(t, entries, 1)
+		require.Equal(t, uint64(2), entries[0].Index)
+		require.Empty(t, respW.Header().Get("X-Nomad-NextToken"))
+
+		req, err = http.NewRequest("GET", "/v1/client/audit?next_token=bad", nil)
+		require.NoError(t, err)
+		_, err = s.Server.ClientAuditRequest(httptest.NewRecorder(), req)
+		require.EqualError(t, err, `Invalid next_token "bad"`)
+
+		// The log is read-only
+		req, err = http.NewRequest("DELETE", "/v1/client/audit", nil)
+		require.NoError(t, err)
+		_, err = s.Server.ClientAuditRequest(httptest.NewRecorder(), req)
+		require.EqualError(t, err, ErrInvalidMethod)
+	})
+}
+
+func TestHTTP_ClientAuditRequest_ACL(t *testing.T) {
+	t.Parallel()
+	httpACLTest(t, nil, func(s *TestAgent) {
+		state := s.Agent.server.State()
+
+		req, err := http.NewRequest("GET", "/v1/client/audit", nil)
+		require.NoError(t, err)
+
+		// Try request without a token and expect failure
+		_, err = s.Server.ClientAuditRequest(httptest.NewRecorder(), req)
+		require.EqualError(t, err, structs.ErrPermissionDenied.Error())
+
+		// Tokens that may write to the agent and node still can't read it
+		token := mock.CreatePolicyAndToken(t, state, 1005, "agent-node-write",
+			mock.AgentPolicy(acl.PolicyWrite)+mock.NodePolicy(acl.PolicyWrite))
+		setToken(req, token)
+		_, err = s.Server.ClientAuditRequest(httptest.NewRecorder(), req)
+		require.EqualError(t, err, structs.ErrPermissionDenied.Error())
+
+		// Try request with a root token
+		setToken(req, s.RootToken)
+		_, err = s.Server.ClientAuditRequest(httptest.NewRecorder(), req)
+		require.NoError(t, err)
+	})
+}
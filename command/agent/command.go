@@ -245,7 +245,7 @@ func (c *Command) readConfig() *Config {
 		if config == nil {
 			config = current
 		} else {
-			config = config.Merge(current)
+			config = config.mergeFromSource(current, ConfigSourceFile)
 		}
 	}
 
@@ -259,7 +259,7 @@ func (c *Command) readConfig() *Config {
 	}
 
 	// Merge any CLI options over config file options
-	config = config.Merge(cmdConfig)
+	config = config.mergeFromSource(cmdConfig, ConfigSourceFlag)
 
 	// Set the version info
 	config.Version = c.Version
@@ -373,6 +373,15 @@ func (c *Command) isValidConfig(config, cmdConfig *Config) bool {
 		return false
 	}
 
+	if err := config.Consul.ValidateTLS(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Invalid consul TLS configuration: %v", err))
+		return false
+	}
+	if err := config.Vault.ValidateTLS(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Invalid vault TLS configuration: %v", err))
+		return false
+	}
+
 	if config.Client.MinDynamicPort < 0 || config.Client.MinDynamicPort > structs.MaxValidPort {
 		c.Ui.Error(fmt.Sprintf("Invalid dynamic port range: min_dynamic_port=%d", config.Client.MinDynamicPort))
 		return false
@@ -1005,6 +1014,14 @@ func (c *Command) handleReload() {
 			return
 		}
 
+		if err := clientConfig.Validate(); err != nil {
+			c.agent.logger.Error("invalid client config", "error", err)
+			return
+		}
+		if err := clientConfig.Warnings(); err != nil {
+			c.agent.logger.Warn("client configuration has warnings", "warnings", err)
+		}
+
 		if err := c.agent.Client().Reload(clientConfig); err != nil {
 			c.agent.logger.Error("reloading client config failed", "error", err)
 			return
@@ -12,7 +12,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/version"
 )
 
@@ -391,6 +393,29 @@ func TestIsValidConfig(t *testing.T) {
 			},
 			err: `host_network["test"].reserved_ports "3-2147483647" invalid: port must be < 65536 but found 2147483647`,
 		},
+		{
+			name: "MissingConsulCAFile",
+			conf: Config{
+				Client: &ClientConfig{Enabled: true},
+				Consul: &config.ConsulConfig{
+					EnableSSL: helper.BoolToPtr(true),
+					CAFile:    "/does/not/exist.pem",
+				},
+			},
+			err: "Invalid consul TLS configuration: failed to read ca_file",
+		},
+		{
+			name: "MismatchedVaultKeypair",
+			conf: Config{
+				Client: &ClientConfig{Enabled: true},
+				Vault: &config.VaultConfig{
+					Enabled:     helper.BoolToPtr(true),
+					TLSCertFile: "../../helper/tlsutil/testdata/nomad-foo.pem",
+					TLSKeyFile:  "../../helper/tlsutil/testdata/nomad-bad-key.pem",
+				},
+			},
+			err: "Invalid vault TLS configuration",
+		},
 	}
 
 	for _, tc := range cases {
@@ -29,9 +29,9 @@ import (
 // Config is the configuration for the Nomad agent.
 //
 // time.Duration values have two parts:
-// - a string field tagged with an hcl:"foo" and json:"-"
-// - a time.Duration field in the same struct and a call to duration
-//   in config_parse.go ParseConfigFile
+//   - a string field tagged with an hcl:"foo" and json:"-"
+//   - a time.Duration field in the same struct and a call to duration
+//     in config_parse.go ParseConfigFile
 //
 // All config structs should have an ExtraKeysHCL field to check for
 // unexpected keys
@@ -177,6 +177,10 @@ type Config struct {
 
 	// ExtraKeysHCL is used by hcl to surface unexpected keys
 	ExtraKeysHCL []string `hcl:",unusedKeys" json:"-"`
+
+	// fieldSources maps the fields set by config files and flags to their
+	// source, and is reported by FieldSources
+	fieldSources map[string]string
 }
 
 // ClientConfig is configuration specific to the client mode
@@ -193,6 +197,10 @@ type ClientConfig struct {
 	// Servers is a list of known server addresses. These are as "host:port"
 	Servers []string `hcl:"servers"`
 
+	// MaxServerConnections is the maximum number of servers the client keeps
+	// connections open to at once. Zero is unlimited.
+	MaxServerConnections int `hcl:"max_server_connections"`
+
 	// NodeClass is used to group the node by class
 	NodeClass string `hcl:"node_class"`
 
@@ -209,6 +217,20 @@ type ClientConfig struct {
 	// task's chroot.
 	ChrootEnv map[string]string `hcl:"chroot_env"`
 
+	// ChrootEnvMergeDefaults merges the chroot_env entries over the default
+	// chroot instead of replacing it.
+	ChrootEnvMergeDefaults bool `hcl:"chroot_env_merge_defaults"`
+
+	// ChrootFragments maps the names tasks may list in chroot_extras to the
+	// host paths embedded in their chroot, overriding the built-in fragments
+	// of the same name.
+	ChrootFragments map[string][]string `hcl:"chroot_fragments"`
+
+	// EnvDenylistPerNamespace maps namespaces to the host environment
+	// variables withheld from their tasks, in addition to the env.denylist
+	// option. The list of the empty namespace applies to every namespace.
+	EnvDenylistPerNamespace map[string][]string `hcl:"env_denylist_per_namespace"`
+
 	// Interface to use for network fingerprinting
 	NetworkInterface string `hcl:"network_interface"`
 
@@ -228,6 +250,10 @@ type ClientConfig struct {
 	// MaxKillTimeout allows capping the user-specifiable KillTimeout.
 	MaxKillTimeout string `hcl:"max_kill_timeout"`
 
+	// MaxFreezeDuration is the longest a task may stay paused before it's
+	// resumed automatically.
+	MaxFreezeDuration string `hcl:"max_freeze_duration"`
+
 	// ClientMaxPort is the upper range of the ports that the client uses for
 	// communicating with plugin subsystems
 	ClientMaxPort int `hcl:"client_max_port"`
@@ -266,10 +292,25 @@ type ClientConfig struct {
 	// client triggers GC of the terminal allocations
 	GCInodeUsageThreshold float64 `hcl:"gc_inode_usage_threshold"`
 
+	// StateDirReserveMB is the disk space kept free for the client's state
+	// when the state dir shares a filesystem with the alloc dir. Zero
+	// disables the reserve.
+	StateDirReserveMB *int `hcl:"state_dir_reserve_mb"`
+
 	// GCMaxAllocs is the maximum number of allocations a node can have
 	// before garbage collection is triggered.
 	GCMaxAllocs int `hcl:"gc_max_allocs"`
 
+	// GCMaxAllocsPerNamespace is the maximum number of terminal allocations
+	// of each listed namespace a node can have before they are garbage
+	// collected.
+	GCMaxAllocsPerNamespace map[string]int `hcl:"gc_max_allocs_per_namespace"`
+
+	// GCMaxAllocAge is the time after which terminal allocations are garbage
+	// collected regardless of the other thresholds.
+	GCMaxAllocAge    time.Duration
+	GCMaxAllocAgeHCL string `hcl:"gc_max_alloc_age" json:"-"`
+
 	// NoHostUUID disables using the host's UUID and will force generation of a
 	// random UUID.
 	NoHostUUID *bool `hcl:"no_host_uuid"`
@@ -287,6 +328,10 @@ type ClientConfig struct {
 	// available to jobs running on this node.
 	HostVolumes []*structs.ClientHostVolumeConfig `hcl:"host_volume"`
 
+	// HostVolumeAllowMissingReadOnly allows the client to start with
+	// read-only host volumes whose path is missing, logging a warning
+	HostVolumeAllowMissingReadOnly bool `hcl:"host_volume_allow_missing_read_only"`
+
 	// CNIPath is the path to search for CNI plugins, multiple paths can be
 	// specified colon delimited
 	CNIPath string `hcl:"cni_path"`
@@ -304,19 +349,138 @@ type ClientConfig struct {
 	// the host
 	BridgeNetworkSubnet string `hcl:"bridge_network_subnet"`
 
+	// BridgeNetworkCNIConfigPath is the path of a CNI config list template
+	// to configure the bridge network with instead of the built-in config
+	BridgeNetworkCNIConfigPath string `hcl:"bridge_network_cni_config_path"`
+
+	// BridgeNetworkIPAMGCDryRun logs the IP addresses of the bridge network
+	// leased to allocations that no longer exist instead of releasing them
+	BridgeNetworkIPAMGCDryRun bool `hcl:"bridge_network_ipam_gc_dry_run"`
+
 	// HostNetworks describes the different host networks available to the host
 	// if the host uses multiple interfaces
 	HostNetworks []*structs.ClientHostNetworkConfig `hcl:"host_network"`
 
-	// BindWildcardDefaultHostNetwork toggles if when there are no host networks,
-	// should the port mapping rules match the default network address (false) or
-	// matching any destination address (true). Defaults to true
-	BindWildcardDefaultHostNetwork bool `hcl:"bind_wildcard_default_host_network"`
+	// BindWildcardDefaultHostNetwork toggles if the port mapping rules of the
+	// default host network should match the default network address (false) or
+	// any destination address (true). When it isn't set, the rules match any
+	// destination address only if no host networks are defined. When it is
+	// set, it applies whether or not host networks are defined.
+	BindWildcardDefaultHostNetwork *bool `hcl:"bind_wildcard_default_host_network"`
 
 	// CgroupParent sets the parent cgroup for subsystems managed by Nomad. If the cgroup
 	// doest not exist Nomad will attempt to create it during startup. Defaults to '/nomad'
 	CgroupParent string `hcl:"cgroup_parent"`
 
+	// CSIVolumeMountTimeout is the maximum amount of time to wait for a CSI
+	// node plugin to mount a single volume. Defaults to "2m".
+	CSIVolumeMountTimeout string `hcl:"csi_volume_mount_timeout"`
+
+	// CSIPluginRegistrationWait is how long to wait for a CSI node plugin
+	// that hasn't registered on the node yet. Defaults to not waiting.
+	CSIPluginRegistrationWait string `hcl:"csi_plugin_registration_wait"`
+
+	// CSIDegradedPluginClaimDelay is the minimum delay between the CSI volume
+	// mounts of a degraded node plugin, which are made one at a time across
+	// the node. Defaults to not throttling degraded plugins.
+	CSIDegradedPluginClaimDelay string `hcl:"csi_degraded_plugin_claim_delay"`
+
+	// CSIVolumeHealthCheck fails the mount of a CSI volume early when its
+	// claim reports the volume as unhealthy. Defaults to false.
+	CSIVolumeHealthCheck bool `hcl:"csi_volume_health_check"`
+
+	// CSIMountMetadata writes a file describing the allocation next to each
+	// of its CSI volume mounts. Defaults to false.
+	CSIMountMetadata bool `hcl:"csi_mount_metadata"`
+
+	// CSIClaimTopology sends the node's CSI topologies with each volume claim
+	// and checks the claimed volume is accessible from them. Defaults to false.
+	CSIClaimTopology bool `hcl:"csi_claim_topology"`
+
+	// CSIClaimRetry configures the retries of CSI volume claims failing with
+	// transient errors, such as while the servers elect a leader.
+	CSIClaimRetry *client.RetryConfig `hcl:"csi_claim_retry"`
+
+	// CSIUnpublishRetry configures the retries of the requests releasing an
+	// allocation's CSI volume claims when it stops.
+	CSIUnpublishRetry *client.RetryConfig `hcl:"csi_unpublish_retry"`
+
+	// CSIClaimLabelEnv maps the names of labels attached to CSI volume
+	// claims to the environment variables their values are read from.
+	CSIClaimLabelEnv map[string]string `hcl:"csi_claim_label_env"`
+
+	// CSIMaxVolumesPerAlloc is the maximum number of CSI volumes a single
+	// allocation may request. Defaults to 16.
+	CSIMaxVolumesPerAlloc int `hcl:"csi_max_volumes_per_alloc"`
+
+	// CSIMaxNodeMounts is the maximum number of CSI volume mount paths
+	// published on the node at once. Defaults to 256.
+	CSIMaxNodeMounts int `hcl:"csi_max_node_mounts"`
+
+	// MaxCSIMountedCapacityGB is the maximum total capacity, in gigabytes,
+	// of the CSI volumes mounted on the node at once. Unlimited by default.
+	MaxCSIMountedCapacityGB int `hcl:"max_csi_mounted_capacity_gb"`
+
+	// CSIIdempotencyKeys persists the CSI volume claims of allocations as
+	// they are made and released, so that a restarted client acts on each
+	// claim once.
+	CSIIdempotencyKeys bool `hcl:"csi_idempotency_keys"`
+
+	// MaxTaskTmpfsMB is the maximum size, in megabytes, of the tmpfs scratch
+	// directory a task may request. Task tmpfs is disabled by default.
+	MaxTaskTmpfsMB int `hcl:"max_task_tmpfs_mb"`
+
+	// ReloadObservationWindow is how long the client watches its health
+	// after a config reload before the reload is kept. Setting it stages
+	// reloads so they are rolled back if failures spike.
+	ReloadObservationWindow string `hcl:"reload_observation_window"`
+
+	// ReloadRollbackThreshold is the number of failures observed during the
+	// ReloadObservationWindow that rolls back a reload. Defaults to 3.
+	ReloadRollbackThreshold int `hcl:"reload_rollback_threshold"`
+
+	// PlacementFailureCacheTTL is how long the client rejects allocations of
+	// a job after one failed to be placed on it for a reason that will
+	// recur. Disabled by default.
+	PlacementFailureCacheTTL string `hcl:"placement_failure_cache_ttl"`
+
+	// PlacementFailureCacheSize is the maximum number of placement failures
+	// the client remembers. Defaults to 256.
+	PlacementFailureCacheSize int `hcl:"placement_failure_cache_size"`
+
+	// DisableOptionEnvInterpolation disables the expansion of environment
+	// variable references in Options values.
+	DisableOptionEnvInterpolation bool `hcl:"disable_option_env_interpolation"`
+
+	// ArtifactRequireChecksum rejects task artifacts that do not specify a
+	// checksum.
+	ArtifactRequireChecksum bool `hcl:"artifact_require_checksum"`
+
+	// ArtifactChecksumExemptPrefixes are artifact URL prefixes, such as
+	// trusted internal registries, that may be downloaded without a checksum
+	// even when checksums are required.
+	ArtifactChecksumExemptPrefixes []string `hcl:"artifact_checksum_exempt_prefixes"`
+
+	// NodeDownloadBandwidthMbps limits the combined bandwidth in Mbps of
+	// artifact downloads and migrations of previous allocations' data.
+	NodeDownloadBandwidthMbps int `hcl:"node_download_bandwidth_mbps"`
+
+	// NodeDownloadBandwidthWeights are the relative shares of the download
+	// bandwidth of each class of traffic when they contend for it.
+	NodeDownloadBandwidthWeights map[string]int `hcl:"node_download_bandwidth_weights"`
+
+	// CoreDumps configures the collection of core files dumped by crashed
+	// tasks.
+	CoreDumps *client.CoreDumpConfig `hcl:"core_dumps"`
+
+	// Audit configures the audit log of mutating calls to the client's
+	// endpoints.
+	Audit *client.AuditConfig `hcl:"audit"`
+
+	// DriverHealth configures, for each driver, when failed task starts mark
+	// the driver degraded.
+	DriverHealth []*client.DriverHealthThreshold `hcl:"driver_health"`
+
 	// ExtraKeysHCL is used by hcl to surface unexpected keys
 	ExtraKeysHCL []string `hcl:",unusedKeys" json:"-"`
 }
@@ -895,8 +1059,8 @@ func DevConfig(mode *devModeConfig) *Config {
 	conf.Client.TemplateConfig = &client.ClientTemplateConfig{
 		FunctionDenylist: []string{"plugin"},
 		DisableSandbox:   false,
+		NomadRetry:       client.DefaultTemplateNomadRetry(),
 	}
-	conf.Client.BindWildcardDefaultHostNetwork = true
 	conf.Telemetry.PrometheusMetrics = true
 	conf.Telemetry.PublishAllocationMetrics = true
 	conf.Telemetry.PublishNodeMetrics = true
@@ -924,6 +1088,7 @@ func DefaultConfig() *Config {
 		Client: &ClientConfig{
 			Enabled:               false,
 			MaxKillTimeout:        "30s",
+			MaxFreezeDuration:     "5m",
 			ClientMinPort:         14000,
 			ClientMaxPort:         14512,
 			MinDynamicPort:        20000,
@@ -944,10 +1109,10 @@ func DefaultConfig() *Config {
 			TemplateConfig: &client.ClientTemplateConfig{
 				FunctionDenylist: []string{"plugin"},
 				DisableSandbox:   false,
+				NomadRetry:       client.DefaultTemplateNomadRetry(),
 			},
-			BindWildcardDefaultHostNetwork: true,
-			CNIPath:                        "/opt/cni/bin",
-			CNIConfigDir:                   "/opt/cni/config",
+			CNIPath:      "/opt/cni/bin",
+			CNIConfigDir: "/opt/cni/config",
 		},
 		Server: &ServerConfig{
 			Enabled:           false,
@@ -1643,6 +1808,9 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.MaxKillTimeout != "" {
 		result.MaxKillTimeout = b.MaxKillTimeout
 	}
+	if b.MaxFreezeDuration != "" {
+		result.MaxFreezeDuration = b.MaxFreezeDuration
+	}
 	if b.ClientMaxPort != 0 {
 		result.ClientMaxPort = b.ClientMaxPort
 	}
@@ -1676,9 +1844,28 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.GCInodeUsageThreshold != 0 {
 		result.GCInodeUsageThreshold = b.GCInodeUsageThreshold
 	}
+	if b.StateDirReserveMB != nil {
+		result.StateDirReserveMB = helper.IntToPtr(*b.StateDirReserveMB)
+	}
 	if b.GCMaxAllocs != 0 {
 		result.GCMaxAllocs = b.GCMaxAllocs
 	}
+	if len(b.GCMaxAllocsPerNamespace) != 0 {
+		if result.GCMaxAllocsPerNamespace == nil {
+			result.GCMaxAllocsPerNamespace = make(map[string]int, len(b.GCMaxAllocsPerNamespace))
+		} else {
+			result.GCMaxAllocsPerNamespace = helper.CopyMapStringInt(result.GCMaxAllocsPerNamespace)
+		}
+		for ns, max := range b.GCMaxAllocsPerNamespace {
+			result.GCMaxAllocsPerNamespace[ns] = max
+		}
+	}
+	if b.GCMaxAllocAge != 0 {
+		result.GCMaxAllocAge = b.GCMaxAllocAge
+	}
+	if b.GCMaxAllocAgeHCL != "" {
+		result.GCMaxAllocAgeHCL = b.GCMaxAllocAgeHCL
+	}
 	// NoHostUUID defaults to true, merge if false
 	if b.NoHostUUID != nil {
 		result.NoHostUUID = b.NoHostUUID
@@ -1688,15 +1875,15 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 		result.DisableRemoteExec = b.DisableRemoteExec
 	}
 
-	if result.TemplateConfig == nil && b.TemplateConfig != nil {
-		templateConfig := *b.TemplateConfig
-		result.TemplateConfig = &templateConfig
-	} else if b.TemplateConfig != nil {
+	if b.TemplateConfig != nil {
 		result.TemplateConfig = result.TemplateConfig.Merge(b.TemplateConfig)
 	}
 
 	// Add the servers
 	result.Servers = append(result.Servers, b.Servers...)
+	if b.MaxServerConnections != 0 {
+		result.MaxServerConnections = b.MaxServerConnections
+	}
 
 	// Add the options map values
 	if result.Options == nil {
@@ -1721,11 +1908,40 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	for k, v := range b.ChrootEnv {
 		result.ChrootEnv[k] = v
 	}
+	if b.ChrootEnvMergeDefaults {
+		result.ChrootEnvMergeDefaults = true
+	}
+
+	if len(b.ChrootFragments) != 0 {
+		if result.ChrootFragments == nil {
+			result.ChrootFragments = make(map[string][]string, len(b.ChrootFragments))
+		} else {
+			result.ChrootFragments = helper.CopyMapStringSliceString(result.ChrootFragments)
+		}
+		for name, paths := range b.ChrootFragments {
+			result.ChrootFragments[name] = helper.CopySliceString(paths)
+		}
+	}
+
+	if len(b.EnvDenylistPerNamespace) != 0 {
+		if result.EnvDenylistPerNamespace == nil {
+			result.EnvDenylistPerNamespace = make(map[string][]string, len(b.EnvDenylistPerNamespace))
+		} else {
+			result.EnvDenylistPerNamespace = helper.CopyMapStringSliceString(result.EnvDenylistPerNamespace)
+		}
+		for ns, envs := range b.EnvDenylistPerNamespace {
+			result.EnvDenylistPerNamespace[ns] = helper.CopySliceString(envs)
+		}
+	}
 
 	if b.ServerJoin != nil {
 		result.ServerJoin = result.ServerJoin.Merge(b.ServerJoin)
 	}
 
+	if b.HostVolumeAllowMissingReadOnly {
+		result.HostVolumeAllowMissingReadOnly = true
+	}
+
 	if len(a.HostVolumes) == 0 && len(b.HostVolumes) != 0 {
 		result.HostVolumes = structs.CopySliceClientHostVolumeConfig(b.HostVolumes)
 	} else if len(b.HostVolumes) != 0 {
@@ -1744,6 +1960,12 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.BridgeNetworkSubnet != "" {
 		result.BridgeNetworkSubnet = b.BridgeNetworkSubnet
 	}
+	if b.BridgeNetworkCNIConfigPath != "" {
+		result.BridgeNetworkCNIConfigPath = b.BridgeNetworkCNIConfigPath
+	}
+	if b.BridgeNetworkIPAMGCDryRun {
+		result.BridgeNetworkIPAMGCDryRun = true
+	}
 
 	result.HostNetworks = a.HostNetworks
 
@@ -1751,12 +1973,127 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 		result.HostNetworks = append(result.HostNetworks, b.HostNetworks...)
 	}
 
-	if b.BindWildcardDefaultHostNetwork {
-		result.BindWildcardDefaultHostNetwork = true
+	if b.BindWildcardDefaultHostNetwork != nil {
+		result.BindWildcardDefaultHostNetwork = helper.BoolToPtr(*b.BindWildcardDefaultHostNetwork)
+	}
+
+	if b.CSIVolumeMountTimeout != "" {
+		result.CSIVolumeMountTimeout = b.CSIVolumeMountTimeout
+	}
+	if b.CSIPluginRegistrationWait != "" {
+		result.CSIPluginRegistrationWait = b.CSIPluginRegistrationWait
+	}
+	if b.CSIDegradedPluginClaimDelay != "" {
+		result.CSIDegradedPluginClaimDelay = b.CSIDegradedPluginClaimDelay
+	}
+	if b.CSIVolumeHealthCheck {
+		result.CSIVolumeHealthCheck = b.CSIVolumeHealthCheck
+	}
+	if b.CSIMountMetadata {
+		result.CSIMountMetadata = b.CSIMountMetadata
+	}
+	if b.CSIClaimTopology {
+		result.CSIClaimTopology = b.CSIClaimTopology
+	}
+	if b.CSIClaimRetry != nil {
+		result.CSIClaimRetry = result.CSIClaimRetry.Merge(b.CSIClaimRetry)
+	}
+	if b.CSIUnpublishRetry != nil {
+		result.CSIUnpublishRetry = result.CSIUnpublishRetry.Merge(b.CSIUnpublishRetry)
+	}
+	if b.CSIMaxVolumesPerAlloc != 0 {
+		result.CSIMaxVolumesPerAlloc = b.CSIMaxVolumesPerAlloc
+	}
+	if b.CSIMaxNodeMounts != 0 {
+		result.CSIMaxNodeMounts = b.CSIMaxNodeMounts
+	}
+	if b.MaxCSIMountedCapacityGB != 0 {
+		result.MaxCSIMountedCapacityGB = b.MaxCSIMountedCapacityGB
+	}
+	if b.CSIIdempotencyKeys {
+		result.CSIIdempotencyKeys = true
+	}
+	if b.MaxTaskTmpfsMB != 0 {
+		result.MaxTaskTmpfsMB = b.MaxTaskTmpfsMB
+	}
+
+	if b.ReloadObservationWindow != "" {
+		result.ReloadObservationWindow = b.ReloadObservationWindow
+	}
+	if b.ReloadRollbackThreshold != 0 {
+		result.ReloadRollbackThreshold = b.ReloadRollbackThreshold
+	}
+
+	if b.PlacementFailureCacheTTL != "" {
+		result.PlacementFailureCacheTTL = b.PlacementFailureCacheTTL
+	}
+	if b.PlacementFailureCacheSize != 0 {
+		result.PlacementFailureCacheSize = b.PlacementFailureCacheSize
+	}
+
+	if len(b.CSIClaimLabelEnv) != 0 {
+		if result.CSIClaimLabelEnv == nil {
+			result.CSIClaimLabelEnv = make(map[string]string, len(b.CSIClaimLabelEnv))
+		} else {
+			result.CSIClaimLabelEnv = helper.CopyMapStringString(result.CSIClaimLabelEnv)
+		}
+		for k, v := range b.CSIClaimLabelEnv {
+			result.CSIClaimLabelEnv[k] = v
+		}
+	}
+
+	if b.DisableOptionEnvInterpolation {
+		result.DisableOptionEnvInterpolation = true
+	}
+	if b.ArtifactRequireChecksum {
+		result.ArtifactRequireChecksum = true
+	}
+	if len(b.ArtifactChecksumExemptPrefixes) != 0 {
+		result.ArtifactChecksumExemptPrefixes = b.ArtifactChecksumExemptPrefixes
+	}
+	if b.NodeDownloadBandwidthMbps != 0 {
+		result.NodeDownloadBandwidthMbps = b.NodeDownloadBandwidthMbps
+	}
+	if len(b.NodeDownloadBandwidthWeights) != 0 {
+		if result.NodeDownloadBandwidthWeights == nil {
+			result.NodeDownloadBandwidthWeights = make(map[string]int, len(b.NodeDownloadBandwidthWeights))
+		} else {
+			result.NodeDownloadBandwidthWeights = helper.CopyMapStringInt(result.NodeDownloadBandwidthWeights)
+		}
+		for class, weight := range b.NodeDownloadBandwidthWeights {
+			result.NodeDownloadBandwidthWeights[class] = weight
+		}
+	}
+	if b.CoreDumps != nil {
+		result.CoreDumps = result.CoreDumps.Merge(b.CoreDumps)
+	}
+	if b.Audit != nil {
+		result.Audit = result.Audit.Merge(b.Audit)
+	}
+	if len(b.DriverHealth) != 0 {
+		result.DriverHealth = mergeDriverHealth(a.DriverHealth, b.DriverHealth)
 	}
 	return &result
 }
 
+// mergeDriverHealth merges the driver health thresholds of b into a by
+// driver. The values set in b take precedence.
+func mergeDriverHealth(a, b []*client.DriverHealthThreshold) []*client.DriverHealthThreshold {
+	result := make([]*client.DriverHealthThreshold, 0, len(a)+len(b))
+	index := make(map[string]int, len(a)+len(b))
+	for _, thresholds := range [][]*client.DriverHealthThreshold{a, b} {
+		for _, threshold := range thresholds {
+			if i, ok := index[threshold.Driver]; ok {
+				result[i] = result[i].Merge(threshold)
+				continue
+			}
+			index[threshold.Driver] = len(result)
+			result = append(result, threshold.Copy())
+		}
+	}
+	return result
+}
+
 // Merge is used to merge two telemetry configs together
 func (a *Telemetry) Merge(b *Telemetry) *Telemetry {
 	result := *a
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"time"
 
+	humanize "github.com/dustin/go-humanize"
 	"github.com/hashicorp/hcl"
 	client "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/helper"
@@ -41,6 +42,7 @@ func ParseConfigFile(path string) (*Config, error) {
 				WaitBounds:  &client.WaitConfig{},
 				ConsulRetry: &client.RetryConfig{},
 				VaultRetry:  &client.RetryConfig{},
+				NomadRetry:  &client.RetryConfig{},
 			},
 		},
 		ACL:       &ACLConfig{},
@@ -60,6 +62,7 @@ func ParseConfigFile(path string) (*Config, error) {
 	// convert strings to time.Durations
 	tds := []durationConversionMap{
 		{"gc_interval", &c.Client.GCInterval, &c.Client.GCIntervalHCL, nil},
+		{"gc_max_alloc_age", &c.Client.GCMaxAllocAge, &c.Client.GCMaxAllocAgeHCL, nil},
 		{"acl.token_ttl", &c.ACL.TokenTTL, &c.ACL.TokenTTLHCL, nil},
 		{"acl.policy_ttl", &c.ACL.PolicyTTL, &c.ACL.PolicyTTLHCL, nil},
 		{"client.server_join.retry_interval", &c.Client.ServerJoin.RetryInterval, &c.Client.ServerJoin.RetryIntervalHCL, nil},
@@ -81,6 +84,14 @@ func ParseConfigFile(path string) (*Config, error) {
 			func(d *time.Duration) {
 				c.Client.TemplateConfig.MaxStale = d
 			}},
+		{"client.template.restart_stage_timeout", nil, &c.Client.TemplateConfig.RestartStageTimeoutHCL,
+			func(d *time.Duration) {
+				c.Client.TemplateConfig.RestartStageTimeout = d
+			}},
+		{"client.template.max_render_cpu_time", nil, &c.Client.TemplateConfig.MaxRenderCPUTimeHCL,
+			func(d *time.Duration) {
+				c.Client.TemplateConfig.MaxRenderCPUTime = d
+			}},
 		{"client.template.wait.min", nil, &c.Client.TemplateConfig.Wait.MinHCL,
 			func(d *time.Duration) {
 				c.Client.TemplateConfig.Wait.Min = d
@@ -121,6 +132,16 @@ func ParseConfigFile(path string) (*Config, error) {
 				c.Client.TemplateConfig.VaultRetry.MaxBackoff = d
 			},
 		},
+		{"client.template.nomad_retry.backoff", nil, &c.Client.TemplateConfig.NomadRetry.BackoffHCL,
+			func(d *time.Duration) {
+				c.Client.TemplateConfig.NomadRetry.Backoff = d
+			},
+		},
+		{"client.template.nomad_retry.max_backoff", nil, &c.Client.TemplateConfig.NomadRetry.MaxBackoffHCL,
+			func(d *time.Duration) {
+				c.Client.TemplateConfig.NomadRetry.MaxBackoff = d
+			},
+		},
 	}
 
 	// Add enterprise audit sinks for time.Duration parsing
@@ -129,12 +150,78 @@ func ParseConfigFile(path string) (*Config, error) {
 			fmt.Sprintf("audit.sink.%d", i), &sink.RotateDuration, &sink.RotateDurationHCL, nil})
 	}
 
+	// Add the Vault namespace template retries for time.Duration parsing
+	for namespace, retry := range c.Client.TemplateConfig.VaultRetries {
+		retry := retry
+		tds = append(tds,
+			durationConversionMap{
+				fmt.Sprintf("client.template.vault_retries.%s.backoff", namespace), nil, &retry.BackoffHCL,
+				func(d *time.Duration) {
+					retry.Backoff = d
+				}},
+			durationConversionMap{
+				fmt.Sprintf("client.template.vault_retries.%s.max_backoff", namespace), nil, &retry.MaxBackoffHCL,
+				func(d *time.Duration) {
+					retry.MaxBackoff = d
+				}})
+	}
+
+	// Add the CSI claim retry for time.Duration parsing
+	if retry := c.Client.CSIClaimRetry; retry != nil {
+		tds = append(tds,
+			durationConversionMap{
+				"client.csi_claim_retry.backoff", nil, &retry.BackoffHCL,
+				func(d *time.Duration) {
+					retry.Backoff = d
+				}},
+			durationConversionMap{
+				"client.csi_claim_retry.max_backoff", nil, &retry.MaxBackoffHCL,
+				func(d *time.Duration) {
+					retry.MaxBackoff = d
+				}})
+	}
+
+	// Add the CSI unpublish retry for time.Duration parsing
+	if retry := c.Client.CSIUnpublishRetry; retry != nil {
+		tds = append(tds,
+			durationConversionMap{
+				"client.csi_unpublish_retry.backoff", nil, &retry.BackoffHCL,
+				func(d *time.Duration) {
+					retry.Backoff = d
+				}},
+			durationConversionMap{
+				"client.csi_unpublish_retry.max_backoff", nil, &retry.MaxBackoffHCL,
+				func(d *time.Duration) {
+					retry.MaxBackoff = d
+				}})
+	}
+
+	// Add the driver health thresholds for time.Duration parsing
+	for _, threshold := range c.Client.DriverHealth {
+		tds = append(tds,
+			durationConversionMap{
+				fmt.Sprintf("client.driver_health.%s.window", threshold.Driver),
+				&threshold.Window, &threshold.WindowHCL, nil},
+			durationConversionMap{
+				fmt.Sprintf("client.driver_health.%s.recovery_period", threshold.Driver),
+				&threshold.RecoveryPeriod, &threshold.RecoveryPeriodHCL, nil})
+	}
+
 	// convert strings to time.Durations
 	err = convertDurations(tds)
 	if err != nil {
 		return nil, err
 	}
 
+	// convert human-friendly sizes such as "10MB" to bytes
+	if size := c.Client.TemplateConfig.MaxRenderSizeHCL; size != "" {
+		b, err := humanize.ParseBytes(size)
+		if err != nil {
+			return nil, fmt.Errorf("client.template.max_render_size can't parse size %s", size)
+		}
+		c.Client.TemplateConfig.MaxRenderSizeBytes = helper.Int64ToPtr(int64(b))
+	}
+
 	// report unexpected keys
 	err = extraKeys(c)
 	if err != nil {
@@ -195,7 +282,7 @@ func extraKeys(c *Config) error {
 		helper.RemoveEqualFold(&c.ExtraKeysHCL, "plugin")
 	}
 
-	for _, k := range []string{"options", "meta", "chroot_env", "servers", "server_join"} {
+	for _, k := range []string{"options", "meta", "chroot_env", "chroot_fragments", "env_denylist_per_namespace", "servers", "server_join", "gc_max_allocs_per_namespace", "node_download_bandwidth_weights"} {
 		helper.RemoveEqualFold(&c.ExtraKeysHCL, k)
 		helper.RemoveEqualFold(&c.ExtraKeysHCL, "client")
 	}
@@ -215,6 +302,12 @@ func extraKeys(c *Config) error {
 		helper.RemoveEqualFold(&c.Client.ExtraKeysHCL, "host_network")
 	}
 
+	// Remove DriverHealth extra keys
+	for _, threshold := range c.Client.DriverHealth {
+		helper.RemoveEqualFold(&c.Client.ExtraKeysHCL, threshold.Driver)
+		helper.RemoveEqualFold(&c.Client.ExtraKeysHCL, "driver_health")
+	}
+
 	// Remove AuditConfig extra keys
 	for _, f := range c.Audit.Filters {
 		helper.RemoveEqualFold(&c.Audit.ExtraKeysHCL, f.Name)
@@ -260,6 +353,14 @@ func finalizeClientTemplateConfig(config *Config) {
 		config.Client.TemplateConfig.VaultRetry = nil
 	}
 
+	if config.Client.TemplateConfig.NomadRetry.IsEmpty() {
+		config.Client.TemplateConfig.NomadRetry = nil
+	}
+
+	if len(config.Client.TemplateConfig.VaultRetries) == 0 {
+		config.Client.TemplateConfig.VaultRetries = nil
+	}
+
 	if config.Client.TemplateConfig.IsEmpty() {
 		config.Client.TemplateConfig = nil
 	}
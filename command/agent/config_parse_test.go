@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	client "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/structs/config"
@@ -44,6 +45,8 @@ var basicConfig = &Config{
 		AllocDir:  "/tmp/alloc",
 		Servers:   []string{"a.b.c:80", "127.0.0.1:1234"},
 		NodeClass: "linux-medium-64bit",
+
+		MaxServerConnections: 1,
 		ServerJoin: &ServerJoin{
 			RetryJoin:        []string{"1.1.1.1", "2.2.2.2"},
 			RetryInterval:    time.Duration(15) * time.Second,
@@ -62,13 +65,21 @@ var basicConfig = &Config{
 			"/opt/myapp/etc": "/etc",
 			"/opt/myapp/bin": "/bin",
 		},
-		NetworkInterface: "eth0",
-		NetworkSpeed:     100,
-		CpuCompute:       4444,
-		MemoryMB:         0,
-		MaxKillTimeout:   "10s",
-		ClientMinPort:    1000,
-		ClientMaxPort:    2000,
+		ChrootEnvMergeDefaults: true,
+		ChrootFragments: map[string][]string{
+			"zoneinfo": {"/opt/zoneinfo"},
+		},
+		EnvDenylistPerNamespace: map[string][]string{
+			"untrusted": {"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+		},
+		NetworkInterface:  "eth0",
+		NetworkSpeed:      100,
+		CpuCompute:        4444,
+		MemoryMB:          0,
+		MaxKillTimeout:    "10s",
+		MaxFreezeDuration: "2m",
+		ClientMinPort:     1000,
+		ClientMaxPort:     2000,
 		Reserved: &Resources{
 			CPU:           10,
 			MemoryMB:      10,
@@ -80,15 +91,59 @@ var basicConfig = &Config{
 		GCParallelDestroys:    6,
 		GCDiskUsageThreshold:  82,
 		GCInodeUsageThreshold: 91,
+		StateDirReserveMB:     helper.IntToPtr(512),
 		GCMaxAllocs:           50,
+		GCMaxAllocAge:         24 * time.Hour,
+		GCMaxAllocAgeHCL:      "24h",
 		NoHostUUID:            helper.BoolToPtr(false),
 		DisableRemoteExec:     true,
+		GCMaxAllocsPerNamespace: map[string]int{
+			"batch": 10,
+		},
 		HostVolumes: []*structs.ClientHostVolumeConfig{
 			{Name: "tmp", Path: "/tmp"},
 		},
-		CNIPath:             "/tmp/cni_path",
-		BridgeNetworkName:   "custom_bridge_name",
-		BridgeNetworkSubnet: "custom_bridge_subnet",
+		HostVolumeAllowMissingReadOnly: true,
+
+		CNIPath:                    "/tmp/cni_path",
+		BridgeNetworkName:          "custom_bridge_name",
+		BridgeNetworkSubnet:        "custom_bridge_subnet",
+		BridgeNetworkCNIConfigPath: "/tmp/nomad.conflist",
+		BridgeNetworkIPAMGCDryRun:  true,
+
+		CSIMaxVolumesPerAlloc:   8,
+		CSIMaxNodeMounts:        64,
+		MaxCSIMountedCapacityGB: 500,
+		CSIIdempotencyKeys:      true,
+
+		CSIDegradedPluginClaimDelay: "5s",
+		CSIPluginRegistrationWait:   "30s",
+		CSIVolumeHealthCheck:        true,
+		CSIMountMetadata:            true,
+		CSIClaimTopology:            true,
+
+		MaxTaskTmpfsMB: 512,
+
+		NodeDownloadBandwidthMbps: 200,
+		NodeDownloadBandwidthWeights: map[string]int{
+			"artifact": 3,
+			"migrate":  1,
+		},
+
+		CSIClaimRetry: &client.RetryConfig{
+			Attempts:      helper.IntToPtr(3),
+			Backoff:       helper.TimeToPtr(2 * time.Second),
+			BackoffHCL:    "2s",
+			MaxBackoff:    helper.TimeToPtr(10 * time.Second),
+			MaxBackoffHCL: "10s",
+		},
+		CSIUnpublishRetry: &client.RetryConfig{
+			Attempts:      helper.IntToPtr(8),
+			Backoff:       helper.TimeToPtr(500 * time.Millisecond),
+			BackoffHCL:    "500ms",
+			MaxBackoff:    helper.TimeToPtr(time.Minute),
+			MaxBackoffHCL: "1m",
+		},
 	},
 	Server: &ServerConfig{
 		Enabled:                   true,
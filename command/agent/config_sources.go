@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	// ConfigSourceDefault is the source of fields left to their default
+	ConfigSourceDefault = "default"
+
+	// ConfigSourceFile is the source of fields set by a config file
+	ConfigSourceFile = "file"
+
+	// ConfigSourceFlag is the source of fields set by a command line flag
+	ConfigSourceFlag = "flag"
+)
+
+// mergeFromSource merges b like Merge, and records the fields b set as coming
+// from source. A field is set by b if it isn't zero in b and it kept b's value
+// once merged.
+func (c *Config) mergeFromSource(b *Config, source string) *Config {
+	result := c.Merge(b)
+
+	sources := make(map[string]string, len(c.fieldSources))
+	for field, src := range c.fieldSources {
+		sources[field] = src
+	}
+
+	merged := configFields(result)
+	for field, value := range configFields(b) {
+		if value.IsZero() {
+			continue
+		}
+		if mergedValue, ok := merged[field]; ok && reflect.DeepEqual(mergedValue.Interface(), value.Interface()) {
+			sources[field] = source
+		}
+	}
+
+	result.fieldSources = sources
+	return result
+}
+
+// FieldSources returns the source of every field of the config, keyed by the
+// dotted path of the field's name in config files, such as
+// "client.state_dir". Fields that no config file or flag set are reported as
+// ConfigSourceDefault.
+func (c *Config) FieldSources() map[string]string {
+	fields := configFields(c)
+	sources := make(map[string]string, len(fields))
+	for field := range fields {
+		source, ok := c.fieldSources[field]
+		if !ok {
+			source = ConfigSourceDefault
+		}
+		sources[field] = source
+	}
+	return sources
+}
+
+// configFields returns the values of the fields of the config that can be set
+// in config files, keyed by their dotted path. Blocks are walked into, and
+// every other field, including lists and maps, is a single value.
+func configFields(c *Config) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	if c != nil {
+		walkConfigFields(reflect.ValueOf(c).Elem(), "", fields)
+	}
+	return fields
+}
+
+func walkConfigFields(v reflect.Value, prefix string, fields map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("hcl"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		path := prefix + name
+
+		value := v.Field(i)
+		elem := value
+		if elem.Kind() == reflect.Ptr && elem.Type().Elem().Kind() == reflect.Struct {
+			if elem.IsNil() {
+				elem = reflect.New(elem.Type().Elem())
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Struct {
+			walkConfigFields(elem, path+".", fields)
+			continue
+		}
+		fields[path] = value
+	}
+}
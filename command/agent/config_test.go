@@ -114,6 +114,7 @@ func TestConfig_Merge(t *testing.T) {
 			MaxDynamicPort:    10002,
 			MemoryMB:          100,
 			MaxKillTimeout:    "20s",
+			MaxFreezeDuration: "1m",
 			ClientMaxPort:     19996,
 			DisableRemoteExec: false,
 			TemplateConfig: &client.ClientTemplateConfig{
@@ -299,6 +300,7 @@ func TestConfig_Merge(t *testing.T) {
 			MaxDynamicPort:    10003,
 			MemoryMB:          105,
 			MaxKillTimeout:    "50s",
+			MaxFreezeDuration: "2m",
 			DisableRemoteExec: false,
 			TemplateConfig: &client.ClientTemplateConfig{
 				FunctionDenylist: []string{"plugin"},
@@ -619,6 +621,48 @@ func TestConfig_LoadConfigsFileOrder(t *testing.T) {
 	}
 }
 
+func TestConfig_FieldSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent.hcl")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+region     = "east"
+datacenter = "dc1"
+
+client {
+  enabled   = true
+  state_dir = "/file/state"
+}
+`), 0600))
+
+	file, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	flags := &Config{
+		Client: &ClientConfig{
+			StateDir: "/flag/state",
+		},
+	}
+
+	config := DefaultConfig().mergeFromSource(file, ConfigSourceFile)
+	config = config.mergeFromSource(flags, ConfigSourceFlag)
+	require.Equal(t, "/flag/state", config.Client.StateDir)
+
+	sources := config.FieldSources()
+	require.Equal(t, ConfigSourceFile, sources["region"])
+	require.Equal(t, ConfigSourceFile, sources["client.enabled"])
+	require.Equal(t, ConfigSourceFlag, sources["client.state_dir"])
+	require.Equal(t, ConfigSourceDefault, sources["log_level"])
+	require.Equal(t, ConfigSourceDefault, sources["server.enabled"])
+	require.Equal(t, ConfigSourceDefault, sources["client.gc_interval"])
+
+	// A file setting a field to its default value is still its source
+	require.Equal(t, ConfigSourceFile, sources["datacenter"])
+
+	// Blocks are walked into rather than reported as a whole
+	require.Equal(t, ConfigSourceDefault, sources["vault.address"])
+	require.NotContains(t, sources, "client")
+}
+
 func TestConfig_Listener(t *testing.T) {
 	config := DefaultConfig()
 
@@ -1391,10 +1435,16 @@ func TestConfig_LoadConsulTemplateConfig(t *testing.T) {
 	require.NotNil(t, templateConfig.WaitBounds)
 	require.NotNil(t, templateConfig.ConsulRetry)
 	require.NotNil(t, templateConfig.VaultRetry)
+	require.NotNil(t, templateConfig.NomadRetry)
 
 	// Direct properties
 	require.Equal(t, 300*time.Second, *templateConfig.MaxStale)
 	require.Equal(t, 90*time.Second, *templateConfig.BlockQueryWaitTime)
+	require.Equal(t, int64(10*1000*1000), *templateConfig.MaxRenderSizeBytes)
+	require.Equal(t, 2*time.Second, *templateConfig.MaxRenderCPUTime)
+	require.Equal(t, 20, *templateConfig.MaxTemplatesPerTask)
+	require.Equal(t, "templates", templateConfig.ConsulNamespace)
+	require.True(t, templateConfig.ValidateRenderedUTF8)
 	// Wait
 	require.Equal(t, 2*time.Second, *templateConfig.Wait.Min)
 	require.Equal(t, 60*time.Second, *templateConfig.Wait.Max)
@@ -1411,6 +1461,131 @@ func TestConfig_LoadConsulTemplateConfig(t *testing.T) {
 	require.Equal(t, 10, *templateConfig.VaultRetry.Attempts)
 	require.Equal(t, 15*time.Second, *templateConfig.VaultRetry.Backoff)
 	require.Equal(t, 20*time.Second, *templateConfig.VaultRetry.MaxBackoff)
+	// Nomad Retry
+	require.Equal(t, 3, *templateConfig.NomadRetry.Attempts)
+	require.Equal(t, 2*time.Second, *templateConfig.NomadRetry.Backoff)
+	require.Equal(t, 30*time.Second, *templateConfig.NomadRetry.MaxBackoff)
+	// Vault Retries
+	require.Len(t, templateConfig.VaultRetries, 1)
+	require.Equal(t, 3, *templateConfig.VaultRetries["ops"].Attempts)
+	require.Equal(t, time.Second, *templateConfig.VaultRetries["ops"].Backoff)
+	require.Nil(t, templateConfig.VaultRetries["ops"].MaxBackoff)
+}
+
+func TestConfig_LoadConsulTemplateConfig_Layered(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.hcl": `client {
+  template {
+    function_denylist = ["plugin"]
+    max_stale         = "1m"
+    wait {
+      min = "2s"
+      max = "10s"
+    }
+  }
+}`,
+		// A template block setting only disable_file_sandbox is not dropped
+		"b.hcl": `client {
+  template {
+    disable_file_sandbox = true
+  }
+}`,
+		"c.hcl": `client {
+  template {
+    function_denylist = ["env"]
+    wait {
+      max = "30s"
+    }
+  }
+}`,
+	}
+	for name, contents := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600))
+	}
+
+	agentConfig, err := LoadConfig(dir)
+	require.NoError(t, err)
+
+	templateConfig := agentConfig.Client.TemplateConfig
+	require.NotNil(t, templateConfig)
+	require.True(t, templateConfig.DisableSandbox)
+	require.Equal(t, []string{"plugin", "env"}, templateConfig.FunctionDenylist)
+	require.Equal(t, time.Minute, *templateConfig.MaxStale)
+	require.Equal(t, 2*time.Second, *templateConfig.Wait.Min)
+	require.Equal(t, 30*time.Second, *templateConfig.Wait.Max)
+}
+
+func TestConfig_LoadConsulTemplateConfig_MaxRenderSize(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := map[string]int64{
+		"512":   512,
+		"10MB":  10 * 1000 * 1000,
+		"1 GiB": 1024 * 1024 * 1024,
+	}
+	for size, expected := range cases {
+		path := filepath.Join(dir, "client.hcl")
+		contents := fmt.Sprintf("client {\n  template {\n    max_render_size = %q\n  }\n}", size)
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+
+		agentConfig, err := LoadConfig(path)
+		require.NoError(t, err)
+		require.Equal(t, expected, *agentConfig.Client.TemplateConfig.MaxRenderSizeBytes, size)
+	}
+
+	path := filepath.Join(dir, "invalid.hcl")
+	contents := "client {\n  template {\n    max_render_size = \"lots\"\n  }\n}"
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	_, err := LoadConfig(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "client.template.max_render_size can't parse size lots")
+}
+
+func TestConfig_LoadDriverHealthConfig_Layered(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.hcl": `client {
+  driver_health "docker" {
+    window       = "10m"
+    max_failures = 5
+  }
+}`,
+		"b.hcl": `client {
+  driver_health "docker" {
+    recovery_period = "30m"
+  }
+  driver_health "exec" {
+    max_failures = 2
+  }
+}`,
+	}
+	for name, contents := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600))
+	}
+
+	agentConfig, err := LoadConfig(dir)
+	require.NoError(t, err)
+	require.Len(t, agentConfig.Client.DriverHealth, 2)
+
+	docker := agentConfig.Client.DriverHealth[0]
+	require.Equal(t, "docker", docker.Driver)
+	require.Equal(t, 10*time.Minute, docker.Window)
+	require.Equal(t, 5, docker.MaxFailures)
+	require.Equal(t, 30*time.Minute, docker.RecoveryPeriod)
+
+	// Unset fields of the client's thresholds use the defaults
+	agentConfig = DevConfig(nil).Merge(agentConfig)
+	agentConfig.Client.Enabled = true
+	conf, err := convertClientConfig(agentConfig)
+	require.NoError(t, err)
+
+	exec := conf.DriverHealthThreshold("exec")
+	require.Equal(t, 2, exec.MaxFailures)
+	require.Equal(t, client.DefaultDriverHealthWindow, exec.Window)
+	require.Equal(t, client.DefaultDriverHealthRecoveryPeriod, exec.RecoveryPeriod)
+	require.Equal(t, client.DefaultDriverHealthThreshold().MaxFailures,
+		conf.DriverHealthThreshold("java").MaxFailures)
 }
 
 func TestParseMultipleIPTemplates(t *testing.T) {
@@ -26,6 +26,13 @@ type WorkloadRestarter interface {
 	Restart(ctx context.Context, event *structs.TaskEvent, failure bool) error
 }
 
+// pausableWorkload is implemented by WorkloadRestarters that can be paused.
+// Their checks are suspended while they're paused instead of counting towards
+// restarting them.
+type pausableWorkload interface {
+	IsPaused() bool
+}
+
 // checkRestart handles restarting a task if a check is unhealthy.
 type checkRestart struct {
 	allocID   string
@@ -66,6 +73,14 @@ func (c *checkRestart) apply(ctx context.Context, now time.Time, status string)
 			c.unhealthyState = time.Time{}
 		}
 	}
+
+	if w, ok := c.task.(pausableWorkload); ok && w.IsPaused() {
+		// Checks of paused workloads are suspended, so forget failures
+		// that happened while paused
+		c.unhealthyState = time.Time{}
+		return false
+	}
+
 	switch status {
 	case api.HealthCritical:
 	case api.HealthWarning:
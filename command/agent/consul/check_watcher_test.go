@@ -248,6 +248,38 @@ func TestCheckWatcher_Unhealthy(t *testing.T) {
 	require.Len(t, restarter1.restarts, 1)
 }
 
+// pausedCheckRestarter is a fakeCheckRestarter for a paused workload.
+type pausedCheckRestarter struct {
+	*fakeCheckRestarter
+}
+
+func (c *pausedCheckRestarter) IsPaused() bool {
+	return true
+}
+
+// TestCheckWatcher_Paused asserts failing checks of paused workloads don't
+// restart them.
+func TestCheckWatcher_Paused(t *testing.T) {
+	t.Parallel()
+
+	fakeAPI, cw := testWatcherSetup(t)
+
+	check1 := testCheck()
+	restarter1 := &pausedCheckRestarter{newFakeCheckRestarter(cw, "testalloc1", "testtask1", "testcheck1", check1)}
+	cw.Watch("testalloc1", "testtask1", "testcheck1", check1, restarter1)
+
+	// Check has always been failing
+	fakeAPI.add("testcheck1", "critical", time.Time{})
+
+	// Run
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	cw.Run(ctx)
+
+	// Ensure restart was never called
+	require.Empty(t, restarter1.GetRestarts())
+}
+
 // TestCheckWatcher_HealthyWarning asserts checks in warning with
 // ignore_warnings=true do not restart tasks.
 func TestCheckWatcher_HealthyWarning(t *testing.T) {
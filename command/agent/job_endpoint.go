@@ -953,6 +953,10 @@ func ApiTgToStructsTG(job *structs.Job, taskGroup *api.TaskGroup, tg *structs.Ta
 		tg.StopAfterClientDisconnect = taskGroup.StopAfterClientDisconnect
 	}
 
+	if taskGroup.CSIClaimTimeout != nil {
+		tg.CSIClaimTimeout = helper.TimeToPtr(*taskGroup.CSIClaimTimeout)
+	}
+
 	if taskGroup.ReschedulePolicy != nil {
 		tg.ReschedulePolicy = &structs.ReschedulePolicy{
 			Attempts:      *taskGroup.ReschedulePolicy.Attempts,
@@ -1072,6 +1076,7 @@ func ApiTaskToStructsTask(job *structs.Job, group *structs.TaskGroup,
 	structsTask.KillTimeout = *apiTask.KillTimeout
 	structsTask.ShutdownDelay = apiTask.ShutdownDelay
 	structsTask.KillSignal = apiTask.KillSignal
+	structsTask.ChrootExtras = apiTask.ChrootExtras
 	structsTask.Kind = structs.TaskKind(apiTask.Kind)
 	structsTask.Constraints = ApiConstraintsToStructs(apiTask.Constraints)
 	structsTask.Affinities = ApiAffinitiesToStructs(apiTask.Affinities)
@@ -1146,6 +1151,11 @@ func ApiTaskToStructsTask(job *structs.Job, group *structs.TaskGroup,
 	if len(apiTask.Templates) > 0 {
 		structsTask.Templates = []*structs.Template{}
 		for _, template := range apiTask.Templates {
+			var restartOrder string
+			if template.RestartOrder != nil {
+				restartOrder = *template.RestartOrder
+			}
+
 			structsTask.Templates = append(structsTask.Templates,
 				&structs.Template{
 					SourcePath:   *template.SourcePath,
@@ -1160,6 +1170,9 @@ func ApiTaskToStructsTask(job *structs.Job, group *structs.TaskGroup,
 					Envvars:      *template.Envvars,
 					VaultGrace:   *template.VaultGrace,
 					Wait:         ApiWaitConfigToStructsWaitConfig(template.Wait),
+					Retry:        ApiTemplateRetryConfigToStructsTemplateRetryConfig(template.Retry),
+					RestartOrder: restartOrder,
+					DependsOn:    helper.CopySliceString(template.DependsOn),
 				})
 		}
 	}
@@ -1176,6 +1189,14 @@ func ApiTaskToStructsTask(job *structs.Job, group *structs.TaskGroup,
 			Sidecar: apiTask.Lifecycle.Sidecar,
 		}
 	}
+
+	if apiTask.Tmpfs != nil {
+		structsTask.Tmpfs = &structs.TaskTmpfs{
+			SizeMB:      *apiTask.Tmpfs.SizeMB,
+			Mode:        *apiTask.Tmpfs.Mode,
+			CountMemory: *apiTask.Tmpfs.CountMemory,
+		}
+	}
 }
 
 // ApiWaitConfigToStructsWaitConfig is a copy and type conversion between the API
@@ -1191,6 +1212,18 @@ func ApiWaitConfigToStructsWaitConfig(waitConfig *api.WaitConfig) *structs.WaitC
 	}
 }
 
+func ApiTemplateRetryConfigToStructsTemplateRetryConfig(retryConfig *api.TemplateRetryConfig) *structs.TemplateRetryConfig {
+	if retryConfig == nil {
+		return nil
+	}
+
+	return &structs.TemplateRetryConfig{
+		Attempts:   retryConfig.Attempts,
+		Backoff:    retryConfig.Backoff,
+		MaxBackoff: retryConfig.MaxBackoff,
+	}
+}
+
 func ApiCSIPluginConfigToStructsCSIPluginConfig(apiConfig *api.TaskCSIPluginConfig) *structs.TaskCSIPluginConfig {
 	if apiConfig == nil {
 		return nil
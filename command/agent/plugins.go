@@ -2,7 +2,9 @@ package agent
 
 import (
 	"fmt"
+	"os"
 
+	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/helper/pluginutils/catalog"
 	"github.com/hashicorp/nomad/helper/pluginutils/loader"
 	"github.com/hashicorp/nomad/helper/pluginutils/singleton"
@@ -49,10 +51,20 @@ func (a *Agent) internalPluginConfigs() (map[loader.PluginID]*loader.InternalPlu
 	// Create our map of plugins
 	internal := make(map[loader.PluginID]*loader.InternalPluginConfig, len(catalog))
 
-	// Grab the client options map if we can
+	// Grab the client options map if we can, expanded the same way as for
+	// the client itself
 	var options map[string]string
 	if a.config != nil && a.config.Client != nil {
 		options = a.config.Client.Options
+		if !a.config.Client.DisableOptionEnvInterpolation {
+			// Unset variables are warned about when the client config is
+			// built
+			expanded, _, err := clientconfig.ExpandOptions(options, os.LookupEnv)
+			if err != nil {
+				return nil, fmt.Errorf("failed to expand client options: %v", err)
+			}
+			options = expanded
+		}
 	}
 
 	for id, reg := range catalog {
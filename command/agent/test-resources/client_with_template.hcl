@@ -2,8 +2,13 @@ client {
   enabled = true
 
   template {
-    max_stale        = "300s"
-    block_query_wait = "90s"
+    max_stale              = "300s"
+    block_query_wait       = "90s"
+    max_render_size        = "10MB"
+    max_render_cpu_time    = "2s"
+    max_templates_per_task = 20
+    consul_namespace       = "templates"
+    validate_rendered_utf8 = true
 
     wait {
       min = "2s"
@@ -26,6 +31,17 @@ client {
       backoff     = "15s"
       max_backoff = "20s"
     }
+
+    nomad_retry {
+      attempts    = 3
+      backoff     = "2s"
+      max_backoff = "30s"
+    }
+
+    vault_retries "ops" {
+      attempts = 3
+      backoff  = "1s"
+    }
   }
 
 }
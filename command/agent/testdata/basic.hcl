@@ -43,6 +43,8 @@ client {
   servers    = ["a.b.c:80", "127.0.0.1:1234"]
   node_class = "linux-medium-64bit"
 
+  max_server_connections = 1
+
   meta {
     foo = "bar"
     baz = "zip"
@@ -64,6 +66,16 @@ client {
     "/opt/myapp/bin" = "/bin"
   }
 
+  chroot_env_merge_defaults = true
+
+  chroot_fragments {
+    zoneinfo = ["/opt/zoneinfo"]
+  }
+
+  env_denylist_per_namespace {
+    untrusted = ["AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"]
+  }
+
   network_interface = "eth0"
   network_speed     = 100
   cpu_total_compute = 4444
@@ -79,6 +91,8 @@ client {
   client_max_port  = 2000
   max_kill_timeout = "10s"
 
+  max_freeze_duration = "2m"
+
   stats {
     data_points         = 35
     collection_interval = "5s"
@@ -89,16 +103,58 @@ client {
   gc_disk_usage_threshold  = 82
   gc_inode_usage_threshold = 91
   gc_max_allocs            = 50
+  state_dir_reserve_mb     = 512
+  gc_max_alloc_age         = "24h"
   no_host_uuid             = false
   disable_remote_exec      = true
 
+  gc_max_allocs_per_namespace {
+    batch = 10
+  }
+
   host_volume "tmp" {
     path = "/tmp"
   }
 
-  cni_path              = "/tmp/cni_path"
-  bridge_network_name   = "custom_bridge_name"
-  bridge_network_subnet = "custom_bridge_subnet"
+  host_volume_allow_missing_read_only = true
+
+  cni_path                       = "/tmp/cni_path"
+  bridge_network_name            = "custom_bridge_name"
+  bridge_network_subnet          = "custom_bridge_subnet"
+  bridge_network_cni_config_path = "/tmp/nomad.conflist"
+  bridge_network_ipam_gc_dry_run = true
+
+  csi_max_volumes_per_alloc   = 8
+  csi_max_node_mounts         = 64
+  max_csi_mounted_capacity_gb = 500
+  csi_idempotency_keys        = true
+
+  csi_degraded_plugin_claim_delay = "5s"
+  csi_plugin_registration_wait    = "30s"
+  csi_volume_health_check         = true
+  csi_mount_metadata              = true
+  csi_claim_topology              = true
+
+  max_task_tmpfs_mb = 512
+
+  node_download_bandwidth_mbps = 200
+
+  node_download_bandwidth_weights {
+    artifact = 3
+    migrate  = 1
+  }
+
+  csi_claim_retry {
+    attempts    = 3
+    backoff     = "2s"
+    max_backoff = "10s"
+  }
+
+  csi_unpublish_retry {
+    attempts    = 8
+    backoff     = "500ms"
+    max_backoff = "1m"
+  }
 }
 
 server {
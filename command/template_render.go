@@ -0,0 +1,45 @@
+package command
+
+import (
+	"os"
+	"strings"
+
+	"github.com/hashicorp/nomad/client/allocrunner/taskrunner/template"
+)
+
+// TemplateRenderCommand is the hidden command the client runs to render a
+// task's templates in a process of its own, when the client limits the CPU
+// time of template renders.
+type TemplateRenderCommand struct {
+	Meta
+}
+
+func (c *TemplateRenderCommand) Help() string {
+	helpText := `
+Usage: nomad template-render
+
+  Renders a task's templates, reading the consul-template runner to run from
+  stdin and writing its render events to stdout. This command is run by the
+  Nomad client and isn't meant to be run by users.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *TemplateRenderCommand) Synopsis() string {
+	return "Render a task's templates for the Nomad client"
+}
+
+func (c *TemplateRenderCommand) Name() string { return "template-render" }
+
+func (c *TemplateRenderCommand) Run(args []string) int {
+	if len(args) != 0 {
+		c.Ui.Error("This command takes no arguments")
+		return 1
+	}
+
+	if err := template.RunRenderProcess(os.Stdin, os.Stdout); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	return 0
+}
@@ -254,11 +254,11 @@ func (d *Driver) StartTask(cfg *drivers.TaskConfig) (*drivers.TaskHandle, *drive
 	var driverConfig TaskConfig
 
 	if err := cfg.DecodeDriverConfig(&driverConfig); err != nil {
-		return nil, nil, fmt.Errorf("failed to decode driver config: %v", err)
+		return nil, nil, drivers.NewTaskConfigError(fmt.Errorf("failed to decode driver config: %v", err))
 	}
 
 	if driverConfig.Image == "" {
-		return nil, nil, fmt.Errorf("image name required for docker driver")
+		return nil, nil, drivers.NewTaskConfigError(fmt.Errorf("image name required for docker driver"))
 	}
 
 	driverConfig.Image = strings.TrimPrefix(driverConfig.Image, "https://")
@@ -4,9 +4,12 @@
 package util
 
 import (
+	"fmt"
 	"runtime"
+	"strings"
 
 	docker "github.com/fsouza/go-dockerclient"
+	"github.com/hashicorp/nomad/client/lib/cgutil"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/helper/stats"
 )
@@ -60,7 +63,54 @@ func DockerStatsToTaskResourceUsage(s *docker.Stats) *cstructs.TaskResourceUsage
 		ResourceUsage: &cstructs.ResourceUsage{
 			MemoryStats: ms,
 			CpuStats:    cs,
+			DiskIOStats: dockerDiskIOStats(s),
 		},
 		Timestamp: s.Read.UTC().UnixNano(),
 	}
 }
+
+// dockerDiskIOStats returns the block device I/O stats of the container's
+// cgroup, or nil if docker doesn't report any. The operations are named
+// "Read" and "Write" with cgroups v1, and "read" and "write" with cgroups v2.
+func dockerDiskIOStats(s *docker.Stats) *cstructs.DiskIOStats {
+	if len(s.BlkioStats.IOServiceBytesRecursive) == 0 && len(s.BlkioStats.IOServicedRecursive) == 0 {
+		return nil
+	}
+
+	ds := &cstructs.DiskIOStats{
+		Devices:  make(map[string]*cstructs.DiskIOStats),
+		Measured: cgutil.DiskIOMeasuredStats,
+	}
+	device := func(e docker.BlkioStatsEntry) *cstructs.DiskIOStats {
+		name := cgutil.BlockDeviceName(fmt.Sprintf("%d:%d", e.Major, e.Minor))
+		dev, ok := ds.Devices[name]
+		if !ok {
+			dev = &cstructs.DiskIOStats{Measured: cgutil.DiskIOMeasuredStats}
+			ds.Devices[name] = dev
+		}
+		return dev
+	}
+
+	for _, e := range s.BlkioStats.IOServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			device(e).ReadBytes += e.Value
+			ds.ReadBytes += e.Value
+		case "write":
+			device(e).WriteBytes += e.Value
+			ds.WriteBytes += e.Value
+		}
+	}
+	for _, e := range s.BlkioStats.IOServicedRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			device(e).ReadOps += e.Value
+			ds.ReadOps += e.Value
+		case "write":
+			device(e).WriteOps += e.Value
+			ds.WriteOps += e.Value
+		}
+	}
+
+	return ds
+}
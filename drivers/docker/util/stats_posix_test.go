@@ -0,0 +1,68 @@
+//go:build !windows
+// +build !windows
+
+package util
+
+import (
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/hashicorp/nomad/client/lib/cgutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerStatsToTaskResourceUsage_DiskIO(t *testing.T) {
+	cases := []struct {
+		name  string
+		read  string
+		write string
+	}{
+		{
+			name:  "cgroups v1",
+			read:  "Read",
+			write: "Write",
+		},
+		{
+			name:  "cgroups v2",
+			read:  "read",
+			write: "write",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The device numbers don't exist, so the devices are named by
+			// their numbers
+			s := &docker.Stats{}
+			s.BlkioStats.IOServiceBytesRecursive = []docker.BlkioStatsEntry{
+				{Major: 9999, Minor: 0, Op: tc.read, Value: 1024},
+				{Major: 9999, Minor: 0, Op: tc.write, Value: 4096},
+				{Major: 9999, Minor: 0, Op: "Total", Value: 5120},
+				{Major: 9999, Minor: 1, Op: tc.read, Value: 512},
+			}
+			s.BlkioStats.IOServicedRecursive = []docker.BlkioStatsEntry{
+				{Major: 9999, Minor: 0, Op: tc.read, Value: 2},
+				{Major: 9999, Minor: 0, Op: tc.write, Value: 8},
+				{Major: 9999, Minor: 1, Op: tc.read, Value: 1},
+			}
+
+			ds := DockerStatsToTaskResourceUsage(s).ResourceUsage.DiskIOStats
+			require.NotNil(t, ds)
+			require.Equal(t, uint64(1536), ds.ReadBytes)
+			require.Equal(t, uint64(4096), ds.WriteBytes)
+			require.Equal(t, uint64(3), ds.ReadOps)
+			require.Equal(t, uint64(8), ds.WriteOps)
+			require.Equal(t, cgutil.DiskIOMeasuredStats, ds.Measured)
+
+			require.Len(t, ds.Devices, 2)
+			require.Equal(t, uint64(1024), ds.Devices["9999:0"].ReadBytes)
+			require.Equal(t, uint64(8), ds.Devices["9999:0"].WriteOps)
+			require.Equal(t, uint64(1), ds.Devices["9999:1"].ReadOps)
+		})
+	}
+}
+
+func TestDockerStatsToTaskResourceUsage_NoDiskIO(t *testing.T) {
+	ru := DockerStatsToTaskResourceUsage(&docker.Stats{})
+	require.Nil(t, ru.ResourceUsage.DiskIOStats)
+}
@@ -435,7 +435,7 @@ func (d *Driver) StartTask(cfg *drivers.TaskConfig) (*drivers.TaskHandle, *drive
 
 	var driverConfig TaskConfig
 	if err := cfg.DecodeDriverConfig(&driverConfig); err != nil {
-		return nil, nil, fmt.Errorf("failed to decode driver config: %v", err)
+		return nil, nil, drivers.NewTaskConfigError(fmt.Errorf("failed to decode driver config: %v", err))
 	}
 
 	if err := driverConfig.validate(); err != nil {
@@ -435,15 +435,15 @@ func (d *Driver) StartTask(cfg *drivers.TaskConfig) (*drivers.TaskHandle, *drive
 
 	var driverConfig TaskConfig
 	if err := cfg.DecodeDriverConfig(&driverConfig); err != nil {
-		return nil, nil, fmt.Errorf("failed to decode driver config: %v", err)
+		return nil, nil, drivers.NewTaskConfigError(fmt.Errorf("failed to decode driver config: %v", err))
 	}
 
 	if err := driverConfig.validate(); err != nil {
-		return nil, nil, fmt.Errorf("failed driver config validation: %v", err)
+		return nil, nil, drivers.NewTaskConfigError(fmt.Errorf("failed driver config validation: %v", err))
 	}
 
 	if driverConfig.Class == "" && driverConfig.JarPath == "" {
-		return nil, nil, fmt.Errorf("jar_path or class must be specified")
+		return nil, nil, drivers.NewTaskConfigError(fmt.Errorf("jar_path or class must be specified"))
 	}
 
 	absPath, err := GetAbsolutePath("java")
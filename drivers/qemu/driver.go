@@ -372,7 +372,7 @@ func (d *Driver) StartTask(cfg *drivers.TaskConfig) (*drivers.TaskHandle, *drive
 	var driverConfig TaskConfig
 
 	if err := cfg.DecodeDriverConfig(&driverConfig); err != nil {
-		return nil, nil, fmt.Errorf("failed to decode driver config: %v", err)
+		return nil, nil, drivers.NewTaskConfigError(fmt.Errorf("failed to decode driver config: %v", err))
 	}
 
 	// ensure that PortMap variables are populated early on
@@ -310,7 +310,7 @@ func (d *Driver) StartTask(cfg *drivers.TaskConfig) (*drivers.TaskHandle, *drive
 
 	var driverConfig TaskConfig
 	if err := cfg.DecodeDriverConfig(&driverConfig); err != nil {
-		return nil, nil, fmt.Errorf("failed to decode driver config: %v", err)
+		return nil, nil, drivers.NewTaskConfigError(fmt.Errorf("failed to decode driver config: %v", err))
 	}
 
 	d.logger.Info("starting task", "driver_cfg", hclog.Fmt("%+v", driverConfig))
@@ -344,6 +344,7 @@ func (d *Driver) StartTask(cfg *drivers.TaskConfig) (*drivers.TaskHandle, *drive
 		StdoutPath:         cfg.StdoutPath,
 		StderrPath:         cfg.StderrPath,
 		NetworkIsolation:   cfg.NetworkIsolation,
+		Resources:          cfg.Resources,
 	}
 
 	ps, err := exec.Launch(execCmd)
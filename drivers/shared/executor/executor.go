@@ -336,6 +336,12 @@ func (e *UniversalExecutor) Launch(command *ExecCommand) (*ProcessState, error)
 	e.childCmd.Args = append([]string{e.childCmd.Path}, command.Args...)
 	e.childCmd.Env = e.commandCfg.Env
 
+	// Raise the core file size limit inherited by the task. The executor
+	// only runs this task, so its own limit can be changed.
+	if err := setCoreLimit(coreLimit(command.Resources)); err != nil {
+		return nil, fmt.Errorf("failed to set core file size limit: %v", err)
+	}
+
 	// Start the process
 	if err = withNetworkIsolation(e.childCmd.Start, command.NetworkIsolation); err != nil {
 		return nil, fmt.Errorf("failed to start command path=%q --- args=%q: %v", path, e.childCmd.Args, err)
@@ -346,6 +352,15 @@ func (e *UniversalExecutor) Launch(command *ExecCommand) (*ProcessState, error)
 	return &ProcessState{Pid: e.childCmd.Process.Pid, ExitCode: -1, Time: time.Now()}, nil
 }
 
+// coreLimit returns the core file size limit requested for the task's
+// processes, or zero if they inherit the limit of the executor.
+func coreLimit(resources *drivers.Resources) int64 {
+	if resources == nil || resources.LinuxResources == nil {
+		return 0
+	}
+	return resources.LinuxResources.CoreLimitBytes
+}
+
 // Exec a command inside a container for exec and java drivers.
 func (e *UniversalExecutor) Exec(deadline time.Time, name string, args []string) ([]byte, int, error) {
 	ctx, cancel := context.WithDeadline(context.Background(), deadline)
@@ -172,6 +172,16 @@ func (l *LibcontainerExecutor) Launch(command *ExecCommand) (*ProcessState, erro
 	if command.User != "" {
 		process.User = command.User
 	}
+
+	// Limit the size of the core files dumped by the task, leaving the
+	// other rlimits inherited from the executor
+	if limit := coreLimit(command.Resources); limit > 0 {
+		process.Rlimits = []lconfigs.Rlimit{{
+			Type: unix.RLIMIT_CORE,
+			Hard: uint64(limit),
+			Soft: uint64(limit),
+		}}
+	}
 	l.userProc = process
 
 	l.totalCpuStats = stats.NewCpuStats()
@@ -755,3 +755,54 @@ func TestUniversalExecutor_NoCgroup(t *testing.T) {
 	})
 
 }
+
+func TestExecutor_CoreLimit(t *testing.T) {
+	// The universal executor sets the core file size limit of the test
+	// process, so restore it afterwards
+	var orig unix.Rlimit
+	require.NoError(t, unix.Getrlimit(unix.RLIMIT_CORE, &orig))
+	t.Cleanup(func() { unix.Setrlimit(unix.RLIMIT_CORE, &orig) })
+
+	limit := uint64(4096 * 1024)
+	coreLimitRe := regexp.MustCompile(`Max core file size\s+(\d+)`)
+
+	for name, factory := range executorFactories {
+		t.Run(name, func(t *testing.T) {
+			expected := limit
+			if name == "LibcontainerExecutor" {
+				testutil.ExecCompatible(t)
+			} else if orig.Max != unix.RLIM_INFINITY && orig.Max < limit {
+				// The soft limit is capped at the hard limit
+				expected = orig.Max
+			}
+
+			testExecCmd := testExecutorCommand(t)
+			execCmd, allocDir := testExecCmd.command, testExecCmd.allocDir
+			defer allocDir.Destroy()
+
+			execCmd.Cmd = "/bin/sh"
+			execCmd.Args = []string{"-c", "while read -r line; do echo \"$line\"; done < /proc/self/limits"}
+			execCmd.Resources.LinuxResources.CoreLimitBytes = int64(limit)
+			factory.configureExecCmd(t, execCmd)
+
+			executor := factory.new(testlog.HCLogger(t))
+			defer executor.Shutdown("", 0)
+
+			_, err := executor.Launch(execCmd)
+			require.NoError(t, err)
+			_, err = executor.Wait(context.Background())
+			require.NoError(t, err)
+
+			tu.WaitForResult(func() (bool, error) {
+				m := coreLimitRe.FindStringSubmatch(testExecCmd.stdout.String())
+				if m == nil {
+					return false, fmt.Errorf("core file size limit not found in:\n%s", testExecCmd.stdout.String())
+				}
+				if m[1] != strconv.FormatUint(expected, 10) {
+					return false, fmt.Errorf("expected core file size limit %d, got %s", expected, m[1])
+				}
+				return true, nil
+			}, func(err error) { require.NoError(t, err) })
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	cgroupFs "github.com/opencontainers/runc/libcontainer/cgroups/fs"
 	lconfigs "github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/specconv"
+	"golang.org/x/sys/unix"
 )
 
 // setCmdUser takes a user id as a string and looks up the user, and sets the command
@@ -191,3 +192,23 @@ func withNetworkIsolation(f func() error, spec *drivers.NetworkIsolationSpec) er
 
 	return f()
 }
+
+// setCoreLimit sets the soft core file size limit of the executor, inherited
+// by the processes it starts, to limit bytes capped at the hard limit. A limit
+// of zero leaves the limit unchanged.
+func setCoreLimit(limit int64) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	var rlim unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_CORE, &rlim); err != nil {
+		return err
+	}
+
+	rlim.Cur = uint64(limit)
+	if rlim.Max != unix.RLIM_INFINITY && rlim.Cur > rlim.Max {
+		rlim.Cur = rlim.Max
+	}
+	return unix.Setrlimit(unix.RLIMIT_CORE, &rlim)
+}
@@ -1,6 +1,7 @@
 package catalog
 
 import (
+	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/drivers/docker"
 	"github.com/hashicorp/nomad/drivers/exec"
 	"github.com/hashicorp/nomad/drivers/java"
@@ -17,4 +18,7 @@ func init() {
 	Register(qemu.PluginID, qemu.PluginConfig)
 	Register(java.PluginID, java.PluginConfig)
 	RegisterDeferredConfig(docker.PluginID, docker.PluginConfig, docker.PluginLoader)
+
+	// The deferred configs are loaded from the client's options
+	clientconfig.RegisterOptionPrefixes("driver.raw_exec.", "docker.")
 }
@@ -182,6 +182,9 @@ type ConnPool struct {
 	// The maximum number of open streams to keep
 	maxStreams int
 
+	// The maximum number of connections to keep, zero for unlimited
+	maxConns int
+
 	// Pool maps an address to a open connection
 	pool map[string]*Conn
 
@@ -261,6 +264,39 @@ func (p *ConnPool) ReloadTLS(tlsWrap tlsutil.RegionWrapper) {
 	p.tlsWrap = tlsWrap
 }
 
+// SetMaxConns bounds the number of connections the pool keeps open. Once
+// the pool is full, connecting to a new address closes the least recently
+// used connection. Connections in use are closed once they are released. A
+// max of zero keeps a connection to every address.
+func (p *ConnPool) SetMaxConns(max int) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.maxConns = max
+	p.evictLocked()
+}
+
+// evictLocked removes the least recently used connections until the pool is
+// within maxConns. p.Lock must be held.
+func (p *ConnPool) evictLocked() {
+	for p.maxConns > 0 && len(p.pool) > p.maxConns {
+		var lru *Conn
+		for _, conn := range p.pool {
+			if lru == nil || conn.lastUsed.Before(lru.lastUsed) {
+				lru = conn
+			}
+		}
+		delete(p.pool, lru.addr.String())
+
+		// Ensure returned streams are closed, and close down immediately if
+		// idle
+		atomic.StoreInt32(&lru.shouldClose, 1)
+		if refCount := atomic.LoadInt32(&lru.refCount); refCount == 0 {
+			lru.Close()
+		}
+	}
+}
+
 // SetConnListener is used to listen to new connections being made. The
 // channel will be closed when the conn pool is closed or a new listener is set.
 func (p *ConnPool) SetConnListener(l chan<- *Conn) {
@@ -315,6 +351,7 @@ func (p *ConnPool) acquire(region string, addr net.Addr, version int) (*Conn, er
 		}
 
 		p.pool[addr.String()] = c
+		p.evictLocked()
 
 		// If there is a connection listener, notify them of the new connection.
 		if p.connListener != nil {
@@ -65,3 +65,74 @@ func TestConnPool_ConnListener(t *testing.T) {
 	_, ok := <-c
 	require.False(ok)
 }
+
+func TestConnPool_MaxConns(t *testing.T) {
+	require := require.New(t)
+
+	// Start more servers than the pool may connect to
+	addrs := make([]net.Addr, 3)
+	for i := range addrs {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(err)
+		defer ln.Close()
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+		addrs[i] = ln.Addr()
+	}
+
+	pool := newTestPool(t)
+	defer pool.Shutdown()
+	pool.SetMaxConns(2)
+
+	numConns := func() int {
+		pool.Lock()
+		defer pool.Unlock()
+		return len(pool.pool)
+	}
+
+	// The least recently used idle connection is closed once the pool is
+	// full
+	conns := make([]*Conn, len(addrs))
+	for i, addr := range addrs {
+		conn, err := pool.acquire("test", addr, structs.ApiMajorVersion)
+		require.Nil(err)
+		conn.releaseUse()
+		conns[i] = conn
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(2, numConns())
+	require.True(conns[0].IsClosed())
+	require.False(conns[1].IsClosed())
+	require.False(conns[2].IsClosed())
+
+	// A connection in use is only closed once it is released
+	busy, err := pool.acquire("test", addrs[1], structs.ApiMajorVersion)
+	require.Nil(err)
+	require.Same(conns[1], busy)
+	time.Sleep(10 * time.Millisecond)
+	_, err = pool.acquire("test", addrs[0], structs.ApiMajorVersion)
+	require.Nil(err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = pool.acquire("test", addrs[2], structs.ApiMajorVersion)
+	require.Nil(err)
+
+	require.Equal(2, numConns())
+	require.False(busy.IsClosed())
+	busy.releaseUse()
+	require.True(busy.IsClosed())
+
+	// Lowering the max closes connections right away, and zero is unlimited
+	pool.SetMaxConns(1)
+	require.Equal(1, numConns())
+	pool.SetMaxConns(0)
+	_, err = pool.acquire("test", addrs[1], structs.ApiMajorVersion)
+	require.Nil(err)
+	require.Equal(2, numConns())
+}
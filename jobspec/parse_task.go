@@ -41,6 +41,8 @@ var (
 		"kind",
 		"volume_mount",
 		"csi_plugin",
+		"chroot_extras",
+		"tmpfs",
 	)
 
 	sidecarTaskKeys = append(commonTaskKeys,
@@ -113,6 +115,7 @@ func parseTask(item *ast.ObjectItem, keys []string) (*api.Task, error) {
 	delete(m, "volume_mount")
 	delete(m, "csi_plugin")
 	delete(m, "scaling")
+	delete(m, "tmpfs")
 
 	// Build the task
 	var t api.Task
@@ -353,6 +356,35 @@ func parseTask(item *ast.ObjectItem, keys []string) (*api.Task, error) {
 			return nil, err
 		}
 	}
+
+	// If we have a tmpfs block parse that
+	if o := listVal.Filter("tmpfs"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return nil, fmt.Errorf("only one tmpfs block is allowed in a task. Number of tmpfs blocks found: %d", len(o.Items))
+		}
+
+		var m map[string]interface{}
+		tmpfsBlock := o.Items[0]
+
+		// Check for invalid keys
+		valid := []string{
+			"size",
+			"mode",
+			"count_memory",
+		}
+		if err := checkHCLKeys(tmpfsBlock.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "tmpfs ->")
+		}
+
+		if err := hcl.DecodeObject(&m, tmpfsBlock.Val); err != nil {
+			return nil, err
+		}
+
+		t.Tmpfs = &api.TaskTmpfs{}
+		if err := mapstructure.WeakDecode(m, t.Tmpfs); err != nil {
+			return nil, err
+		}
+	}
 	return &t, nil
 }
 
@@ -438,6 +470,8 @@ func parseTemplates(result *[]*api.Template, list *ast.ObjectList) error {
 			"splay",
 			"env",
 			"vault_grace", //COMPAT(0.12) not used; emits warning in 0.11.
+			"restart_order",
+			"depends_on",
 		}
 		if err := checkHCLKeys(o.Val, valid); err != nil {
 			return err
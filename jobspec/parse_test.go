@@ -198,6 +198,7 @@ func TestParse(t *testing.T) {
 							},
 						},
 						StopAfterClientDisconnect: timeToPtr(120 * time.Second),
+						CSIClaimTimeout:           timeToPtr(45 * time.Second),
 						ReschedulePolicy: &api.ReschedulePolicy{
 							Interval: timeToPtr(12 * time.Hour),
 							Attempts: intToPtr(5),
@@ -224,10 +225,16 @@ func TestParse(t *testing.T) {
 						},
 						Tasks: []*api.Task{
 							{
-								Name:   "binstore",
-								Driver: "docker",
-								User:   "bob",
-								Kind:   "connect-proxy:test",
+								Name:         "binstore",
+								Driver:       "docker",
+								User:         "bob",
+								Kind:         "connect-proxy:test",
+								ChrootExtras: []string{"zoneinfo", "ca-certificates"},
+								Tmpfs: &api.TaskTmpfs{
+									SizeMB:      intToPtr(256),
+									Mode:        stringToPtr("0750"),
+									CountMemory: boolToPtr(false),
+								},
 								Config: map[string]interface{}{
 									"image": "hashicorp/binstore",
 									"labels": []map[string]interface{}{
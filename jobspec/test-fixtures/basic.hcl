@@ -158,6 +158,7 @@ job "binstore-storagelocker" {
     }
 
     stop_after_client_disconnect = "120s"
+    csi_claim_timeout            = "45s"
 
     task "binstore" {
       driver = "docker"
@@ -165,6 +166,14 @@ job "binstore-storagelocker" {
       leader = true
       kind   = "connect-proxy:test"
 
+      chroot_extras = ["zoneinfo", "ca-certificates"]
+
+      tmpfs {
+        size         = 256
+        mode         = "0750"
+        count_memory = false
+      }
+
       affinity {
         attribute = "${meta.foo}"
         value     = "a,b,c"
@@ -970,3 +970,23 @@ func TestWaitConfig(t *testing.T) {
 	require.Equal(t, 5*time.Second, *tmpl.Wait.Min)
 	require.Equal(t, 60*time.Second, *tmpl.Wait.Max)
 }
+
+func TestTemplateRetryConfig(t *testing.T) {
+	hclBytes, err := os.ReadFile("test-fixtures/template-retry-config.hcl")
+	require.NoError(t, err)
+
+	job, err := ParseWithConfig(&ParseConfig{
+		Path:    "test-fixtures/template-retry-config.hcl",
+		Body:    hclBytes,
+		AllowFS: false,
+	})
+
+	require.NoError(t, err)
+
+	tmpl := job.TaskGroups[0].Tasks[0].Templates[0]
+	require.NotNil(t, tmpl)
+	require.NotNil(t, tmpl.Retry)
+	require.Equal(t, 3, *tmpl.Retry.Attempts)
+	require.Equal(t, time.Second, *tmpl.Retry.Backoff)
+	require.Equal(t, 30*time.Second, *tmpl.Retry.MaxBackoff)
+}
@@ -0,0 +1,13 @@
+job "example" {
+  group "group" {
+    task "task" {
+      template {
+        retry {
+          attempts    = 3
+          backoff     = "1s"
+          max_backoff = "30s"
+        }
+      }
+    }
+  }
+}
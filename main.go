@@ -44,6 +44,7 @@ var (
 		"server-join",
 		"server-members",
 		"syslog",
+		"template-render",
 		"docker_logger",
 		"operator raft _info",
 		"operator raft _logs",
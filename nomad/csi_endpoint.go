@@ -452,6 +452,13 @@ func (v *CSIVolume) controllerPublishVolume(req *structs.CSIVolumeClaimRequest,
 		return fmt.Errorf("%s: %s", structs.ErrUnknownAllocationPrefix, req.AllocationID)
 	}
 
+	// Clients checking the topology of their volumes send the topology of
+	// their node plugins, so that a volume in another zone isn't published
+	if topology := req.NodeTopologies[vol.PluginID]; !vol.IsAccessibleFrom(topology) {
+		return fmt.Errorf("volume %s is not accessible from topology %v of plugin %q on node %s",
+			vol.ID, topology.Segments, vol.PluginID, alloc.NodeID)
+	}
+
 	// Some plugins support controllers for create/snapshot but not attach. So
 	// if there's no plugin or the plugin doesn't attach volumes, then we can
 	// skip the controller publish workflow and return nil.
@@ -472,7 +479,7 @@ func (v *CSIVolume) controllerPublishVolume(req *structs.CSIVolumeClaimRequest,
 	// Nomad's ID for the node)
 	targetCSIInfo, ok := targetNode.CSINodePlugins[plug.ID]
 	if !ok {
-		return fmt.Errorf("failed to find storage provider info for client %q, node plugin %q is not running or has not fingerprinted on this client", targetNode.ID, plug.ID)
+		return fmt.Errorf("failed to find storage provider info for client %q, node plugin %q: %w", targetNode.ID, plug.ID, structs.ErrCSIPluginNotFingerprinted)
 	}
 	externalNodeID := targetCSIInfo.NodeInfo.ID
 	req.ExternalNodeID = externalNodeID // update with the target info
@@ -785,7 +792,7 @@ func (v *CSIVolume) lookupExternalNodeID(vol *structs.CSIVolume, claim *structs.
 	// Nomad's ID for the node)
 	targetCSIInfo, ok := targetNode.CSINodePlugins[vol.PluginID]
 	if !ok || targetCSIInfo.NodeInfo == nil {
-		return "", fmt.Errorf("failed to find storage provider info for client %q, node plugin %q is not running or has not fingerprinted on this client", targetNode.ID, vol.PluginID)
+		return "", fmt.Errorf("failed to find storage provider info for client %q, node plugin %q: %w", targetNode.ID, vol.PluginID, structs.ErrCSIPluginNotFingerprinted)
 	}
 	return targetCSIInfo.NodeInfo.ID, nil
 }
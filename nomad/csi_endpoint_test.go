@@ -302,7 +302,20 @@ func TestCSIVolumeEndpoint_Claim(t *testing.T) {
 	require.Len(t, volGetResp.Volume.ReadAllocs, 0)
 	require.Len(t, volGetResp.Volume.WriteAllocs, 0)
 
+	// A claim from a node whose plugin topology the volume isn't
+	// accessible from is rejected
+	claimReq.NodeTopologies = map[string]*structs.CSITopology{
+		"minnie": {Segments: map[string]string{"foo": "baz"}},
+	}
+	err = msgpackrpc.CallWithCodec(codec, "CSIVolume.Claim", claimReq, claimResp)
+	require.EqualError(t, err, fmt.Sprintf(
+		`controller publish: volume %s is not accessible from topology map[foo:baz] of plugin "minnie" on node %s`,
+		id0, node.ID))
+
 	// Now our claim should succeed
+	claimReq.NodeTopologies = map[string]*structs.CSITopology{
+		"minnie": {Segments: map[string]string{"foo": "bar", "zone": "a"}},
+	}
 	err = msgpackrpc.CallWithCodec(codec, "CSIVolume.Claim", claimReq, claimResp)
 	require.NoError(t, err)
 
@@ -1146,8 +1146,12 @@ func (n *Node) UpdateAlloc(args *structs.AllocUpdateRequest, reply *structs.Gene
 			continue
 		}
 
-		// Add an evaluation if this is a failed alloc that is eligible for rescheduling
-		if allocToUpdate.ClientStatus == structs.AllocClientStatusFailed && alloc.FollowupEvalID == "" && alloc.RescheduleEligible(taskGroup.ReschedulePolicy, now) {
+		// Add an evaluation if this is a failed alloc that is eligible for
+		// rescheduling, or that the client rejected, which is replaced
+		// regardless of the reschedule policy's attempts. The scheduler
+		// still delays the replacement by the policy's delay.
+		if allocToUpdate.ClientStatus == structs.AllocClientStatusFailed && alloc.FollowupEvalID == "" &&
+			(allocToUpdate.RejectedByClient() || alloc.RescheduleEligible(taskGroup.ReschedulePolicy, now)) {
 			eval := &structs.Evaluation{
 				ID:          uuid.Generate(),
 				Namespace:   alloc.Namespace,
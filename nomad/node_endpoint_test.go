@@ -2478,6 +2478,62 @@ func TestClientEndpoint_UpdateAlloc(t *testing.T) {
 
 }
 
+// TestClientEndpoint_UpdateAlloc_Rejected asserts an eval is created for an
+// alloc the client rejected, even though its reschedule policy doesn't allow
+// rescheduling it.
+func TestClientEndpoint_UpdateAlloc_Rejected(t *testing.T) {
+	t.Parallel()
+
+	s1, cleanupS1 := TestServer(t, func(c *Config) {
+		c.NumSchedulers = 0
+	})
+	defer cleanupS1()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	require := require.New(t)
+
+	node := mock.Node()
+	reg := &structs.NodeRegisterRequest{
+		Node:         node,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	require.NoError(msgpackrpc.CallWithCodec(codec, "Node.Register", reg, &resp))
+
+	state := s1.fsm.State()
+	job := mock.Job()
+	job.TaskGroups[0].ReschedulePolicy = &structs.ReschedulePolicy{Attempts: 0, Interval: time.Hour}
+	require.NoError(state.UpsertJob(structs.MsgTypeTestSetup, 101, job))
+
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.NodeID = node.ID
+	alloc.TaskGroup = job.TaskGroups[0].Name
+	require.NoError(state.UpsertJobSummary(99, mock.JobSummary(alloc.JobID)))
+	require.NoError(state.UpsertAllocs(structs.MsgTypeTestSetup, 102, []*structs.Allocation{alloc}))
+
+	event := structs.NewTaskEvent(structs.TaskSetupFailure)
+	event.Details[structs.TaskEventDetailRejected] = "true"
+	clientAlloc := alloc.Copy()
+	clientAlloc.ClientStatus = structs.AllocClientStatusFailed
+	clientAlloc.TaskStates = map[string]*structs.TaskState{
+		"web": {State: structs.TaskStateDead, Failed: true, Events: []*structs.TaskEvent{event}},
+	}
+
+	update := &structs.AllocUpdateRequest{
+		Alloc:        []*structs.Allocation{clientAlloc},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp2 structs.NodeAllocsResponse
+	require.NoError(msgpackrpc.CallWithCodec(codec, "Node.UpdateAlloc", update, &resp2))
+
+	evals, err := state.EvalsByJob(nil, job.Namespace, job.ID)
+	require.NoError(err)
+	require.Len(evals, 1)
+	require.Equal(structs.EvalTriggerRetryFailedAlloc, evals[0].TriggeredBy)
+}
+
 func TestClientEndpoint_BatchUpdate(t *testing.T) {
 	t.Parallel()
 
@@ -302,6 +302,16 @@ func (c *ConsulConfig) ApiConfig() (*consul.Config, error) {
 	return config, nil
 }
 
+// ValidateTLS returns an error if the config enables TLS but its CA,
+// certificate or key files are missing or invalid, so that it fails when the
+// agent starts rather than on first use.
+func (c *ConsulConfig) ValidateTLS() error {
+	if c == nil || c.EnableSSL == nil || !*c.EnableSSL {
+		return nil
+	}
+	return validateTLSFiles(c.CAFile, "", c.CertFile, c.KeyFile)
+}
+
 // Copy returns a copy of this Consul config.
 func (c *ConsulConfig) Copy() *ConsulConfig {
 	if c == nil {
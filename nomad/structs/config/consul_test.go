@@ -9,6 +9,7 @@ import (
 	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -167,3 +168,81 @@ func TestConsulConfig_Exec(t *testing.T) {
 	require.NotNil(t, conf.VerifySSL)
 	assert.True(t, *conf.VerifySSL)
 }
+
+func TestConsulConfig_ValidateTLS(t *testing.T) {
+	const (
+		cafile  = "../../../helper/tlsutil/testdata/ca.pem"
+		foocert = "../../../helper/tlsutil/testdata/nomad-foo.pem"
+		fookey  = "../../../helper/tlsutil/testdata/nomad-foo-key.pem"
+		badkey  = "../../../helper/tlsutil/testdata/nomad-bad-key.pem"
+	)
+
+	cases := []struct {
+		name   string
+		config *ConsulConfig
+		err    string
+	}{
+		{
+			name: "valid",
+			config: &ConsulConfig{
+				EnableSSL: helper.BoolToPtr(true),
+				CAFile:    cafile,
+				CertFile:  foocert,
+				KeyFile:   fookey,
+			},
+		},
+		{
+			name: "ssl disabled",
+			config: &ConsulConfig{
+				EnableSSL: helper.BoolToPtr(false),
+				CAFile:    "/does/not/exist.pem",
+			},
+		},
+		{
+			name: "missing ca",
+			config: &ConsulConfig{
+				EnableSSL: helper.BoolToPtr(true),
+				CAFile:    "/does/not/exist.pem",
+			},
+			err: "failed to read ca_file",
+		},
+		{
+			name: "ca is not a certificate",
+			config: &ConsulConfig{
+				EnableSSL: helper.BoolToPtr(true),
+				CAFile:    fookey,
+			},
+			err: "contains no PEM certificates",
+		},
+		{
+			name: "mismatched keypair",
+			config: &ConsulConfig{
+				EnableSSL: helper.BoolToPtr(true),
+				CAFile:    cafile,
+				CertFile:  foocert,
+				KeyFile:   badkey,
+			},
+			err: "are not a valid keypair",
+		},
+		{
+			name: "cert without key",
+			config: &ConsulConfig{
+				EnableSSL: helper.BoolToPtr(true),
+				CertFile:  foocert,
+			},
+			err: "cert_file and key_file must be set together",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.ValidateTLS()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
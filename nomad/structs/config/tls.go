@@ -3,11 +3,15 @@ package config
 import (
 	"crypto/md5"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"sync"
+
+	multierror "github.com/hashicorp/go-multierror"
 )
 
 // TLSConfig provides TLS related configuration
@@ -308,3 +312,43 @@ func createChecksumOfFiles(inputs ...string) (string, error) {
 
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
+
+// validateTLSFiles returns an error if the CA file doesn't hold any PEM
+// certificates, the CA path isn't a directory, or the certificate and key
+// files don't form a keypair. Empty paths aren't checked.
+func validateTLSFiles(caFile, caPath, certFile, keyFile string) error {
+	var mErr multierror.Error
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("failed to read ca_file: %v", err))
+		} else if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("ca_file %q contains no PEM certificates", caFile))
+		}
+	}
+
+	if caPath != "" {
+		if fi, err := os.Stat(caPath); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("failed to read ca_path: %v", err))
+		} else if !fi.IsDir() {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("ca_path %q is not a directory", caPath))
+		}
+	}
+
+	switch {
+	case certFile == "" && keyFile == "":
+	case certFile == "" || keyFile == "":
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("cert_file and key_file must be set together"))
+	default:
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("cert_file %q and key_file %q are not a valid keypair: %v",
+				certFile, keyFile, err))
+		}
+	}
+
+	if len(mErr.Errors) == 1 {
+		return mErr.Errors[0]
+	}
+	return mErr.ErrorOrNil()
+}
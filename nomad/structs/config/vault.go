@@ -177,6 +177,16 @@ func (c *VaultConfig) ApiConfig() (*vault.Config, error) {
 	return conf, nil
 }
 
+// ValidateTLS returns an error if the config enables Vault but its CA,
+// certificate or key files are missing or invalid, so that it fails when the
+// agent starts rather than on first use.
+func (c *VaultConfig) ValidateTLS() error {
+	if c == nil || !c.IsEnabled() {
+		return nil
+	}
+	return validateTLSFiles(c.TLSCaFile, c.TLSCaPath, c.TLSCertFile, c.TLSKeyFile)
+}
+
 // Copy returns a copy of this Vault config.
 func (c *VaultConfig) Copy() *VaultConfig {
 	if c == nil {
@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/hashicorp/nomad/helper"
 	"github.com/stretchr/testify/require"
 )
 
@@ -127,3 +128,36 @@ func TestVaultConfig_IsEqual(t *testing.T) {
 	}
 	require.False(c3.IsEqual(c4))
 }
+
+func TestVaultConfig_ValidateTLS(t *testing.T) {
+	const (
+		cafile  = "../../../helper/tlsutil/testdata/ca.pem"
+		foocert = "../../../helper/tlsutil/testdata/nomad-foo.pem"
+		fookey  = "../../../helper/tlsutil/testdata/nomad-foo-key.pem"
+		badkey  = "../../../helper/tlsutil/testdata/nomad-bad-key.pem"
+	)
+
+	c := &VaultConfig{
+		Enabled:     helper.BoolToPtr(true),
+		TLSCaFile:   cafile,
+		TLSCaPath:   "../../../helper/tlsutil/testdata",
+		TLSCertFile: foocert,
+		TLSKeyFile:  fookey,
+	}
+	require.NoError(t, c.ValidateTLS())
+
+	// A missing CA and a mismatched keypair are both reported
+	c.TLSCaFile = "/does/not/exist.pem"
+	c.TLSKeyFile = badkey
+	err := c.ValidateTLS()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read ca_file")
+	require.Contains(t, err.Error(), "are not a valid keypair")
+
+	c.TLSCaPath = cafile
+	require.Contains(t, c.ValidateTLS().Error(), "is not a directory")
+
+	// Files aren't checked while Vault is disabled
+	c.Enabled = helper.BoolToPtr(false)
+	require.NoError(t, c.ValidateTLS())
+}
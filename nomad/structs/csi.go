@@ -227,6 +227,10 @@ type CSIVolumeClaim struct {
 	AccessMode     CSIVolumeAccessMode
 	AttachmentMode CSIVolumeAttachmentMode
 	State          CSIVolumeClaimState
+
+	// Labels are attached by the client from its environment to trace the
+	// claim back to the context it was made in, such as a deployment.
+	Labels map[string]string
 }
 
 type CSIVolumeClaimState int
@@ -379,6 +383,33 @@ func (v *CSIVolume) ReadSchedulable() bool {
 	return v.ResourceExhausted == time.Time{}
 }
 
+// IsAccessibleFrom returns whether the volume is accessible from the node
+// topology: every segment of one of the volume's topologies must match the
+// node's segment of the same name. Volumes without topologies are accessible
+// from any node, and a nil node topology isn't checked.
+func (v *CSIVolume) IsAccessibleFrom(node *CSITopology) bool {
+	if node == nil || len(v.Topologies) == 0 {
+		return true
+	}
+
+	for _, topology := range v.Topologies {
+		if topology == nil {
+			continue
+		}
+		matches := true
+		for segment, value := range topology.Segments {
+			if node.Segments[segment] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
 // WriteSchedulable determines if the volume is schedulable for writes,
 // considering only volume capabilities and plugin health
 func (v *CSIVolume) WriteSchedulable() bool {
@@ -751,6 +782,18 @@ type CSIVolumeClaimRequest struct {
 	AccessMode     CSIVolumeAccessMode
 	AttachmentMode CSIVolumeAttachmentMode
 	State          CSIVolumeClaimState
+
+	// Labels are attached to the claim to trace it back to the context it
+	// was made in.
+	Labels map[string]string
+
+	// NodeTopologies are the accessible topologies of the claiming node's CSI
+	// node plugins, by plugin ID, as the client fingerprinted them. They're
+	// only sent by clients configured to check the topology of the volumes
+	// they claim, and the claim of a volume that isn't accessible from the
+	// topology of its plugin is rejected before it's published.
+	NodeTopologies map[string]*CSITopology
+
 	WriteRequest
 }
 
@@ -763,6 +806,7 @@ func (req *CSIVolumeClaimRequest) ToClaim() *CSIVolumeClaim {
 		AccessMode:     req.AccessMode,
 		AttachmentMode: req.AttachmentMode,
 		State:          req.State,
+		Labels:         helper.CopyMapStringString(req.Labels),
 	}
 }
 
@@ -789,6 +833,22 @@ type CSIVolumeClaimResponse struct {
 	QueryMeta
 }
 
+// CSIVolumeBatchClaimRequest is the request of the CSIVolume.ClaimBatch RPC,
+// which claims several volumes for an allocation in a single round trip. The
+// server makes every claim or none of them. It isn't to be confused with
+// CSIVolumeClaimBatchRequest, which batches claims applied to the raft log.
+type CSIVolumeBatchClaimRequest struct {
+	Claims []*CSIVolumeClaimRequest
+	WriteRequest
+}
+
+// CSIVolumeBatchClaimResponse holds the response to each claim of a
+// CSIVolumeBatchClaimRequest, in the order of the request's claims.
+type CSIVolumeBatchClaimResponse struct {
+	Claims []*CSIVolumeClaimResponse
+	QueryMeta
+}
+
 type CSIVolumeListRequest struct {
 	PluginID string
 	NodeID   string
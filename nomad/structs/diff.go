@@ -265,6 +265,20 @@ func (tg *TaskGroup) Diff(other *TaskGroup, contextual bool) (*TaskGroupDiff, er
 		}
 	}
 
+	// CSIClaimTimeout diff
+	if oldPrimitiveFlat != nil && newPrimitiveFlat != nil {
+		if tg.CSIClaimTimeout == nil {
+			oldPrimitiveFlat["CSIClaimTimeout"] = ""
+		} else {
+			oldPrimitiveFlat["CSIClaimTimeout"] = fmt.Sprintf("%d", *tg.CSIClaimTimeout)
+		}
+		if other.CSIClaimTimeout == nil {
+			newPrimitiveFlat["CSIClaimTimeout"] = ""
+		} else {
+			newPrimitiveFlat["CSIClaimTimeout"] = fmt.Sprintf("%d", *other.CSIClaimTimeout)
+		}
+	}
+
 	// Diff the primitive fields.
 	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, false)
 
@@ -458,6 +472,11 @@ func (t *Task) Diff(other *Task, contextual bool) (*TaskDiff, error) {
 	// Diff the primitive fields.
 	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, false)
 
+	// ChrootExtras diff
+	if setDiff := stringSetDiff(t.ChrootExtras, other.ChrootExtras, "ChrootExtras", contextual); setDiff != nil && setDiff.Type != DiffTypeNone {
+		diff.Objects = append(diff.Objects, setDiff)
+	}
+
 	// Constraints diff
 	conDiff := primitiveObjectSetDiff(
 		interfaceSlice(t.Constraints),
@@ -502,6 +521,11 @@ func (t *Task) Diff(other *Task, contextual bool) (*TaskDiff, error) {
 		diff.Objects = append(diff.Objects, dDiff)
 	}
 
+	// Tmpfs diff
+	if tDiff := primitiveObjectDiff(t.Tmpfs, other.Tmpfs, nil, "Tmpfs", contextual); tDiff != nil {
+		diff.Objects = append(diff.Objects, tDiff)
+	}
+
 	// Artifacts diff
 	diffs := primitiveObjectSetDiff(
 		interfaceSlice(t.Artifacts),
@@ -1628,6 +1652,33 @@ func waitConfigDiff(old, new *WaitConfig, contextual bool) *ObjectDiff {
 	return diff
 }
 
+// templateRetryConfigDiff returns the diff of two template retry configs. If
+// contextual diff is enabled, all fields will be returned, even if no diff
+// occurred.
+func templateRetryConfigDiff(old, new *TemplateRetryConfig, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "Retry"}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, nil, false)
+	} else if new == nil {
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, false)
+	} else {
+		diff.Type = DiffTypeEdited
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, false)
+		newPrimitiveFlat = flatmap.Flatten(new, nil, false)
+	}
+
+	// Diff the primitive fields.
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	return diff
+}
+
 // templateDiff returns the diff of two Consul Template objects. If contextual diff is
 // enabled, all fields will be returned, even if no diff occurred.
 func templateDiff(old, new *Template, contextual bool) *ObjectDiff {
@@ -1658,6 +1709,16 @@ func templateDiff(old, new *Template, contextual bool) *ObjectDiff {
 		diff.Objects = append(diff.Objects, waitDiffs)
 	}
 
+	// Retry diffs
+	if retryDiffs := templateRetryConfigDiff(old.Retry, new.Retry, contextual); retryDiffs != nil {
+		diff.Objects = append(diff.Objects, retryDiffs)
+	}
+
+	// DependsOn diffs
+	if setDiff := stringSetDiff(old.DependsOn, new.DependsOn, "DependsOn", contextual); setDiff != nil {
+		diff.Objects = append(diff.Objects, setDiff)
+	}
+
 	return diff
 }
 
@@ -4105,6 +4105,70 @@ func TestTaskDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "ChrootExtras edited",
+			Old: &Task{
+				ChrootExtras: []string{"zoneinfo", "locales"},
+			},
+			New: &Task{
+				ChrootExtras: []string{"zoneinfo", "ca-certificates"},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "ChrootExtras",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "ChrootExtras",
+								Old:  "",
+								New:  "ca-certificates",
+							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "ChrootExtras",
+								Old:  "locales",
+								New:  "",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "Tmpfs edited",
+			Old: &Task{
+				Tmpfs: &TaskTmpfs{SizeMB: 128, Mode: "0700", CountMemory: true},
+			},
+			New: &Task{
+				Tmpfs: &TaskTmpfs{SizeMB: 256, Mode: "0700", CountMemory: false},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Tmpfs",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "CountMemory",
+								Old:  "true",
+								New:  "false",
+							},
+							{
+								Type: DiffTypeEdited,
+								Name: "SizeMB",
+								Old:  "128",
+								New:  "256",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			Name: "Constraints edited",
 			Old: &Task{
@@ -16,9 +16,11 @@ const (
 	errJobRegistrationDisabled    = "Job registration, dispatch, and scale are disabled by the scheduler configuration"
 	errNoNodeConn                 = "No path to node"
 	errUnknownMethod              = "Unknown rpc method"
+	errRPCMethodNotFound          = "can't find method"
 	errUnknownNomadVersion        = "Unable to determine Nomad version"
 	errNodeLacksRpc               = "Node does not support RPC; requires 0.8 or later"
 	errMissingAllocID             = "Missing allocation ID"
+	errNoServers                  = "no servers"
 
 	// Prefix based errors that are used to check if the error is of a given
 	// type. These errors should be created with the associated constructor.
@@ -39,6 +41,9 @@ const (
 	errDeploymentTerminalNoRun       = "can't run terminal deployment"
 	errDeploymentTerminalNoSetHealth = "can't set health of allocations for a terminal deployment"
 	errDeploymentRunningNoUnblock    = "can't unblock running deployment"
+
+	errCSIClientRPCRetryable     = "CSI client error (retryable)"
+	errCSIPluginNotFingerprinted = "CSI node plugin is not running or has not fingerprinted on this client"
 )
 
 var (
@@ -53,6 +58,7 @@ var (
 	ErrUnknownNomadVersion        = errors.New(errUnknownNomadVersion)
 	ErrNodeLacksRpc               = errors.New(errNodeLacksRpc)
 	ErrMissingAllocID             = errors.New(errMissingAllocID)
+	ErrNoServers                  = errors.New(errNoServers)
 
 	ErrUnknownNode = errors.New(ErrUnknownNodePrefix)
 
@@ -67,7 +73,9 @@ var (
 	ErrDeploymentRunningNoUnblock    = errors.New(errDeploymentRunningNoUnblock)
 
 	ErrCSIClientRPCIgnorable = errors.New("CSI client error (ignorable)")
-	ErrCSIClientRPCRetryable = errors.New("CSI client error (retryable)")
+	ErrCSIClientRPCRetryable = errors.New(errCSIClientRPCRetryable)
+
+	ErrCSIPluginNotFingerprinted = errors.New(errCSIPluginNotFingerprinted)
 )
 
 // IsErrNoLeader returns whether the error is due to there being no leader.
@@ -99,10 +107,35 @@ func IsErrNoNodeConn(err error) bool {
 	return err != nil && strings.Contains(err.Error(), errNoNodeConn)
 }
 
-// IsErrUnknownMethod returns whether the error is due to the operation not
-// being allowed due to lack of permissions.
+// IsErrUnknownMethod returns whether the error is due to the RPC method being
+// unknown, including the error of a server that doesn't have the method.
 func IsErrUnknownMethod(err error) bool {
-	return err != nil && strings.Contains(err.Error(), errUnknownMethod)
+	return err != nil && (strings.Contains(err.Error(), errUnknownMethod) ||
+		strings.Contains(err.Error(), errRPCMethodNotFound))
+}
+
+// IsErrNotReadyForConsistentReads returns whether the error is due to the
+// server not being ready to serve consistent reads.
+func IsErrNotReadyForConsistentReads(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errNotReadyForConsistentReads)
+}
+
+// IsErrNoServers returns whether the error is due to the client not knowing
+// of any servers.
+func IsErrNoServers(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errNoServers)
+}
+
+// IsErrCSIClientRPCRetryable returns whether the error is a transient
+// failure of a CSI plugin, as returned by the client running it.
+func IsErrCSIClientRPCRetryable(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errCSIClientRPCRetryable)
+}
+
+// IsErrCSIPluginNotFingerprinted returns whether the error is due to the
+// servers not having seen a volume's node plugin fingerprinted on the client.
+func IsErrCSIPluginNotFingerprinted(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errCSIPluginNotFingerprinted)
 }
 
 func IsErrRPCCoded(err error) bool {
@@ -217,3 +250,79 @@ func CodeFromRPCCodedErr(err error) (code int, msg string, ok bool) {
 
 	return code, parts[1], true
 }
+
+const (
+	// PlacementFailureMissingHostVolume is the placement failure code of an
+	// allocation requesting a host volume the node does not have.
+	PlacementFailureMissingHostVolume = "missing_host_volume"
+
+	// PlacementFailureDriverUnavailable is the placement failure code of an
+	// allocation whose task driver is not available on the node.
+	PlacementFailureDriverUnavailable = "driver_unavailable"
+
+	// TaskEventDetailPlacementFailure is the task event detail holding the
+	// placement failure code of the error that failed the task.
+	TaskEventDetailPlacementFailure = "placement_failure"
+
+	// TaskEventDetailRejected is the task event detail set when the client
+	// rejected the allocation with an AllocRejectedError.
+	TaskEventDetailRejected = "client_rejected"
+)
+
+// NodePlacementError is an error that fails an allocation every time an
+// allocation of the same job is placed on the node, because of the node
+// rather than the allocation. Code classifies the failure.
+type NodePlacementError struct {
+	Code string
+	Err  error
+}
+
+// NewNodePlacementError returns a NodePlacementError wrapping err.
+func NewNodePlacementError(code string, err error) error {
+	return &NodePlacementError{Code: code, Err: err}
+}
+
+func (e *NodePlacementError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NodePlacementError) Unwrap() error {
+	return e.Err
+}
+
+// PlacementFailureCode returns the code of the NodePlacementError wrapped by
+// err, if any.
+func PlacementFailureCode(err error) (string, bool) {
+	var perr *NodePlacementError
+	if errors.As(err, &perr) {
+		return perr.Code, true
+	}
+	return "", false
+}
+
+// AllocRejectedError is an error the client rejects a new allocation with,
+// before running it, because of a condition of the node that may not last,
+// such as low disk space. The scheduler replaces rejected allocations after
+// their reschedule policy's delay, even once its attempts are used up.
+type AllocRejectedError struct {
+	Err error
+}
+
+// NewAllocRejectedError returns an AllocRejectedError wrapping err.
+func NewAllocRejectedError(err error) error {
+	return &AllocRejectedError{Err: err}
+}
+
+func (e *AllocRejectedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AllocRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// IsAllocRejected returns whether err wraps an AllocRejectedError.
+func IsAllocRejected(err error) bool {
+	var rerr *AllocRejectedError
+	return errors.As(err, &rerr)
+}
@@ -2,6 +2,7 @@ package structs
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,3 +48,38 @@ func TestRPCCodedErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestPlacementFailureCode(t *testing.T) {
+	err := NewNodePlacementError(PlacementFailureMissingHostVolume, errors.New("missing data"))
+	wrapped := fmt.Errorf("failed creating runner: %w", err)
+
+	code, ok := PlacementFailureCode(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, PlacementFailureMissingHostVolume, code)
+	assert.Equal(t, "failed creating runner: missing data", wrapped.Error())
+
+	_, ok = PlacementFailureCode(errors.New("other"))
+	assert.False(t, ok)
+}
+
+func TestIsErr_OverRPC(t *testing.T) {
+	// Errors returned over RPC only keep their message, so the helpers
+	// match errors wrapping the sentinels once they're flattened
+	rpcErr := func(err error) error { return errors.New(err.Error()) }
+
+	notFingerprinted := fmt.Errorf("failed to find storage provider info for client %q, node plugin %q: %w",
+		"node1", "minnie", ErrCSIPluginNotFingerprinted)
+	assert.True(t, IsErrCSIPluginNotFingerprinted(rpcErr(notFingerprinted)))
+	assert.False(t, IsErrCSIPluginNotFingerprinted(rpcErr(ErrNoLeader)))
+	assert.False(t, IsErrCSIPluginNotFingerprinted(nil))
+
+	retryable := fmt.Errorf("controller publish: %v: %w", ErrCSIClientRPCRetryable, errors.New("busy"))
+	assert.True(t, IsErrCSIClientRPCRetryable(rpcErr(retryable)))
+	assert.True(t, IsErrNotReadyForConsistentReads(rpcErr(ErrNotReadyForConsistentReads)))
+	assert.True(t, IsErrNoServers(fmt.Errorf("rpc: %w", ErrNoServers)))
+
+	// Servers without a method return net/rpc's error rather than Nomad's
+	assert.True(t, IsErrUnknownMethod(rpcErr(ErrUnknownMethod)))
+	assert.True(t, IsErrUnknownMethod(errors.New("rpc: can't find method CSIVolume.ClaimBatch")))
+	assert.False(t, IsErrUnknownMethod(nil))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"strings"
 	"sync"
 
 	"github.com/hashicorp/nomad/helper"
@@ -708,10 +709,24 @@ func AllocatedPortsToNetworkResouce(ask *NetworkResource, ports AllocatedPorts,
 }
 
 type ClientHostNetworkConfig struct {
-	Name          string `hcl:",key"`
-	CIDR          string `hcl:"cidr"`
+	Name string `hcl:",key"`
+	CIDR string `hcl:"cidr"`
+
+	// CIDRs are additional CIDR blocks of the host network. When set, or when
+	// Interface is a glob pattern, the host network is registered with the
+	// first matching address of each address family rather than with every
+	// matching address.
+	CIDRs []string `hcl:"cidrs"`
+
+	// Interface is the interface of the host network, which may be a glob
+	// pattern such as "eth*".
 	Interface     string `hcl:"interface"`
 	ReservedPorts string `hcl:"reserved_ports"`
+
+	// BindWildcard makes the port mappings of ports on the host network
+	// match any destination address rather than only the address of the
+	// host network, such as for load balancers using direct server return.
+	BindWildcard bool `hcl:"bind_wildcard"`
 }
 
 func (p *ClientHostNetworkConfig) Copy() *ClientHostNetworkConfig {
@@ -721,5 +736,34 @@ func (p *ClientHostNetworkConfig) Copy() *ClientHostNetworkConfig {
 
 	c := new(ClientHostNetworkConfig)
 	*c = *p
+	c.CIDRs = helper.CopySliceString(p.CIDRs)
 	return c
 }
+
+// AllCIDRs returns the CIDR blocks of the host network: those of CIDR, which
+// may be a comma-separated list, followed by CIDRs.
+func (p *ClientHostNetworkConfig) AllCIDRs() []string {
+	var cidrs []string
+	if p.CIDR != "" {
+		for _, cidr := range strings.Split(p.CIDR, ",") {
+			cidrs = append(cidrs, strings.TrimSpace(cidr))
+		}
+	}
+	for _, cidr := range p.CIDRs {
+		cidrs = append(cidrs, strings.TrimSpace(cidr))
+	}
+	return cidrs
+}
+
+func CopyMapStringClientHostNetworkConfig(m map[string]*ClientHostNetworkConfig) map[string]*ClientHostNetworkConfig {
+	if m == nil {
+		return nil
+	}
+
+	nm := make(map[string]*ClientHostNetworkConfig, len(m))
+	for k, v := range m {
+		nm[k] = v.Copy()
+	}
+
+	return nm
+}
@@ -262,6 +262,11 @@ type DriverInfo struct {
 	Healthy           bool
 	HealthDescription string
 	UpdateTime        time.Time
+
+	// Degraded is set when task starts of a healthy driver have been
+	// failing on the node. The scheduler prefers other nodes for the task
+	// groups that use the driver.
+	Degraded bool
 }
 
 func (di *DriverInfo) Copy() *DriverInfo {
@@ -280,6 +285,7 @@ func (di *DriverInfo) Copy() *DriverInfo {
 func (di *DriverInfo) MergeHealthCheck(other *DriverInfo) {
 	di.Healthy = other.Healthy
 	di.HealthDescription = other.HealthDescription
+	di.Degraded = other.Degraded
 	di.UpdateTime = other.UpdateTime
 }
 
@@ -306,5 +312,9 @@ func (di *DriverInfo) HealthCheckEquals(other *DriverInfo) bool {
 		return false
 	}
 
+	if di.Degraded != other.Degraded {
+		return false
+	}
+
 	return true
 }
@@ -3,6 +3,7 @@ package structs
 import (
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 )
 
@@ -52,6 +53,16 @@ func (s *StreamingRpcRegistry) GetHandler(method string) (StreamingRpcHandler, e
 	return h, nil
 }
 
+// Methods returns the sorted names of the registered methods.
+func (s *StreamingRpcRegistry) Methods() []string {
+	methods := make([]string, 0, len(s.registry))
+	for method := range s.registry {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
 // Bridge is used to just link two connections together and copy traffic
 func Bridge(a, b io.ReadWriteCloser) {
 	wg := sync.WaitGroup{}
@@ -996,6 +996,14 @@ type AllocSignalRequest struct {
 	QueryOptions
 }
 
+// AllocPauseRequest is used to pause or resume a specific allocation, or a
+// single task of it
+type AllocPauseRequest struct {
+	AllocID string
+	Task    string
+	QueryOptions
+}
+
 // AllocsGetRequest is used to query a set of allocations
 type AllocsGetRequest struct {
 	AllocIDs []string
@@ -1637,6 +1645,7 @@ const (
 	NodeEventSubsystemHeartbeat = "Heartbeat"
 	NodeEventSubsystemCluster   = "Cluster"
 	NodeEventSubsystemStorage   = "Storage"
+	NodeEventSubsystemConfig    = "Config"
 )
 
 // NodeEvent is a single unit representing a node’s state change
@@ -4358,6 +4367,10 @@ func (j *Job) Validate() error {
 			}
 		}
 
+		if tg.CSIClaimTimeout != nil && *tg.CSIClaimTimeout < 0 {
+			mErr.Errors = append(mErr.Errors, errors.New("csi_claim_timeout must be a positive value"))
+		}
+
 		if j.Type == "system" && tg.Count > 1 {
 			mErr.Errors = append(mErr.Errors,
 				fmt.Errorf("Job task group %s has count %d. Count cannot exceed 1 with system scheduler",
@@ -5364,6 +5377,71 @@ func (d *TaskLifecycleConfig) Validate() error {
 	return nil
 }
 
+// DefaultTaskTmpfsMode is the mode of a task's tmpfs scratch directory when
+// the tmpfs block doesn't set one
+const DefaultTaskTmpfsMode = "0700"
+
+// TaskTmpfs configures a scratch directory backed by RAM that is mounted into
+// the task, separately from its secrets directory.
+type TaskTmpfs struct {
+	// SizeMB is the size of the tmpfs, enforced by the tmpfs size option
+	SizeMB int
+
+	// Mode is the octal permissions of the tmpfs directory
+	Mode string
+
+	// CountMemory marks that the tmpfs is counted against the task's memory
+	// resource. Otherwise its size is reserved for the task in addition to
+	// its memory, so the scheduler accounts for the RAM it may use.
+	CountMemory bool
+}
+
+func (t *TaskTmpfs) Copy() *TaskTmpfs {
+	if t == nil {
+		return nil
+	}
+	nt := new(TaskTmpfs)
+	*nt = *t
+	return nt
+}
+
+func (t *TaskTmpfs) Validate() error {
+	if t == nil {
+		return nil
+	}
+
+	var mErr multierror.Error
+	if t.SizeMB <= 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("size must be positive, got %d", t.SizeMB))
+	}
+	if _, err := t.FileMode(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	return mErr.ErrorOrNil()
+}
+
+// FileMode parses the mode of the tmpfs directory.
+func (t *TaskTmpfs) FileMode() (os.FileMode, error) {
+	mode := t.Mode
+	if mode == "" {
+		mode = DefaultTaskTmpfsMode
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid mode %q, must be octal permissions such as %q", t.Mode, DefaultTaskTmpfsMode)
+	}
+	return os.FileMode(m), nil
+}
+
+// ReservedMemoryMB returns the memory reserved for the tmpfs in addition to
+// the task's memory resource.
+func (t *TaskTmpfs) ReservedMemoryMB() int {
+	if t == nil || t.CountMemory {
+		return 0
+	}
+	return t.SizeMB
+}
+
 var (
 	// These default restart policies needs to be in sync with
 	// Canonicalize in api/tasks.go
@@ -6084,6 +6162,11 @@ type TaskGroup struct {
 	// StopAfterClientDisconnect, if set, configures the client to stop the task group
 	// after this duration since the last known good heartbeat
 	StopAfterClientDisconnect *time.Duration
+
+	// CSIClaimTimeout, if set, bounds the time the client waits for each of
+	// the group's CSI volume claims, overriding the client's
+	// csi_volume_mount_timeout.
+	CSIClaimTimeout *time.Duration
 }
 
 func (tg *TaskGroup) Copy() *TaskGroup {
@@ -6140,6 +6223,10 @@ func (tg *TaskGroup) Copy() *TaskGroup {
 		ntg.StopAfterClientDisconnect = tg.StopAfterClientDisconnect
 	}
 
+	if tg.CSIClaimTimeout != nil {
+		ntg.CSIClaimTimeout = helper.TimeToPtr(*tg.CSIClaimTimeout)
+	}
+
 	return ntg
 }
 
@@ -6844,6 +6931,15 @@ type Task struct {
 
 	// CSIPluginConfig is used to configure the plugin supervisor for the task.
 	CSIPluginConfig *TaskCSIPluginConfig
+
+	// ChrootExtras names the client chroot fragments, such as "zoneinfo",
+	// whose host paths are embedded in the task's chroot in addition to
+	// the client's chroot_env.
+	ChrootExtras []string
+
+	// Tmpfs configures a scratch directory backed by RAM that is mounted
+	// into the task.
+	Tmpfs *TaskTmpfs
 }
 
 // UsesConnect is for conveniently detecting if the Task is able to make use
@@ -6885,6 +6981,8 @@ func (t *Task) Copy() *Task {
 	nt.Meta = helper.CopyMapStringString(nt.Meta)
 	nt.DispatchPayload = nt.DispatchPayload.Copy()
 	nt.Lifecycle = nt.Lifecycle.Copy()
+	nt.ChrootExtras = helper.CopySliceString(nt.ChrootExtras)
+	nt.Tmpfs = nt.Tmpfs.Copy()
 
 	if t.Artifacts != nil {
 		artifacts := make([]*TaskArtifact, 0, len(t.Artifacts))
@@ -7063,6 +7161,12 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk, jobType string, tgServices
 		}
 	}
 
+	if len(destinations) == len(t.Templates) {
+		if _, err := SortTemplates(t.Templates); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Template dependencies invalid: %v", err))
+		}
+	}
+
 	// Validate the dispatch payload block if there
 	if t.DispatchPayload != nil {
 		if err := t.DispatchPayload.Validate(); err != nil {
@@ -7078,6 +7182,10 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk, jobType string, tgServices
 
 	}
 
+	if err := t.Tmpfs.Validate(); err != nil {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Tmpfs validation failed: %v", err))
+	}
+
 	// Validation for TaskKind field which is used for Consul Connect integration
 	if t.Kind.IsConnectProxy() {
 		// This task is a Connect proxy so it should not have service stanzas
@@ -7432,6 +7540,17 @@ const (
 	TemplateChangeModeRestart = "restart"
 )
 
+const (
+	// TemplateRestartOrderParallel marks that a re-rendered template restarts
+	// its task immediately, regardless of the other tasks in the group
+	TemplateRestartOrderParallel = "parallel"
+
+	// TemplateRestartOrderLifecycle marks that a re-rendered template
+	// restarts its task in stages ordered by the task lifecycle, together
+	// with any other tasks restarted by the same change
+	TemplateRestartOrderLifecycle = "lifecycle"
+)
+
 var (
 	// TemplateChangeModeInvalidError is the error for when an invalid change
 	// mode is given
@@ -7490,6 +7609,21 @@ type Template struct {
 
 	// WaitConfig is used to override the global WaitConfig on a per-template basis
 	Wait *WaitConfig
+
+	// Retry overrides the client's retry configs for the Consul, Vault and
+	// Nomad requests made to render the task's templates. The templates of
+	// a task share these clients, so the overrides of all of them apply.
+	Retry *TemplateRetryConfig
+
+	// RestartOrder controls how a restart triggered by this template is
+	// ordered relative to the other tasks in the group. It is only used when
+	// ChangeMode is restart.
+	RestartOrder string
+
+	// DependsOn is the destination paths of the task's other templates that
+	// this template depends on, such as by reading the files they render.
+	// The template is rendered after them.
+	DependsOn []string
 }
 
 // DefaultTemplate returns a default template.
@@ -7511,7 +7645,9 @@ func (t *Template) Copy() *Template {
 	if t.Wait != nil {
 		nt.Wait = t.Wait.Copy()
 	}
+	nt.Retry = t.Retry.Copy()
 
+	nt.DependsOn = helper.CopySliceString(t.DependsOn)
 	return nt
 }
 
@@ -7556,6 +7692,13 @@ func (t *Template) Validate() error {
 		_ = multierror.Append(&mErr, TemplateChangeModeInvalidError)
 	}
 
+	// Verify a proper restart order
+	switch t.RestartOrder {
+	case "", TemplateRestartOrderParallel, TemplateRestartOrderLifecycle:
+	default:
+		_ = multierror.Append(&mErr, fmt.Errorf("Invalid restart order %q. Must be one of the following: parallel, lifecycle", t.RestartOrder))
+	}
+
 	// Verify the splay is positive
 	if t.Splay < 0 {
 		_ = multierror.Append(&mErr, fmt.Errorf("Must specify positive splay value"))
@@ -7572,6 +7715,10 @@ func (t *Template) Validate() error {
 		_ = multierror.Append(&mErr, err)
 	}
 
+	if err = t.Retry.Validate(); err != nil {
+		_ = multierror.Append(&mErr, err)
+	}
+
 	return mErr.ErrorOrNil()
 }
 
@@ -7591,6 +7738,68 @@ func (t *Template) DiffID() string {
 	return t.DestPath
 }
 
+// SortTemplates returns the templates ordered so that each template comes
+// after the templates it depends on, keeping the templates' order otherwise.
+// It returns an error if a template depends on an unknown template or if the
+// dependencies form a cycle.
+func SortTemplates(templates []*Template) ([]*Template, error) {
+	byDest := make(map[string]*Template, len(templates))
+	for _, tmpl := range templates {
+		byDest[tmpl.DestPath] = tmpl
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*Template]int, len(templates))
+	sorted := make([]*Template, 0, len(templates))
+	var path []string
+
+	var visit func(tmpl *Template) error
+	visit = func(tmpl *Template) error {
+		switch state[tmpl] {
+		case visited:
+			return nil
+		case visiting:
+			// Report the cycle from where it starts in the path
+			start := 0
+			for i, dest := range path {
+				if dest == tmpl.DestPath {
+					start = i
+				}
+			}
+			cycle := append(helper.CopySliceString(path[start:]), tmpl.DestPath)
+			return fmt.Errorf("template dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[tmpl] = visiting
+		path = append(path, tmpl.DestPath)
+		for _, dep := range tmpl.DependsOn {
+			other, ok := byDest[dep]
+			if !ok {
+				return fmt.Errorf("template %q depends on unknown template %q", tmpl.DestPath, dep)
+			}
+			if err := visit(other); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[tmpl] = visited
+
+		sorted = append(sorted, tmpl)
+		return nil
+	}
+
+	for _, tmpl := range templates {
+		if err := visit(tmpl); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
 // WaitConfig is the Min/Max duration used by the Consul Template Watcher. Consul
 // Template relies on pointer based business logic. This struct uses pointers so
 // that we tell the different between zero values and unset values.
@@ -7656,6 +7865,56 @@ func (wc *WaitConfig) Validate() error {
 	return nil
 }
 
+// TemplateRetryConfig overrides the client's retry configs for the requests
+// made to render templates. The client's configs clamp it: Attempts can't
+// exceed the client's limit.
+type TemplateRetryConfig struct {
+	Attempts   *int
+	Backoff    *time.Duration
+	MaxBackoff *time.Duration
+}
+
+// Copy returns a deep copy of this configuration.
+func (rc *TemplateRetryConfig) Copy() *TemplateRetryConfig {
+	if rc == nil {
+		return nil
+	}
+
+	nrc := new(TemplateRetryConfig)
+	if rc.Attempts != nil {
+		nrc.Attempts = helper.IntToPtr(*rc.Attempts)
+	}
+	if rc.Backoff != nil {
+		nrc.Backoff = helper.TimeToPtr(*rc.Backoff)
+	}
+	if rc.MaxBackoff != nil {
+		nrc.MaxBackoff = helper.TimeToPtr(*rc.MaxBackoff)
+	}
+	return nrc
+}
+
+// Validate that the values aren't negative and the backoff doesn't exceed
+// the max backoff
+func (rc *TemplateRetryConfig) Validate() error {
+	if rc == nil {
+		return nil
+	}
+
+	if rc.Attempts != nil && *rc.Attempts < 0 {
+		return fmt.Errorf("retry attempts must not be negative, got %d", *rc.Attempts)
+	}
+	if rc.Backoff != nil && *rc.Backoff < 0 {
+		return fmt.Errorf("retry backoff must not be negative, got %s", *rc.Backoff)
+	}
+	if rc.MaxBackoff != nil && *rc.MaxBackoff < 0 {
+		return fmt.Errorf("retry max_backoff must not be negative, got %s", *rc.MaxBackoff)
+	}
+	if rc.Backoff != nil && rc.MaxBackoff != nil && *rc.MaxBackoff > 0 && *rc.Backoff > *rc.MaxBackoff {
+		return fmt.Errorf("retry backoff %s is greater than max_backoff %s", *rc.Backoff, *rc.MaxBackoff)
+	}
+	return nil
+}
+
 // AllocState records a single event that changes the state of the whole allocation
 type AllocStateField uint8
 
@@ -7734,6 +7993,11 @@ type TaskState struct {
 	// Experimental -  TaskHandle is based on drivers.TaskHandle and used
 	// by remote task drivers to migrate task handles between allocations.
 	TaskHandle *TaskHandle
+
+	// TerminationReason classifies the task's most recent termination or
+	// failure to start. It's nil until the task terminates, and for task
+	// states written by older clients.
+	TerminationReason *TaskTerminationReason
 }
 
 // NewTaskState returns a TaskState initialized in the Pending state.
@@ -7766,6 +8030,7 @@ func (ts *TaskState) Copy() *TaskState {
 	}
 
 	newTS.TaskHandle = ts.TaskHandle.Copy()
+	newTS.TerminationReason = ts.TerminationReason.Copy()
 	return newTS
 }
 
@@ -7776,6 +8041,57 @@ func (ts *TaskState) Successful() bool {
 	return ts.State == TaskStateDead && !ts.Failed
 }
 
+const (
+	// TaskTerminationExited indicates the task exited on its own
+	TaskTerminationExited = "exited"
+
+	// TaskTerminationSignaled indicates the task was killed by a signal
+	TaskTerminationSignaled = "signaled"
+
+	// TaskTerminationOOMKilled indicates the task was killed for running out
+	// of memory
+	TaskTerminationOOMKilled = "oom_killed"
+
+	// TaskTerminationDriverFailure indicates the driver failed to start or
+	// run the task
+	TaskTerminationDriverFailure = "driver_failure"
+
+	// TaskTerminationSetupFailure indicates the task failed before it was
+	// started, such as while validating it or downloading its artifacts
+	TaskTerminationSetupFailure = "setup_failure"
+
+	// TaskTerminationSubsystemDriver is the subsystem of terminations
+	// reported by the task driver
+	TaskTerminationSubsystemDriver = "driver"
+
+	// TaskTerminationSubsystemTaskRunner is the subsystem of failures of the
+	// client to set up the task
+	TaskTerminationSubsystemTaskRunner = "task_runner"
+)
+
+// TaskTerminationReason is the structured classification of why a task
+// terminated or failed to start, so that failures can be told apart without
+// parsing the messages of task events.
+type TaskTerminationReason struct {
+	// Reason is one of the TaskTermination constants
+	Reason string
+
+	// ExitCode and Signal are those the task exited with, if it was started
+	ExitCode int
+	Signal   int
+
+	// Subsystem is the part of the client reporting the termination
+	Subsystem string
+}
+
+func (r *TaskTerminationReason) Copy() *TaskTerminationReason {
+	if r == nil {
+		return nil
+	}
+	nr := *r
+	return &nr
+}
+
 const (
 	// TaskSetupFailure indicates that the task could not be started due to a
 	// a setup failure.
@@ -7817,9 +8133,20 @@ const (
 	// restarted
 	TaskRestartSignal = "Restart Signaled"
 
+	// TaskRestartSequenced indicates that a template driven restart of the
+	// task is being ordered with the other tasks in its group.
+	TaskRestartSequenced = "Restart Sequenced"
+
 	// TaskSignaling indicates that the task is being signalled.
 	TaskSignaling = "Signaling"
 
+	// TaskPaused indicates that the task's processes have been frozen.
+	TaskPaused = "Paused"
+
+	// TaskResumed indicates that the task's frozen processes have been
+	// thawed.
+	TaskResumed = "Resumed"
+
 	// TaskDownloadingArtifacts means the task is downloading the artifacts
 	// specified in the task.
 	TaskDownloadingArtifacts = "Downloading Artifacts"
@@ -7866,6 +8193,15 @@ const (
 
 	// TaskPluginHealthy indicates that a plugin managed by Nomad became healthy
 	TaskPluginHealthy = "Plugin became healthy"
+
+	// TaskCoreDumped indicates that a core file dumped by the task was
+	// collected.
+	TaskCoreDumped = "Core Dumped"
+
+	// TaskTmpfsMemoryWarning indicates that the task's tmpfs and memory
+	// together exceed its memory limit, so filling the tmpfs may get the
+	// task OOM killed.
+	TaskTmpfsMemoryWarning = "Tmpfs Memory Warning"
 )
 
 // TaskEvent is an event that effects the state of a task and contains meta-data
@@ -8258,6 +8594,37 @@ func (e *TaskEvent) SetOOMKilled(oom bool) *TaskEvent {
 	return e
 }
 
+// TerminationReason returns the classification of the termination the event
+// records, or nil if it doesn't record one.
+func (e *TaskEvent) TerminationReason() *TaskTerminationReason {
+	switch e.Type {
+	case TaskTerminated:
+		reason := &TaskTerminationReason{
+			Reason:    TaskTerminationExited,
+			ExitCode:  e.ExitCode,
+			Signal:    e.Signal,
+			Subsystem: TaskTerminationSubsystemDriver,
+		}
+		if oom, _ := strconv.ParseBool(e.Details["oom_killed"]); oom {
+			reason.Reason = TaskTerminationOOMKilled
+		} else if e.Signal != 0 {
+			reason.Reason = TaskTerminationSignaled
+		}
+		return reason
+	case TaskDriverFailure:
+		return &TaskTerminationReason{
+			Reason:    TaskTerminationDriverFailure,
+			Subsystem: TaskTerminationSubsystemDriver,
+		}
+	case TaskSetupFailure, TaskFailedValidation, TaskArtifactDownloadFailed:
+		return &TaskTerminationReason{
+			Reason:    TaskTerminationSetupFailure,
+			Subsystem: TaskTerminationSubsystemTaskRunner,
+		}
+	}
+	return nil
+}
+
 // TaskArtifact is an artifact to download before running the task.
 type TaskArtifact struct {
 	// GetterSource is the source to download an artifact using go-getter
@@ -9602,6 +9969,23 @@ func (a *Allocation) ShouldReschedule(reschedulePolicy *ReschedulePolicy, failTi
 	}
 }
 
+// RejectedByClient returns whether the client failed the allocation because
+// it rejected it before running it, for a reason that may not apply to a
+// replacement, as recorded by a task event.
+func (a *Allocation) RejectedByClient() bool {
+	if a.ClientStatus != AllocClientStatusFailed {
+		return false
+	}
+	for _, state := range a.TaskStates {
+		for _, event := range state.Events {
+			if _, ok := event.Details[TaskEventDetailRejected]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // RescheduleEligible returns if the allocation is eligible to be rescheduled according
 // to its ReschedulePolicy and the current state of its reschedule trackers
 func (a *Allocation) RescheduleEligible(reschedulePolicy *ReschedulePolicy, failTime time.Time) bool {
@@ -2468,6 +2468,79 @@ func TestTask_Validate_Template(t *testing.T) {
 	if expected := "cannot use signals"; !strings.Contains(err.Error(), expected) {
 		t.Errorf("expected to find %q but found %v", expected, err)
 	}
+
+	// Template dependencies can't form a cycle
+	task.Templates = []*Template{
+		{SourcePath: "foo", DestPath: "local/a", ChangeMode: "noop", DependsOn: []string{"local/b"}},
+		{SourcePath: "foo", DestPath: "local/b", ChangeMode: "noop", DependsOn: []string{"local/a"}},
+	}
+	err = task.Validate(ephemeralDisk, JobTypeService, nil, nil)
+	if expected := "template dependency cycle"; err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected to find %q but found %v", expected, err)
+	}
+}
+
+func TestSortTemplates(t *testing.T) {
+	tmpl := func(dest string, deps ...string) *Template {
+		return &Template{DestPath: dest, DependsOn: deps}
+	}
+	dests := func(templates []*Template) []string {
+		var result []string
+		for _, t := range templates {
+			result = append(result, t.DestPath)
+		}
+		return result
+	}
+
+	cases := []struct {
+		Name      string
+		Templates []*Template
+		Expected  []string
+		ExpectErr string
+	}{
+		{
+			Name:      "no dependencies",
+			Templates: []*Template{tmpl("a"), tmpl("b"), tmpl("c")},
+			Expected:  []string{"a", "b", "c"},
+		},
+		{
+			Name:      "dependency chain",
+			Templates: []*Template{tmpl("a", "b"), tmpl("b", "c"), tmpl("c"), tmpl("d")},
+			Expected:  []string{"c", "b", "a", "d"},
+		},
+		{
+			Name:      "shared dependency",
+			Templates: []*Template{tmpl("a", "c"), tmpl("b", "c"), tmpl("c")},
+			Expected:  []string{"c", "a", "b"},
+		},
+		{
+			Name:      "cycle",
+			Templates: []*Template{tmpl("d"), tmpl("a", "b"), tmpl("b", "c"), tmpl("c", "a")},
+			ExpectErr: "template dependency cycle: a -> b -> c -> a",
+		},
+		{
+			Name:      "self dependency",
+			Templates: []*Template{tmpl("a", "a")},
+			ExpectErr: "template dependency cycle: a -> a",
+		},
+		{
+			Name:      "unknown dependency",
+			Templates: []*Template{tmpl("a", "missing")},
+			ExpectErr: `template "a" depends on unknown template "missing"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			sorted, err := SortTemplates(tc.Templates)
+			if tc.ExpectErr != "" {
+				require.EqualError(t, err, tc.ExpectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Expected, dests(sorted))
+		})
+	}
 }
 
 func TestTemplate_Validate(t *testing.T) {
@@ -3627,6 +3700,68 @@ func TestTaskLifecycleConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestTaskTmpfs_Validate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		tmpfs *TaskTmpfs
+		mode  os.FileMode
+		err   string
+	}{
+		{
+			name:  "default mode",
+			tmpfs: &TaskTmpfs{SizeMB: 64},
+			mode:  0700,
+		},
+		{
+			name:  "mode",
+			tmpfs: &TaskTmpfs{SizeMB: 64, Mode: "0750"},
+			mode:  0750,
+		},
+		{
+			name:  "no size",
+			tmpfs: &TaskTmpfs{Mode: "0700"},
+			err:   "size must be positive, got 0",
+		},
+		{
+			name:  "non octal mode",
+			tmpfs: &TaskTmpfs{SizeMB: 64, Mode: "0789"},
+			err:   `invalid mode "0789"`,
+		},
+		{
+			name:  "mode with special bits",
+			tmpfs: &TaskTmpfs{SizeMB: 64, Mode: "4700"},
+			err:   `invalid mode "4700"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.tmpfs.Validate()
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			mode, err := tc.tmpfs.FileMode()
+			require.NoError(t, err)
+			require.Equal(t, tc.mode, mode)
+		})
+	}
+}
+
+func TestTaskTmpfs_ReservedMemoryMB(t *testing.T) {
+	var tmpfs *TaskTmpfs
+	require.Zero(t, tmpfs.ReservedMemoryMB())
+
+	tmpfs = &TaskTmpfs{SizeMB: 64, CountMemory: true}
+	require.Zero(t, tmpfs.ReservedMemoryMB())
+
+	tmpfs.CountMemory = false
+	require.Equal(t, 64, tmpfs.ReservedMemoryMB())
+}
+
 func TestRestartPolicy_Validate(t *testing.T) {
 	// Policy with acceptable restart options passes
 	p := &RestartPolicy{
@@ -4296,6 +4431,25 @@ func TestAllocation_Terminated(t *testing.T) {
 	}
 }
 
+func TestAllocation_RejectedByClient(t *testing.T) {
+	alloc := &Allocation{
+		ClientStatus: AllocClientStatusFailed,
+		TaskStates: map[string]*TaskState{
+			"web": {Events: []*TaskEvent{NewTaskEvent(TaskSetupFailure)}},
+		},
+	}
+	require.False(t, alloc.RejectedByClient())
+
+	event := NewTaskEvent(TaskSetupFailure)
+	event.Details[TaskEventDetailRejected] = "true"
+	alloc.TaskStates["web"].Events = append(alloc.TaskStates["web"].Events, event)
+	require.True(t, alloc.RejectedByClient())
+
+	// Only failed allocations are rejected
+	alloc.ClientStatus = AllocClientStatusComplete
+	require.False(t, alloc.RejectedByClient())
+}
+
 func TestAllocation_ShouldReschedule(t *testing.T) {
 	type testCase struct {
 		Desc               string
@@ -5207,6 +5361,20 @@ func TestJobConfig_Validate_StopAferClientDisconnect(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestJobConfig_Validate_CSIClaimTimeout(t *testing.T) {
+	job := testJob()
+	invalid := -1 * time.Second
+	job.TaskGroups[0].CSIClaimTimeout = &invalid
+
+	err := job.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "csi_claim_timeout must be a positive value")
+
+	valid := 30 * time.Second
+	job.TaskGroups[0].CSIClaimTimeout = &valid
+	require.NoError(t, job.Validate())
+}
+
 func TestParameterizedJobConfig_Canonicalize(t *testing.T) {
 	d := &ParameterizedJobConfig{}
 	d.Canonicalize()
@@ -5530,6 +5698,70 @@ func TestACLPolicySetHash(t *testing.T) {
 	assert.NotEqual(t, out1, out2)
 }
 
+func TestTaskEvent_TerminationReason(t *testing.T) {
+	cases := []struct {
+		name     string
+		event    *TaskEvent
+		expected *TaskTerminationReason
+	}{
+		{
+			name:  "exited",
+			event: NewTaskEvent(TaskTerminated).SetExitCode(1).SetSignal(0).SetOOMKilled(false),
+			expected: &TaskTerminationReason{
+				Reason:    TaskTerminationExited,
+				ExitCode:  1,
+				Subsystem: TaskTerminationSubsystemDriver,
+			},
+		},
+		{
+			name:  "signaled",
+			event: NewTaskEvent(TaskTerminated).SetExitCode(137).SetSignal(9).SetOOMKilled(false),
+			expected: &TaskTerminationReason{
+				Reason:    TaskTerminationSignaled,
+				ExitCode:  137,
+				Signal:    9,
+				Subsystem: TaskTerminationSubsystemDriver,
+			},
+		},
+		{
+			name:  "oom killed",
+			event: NewTaskEvent(TaskTerminated).SetExitCode(137).SetSignal(9).SetOOMKilled(true),
+			expected: &TaskTerminationReason{
+				Reason:    TaskTerminationOOMKilled,
+				ExitCode:  137,
+				Signal:    9,
+				Subsystem: TaskTerminationSubsystemDriver,
+			},
+		},
+		{
+			name:  "driver failure",
+			event: NewTaskEvent(TaskDriverFailure).SetDriverError(fmt.Errorf("boom")),
+			expected: &TaskTerminationReason{
+				Reason:    TaskTerminationDriverFailure,
+				Subsystem: TaskTerminationSubsystemDriver,
+			},
+		},
+		{
+			name:  "setup failure",
+			event: NewTaskEvent(TaskArtifactDownloadFailed),
+			expected: &TaskTerminationReason{
+				Reason:    TaskTerminationSetupFailure,
+				Subsystem: TaskTerminationSubsystemTaskRunner,
+			},
+		},
+		{
+			name:  "not a termination",
+			event: NewTaskEvent(TaskStarted),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.event.TerminationReason())
+		})
+	}
+}
+
 func TestTaskEventPopulate(t *testing.T) {
 	prepopulatedEvent := NewTaskEvent(TaskSetup)
 	prepopulatedEvent.DisplayMessage = "Hola"
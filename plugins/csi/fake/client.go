@@ -86,6 +86,8 @@ type Client struct {
 	NextNodeStageVolumeErr   error
 	NodeStageVolumeCallCount int64
 
+	PrevNodeUnstageVolumeID    string
+	PrevNodeUnstageStagingPath string
 	NextNodeUnstageVolumeErr   error
 	NodeUnstageVolumeCallCount int64
 
@@ -93,6 +95,7 @@ type Client struct {
 	NextNodePublishVolumeErr   error
 	NodePublishVolumeCallCount int64
 
+	PrevNodeUnpublishVolumeID    string
 	NextNodeUnpublishVolumeErr   error
 	NodeUnpublishVolumeCallCount int64
 }
@@ -273,6 +276,8 @@ func (c *Client) NodeUnstageVolume(ctx context.Context, volumeID string, staging
 	c.Mu.Lock()
 	defer c.Mu.Unlock()
 
+	c.PrevNodeUnstageVolumeID = volumeID
+	c.PrevNodeUnstageStagingPath = stagingTargetPath
 	c.NodeUnstageVolumeCallCount++
 
 	return c.NextNodeUnstageVolumeErr
@@ -292,6 +297,7 @@ func (c *Client) NodeUnpublishVolume(ctx context.Context, volumeID, targetPath s
 	c.Mu.Lock()
 	defer c.Mu.Unlock()
 
+	c.PrevNodeUnpublishVolumeID = volumeID
 	c.NodeUnpublishVolumeCallCount++
 
 	return c.NextNodeUnpublishVolumeErr
@@ -17,6 +17,7 @@ import (
 	"github.com/hashicorp/nomad/plugins/shared/hclspec"
 	pstructs "github.com/hashicorp/nomad/plugins/shared/structs"
 	sproto "github.com/hashicorp/nomad/plugins/shared/structs/proto"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
@@ -74,6 +75,7 @@ func (d *driverPluginClient) Capabilities() (*Capabilities, error) {
 
 		caps.MountConfigs = MountConfigSupport(resp.Capabilities.MountConfigs)
 		caps.RemoteTasks = resp.Capabilities.RemoteTasks
+		caps.CgroupFreeze = resp.Capabilities.CgroupFreeze
 	}
 
 	return caps, nil
@@ -151,6 +153,9 @@ func (d *driverPluginClient) StartTask(c *TaskConfig) (*TaskHandle, *DriverNetwo
 				return nil, nil, structs.NewRecoverableError(err, rec.Recoverable)
 			}
 		}
+		if st.Code() == codes.InvalidArgument {
+			return nil, nil, NewTaskConfigError(errors.New(st.Message()))
+		}
 		return nil, nil, grpcutils.HandleGrpcErr(err, d.doneCtx)
 	}
 
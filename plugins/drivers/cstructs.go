@@ -14,6 +14,9 @@ type MemoryStats = cstructs.MemoryStats
 // CpuStats holds cpu usage related stats
 type CpuStats = cstructs.CpuStats
 
+// DiskIOStats holds block device I/O stats
+type DiskIOStats = cstructs.DiskIOStats
+
 // ResourceUsage holds information related to cpu and memory stats
 type ResourceUsage = cstructs.ResourceUsage
 
@@ -171,6 +171,20 @@ type Capabilities struct {
 	// adjust behavior such as propogating task handles between allocations
 	// to avoid downtime when a client is lost.
 	RemoteTasks bool
+
+	// CgroupFreeze indicates the driver's tasks may be paused by freezing
+	// their cgroup. Drivers whose tasks are isolated outside of Nomad, such
+	// as in an image or on a remote system, must set it to opt in to pausing.
+	CgroupFreeze bool
+}
+
+// Freezable returns whether the driver's tasks may be paused by freezing
+// their cgroup.
+func (c *Capabilities) Freezable() bool {
+	if c.CgroupFreeze {
+		return true
+	}
+	return !c.RemoteTasks && c.FSIsolation != FSIsolationImage
 }
 
 func (c *Capabilities) HasNetIsolationMode(m NetIsolationMode) bool {
@@ -407,6 +421,10 @@ type LinuxResources struct {
 	// specific options are deprecated in favor of exposes CPUPeriod and
 	// CPUQuota at the task resource stanza.
 	PercentTicks float64
+
+	// CoreLimitBytes is the core file size limit of the task's processes.
+	// If zero, the task inherits the limit of the process launching it.
+	CoreLimitBytes int64
 }
 
 func (r *LinuxResources) Copy() *LinuxResources {
@@ -1,6 +1,9 @@
 package drivers
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrTaskNotFound = fmt.Errorf("task not found for given id")
 
@@ -9,3 +12,30 @@ var DriverRequiresRootMessage = "Driver must run as root"
 var NoCgroupMountMessage = "Failed to discover cgroup mount point"
 
 var CgroupMountEmpty = "Cgroup mount point unavailable"
+
+// TaskConfigError is returned by StartTask when a task can't be started
+// because of its own configuration rather than a problem with the driver,
+// such as a driver config that fails to decode or validate.
+type TaskConfigError struct {
+	Err error
+}
+
+// NewTaskConfigError wraps err as a TaskConfigError.
+func NewTaskConfigError(err error) error {
+	return &TaskConfigError{Err: err}
+}
+
+func (e *TaskConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TaskConfigError) Unwrap() error {
+	return e.Err
+}
+
+// IsTaskConfigError returns whether err is caused by the task's
+// configuration.
+func IsTaskConfigError(err error) bool {
+	var configErr *TaskConfigError
+	return errors.As(err, &configErr)
+}
@@ -276,6 +276,37 @@ func (MemoryUsage_Fields) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_4a8f45747846a74d, []int{55, 0}
 }
 
+type DiskIOUsage_Fields int32
+
+const (
+	DiskIOUsage_READ_BYTES  DiskIOUsage_Fields = 0
+	DiskIOUsage_WRITE_BYTES DiskIOUsage_Fields = 1
+	DiskIOUsage_READ_OPS    DiskIOUsage_Fields = 2
+	DiskIOUsage_WRITE_OPS   DiskIOUsage_Fields = 3
+)
+
+var DiskIOUsage_Fields_name = map[int32]string{
+	0: "READ_BYTES",
+	1: "WRITE_BYTES",
+	2: "READ_OPS",
+	3: "WRITE_OPS",
+}
+
+var DiskIOUsage_Fields_value = map[string]int32{
+	"READ_BYTES":  0,
+	"WRITE_BYTES": 1,
+	"READ_OPS":    2,
+	"WRITE_OPS":   3,
+}
+
+func (x DiskIOUsage_Fields) String() string {
+	return proto.EnumName(DiskIOUsage_Fields_name, int32(x))
+}
+
+func (DiskIOUsage_Fields) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_4a8f45747846a74d, []int{56, 0}
+}
+
 type TaskConfigSchemaRequest struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -1848,7 +1879,10 @@ type DriverCapabilities struct {
 	MountConfigs DriverCapabilities_MountConfigs `protobuf:"varint,6,opt,name=mount_configs,json=mountConfigs,proto3,enum=hashicorp.nomad.plugins.drivers.proto.DriverCapabilities_MountConfigs" json:"mount_configs,omitempty"`
 	// remote_tasks indicates whether the driver executes tasks remotely such
 	// on cloud runtimes like AWS ECS.
-	RemoteTasks          bool     `protobuf:"varint,7,opt,name=remote_tasks,json=remoteTasks,proto3" json:"remote_tasks,omitempty"`
+	RemoteTasks bool `protobuf:"varint,7,opt,name=remote_tasks,json=remoteTasks,proto3" json:"remote_tasks,omitempty"`
+	// cgroup_freeze indicates that the driver's tasks may be paused by
+	// freezing their cgroup.
+	CgroupFreeze         bool     `protobuf:"varint,8,opt,name=cgroup_freeze,json=cgroupFreeze,proto3" json:"cgroup_freeze,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1928,6 +1962,13 @@ func (m *DriverCapabilities) GetRemoteTasks() bool {
 	return false
 }
 
+func (m *DriverCapabilities) GetCgroupFreeze() bool {
+	if m != nil {
+		return m.CgroupFreeze
+	}
+	return false
+}
+
 type NetworkIsolationSpec struct {
 	Mode                 NetworkIsolationSpec_NetworkIsolationMode `protobuf:"varint,1,opt,name=mode,proto3,enum=hashicorp.nomad.plugins.drivers.proto.NetworkIsolationSpec_NetworkIsolationMode" json:"mode,omitempty"`
 	Path                 string                                    `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
@@ -2690,7 +2731,10 @@ type LinuxResources struct {
 	CpusetCgroup string `protobuf:"bytes,9,opt,name=cpuset_cgroup,json=cpusetCgroup,proto3" json:"cpuset_cgroup,omitempty"`
 	// PercentTicks is a compatibility option for docker and should not be used
 	// buf:lint:ignore FIELD_LOWER_SNAKE_CASE
-	PercentTicks         float64  `protobuf:"fixed64,8,opt,name=PercentTicks,proto3" json:"PercentTicks,omitempty"`
+	PercentTicks float64 `protobuf:"fixed64,8,opt,name=PercentTicks,proto3" json:"PercentTicks,omitempty"`
+	// CoreLimitBytes is the core file size limit of the task's processes.
+	// Default: 0 (inherited from the executor)
+	CoreLimitBytes       int64    `protobuf:"varint,10,opt,name=core_limit_bytes,json=coreLimitBytes,proto3" json:"core_limit_bytes,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -2777,6 +2821,13 @@ func (m *LinuxResources) GetPercentTicks() float64 {
 	return 0
 }
 
+func (m *LinuxResources) GetCoreLimitBytes() int64 {
+	if m != nil {
+		return m.CoreLimitBytes
+	}
+	return 0
+}
+
 type Mount struct {
 	// TaskPath is the file path within the task directory to mount to
 	TaskPath string `protobuf:"bytes,1,opt,name=task_path,json=taskPath,proto3" json:"task_path,omitempty"`
@@ -3285,7 +3336,9 @@ type TaskResourceUsage struct {
 	// CPU usage stats
 	Cpu *CPUUsage `protobuf:"bytes,1,opt,name=cpu,proto3" json:"cpu,omitempty"`
 	// Memory usage stats
-	Memory               *MemoryUsage `protobuf:"bytes,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	Memory *MemoryUsage `protobuf:"bytes,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	// Block device I/O stats
+	DiskIo               *DiskIOUsage `protobuf:"bytes,3,opt,name=disk_io,json=diskIo,proto3" json:"disk_io,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
 	XXX_unrecognized     []byte       `json:"-"`
 	XXX_sizecache        int32        `json:"-"`
@@ -3330,6 +3383,13 @@ func (m *TaskResourceUsage) GetMemory() *MemoryUsage {
 	return nil
 }
 
+func (m *TaskResourceUsage) GetDiskIo() *DiskIOUsage {
+	if m != nil {
+		return m.DiskIo
+	}
+	return nil
+}
+
 type CPUUsage struct {
 	SystemMode       float64 `protobuf:"fixed64,1,opt,name=system_mode,json=systemMode,proto3" json:"system_mode,omitempty"`
 	UserMode         float64 `protobuf:"fixed64,2,opt,name=user_mode,json=userMode,proto3" json:"user_mode,omitempty"`
@@ -3514,6 +3574,87 @@ func (m *MemoryUsage) GetMeasuredFields() []MemoryUsage_Fields {
 	return nil
 }
 
+type DiskIOUsage struct {
+	ReadBytes  uint64 `protobuf:"varint,1,opt,name=read_bytes,json=readBytes,proto3" json:"read_bytes,omitempty"`
+	WriteBytes uint64 `protobuf:"varint,2,opt,name=write_bytes,json=writeBytes,proto3" json:"write_bytes,omitempty"`
+	ReadOps    uint64 `protobuf:"varint,3,opt,name=read_ops,json=readOps,proto3" json:"read_ops,omitempty"`
+	WriteOps   uint64 `protobuf:"varint,4,opt,name=write_ops,json=writeOps,proto3" json:"write_ops,omitempty"`
+	// Devices holds the stats of each block device, keyed by device name
+	Devices map[string]*DiskIOUsage `protobuf:"bytes,5,rep,name=devices,proto3" json:"devices,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// MeasuredFields indicates which fields were actually sampled
+	MeasuredFields       []DiskIOUsage_Fields `protobuf:"varint,6,rep,packed,name=measured_fields,json=measuredFields,proto3,enum=hashicorp.nomad.plugins.drivers.proto.DiskIOUsage_Fields" json:"measured_fields,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *DiskIOUsage) Reset()         { *m = DiskIOUsage{} }
+func (m *DiskIOUsage) String() string { return proto.CompactTextString(m) }
+func (*DiskIOUsage) ProtoMessage()    {}
+func (*DiskIOUsage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4a8f45747846a74d, []int{56}
+}
+
+func (m *DiskIOUsage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiskIOUsage.Unmarshal(m, b)
+}
+func (m *DiskIOUsage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiskIOUsage.Marshal(b, m, deterministic)
+}
+func (m *DiskIOUsage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiskIOUsage.Merge(m, src)
+}
+func (m *DiskIOUsage) XXX_Size() int {
+	return xxx_messageInfo_DiskIOUsage.Size(m)
+}
+func (m *DiskIOUsage) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiskIOUsage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiskIOUsage proto.InternalMessageInfo
+
+func (m *DiskIOUsage) GetReadBytes() uint64 {
+	if m != nil {
+		return m.ReadBytes
+	}
+	return 0
+}
+
+func (m *DiskIOUsage) GetWriteBytes() uint64 {
+	if m != nil {
+		return m.WriteBytes
+	}
+	return 0
+}
+
+func (m *DiskIOUsage) GetReadOps() uint64 {
+	if m != nil {
+		return m.ReadOps
+	}
+	return 0
+}
+
+func (m *DiskIOUsage) GetWriteOps() uint64 {
+	if m != nil {
+		return m.WriteOps
+	}
+	return 0
+}
+
+func (m *DiskIOUsage) GetDevices() map[string]*DiskIOUsage {
+	if m != nil {
+		return m.Devices
+	}
+	return nil
+}
+
+func (m *DiskIOUsage) GetMeasuredFields() []DiskIOUsage_Fields {
+	if m != nil {
+		return m.MeasuredFields
+	}
+	return nil
+}
+
 type DriverTaskEvent struct {
 	// TaskId is the id of the task for the event
 	TaskId string `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
@@ -3536,7 +3677,7 @@ func (m *DriverTaskEvent) Reset()         { *m = DriverTaskEvent{} }
 func (m *DriverTaskEvent) String() string { return proto.CompactTextString(m) }
 func (*DriverTaskEvent) ProtoMessage()    {}
 func (*DriverTaskEvent) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4a8f45747846a74d, []int{56}
+	return fileDescriptor_4a8f45747846a74d, []int{57}
 }
 
 func (m *DriverTaskEvent) XXX_Unmarshal(b []byte) error {
@@ -3608,6 +3749,7 @@ func init() {
 	proto.RegisterEnum("hashicorp.nomad.plugins.drivers.proto.NetworkIsolationSpec_NetworkIsolationMode", NetworkIsolationSpec_NetworkIsolationMode_name, NetworkIsolationSpec_NetworkIsolationMode_value)
 	proto.RegisterEnum("hashicorp.nomad.plugins.drivers.proto.CPUUsage_Fields", CPUUsage_Fields_name, CPUUsage_Fields_value)
 	proto.RegisterEnum("hashicorp.nomad.plugins.drivers.proto.MemoryUsage_Fields", MemoryUsage_Fields_name, MemoryUsage_Fields_value)
+	proto.RegisterEnum("hashicorp.nomad.plugins.drivers.proto.DiskIOUsage_Fields", DiskIOUsage_Fields_name, DiskIOUsage_Fields_value)
 	proto.RegisterType((*TaskConfigSchemaRequest)(nil), "hashicorp.nomad.plugins.drivers.proto.TaskConfigSchemaRequest")
 	proto.RegisterType((*TaskConfigSchemaResponse)(nil), "hashicorp.nomad.plugins.drivers.proto.TaskConfigSchemaResponse")
 	proto.RegisterType((*CapabilitiesRequest)(nil), "hashicorp.nomad.plugins.drivers.proto.CapabilitiesRequest")
@@ -3673,6 +3815,8 @@ func init() {
 	proto.RegisterType((*TaskResourceUsage)(nil), "hashicorp.nomad.plugins.drivers.proto.TaskResourceUsage")
 	proto.RegisterType((*CPUUsage)(nil), "hashicorp.nomad.plugins.drivers.proto.CPUUsage")
 	proto.RegisterType((*MemoryUsage)(nil), "hashicorp.nomad.plugins.drivers.proto.MemoryUsage")
+	proto.RegisterType((*DiskIOUsage)(nil), "hashicorp.nomad.plugins.drivers.proto.DiskIOUsage")
+	proto.RegisterMapType((map[string]*DiskIOUsage)(nil), "hashicorp.nomad.plugins.drivers.proto.DiskIOUsage.DevicesEntry")
 	proto.RegisterType((*DriverTaskEvent)(nil), "hashicorp.nomad.plugins.drivers.proto.DriverTaskEvent")
 	proto.RegisterMapType((map[string]string)(nil), "hashicorp.nomad.plugins.drivers.proto.DriverTaskEvent.AnnotationsEntry")
 }
@@ -3682,243 +3826,255 @@ func init() {
 }
 
 var fileDescriptor_4a8f45747846a74d = []byte{
-	// 3776 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x5a, 0xcd, 0x6f, 0x1b, 0x49,
-	0x76, 0x57, 0xb3, 0xf9, 0xf9, 0x48, 0x51, 0xad, 0xb2, 0x6c, 0xd3, 0x9c, 0x24, 0xe3, 0xed, 0x60,
-	0x02, 0x61, 0x77, 0x86, 0x9e, 0xd5, 0x22, 0xe3, 0xf1, 0xac, 0x67, 0x3d, 0x1c, 0x8a, 0xb6, 0x34,
-	0x96, 0x28, 0xa5, 0x48, 0xc1, 0xeb, 0x38, 0x3b, 0x9d, 0x16, 0xbb, 0x4c, 0xb5, 0xc5, 0xfe, 0x98,
-	0xae, 0xa6, 0x2c, 0x6d, 0x10, 0x24, 0xd8, 0x00, 0xc1, 0x06, 0x48, 0x90, 0x5c, 0x26, 0x7b, 0xc9,
-	0x69, 0x81, 0x9c, 0xf2, 0x0f, 0x04, 0x1b, 0xec, 0x29, 0x87, 0xfc, 0x13, 0xb9, 0xe4, 0x96, 0x63,
-	0x72, 0xca, 0x35, 0xa8, 0x8f, 0x6e, 0x76, 0x93, 0xf4, 0xb8, 0x49, 0xf9, 0xc4, 0x7e, 0xf5, 0xf1,
-	0xab, 0xc7, 0xf7, 0x5e, 0xbd, 0x7a, 0xf5, 0xea, 0x81, 0xee, 0x8f, 0x27, 0x23, 0xdb, 0xa5, 0xf7,
-	0xac, 0xc0, 0xbe, 0x20, 0x01, 0xbd, 0xe7, 0x07, 0x5e, 0xe8, 0x49, 0xaa, 0xc5, 0x09, 0xf4, 0xc1,
-	0x99, 0x49, 0xcf, 0xec, 0xa1, 0x17, 0xf8, 0x2d, 0xd7, 0x73, 0x4c, 0xab, 0x25, 0xe7, 0xb4, 0xe4,
-	0x1c, 0x31, 0xac, 0xf9, 0x7b, 0x23, 0xcf, 0x1b, 0x8d, 0x89, 0x40, 0x38, 0x9d, 0xbc, 0xbc, 0x67,
-	0x4d, 0x02, 0x33, 0xb4, 0x3d, 0x57, 0xf6, 0xbf, 0x3f, 0xdb, 0x1f, 0xda, 0x0e, 0xa1, 0xa1, 0xe9,
-	0xf8, 0x72, 0xc0, 0x07, 0x11, 0x2f, 0xf4, 0xcc, 0x0c, 0x88, 0x75, 0xef, 0x6c, 0x38, 0xa6, 0x3e,
-	0x19, 0xb2, 0x5f, 0x83, 0x7d, 0xc8, 0x61, 0x1f, 0xce, 0x0c, 0xa3, 0x61, 0x30, 0x19, 0x86, 0x11,
-	0xe7, 0x66, 0x18, 0x06, 0xf6, 0xe9, 0x24, 0x24, 0x62, 0xb4, 0x7e, 0x07, 0x6e, 0x0f, 0x4c, 0x7a,
-	0xde, 0xf1, 0xdc, 0x97, 0xf6, 0xa8, 0x3f, 0x3c, 0x23, 0x8e, 0x89, 0xc9, 0x37, 0x13, 0x42, 0x43,
-	0xfd, 0x4f, 0xa0, 0x31, 0xdf, 0x45, 0x7d, 0xcf, 0xa5, 0x04, 0x7d, 0x01, 0x79, 0xb6, 0x64, 0x43,
-	0xb9, 0xab, 0x6c, 0x57, 0x77, 0x3e, 0x6c, 0xbd, 0x49, 0x04, 0x82, 0x87, 0x96, 0x64, 0xb5, 0xd5,
-	0xf7, 0xc9, 0x10, 0xf3, 0x99, 0xfa, 0x4d, 0xb8, 0xd1, 0x31, 0x7d, 0xf3, 0xd4, 0x1e, 0xdb, 0xa1,
-	0x4d, 0x68, 0xb4, 0xe8, 0x04, 0xb6, 0xd2, 0xcd, 0x72, 0xc1, 0x9f, 0x41, 0x6d, 0x98, 0x68, 0x97,
-	0x0b, 0x3f, 0x68, 0x65, 0x92, 0x7d, 0x6b, 0x97, 0x53, 0x29, 0xe0, 0x14, 0x9c, 0xbe, 0x05, 0xe8,
-	0xb1, 0xed, 0x8e, 0x48, 0xe0, 0x07, 0xb6, 0x1b, 0x46, 0xcc, 0xfc, 0x56, 0x85, 0x1b, 0xa9, 0x66,
-	0xc9, 0xcc, 0x2b, 0x80, 0x58, 0x8e, 0x8c, 0x15, 0x75, 0xbb, 0xba, 0xf3, 0x55, 0x46, 0x56, 0x16,
-	0xe0, 0xb5, 0xda, 0x31, 0x58, 0xd7, 0x0d, 0x83, 0x2b, 0x9c, 0x40, 0x47, 0x5f, 0x43, 0xf1, 0x8c,
-	0x98, 0xe3, 0xf0, 0xac, 0x91, 0xbb, 0xab, 0x6c, 0xd7, 0x77, 0x1e, 0x5f, 0x63, 0x9d, 0x3d, 0x0e,
-	0xd4, 0x0f, 0xcd, 0x90, 0x60, 0x89, 0x8a, 0x3e, 0x02, 0x24, 0xbe, 0x0c, 0x8b, 0xd0, 0x61, 0x60,
-	0xfb, 0xcc, 0x24, 0x1b, 0xea, 0x5d, 0x65, 0xbb, 0x82, 0x37, 0x45, 0xcf, 0xee, 0xb4, 0xa3, 0xe9,
-	0xc3, 0xc6, 0x0c, 0xb7, 0x48, 0x03, 0xf5, 0x9c, 0x5c, 0x71, 0x8d, 0x54, 0x30, 0xfb, 0x44, 0x4f,
-	0xa0, 0x70, 0x61, 0x8e, 0x27, 0x84, 0xb3, 0x5c, 0xdd, 0xf9, 0xe1, 0xdb, 0xcc, 0x43, 0x9a, 0xe8,
-	0x54, 0x0e, 0x58, 0xcc, 0xff, 0x2c, 0xf7, 0xa9, 0xa2, 0x3f, 0x80, 0x6a, 0x82, 0x6f, 0x54, 0x07,
-	0x38, 0xe9, 0xed, 0x76, 0x07, 0xdd, 0xce, 0xa0, 0xbb, 0xab, 0xad, 0xa1, 0x75, 0xa8, 0x9c, 0xf4,
-	0xf6, 0xba, 0xed, 0x83, 0xc1, 0xde, 0x73, 0x4d, 0x41, 0x55, 0x28, 0x45, 0x44, 0x4e, 0xbf, 0x04,
-	0x84, 0xc9, 0xd0, 0xbb, 0x20, 0x01, 0x33, 0x64, 0xa9, 0x55, 0x74, 0x1b, 0x4a, 0xa1, 0x49, 0xcf,
-	0x0d, 0xdb, 0x92, 0x3c, 0x17, 0x19, 0xb9, 0x6f, 0xa1, 0x7d, 0x28, 0x9e, 0x99, 0xae, 0x35, 0x7e,
-	0x3b, 0xdf, 0x69, 0x51, 0x33, 0xf0, 0x3d, 0x3e, 0x11, 0x4b, 0x00, 0x66, 0xdd, 0xa9, 0x95, 0x85,
-	0x02, 0xf4, 0xe7, 0xa0, 0xf5, 0x43, 0x33, 0x08, 0x93, 0xec, 0x74, 0x21, 0xcf, 0xd6, 0x97, 0x16,
-	0xbd, 0xcc, 0x9a, 0x62, 0x67, 0x62, 0x3e, 0x5d, 0xff, 0xdf, 0x1c, 0x6c, 0x26, 0xb0, 0xa5, 0xa5,
-	0x3e, 0x83, 0x62, 0x40, 0xe8, 0x64, 0x1c, 0x72, 0xf8, 0xfa, 0xce, 0xa3, 0x8c, 0xf0, 0x73, 0x48,
-	0x2d, 0xcc, 0x61, 0xb0, 0x84, 0x43, 0xdb, 0xa0, 0x89, 0x19, 0x06, 0x09, 0x02, 0x2f, 0x30, 0x1c,
-	0x3a, 0xe2, 0x52, 0xab, 0xe0, 0xba, 0x68, 0xef, 0xb2, 0xe6, 0x43, 0x3a, 0x4a, 0x48, 0x55, 0xbd,
-	0xa6, 0x54, 0x91, 0x09, 0x9a, 0x4b, 0xc2, 0xd7, 0x5e, 0x70, 0x6e, 0x30, 0xd1, 0x06, 0xb6, 0x45,
-	0x1a, 0x79, 0x0e, 0xfa, 0x49, 0x46, 0xd0, 0x9e, 0x98, 0x7e, 0x24, 0x67, 0xe3, 0x0d, 0x37, 0xdd,
-	0xa0, 0xff, 0x00, 0x8a, 0xe2, 0x9f, 0x32, 0x4b, 0xea, 0x9f, 0x74, 0x3a, 0xdd, 0x7e, 0x5f, 0x5b,
-	0x43, 0x15, 0x28, 0xe0, 0xee, 0x00, 0x33, 0x0b, 0xab, 0x40, 0xe1, 0x71, 0x7b, 0xd0, 0x3e, 0xd0,
-	0x72, 0xfa, 0xf7, 0x61, 0xe3, 0x99, 0x69, 0x87, 0x59, 0x8c, 0x4b, 0xf7, 0x40, 0x9b, 0x8e, 0x95,
-	0xda, 0xd9, 0x4f, 0x69, 0x27, 0xbb, 0x68, 0xba, 0x97, 0x76, 0x38, 0xa3, 0x0f, 0x0d, 0x54, 0x12,
-	0x04, 0x52, 0x05, 0xec, 0x53, 0x7f, 0x0d, 0x1b, 0xfd, 0xd0, 0xf3, 0x33, 0x59, 0xfe, 0x8f, 0xa0,
-	0xc4, 0x4e, 0x1b, 0x6f, 0x12, 0x4a, 0xd3, 0xbf, 0xd3, 0x12, 0xa7, 0x51, 0x2b, 0x3a, 0x8d, 0x5a,
-	0xbb, 0xf2, 0xb4, 0xc2, 0xd1, 0x48, 0x74, 0x0b, 0x8a, 0xd4, 0x1e, 0xb9, 0xe6, 0x58, 0x7a, 0x0b,
-	0x49, 0xe9, 0x88, 0x19, 0x79, 0xb4, 0xb0, 0x34, 0xfc, 0x0e, 0xa0, 0x5d, 0x42, 0xc3, 0xc0, 0xbb,
-	0xca, 0xc4, 0xcf, 0x16, 0x14, 0x5e, 0x7a, 0xc1, 0x50, 0x6c, 0xc4, 0x32, 0x16, 0x04, 0xdb, 0x54,
-	0x29, 0x10, 0x89, 0xfd, 0x11, 0xa0, 0x7d, 0x97, 0x9d, 0x29, 0xd9, 0x14, 0xf1, 0x0f, 0x39, 0xb8,
-	0x91, 0x1a, 0x2f, 0x95, 0xb1, 0xfa, 0x3e, 0x64, 0x8e, 0x69, 0x42, 0xc5, 0x3e, 0x44, 0x47, 0x50,
-	0x14, 0x23, 0xa4, 0x24, 0xef, 0x2f, 0x01, 0x24, 0x8e, 0x29, 0x09, 0x27, 0x61, 0x16, 0x1a, 0xbd,
-	0xfa, 0x6e, 0x8d, 0xfe, 0x35, 0x68, 0xd1, 0xff, 0xa0, 0x6f, 0xd5, 0xcd, 0x57, 0x70, 0x63, 0xe8,
-	0x8d, 0xc7, 0x64, 0xc8, 0xac, 0xc1, 0xb0, 0xdd, 0x90, 0x04, 0x17, 0xe6, 0xf8, 0xed, 0x76, 0x83,
-	0xa6, 0xb3, 0xf6, 0xe5, 0x24, 0xfd, 0x05, 0x6c, 0x26, 0x16, 0x96, 0x8a, 0x78, 0x0c, 0x05, 0xca,
-	0x1a, 0xa4, 0x26, 0x3e, 0x5e, 0x52, 0x13, 0x14, 0x8b, 0xe9, 0xfa, 0x0d, 0x01, 0xde, 0xbd, 0x20,
-	0x6e, 0xfc, 0xb7, 0xf4, 0x5d, 0xd8, 0xec, 0x73, 0x33, 0xcd, 0x64, 0x87, 0x53, 0x13, 0xcf, 0xa5,
-	0x4c, 0x7c, 0x0b, 0x50, 0x12, 0x45, 0x1a, 0xe2, 0x15, 0x6c, 0x74, 0x2f, 0xc9, 0x30, 0x13, 0x72,
-	0x03, 0x4a, 0x43, 0xcf, 0x71, 0x4c, 0xd7, 0x6a, 0xe4, 0xee, 0xaa, 0xdb, 0x15, 0x1c, 0x91, 0xc9,
-	0xbd, 0xa8, 0x66, 0xdd, 0x8b, 0xfa, 0xdf, 0x29, 0xa0, 0x4d, 0xd7, 0x96, 0x82, 0x64, 0xdc, 0x87,
-	0x16, 0x03, 0x62, 0x6b, 0xd7, 0xb0, 0xa4, 0x64, 0x7b, 0xe4, 0x2e, 0x44, 0x3b, 0x09, 0x82, 0x84,
-	0x3b, 0x52, 0xaf, 0xe9, 0x8e, 0xf4, 0x3d, 0xf8, 0x9d, 0x88, 0x9d, 0x7e, 0x18, 0x10, 0xd3, 0xb1,
-	0xdd, 0xd1, 0xfe, 0xd1, 0x91, 0x4f, 0x04, 0xe3, 0x08, 0x41, 0xde, 0x32, 0x43, 0x53, 0x32, 0xc6,
-	0xbf, 0xd9, 0xa6, 0x1f, 0x8e, 0x3d, 0x1a, 0x6f, 0x7a, 0x4e, 0xe8, 0xff, 0xa1, 0x42, 0x63, 0x0e,
-	0x2a, 0x12, 0xef, 0x0b, 0x28, 0x50, 0x12, 0x4e, 0x7c, 0x69, 0x2a, 0xdd, 0xcc, 0x0c, 0x2f, 0xc6,
-	0x6b, 0xf5, 0x19, 0x18, 0x16, 0x98, 0x68, 0x04, 0xe5, 0x30, 0xbc, 0x32, 0xa8, 0xfd, 0xf3, 0x28,
-	0x20, 0x38, 0xb8, 0x2e, 0xfe, 0x80, 0x04, 0x8e, 0xed, 0x9a, 0xe3, 0xbe, 0xfd, 0x73, 0x82, 0x4b,
-	0x61, 0x78, 0xc5, 0x3e, 0xd0, 0x73, 0x66, 0xf0, 0x96, 0xed, 0x4a, 0xb1, 0x77, 0x56, 0x5d, 0x25,
-	0x21, 0x60, 0x2c, 0x10, 0x9b, 0x07, 0x50, 0xe0, 0xff, 0x69, 0x15, 0x43, 0xd4, 0x40, 0x0d, 0xc3,
-	0x2b, 0xce, 0x54, 0x19, 0xb3, 0xcf, 0xe6, 0x43, 0xa8, 0x25, 0xff, 0x01, 0x33, 0xa4, 0x33, 0x62,
-	0x8f, 0xce, 0x84, 0x81, 0x15, 0xb0, 0xa4, 0x98, 0x26, 0x5f, 0xdb, 0x96, 0x0c, 0x59, 0x0b, 0x58,
-	0x10, 0xfa, 0xbf, 0xe6, 0xe0, 0xce, 0x02, 0xc9, 0x48, 0x63, 0x7d, 0x91, 0x32, 0xd6, 0x77, 0x24,
-	0x85, 0xc8, 0xe2, 0x5f, 0xa4, 0x2c, 0xfe, 0x1d, 0x82, 0xb3, 0x6d, 0x73, 0x0b, 0x8a, 0xe4, 0xd2,
-	0x0e, 0x89, 0x25, 0x45, 0x25, 0xa9, 0xc4, 0x76, 0xca, 0x5f, 0x77, 0x3b, 0x1d, 0xc2, 0x56, 0x27,
-	0x20, 0x66, 0x48, 0xa4, 0x2b, 0x8f, 0xec, 0xff, 0x0e, 0x94, 0xcd, 0xf1, 0xd8, 0x1b, 0x4e, 0xd5,
-	0x5a, 0xe2, 0xf4, 0xbe, 0x85, 0x9a, 0x50, 0x3e, 0xf3, 0x68, 0xe8, 0x9a, 0x0e, 0x91, 0xce, 0x2b,
-	0xa6, 0xf5, 0x6f, 0x15, 0xb8, 0x39, 0x83, 0x27, 0xb5, 0x70, 0x0a, 0x75, 0x9b, 0x7a, 0x63, 0xfe,
-	0x07, 0x8d, 0xc4, 0x0d, 0xef, 0xc7, 0xcb, 0x1d, 0x35, 0xfb, 0x11, 0x06, 0xbf, 0xf0, 0xad, 0xdb,
-	0x49, 0x92, 0x5b, 0x1c, 0x5f, 0xdc, 0x92, 0x3b, 0x3d, 0x22, 0xf5, 0x7f, 0x54, 0xe0, 0xa6, 0x3c,
-	0xe1, 0xb3, 0xff, 0xd1, 0x79, 0x96, 0x73, 0xef, 0x9a, 0x65, 0xbd, 0x01, 0xb7, 0x66, 0xf9, 0x92,
-	0x3e, 0xff, 0xff, 0xf2, 0x80, 0xe6, 0x6f, 0x97, 0xe8, 0x7b, 0x50, 0xa3, 0xc4, 0xb5, 0x0c, 0x71,
-	0x5e, 0x88, 0xa3, 0xac, 0x8c, 0xab, 0xac, 0x4d, 0x1c, 0x1c, 0x94, 0xb9, 0x40, 0x72, 0x29, 0xb9,
-	0x2d, 0x63, 0xfe, 0x8d, 0xce, 0xa0, 0xf6, 0x92, 0x1a, 0xf1, 0xda, 0xdc, 0xa0, 0xea, 0x99, 0xdd,
-	0xda, 0x3c, 0x1f, 0xad, 0xc7, 0xfd, 0xf8, 0x7f, 0xe1, 0xea, 0x4b, 0x1a, 0x13, 0xe8, 0x97, 0x0a,
-	0xdc, 0x8e, 0xc2, 0x8a, 0xa9, 0xf8, 0x1c, 0xcf, 0x22, 0xb4, 0x91, 0xbf, 0xab, 0x6e, 0xd7, 0x77,
-	0x8e, 0xaf, 0x21, 0xbf, 0xb9, 0xc6, 0x43, 0xcf, 0x22, 0xf8, 0xa6, 0xbb, 0xa0, 0x95, 0xa2, 0x16,
-	0xdc, 0x70, 0x26, 0x34, 0x34, 0x84, 0x15, 0x18, 0x72, 0x50, 0xa3, 0xc0, 0xe5, 0xb2, 0xc9, 0xba,
-	0x52, 0xb6, 0x8a, 0xce, 0x61, 0xdd, 0xf1, 0x26, 0x6e, 0x68, 0x0c, 0xf9, 0xfd, 0x87, 0x36, 0x8a,
-	0x4b, 0x5d, 0x8c, 0x17, 0x48, 0xe9, 0x90, 0xc1, 0x89, 0xdb, 0x14, 0xc5, 0x35, 0x27, 0x41, 0x31,
-	0x45, 0x06, 0xc4, 0xf1, 0x42, 0x62, 0x30, 0x7f, 0x49, 0x1b, 0x25, 0xa1, 0x48, 0xd1, 0xc6, 0x5c,
-	0x03, 0xd5, 0x5b, 0x50, 0x4d, 0x88, 0x19, 0x95, 0x21, 0xdf, 0x3b, 0xea, 0x75, 0xb5, 0x35, 0x04,
-	0x50, 0xec, 0xec, 0xe1, 0xa3, 0xa3, 0x81, 0xb8, 0x35, 0xec, 0x1f, 0xb6, 0x9f, 0x74, 0xb5, 0x9c,
-	0xde, 0x85, 0x5a, 0x72, 0x41, 0x84, 0xa0, 0x7e, 0xd2, 0x7b, 0xda, 0x3b, 0x7a, 0xd6, 0x33, 0x0e,
-	0x8f, 0x4e, 0x7a, 0x03, 0x76, 0xdf, 0xa8, 0x03, 0xb4, 0x7b, 0xcf, 0xa7, 0xf4, 0x3a, 0x54, 0x7a,
-	0x47, 0x11, 0xa9, 0x34, 0x73, 0x9a, 0xa2, 0xff, 0xbb, 0x0a, 0x5b, 0x8b, 0x64, 0x8f, 0x2c, 0xc8,
-	0x33, 0x3d, 0xca, 0x1b, 0xdf, 0xbb, 0x57, 0x23, 0x47, 0x67, 0xe6, 0xeb, 0x9b, 0xd2, 0xc5, 0x57,
-	0x30, 0xff, 0x46, 0x06, 0x14, 0xc7, 0xe6, 0x29, 0x19, 0xd3, 0x86, 0xca, 0x73, 0x22, 0x4f, 0xae,
-	0xb3, 0xf6, 0x01, 0x47, 0x12, 0x09, 0x11, 0x09, 0x8b, 0x06, 0x50, 0x65, 0x4e, 0x8c, 0x0a, 0xd1,
-	0x49, 0xbf, 0xba, 0x93, 0x71, 0x95, 0xbd, 0xe9, 0x4c, 0x9c, 0x84, 0x69, 0x3e, 0x80, 0x6a, 0x62,
-	0xb1, 0x05, 0xf9, 0x8c, 0xad, 0x64, 0x3e, 0xa3, 0x92, 0x4c, 0x4e, 0x3c, 0x9a, 0xd7, 0x01, 0x93,
-	0x11, 0x33, 0x82, 0xbd, 0xa3, 0xfe, 0x40, 0xdc, 0x1c, 0x9f, 0xe0, 0xa3, 0x93, 0x63, 0x4d, 0x61,
-	0x8d, 0x83, 0x76, 0xff, 0xa9, 0x96, 0x8b, 0x6d, 0x44, 0xd5, 0x3b, 0x50, 0x4d, 0xf0, 0x95, 0xf2,
-	0xda, 0x4a, 0xda, 0x6b, 0x33, 0xbf, 0x69, 0x5a, 0x56, 0x40, 0x28, 0x95, 0x7c, 0x44, 0xa4, 0xfe,
-	0x02, 0x2a, 0xbb, 0xbd, 0xbe, 0x84, 0x68, 0x40, 0x89, 0x92, 0x80, 0xfd, 0x6f, 0x9e, 0x99, 0xaa,
-	0xe0, 0x88, 0x64, 0xe0, 0x94, 0x98, 0xc1, 0xf0, 0x8c, 0x50, 0x79, 0xd6, 0xc7, 0x34, 0x9b, 0xe5,
-	0xf1, 0x0c, 0x8f, 0xd0, 0x5d, 0x05, 0x47, 0xa4, 0xfe, 0x3f, 0x25, 0x80, 0x69, 0xb6, 0x01, 0xd5,
-	0x21, 0x17, 0xfb, 0xe0, 0x9c, 0x6d, 0x31, 0x3b, 0x48, 0x9c, 0x31, 0xfc, 0x1b, 0xed, 0xc0, 0x4d,
-	0x87, 0x8e, 0x7c, 0x73, 0x78, 0x6e, 0xc8, 0x24, 0x81, 0xd8, 0xaa, 0xdc, 0x9f, 0xd5, 0xf0, 0x0d,
-	0xd9, 0x29, 0x77, 0xa2, 0xc0, 0x3d, 0x00, 0x95, 0xb8, 0x17, 0xdc, 0xf7, 0x54, 0x77, 0x3e, 0x5b,
-	0x3a, 0x0b, 0xd2, 0xea, 0xba, 0x17, 0xc2, 0x56, 0x18, 0x0c, 0x32, 0x00, 0x2c, 0x72, 0x61, 0x0f,
-	0x89, 0xc1, 0x40, 0x0b, 0x1c, 0xf4, 0x8b, 0xe5, 0x41, 0x77, 0x39, 0x46, 0x0c, 0x5d, 0xb1, 0x22,
-	0x1a, 0xf5, 0xa0, 0x12, 0x10, 0xea, 0x4d, 0x82, 0x21, 0x11, 0x0e, 0x28, 0xfb, 0x45, 0x05, 0x47,
-	0xf3, 0xf0, 0x14, 0x02, 0xed, 0x42, 0x91, 0xfb, 0x1d, 0xe6, 0x61, 0xd4, 0xef, 0x4c, 0xa9, 0xa6,
-	0xc1, 0xb8, 0x27, 0xc1, 0x72, 0x2e, 0x7a, 0x02, 0x25, 0xc1, 0x22, 0x6d, 0x94, 0x39, 0xcc, 0x47,
-	0x59, 0x9d, 0x22, 0x9f, 0x85, 0xa3, 0xd9, 0x4c, 0xab, 0x13, 0x4a, 0x82, 0x46, 0x45, 0x68, 0x95,
-	0x7d, 0xa3, 0xf7, 0xa0, 0x22, 0xce, 0x60, 0xcb, 0x0e, 0x1a, 0x20, 0x8c, 0x93, 0x37, 0xec, 0xda,
-	0x01, 0x7a, 0x1f, 0xaa, 0x22, 0xd6, 0x32, 0xb8, 0x57, 0xa8, 0xf2, 0x6e, 0x10, 0x4d, 0xc7, 0xcc,
-	0x37, 0x88, 0x01, 0x24, 0x08, 0xc4, 0x80, 0x5a, 0x3c, 0x80, 0x04, 0x01, 0x1f, 0xf0, 0x07, 0xb0,
-	0xc1, 0x23, 0xd4, 0x51, 0xe0, 0x4d, 0x7c, 0x83, 0xdb, 0xd4, 0x3a, 0x1f, 0xb4, 0xce, 0x9a, 0x9f,
-	0xb0, 0xd6, 0x1e, 0x33, 0xae, 0x3b, 0x50, 0x7e, 0xe5, 0x9d, 0x8a, 0x01, 0x75, 0xb1, 0x0f, 0x5e,
-	0x79, 0xa7, 0x51, 0x57, 0x1c, 0x25, 0x6c, 0xa4, 0xa3, 0x84, 0x6f, 0xe0, 0xd6, 0xfc, 0x71, 0xc7,
-	0xa3, 0x05, 0xed, 0xfa, 0xd1, 0xc2, 0x96, 0xbb, 0xc8, 0x0f, 0x7f, 0x09, 0xaa, 0xe5, 0xd2, 0xc6,
-	0xe6, 0x52, 0xc6, 0x11, 0xef, 0x63, 0xcc, 0x26, 0x37, 0x3f, 0x81, 0x72, 0x64, 0x7d, 0xcb, 0xf8,
-	0xa5, 0xe6, 0x43, 0xa8, 0xa7, 0x6d, 0x77, 0x29, 0xaf, 0xf6, 0xcf, 0x39, 0xa8, 0xc4, 0x56, 0x8a,
-	0x5c, 0xb8, 0xc1, 0xa5, 0xc8, 0x42, 0x34, 0x63, 0x6a, 0xf4, 0x22, 0x30, 0xfc, 0x3c, 0xe3, 0xff,
-	0x6a, 0x47, 0x08, 0xf2, 0x86, 0x2a, 0x77, 0x00, 0x8a, 0x91, 0xa7, 0xeb, 0x7d, 0x0d, 0x1b, 0x63,
-	0xdb, 0x9d, 0x5c, 0x26, 0xd6, 0x12, 0x11, 0xdd, 0x1f, 0x66, 0x5c, 0xeb, 0x80, 0xcd, 0x9e, 0xae,
-	0x51, 0x1f, 0xa7, 0x68, 0xb4, 0x07, 0x05, 0xdf, 0x0b, 0xc2, 0xe8, 0x90, 0xca, 0x7a, 0x7c, 0x1c,
-	0x7b, 0x41, 0x78, 0x68, 0xfa, 0x3e, 0xbb, 0xb4, 0x08, 0x00, 0xfd, 0xdb, 0x1c, 0xdc, 0x5a, 0xfc,
-	0xc7, 0x50, 0x0f, 0xd4, 0xa1, 0x3f, 0x91, 0x42, 0x7a, 0xb8, 0xac, 0x90, 0x3a, 0xfe, 0x64, 0xca,
-	0x3f, 0x03, 0x42, 0xcf, 0xa0, 0xe8, 0x10, 0xc7, 0x0b, 0xae, 0xa4, 0x2c, 0x1e, 0x2d, 0x0b, 0x79,
-	0xc8, 0x67, 0x4f, 0x51, 0x25, 0x1c, 0xc2, 0x50, 0x96, 0xd6, 0x4b, 0xa5, 0x9f, 0x5c, 0x32, 0xad,
-	0x14, 0x41, 0xe2, 0x18, 0x47, 0xff, 0x04, 0x6e, 0x2e, 0xfc, 0x2b, 0xe8, 0x77, 0x01, 0x86, 0xfe,
-	0xc4, 0xe0, 0x69, 0x7f, 0x61, 0x41, 0x2a, 0xae, 0x0c, 0xfd, 0x49, 0x9f, 0x37, 0xe8, 0x2f, 0xa0,
-	0xf1, 0x26, 0x7e, 0x99, 0xf7, 0x11, 0x1c, 0x1b, 0xce, 0x29, 0x97, 0x81, 0x8a, 0xcb, 0xa2, 0xe1,
-	0xf0, 0x14, 0xe9, 0xb0, 0x1e, 0x75, 0x9a, 0x97, 0x6c, 0x80, 0xca, 0x07, 0x54, 0xe5, 0x00, 0xf3,
-	0xf2, 0xf0, 0x54, 0xff, 0x55, 0x0e, 0x36, 0x66, 0x58, 0x66, 0x57, 0x37, 0xe1, 0xf1, 0xa2, 0x4b,
-	0xb1, 0xa0, 0x98, 0xfb, 0x1b, 0xda, 0x56, 0x94, 0x4e, 0xe5, 0xdf, 0xfc, 0xe0, 0xf3, 0x65, 0xaa,
-	0x33, 0x67, 0xfb, 0x6c, 0xfb, 0x38, 0xa7, 0x76, 0x48, 0x79, 0x14, 0x52, 0xc0, 0x82, 0x40, 0xcf,
-	0xa1, 0x1e, 0x10, 0x7e, 0xe0, 0x5a, 0x86, 0xb0, 0xb2, 0xc2, 0x52, 0x56, 0x26, 0x39, 0x64, 0xc6,
-	0x86, 0xd7, 0x23, 0x24, 0x46, 0x51, 0xf4, 0x0c, 0xd6, 0xad, 0x2b, 0xd7, 0x74, 0xec, 0xa1, 0x44,
-	0x2e, 0xae, 0x8c, 0x5c, 0x93, 0x40, 0x1c, 0x58, 0x7f, 0x00, 0xd5, 0x44, 0x27, 0xfb, 0x63, 0x3c,
-	0xdc, 0x92, 0x32, 0x11, 0x44, 0xda, 0x5b, 0x14, 0xa4, 0xb7, 0xd0, 0x4f, 0xa1, 0x9a, 0xd8, 0x17,
-	0xcb, 0x4c, 0x65, 0xf2, 0x0c, 0x3d, 0x2e, 0xcf, 0x02, 0xce, 0x85, 0x1e, 0xba, 0x0d, 0x25, 0x16,
-	0xea, 0x18, 0xb6, 0xcf, 0x25, 0x5a, 0xc1, 0x45, 0x46, 0xee, 0xfb, 0xfa, 0x6f, 0x72, 0x50, 0x4f,
-	0x6f, 0xe9, 0xc8, 0x8e, 0x7c, 0x12, 0xd8, 0x9e, 0x95, 0xb0, 0xa3, 0x63, 0xde, 0xc0, 0x6c, 0x85,
-	0x75, 0x7f, 0x33, 0xf1, 0x42, 0x33, 0xb2, 0x95, 0xa1, 0x3f, 0xf9, 0x23, 0x46, 0xcf, 0xd8, 0xa0,
-	0x3a, 0x63, 0x83, 0xe8, 0x43, 0x40, 0xd2, 0x94, 0xc6, 0xb6, 0x63, 0x87, 0xc6, 0xe9, 0x55, 0x48,
-	0x84, 0x8e, 0x55, 0xac, 0x89, 0x9e, 0x03, 0xd6, 0xf1, 0x25, 0x6b, 0x67, 0x86, 0xe7, 0x79, 0x8e,
-	0x41, 0x87, 0x5e, 0x40, 0x0c, 0xd3, 0x7a, 0xc5, 0x6f, 0x2d, 0x2a, 0xae, 0x7a, 0x9e, 0xd3, 0x67,
-	0x6d, 0x6d, 0xeb, 0x15, 0x3b, 0xf9, 0x86, 0xfe, 0x84, 0x92, 0xd0, 0x60, 0x3f, 0x3c, 0x58, 0xa8,
-	0x60, 0x10, 0x4d, 0x1d, 0x7f, 0x42, 0xd1, 0xef, 0xc3, 0x7a, 0x34, 0x80, 0x1f, 0x7e, 0xf2, 0xd4,
-	0xad, 0xc9, 0x21, 0xbc, 0x0d, 0xe9, 0x50, 0x3b, 0x26, 0xc1, 0x90, 0xb8, 0xe1, 0xc0, 0x1e, 0x9e,
-	0xb3, 0xf3, 0x5d, 0xd9, 0x56, 0x70, 0xaa, 0xed, 0xab, 0x7c, 0xb9, 0xa4, 0x95, 0x71, 0xb4, 0x9a,
-	0x43, 0x1c, 0xaa, 0xff, 0x0c, 0x0a, 0x3c, 0x44, 0x60, 0x32, 0xe1, 0xc7, 0x2b, 0x3f, 0x7d, 0x65,
-	0x68, 0xc9, 0x1a, 0xf8, 0xd9, 0xfb, 0x1e, 0x54, 0xb8, 0xec, 0x13, 0x11, 0x3d, 0x8f, 0x3b, 0x79,
-	0x67, 0x13, 0xca, 0x01, 0x31, 0x2d, 0xcf, 0x1d, 0x47, 0xc9, 0xa0, 0x98, 0xd6, 0xbf, 0x81, 0xa2,
-	0x38, 0x67, 0xae, 0x81, 0xff, 0x11, 0x20, 0xf1, 0xbf, 0x99, 0x3e, 0x1d, 0x9b, 0x52, 0x19, 0x85,
-	0xf2, 0x17, 0x48, 0xd1, 0x73, 0x3c, 0xed, 0xd0, 0xff, 0x53, 0x11, 0xf1, 0xa8, 0x78, 0x1b, 0x62,
-	0x81, 0x2b, 0x33, 0x72, 0x76, 0x5b, 0x16, 0x49, 0xa8, 0x88, 0x44, 0xfb, 0x50, 0x94, 0x61, 0x67,
-	0x6e, 0xd5, 0xa7, 0x35, 0x09, 0x10, 0xa5, 0xa4, 0x89, 0xbc, 0x90, 0x2f, 0x9b, 0x92, 0x26, 0x22,
-	0x25, 0x4d, 0xd8, 0x6d, 0x52, 0x06, 0xc4, 0x02, 0x2e, 0xcf, 0xe3, 0xe1, 0xaa, 0x15, 0xe7, 0xfd,
-	0x89, 0xfe, 0xdf, 0x4a, 0xec, 0xa6, 0xa2, 0xfc, 0x3c, 0xfa, 0x1a, 0xca, 0x6c, 0xc7, 0x1b, 0x8e,
-	0xe9, 0xcb, 0xd7, 0xe6, 0xce, 0x6a, 0xa9, 0xff, 0xe8, 0x10, 0x13, 0xe1, 0x6c, 0xc9, 0x17, 0x14,
-	0x73, 0x77, 0xec, 0x2a, 0x11, 0xb9, 0x3b, 0xf6, 0x8d, 0x3e, 0x80, 0xba, 0x39, 0x09, 0x3d, 0xc3,
-	0xb4, 0x2e, 0x48, 0x10, 0xda, 0x94, 0x48, 0xdd, 0xaf, 0xb3, 0xd6, 0x76, 0xd4, 0xd8, 0xfc, 0x0c,
-	0x6a, 0x49, 0xcc, 0xb7, 0x85, 0x19, 0x85, 0x64, 0x98, 0xf1, 0xa7, 0x00, 0xd3, 0x5c, 0x17, 0xb3,
-	0x11, 0x72, 0x69, 0xb3, 0x5b, 0xbd, 0xbc, 0xbb, 0x16, 0x70, 0x99, 0x35, 0x74, 0xd8, 0x7d, 0x2a,
-	0x9d, 0x88, 0x2f, 0x44, 0x89, 0x78, 0xb6, 0x99, 0xd9, 0xfe, 0x3b, 0xb7, 0xc7, 0xe3, 0x38, 0xff,
-	0x56, 0xf1, 0x3c, 0xe7, 0x29, 0x6f, 0xd0, 0x7f, 0x9b, 0x13, 0xb6, 0x22, 0x9e, 0x54, 0x32, 0xdd,
-	0x5d, 0xde, 0x95, 0xaa, 0x1f, 0x00, 0xd0, 0xd0, 0x0c, 0x58, 0xcc, 0x64, 0x46, 0x19, 0xc0, 0xe6,
-	0x5c, 0x26, 0x7f, 0x10, 0xd5, 0x78, 0xe0, 0x8a, 0x1c, 0xdd, 0x0e, 0xd1, 0xe7, 0x50, 0x1b, 0x7a,
-	0x8e, 0x3f, 0x26, 0x72, 0x72, 0xe1, 0xad, 0x93, 0xab, 0xf1, 0xf8, 0x76, 0x98, 0xc8, 0x3b, 0x16,
-	0xaf, 0x9b, 0x77, 0xfc, 0x8d, 0x22, 0x5e, 0x86, 0x92, 0x0f, 0x53, 0x68, 0xb4, 0xa0, 0xfa, 0xe1,
-	0xc9, 0x8a, 0xaf, 0x5c, 0xdf, 0x55, 0xfa, 0xd0, 0xfc, 0x3c, 0x4b, 0xad, 0xc1, 0x9b, 0xa3, 0xd8,
-	0x7f, 0x53, 0xa1, 0x12, 0x3f, 0x0a, 0xcd, 0xe9, 0xfe, 0x53, 0xa8, 0xc4, 0x05, 0x36, 0xd2, 0x41,
-	0x7c, 0xa7, 0x7a, 0xe2, 0xc1, 0xe8, 0x25, 0x20, 0x73, 0x34, 0x8a, 0xa3, 0x53, 0x63, 0x42, 0xcd,
-	0x51, 0xf4, 0x24, 0xf7, 0xe9, 0x12, 0x72, 0x88, 0x8e, 0xb3, 0x13, 0x36, 0x1f, 0x6b, 0xe6, 0x68,
-	0x94, 0x6a, 0x41, 0x7f, 0x06, 0x37, 0xd3, 0x6b, 0x18, 0xa7, 0x57, 0x86, 0x6f, 0x5b, 0xf2, 0x8e,
-	0xbc, 0xb7, 0xec, 0xbb, 0x58, 0x2b, 0x05, 0xff, 0xe5, 0xd5, 0xb1, 0x6d, 0x09, 0x99, 0xa3, 0x60,
-	0xae, 0xa3, 0xf9, 0x17, 0x70, 0xfb, 0x0d, 0xc3, 0x17, 0xe8, 0xa0, 0x97, 0xae, 0xf7, 0x58, 0x5d,
-	0x08, 0x09, 0xed, 0xfd, 0x5a, 0x11, 0xcf, 0x77, 0x69, 0x99, 0xb4, 0x93, 0x61, 0xf5, 0xbd, 0x8c,
-	0xeb, 0x74, 0x8e, 0x4f, 0x04, 0x3c, 0x8f, 0xa4, 0xbf, 0x9a, 0x89, 0xa4, 0xb3, 0xc6, 0x4f, 0x22,
-	0x20, 0x15, 0x40, 0x12, 0x41, 0xff, 0x17, 0x15, 0xca, 0x11, 0x3a, 0xbf, 0xe1, 0x5e, 0xd1, 0x90,
-	0x38, 0x46, 0x9c, 0x7e, 0x53, 0x30, 0x88, 0x26, 0x9e, 0x14, 0x7a, 0x0f, 0x2a, 0xec, 0x22, 0x2d,
-	0xba, 0x73, 0xbc, 0xbb, 0xcc, 0x1a, 0x78, 0xe7, 0xfb, 0x50, 0x0d, 0xbd, 0xd0, 0x1c, 0x1b, 0x21,
-	0x3f, 0xde, 0x55, 0x31, 0x9b, 0x37, 0xf1, 0xc3, 0x1d, 0xfd, 0x00, 0x36, 0xc3, 0xb3, 0xc0, 0x0b,
-	0xc3, 0x31, 0x0b, 0x2d, 0x79, 0xa0, 0x23, 0xe2, 0x92, 0x3c, 0xd6, 0xe2, 0x0e, 0x11, 0x00, 0x51,
-	0xe6, 0xbd, 0xa7, 0x83, 0x99, 0xe9, 0x72, 0x27, 0x92, 0xc7, 0xeb, 0x71, 0x2b, 0x33, 0x6d, 0x76,
-	0x78, 0xfa, 0x22, 0x80, 0xe0, 0xbe, 0x42, 0xc1, 0x11, 0x89, 0x0c, 0xd8, 0x70, 0x88, 0x49, 0x27,
-	0x01, 0xb1, 0x8c, 0x97, 0x36, 0x19, 0x5b, 0x22, 0x31, 0x51, 0xcf, 0x7c, 0x3b, 0x88, 0xc4, 0xd2,
-	0x7a, 0xcc, 0x67, 0xe3, 0x7a, 0x04, 0x27, 0x68, 0x16, 0x39, 0x88, 0x2f, 0xb4, 0x01, 0xd5, 0xfe,
-	0xf3, 0xfe, 0xa0, 0x7b, 0x68, 0x1c, 0x1e, 0xed, 0x76, 0x65, 0x49, 0x4f, 0xbf, 0x8b, 0x05, 0xa9,
-	0xb0, 0xfe, 0xc1, 0xd1, 0xa0, 0x7d, 0x60, 0x0c, 0xf6, 0x3b, 0x4f, 0xfb, 0x5a, 0x0e, 0xdd, 0x84,
-	0xcd, 0xc1, 0x1e, 0x3e, 0x1a, 0x0c, 0x0e, 0xba, 0xbb, 0xc6, 0x71, 0x17, 0xef, 0x1f, 0xed, 0xf6,
-	0x35, 0x15, 0x21, 0xa8, 0x4f, 0x9b, 0x07, 0xfb, 0x87, 0x5d, 0x2d, 0x8f, 0xaa, 0x50, 0x3a, 0xee,
-	0xe2, 0x4e, 0xb7, 0x37, 0xd0, 0x0a, 0xfa, 0xaf, 0x54, 0xa8, 0x26, 0xb4, 0xc8, 0x0c, 0x39, 0xa0,
-	0xe2, 0x1a, 0x92, 0xc7, 0xec, 0x93, 0x3f, 0x41, 0x9a, 0xc3, 0x33, 0xa1, 0x9d, 0x3c, 0x16, 0x04,
-	0xbf, 0x7a, 0x98, 0x97, 0x89, 0x7d, 0x9e, 0xc7, 0x65, 0xc7, 0xbc, 0x14, 0x20, 0xdf, 0x83, 0xda,
-	0x39, 0x09, 0x5c, 0x32, 0x96, 0xfd, 0x42, 0x23, 0x55, 0xd1, 0x26, 0x86, 0x6c, 0x83, 0x26, 0x87,
-	0x4c, 0x61, 0x84, 0x3a, 0xea, 0xa2, 0xfd, 0x30, 0x02, 0xdb, 0x82, 0x82, 0xe8, 0x2e, 0x89, 0xf5,
-	0x39, 0xc1, 0x8e, 0x29, 0xfa, 0xda, 0xf4, 0x79, 0xc8, 0x97, 0xc7, 0xfc, 0x1b, 0x9d, 0xce, 0xeb,
-	0xa7, 0xc8, 0xf5, 0xf3, 0x60, 0x79, 0x73, 0x7e, 0x93, 0x8a, 0xce, 0x62, 0x15, 0x95, 0x40, 0xc5,
-	0x51, 0x1d, 0x4c, 0xa7, 0xdd, 0xd9, 0x63, 0x6a, 0x59, 0x87, 0xca, 0x61, 0xfb, 0xa7, 0xc6, 0x49,
-	0x9f, 0x67, 0xb5, 0x91, 0x06, 0xb5, 0xa7, 0x5d, 0xdc, 0xeb, 0x1e, 0xc8, 0x16, 0x15, 0x6d, 0x81,
-	0x26, 0x5b, 0xa6, 0xe3, 0xf2, 0x0c, 0x41, 0x7c, 0x16, 0x50, 0x19, 0xf2, 0xfd, 0x67, 0xed, 0x63,
-	0xad, 0xa8, 0xff, 0x57, 0x0e, 0x36, 0xc4, 0xb1, 0x10, 0xbf, 0xd8, 0xbf, 0xf9, 0xc5, 0x32, 0x99,
-	0xe5, 0xc9, 0xa5, 0xb3, 0x3c, 0x51, 0x10, 0xca, 0x4f, 0x75, 0x75, 0x1a, 0x84, 0xf2, 0xec, 0x50,
-	0xca, 0xe3, 0xe7, 0x97, 0xf1, 0xf8, 0x0d, 0x28, 0x39, 0x84, 0xc6, 0x7a, 0xab, 0xe0, 0x88, 0x44,
-	0x36, 0x54, 0x4d, 0xd7, 0xf5, 0x42, 0x53, 0xa4, 0x4e, 0x8b, 0x4b, 0x1d, 0x86, 0x33, 0xff, 0xb8,
-	0xd5, 0x9e, 0x22, 0x09, 0xc7, 0x9c, 0xc4, 0x6e, 0xfe, 0x04, 0xb4, 0xd9, 0x01, 0xcb, 0x1c, 0x87,
-	0xdf, 0xff, 0xe1, 0xf4, 0x34, 0x24, 0x6c, 0x5f, 0xc8, 0x37, 0x07, 0x6d, 0x8d, 0x11, 0xf8, 0xa4,
-	0xd7, 0xdb, 0xef, 0x3d, 0xd1, 0x14, 0x04, 0x50, 0xec, 0xfe, 0x74, 0x7f, 0xd0, 0xdd, 0xd5, 0x72,
-	0x3b, 0xbf, 0xde, 0x84, 0xa2, 0x60, 0x12, 0x7d, 0x2b, 0x23, 0x81, 0x64, 0x35, 0x28, 0xfa, 0xc9,
-	0xd2, 0x11, 0x75, 0xaa, 0xc2, 0xb4, 0xf9, 0x68, 0xe5, 0xf9, 0xf2, 0xf5, 0x6d, 0x0d, 0xfd, 0x8d,
-	0x02, 0xb5, 0xd4, 0xcb, 0x5b, 0xd6, 0xd4, 0xf1, 0x82, 0xe2, 0xd3, 0xe6, 0x8f, 0x57, 0x9a, 0x1b,
-	0xf3, 0xf2, 0x4b, 0x05, 0xaa, 0x89, 0xb2, 0x4b, 0xf4, 0x60, 0x95, 0x52, 0x4d, 0xc1, 0xc9, 0x67,
-	0xab, 0x57, 0x79, 0xea, 0x6b, 0x1f, 0x2b, 0xe8, 0xaf, 0x15, 0xa8, 0x26, 0x0a, 0x10, 0x33, 0xb3,
-	0x32, 0x5f, 0x2e, 0x99, 0x99, 0x95, 0x45, 0xf5, 0x8e, 0x6b, 0xe8, 0x2f, 0x15, 0xa8, 0xc4, 0xc5,
-	0x84, 0xe8, 0xfe, 0xf2, 0xe5, 0x87, 0x82, 0x89, 0x4f, 0x57, 0xad, 0x5b, 0xd4, 0xd7, 0xd0, 0x9f,
-	0x43, 0x39, 0xaa, 0xbc, 0x43, 0x59, 0x4f, 0xaf, 0x99, 0xb2, 0xbe, 0xe6, 0xfd, 0xa5, 0xe7, 0x25,
-	0x97, 0x8f, 0xca, 0xe1, 0x32, 0x2f, 0x3f, 0x53, 0xb8, 0xd7, 0xbc, 0xbf, 0xf4, 0xbc, 0x78, 0x79,
-	0x66, 0x09, 0x89, 0xaa, 0xb9, 0xcc, 0x96, 0x30, 0x5f, 0xae, 0x97, 0xd9, 0x12, 0x16, 0x15, 0xe9,
-	0x09, 0x46, 0x12, 0x75, 0x77, 0x99, 0x19, 0x99, 0xaf, 0xed, 0xcb, 0xcc, 0xc8, 0x82, 0x32, 0x3f,
-	0x7d, 0x0d, 0xfd, 0x42, 0x49, 0xde, 0x0b, 0xee, 0x2f, 0x5d, 0x5e, 0xb6, 0xa4, 0x49, 0xce, 0x15,
-	0xb8, 0xf1, 0x0d, 0xfa, 0x0b, 0x99, 0xc5, 0x10, 0xd5, 0x69, 0x68, 0x19, 0xb0, 0x54, 0x41, 0x5b,
-	0xf3, 0x93, 0xd5, 0x0e, 0x1b, 0xce, 0xc4, 0x5f, 0x29, 0x00, 0xd3, 0x3a, 0xb6, 0xcc, 0x4c, 0xcc,
-	0x15, 0xd0, 0x35, 0x1f, 0xac, 0x30, 0x33, 0xb9, 0x41, 0xa2, 0x3a, 0x9b, 0xcc, 0x1b, 0x64, 0xa6,
-	0xce, 0x2e, 0xf3, 0x06, 0x99, 0xad, 0x91, 0xd3, 0xd7, 0xd0, 0x3f, 0x29, 0xb0, 0x39, 0x57, 0xe7,
-	0x83, 0x1e, 0x5d, 0xb3, 0xd4, 0xab, 0xf9, 0xc5, 0xea, 0x00, 0x11, 0x6b, 0xdb, 0xca, 0xc7, 0x0a,
-	0xfa, 0x5b, 0x05, 0xd6, 0xd3, 0xf5, 0x0f, 0x99, 0x4f, 0xa9, 0x05, 0x15, 0x43, 0xcd, 0x87, 0xab,
-	0x4d, 0x8e, 0xa5, 0xf5, 0xf7, 0x0a, 0xd4, 0xd3, 0xa5, 0x30, 0xe8, 0xe1, 0x72, 0x6e, 0x61, 0x86,
-	0xa1, 0xcf, 0x57, 0x9c, 0x1d, 0x71, 0xf4, 0x65, 0xe9, 0x8f, 0x0b, 0x22, 0x7a, 0x2b, 0xf2, 0x9f,
-	0x1f, 0xfd, 0x7f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x76, 0x8b, 0xb6, 0x52, 0xb4, 0x33, 0x00, 0x00,
+	// 3958 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x5a, 0x41, 0x6f, 0x1b, 0x49,
+	0x76, 0x76, 0xb3, 0x49, 0x8a, 0x7c, 0xa4, 0x28, 0xaa, 0x2c, 0x7b, 0x68, 0x4e, 0x92, 0xf1, 0x76,
+	0x30, 0x81, 0xb1, 0x3b, 0x43, 0xcf, 0x6a, 0x91, 0xf1, 0xd8, 0xeb, 0x59, 0x0f, 0x4d, 0xd1, 0x96,
+	0xc6, 0x12, 0xa9, 0x14, 0x29, 0x78, 0x1d, 0x67, 0xa7, 0xd3, 0x62, 0x97, 0xa9, 0xb6, 0xc8, 0xee,
+	0x9e, 0xae, 0xa6, 0x2d, 0x4d, 0x10, 0x24, 0xd8, 0x00, 0xc1, 0x06, 0x48, 0x90, 0x5c, 0x26, 0x7b,
+	0xd9, 0x53, 0x80, 0x9c, 0xf2, 0x07, 0x82, 0x04, 0x7b, 0xca, 0x21, 0x7f, 0x22, 0x97, 0x00, 0x39,
+	0xe4, 0x96, 0xe4, 0x9e, 0xc3, 0xe2, 0x55, 0x55, 0x37, 0xbb, 0x45, 0x7a, 0x4c, 0x52, 0x3e, 0xb1,
+	0xdf, 0xab, 0xaa, 0xaf, 0x1e, 0xeb, 0xbd, 0x7a, 0xf5, 0xea, 0xd5, 0x03, 0xc3, 0x1f, 0x4d, 0x86,
+	0x8e, 0xcb, 0x6f, 0xdb, 0x81, 0xf3, 0x8a, 0x05, 0xfc, 0xb6, 0x1f, 0x78, 0xa1, 0xa7, 0xa8, 0x86,
+	0x20, 0xc8, 0x87, 0x27, 0x16, 0x3f, 0x71, 0x06, 0x5e, 0xe0, 0x37, 0x5c, 0x6f, 0x6c, 0xd9, 0x0d,
+	0x35, 0xa6, 0xa1, 0xc6, 0xc8, 0x6e, 0xf5, 0xdf, 0x19, 0x7a, 0xde, 0x70, 0xc4, 0x24, 0xc2, 0xf1,
+	0xe4, 0xc5, 0x6d, 0x7b, 0x12, 0x58, 0xa1, 0xe3, 0xb9, 0xaa, 0xfd, 0x83, 0x8b, 0xed, 0xa1, 0x33,
+	0x66, 0x3c, 0xb4, 0xc6, 0xbe, 0xea, 0xf0, 0x61, 0x24, 0x0b, 0x3f, 0xb1, 0x02, 0x66, 0xdf, 0x3e,
+	0x19, 0x8c, 0xb8, 0xcf, 0x06, 0xf8, 0x6b, 0xe2, 0x87, 0xea, 0xf6, 0xd1, 0x85, 0x6e, 0x3c, 0x0c,
+	0x26, 0x83, 0x30, 0x92, 0xdc, 0x0a, 0xc3, 0xc0, 0x39, 0x9e, 0x84, 0x4c, 0xf6, 0x36, 0x6e, 0xc0,
+	0x7b, 0x7d, 0x8b, 0x9f, 0xb6, 0x3c, 0xf7, 0x85, 0x33, 0xec, 0x0d, 0x4e, 0xd8, 0xd8, 0xa2, 0xec,
+	0xeb, 0x09, 0xe3, 0xa1, 0xf1, 0x47, 0x50, 0x9b, 0x6d, 0xe2, 0xbe, 0xe7, 0x72, 0x46, 0xbe, 0x80,
+	0x2c, 0x4e, 0x59, 0xd3, 0x6e, 0x6a, 0xb7, 0x4a, 0xdb, 0x1f, 0x35, 0xde, 0xb4, 0x04, 0x52, 0x86,
+	0x86, 0x12, 0xb5, 0xd1, 0xf3, 0xd9, 0x80, 0x8a, 0x91, 0xc6, 0x35, 0xb8, 0xda, 0xb2, 0x7c, 0xeb,
+	0xd8, 0x19, 0x39, 0xa1, 0xc3, 0x78, 0x34, 0xe9, 0x04, 0xb6, 0xd2, 0x6c, 0x35, 0xe1, 0xcf, 0xa0,
+	0x3c, 0x48, 0xf0, 0xd5, 0xc4, 0x77, 0x1b, 0x0b, 0xad, 0x7d, 0x63, 0x47, 0x50, 0x29, 0xe0, 0x14,
+	0x9c, 0xb1, 0x05, 0xe4, 0x91, 0xe3, 0x0e, 0x59, 0xe0, 0x07, 0x8e, 0x1b, 0x46, 0xc2, 0xfc, 0x5a,
+	0x87, 0xab, 0x29, 0xb6, 0x12, 0xe6, 0x25, 0x40, 0xbc, 0x8e, 0x28, 0x8a, 0x7e, 0xab, 0xb4, 0xfd,
+	0xe5, 0x82, 0xa2, 0xcc, 0xc1, 0x6b, 0x34, 0x63, 0xb0, 0xb6, 0x1b, 0x06, 0xe7, 0x34, 0x81, 0x4e,
+	0xbe, 0x82, 0xfc, 0x09, 0xb3, 0x46, 0xe1, 0x49, 0x2d, 0x73, 0x53, 0xbb, 0x55, 0xd9, 0x7e, 0x74,
+	0x89, 0x79, 0x76, 0x05, 0x50, 0x2f, 0xb4, 0x42, 0x46, 0x15, 0x2a, 0xf9, 0x18, 0x88, 0xfc, 0x32,
+	0x6d, 0xc6, 0x07, 0x81, 0xe3, 0xa3, 0x49, 0xd6, 0xf4, 0x9b, 0xda, 0xad, 0x22, 0xdd, 0x94, 0x2d,
+	0x3b, 0xd3, 0x86, 0xba, 0x0f, 0x1b, 0x17, 0xa4, 0x25, 0x55, 0xd0, 0x4f, 0xd9, 0xb9, 0xd0, 0x48,
+	0x91, 0xe2, 0x27, 0x79, 0x0c, 0xb9, 0x57, 0xd6, 0x68, 0xc2, 0x84, 0xc8, 0xa5, 0xed, 0x1f, 0xbe,
+	0xcd, 0x3c, 0x94, 0x89, 0x4e, 0xd7, 0x81, 0xca, 0xf1, 0xf7, 0x32, 0x9f, 0x69, 0xc6, 0x5d, 0x28,
+	0x25, 0xe4, 0x26, 0x15, 0x80, 0xa3, 0xce, 0x4e, 0xbb, 0xdf, 0x6e, 0xf5, 0xdb, 0x3b, 0xd5, 0x2b,
+	0x64, 0x1d, 0x8a, 0x47, 0x9d, 0xdd, 0x76, 0x73, 0xbf, 0xbf, 0xfb, 0xac, 0xaa, 0x91, 0x12, 0xac,
+	0x45, 0x44, 0xc6, 0x38, 0x03, 0x42, 0xd9, 0xc0, 0x7b, 0xc5, 0x02, 0x34, 0x64, 0xa5, 0x55, 0xf2,
+	0x1e, 0xac, 0x85, 0x16, 0x3f, 0x35, 0x1d, 0x5b, 0xc9, 0x9c, 0x47, 0x72, 0xcf, 0x26, 0x7b, 0x90,
+	0x3f, 0xb1, 0x5c, 0x7b, 0xf4, 0x76, 0xb9, 0xd3, 0x4b, 0x8d, 0xe0, 0xbb, 0x62, 0x20, 0x55, 0x00,
+	0x68, 0xdd, 0xa9, 0x99, 0xa5, 0x02, 0x8c, 0x67, 0x50, 0xed, 0x85, 0x56, 0x10, 0x26, 0xc5, 0x69,
+	0x43, 0x16, 0xe7, 0xaf, 0x69, 0x4b, 0xcf, 0x29, 0x77, 0x26, 0x15, 0xc3, 0x8d, 0xff, 0xcb, 0xc0,
+	0x66, 0x02, 0x5b, 0x59, 0xea, 0x53, 0xc8, 0x07, 0x8c, 0x4f, 0x46, 0xa1, 0x80, 0xaf, 0x6c, 0x3f,
+	0x58, 0x10, 0x7e, 0x06, 0xa9, 0x41, 0x05, 0x0c, 0x55, 0x70, 0xe4, 0x16, 0x54, 0xe5, 0x08, 0x93,
+	0x05, 0x81, 0x17, 0x98, 0x63, 0x3e, 0x14, 0xab, 0x56, 0xa4, 0x15, 0xc9, 0x6f, 0x23, 0xfb, 0x80,
+	0x0f, 0x13, 0xab, 0xaa, 0x5f, 0x72, 0x55, 0x89, 0x05, 0x55, 0x97, 0x85, 0xaf, 0xbd, 0xe0, 0xd4,
+	0xc4, 0xa5, 0x0d, 0x1c, 0x9b, 0xd5, 0xb2, 0x02, 0xf4, 0xd3, 0x05, 0x41, 0x3b, 0x72, 0x78, 0x57,
+	0x8d, 0xa6, 0x1b, 0x6e, 0x9a, 0x61, 0xfc, 0x00, 0xf2, 0xf2, 0x9f, 0xa2, 0x25, 0xf5, 0x8e, 0x5a,
+	0xad, 0x76, 0xaf, 0x57, 0xbd, 0x42, 0x8a, 0x90, 0xa3, 0xed, 0x3e, 0x45, 0x0b, 0x2b, 0x42, 0xee,
+	0x51, 0xb3, 0xdf, 0xdc, 0xaf, 0x66, 0x8c, 0xef, 0xc3, 0xc6, 0x53, 0xcb, 0x09, 0x17, 0x31, 0x2e,
+	0xc3, 0x83, 0xea, 0xb4, 0xaf, 0xd2, 0xce, 0x5e, 0x4a, 0x3b, 0x8b, 0x2f, 0x4d, 0xfb, 0xcc, 0x09,
+	0x2f, 0xe8, 0xa3, 0x0a, 0x3a, 0x0b, 0x02, 0xa5, 0x02, 0xfc, 0x34, 0x5e, 0xc3, 0x46, 0x2f, 0xf4,
+	0xfc, 0x85, 0x2c, 0xff, 0x47, 0xb0, 0x86, 0xa7, 0x8d, 0x37, 0x09, 0x95, 0xe9, 0xdf, 0x68, 0xc8,
+	0xd3, 0xa8, 0x11, 0x9d, 0x46, 0x8d, 0x1d, 0x75, 0x5a, 0xd1, 0xa8, 0x27, 0xb9, 0x0e, 0x79, 0xee,
+	0x0c, 0x5d, 0x6b, 0xa4, 0xbc, 0x85, 0xa2, 0x0c, 0x02, 0xd5, 0xe9, 0xc4, 0xca, 0xf0, 0x5b, 0x40,
+	0x76, 0x18, 0x0f, 0x03, 0xef, 0x7c, 0x21, 0x79, 0xb6, 0x20, 0xf7, 0xc2, 0x0b, 0x06, 0x72, 0x23,
+	0x16, 0xa8, 0x24, 0x70, 0x53, 0xa5, 0x40, 0x14, 0xf6, 0xc7, 0x40, 0xf6, 0x5c, 0x3c, 0x53, 0x16,
+	0x53, 0xc4, 0xdf, 0x65, 0xe0, 0x6a, 0xaa, 0xbf, 0x52, 0xc6, 0xea, 0xfb, 0x10, 0x1d, 0xd3, 0x84,
+	0xcb, 0x7d, 0x48, 0xba, 0x90, 0x97, 0x3d, 0xd4, 0x4a, 0xde, 0x59, 0x02, 0x48, 0x1e, 0x53, 0x0a,
+	0x4e, 0xc1, 0xcc, 0x35, 0x7a, 0xfd, 0xdd, 0x1a, 0xfd, 0x6b, 0xa8, 0x46, 0xff, 0x83, 0xbf, 0x55,
+	0x37, 0x5f, 0xc2, 0xd5, 0x81, 0x37, 0x1a, 0xb1, 0x01, 0x5a, 0x83, 0xe9, 0xb8, 0x21, 0x0b, 0x5e,
+	0x59, 0xa3, 0xb7, 0xdb, 0x0d, 0x99, 0x8e, 0xda, 0x53, 0x83, 0x8c, 0xe7, 0xb0, 0x99, 0x98, 0x58,
+	0x29, 0xe2, 0x11, 0xe4, 0x38, 0x32, 0x94, 0x26, 0x3e, 0x59, 0x52, 0x13, 0x9c, 0xca, 0xe1, 0xc6,
+	0x55, 0x09, 0xde, 0x7e, 0xc5, 0xdc, 0xf8, 0x6f, 0x19, 0x3b, 0xb0, 0xd9, 0x13, 0x66, 0xba, 0x90,
+	0x1d, 0x4e, 0x4d, 0x3c, 0x93, 0x32, 0xf1, 0x2d, 0x20, 0x49, 0x14, 0x65, 0x88, 0xe7, 0xb0, 0xd1,
+	0x3e, 0x63, 0x83, 0x85, 0x90, 0x6b, 0xb0, 0x36, 0xf0, 0xc6, 0x63, 0xcb, 0xb5, 0x6b, 0x99, 0x9b,
+	0xfa, 0xad, 0x22, 0x8d, 0xc8, 0xe4, 0x5e, 0xd4, 0x17, 0xdd, 0x8b, 0xc6, 0xdf, 0x68, 0x50, 0x9d,
+	0xce, 0xad, 0x16, 0x12, 0xa5, 0x0f, 0x6d, 0x04, 0xc2, 0xb9, 0xcb, 0x54, 0x51, 0x8a, 0x1f, 0xb9,
+	0x0b, 0xc9, 0x67, 0x41, 0x90, 0x70, 0x47, 0xfa, 0x25, 0xdd, 0x91, 0xb1, 0x0b, 0xbf, 0x15, 0x89,
+	0xd3, 0x0b, 0x03, 0x66, 0x8d, 0x1d, 0x77, 0xb8, 0xd7, 0xed, 0xfa, 0x4c, 0x0a, 0x4e, 0x08, 0x64,
+	0x6d, 0x2b, 0xb4, 0x94, 0x60, 0xe2, 0x1b, 0x37, 0xfd, 0x60, 0xe4, 0xf1, 0x78, 0xd3, 0x0b, 0xc2,
+	0xf8, 0x77, 0x1d, 0x6a, 0x33, 0x50, 0xd1, 0xf2, 0x3e, 0x87, 0x1c, 0x67, 0xe1, 0xc4, 0x57, 0xa6,
+	0xd2, 0x5e, 0x58, 0xe0, 0xf9, 0x78, 0x8d, 0x1e, 0x82, 0x51, 0x89, 0x49, 0x86, 0x50, 0x08, 0xc3,
+	0x73, 0x93, 0x3b, 0xdf, 0x44, 0x01, 0xc1, 0xfe, 0x65, 0xf1, 0xfb, 0x2c, 0x18, 0x3b, 0xae, 0x35,
+	0xea, 0x39, 0xdf, 0x30, 0xba, 0x16, 0x86, 0xe7, 0xf8, 0x41, 0x9e, 0xa1, 0xc1, 0xdb, 0x8e, 0xab,
+	0x96, 0xbd, 0xb5, 0xea, 0x2c, 0x89, 0x05, 0xa6, 0x12, 0xb1, 0xbe, 0x0f, 0x39, 0xf1, 0x9f, 0x56,
+	0x31, 0xc4, 0x2a, 0xe8, 0x61, 0x78, 0x2e, 0x84, 0x2a, 0x50, 0xfc, 0xac, 0xdf, 0x87, 0x72, 0xf2,
+	0x1f, 0xa0, 0x21, 0x9d, 0x30, 0x67, 0x78, 0x22, 0x0d, 0x2c, 0x47, 0x15, 0x85, 0x9a, 0x7c, 0xed,
+	0xd8, 0x2a, 0x64, 0xcd, 0x51, 0x49, 0x18, 0xff, 0x9c, 0x81, 0x1b, 0x73, 0x56, 0x46, 0x19, 0xeb,
+	0xf3, 0x94, 0xb1, 0xbe, 0xa3, 0x55, 0x88, 0x2c, 0xfe, 0x79, 0xca, 0xe2, 0xdf, 0x21, 0x38, 0x6e,
+	0x9b, 0xeb, 0x90, 0x67, 0x67, 0x4e, 0xc8, 0x6c, 0xb5, 0x54, 0x8a, 0x4a, 0x6c, 0xa7, 0xec, 0x65,
+	0xb7, 0xd3, 0x01, 0x6c, 0xb5, 0x02, 0x66, 0x85, 0x4c, 0xb9, 0xf2, 0xc8, 0xfe, 0x6f, 0x40, 0xc1,
+	0x1a, 0x8d, 0xbc, 0xc1, 0x54, 0xad, 0x6b, 0x82, 0xde, 0xb3, 0x49, 0x1d, 0x0a, 0x27, 0x1e, 0x0f,
+	0x5d, 0x6b, 0xcc, 0x94, 0xf3, 0x8a, 0x69, 0xe3, 0x5b, 0x0d, 0xae, 0x5d, 0xc0, 0x53, 0x5a, 0x38,
+	0x86, 0x8a, 0xc3, 0xbd, 0x91, 0xf8, 0x83, 0x66, 0xe2, 0x86, 0xf7, 0xe3, 0xe5, 0x8e, 0x9a, 0xbd,
+	0x08, 0x43, 0x5c, 0xf8, 0xd6, 0x9d, 0x24, 0x29, 0x2c, 0x4e, 0x4c, 0x6e, 0xab, 0x9d, 0x1e, 0x91,
+	0xc6, 0xdf, 0x6b, 0x70, 0x4d, 0x9d, 0xf0, 0x8b, 0xff, 0xd1, 0x59, 0x91, 0x33, 0xef, 0x5a, 0x64,
+	0xa3, 0x06, 0xd7, 0x2f, 0xca, 0xa5, 0x7c, 0xfe, 0xaf, 0x72, 0x40, 0x66, 0x6f, 0x97, 0xe4, 0x7b,
+	0x50, 0xe6, 0xcc, 0xb5, 0x4d, 0x79, 0x5e, 0xc8, 0xa3, 0xac, 0x40, 0x4b, 0xc8, 0x93, 0x07, 0x07,
+	0x47, 0x17, 0xc8, 0xce, 0x94, 0xb4, 0x05, 0x2a, 0xbe, 0xc9, 0x09, 0x94, 0x5f, 0x70, 0x33, 0x9e,
+	0x5b, 0x18, 0x54, 0x65, 0x61, 0xb7, 0x36, 0x2b, 0x47, 0xe3, 0x51, 0x2f, 0xfe, 0x5f, 0xb4, 0xf4,
+	0x82, 0xc7, 0x04, 0xf9, 0x85, 0x06, 0xef, 0x45, 0x61, 0xc5, 0x74, 0xf9, 0xc6, 0x9e, 0xcd, 0x78,
+	0x2d, 0x7b, 0x53, 0xbf, 0x55, 0xd9, 0x3e, 0xbc, 0xc4, 0xfa, 0xcd, 0x30, 0x0f, 0x3c, 0x9b, 0xd1,
+	0x6b, 0xee, 0x1c, 0x2e, 0x27, 0x0d, 0xb8, 0x3a, 0x9e, 0xf0, 0xd0, 0x94, 0x56, 0x60, 0xaa, 0x4e,
+	0xb5, 0x9c, 0x58, 0x97, 0x4d, 0x6c, 0x4a, 0xd9, 0x2a, 0x39, 0x85, 0xf5, 0xb1, 0x37, 0x71, 0x43,
+	0x73, 0x20, 0xee, 0x3f, 0xbc, 0x96, 0x5f, 0xea, 0x62, 0x3c, 0x67, 0x95, 0x0e, 0x10, 0x4e, 0xde,
+	0xa6, 0x38, 0x2d, 0x8f, 0x13, 0x14, 0x2a, 0x32, 0x60, 0x63, 0x2f, 0x64, 0x26, 0xfa, 0x4b, 0x5e,
+	0x5b, 0x93, 0x8a, 0x94, 0x3c, 0x74, 0x0d, 0x9c, 0xfc, 0x2e, 0xac, 0x0f, 0x86, 0x81, 0x37, 0xf1,
+	0xcd, 0x17, 0x01, 0x63, 0xdf, 0xb0, 0x5a, 0x41, 0xf4, 0x29, 0x4b, 0xe6, 0x23, 0xc1, 0x33, 0x1a,
+	0x50, 0x4a, 0xe8, 0x82, 0x14, 0x20, 0xdb, 0xe9, 0x76, 0xda, 0xd5, 0x2b, 0x04, 0x20, 0xdf, 0xda,
+	0xa5, 0xdd, 0x6e, 0x5f, 0x5e, 0x2d, 0xf6, 0x0e, 0x9a, 0x8f, 0xdb, 0xd5, 0x8c, 0xd1, 0x86, 0x72,
+	0x52, 0x2a, 0x42, 0xa0, 0x72, 0xd4, 0x79, 0xd2, 0xe9, 0x3e, 0xed, 0x98, 0x07, 0xdd, 0xa3, 0x4e,
+	0x1f, 0x2f, 0x25, 0x15, 0x80, 0x66, 0xe7, 0xd9, 0x94, 0x5e, 0x87, 0x62, 0xa7, 0x1b, 0x91, 0x5a,
+	0x3d, 0x53, 0xd5, 0x8c, 0x7f, 0xd3, 0x61, 0x6b, 0x9e, 0x82, 0x88, 0x0d, 0x59, 0x54, 0xb6, 0xba,
+	0x16, 0xbe, 0x7b, 0x5d, 0x0b, 0x74, 0xb4, 0x71, 0xdf, 0x52, 0xe7, 0x40, 0x91, 0x8a, 0x6f, 0x62,
+	0x42, 0x7e, 0x64, 0x1d, 0xb3, 0x11, 0xaf, 0xe9, 0x22, 0x71, 0xf2, 0xf8, 0x32, 0x73, 0xef, 0x0b,
+	0x24, 0x99, 0x35, 0x51, 0xb0, 0xa4, 0x0f, 0x25, 0xf4, 0x74, 0x5c, 0x2e, 0x9d, 0x72, 0xbe, 0xdb,
+	0x0b, 0xce, 0xb2, 0x3b, 0x1d, 0x49, 0x93, 0x30, 0xf5, 0xbb, 0x50, 0x4a, 0x4c, 0x36, 0x27, 0xe9,
+	0xb1, 0x95, 0x4c, 0x7a, 0x14, 0x93, 0x19, 0x8c, 0x07, 0xb0, 0x35, 0x6f, 0x8d, 0xd0, 0x08, 0x76,
+	0xbb, 0xbd, 0xbe, 0xbc, 0x5e, 0x3e, 0xa6, 0xdd, 0xa3, 0xc3, 0xaa, 0x86, 0xcc, 0x7e, 0xb3, 0xf7,
+	0xa4, 0x9a, 0x89, 0x6d, 0x44, 0x37, 0x5a, 0x50, 0x4a, 0xc8, 0x95, 0x72, 0xed, 0x5a, 0xda, 0xb5,
+	0xa3, 0x73, 0xb5, 0x6c, 0x3b, 0x60, 0x9c, 0x2b, 0x39, 0x22, 0xd2, 0x78, 0x0e, 0xc5, 0x9d, 0x4e,
+	0x4f, 0x41, 0xd4, 0x60, 0x8d, 0xb3, 0x00, 0xff, 0xb7, 0x48, 0x5f, 0x15, 0x69, 0x44, 0x22, 0x38,
+	0x67, 0x56, 0x30, 0x38, 0x61, 0x5c, 0x05, 0x04, 0x31, 0x8d, 0xa3, 0x3c, 0x91, 0x06, 0x92, 0xba,
+	0x2b, 0xd2, 0x88, 0x34, 0xfe, 0x77, 0x0d, 0x60, 0x9a, 0x92, 0x20, 0x15, 0xc8, 0xc4, 0x8e, 0x3a,
+	0xe3, 0xd8, 0x68, 0x07, 0x89, 0x83, 0x48, 0x7c, 0x93, 0x6d, 0xb8, 0x36, 0xe6, 0x43, 0xdf, 0x1a,
+	0x9c, 0x9a, 0x2a, 0x93, 0x20, 0xf7, 0xb3, 0x70, 0x7a, 0x65, 0x7a, 0x55, 0x35, 0xaa, 0xed, 0x2a,
+	0x71, 0xf7, 0x41, 0x67, 0xee, 0x2b, 0xe1, 0xa0, 0x4a, 0xdb, 0xf7, 0x96, 0x4e, 0x95, 0x34, 0xda,
+	0xee, 0x2b, 0x69, 0x2b, 0x08, 0x43, 0x4c, 0x00, 0x9b, 0xbd, 0x72, 0x06, 0xcc, 0x44, 0xd0, 0x9c,
+	0x00, 0xfd, 0x62, 0x79, 0xd0, 0x1d, 0x81, 0x11, 0x43, 0x17, 0xed, 0x88, 0x26, 0x1d, 0x28, 0x06,
+	0x8c, 0x7b, 0x93, 0x60, 0xc0, 0xa4, 0x97, 0x5a, 0xfc, 0x36, 0x43, 0xa3, 0x71, 0x74, 0x0a, 0x41,
+	0x76, 0x20, 0x2f, 0x9c, 0x13, 0xba, 0x21, 0xfd, 0x3b, 0xf3, 0xae, 0x69, 0x30, 0xe1, 0x49, 0xa8,
+	0x1a, 0x4b, 0x1e, 0xc3, 0x9a, 0x14, 0x91, 0xd7, 0x0a, 0x02, 0xe6, 0xe3, 0x45, 0x3d, 0xa7, 0x18,
+	0x45, 0xa3, 0xd1, 0xa8, 0xd5, 0x09, 0x67, 0x41, 0xad, 0x28, 0xb5, 0x8a, 0xdf, 0xe4, 0x7d, 0x28,
+	0xca, 0x83, 0xda, 0x76, 0x82, 0x1a, 0x48, 0xe3, 0x14, 0x8c, 0x1d, 0x27, 0x20, 0x1f, 0x40, 0x49,
+	0x06, 0x64, 0xa6, 0xf0, 0x0a, 0x25, 0xd1, 0x0c, 0x92, 0x75, 0x88, 0xbe, 0x41, 0x76, 0x60, 0x41,
+	0x20, 0x3b, 0x94, 0xe3, 0x0e, 0x2c, 0x08, 0x44, 0x87, 0xdf, 0x83, 0x0d, 0x11, 0xc6, 0x4a, 0x7f,
+	0x2b, 0x6c, 0x6a, 0x5d, 0x74, 0x5a, 0x47, 0xf6, 0x63, 0xe4, 0x76, 0xd0, 0xb8, 0x6e, 0x40, 0xe1,
+	0xa5, 0x77, 0x2c, 0x3b, 0x54, 0xe4, 0x3e, 0x78, 0xe9, 0x1d, 0x47, 0x4d, 0x71, 0x28, 0xb1, 0x91,
+	0x0e, 0x25, 0xbe, 0x86, 0xeb, 0xb3, 0x67, 0xa2, 0x08, 0x29, 0xaa, 0x97, 0x0f, 0x29, 0xb6, 0xdc,
+	0x39, 0x5c, 0xf2, 0x10, 0x74, 0xdb, 0xe5, 0xb5, 0xcd, 0xa5, 0x8c, 0x23, 0xde, 0xc7, 0x14, 0x07,
+	0xd7, 0x3f, 0x85, 0x42, 0x64, 0x7d, 0xcb, 0xf8, 0xa5, 0xfa, 0x7d, 0xa8, 0xa4, 0x6d, 0x77, 0x29,
+	0xaf, 0xf6, 0x8f, 0x19, 0x28, 0xc6, 0x56, 0x4a, 0x5c, 0xb8, 0x2a, 0x56, 0xd1, 0x0a, 0x99, 0x6d,
+	0x4e, 0x8d, 0x5e, 0x46, 0x8f, 0x9f, 0x2f, 0xf8, 0xbf, 0x9a, 0x11, 0x82, 0xba, 0xc6, 0xaa, 0x1d,
+	0x40, 0x62, 0xe4, 0xe9, 0x7c, 0x5f, 0xc1, 0xc6, 0xc8, 0x71, 0x27, 0x67, 0x89, 0xb9, 0x64, 0xd8,
+	0xf7, 0xfb, 0x0b, 0xce, 0xb5, 0x8f, 0xa3, 0xa7, 0x73, 0x54, 0x46, 0x29, 0x9a, 0xec, 0x42, 0xce,
+	0xf7, 0x82, 0x30, 0x3a, 0xa4, 0x16, 0x3d, 0x3e, 0x0e, 0xbd, 0x20, 0x3c, 0xb0, 0x7c, 0x1f, 0x6f,
+	0x36, 0x12, 0xc0, 0xf8, 0x36, 0x03, 0xd7, 0xe7, 0xff, 0x31, 0xd2, 0x01, 0x7d, 0xe0, 0x4f, 0xd4,
+	0x22, 0xdd, 0x5f, 0x76, 0x91, 0x5a, 0xfe, 0x64, 0x2a, 0x3f, 0x02, 0x61, 0xb6, 0x77, 0xcc, 0xc6,
+	0x5e, 0x70, 0xae, 0xd6, 0xe2, 0xc1, 0xb2, 0x90, 0x07, 0x62, 0xf4, 0x14, 0x55, 0xc1, 0x11, 0x0a,
+	0x05, 0x65, 0xbd, 0x5c, 0xf9, 0xc9, 0x25, 0x73, 0x4f, 0x11, 0x24, 0x8d, 0x71, 0x8c, 0x4f, 0xe1,
+	0xda, 0xdc, 0xbf, 0x42, 0x7e, 0x1b, 0x60, 0xe0, 0x4f, 0x4c, 0xf1, 0x36, 0x20, 0x2d, 0x48, 0xa7,
+	0xc5, 0x81, 0x3f, 0xe9, 0x09, 0x86, 0xf1, 0x1c, 0x6a, 0x6f, 0x92, 0x17, 0xbd, 0x8f, 0x94, 0xd8,
+	0x1c, 0x1f, 0x8b, 0x35, 0xd0, 0x69, 0x41, 0x32, 0x0e, 0x8e, 0x89, 0x01, 0xeb, 0x51, 0xa3, 0x75,
+	0x86, 0x1d, 0x74, 0xd1, 0xa1, 0xa4, 0x3a, 0x58, 0x67, 0x07, 0xc7, 0xc6, 0x2f, 0x33, 0xb0, 0x71,
+	0x41, 0x64, 0xbc, 0xdf, 0x49, 0x8f, 0x17, 0xdd, 0x9c, 0x25, 0x85, 0xee, 0x6f, 0xe0, 0xd8, 0x51,
+	0xce, 0x55, 0x7c, 0x8b, 0x83, 0xcf, 0x57, 0xf9, 0xd0, 0x8c, 0xe3, 0xe3, 0xf6, 0x19, 0x1f, 0x3b,
+	0x21, 0x17, 0x51, 0x48, 0x8e, 0x4a, 0x82, 0x3c, 0x83, 0x4a, 0xc0, 0xc4, 0x81, 0x6b, 0x9b, 0xd2,
+	0xca, 0x72, 0x4b, 0x59, 0x99, 0x92, 0x10, 0x8d, 0x8d, 0xae, 0x47, 0x48, 0x48, 0x71, 0xf2, 0x14,
+	0xd6, 0xed, 0x73, 0xd7, 0x1a, 0x3b, 0x03, 0x85, 0x9c, 0x5f, 0x19, 0xb9, 0xac, 0x80, 0x04, 0x30,
+	0x3e, 0xc3, 0x24, 0x1a, 0xf1, 0x8f, 0x89, 0x70, 0x4b, 0xad, 0x89, 0x24, 0xd2, 0xde, 0x22, 0xa7,
+	0xbc, 0x85, 0x71, 0x0c, 0xa5, 0xc4, 0xbe, 0x58, 0x66, 0x28, 0xae, 0x67, 0xe8, 0x89, 0xf5, 0xcc,
+	0xd1, 0x4c, 0xe8, 0x61, 0x1a, 0x03, 0x43, 0x1d, 0xd3, 0xf1, 0xc5, 0x8a, 0x16, 0x69, 0x1e, 0xc9,
+	0x3d, 0xdf, 0xf8, 0xaf, 0x0c, 0x54, 0xd2, 0x5b, 0x3a, 0xb2, 0x23, 0x9f, 0x05, 0x8e, 0x67, 0x27,
+	0xec, 0xe8, 0x50, 0x30, 0xd0, 0x56, 0xb0, 0xf9, 0xeb, 0x89, 0x17, 0x5a, 0x91, 0xad, 0x0c, 0xfc,
+	0xc9, 0x1f, 0x20, 0x7d, 0xc1, 0x06, 0xf5, 0x0b, 0x36, 0x48, 0x3e, 0x02, 0xa2, 0x4c, 0x69, 0xe4,
+	0x8c, 0x9d, 0xd0, 0x3c, 0x3e, 0x0f, 0x99, 0xd4, 0xb1, 0x4e, 0xab, 0xb2, 0x65, 0x1f, 0x1b, 0x1e,
+	0x22, 0x1f, 0x0d, 0xcf, 0xf3, 0xc6, 0x26, 0x1f, 0x78, 0x01, 0x33, 0x2d, 0xfb, 0xa5, 0xb8, 0xda,
+	0xe8, 0xb4, 0xe4, 0x79, 0xe3, 0x1e, 0xf2, 0x9a, 0xf6, 0x4b, 0x3c, 0xf9, 0x06, 0xfe, 0x84, 0xb3,
+	0xd0, 0xc4, 0x1f, 0x11, 0x2c, 0x14, 0x29, 0x48, 0x56, 0xcb, 0x9f, 0xc8, 0x5b, 0x86, 0xea, 0x20,
+	0x0e, 0x3f, 0x75, 0xea, 0x96, 0x55, 0x17, 0xc1, 0x23, 0x06, 0x94, 0x0f, 0x59, 0x30, 0x60, 0x6e,
+	0xd8, 0x77, 0x06, 0xa7, 0x5c, 0xdc, 0x44, 0x34, 0x9a, 0xe2, 0xe1, 0xcb, 0x8d, 0x10, 0x24, 0x29,
+	0x39, 0x08, 0x81, 0x2a, 0xc8, 0x9f, 0xca, 0xfd, 0x65, 0xb6, 0xb0, 0x56, 0x2d, 0xd0, 0x48, 0xae,
+	0x31, 0x1b, 0x73, 0xe3, 0x67, 0x90, 0x13, 0xc1, 0x04, 0xae, 0x9e, 0x38, 0x88, 0xc5, 0x39, 0xad,
+	0x82, 0x50, 0x64, 0x88, 0x53, 0xfa, 0x7d, 0x28, 0x0a, 0x2d, 0x25, 0x62, 0x7f, 0x11, 0xa1, 0x8a,
+	0xc6, 0x3a, 0x14, 0x02, 0x66, 0xd9, 0x9e, 0x3b, 0x8a, 0x72, 0x4b, 0x31, 0x6d, 0x7c, 0x0d, 0x79,
+	0x79, 0x22, 0x5d, 0x02, 0xff, 0x63, 0x20, 0xea, 0x3a, 0xe6, 0x63, 0xae, 0x8a, 0x73, 0x15, 0xaf,
+	0x8a, 0x07, 0x4d, 0xd9, 0x72, 0x38, 0x6d, 0x30, 0xfe, 0x43, 0x03, 0x98, 0x3e, 0x35, 0x61, 0x88,
+	0x8b, 0xdb, 0x01, 0x2f, 0xdf, 0x32, 0xa7, 0x15, 0x91, 0x98, 0xce, 0x51, 0x01, 0x6a, 0x66, 0xd5,
+	0x97, 0x3a, 0x05, 0x10, 0x65, 0xb8, 0x99, 0xba, 0xdf, 0x2f, 0x9b, 0xe1, 0x66, 0x32, 0xc3, 0xcd,
+	0xf0, 0x72, 0xaa, 0x42, 0x67, 0x09, 0x97, 0x15, 0x91, 0x73, 0xc9, 0x8e, 0x9f, 0x11, 0x98, 0xf1,
+	0xdf, 0x5a, 0xec, 0xd0, 0xa2, 0x74, 0x3f, 0xf9, 0x0a, 0x0a, 0xe8, 0x1b, 0xcc, 0xb1, 0xe5, 0xab,
+	0xc7, 0xeb, 0xd6, 0x6a, 0x2f, 0x09, 0xd1, 0x71, 0x27, 0x03, 0xdf, 0x35, 0x5f, 0x52, 0xe8, 0x18,
+	0xf1, 0xd2, 0x11, 0x39, 0x46, 0xfc, 0x26, 0x1f, 0x42, 0xc5, 0x9a, 0x84, 0x9e, 0x69, 0xd9, 0xaf,
+	0x58, 0x10, 0x3a, 0x9c, 0x29, 0xdd, 0xaf, 0x23, 0xb7, 0x19, 0x31, 0xeb, 0xf7, 0xa0, 0x9c, 0xc4,
+	0x7c, 0x5b, 0x40, 0x92, 0x4b, 0x06, 0x24, 0x7f, 0x0c, 0x30, 0x4d, 0x9d, 0xa1, 0x8d, 0x60, 0x1e,
+	0xce, 0x1c, 0x44, 0xb7, 0xdc, 0x1c, 0x2d, 0x20, 0xa3, 0x85, 0x37, 0xaf, 0x74, 0x5e, 0x3f, 0x17,
+	0xe5, 0xf5, 0x71, 0xdb, 0xe3, 0x4e, 0x3d, 0x75, 0x46, 0xa3, 0x38, 0x9d, 0x57, 0xf4, 0xbc, 0xf1,
+	0x13, 0xc1, 0x30, 0x7e, 0x9d, 0x91, 0xb6, 0x22, 0x5f, 0x68, 0x16, 0xba, 0xe5, 0xbc, 0x2b, 0x55,
+	0xdf, 0x05, 0xe0, 0xa1, 0x15, 0x60, 0x74, 0x65, 0x45, 0x09, 0xc5, 0xfa, 0xcc, 0xc3, 0x40, 0x3f,
+	0x2a, 0x19, 0xa1, 0x45, 0xd5, 0xbb, 0x19, 0x92, 0xcf, 0xa1, 0x3c, 0xf0, 0xc6, 0xfe, 0x88, 0xa9,
+	0xc1, 0xb9, 0xb7, 0x0e, 0x2e, 0xc5, 0xfd, 0x9b, 0x61, 0x22, 0x8d, 0x99, 0xbf, 0x6c, 0x1a, 0xf3,
+	0x5f, 0x34, 0xf9, 0xd0, 0x94, 0x7c, 0xe7, 0x22, 0xc3, 0x39, 0xc5, 0x14, 0x8f, 0x57, 0x7c, 0x34,
+	0xfb, 0xae, 0x4a, 0x8a, 0xfa, 0xe7, 0x8b, 0x94, 0x2e, 0xbc, 0x39, 0xde, 0xfd, 0x57, 0x1d, 0x8a,
+	0x91, 0x5a, 0x66, 0x75, 0xff, 0x19, 0x14, 0xe3, 0x7a, 0x9d, 0x5a, 0xe6, 0xad, 0x2b, 0x3c, 0xed,
+	0x4c, 0x5e, 0x00, 0xb1, 0x86, 0xc3, 0x38, 0x8e, 0x35, 0x27, 0xdc, 0x1a, 0x46, 0x2f, 0x7c, 0x9f,
+	0x2d, 0xb1, 0x0e, 0xd1, 0xc1, 0x77, 0x84, 0xe3, 0x69, 0xd5, 0x1a, 0x0e, 0x53, 0x1c, 0xf2, 0x27,
+	0x70, 0x2d, 0x3d, 0x87, 0x79, 0x7c, 0x6e, 0xfa, 0x8e, 0xad, 0x6e, 0xd3, 0xbb, 0xcb, 0x3e, 0xb3,
+	0x35, 0x52, 0xf0, 0x0f, 0xcf, 0x0f, 0x1d, 0x5b, 0xae, 0x39, 0x09, 0x66, 0x1a, 0xea, 0x7f, 0x06,
+	0xef, 0xbd, 0xa1, 0xfb, 0x1c, 0x1d, 0x74, 0xd2, 0xe5, 0x23, 0xab, 0x2f, 0x42, 0x42, 0x7b, 0xff,
+	0xa3, 0xc1, 0xe6, 0x4c, 0x07, 0xd2, 0x4c, 0x06, 0xe0, 0xb7, 0x17, 0x9c, 0xa7, 0x75, 0x78, 0x24,
+	0xe1, 0x71, 0x2c, 0xf9, 0xf2, 0x42, 0xcc, 0xbd, 0x68, 0xa4, 0x25, 0x43, 0x57, 0x09, 0x14, 0x85,
+	0xd9, 0x4f, 0x60, 0xcd, 0x76, 0xf0, 0x91, 0xc6, 0xab, 0xe9, 0x4b, 0x81, 0xed, 0x38, 0xfc, 0x74,
+	0xaf, 0xab, 0xc0, 0x10, 0x62, 0xcf, 0x33, 0xfe, 0x49, 0x87, 0x42, 0x24, 0xaa, 0xb8, 0x58, 0x9f,
+	0xf3, 0x90, 0x8d, 0xcd, 0x38, 0xeb, 0xa7, 0x51, 0x90, 0x2c, 0x91, 0x8b, 0x7a, 0x1f, 0x8a, 0x78,
+	0x7f, 0x97, 0xcd, 0x19, 0xd1, 0x5c, 0x40, 0x86, 0x68, 0xfc, 0x00, 0x4a, 0xa1, 0x17, 0x5a, 0x23,
+	0x33, 0x14, 0x51, 0x85, 0x2e, 0x47, 0x0b, 0x96, 0x8c, 0x29, 0x7e, 0x00, 0x9b, 0xe1, 0x49, 0xe0,
+	0x85, 0xe1, 0x08, 0x23, 0x5a, 0x11, 0x5f, 0xc9, 0x70, 0x28, 0x4b, 0xab, 0x71, 0x83, 0x8c, 0xbb,
+	0x38, 0x1e, 0x05, 0xd3, 0xce, 0xb8, 0x0f, 0x84, 0x47, 0xca, 0xd2, 0xf5, 0x98, 0x8b, 0xfb, 0x04,
+	0x4f, 0x62, 0x5f, 0xc6, 0x2d, 0xc2, 0xf1, 0x68, 0x34, 0x22, 0x89, 0x09, 0x1b, 0x63, 0x66, 0xf1,
+	0x49, 0xc0, 0x6c, 0xf3, 0x85, 0xc3, 0x46, 0xb6, 0xcc, 0x87, 0x54, 0x16, 0xbe, 0x94, 0x44, 0xcb,
+	0xd2, 0x78, 0x24, 0x46, 0xd3, 0x4a, 0x04, 0x27, 0x69, 0x0c, 0x43, 0xe4, 0x17, 0xd9, 0x80, 0x52,
+	0xef, 0x59, 0xaf, 0xdf, 0x3e, 0x30, 0x0f, 0xba, 0x3b, 0x6d, 0x55, 0x6e, 0xd4, 0x6b, 0x53, 0x49,
+	0x6a, 0xd8, 0xde, 0xef, 0xf6, 0x9b, 0xfb, 0x66, 0x7f, 0xaf, 0xf5, 0xa4, 0x57, 0xcd, 0x90, 0x6b,
+	0xb0, 0xd9, 0xdf, 0xa5, 0xdd, 0x7e, 0x7f, 0xbf, 0xbd, 0x63, 0x1e, 0xb6, 0xe9, 0x5e, 0x77, 0xa7,
+	0x57, 0xd5, 0x31, 0x7d, 0x3b, 0x65, 0xf7, 0xf7, 0x0e, 0xda, 0xd5, 0x2c, 0x16, 0x98, 0x1c, 0xb6,
+	0x69, 0xab, 0xdd, 0xe9, 0x57, 0x73, 0xc6, 0x2f, 0x75, 0x28, 0x25, 0x4c, 0x02, 0x77, 0x45, 0xc0,
+	0xe5, 0xed, 0x27, 0x4b, 0xf1, 0x53, 0x3c, 0x8f, 0x5a, 0x83, 0x13, 0xa9, 0x9d, 0x2c, 0x95, 0x84,
+	0xb8, 0xf1, 0x58, 0x67, 0x09, 0xa7, 0x91, 0xa5, 0x85, 0xb1, 0x75, 0x26, 0x41, 0xbe, 0x07, 0xe5,
+	0x53, 0x16, 0xb8, 0x6c, 0xa4, 0xda, 0xa5, 0x46, 0x4a, 0x92, 0x27, 0xbb, 0xdc, 0x82, 0xaa, 0xea,
+	0x32, 0x85, 0x91, 0xea, 0xa8, 0x48, 0xfe, 0x41, 0x04, 0xb6, 0x05, 0x39, 0xd9, 0xbc, 0x26, 0xe7,
+	0x17, 0x04, 0x9e, 0x79, 0xfc, 0xb5, 0xe5, 0x8b, 0x48, 0x33, 0x4b, 0xc5, 0x37, 0x39, 0x9e, 0xd5,
+	0x4f, 0x5e, 0xe8, 0xe7, 0xee, 0xf2, 0x7b, 0xe3, 0x4d, 0x2a, 0x3a, 0x89, 0x55, 0xb4, 0x06, 0x3a,
+	0x8d, 0x6a, 0x74, 0x5a, 0xcd, 0xd6, 0x2e, 0xaa, 0x65, 0x1d, 0x8a, 0x07, 0xcd, 0x9f, 0x9a, 0x47,
+	0x3d, 0x91, 0x4c, 0x27, 0x55, 0x28, 0x3f, 0x69, 0xd3, 0x4e, 0x7b, 0x5f, 0x71, 0x74, 0xb2, 0x05,
+	0x55, 0xc5, 0x99, 0xf6, 0xcb, 0x22, 0x82, 0xfc, 0xcc, 0x61, 0xf2, 0xb5, 0xf7, 0xb4, 0x79, 0x58,
+	0xcd, 0x1b, 0xff, 0xaf, 0x43, 0x29, 0xb1, 0xbf, 0x30, 0x46, 0x08, 0x98, 0x65, 0xab, 0xc8, 0x59,
+	0x2a, 0xa8, 0x88, 0x1c, 0x19, 0xec, 0x7f, 0x00, 0xa5, 0xd7, 0x81, 0x13, 0x32, 0xd5, 0x2e, 0x95,
+	0x05, 0x82, 0x25, 0x3b, 0xdc, 0x90, 0xf1, 0xaf, 0xe9, 0xf9, 0x5c, 0x29, 0x6c, 0x0d, 0xe9, 0xae,
+	0x2f, 0xae, 0xaf, 0x72, 0x2c, 0xb6, 0x49, 0x65, 0x15, 0x04, 0x03, 0x1b, 0x9f, 0x4d, 0xd3, 0x76,
+	0xf2, 0xb6, 0xf8, 0x60, 0x79, 0xe7, 0xa0, 0x52, 0x78, 0xea, 0x70, 0x8c, 0xf0, 0x2e, 0xaf, 0xb0,
+	0xe4, 0x14, 0xf3, 0x15, 0x56, 0x77, 0xa1, 0x9c, 0x9c, 0x7c, 0x8e, 0xdb, 0xdf, 0x4d, 0xbb, 0xfd,
+	0x55, 0x7c, 0x5f, 0xc2, 0xe1, 0x3f, 0x8a, 0x0d, 0xa4, 0x02, 0x40, 0xdb, 0xcd, 0x1d, 0xf3, 0xe1,
+	0xb3, 0x7e, 0x1b, 0xed, 0x64, 0x03, 0x4a, 0x4f, 0xe9, 0x5e, 0xbf, 0xad, 0x18, 0x1a, 0x29, 0x43,
+	0x41, 0x74, 0xe8, 0x1e, 0xe2, 0x0e, 0x5e, 0x87, 0xa2, 0x6c, 0x46, 0x52, 0x37, 0xfe, 0x33, 0x03,
+	0x1b, 0x32, 0xc4, 0x88, 0x8b, 0x49, 0xde, 0xfc, 0x98, 0x9e, 0xcc, 0x2d, 0x66, 0xd2, 0xb9, 0xc5,
+	0xe8, 0x42, 0x23, 0x22, 0x44, 0x7d, 0x7a, 0xa1, 0x11, 0x39, 0xc9, 0x54, 0xf4, 0x90, 0x5d, 0x26,
+	0x7a, 0xa8, 0xc1, 0xda, 0x98, 0xf1, 0x78, 0xdb, 0x16, 0x69, 0x44, 0x12, 0x07, 0x4a, 0x96, 0xeb,
+	0x7a, 0xa1, 0x25, 0x13, 0xf6, 0xf9, 0xa5, 0x02, 0xab, 0x0b, 0xff, 0xb8, 0xd1, 0x9c, 0x22, 0x49,
+	0xdb, 0x49, 0x62, 0xd7, 0x7f, 0x02, 0xd5, 0x8b, 0x1d, 0x96, 0x09, 0xad, 0xbe, 0xff, 0xc3, 0x69,
+	0x64, 0xc5, 0xd0, 0x2d, 0xaa, 0x97, 0xae, 0xea, 0x15, 0x24, 0xe8, 0x51, 0xa7, 0xb3, 0xd7, 0x79,
+	0x5c, 0xd5, 0xf0, 0xa9, 0xac, 0xfd, 0xd3, 0x3d, 0x2c, 0xfb, 0xcc, 0x6c, 0xff, 0xc3, 0x26, 0xe4,
+	0xa5, 0x90, 0xe4, 0x5b, 0x15, 0x55, 0x26, 0x0b, 0x95, 0xc9, 0x4f, 0x96, 0xbe, 0x9d, 0xa5, 0x8a,
+	0x9f, 0xeb, 0x0f, 0x56, 0x1e, 0xaf, 0x1e, 0x86, 0xaf, 0x90, 0xbf, 0xd2, 0xa0, 0x9c, 0x7a, 0x14,
+	0x5e, 0xf4, 0xc1, 0x62, 0x4e, 0x5d, 0x74, 0xfd, 0xc7, 0x2b, 0x8d, 0x8d, 0x65, 0xf9, 0x85, 0x06,
+	0xa5, 0x44, 0x45, 0x30, 0xb9, 0xbb, 0x4a, 0x15, 0xb1, 0x94, 0xe4, 0xde, 0xea, 0x05, 0xc8, 0xc6,
+	0x95, 0x4f, 0x34, 0xf2, 0x97, 0x1a, 0x94, 0x12, 0xb5, 0xb1, 0x0b, 0x8b, 0x32, 0x5b, 0xc9, 0x5b,
+	0xbf, 0xb7, 0xca, 0xd0, 0x78, 0x4d, 0xfe, 0x5c, 0x83, 0x62, 0x5c, 0xe7, 0x4a, 0xee, 0x2c, 0x5f,
+	0x19, 0x2b, 0x85, 0xf8, 0x6c, 0xd5, 0x92, 0x5a, 0xe3, 0x0a, 0xf9, 0x53, 0x28, 0x44, 0x45, 0xa1,
+	0x64, 0xd1, 0xe0, 0xe5, 0x42, 0xc5, 0x69, 0xfd, 0xce, 0xd2, 0xe3, 0x92, 0xd3, 0x47, 0x95, 0x9a,
+	0x0b, 0x4f, 0x7f, 0xa1, 0xa6, 0xb4, 0x7e, 0x67, 0xe9, 0x71, 0xf1, 0xf4, 0x68, 0x09, 0x89, 0x82,
+	0xce, 0x85, 0x2d, 0x61, 0xb6, 0x92, 0xb4, 0x7e, 0x6f, 0x95, 0xa1, 0x29, 0x41, 0x12, 0x25, 0xa1,
+	0x0b, 0x0b, 0x32, 0x5b, 0x76, 0x5a, 0xbf, 0xb7, 0xca, 0xd0, 0x58, 0x90, 0x9f, 0x6b, 0xc9, 0x3b,
+	0xe6, 0x9d, 0xa5, 0x2b, 0x1f, 0x97, 0x34, 0xc9, 0x99, 0xda, 0x4b, 0xb1, 0x41, 0x7f, 0xae, 0x32,
+	0x62, 0xb2, 0x70, 0x92, 0x2c, 0x03, 0x96, 0xaa, 0xb5, 0xac, 0x7f, 0xba, 0xda, 0x61, 0x23, 0x84,
+	0xf8, 0x0b, 0x0d, 0x60, 0x5a, 0x62, 0xb9, 0xb0, 0x10, 0x33, 0xb5, 0x9d, 0xf5, 0xbb, 0x2b, 0x8c,
+	0x4c, 0x6e, 0x90, 0xa8, 0x04, 0x6c, 0xe1, 0x0d, 0x72, 0xa1, 0x04, 0xb4, 0x7e, 0x67, 0xe9, 0x71,
+	0xf1, 0xf4, 0xbf, 0xd2, 0x60, 0x73, 0xa6, 0x04, 0x8d, 0x3c, 0xb8, 0x64, 0x15, 0x62, 0xfd, 0x8b,
+	0xd5, 0x01, 0x22, 0xd1, 0x6e, 0x69, 0x9f, 0x68, 0xe4, 0xaf, 0x35, 0x58, 0x4f, 0x97, 0xe6, 0x2c,
+	0x7c, 0x4a, 0xcd, 0x29, 0x66, 0xab, 0xdf, 0x5f, 0x6d, 0x70, 0xbc, 0x5a, 0x7f, 0xab, 0x41, 0x45,
+	0xed, 0xef, 0x48, 0x9e, 0xfb, 0xcb, 0xb9, 0x85, 0x0b, 0x02, 0x7d, 0xbe, 0xe2, 0xe8, 0x48, 0xa2,
+	0x87, 0x6b, 0x7f, 0x98, 0x93, 0xd1, 0x5b, 0x5e, 0xfc, 0xfc, 0xe8, 0x37, 0x03, 0x00, 0xd3, 0x71,
+	0xa0, 0x1e, 0x4f, 0x36, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -44,6 +44,7 @@ func (b *driverPluginServer) Capabilities(ctx context.Context, req *proto.Capabi
 			MustCreateNetwork:     caps.MustInitiateNetwork,
 			NetworkIsolationModes: []proto.NetworkIsolationSpec_NetworkIsolationMode{},
 			RemoteTasks:           caps.RemoteTasks,
+			CgroupFreeze:          caps.CgroupFreeze,
 		},
 	}
 
@@ -105,6 +106,9 @@ func (b *driverPluginServer) RecoverTask(ctx context.Context, req *proto.Recover
 func (b *driverPluginServer) StartTask(ctx context.Context, req *proto.StartTaskRequest) (*proto.StartTaskResponse, error) {
 	handle, net, err := b.impl.StartTask(taskConfigFromProto(req.Task))
 	if err != nil {
+		if IsTaskConfigError(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		if rec, ok := err.(structs.Recoverable); ok {
 			st := status.New(codes.FailedPrecondition, rec.Error())
 			st, err := st.WithDetails(&sproto.RecoverableError{Recoverable: rec.IsRecoverable()})
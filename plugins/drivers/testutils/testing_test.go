@@ -3,6 +3,7 @@ package testutils
 import (
 	"bytes"
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -32,6 +33,30 @@ func TestDriverHarness(t *testing.T) {
 	require.Equal(t, handle.Config.Name, actual.Config.Name)
 }
 
+// TestDriverHarness_TaskConfigError asserts task config errors are still
+// identified after crossing the plugin boundary.
+func TestDriverHarness_TaskConfigError(t *testing.T) {
+	d := &MockDriver{
+		StartTaskF: func(task *drivers.TaskConfig) (*drivers.TaskHandle, *drivers.DriverNetwork, error) {
+			if task.Name == "bad" {
+				return nil, nil, drivers.NewTaskConfigError(errors.New("image name required"))
+			}
+			return nil, nil, errors.New("daemon unavailable")
+		},
+	}
+	harness := NewDriverHarness(t, d)
+	defer harness.Kill()
+
+	_, _, err := harness.StartTask(&drivers.TaskConfig{Name: "bad"})
+	require.Error(t, err)
+	require.True(t, drivers.IsTaskConfigError(err))
+	require.Contains(t, err.Error(), "image name required")
+
+	_, _, err = harness.StartTask(&drivers.TaskConfig{Name: "good"})
+	require.Error(t, err)
+	require.False(t, drivers.IsTaskConfigError(err))
+}
+
 type testDriverState struct {
 	Pid int
 	Log string
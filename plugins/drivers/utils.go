@@ -145,6 +145,7 @@ func ResourcesFromProto(pb *proto.Resources) *Resources {
 			CpusetCpus:       pb.LinuxResources.CpusetCpus,
 			CpusetCgroupPath: pb.LinuxResources.CpusetCgroup,
 			PercentTicks:     pb.LinuxResources.PercentTicks,
+			CoreLimitBytes:   pb.LinuxResources.CoreLimitBytes,
 		}
 	}
 
@@ -215,6 +216,7 @@ func ResourcesToProto(r *Resources) *proto.Resources {
 			CpusetCpus:       r.LinuxResources.CpusetCpus,
 			CpusetCgroup:     r.LinuxResources.CpusetCgroupPath,
 			PercentTicks:     r.LinuxResources.PercentTicks,
+			CoreLimitBytes:   r.LinuxResources.CoreLimitBytes,
 		}
 	}
 
@@ -478,6 +480,30 @@ func resourceUsageToProto(ru *ResourceUsage) *proto.TaskResourceUsage {
 	return &proto.TaskResourceUsage{
 		Cpu:    cpu,
 		Memory: memory,
+		DiskIo: diskIOUsageToProto(ru.DiskIOStats),
+	}
+}
+
+func diskIOUsageToProto(ds *DiskIOStats) *proto.DiskIOUsage {
+	if ds == nil {
+		return nil
+	}
+
+	var devices map[string]*proto.DiskIOUsage
+	if len(ds.Devices) > 0 {
+		devices = make(map[string]*proto.DiskIOUsage, len(ds.Devices))
+		for name, dev := range ds.Devices {
+			devices[name] = diskIOUsageToProto(dev)
+		}
+	}
+
+	return &proto.DiskIOUsage{
+		MeasuredFields: diskIOUsageMeasuredFieldsToProto(ds.Measured),
+		ReadBytes:      ds.ReadBytes,
+		WriteBytes:     ds.WriteBytes,
+		ReadOps:        ds.ReadOps,
+		WriteOps:       ds.WriteOps,
+		Devices:        devices,
 	}
 }
 
@@ -512,6 +538,30 @@ func resourceUsageFromProto(pb *proto.TaskResourceUsage) *ResourceUsage {
 	return &ResourceUsage{
 		CpuStats:    &cpu,
 		MemoryStats: &memory,
+		DiskIOStats: diskIOUsageFromProto(pb.DiskIo),
+	}
+}
+
+func diskIOUsageFromProto(pb *proto.DiskIOUsage) *DiskIOStats {
+	if pb == nil {
+		return nil
+	}
+
+	var devices map[string]*DiskIOStats
+	if len(pb.Devices) > 0 {
+		devices = make(map[string]*DiskIOStats, len(pb.Devices))
+		for name, dev := range pb.Devices {
+			devices[name] = diskIOUsageFromProto(dev)
+		}
+	}
+
+	return &DiskIOStats{
+		Measured:   diskIOUsageMeasuredFieldsFromProto(pb.MeasuredFields),
+		ReadBytes:  pb.ReadBytes,
+		WriteBytes: pb.WriteBytes,
+		ReadOps:    pb.ReadOps,
+		WriteOps:   pb.WriteOps,
+		Devices:    devices,
 	}
 }
 
@@ -605,6 +655,44 @@ func memoryUsageMeasuredFieldsFromProto(fields []proto.MemoryUsage_Fields) []str
 	return r
 }
 
+var diskIOUsageMeasuredFieldToProtoMap = map[string]proto.DiskIOUsage_Fields{
+	"Read Bytes":  proto.DiskIOUsage_READ_BYTES,
+	"Write Bytes": proto.DiskIOUsage_WRITE_BYTES,
+	"Read Ops":    proto.DiskIOUsage_READ_OPS,
+	"Write Ops":   proto.DiskIOUsage_WRITE_OPS,
+}
+
+var diskIOUsageMeasuredFieldFromProtoMap = map[proto.DiskIOUsage_Fields]string{
+	proto.DiskIOUsage_READ_BYTES:  "Read Bytes",
+	proto.DiskIOUsage_WRITE_BYTES: "Write Bytes",
+	proto.DiskIOUsage_READ_OPS:    "Read Ops",
+	proto.DiskIOUsage_WRITE_OPS:   "Write Ops",
+}
+
+func diskIOUsageMeasuredFieldsToProto(fields []string) []proto.DiskIOUsage_Fields {
+	r := make([]proto.DiskIOUsage_Fields, 0, len(fields))
+
+	for _, f := range fields {
+		if v, ok := diskIOUsageMeasuredFieldToProtoMap[f]; ok {
+			r = append(r, v)
+		}
+	}
+
+	return r
+}
+
+func diskIOUsageMeasuredFieldsFromProto(fields []proto.DiskIOUsage_Fields) []string {
+	r := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		if v, ok := diskIOUsageMeasuredFieldFromProtoMap[f]; ok {
+			r = append(r, v)
+		}
+	}
+
+	return r
+}
+
 func netIsolationModeToProto(mode NetIsolationMode) proto.NetworkIsolationSpec_NetworkIsolationMode {
 	switch mode {
 	case NetIsolationModeHost:
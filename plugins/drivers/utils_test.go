@@ -37,6 +37,52 @@ func TestResourceUsageRoundTrip(t *testing.T) {
 	require.EqualValues(t, parsed, input)
 }
 
+func TestTaskStatsRoundTrip(t *testing.T) {
+	input := &TaskResourceUsage{
+		ResourceUsage: &ResourceUsage{
+			CpuStats: &CpuStats{
+				TotalTicks: 21.920595295932515,
+				Percent:    0.9963906952696598,
+				Measured:   []string{"Total Ticks", "Percent"},
+			},
+			MemoryStats: &MemoryStats{
+				RSS:      25681920,
+				Measured: []string{"RSS"},
+			},
+			DiskIOStats: &DiskIOStats{
+				ReadBytes:  4096,
+				WriteBytes: 8192,
+				ReadOps:    1,
+				WriteOps:   2,
+				Measured:   []string{"Read Bytes", "Write Bytes", "Read Ops", "Write Ops"},
+				Devices: map[string]*DiskIOStats{
+					"sda": {
+						ReadBytes:  4096,
+						WriteBytes: 8192,
+						ReadOps:    1,
+						WriteOps:   2,
+						Measured:   []string{"Read Bytes", "Write Bytes", "Read Ops", "Write Ops"},
+					},
+				},
+			},
+		},
+		Timestamp: 1635000000000000000,
+		Pids: map[string]*ResourceUsage{
+			"123": {
+				CpuStats:    &CpuStats{Measured: []string{}},
+				MemoryStats: &MemoryStats{Measured: []string{}},
+			},
+		},
+	}
+
+	pb, err := TaskStatsToProto(input)
+	require.NoError(t, err)
+
+	parsed, err := TaskStatsFromProto(pb)
+	require.NoError(t, err)
+	require.EqualValues(t, input, parsed)
+}
+
 func TestTaskConfigRoundTrip(t *testing.T) {
 
 	input := &TaskConfig{
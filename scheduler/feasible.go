@@ -26,6 +26,7 @@ const (
 	FilterConstraintCSIVolumeInUseTemplate      = "CSI volume %s has exhausted its available writer claims" //
 	FilterConstraintDrivers                     = "missing drivers"
 	FilterConstraintDevices                     = "missing devices"
+	FilterConstraintTmpfs                       = "tmpfs exceeds max task size"
 )
 
 var (
@@ -1168,6 +1169,55 @@ func (w *FeasibilityWrapper) available(option *structs.Node) bool {
 	return true
 }
 
+// TmpfsChecker is a FeasibilityChecker which returns whether a node allows
+// tmpfs scratch directories as large as the task group's tasks request, as
+// advertised by its tmpfs.max_task_mb attribute.
+type TmpfsChecker struct {
+	ctx Context
+
+	// sizeMB is the size of the largest tmpfs of the task group's tasks, or
+	// 0 if none of them requests one
+	sizeMB int
+}
+
+// NewTmpfsChecker creates a TmpfsChecker
+func NewTmpfsChecker(ctx Context) *TmpfsChecker {
+	return &TmpfsChecker{
+		ctx: ctx,
+	}
+}
+
+func (c *TmpfsChecker) SetTaskGroup(tg *structs.TaskGroup) {
+	c.sizeMB = 0
+	for _, task := range tg.Tasks {
+		if task.Tmpfs != nil && task.Tmpfs.SizeMB > c.sizeMB {
+			c.sizeMB = task.Tmpfs.SizeMB
+		}
+	}
+}
+
+func (c *TmpfsChecker) Feasible(option *structs.Node) bool {
+	if c.hasTmpfs(option) {
+		return true
+	}
+
+	c.ctx.Metrics().FilterNode(option, FilterConstraintTmpfs)
+	return false
+}
+
+func (c *TmpfsChecker) hasTmpfs(option *structs.Node) bool {
+	if c.sizeMB == 0 {
+		return true
+	}
+
+	// Nodes that don't allow task tmpfs don't set the attribute
+	maxMB, err := strconv.Atoi(option.Attributes["tmpfs.max_task_mb"])
+	if err != nil {
+		return false
+	}
+	return c.sizeMB <= maxMB
+}
+
 // DeviceChecker is a FeasibilityChecker which returns whether a node has the
 // devices necessary to scheduler a task group.
 type DeviceChecker struct {
@@ -2345,6 +2345,50 @@ func TestSetContainsAny(t *testing.T) {
 	require.False(t, checkSetContainsAny("b", "a"))
 }
 
+func TestTmpfsChecker(t *testing.T) {
+	_, ctx := testContext(t)
+
+	node := func(maxMB string) *structs.Node {
+		n := mock.Node()
+		if maxMB != "" {
+			n.Attributes["tmpfs.max_task_mb"] = maxMB
+		}
+		return n
+	}
+	tg := func(sizes ...int) *structs.TaskGroup {
+		tg := &structs.TaskGroup{Name: "example"}
+		for _, size := range sizes {
+			task := &structs.Task{}
+			if size > 0 {
+				task.Tmpfs = &structs.TaskTmpfs{SizeMB: size}
+			}
+			tg.Tasks = append(tg.Tasks, task)
+		}
+		return tg
+	}
+
+	cases := []struct {
+		Name   string
+		Node   *structs.Node
+		TG     *structs.TaskGroup
+		Result bool
+	}{
+		{"no tmpfs", node(""), tg(0), true},
+		{"node without tmpfs", node(""), tg(64), false},
+		{"within max", node("128"), tg(64, 128), true},
+		{"over max", node("100"), tg(64, 128), false},
+		{"numeric compare", node("1024"), tg(512), true},
+	}
+
+	checker := NewTmpfsChecker(ctx)
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			checker.SetTaskGroup(c.TG)
+			require.Equal(t, c.Result, checker.Feasible(c.Node))
+		})
+	}
+}
+
 func TestDeviceChecker(t *testing.T) {
 	getTg := func(devices ...*structs.RequestedDevice) *structs.TaskGroup {
 		return &structs.TaskGroup{
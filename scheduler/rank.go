@@ -347,17 +347,20 @@ OUTER:
 		}
 
 		for _, task := range iter.taskGroup.Tasks {
-			// Allocate the resources
+			// Allocate the resources. A tmpfs not counted against the
+			// task's memory is reserved in addition to it, as its pages
+			// are charged to the task's memory.
+			tmpfsMB := int64(task.Tmpfs.ReservedMemoryMB())
 			taskResources := &structs.AllocatedTaskResources{
 				Cpu: structs.AllocatedCpuResources{
 					CpuShares: int64(task.Resources.CPU),
 				},
 				Memory: structs.AllocatedMemoryResources{
-					MemoryMB: int64(task.Resources.MemoryMB),
+					MemoryMB: int64(task.Resources.MemoryMB) + tmpfsMB,
 				},
 			}
-			if iter.memoryOversubscription {
-				taskResources.Memory.MemoryMaxMB = int64(task.Resources.MemoryMaxMB)
+			if iter.memoryOversubscription && task.Resources.MemoryMaxMB > 0 {
+				taskResources.Memory.MemoryMaxMB = int64(task.Resources.MemoryMaxMB) + tmpfsMB
 			}
 
 			// Check if we need a network resource
@@ -669,6 +672,51 @@ func (iter *NodeReschedulingPenaltyIterator) Reset() {
 	iter.source.Reset()
 }
 
+// DriverHealthPenaltyIterator applies a scoring penalty to nodes on which a
+// driver used by the task group is degraded, so that they are only chosen when
+// other nodes are worse fits.
+type DriverHealthPenaltyIterator struct {
+	ctx     Context
+	source  RankIterator
+	drivers map[string]struct{}
+}
+
+// NewDriverHealthPenaltyIterator is used to create a
+// DriverHealthPenaltyIterator that penalizes nodes with degraded drivers.
+func NewDriverHealthPenaltyIterator(ctx Context, source RankIterator) *DriverHealthPenaltyIterator {
+	return &DriverHealthPenaltyIterator{
+		ctx:    ctx,
+		source: source,
+	}
+}
+
+func (iter *DriverHealthPenaltyIterator) SetDrivers(d map[string]struct{}) {
+	iter.drivers = d
+}
+
+func (iter *DriverHealthPenaltyIterator) Next() *RankedNode {
+	option := iter.source.Next()
+	if option == nil {
+		return nil
+	}
+
+	// Only score nodes with a degraded driver, so that scores normalized
+	// across the other nodes are unaffected
+	for driver := range iter.drivers {
+		if info := option.Node.Drivers[driver]; info != nil && info.Degraded {
+			option.Scores = append(option.Scores, -1)
+			iter.ctx.Metrics().ScoreNode(option.Node, "driver-health-penalty", -1)
+			break
+		}
+	}
+
+	return option
+}
+
+func (iter *DriverHealthPenaltyIterator) Reset() {
+	iter.source.Reset()
+}
+
 // NodeAffinityIterator is used to resolve any affinity rules in the job or task group,
 // and apply a weighted score to nodes if they match.
 type NodeAffinityIterator struct {
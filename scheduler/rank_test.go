@@ -133,6 +133,59 @@ func TestBinPackIterator_NoExistingAlloc(t *testing.T) {
 	}
 }
 
+// TestBinPackIterator_TaskTmpfs asserts that a task's tmpfs not counted
+// against its memory is reserved in addition to it.
+func TestBinPackIterator_TaskTmpfs(t *testing.T) {
+	_, ctx := testContext(t)
+	node := &RankedNode{
+		Node: &structs.Node{
+			NodeResources: &structs.NodeResources{
+				Cpu: structs.NodeCpuResources{
+					CpuShares: 4096,
+				},
+				Memory: structs.NodeMemoryResources{
+					MemoryMB: 2048,
+				},
+			},
+		},
+	}
+
+	rank := func(tmpfs *structs.TaskTmpfs) []*RankedNode {
+		taskGroup := &structs.TaskGroup{
+			EphemeralDisk: &structs.EphemeralDisk{},
+			Tasks: []*structs.Task{
+				{
+					Name: "web",
+					Resources: &structs.Resources{
+						CPU:      1024,
+						MemoryMB: 1536,
+					},
+					Tmpfs: tmpfs,
+				},
+			},
+		}
+		static := NewStaticRankIterator(ctx, []*RankedNode{node})
+		binp := NewBinPackIterator(ctx, static, false, 0, testSchedulerConfig)
+		binp.SetTaskGroup(taskGroup)
+		return collectRanked(NewScoreNormalizationIterator(ctx, binp))
+	}
+
+	// A tmpfs counted against the task's memory doesn't reserve more
+	out := rank(&structs.TaskTmpfs{SizeMB: 256, CountMemory: true})
+	require.Len(t, out, 1)
+	require.Equal(t, int64(1536), out[0].TaskResources["web"].Memory.MemoryMB)
+
+	// A tmpfs not counted against it is reserved in addition
+	out = rank(&structs.TaskTmpfs{SizeMB: 256})
+	require.Len(t, out, 1)
+	require.Equal(t, int64(1792), out[0].TaskResources["web"].Memory.MemoryMB)
+
+	// The node is exhausted once the tmpfs is reserved alongside the
+	// task's memory
+	out = rank(&structs.TaskTmpfs{SizeMB: 1024})
+	require.Empty(t, out)
+}
+
 // TestBinPackIterator_NoExistingAlloc_MixedReserve asserts that node's with
 // reserved resources are scored equivalent to as if they had a lower amount of
 // resources.
@@ -1956,6 +2009,56 @@ func TestNodeAntiAffinity_PenaltyNodes(t *testing.T) {
 
 }
 
+func TestDriverHealthPenaltyIterator(t *testing.T) {
+	_, ctx := testContext(t)
+	node1 := &structs.Node{
+		ID: uuid.Generate(),
+		Drivers: map[string]*structs.DriverInfo{
+			"exec": {Detected: true, Healthy: true, Degraded: true},
+		},
+	}
+	node2 := &structs.Node{
+		ID: uuid.Generate(),
+		Drivers: map[string]*structs.DriverInfo{
+			"exec": {Detected: true, Healthy: true},
+		},
+	}
+
+	nodes := []*RankedNode{
+		{
+			Node: node1,
+		},
+		{
+			Node: node2,
+		},
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	driverHealthIter := NewDriverHealthPenaltyIterator(ctx, static)
+	driverHealthIter.SetDrivers(map[string]struct{}{"exec": {}})
+
+	scoreNorm := NewScoreNormalizationIterator(ctx, driverHealthIter)
+
+	out := collectRanked(scoreNorm)
+
+	require := require.New(t)
+	require.Equal(2, len(out))
+	require.Equal(node1.ID, out[0].Node.ID)
+	require.Equal(-1.0, out[0].FinalScore)
+
+	require.Equal(node2.ID, out[1].Node.ID)
+	require.Equal(0.0, out[1].FinalScore)
+
+	// Drivers the task group doesn't use are ignored
+	static = NewStaticRankIterator(ctx, []*RankedNode{{Node: node1}, {Node: node2}})
+	driverHealthIter = NewDriverHealthPenaltyIterator(ctx, static)
+	driverHealthIter.SetDrivers(map[string]struct{}{"docker": {}})
+	out = collectRanked(NewScoreNormalizationIterator(ctx, driverHealthIter))
+	require.Equal(2, len(out))
+	require.Equal(0.0, out[0].FinalScore)
+	require.Equal(0.0, out[1].FinalScore)
+}
+
 func TestScoreNormalizationIterator(t *testing.T) {
 	// Test normalized scores when there is more than one scorer
 	_, ctx := testContext(t)
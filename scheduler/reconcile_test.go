@@ -4854,6 +4854,99 @@ func TestReconciler_ForceReschedule_Service(t *testing.T) {
 	assertPlacementsAreRescheduled(t, 1, r.place)
 }
 
+// Tests that an alloc the client rejected is rescheduled after the reschedule
+// policy's delay, even though the policy's attempts don't allow it
+func TestReconciler_ClientRejected_Service(t *testing.T) {
+	require := require.New(t)
+
+	// Set desired 5
+	job := mock.Job()
+	job.TaskGroups[0].Count = 5
+	tgName := job.TaskGroups[0].Name
+	now := time.Now()
+
+	// Set up a reschedule policy that doesn't allow rescheduling
+	delayDur := 15 * time.Second
+	job.TaskGroups[0].ReschedulePolicy = &structs.ReschedulePolicy{
+		Attempts:  0,
+		Interval:  24 * time.Hour,
+		Delay:     delayDur,
+		Unlimited: false,
+	}
+	job.TaskGroups[0].Update = noCanaryUpdate
+
+	// Create 5 existing allocations
+	var allocs []*structs.Allocation
+	for i := 0; i < 5; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = uuid.Generate()
+		alloc.Name = structs.AllocName(job.ID, job.TaskGroups[0].Name, uint(i))
+		allocs = append(allocs, alloc)
+		alloc.ClientStatus = structs.AllocClientStatusRunning
+	}
+
+	// Mark one as rejected by its client just now
+	event := structs.NewTaskEvent(structs.TaskSetupFailure)
+	event.Details[structs.TaskEventDetailRejected] = "true"
+	allocs[0].ClientStatus = structs.AllocClientStatusFailed
+	allocs[0].TaskStates = map[string]*structs.TaskState{
+		"web": {State: "dead", Failed: true, FinishedAt: now, Events: []*structs.TaskEvent{event}},
+	}
+
+	reconciler := NewAllocReconciler(testlog.HCLogger(t), allocUpdateFnIgnore, false, job.ID, job,
+		nil, allocs, nil, uuid.Generate(), 50)
+	r := reconciler.Compute()
+
+	// Verify that a follow up eval was created for the policy's delay
+	evals := r.desiredFollowupEvals[tgName]
+	require.Len(evals, 1)
+	require.Equal(now.Add(delayDur), evals[0].WaitUntil)
+
+	// Verify that the rejected alloc isn't replaced yet
+	assertResults(t, r, &resultExpectation{
+		createDeployment:  nil,
+		deploymentUpdates: nil,
+		place:             0,
+		stop:              0,
+		inplace:           0,
+		attributeUpdates:  1,
+		desiredTGUpdates: map[string]*structs.DesiredUpdates{
+			job.TaskGroups[0].Name: {
+				Ignore: 5,
+			},
+		},
+	})
+	assertNamesHaveIndexes(t, intRange(0, 0), attributeUpdatesToNames(r.attributeUpdates))
+
+	// Once the delay has passed, the follow up eval replaces the rejected
+	// alloc
+	allocs[0].FollowupEvalID = evals[0].ID
+	reconciler = NewAllocReconciler(testlog.HCLogger(t), allocUpdateFnIgnore, false, job.ID, job,
+		nil, allocs, nil, evals[0].ID, 50)
+	r = reconciler.Compute()
+
+	assertResults(t, r, &resultExpectation{
+		createDeployment:  nil,
+		deploymentUpdates: nil,
+		place:             1,
+		stop:              1,
+		inplace:           0,
+		desiredTGUpdates: map[string]*structs.DesiredUpdates{
+			job.TaskGroups[0].Name: {
+				Place:  1,
+				Stop:   1,
+				Ignore: 4,
+			},
+		},
+	})
+
+	assertNamesHaveIndexes(t, intRange(0, 0), placeResultsToNames(r.place))
+	assertPlaceResultsHavePreviousAllocs(t, 1, r.place)
+	assertPlacementsAreRescheduled(t, 1, r.place)
+}
+
 // Tests behavior of service failure with rescheduling policy preventing rescheduling:
 // new allocs should be placed to satisfy the job count, and current allocations are
 // left unmodified
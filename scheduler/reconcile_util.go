@@ -356,6 +356,14 @@ func updateByReschedulable(alloc *structs.Allocation, now time.Time, evalID stri
 
 	// Reschedule if the eval ID matches the alloc's followup evalID or if its close to its reschedule time
 	rescheduleTime, eligible := alloc.NextRescheduleTime()
+
+	// Allocations the client rejected before running them are replaced even
+	// once the policy's attempts are used up, as they never ran, but still
+	// after the policy's delay so that a node that keeps rejecting them
+	// doesn't cause a tight placement loop
+	if alloc.RejectedByClient() {
+		eligible = !rescheduleTime.IsZero()
+	}
 	if eligible && (alloc.FollowupEvalID == evalID || rescheduleTime.Sub(now) <= rescheduleWindowSize) {
 		rescheduleNow = true
 		return
@@ -52,6 +52,7 @@ type GenericStack struct {
 	taskGroupDrivers     *DriverChecker
 	taskGroupConstraint  *ConstraintChecker
 	taskGroupDevices     *DeviceChecker
+	taskGroupTmpfs       *TmpfsChecker
 	taskGroupHostVolumes *HostVolumeChecker
 	taskGroupCSIVolumes  *CSIVolumeChecker
 	taskGroupNetwork     *NetworkChecker
@@ -61,6 +62,7 @@ type GenericStack struct {
 	binPack                    *BinPackIterator
 	jobAntiAff                 *JobAntiAffinityIterator
 	nodeReschedulingPenalty    *NodeReschedulingPenaltyIterator
+	driverHealthPenalty        *DriverHealthPenaltyIterator
 	limit                      *LimitIterator
 	maxScore                   *MaxScoreIterator
 	nodeAffinity               *NodeAffinityIterator
@@ -143,6 +145,7 @@ func (s *GenericStack) Select(tg *structs.TaskGroup, options *SelectOptions) *Ra
 	s.taskGroupDrivers.SetDrivers(tgConstr.drivers)
 	s.taskGroupConstraint.SetConstraints(tgConstr.constraints)
 	s.taskGroupDevices.SetTaskGroup(tg)
+	s.taskGroupTmpfs.SetTaskGroup(tg)
 	s.taskGroupHostVolumes.SetVolumes(tg.Volumes)
 	s.taskGroupCSIVolumes.SetVolumes(options.AllocName, tg.Volumes)
 	if len(tg.Networks) > 0 {
@@ -159,6 +162,7 @@ func (s *GenericStack) Select(tg *structs.TaskGroup, options *SelectOptions) *Ra
 	if options != nil {
 		s.nodeReschedulingPenalty.SetPenaltyNodes(options.PenaltyNodeIDs)
 	}
+	s.driverHealthPenalty.SetDrivers(tgConstr.drivers)
 	s.nodeAffinity.SetTaskGroup(tg)
 	s.spread.SetTaskGroup(tg)
 
@@ -197,6 +201,7 @@ type SystemStack struct {
 	taskGroupDrivers     *DriverChecker
 	taskGroupConstraint  *ConstraintChecker
 	taskGroupDevices     *DeviceChecker
+	taskGroupTmpfs       *TmpfsChecker
 	taskGroupHostVolumes *HostVolumeChecker
 	taskGroupCSIVolumes  *CSIVolumeChecker
 	taskGroupNetwork     *NetworkChecker
@@ -237,6 +242,9 @@ func NewSystemStack(sysbatch bool, ctx Context) *SystemStack {
 	// Filter on task group devices
 	s.taskGroupDevices = NewDeviceChecker(ctx)
 
+	// Filter on the size of task tmpfs the nodes allow
+	s.taskGroupTmpfs = NewTmpfsChecker(ctx)
+
 	// Filter on available client networks
 	s.taskGroupNetwork = NewNetworkChecker(ctx)
 
@@ -250,6 +258,7 @@ func NewSystemStack(sysbatch bool, ctx Context) *SystemStack {
 		s.taskGroupConstraint,
 		s.taskGroupHostVolumes,
 		s.taskGroupDevices,
+		s.taskGroupTmpfs,
 		s.taskGroupNetwork,
 	}
 	avail := []FeasibilityChecker{s.taskGroupCSIVolumes}
@@ -318,6 +327,7 @@ func (s *SystemStack) Select(tg *structs.TaskGroup, options *SelectOptions) *Ran
 	s.taskGroupDrivers.SetDrivers(tgConstr.drivers)
 	s.taskGroupConstraint.SetConstraints(tgConstr.constraints)
 	s.taskGroupDevices.SetTaskGroup(tg)
+	s.taskGroupTmpfs.SetTaskGroup(tg)
 	s.taskGroupHostVolumes.SetVolumes(tg.Volumes)
 	s.taskGroupCSIVolumes.SetVolumes(options.AllocName, tg.Volumes)
 	if len(tg.Networks) > 0 {
@@ -364,6 +374,9 @@ func NewGenericStack(batch bool, ctx Context) *GenericStack {
 	// Filter on task group devices
 	s.taskGroupDevices = NewDeviceChecker(ctx)
 
+	// Filter on the size of task tmpfs the nodes allow
+	s.taskGroupTmpfs = NewTmpfsChecker(ctx)
+
 	// Filter on task group host volumes
 	s.taskGroupHostVolumes = NewHostVolumeChecker(ctx)
 
@@ -383,6 +396,7 @@ func NewGenericStack(batch bool, ctx Context) *GenericStack {
 		s.taskGroupConstraint,
 		s.taskGroupHostVolumes,
 		s.taskGroupDevices,
+		s.taskGroupTmpfs,
 		s.taskGroupNetwork,
 	}
 	avail := []FeasibilityChecker{s.taskGroupCSIVolumes}
@@ -417,8 +431,12 @@ func NewGenericStack(batch bool, ctx Context) *GenericStack {
 	// node where the allocation failed previously
 	s.nodeReschedulingPenalty = NewNodeReschedulingPenaltyIterator(ctx, s.jobAntiAff)
 
+	// Apply driver health penalty. This tries to avoid placing on a node
+	// where the task starts of a driver used by the task group are failing
+	s.driverHealthPenalty = NewDriverHealthPenaltyIterator(ctx, s.nodeReschedulingPenalty)
+
 	// Apply scores based on affinity stanza
-	s.nodeAffinity = NewNodeAffinityIterator(ctx, s.nodeReschedulingPenalty)
+	s.nodeAffinity = NewNodeAffinityIterator(ctx, s.driverHealthPenalty)
 
 	// Apply scores based on spread stanza
 	s.spread = NewSpreadIterator(ctx, s.nodeAffinity)